@@ -3,38 +3,138 @@ package main
 import (
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/anthropics/aef/codex/internal/core"
+	"github.com/anthropics/aef/codex/internal/env"
+	"github.com/anthropics/aef/codex/internal/project"
+	"github.com/anthropics/aef/codex/internal/walk"
 )
 
 // Config holds CLI configuration loaded from environment
 type Config struct {
-	AnthropicAPIKey        string
-	ModelsPath             string
-	MetadataDBPath         string
-	LocalEmbeddingURL   string
-	LocalEmbeddingModel string
+	AnthropicAPIKey         string
+	ModelsPath              string
+	MetadataDBPath          string
+	EmbeddingBackend        string
+	LocalEmbeddingURL       string
+	LocalEmbeddingModel     string
+	EmbeddingAPIKey         string
+	EmbeddingQueryPrefix    string
+	EmbeddingDocumentPrefix string
+	EmbeddingDimensions     int
+	EmbeddingMaxTokens      int
+	EmbeddingModelCheck     string
+	EnrichmentProvider      string
+	EnrichmentModel         string
+	EnrichmentURL           string
+	VectorIndex             string
+	EmbedBatchSize          int
+	EmbedParallelism        int
+	ChunkMaxTokens          int
+	Project                 string
+	ProjectBoost            float64
+	FeedbackRanking         string
+	FeedbackWeight          float64
+	FeedbackHalfLife        time.Duration
+	FeedbackProjectWeight   float64
+	Fusion                  string
+	FusionK                 float64
+	FusionWeights           core.FusionWeights
+	CodeFusionWeights       core.FusionWeights
+	ExpansionProvider       string
+	ExpansionModel          string
+	ExpansionURL            string
+	ExpansionParaphrases    int
+	Walk                    walk.Options
 }
 
-// LoadConfig loads configuration from environment variables
+// LoadConfig loads configuration from environment variables and global flags
 func LoadConfig() *Config {
-	return &Config{
-		AnthropicAPIKey:        os.Getenv("ANTHROPIC_API_KEY"),
-		ModelsPath:             os.Getenv("CODEX_MODELS_PATH"),
-		MetadataDBPath:         getEnv("CODEX_METADATA_DB", defaultMetadataPath()),
-		LocalEmbeddingURL:   os.Getenv("LOCAL_EMBEDDING_URL"),
-		LocalEmbeddingModel: os.Getenv("LOCAL_EMBEDDING_MODEL"),
+	cfg := &Config{
+		AnthropicAPIKey:         os.Getenv("ANTHROPIC_API_KEY"),
+		ModelsPath:              os.Getenv("CODEX_MODELS_PATH"),
+		MetadataDBPath:          env.Get("CODEX_METADATA_DB", defaultMetadataPath()),
+		EmbeddingBackend:        os.Getenv("CODEX_EMBEDDING_BACKEND"),
+		LocalEmbeddingURL:       os.Getenv("LOCAL_EMBEDDING_URL"),
+		LocalEmbeddingModel:     os.Getenv("LOCAL_EMBEDDING_MODEL"),
+		EmbeddingAPIKey:         os.Getenv("CODEX_EMBEDDING_API_KEY"),
+		EmbeddingQueryPrefix:    os.Getenv("CODEX_EMBEDDING_QUERY_PREFIX"),
+		EmbeddingDocumentPrefix: os.Getenv("CODEX_EMBEDDING_DOCUMENT_PREFIX"),
+		EmbeddingDimensions:     env.Int("CODEX_EMBEDDING_DIMENSIONS", 0),
+		EmbeddingMaxTokens:      env.Int("CODEX_EMBEDDING_MAX_TOKENS", 0),
+		EmbeddingModelCheck:     os.Getenv("CODEX_EMBEDDING_MODEL_CHECK"),
+		EnrichmentProvider:      os.Getenv("CODEX_ENRICHMENT_PROVIDER"),
+		EnrichmentModel:         os.Getenv("CODEX_ENRICHMENT_MODEL"),
+		EnrichmentURL:           os.Getenv("CODEX_ENRICHMENT_URL"),
+		VectorIndex:             os.Getenv("CODEX_VECTOR_INDEX"),
+		EmbedBatchSize:          env.Int("CODEX_EMBED_BATCH_SIZE", 0),
+		EmbedParallelism:        env.Int("CODEX_EMBED_PARALLELISM", 0),
+		ChunkMaxTokens:          env.Int("CODEX_CHUNK_MAX_TOKENS", 0),
+		Project:                 project.Resolve(project.Dir()),
+		ProjectBoost:            env.Float("CODEX_PROJECT_BOOST", 0),
+		FeedbackRanking:         os.Getenv("CODEX_FEEDBACK_RANKING"),
+		FeedbackWeight:          env.Float("CODEX_FEEDBACK_WEIGHT", 0),
+		FeedbackHalfLife:        env.Days("CODEX_FEEDBACK_HALF_LIFE_DAYS"),
+		FeedbackProjectWeight:   env.Float("CODEX_FEEDBACK_PROJECT_WEIGHT", 0),
+		Fusion:                  os.Getenv("CODEX_FUSION"),
+		FusionK:                 env.Float("CODEX_FUSION_K", 0),
+		FusionWeights:           env.Weights("CODEX_FUSION_WEIGHTS"),
+		CodeFusionWeights:       env.Weights("CODEX_FUSION_CODE_WEIGHTS"),
+		ExpansionProvider:       os.Getenv("CODEX_EXPANSION_PROVIDER"),
+		ExpansionModel:          os.Getenv("CODEX_EXPANSION_MODEL"),
+		ExpansionURL:            os.Getenv("CODEX_EXPANSION_URL"),
+		ExpansionParaphrases:    env.Int("CODEX_EXPANSION_PARAPHRASES", 0),
+		Walk: walk.Options{
+			Include:     env.List("CODEX_INDEX_INCLUDE"),
+			Exclude:     env.List("CODEX_INDEX_EXCLUDE"),
+			MaxFileSize: env.Size("CODEX_INDEX_MAX_FILE_SIZE"),
+			Symlinks:    os.Getenv("CODEX_INDEX_SYMLINKS"),
+		},
 	}
+	if embeddingBackend != "" {
+		cfg.EmbeddingBackend = embeddingBackend
+	}
+	return cfg
 }
 
 // ToEngineConfig converts to core.Config
 func (c *Config) ToEngineConfig() core.Config {
 	return core.Config{
-		AnthropicAPIKey:        c.AnthropicAPIKey,
-		ModelsPath:             c.ModelsPath,
-		MetadataDBPath:         c.MetadataDBPath,
-		LocalEmbeddingURL:   c.LocalEmbeddingURL,
-		LocalEmbeddingModel: c.LocalEmbeddingModel,
+		AnthropicAPIKey:         c.AnthropicAPIKey,
+		ModelsPath:              c.ModelsPath,
+		MetadataDBPath:          c.MetadataDBPath,
+		EmbeddingBackend:        c.EmbeddingBackend,
+		LocalEmbeddingURL:       c.LocalEmbeddingURL,
+		LocalEmbeddingModel:     c.LocalEmbeddingModel,
+		EmbeddingAPIKey:         c.EmbeddingAPIKey,
+		EmbeddingQueryPrefix:    c.EmbeddingQueryPrefix,
+		EmbeddingDocumentPrefix: c.EmbeddingDocumentPrefix,
+		EmbeddingDimensions:     c.EmbeddingDimensions,
+		EmbeddingMaxTokens:      c.EmbeddingMaxTokens,
+		EmbeddingModelCheck:     c.EmbeddingModelCheck,
+		EnrichmentProvider:      c.EnrichmentProvider,
+		EnrichmentModel:         c.EnrichmentModel,
+		EnrichmentURL:           c.EnrichmentURL,
+		VectorIndex:             c.VectorIndex,
+		EmbedBatchSize:          c.EmbedBatchSize,
+		EmbedParallelism:        c.EmbedParallelism,
+		ChunkMaxTokens:          c.ChunkMaxTokens,
+		Project:                 c.Project,
+		ProjectBoost:            c.ProjectBoost,
+		FeedbackRanking:         c.FeedbackRanking,
+		FeedbackWeight:          c.FeedbackWeight,
+		FeedbackHalfLife:        c.FeedbackHalfLife,
+		FeedbackProjectWeight:   c.FeedbackProjectWeight,
+		Fusion:                  c.Fusion,
+		FusionK:                 c.FusionK,
+		FusionWeights:           c.FusionWeights,
+		CodeFusionWeights:       c.CodeFusionWeights,
+		ExpansionProvider:       c.ExpansionProvider,
+		ExpansionModel:          c.ExpansionModel,
+		ExpansionURL:            c.ExpansionURL,
+		ExpansionParaphrases:    c.ExpansionParaphrases,
+		Walk:                    c.Walk,
 	}
 }
 
@@ -43,13 +143,6 @@ func (c *Config) Validate() error {
 	return nil
 }
 
-func getEnv(key, defaultVal string) string {
-	if val := os.Getenv(key); val != "" {
-		return val
-	}
-	return defaultVal
-}
-
 func defaultMetadataPath() string {
 	home, err := os.UserHomeDir()
 	if err != nil {
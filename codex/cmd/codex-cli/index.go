@@ -2,35 +2,72 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/anthropics/aef/codex/internal/core"
+	"github.com/anthropics/aef/codex/internal/project"
+	"github.com/anthropics/aef/codex/internal/walk"
 )
 
 var (
-	indexRecursive bool
-	indexScope     string
-	indexType      string
-	indexTags      []string
+	indexRecursive   bool
+	indexScope       string
+	indexType        string
+	indexTags        []string
+	indexInclude     []string
+	indexExclude     []string
+	indexMaxFileSize string
+	indexSymlinks    string
+	indexNoIgnore    bool
+	indexWatch       bool
+	indexDebounce    time.Duration
+	indexGit         string
+	indexRef         string
+	indexSince       string
 )
 
 var indexCmd = &cobra.Command{
-	Use:   "index [path]",
+	Use:   "index [path] | --git <repo>",
 	Short: "Index files or directories into Codex",
 	Long: `Index files or directories into the Codex knowledge base.
 
 For code files, uses AST-aware chunking and Voyage embeddings.
 For documentation files, uses semantic chunking and OpenAI embeddings.
 
+Directory indexing honours .gitignore, .git/info/exclude and .codexignore
+files, and skips hidden, binary, minified and generated files. --include and
+--exclude take gitignore-style globs relative to the directory, in addition
+to CODEX_INDEX_INCLUDE and CODEX_INDEX_EXCLUDE.
+
+With --watch, the directory is indexed and then kept in sync: changed files
+are re-indexed after a short quiet period, and deleted or renamed files lose
+their chunks. Ctrl-C stops watching.
+
+With --git, a repository is indexed from git: only the files that changed
+since the last indexed commit (or --since) are indexed, and deleted files
+lose their chunks. Chunks record the commit, author and date that last
+changed their file. --ref indexes a branch or tag without checking it out;
+find its chunks with "codex-cli search --git-ref". The last indexed commit
+is stored per repository and ref, so running the same command after each
+merge keeps the index current.
+
 Examples:
   codex-cli index path/to/file.go --scope project --tags "api,auth"
   codex-cli index ./src --recursive --scope project
+  codex-cli index . -r --exclude "*.pb.go" --exclude "testdata/" --max-file-size 256KB
+  codex-cli index . --watch
+  codex-cli index --git . --since main~20
+  codex-cli index --git . --ref release/2.3
   codex-cli index README.md --type doc`,
-	Args: cobra.ExactArgs(1),
+	Args: cobra.RangeArgs(0, 1),
 	RunE: runIndex,
 }
 
@@ -39,9 +76,31 @@ func init() {
 	indexCmd.Flags().StringVarP(&indexScope, "scope", "s", "project", "scope (global or project)")
 	indexCmd.Flags().StringVarP(&indexType, "type", "t", "", "content type (code, doc, or auto-detect)")
 	indexCmd.Flags().StringSliceVar(&indexTags, "tags", nil, "tags to apply")
+	indexCmd.Flags().StringArrayVar(&indexInclude, "include", nil, "only index files matching this glob (repeatable)")
+	indexCmd.Flags().StringArrayVar(&indexExclude, "exclude", nil, "skip files and directories matching this glob (repeatable)")
+	indexCmd.Flags().StringVar(&indexMaxFileSize, "max-file-size", "", "skip larger files, e.g. 512KB (default 1MB, -1: no limit)")
+	indexCmd.Flags().StringVar(&indexSymlinks, "symlinks", "", "symlink policy: files (default), skip or follow")
+	indexCmd.Flags().BoolVar(&indexNoIgnore, "no-ignore", false, "do not read .gitignore, .git/info/exclude or .codexignore")
+	indexCmd.Flags().BoolVarP(&indexWatch, "watch", "w", false, "keep indexing the directory as files change, until interrupted (implies --recursive)")
+	indexCmd.Flags().DurationVar(&indexDebounce, "debounce", 0, "with --watch, wait this long after the last change before indexing (default 500ms)")
+	indexCmd.Flags().StringVar(&indexGit, "git", "", "index the git repository containing this directory, incrementally since the last indexed commit")
+	indexCmd.Flags().StringVar(&indexRef, "ref", "", "with --git, index this branch, tag or commit without checking it out (default: the work tree)")
+	indexCmd.Flags().StringVar(&indexSince, "since", "", "with --git, index the changes since this commit instead of the last indexed one")
 }
 
 func runIndex(cmd *cobra.Command, args []string) error {
+	if indexGit != "" {
+		if len(args) > 0 || indexWatch {
+			return fmt.Errorf("--git takes the repository instead of a path and cannot be combined with --watch")
+		}
+		return runGitIndex()
+	}
+	if indexRef != "" || indexSince != "" {
+		return fmt.Errorf("--ref and --since require --git")
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("accepts 1 arg, received %d", len(args))
+	}
 	path := args[0]
 
 	// Resolve path
@@ -59,6 +118,18 @@ func runIndex(cmd *cobra.Command, args []string) error {
 	// Load config and create engine
 	cfg := LoadConfig()
 	// Local Ollama embedders are used — no API keys required for indexing.
+	if err := applyWalkFlags(&cfg.Walk); err != nil {
+		return err
+	}
+
+	// Attribute items to the repository being indexed, not the working directory
+	if os.Getenv("CODEX_PROJECT_PATH") == "" {
+		projectDir := absPath
+		if !info.IsDir() {
+			projectDir = filepath.Dir(absPath)
+		}
+		cfg.Project = project.Resolve(projectDir)
+	}
 
 	ctx := context.Background()
 	engine, err := core.NewSearchEngine(ctx, cfg.ToEngineConfig())
@@ -70,9 +141,99 @@ func runIndex(cmd *cobra.Command, args []string) error {
 	if info.IsDir() {
 		return indexDirectory(ctx, engine, absPath)
 	}
+	if indexWatch {
+		return fmt.Errorf("--watch requires a directory")
+	}
 	return indexFile(ctx, engine, absPath)
 }
 
+// runGitIndex indexes the repository of --git since its last indexed commit.
+func runGitIndex() error {
+	absPath, err := filepath.Abs(indexGit)
+	if err != nil {
+		return fmt.Errorf("invalid path: %w", err)
+	}
+
+	cfg := LoadConfig()
+	if err := applyWalkFlags(&cfg.Walk); err != nil {
+		return err
+	}
+	if os.Getenv("CODEX_PROJECT_PATH") == "" {
+		cfg.Project = project.Resolve(absPath)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	engine, err := core.NewSearchEngine(ctx, cfg.ToEngineConfig())
+	if err != nil {
+		return fmt.Errorf("failed to create engine: %w", err)
+	}
+	defer engine.Close()
+
+	target := "work tree"
+	if indexRef != "" {
+		target = indexRef
+	}
+	fmt.Printf("Indexing %s of %s\n", target, absPath)
+
+	var progress progressLine
+	stats, err := engine.IndexGit(ctx, core.GitIndexRequest{
+		Repo:  absPath,
+		Ref:   indexRef,
+		Since: indexSince,
+		Scope: indexScope,
+	}, progress.update)
+	progress.finish()
+	if stats == nil {
+		return fmt.Errorf("failed to index repository: %w", err)
+	}
+	if stats.GitBase == "" {
+		fmt.Printf("Commit %s: all files\n", shortSHA(stats.GitCommit))
+	} else {
+		fmt.Printf("Commit %s: changes since %s\n", shortSHA(stats.GitCommit), shortSHA(stats.GitBase))
+	}
+	printIndexReport(stats)
+
+	if errors.Is(err, context.Canceled) {
+		return fmt.Errorf("indexing interrupted after %d of %d files", progress.done, stats.FilesFound)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to index repository: %w", err)
+	}
+	if stats.FilesFailed > 0 {
+		fmt.Println("Some files failed; the next run indexes the same changes again")
+	}
+	return nil
+}
+
+// shortSHA abbreviates a commit SHA for display.
+func shortSHA(sha string) string {
+	if len(sha) > 12 {
+		return sha[:12]
+	}
+	return sha
+}
+
+// applyWalkFlags adds the file selection flags to the configured options.
+func applyWalkFlags(opts *walk.Options) error {
+	opts.Include = append(opts.Include, indexInclude...)
+	opts.Exclude = append(opts.Exclude, indexExclude...)
+	if indexMaxFileSize != "" {
+		size, err := walk.ParseSize(indexMaxFileSize)
+		if err != nil {
+			return fmt.Errorf("invalid --max-file-size: %w", err)
+		}
+		opts.MaxFileSize = size
+	}
+	if indexSymlinks != "" {
+		opts.Symlinks = indexSymlinks
+	}
+	if indexNoIgnore {
+		opts.NoIgnoreFiles = true
+	}
+	return opts.Validate()
+}
+
 func indexFile(ctx context.Context, engine *core.SearchEngine, path string) error {
 	content, err := os.ReadFile(path)
 	if err != nil {
@@ -92,27 +253,138 @@ func indexFile(ctx context.Context, engine *core.SearchEngine, path string) erro
 		return fmt.Errorf("failed to index: %w", err)
 	}
 
+	if result.Skipped {
+		fmt.Printf("Unchanged %s: %d chunks already indexed (ID: %s)\n", path, result.ChunksCount, result.ItemID)
+		return nil
+	}
 	fmt.Printf("Indexed %s: %d chunks created (ID: %s)\n", path, result.ChunksCount, result.ItemID)
 	return nil
 }
 
 func indexDirectory(ctx context.Context, engine *core.SearchEngine, dirPath string) error {
-	if !indexRecursive {
+	if !indexRecursive && !indexWatch {
 		return fmt.Errorf("directory indexing requires --recursive flag")
 	}
 
+	// Ctrl-C stops the run; files already written stay indexed
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if indexWatch {
+		return watchDirectory(ctx, engine, dirPath, indexScope, true)
+	}
+
 	fmt.Printf("Indexing directory: %s\n", dirPath)
 
-	results, err := engine.IndexDirectory(ctx, dirPath, indexScope)
+	var progress progressLine
+	stats, err := engine.IndexDirectoryWithProgress(ctx, dirPath, indexScope, progress.update)
+	progress.finish()
+	if stats == nil {
+		return fmt.Errorf("failed to index directory: %w", err)
+	}
+	printIndexReport(stats)
+
+	if errors.Is(err, context.Canceled) {
+		return fmt.Errorf("indexing interrupted after %d of %d files", progress.done, stats.FilesFound)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to index directory: %w", err)
 	}
+	return nil
+}
+
+// watchDirectory indexes a directory, then re-indexes changes until ctx is
+// cancelled. showProgress prints the progress of the initial index.
+func watchDirectory(ctx context.Context, engine *core.SearchEngine, dirPath, scope string, showProgress bool) error {
+	fmt.Printf("Indexing directory: %s\n", dirPath)
 
-	totalChunks := 0
-	for _, r := range results {
-		totalChunks += r.ChunksCount
+	var progress progressLine
+	opts := core.WatchOptions{Debounce: indexDebounce}
+	if showProgress {
+		opts.Progress = progress.update
+	}
+	initial := true
+	opts.OnSync = func(stats *core.IndexStats, err error) {
+		if initial {
+			initial = false
+			progress.finish()
+			printIndexReport(stats)
+			if err == nil {
+				fmt.Printf("Watching %s for changes\n", dirPath)
+			}
+			return
+		}
+		printSync(dirPath, stats, err)
 	}
 
-	fmt.Printf("Indexed %d files, %d total chunks\n", len(results), totalChunks)
+	if err := engine.WatchDirectory(ctx, dirPath, scope, opts); err != nil {
+		return fmt.Errorf("failed to watch %s: %w", dirPath, err)
+	}
+	fmt.Printf("Stopped watching %s\n", dirPath)
 	return nil
 }
+
+// progressLine prints indexing progress: a line per file with -v,
+// otherwise a throttled line rewritten in place.
+type progressLine struct {
+	last        time.Time
+	done, found int
+}
+
+func (p *progressLine) update(done, found int, file string) {
+	p.done, p.found = done, found
+	if verbose {
+		fmt.Printf("[%d/%d] %s\n", done, found, file)
+		return
+	}
+	if time.Since(p.last) >= 100*time.Millisecond {
+		p.last = time.Now()
+		fmt.Printf("\rProgress: %d/%d files", done, found)
+	}
+}
+
+// finish completes the in-place line with the final count.
+func (p *progressLine) finish() {
+	if !verbose && p.done > 0 {
+		fmt.Printf("\rProgress: %d/%d files\n", p.done, p.found)
+	}
+}
+
+// printIndexReport prints the summary of a directory index, listing
+// ignored files with -v.
+func printIndexReport(stats *core.IndexStats) {
+	if verbose {
+		for _, r := range stats.Results {
+			if r.Ignored != "" {
+				fmt.Printf("Ignored %s (%s)\n", r.FilePath, r.Ignored)
+			}
+		}
+	}
+	core.PrintIndexStats(stats)
+}
+
+// printSync prints one line for a batch of changes that did something.
+func printSync(dirPath string, stats *core.IndexStats, err error) {
+	if err != nil && !errors.Is(err, context.Canceled) {
+		fmt.Printf("[%s] %s: sync failed: %v\n", time.Now().Format("15:04:05"), dirPath, err)
+	}
+	if stats.FilesIndexed+stats.FilesRemoved+stats.FilesFailed == 0 {
+		return
+	}
+	fmt.Printf("[%s] %s: %d indexed, %d removed, %d failed (%d chunks)\n",
+		time.Now().Format("15:04:05"), dirPath,
+		stats.FilesIndexed, stats.FilesRemoved, stats.FilesFailed, stats.TotalChunks)
+	if verbose {
+		for _, r := range stats.Results {
+			switch {
+			case r.Removed:
+				fmt.Printf("  removed %s\n", r.FilePath)
+			case !r.Skipped && r.Ignored == "":
+				fmt.Printf("  indexed %s\n", r.FilePath)
+			}
+		}
+	}
+	for _, msg := range stats.Errors {
+		fmt.Printf("  - %s\n", msg)
+	}
+}
@@ -11,6 +11,8 @@ import (
 var (
 	version = "0.1.0"
 	verbose bool
+
+	embeddingBackend string
 )
 
 func main() {
@@ -29,13 +31,40 @@ Commands:
   index    - Index files or directories into Codex
   search   - Search the knowledge base
   migrate  - Migrate from RECALL v0 (SQLite FTS) to Codex v1
+  reembed  - Re-embed all items after changing the embedding model
   status   - Show system status and statistics
   serve    - Start MCP server and/or web UI
 
 Environment Variables:
   ANTHROPIC_API_KEY          Anthropic API key (optional, contextual enrichment)
-  LOCAL_EMBEDDING_URL        Ollama URL (default: http://localhost:11434/api/embed)
+  CODEX_EMBEDDING_BACKEND    Embedding backend: ollama (default), openai-compatible or llama.cpp
+  LOCAL_EMBEDDING_URL        Embedding endpoint (default: http://localhost:11434/api/embed for ollama)
   LOCAL_EMBEDDING_MODEL      Embedding model (default: nomic-embed-text)
+  CODEX_EMBEDDING_API_KEY    Bearer token for OpenAI-compatible servers (optional)
+  CODEX_EMBEDDING_QUERY_PREFIX / CODEX_EMBEDDING_DOCUMENT_PREFIX / CODEX_EMBEDDING_DIMENSIONS /
+  CODEX_EMBEDDING_MAX_TOKENS Override the model profile (for models without a built-in profile)
+  CODEX_EMBEDDING_MODEL_CHECK  On an embedding model change: strict (default, refuse) or warn
+  CODEX_ENRICHMENT_PROVIDER  Contextual enrichment LLM: anthropic or ollama (optional)
+  CODEX_ENRICHMENT_MODEL     Enrichment model (default: claude-haiku-4-5 / llama3.2)
+  CODEX_ENRICHMENT_URL       Enrichment endpoint override (e.g. Ollama /api/generate)
+  CODEX_VECTOR_INDEX         Vector search: hnsw (default) or exact
+  CODEX_EMBED_BATCH_SIZE     Chunks per embedding request when indexing (default: 32)
+  CODEX_EMBED_PARALLELISM    Concurrent embedding requests when indexing (default: 4)
+  CODEX_CHUNK_MAX_TOKENS     Largest code chunk in tokens, capped by the embedder (default: 512, -1: off)
+  CODEX_FEEDBACK_RANKING     Rank with recorded feedback: off (default), rrf or prior
+  CODEX_FEEDBACK_WEIGHT / CODEX_FEEDBACK_HALF_LIFE_DAYS / CODEX_FEEDBACK_PROJECT_WEIGHT
+                             Prior strength (0.2), feedback half-life (30), current-project weight (2)
+  CODEX_FUSION               Ranking fusion: rrf (default), combsum, combmnz or convex
+  CODEX_FUSION_K             RRF rank constant (default: 60)
+  CODEX_FUSION_WEIGHTS       Per-ranking weights, e.g. vector:2,keyword:1 (default: all 1)
+  CODEX_FUSION_CODE_WEIGHTS  Weights for code-like queries, e.g. vector:1,keyword:2
+  CODEX_EXPANSION_PROVIDER / CODEX_EXPANSION_MODEL / CODEX_EXPANSION_URL
+                             LLM for search --expand/--hyde (default: the enrichment LLM)
+  CODEX_EXPANSION_PARAPHRASES  Paraphrases searched per expanded query (default: 3)
+  CODEX_INDEX_INCLUDE / CODEX_INDEX_EXCLUDE
+                             Comma-separated globs selecting files for index -r (gitignore syntax)
+  CODEX_INDEX_MAX_FILE_SIZE  Largest file indexed by index -r, e.g. 512KB (default: 1MB, -1: no limit)
+  CODEX_INDEX_SYMLINKS       Symlinks in index -r: files (default), skip or follow
   CODEX_MODELS_PATH          Path to reranking models (optional)
   CODEX_METADATA_DB          SQLite metadata DB path (default: ~/.codex/metadata.db)`,
 	Version: version,
@@ -43,10 +72,12 @@ Environment Variables:
 
 func init() {
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
+	rootCmd.PersistentFlags().StringVar(&embeddingBackend, "embedding-backend", "", "embedding backend: ollama, openai-compatible or llama.cpp (overrides CODEX_EMBEDDING_BACKEND)")
 
 	rootCmd.AddCommand(indexCmd)
 	rootCmd.AddCommand(searchCmd)
 	rootCmd.AddCommand(migrateCmd)
+	rootCmd.AddCommand(reembedCmd)
 	rootCmd.AddCommand(statusCmd)
 	rootCmd.AddCommand(serveCmd)
 }
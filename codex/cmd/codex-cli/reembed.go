@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/anthropics/aef/codex/internal/core"
+)
+
+var reembedCmd = &cobra.Command{
+	Use:   "reembed",
+	Short: "Re-embed all items with the configured embedding model",
+	Long: `Re-embed every item with the configured embedding model and switch the
+index over to it.
+
+Vectors from different models cannot be compared, so Codex refuses to open
+an index embedded with another model. After changing LOCAL_EMBEDDING_MODEL
+(or the backend), run reembed to rebuild the vectors:
+
+1. Each item is embedded with the new model into a staging table; search
+   keeps using the old vectors meanwhile
+2. Once every item is staged, the old vectors are replaced in a single
+   transaction and the new model is recorded as active
+
+An interrupted run, or one where some items failed to embed, resumes where
+it left off when run again.
+
+Examples:
+  LOCAL_EMBEDDING_MODEL=mxbai-embed-large codex-cli reembed
+  codex-cli reembed --embedding-backend llama.cpp`,
+	Args: cobra.NoArgs,
+	RunE: runReembed,
+}
+
+func runReembed(cmd *cobra.Command, args []string) error {
+	cfg := LoadConfig()
+
+	// The model mismatch is what reembed resolves
+	engineCfg := cfg.ToEngineConfig()
+	engineCfg.EmbeddingModelCheck = core.ModelCheckWarn
+
+	ctx := context.Background()
+	engine, err := core.NewSearchEngine(ctx, engineCfg)
+	if err != nil {
+		return fmt.Errorf("failed to create engine: %w", err)
+	}
+	defer engine.Close()
+
+	configured, active, _, err := engine.EmbeddingModelStatus()
+	if err != nil {
+		return fmt.Errorf("failed to read embedding model: %w", err)
+	}
+	fmt.Printf("Re-embedding with %s (index currently uses %s)...\n", configured, active)
+
+	stats, err := engine.Reembed(ctx, func(done, total int) {
+		fmt.Printf("\rProgress: %d/%d items", done, total)
+	})
+	fmt.Println() // Clear progress line
+
+	if stats != nil {
+		fmt.Printf("Items:    %d\n", stats.Total)
+		fmt.Printf("Embedded: %d\n", stats.Embedded)
+		if stats.Resumed > 0 {
+			fmt.Printf("Resumed:  %d (staged by an earlier run)\n", stats.Resumed)
+		}
+		if stats.Failed > 0 {
+			fmt.Printf("Failed:   %d\n", stats.Failed)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("reembed failed: %w", err)
+	}
+
+	fmt.Printf("Switched %d vectors to %s\n", stats.Swapped, stats.Model)
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -16,6 +17,25 @@ var (
 	searchTypes  []string
 	searchScope  string
 	searchJSON   bool
+	searchExact  bool
+
+	searchFusion        string
+	searchFusionWeights string
+	searchExpand        bool
+	searchHyDE          bool
+	searchExplain       bool
+
+	searchGroup     bool
+	searchNeighbors int
+	searchSection   bool
+
+	searchTags          []string
+	searchSourcePrefix  string
+	searchGitRef        string
+	searchCreatedAfter  string
+	searchCreatedBefore string
+	searchUpdatedAfter  string
+	searchUpdatedBefore string
 )
 
 var searchCmd = &cobra.Command{
@@ -23,10 +43,26 @@ var searchCmd = &cobra.Command{
 	Short: "Search the knowledge base",
 	Long: `Search the Codex knowledge base using hybrid search (vector + BM25).
 
+Keyword matching accepts optional syntax: "quoted phrases", title:term,
+tags:term, and upper-case AND/OR between terms.
+
 Examples:
   codex-cli search "authentication patterns"
   codex-cli search "error handling" --type pattern --limit 5
-  codex-cli search "API design" --json`,
+  codex-cli search "API design" --json
+  codex-cli search 'tags:payments "retry policy"'
+  codex-cli search "retry" --tag payments --updated-after 2024-01-01
+  codex-cli search "handler" --type code --source-prefix internal/web/
+  codex-cli search "rate limiter" --git-ref release/2.3
+  codex-cli search "deploy checklist" --scope both
+  codex-cli search "why do refunds stall" --expand
+  codex-cli search "how are webhooks retried" --hyde
+  codex-cli search "parseConfig" --explain
+  codex-cli search "session timeout" --type code --group
+  codex-cli search "retry backoff" --neighbors 2
+  codex-cli search "install on macOS" --type doc --section
+  codex-cli search "parseConfig" --fusion-weights vector:1,keyword:3
+  codex-cli search "why did we drop redis" --fusion convex --fusion-weights vector:0.7,keyword:0.3`,
 	Args: cobra.ExactArgs(1),
 	RunE: runSearch,
 }
@@ -34,13 +70,75 @@ Examples:
 func init() {
 	searchCmd.Flags().IntVarP(&searchLimit, "limit", "l", 10, "maximum results")
 	searchCmd.Flags().StringSliceVarP(&searchTypes, "type", "t", nil, "filter by type (pattern, failure, decision, code, doc)")
-	searchCmd.Flags().StringVarP(&searchScope, "scope", "s", "", "filter by scope (global, project)")
+	searchCmd.Flags().StringVarP(&searchScope, "scope", "s", "", "filter by scope (global, project, both, all); project means the current repository")
 	searchCmd.Flags().BoolVar(&searchJSON, "json", false, "output as JSON")
+	searchCmd.Flags().BoolVar(&searchExact, "exact", false, "exact vector search instead of the approximate index")
+	searchCmd.Flags().BoolVar(&searchExpand, "expand", false, "also search with LLM paraphrases and identifiers from the query")
+	searchCmd.Flags().BoolVar(&searchHyDE, "hyde", false, "like --expand, plus a hypothetical answer passage")
+	searchCmd.Flags().BoolVar(&searchExplain, "explain", false, "show why each result ranked where it did")
+	searchCmd.Flags().BoolVar(&searchGroup, "group", false, "show only the best chunk of each file, with how many of its chunks matched")
+	searchCmd.Flags().IntVar(&searchNeighbors, "neighbors", 0, "join each chunk with this many chunks before and after it")
+	searchCmd.Flags().BoolVar(&searchSection, "section", false, "join each doc chunk with the rest of its heading section")
+	searchCmd.Flags().StringVar(&searchFusion, "fusion", "", "fusion strategy (rrf, combsum, combmnz, convex); default CODEX_FUSION or rrf")
+	searchCmd.Flags().StringVar(&searchFusionWeights, "fusion-weights", "", "per-ranking weights, e.g. vector:1,keyword:2; 0 leaves a ranking out")
+	searchCmd.Flags().StringSliceVar(&searchTags, "tag", nil, "only items carrying all of these tags")
+	searchCmd.Flags().StringVar(&searchSourcePrefix, "source-prefix", "", "only items whose source starts with this prefix")
+	searchCmd.Flags().StringVar(&searchGitRef, "git-ref", "", "only code as of this ref, as indexed by index --git --ref")
+	searchCmd.Flags().StringVar(&searchCreatedAfter, "created-after", "", "only items created on or after this date (YYYY-MM-DD or RFC 3339)")
+	searchCmd.Flags().StringVar(&searchCreatedBefore, "created-before", "", "only items created before this date")
+	searchCmd.Flags().StringVar(&searchUpdatedAfter, "updated-after", "", "only items updated on or after this date")
+	searchCmd.Flags().StringVar(&searchUpdatedBefore, "updated-before", "", "only items updated before this date")
 }
 
 func runSearch(cmd *cobra.Command, args []string) error {
 	query := args[0]
 
+	req := core.SearchRequest{
+		Query:        query,
+		Types:        searchTypes,
+		Scope:        searchScope,
+		Limit:        searchLimit,
+		Tags:         searchTags,
+		SourcePrefix: searchSourcePrefix,
+		GitRef:       searchGitRef,
+		Exact:        searchExact,
+		Fusion:       searchFusion,
+		Expand:       searchExpand,
+		HyDE:         searchHyDE,
+		Explain:      searchExplain,
+
+		GroupByParent: searchGroup,
+		Neighbors:     searchNeighbors,
+		ExpandSection: searchSection,
+	}
+	if searchFusionWeights != "" {
+		weights, err := core.ParseFusionWeights(searchFusionWeights)
+		if err != nil {
+			return fmt.Errorf("--fusion-weights: %w", err)
+		}
+		req.FusionWeights = weights
+	}
+	dates := []struct {
+		flag  string
+		value string
+		dst   *time.Time
+	}{
+		{"--created-after", searchCreatedAfter, &req.CreatedAfter},
+		{"--created-before", searchCreatedBefore, &req.CreatedBefore},
+		{"--updated-after", searchUpdatedAfter, &req.UpdatedAfter},
+		{"--updated-before", searchUpdatedBefore, &req.UpdatedBefore},
+	}
+	for _, d := range dates {
+		if d.value == "" {
+			continue
+		}
+		t, err := core.ParseFilterTime(d.value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", d.flag, err)
+		}
+		*d.dst = t
+	}
+
 	cfg := LoadConfig()
 	ctx := context.Background()
 
@@ -50,12 +148,7 @@ func runSearch(cmd *cobra.Command, args []string) error {
 	}
 	defer engine.Close()
 
-	results, err := engine.Search(ctx, core.SearchRequest{
-		Query: query,
-		Types: searchTypes,
-		Scope: searchScope,
-		Limit: searchLimit,
-	})
+	results, err := engine.Search(ctx, req)
 	if err != nil {
 		return fmt.Errorf("search failed: %w", err)
 	}
@@ -110,21 +203,36 @@ func outputHuman(query string, results []core.SearchResult) error {
 		if len(r.Tags) > 0 {
 			meta = append(meta, "tags: "+strings.Join(r.Tags, ", "))
 		}
+		if f := r.Feedback; f != nil {
+			meta = append(meta, fmt.Sprintf("feedback: %d useful, %d not", f.Useful, f.NotUseful))
+		}
+		if r.ParentHits > 1 {
+			meta = append(meta, fmt.Sprintf("%d matching chunks in file", r.ParentHits))
+		}
 		if len(meta) > 0 {
 			fmt.Printf("   %s\n", strings.Join(meta, " | "))
 		}
+		if r.Explanation != nil {
+			fmt.Printf("   why: %s\n", formatExplanation(r.Explanation))
+		}
 
-		// Content preview (first 200 chars)
-		preview := r.Content
-		if len(preview) > 200 {
-			preview = preview[:200] + "..."
+		// Expanded results are shown whole, others as a preview (first 200 chars)
+		if len(r.Expanded) > 0 {
+			fmt.Printf("   %s\n", strings.ReplaceAll(r.Content, "\n", "\n   "))
+		} else {
+			preview := r.Content
+			if len(preview) > 200 {
+				preview = preview[:200] + "..."
+			}
+			preview = strings.ReplaceAll(preview, "\n", " ")
+			fmt.Printf("   %s\n", preview)
 		}
-		preview = strings.ReplaceAll(preview, "\n", " ")
-		fmt.Printf("   %s\n", preview)
 
-		// Source if available
-		if r.Source != "" {
-			fmt.Printf("   Source: %s\n", r.Source)
+		// Source if available, with the lines or page the result comes from
+		if r.Citation != nil {
+			fmt.Printf("   Source: %s%s\n", r.Citation, formatProvenance(r.Metadata))
+		} else if r.Source != "" {
+			fmt.Printf("   Source: %s%s\n", r.Source, formatProvenance(r.Metadata))
 		}
 
 		fmt.Println()
@@ -132,3 +240,56 @@ func outputHuman(query string, results []core.SearchResult) error {
 
 	return nil
 }
+
+// formatExplanation summarizes how a result was scored on one line, e.g.
+// "vector #1 (0.91) + keyword #2 (3.20) -> rrf 0.0325 | rerank 0.50 | 98% of top".
+func formatExplanation(x *core.SearchExplanation) string {
+	var signals []string
+	for _, s := range x.Signals {
+		sig := fmt.Sprintf("%s #%d (%.2f)", s.List, s.Rank, s.Score)
+		if s.Weight != 1 {
+			sig += fmt.Sprintf(" x%g", s.Weight)
+		}
+		signals = append(signals, sig)
+	}
+	parts := []string{fmt.Sprintf("%s -> %s %.4f", strings.Join(signals, " + "), x.Fusion, x.FusedScore)}
+	if x.RerankScore != nil {
+		parts = append(parts, fmt.Sprintf("rerank %.2f", *x.RerankScore))
+	}
+	if x.ProjectBoost != 0 {
+		parts = append(parts, fmt.Sprintf("project x%.2f", x.ProjectBoost))
+	}
+	if x.FeedbackBoost != 0 {
+		parts = append(parts, fmt.Sprintf("feedback x%.2f", x.FeedbackBoost))
+	}
+	top := fmt.Sprintf("%.0f%% of top", x.TopRatio*100)
+	if x.NearThreshold {
+		top += fmt.Sprintf(", near the %.0f%% threshold", x.Threshold*100)
+	}
+	return strings.Join(append(parts, top), " | ")
+}
+
+// formatProvenance describes where an indexed file came from in git, or
+// returns "" for items without git metadata.
+func formatProvenance(meta map[string]any) string {
+	var parts []string
+	if ref, ok := meta["git_ref"].(string); ok {
+		parts = append(parts, "@ "+ref)
+	}
+	if sha, ok := meta["git_commit"].(string); ok {
+		parts = append(parts, shortSHA(sha))
+	}
+	if uncommitted, _ := meta["git_uncommitted"].(bool); uncommitted {
+		parts = append(parts, "uncommitted")
+	}
+	if author, ok := meta["git_author"].(string); ok {
+		parts = append(parts, author)
+	}
+	if modified, ok := meta["git_modified"].(string); ok {
+		parts = append(parts, modified)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
+}
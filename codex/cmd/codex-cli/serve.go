@@ -4,6 +4,10 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
 
 	"github.com/spf13/cobra"
 
@@ -12,9 +16,10 @@ import (
 )
 
 var (
-	serveAddr string
-	serveMCP  bool
-	serveWeb  bool
+	serveAddr  string
+	serveMCP   bool
+	serveWeb   bool
+	serveWatch []string
 )
 
 var serveCmd = &cobra.Command{
@@ -22,10 +27,16 @@ var serveCmd = &cobra.Command{
 	Short: "Start MCP server and/or web UI",
 	Long: `Start the Codex servers.
 
+--watch keeps a directory indexed while serving, as with index --watch. It
+may be given alone to run only the watcher.
+
 Examples:
   codex-cli serve --web --addr :8080
   codex-cli serve --mcp
-  codex-cli serve --web --mcp`,
+  codex-cli serve --web --mcp
+  codex-cli serve --web --watch ./src --watch ./docs
+  codex-cli serve --watch .
+  codex-cli serve --watch ~/notes --scope global`,
 	RunE: runServe,
 }
 
@@ -33,15 +44,19 @@ func init() {
 	serveCmd.Flags().StringVar(&serveAddr, "addr", ":8080", "web server address")
 	serveCmd.Flags().BoolVar(&serveMCP, "mcp", false, "start MCP server (stdio)")
 	serveCmd.Flags().BoolVar(&serveWeb, "web", false, "start web UI server")
+	serveCmd.Flags().StringArrayVar(&serveWatch, "watch", nil, "keep this directory indexed as files change (repeatable)")
+	serveCmd.Flags().DurationVar(&indexDebounce, "debounce", 0, "with --watch, wait this long after the last change before indexing (default 500ms)")
+	serveCmd.Flags().StringVarP(&indexScope, "scope", "s", "project", "with --watch, scope of the indexed files (global or project)")
 }
 
 func runServe(cmd *cobra.Command, args []string) error {
-	if !serveMCP && !serveWeb {
-		return fmt.Errorf("specify --mcp and/or --web")
+	if !serveMCP && !serveWeb && len(serveWatch) == 0 {
+		return fmt.Errorf("specify --mcp, --web and/or --watch")
 	}
 
 	cfg := LoadConfig()
-	ctx := context.Background()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	engine, err := core.NewSearchEngine(ctx, cfg.ToEngineConfig())
 	if err != nil {
@@ -58,6 +73,22 @@ func runServe(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("MCP server available via: go run ./cmd/recall-mcp")
 	}
 
+	errs := make(chan error, len(serveWatch)+1)
+	var watchers sync.WaitGroup
+	for _, dir := range serveWatch {
+		absDir, err := filepath.Abs(dir)
+		if err != nil {
+			return fmt.Errorf("invalid path: %w", err)
+		}
+		watchers.Add(1)
+		go func() {
+			defer watchers.Done()
+			if err := watchDirectory(ctx, engine, absDir, indexScope, false); err != nil {
+				errs <- err
+			}
+		}()
+	}
+
 	if serveWeb {
 		fmt.Printf("Starting web server at http://localhost%s\n", serveAddr)
 		var serverOpts []web.ServerOption
@@ -66,8 +97,19 @@ func runServe(cmd *cobra.Command, args []string) error {
 			fmt.Println("API key authentication enabled")
 		}
 		server := web.NewServer(engine, serverOpts...)
-		return server.Run(serveAddr)
+		go func() {
+			errs <- server.Run(serveAddr)
+		}()
 	}
 
-	return nil
+	// Stop on Ctrl-C or the first failure, letting watchers finish their batch
+	select {
+	case <-ctx.Done():
+		watchers.Wait()
+		return nil
+	case err := <-errs:
+		stop()
+		watchers.Wait()
+		return err
+	}
 }
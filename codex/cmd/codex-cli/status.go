@@ -17,6 +17,8 @@ var statusCmd = &cobra.Command{
 - SQLite storage status
 - Item counts by type
 - Configuration summary
+- Embedding model of the stored vectors
+- Whether cross-encoder reranking is active
 - API key status`,
 	RunE: runStatus,
 }
@@ -33,8 +35,13 @@ func runStatus(cmd *cobra.Command, args []string) error {
 
 	// Embedding Config
 	fmt.Println("\nEmbedding:")
+	fmt.Printf("  Backend:   %s\n", valueOrDefault(cfg.EmbeddingBackend, "ollama"))
 	fmt.Printf("  Model:     %s\n", valueOrDefault(cfg.LocalEmbeddingModel, "nomic-embed-text"))
+	if cfg.LocalEmbeddingURL != "" {
+		fmt.Printf("  URL:       %s\n", cfg.LocalEmbeddingURL)
+	}
 	fmt.Printf("  Anthropic: %s\n", keyStatus(cfg.AnthropicAPIKey))
+	fmt.Printf("  Enrich:    %s\n", enrichmentStatus(cfg))
 
 	// Models
 	fmt.Println("\nReranking:")
@@ -47,8 +54,12 @@ func runStatus(cmd *cobra.Command, args []string) error {
 	// Try to connect and get stats
 	fmt.Println("\nConnecting to storage...")
 
+	// Open even on a model mismatch so the mismatch can be reported
+	engineCfg := cfg.ToEngineConfig()
+	engineCfg.EmbeddingModelCheck = core.ModelCheckWarn
+
 	ctx := context.Background()
-	engine, err := core.NewSearchEngine(ctx, cfg.ToEngineConfig())
+	engine, err := core.NewSearchEngine(ctx, engineCfg)
 	if err != nil {
 		fmt.Printf("  Status:    FAILED (%s)\n", err)
 		return nil // Don't fail command, just report status
@@ -57,6 +68,29 @@ func runStatus(cmd *cobra.Command, args []string) error {
 
 	fmt.Println("  Status:    CONNECTED")
 
+	if configured, active, counts, err := engine.EmbeddingModelStatus(); err != nil {
+		fmt.Printf("\nVectors:     error (%s)\n", err)
+	} else {
+		fmt.Printf("\nVectors:     %s\n", active)
+		for model, n := range counts {
+			if model == "" {
+				model = "untagged"
+			}
+			fmt.Printf("  %-24s %d\n", model+":", n)
+		}
+		if active != configured {
+			fmt.Printf("  MISMATCH: configured model is %s; run `codex-cli reembed`\n", configured)
+		}
+	}
+
+	fmt.Printf("\nVector index: %s\n", engine.VectorIndexStatus())
+
+	if active, detail := engine.RerankerStatus(); active {
+		fmt.Printf("\nReranking:   ACTIVE (%s)\n", detail)
+	} else {
+		fmt.Printf("\nReranking:   OFF, using fusion scores (%s)\n", detail)
+	}
+
 	// Get item stats
 	stats, err := engine.Stats(ctx)
 	if err != nil {
@@ -86,6 +120,22 @@ func valueOrDefault(val, def string) string {
 	return val
 }
 
+// enrichmentStatus describes which LLM, if any, enriches document chunks.
+func enrichmentStatus(cfg *Config) string {
+	provider := cfg.EnrichmentProvider
+	if provider == "" {
+		if cfg.AnthropicAPIKey == "" {
+			return "off"
+		}
+		provider = "anthropic"
+	}
+	model := cfg.EnrichmentModel
+	if model == "" {
+		model = "default model"
+	}
+	return fmt.Sprintf("%s (%s)", provider, model)
+}
+
 func keyStatus(key string) string {
 	if key == "" {
 		return "not set"
@@ -8,6 +8,7 @@ import (
 	"syscall"
 
 	"github.com/anthropics/aef/codex/internal/core"
+	"github.com/anthropics/aef/codex/internal/env"
 	"github.com/anthropics/aef/codex/internal/web"
 )
 
@@ -33,10 +34,34 @@ func main() {
 	// Initialize search engine
 	engine, err := core.NewSearchEngine(ctx, core.Config{
 		AnthropicAPIKey:        os.Getenv("ANTHROPIC_API_KEY"),
-		ModelsPath:             getEnv("CODEX_MODELS_PATH", "./models"),
-		MetadataDBPath:         getEnv("CODEX_METADATA_DB", "~/.edi/codex.db"),
+		ModelsPath:             env.Get("CODEX_MODELS_PATH", "./models"),
+		MetadataDBPath:         env.Get("CODEX_METADATA_DB", "~/.edi/codex.db"),
+		EmbeddingBackend:    os.Getenv("CODEX_EMBEDDING_BACKEND"),
 		LocalEmbeddingURL:   os.Getenv("LOCAL_EMBEDDING_URL"),
 		LocalEmbeddingModel: os.Getenv("LOCAL_EMBEDDING_MODEL"),
+		EmbeddingAPIKey:     os.Getenv("CODEX_EMBEDDING_API_KEY"),
+		EmbeddingQueryPrefix:    os.Getenv("CODEX_EMBEDDING_QUERY_PREFIX"),
+		EmbeddingDocumentPrefix: os.Getenv("CODEX_EMBEDDING_DOCUMENT_PREFIX"),
+		EmbeddingDimensions:     env.Int("CODEX_EMBEDDING_DIMENSIONS", 0),
+		EmbeddingMaxTokens:      env.Int("CODEX_EMBEDDING_MAX_TOKENS", 0),
+		ChunkMaxTokens:          env.Int("CODEX_CHUNK_MAX_TOKENS", 0),
+		EmbeddingModelCheck:     os.Getenv("CODEX_EMBEDDING_MODEL_CHECK"),
+		EnrichmentProvider:  os.Getenv("CODEX_ENRICHMENT_PROVIDER"),
+		EnrichmentModel:     os.Getenv("CODEX_ENRICHMENT_MODEL"),
+		EnrichmentURL:       os.Getenv("CODEX_ENRICHMENT_URL"),
+		VectorIndex:         os.Getenv("CODEX_VECTOR_INDEX"),
+		FeedbackRanking:       os.Getenv("CODEX_FEEDBACK_RANKING"),
+		FeedbackWeight:        env.Float("CODEX_FEEDBACK_WEIGHT", 0),
+		FeedbackHalfLife:      env.Days("CODEX_FEEDBACK_HALF_LIFE_DAYS"),
+		FeedbackProjectWeight: env.Float("CODEX_FEEDBACK_PROJECT_WEIGHT", 0),
+		Fusion:                os.Getenv("CODEX_FUSION"),
+		FusionK:               env.Float("CODEX_FUSION_K", 0),
+		FusionWeights:         env.Weights("CODEX_FUSION_WEIGHTS"),
+		CodeFusionWeights:     env.Weights("CODEX_FUSION_CODE_WEIGHTS"),
+		ExpansionProvider:     os.Getenv("CODEX_EXPANSION_PROVIDER"),
+		ExpansionModel:        os.Getenv("CODEX_EXPANSION_MODEL"),
+		ExpansionURL:          os.Getenv("CODEX_EXPANSION_URL"),
+		ExpansionParaphrases:  env.Int("CODEX_EXPANSION_PARAPHRASES", 0),
 	})
 	if err != nil {
 		log.Fatalf("Failed to initialize search engine: %v", err)
@@ -44,7 +69,7 @@ func main() {
 	defer engine.Close()
 
 	// Create and run web server
-	addr := getEnv("CODEX_WEB_ADDR", ":8080")
+	addr := env.Get("CODEX_WEB_ADDR", ":8080")
 	var serverOpts []web.ServerOption
 	if apiKey := os.Getenv("CODEX_API_KEY"); apiKey != "" {
 		serverOpts = append(serverOpts, web.WithAPIKey(apiKey))
@@ -57,10 +82,3 @@ func main() {
 		log.Fatalf("Web server error: %v", err)
 	}
 }
-
-func getEnv(key, defaultVal string) string {
-	if val := os.Getenv(key); val != "" {
-		return val
-	}
-	return defaultVal
-}
@@ -0,0 +1,93 @@
+package eval
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/anthropics/aef/codex/internal/core"
+)
+
+// FusionSetting is one fusion configuration to evaluate.
+type FusionSetting struct {
+	Name    string             // pipeline name in the report
+	Fusion  string             // rrf, combsum, combmnz or convex
+	Weights core.FusionWeights // nil uses the engine's configured weights
+}
+
+// args returns the recall_search arguments selecting this setting.
+func (s FusionSetting) args() map[string]interface{} {
+	args := map[string]interface{}{}
+	if s.Fusion != "" {
+		args["fusion"] = s.Fusion
+	}
+	if s.Weights != nil {
+		weights := make(map[string]interface{}, len(s.Weights))
+		for name, w := range s.Weights {
+			weights[name] = w
+		}
+		args["fusion_weights"] = weights
+	}
+	return args
+}
+
+// DefaultFusionSweep compares single retrievers, each fusion strategy with
+// equal weights, and keyword- and vector-heavy RRF.
+func DefaultFusionSweep() []FusionSetting {
+	return []FusionSetting{
+		{Name: "vector-only", Weights: core.FusionWeights{core.ListKeyword: 0}},
+		{Name: "fts5-only", Weights: core.FusionWeights{core.ListVector: 0}},
+		{Name: "rrf", Fusion: core.FusionRRF, Weights: core.FusionWeights{}},
+		{Name: "rrf-kw2", Fusion: core.FusionRRF, Weights: core.FusionWeights{core.ListKeyword: 2}},
+		{Name: "rrf-vec2", Fusion: core.FusionRRF, Weights: core.FusionWeights{core.ListVector: 2}},
+		{Name: "combsum", Fusion: core.FusionCombSUM, Weights: core.FusionWeights{}},
+		{Name: "combmnz", Fusion: core.FusionCombMNZ, Weights: core.FusionWeights{}},
+		{Name: "convex-0.7", Fusion: core.FusionConvex, Weights: core.FusionWeights{core.ListVector: 0.7, core.ListKeyword: 0.3}},
+	}
+}
+
+// FusionGrid returns one setting per strategy and keyword weight, with the
+// vector weight fixed at 1.
+func FusionGrid(strategies []string, keywordWeights []float64) []FusionSetting {
+	var settings []FusionSetting
+	for _, f := range strategies {
+		for _, kw := range keywordWeights {
+			settings = append(settings, FusionSetting{
+				Name:    f + "-kw" + strconv.FormatFloat(kw, 'g', -1, 64),
+				Fusion:  f,
+				Weights: core.FusionWeights{core.ListVector: 1, core.ListKeyword: kw},
+			})
+		}
+	}
+	return settings
+}
+
+// SweepFusion runs retrieval once per setting over the indexed collection.
+// Each summary is named after its setting; compare ByCategory to see which
+// weighting suits keyword-style and semantic queries.
+func (h *EvalHarness) SweepFusion(ctx context.Context, settings []FusionSetting) ([]EvalSummary, error) {
+	summaries := make([]EvalSummary, 0, len(settings))
+	for _, s := range settings {
+		summary, err := h.runRetrieval(ctx, s.Name, s.args())
+		if err != nil {
+			return summaries, fmt.Errorf("fusion %s: %w", s.Name, err)
+		}
+		summaries = append(summaries, *summary)
+	}
+	return summaries, nil
+}
+
+// BestSummary returns the summary with the highest value of metric, e.g.
+// func(s EvalSummary) float64 { return s.NDCGAt10 }.
+func BestSummary(summaries []EvalSummary, metric func(EvalSummary) float64) (EvalSummary, bool) {
+	if len(summaries) == 0 {
+		return EvalSummary{}, false
+	}
+	best := summaries[0]
+	for _, s := range summaries[1:] {
+		if metric(s) > metric(best) {
+			best = s
+		}
+	}
+	return best, true
+}
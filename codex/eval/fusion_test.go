@@ -0,0 +1,42 @@
+//go:build fts5
+
+package eval_test
+
+import (
+	"testing"
+
+	"github.com/anthropics/aef/codex/eval"
+)
+
+func TestFusionGrid(t *testing.T) {
+	settings := eval.FusionGrid([]string{"rrf", "combsum"}, []float64{0.5, 2})
+
+	if len(settings) != 4 {
+		t.Fatalf("expected 4 settings, got %d", len(settings))
+	}
+	if settings[0].Name != "rrf-kw0.5" || settings[3].Name != "combsum-kw2" {
+		t.Errorf("unexpected names %q, %q", settings[0].Name, settings[3].Name)
+	}
+	if w := settings[3].Weights; w.Weight("vector") != 1 || w.Weight("keyword") != 2 {
+		t.Errorf("unexpected weights %v", w)
+	}
+}
+
+func TestBestSummary(t *testing.T) {
+	summaries := []eval.EvalSummary{
+		{Pipeline: "rrf", NDCGAt10: 0.6, MRRScore: 0.8},
+		{Pipeline: "combsum", NDCGAt10: 0.7, MRRScore: 0.5},
+	}
+
+	best, ok := eval.BestSummary(summaries, func(s eval.EvalSummary) float64 { return s.NDCGAt10 })
+	if !ok || best.Pipeline != "combsum" {
+		t.Errorf("best by nDCG = %q, want combsum", best.Pipeline)
+	}
+	best, _ = eval.BestSummary(summaries, func(s eval.EvalSummary) float64 { return s.MRRScore })
+	if best.Pipeline != "rrf" {
+		t.Errorf("best by MRR = %q, want rrf", best.Pipeline)
+	}
+	if _, ok := eval.BestSummary(nil, func(s eval.EvalSummary) float64 { return 0 }); ok {
+		t.Error("expected no best summary for an empty sweep")
+	}
+}
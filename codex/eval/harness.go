@@ -152,8 +152,14 @@ func (h *EvalHarness) VerifyIndexed(ctx context.Context) (int, error) {
 
 // RunRetrieval runs all queries and computes retrieval quality metrics.
 func (h *EvalHarness) RunRetrieval(ctx context.Context) (*EvalSummary, error) {
+	return h.runRetrieval(ctx, "hybrid", nil)
+}
+
+// runRetrieval runs all queries, passing extra arguments to recall_search,
+// and reports the metrics under the given pipeline name.
+func (h *EvalHarness) runRetrieval(ctx context.Context, pipeline string, extra map[string]interface{}) (*EvalSummary, error) {
 	summary := &EvalSummary{
-		Pipeline:     "hybrid",
+		Pipeline:     pipeline,
 		ByCategory:   make(map[string]float64),
 		QueryResults: make([]QueryResult, 0, len(h.collection.Queries)),
 	}
@@ -164,7 +170,7 @@ func (h *EvalHarness) RunRetrieval(ctx context.Context) (*EvalSummary, error) {
 	var totalRecall5, totalRecall10, totalPrec5, totalNDCG10, totalMRR float64
 
 	for _, q := range h.collection.Queries {
-		results, err := h.client.RecallSearch(ctx, q.Query, 10)
+		results, err := h.client.RecallSearchWith(ctx, q.Query, 10, extra)
 		if err != nil {
 			return nil, fmt.Errorf("search %s: %w", q.ID, err)
 		}
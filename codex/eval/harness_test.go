@@ -89,6 +89,18 @@ func TestE2E(t *testing.T) {
 		}
 	})
 
+	t.Run("FusionSweep", func(t *testing.T) {
+		summaries, err := h.SweepFusion(ctx, eval.DefaultFusionSweep())
+		if err != nil {
+			t.Fatalf("SweepFusion: %v", err)
+		}
+		for _, s := range summaries {
+			t.Logf("%-12s nDCG@10=%.3f MRR=%.3f by category=%v", s.Pipeline, s.NDCGAt10, s.MRRScore, s.ByCategory)
+		}
+		best, _ := eval.BestSummary(summaries, func(s eval.EvalSummary) float64 { return s.NDCGAt10 })
+		t.Logf("Best nDCG@10: %s", best.Pipeline)
+	})
+
 	t.Run("FeedbackLoop", func(t *testing.T) {
 		if err := h.TestFeedback(ctx); err != nil {
 			t.Fatalf("TestFeedback: %v", err)
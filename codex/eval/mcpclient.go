@@ -237,10 +237,19 @@ func (c *MCPClient) RecallAdd(ctx context.Context, doc TestDocument) (string, er
 
 // RecallSearch searches through the MCP protocol.
 func (c *MCPClient) RecallSearch(ctx context.Context, query string, limit int) ([]SearchResultFromMCP, error) {
+	return c.RecallSearchWith(ctx, query, limit, nil)
+}
+
+// RecallSearchWith searches with extra recall_search arguments, such as
+// fusion settings.
+func (c *MCPClient) RecallSearchWith(ctx context.Context, query string, limit int, extra map[string]interface{}) ([]SearchResultFromMCP, error) {
 	args := map[string]interface{}{
 		"query": query,
 		"limit": limit,
 	}
+	for k, v := range extra {
+		args[k] = v
+	}
 
 	result, err := c.CallTool(ctx, "recall_search", args)
 	if err != nil {
@@ -2,63 +2,229 @@ package chunking
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/c"
+	"github.com/smacker/go-tree-sitter/cpp"
+	"github.com/smacker/go-tree-sitter/csharp"
 	"github.com/smacker/go-tree-sitter/golang"
+	"github.com/smacker/go-tree-sitter/java"
+	"github.com/smacker/go-tree-sitter/javascript"
 	"github.com/smacker/go-tree-sitter/python"
+	"github.com/smacker/go-tree-sitter/ruby"
+	"github.com/smacker/go-tree-sitter/rust"
+	"github.com/smacker/go-tree-sitter/typescript/tsx"
 	"github.com/smacker/go-tree-sitter/typescript/typescript"
 )
 
+// nodeKind describes a syntax node type that becomes a chunk.
+type nodeKind struct {
+	chunkType string
+	// needsBody skips nodes without a body field, such as the struct
+	// specifier in a C declaration like `struct point *p;`.
+	needsBody bool
+}
+
+// languageSpec drives chunk extraction for one Tree-sitter grammar: which
+// node types become chunks, and which carry the structural metadata
+// recorded on them (see structure.go).
+type languageSpec struct {
+	grammar func() *sitter.Language
+	kinds   map[string]nodeKind
+
+	// methodScopes are chunk node types that make a function inside them
+	// a method.
+	methodScopes map[string]bool
+	// scopes are node types that are not chunks but still qualify the
+	// symbols inside them, such as C++ namespaces.
+	scopes map[string]bool
+	// packages are top-level node types naming the file's package.
+	packages map[string]bool
+	// imports are node types recorded as the file's imports, one each.
+	imports map[string]bool
+	// calls maps call node types to the field holding the callee.
+	calls map[string]string
+	// docstrings reads a leading string in a body as documentation.
+	docstrings bool
+}
+
+var (
+	typescriptKinds = map[string]nodeKind{
+		"function_declaration":  {chunkType: "function"},
+		"function":              {chunkType: "function"}, // function expressions
+		"function_expression":   {chunkType: "function"}, // in newer grammars
+		"method_definition":     {chunkType: "method"},
+		"class_declaration":     {chunkType: "class"},
+		"interface_declaration": {chunkType: "type"},
+	}
+	javascriptKinds = map[string]nodeKind{
+		"function_declaration":           {chunkType: "function"},
+		"generator_function_declaration": {chunkType: "function"},
+		"method_definition":              {chunkType: "method"},
+		"class_declaration":              {chunkType: "class"},
+	}
+	jsImports = map[string]bool{"import_statement": true}
+	jsCalls   = map[string]string{"call_expression": "function"}
+
+	cppKinds = map[string]nodeKind{
+		"function_definition": {chunkType: "function"},
+		"class_specifier":     {chunkType: "class", needsBody: true},
+		"struct_specifier":    {chunkType: "struct", needsBody: true},
+		"enum_specifier":      {chunkType: "enum", needsBody: true},
+	}
+	cppMethodScopes = map[string]bool{"class_specifier": true, "struct_specifier": true}
+	cImports        = map[string]bool{"preproc_include": true}
+	cCalls          = map[string]string{"call_expression": "function"}
+)
+
+// languageSpecs maps DetectLanguage names to their grammars and node tables.
+var languageSpecs = map[string]languageSpec{
+	"go": {
+		grammar: golang.GetLanguage,
+		kinds: map[string]nodeKind{
+			"function_declaration": {chunkType: "function"},
+			"method_declaration":   {chunkType: "method"},
+			"type_declaration":     {chunkType: "type"},
+		},
+		packages: map[string]bool{"package_clause": true},
+		imports:  map[string]bool{"import_spec": true},
+		calls:    map[string]string{"call_expression": "function"},
+	},
+	"python": {
+		grammar: python.GetLanguage,
+		kinds: map[string]nodeKind{
+			"function_definition": {chunkType: "function"},
+			"class_definition":    {chunkType: "class"},
+		},
+		methodScopes: map[string]bool{"class_definition": true},
+		imports:      map[string]bool{"import_statement": true, "import_from_statement": true},
+		calls:        map[string]string{"call": "function"},
+		docstrings:   true,
+	},
+	"typescript": {grammar: typescript.GetLanguage, kinds: typescriptKinds, imports: jsImports, calls: jsCalls},
+	"tsx":        {grammar: tsx.GetLanguage, kinds: typescriptKinds, imports: jsImports, calls: jsCalls},
+	"javascript": {grammar: javascript.GetLanguage, kinds: javascriptKinds, imports: jsImports, calls: jsCalls},
+	"jsx":        {grammar: javascript.GetLanguage, kinds: javascriptKinds, imports: jsImports, calls: jsCalls},
+	"rust": {
+		grammar: rust.GetLanguage,
+		kinds: map[string]nodeKind{
+			"function_item": {chunkType: "function"},
+			"impl_item":     {chunkType: "impl"},
+			"trait_item":    {chunkType: "interface"},
+			"struct_item":   {chunkType: "struct"},
+			"enum_item":     {chunkType: "enum"},
+		},
+		methodScopes: map[string]bool{"impl_item": true, "trait_item": true},
+		scopes:       map[string]bool{"mod_item": true},
+		imports:      map[string]bool{"use_declaration": true},
+		calls:        map[string]string{"call_expression": "function"},
+	},
+	"java": {
+		grammar: java.GetLanguage,
+		kinds: map[string]nodeKind{
+			"method_declaration":      {chunkType: "method"},
+			"constructor_declaration": {chunkType: "method"},
+			"class_declaration":       {chunkType: "class"},
+			"record_declaration":      {chunkType: "class"},
+			"interface_declaration":   {chunkType: "interface"},
+			"enum_declaration":        {chunkType: "enum"},
+		},
+		packages: map[string]bool{"package_declaration": true},
+		imports:  map[string]bool{"import_declaration": true},
+		calls:    map[string]string{"method_invocation": "name"},
+	},
+	"c": {
+		grammar: c.GetLanguage,
+		kinds: map[string]nodeKind{
+			"function_definition": {chunkType: "function"},
+			"struct_specifier":    {chunkType: "struct", needsBody: true},
+			"enum_specifier":      {chunkType: "enum", needsBody: true},
+		},
+		imports: cImports,
+		calls:   cCalls,
+	},
+	"cpp": {
+		grammar:      cpp.GetLanguage,
+		kinds:        cppKinds,
+		methodScopes: cppMethodScopes,
+		scopes:       map[string]bool{"namespace_definition": true},
+		imports:      cImports,
+		calls:        cCalls,
+	},
+	"csharp": {
+		grammar: csharp.GetLanguage,
+		kinds: map[string]nodeKind{
+			"method_declaration":      {chunkType: "method"},
+			"constructor_declaration": {chunkType: "method"},
+			"class_declaration":       {chunkType: "class"},
+			"record_declaration":      {chunkType: "class"},
+			"struct_declaration":      {chunkType: "struct"},
+			"interface_declaration":   {chunkType: "interface"},
+			"enum_declaration":        {chunkType: "enum"},
+		},
+		scopes:   map[string]bool{"namespace_declaration": true},
+		packages: map[string]bool{"file_scoped_namespace_declaration": true},
+		imports:  map[string]bool{"using_directive": true},
+		calls:    map[string]string{"invocation_expression": "function"},
+	},
+	"ruby": {
+		grammar: ruby.GetLanguage,
+		kinds: map[string]nodeKind{
+			"method":           {chunkType: "method"},
+			"singleton_method": {chunkType: "method"},
+			"class":            {chunkType: "class"},
+			"module":           {chunkType: "module"},
+		},
+		calls: map[string]string{"call": "method"},
+	},
+}
+
 // ASTChunker extracts semantic code chunks using Tree-sitter
 type ASTChunker struct {
-	goParser *sitter.Parser
-	pyParser *sitter.Parser
-	tsParser *sitter.Parser
 	available bool
+	size      SizeOptions
+}
+
+// ASTOption configures an ASTChunker.
+type ASTOption func(*ASTChunker)
+
+// WithSizeOptions bounds chunk sizes: oversized declarations are split and
+// tiny adjacent siblings merged. Without it chunks are whole declarations.
+func WithSizeOptions(opts SizeOptions) ASTOption {
+	return func(c *ASTChunker) { c.size = opts }
 }
 
 // NewASTChunker creates a new AST-based chunker
-func NewASTChunker() *ASTChunker {
+func NewASTChunker(opts ...ASTOption) *ASTChunker {
 	chunker := &ASTChunker{
 		available: true,
 	}
-
-	// Initialize Go parser
-	chunker.goParser = sitter.NewParser()
-	chunker.goParser.SetLanguage(golang.GetLanguage())
-
-	// Initialize Python parser
-	chunker.pyParser = sitter.NewParser()
-	chunker.pyParser.SetLanguage(python.GetLanguage())
-
-	// Initialize TypeScript parser
-	chunker.tsParser = sitter.NewParser()
-	chunker.tsParser.SetLanguage(typescript.GetLanguage())
-
+	for _, opt := range opts {
+		opt(chunker)
+	}
 	return chunker
 }
 
-// ChunkFile extracts semantic chunks from a source file
+// ChunkFile extracts semantic chunks from a source file. It is safe for
+// concurrent use: each call parses with its own parser.
 func (c *ASTChunker) ChunkFile(content []byte, lang, filePath string) ([]CodeChunk, error) {
 	if !c.available {
 		return c.fallbackChunk(content, filePath, lang), nil
 	}
 
-	// Parse based on language
-	var tree *sitter.Tree
-	switch lang {
-	case "go":
-		tree = c.goParser.Parse(nil, content)
-	case "python":
-		tree = c.pyParser.Parse(nil, content)
-	case "typescript", "tsx", "javascript", "jsx":
-		tree = c.tsParser.Parse(nil, content)
-	default:
+	spec, ok := languageSpecs[lang]
+	if !ok {
 		// Unsupported language - fall back
 		return c.fallbackChunk(content, filePath, lang), nil
 	}
 
+	parser := sitter.NewParser()
+	defer parser.Close()
+	parser.SetLanguage(spec.grammar())
+
+	tree := parser.Parse(nil, content)
 	if tree == nil {
 		// Parsing failed - fall back
 		return c.fallbackChunk(content, filePath, lang), nil
@@ -70,238 +236,134 @@ func (c *ASTChunker) ChunkFile(content []byte, lang, filePath string) ([]CodeChu
 		return c.fallbackChunk(content, filePath, lang), nil
 	}
 
-	// Extract chunks based on language
-	var chunks []CodeChunk
-	switch lang {
-	case "go":
-		chunks = c.extractGoChunks(rootNode, content, filePath)
-	case "python":
-		chunks = c.extractPythonChunks(rootNode, content, filePath)
-	case "typescript", "tsx", "javascript", "jsx":
-		chunks = c.extractTypeScriptChunks(rootNode, content, filePath)
-	}
+	found := c.extractChunks(rootNode, spec, content, filePath, lang)
 
 	// If we didn't extract any chunks, fall back
-	if len(chunks) == 0 {
+	if len(found) == 0 {
 		return c.fallbackChunk(content, filePath, lang), nil
 	}
 
-	return chunks, nil
+	return c.sizeChunks(found, content), nil
 }
 
-// extractGoChunks extracts semantic units from Go code
-func (c *ASTChunker) extractGoChunks(node *sitter.Node, content []byte, filePath string) []CodeChunk {
-	var chunks []CodeChunk
-
-	// Walk the tree and extract semantic units
-	var walk func(*sitter.Node)
-	walk = func(n *sitter.Node) {
-		if n == nil {
-			return
-		}
-
-		nodeType := n.Type()
-
-		// Extract function declarations
-		if nodeType == "function_declaration" {
-			chunk := c.extractFunctionChunk(n, content, filePath, "go", "function")
-			if chunk != nil {
-				chunks = append(chunks, *chunk)
-			}
-		}
-
-		// Extract method declarations
-		if nodeType == "method_declaration" {
-			chunk := c.extractFunctionChunk(n, content, filePath, "go", "method")
-			if chunk != nil {
-				chunks = append(chunks, *chunk)
-			}
-		}
-
-		// Extract type declarations (structs, interfaces)
-		if nodeType == "type_declaration" {
-			chunk := c.extractTypeChunk(n, content, filePath, "go")
-			if chunk != nil {
-				chunks = append(chunks, *chunk)
-			}
-		}
-
-		// Recurse into children
-		for i := 0; i < int(n.ChildCount()); i++ {
-			walk(n.Child(i))
-		}
-	}
-
-	walk(node)
-	return chunks
+// foundChunk is an extracted chunk with the node it came from and the
+// index of its enclosing chunk (-1 at top level), for the sizing pass.
+type foundChunk struct {
+	chunk  CodeChunk
+	node   *sitter.Node
+	parent int
 }
 
-// extractPythonChunks extracts semantic units from Python code
-func (c *ASTChunker) extractPythonChunks(node *sitter.Node, content []byte, filePath string) []CodeChunk {
-	var chunks []CodeChunk
-
-	var walk func(*sitter.Node)
-	walk = func(n *sitter.Node) {
-		if n == nil {
-			return
-		}
-
-		nodeType := n.Type()
-
-		// Extract function definitions
-		if nodeType == "function_definition" {
-			chunk := c.extractFunctionChunk(n, content, filePath, "python", "function")
-			if chunk != nil {
-				chunks = append(chunks, *chunk)
-			}
-		}
-
-		// Extract class definitions
-		if nodeType == "class_definition" {
-			chunk := c.extractClassChunk(n, content, filePath, "python")
-			if chunk != nil {
-				chunks = append(chunks, *chunk)
-			}
-		}
-
-		// Recurse into children
-		for i := 0; i < int(n.ChildCount()); i++ {
-			walk(n.Child(i))
-		}
+// extractChunks walks the tree and extracts a chunk for each named node
+// whose type is in the language's table (keywords like Ruby's `class` share
+// their declaration's type but are anonymous). Nested declarations
+// (methods inside a class) are extracted as well as their container. A
+// function whose nearest enclosing chunk is a method scope, such as a Rust
+// impl block, is a method.
+func (c *ASTChunker) extractChunks(node *sitter.Node, spec languageSpec, content []byte, filePath, lang string) []foundChunk {
+	var chunks []foundChunk
+	imports := extractImports(node, spec, content)
+
+	// path holds the names enclosing the current node, starting with the
+	// file's package
+	var path []string
+	if pkg := packageName(node, spec, content); pkg != "" {
+		path = append(path, pkg)
 	}
 
-	walk(node)
-	return chunks
-}
-
-// extractTypeScriptChunks extracts semantic units from TypeScript/JavaScript code
-func (c *ASTChunker) extractTypeScriptChunks(node *sitter.Node, content []byte, filePath string) []CodeChunk {
-	var chunks []CodeChunk
-
-	var walk func(*sitter.Node)
-	walk = func(n *sitter.Node) {
+	var walk func(n *sitter.Node, path []string, parent int, inMethodScope bool)
+	walk = func(n *sitter.Node, path []string, parent int, inMethodScope bool) {
 		if n == nil {
 			return
 		}
 
 		nodeType := n.Type()
-
-		// Extract function declarations
-		if nodeType == "function_declaration" || nodeType == "function" {
-			chunk := c.extractFunctionChunk(n, content, filePath, "typescript", "function")
-			if chunk != nil {
-				chunks = append(chunks, *chunk)
+		if kind, ok := spec.kinds[nodeType]; ok && n.IsNamed() && (!kind.needsBody || n.ChildByFieldName("body") != nil) {
+			chunkType := kind.chunkType
+			if chunkType == "function" && inMethodScope {
+				chunkType = "method"
 			}
-		}
-
-		// Extract method definitions
-		if nodeType == "method_definition" {
-			chunk := c.extractFunctionChunk(n, content, filePath, "typescript", "method")
-			if chunk != nil {
-				chunks = append(chunks, *chunk)
+			chunk := c.extractChunk(n, content, filePath, lang, chunkType)
+			chunk.Imports = imports
+			chunk.Receiver = receiverType(n, content)
+			chunk.Doc = docComment(n, spec, content)
+			chunk.Calls = extractCalls(n, spec, content)
+
+			enclosing := path
+			if chunk.Receiver != "" {
+				enclosing = append(enclosing[:len(enclosing):len(enclosing)], chunk.Receiver)
 			}
-		}
-
-		// Extract class declarations
-		if nodeType == "class_declaration" {
-			chunk := c.extractClassChunk(n, content, filePath, "typescript")
-			if chunk != nil {
-				chunks = append(chunks, *chunk)
+			if chunk.Name != "" {
+				chunk.Symbol = strings.Join(append(enclosing[:len(enclosing):len(enclosing)], chunk.Name), ".")
+				path = append(enclosing[:len(enclosing):len(enclosing)], chunk.Name)
 			}
-		}
-
-		// Extract interface declarations
-		if nodeType == "interface_declaration" {
-			chunk := c.extractTypeChunk(n, content, filePath, "typescript")
-			if chunk != nil {
-				chunks = append(chunks, *chunk)
+			chunks = append(chunks, foundChunk{chunk: chunk, node: n, parent: parent})
+			parent = len(chunks) - 1
+			inMethodScope = spec.methodScopes[nodeType]
+		} else if spec.scopes[nodeType] {
+			if name := c.extractName(n, content); name != "" {
+				path = append(path[:len(path):len(path)], name)
 			}
 		}
 
 		// Recurse into children
 		for i := 0; i < int(n.ChildCount()); i++ {
-			walk(n.Child(i))
+			walk(n.Child(i), path, parent, inMethodScope)
 		}
 	}
 
-	walk(node)
+	walk(node, path, -1, false)
 	return chunks
 }
 
-// extractFunctionChunk extracts a function or method chunk
-func (c *ASTChunker) extractFunctionChunk(node *sitter.Node, content []byte, filePath, lang, chunkType string) *CodeChunk {
-	startLine := int(node.StartPoint().Row) + 1
-	endLine := int(node.EndPoint().Row) + 1
-
+// extractChunk builds a chunk from a declaration node
+func (c *ASTChunker) extractChunk(node *sitter.Node, content []byte, filePath, lang, chunkType string) CodeChunk {
 	nodeContent := content[node.StartByte():node.EndByte()]
 
-	// Extract function name
-	name := c.extractName(node, content)
-
-	// Extract signature (first line or until opening brace)
-	signature := c.extractSignature(nodeContent)
-
-	return &CodeChunk{
+	return CodeChunk{
 		Content:   string(nodeContent),
 		Type:      chunkType,
-		Name:      name,
-		StartLine: startLine,
-		EndLine:   endLine,
+		Name:      c.extractName(node, content),
+		StartLine: int(node.StartPoint().Row) + 1,
+		EndLine:   int(node.EndPoint().Row) + 1,
 		FilePath:  filePath,
-		Signature: signature,
+		Signature: c.extractSignature(nodeContent),
 		Language:  lang,
 	}
 }
 
-// extractClassChunk extracts a class chunk
-func (c *ASTChunker) extractClassChunk(node *sitter.Node, content []byte, filePath, lang string) *CodeChunk {
-	startLine := int(node.StartPoint().Row) + 1
-	endLine := int(node.EndPoint().Row) + 1
-
-	nodeContent := content[node.StartByte():node.EndByte()]
-	name := c.extractName(node, content)
-	signature := c.extractSignature(nodeContent)
-
-	return &CodeChunk{
-		Content:   string(nodeContent),
-		Type:      "class",
-		Name:      name,
-		StartLine: startLine,
-		EndLine:   endLine,
-		FilePath:  filePath,
-		Signature: signature,
-		Language:  lang,
+// extractName extracts the declared name from a node: its name field, the
+// innermost declarator for C-family functions, the implemented type for
+// Rust impls, or the name of a wrapped spec (Go type declarations).
+func (c *ASTChunker) extractName(node *sitter.Node, content []byte) string {
+	if name := node.ChildByFieldName("name"); name != nil {
+		return name.Content(content)
 	}
-}
-
-// extractTypeChunk extracts a type/interface chunk
-func (c *ASTChunker) extractTypeChunk(node *sitter.Node, content []byte, filePath, lang string) *CodeChunk {
-	startLine := int(node.StartPoint().Row) + 1
-	endLine := int(node.EndPoint().Row) + 1
 
-	nodeContent := content[node.StartByte():node.EndByte()]
-	name := c.extractName(node, content)
-	signature := c.extractSignature(nodeContent)
+	// C/C++: int *(*f)(void) nests the name in declarators
+	if decl := node.ChildByFieldName("declarator"); decl != nil {
+		for next := decl.ChildByFieldName("declarator"); next != nil; next = decl.ChildByFieldName("declarator") {
+			decl = next
+		}
+		return decl.Content(content)
+	}
 
-	return &CodeChunk{
-		Content:   string(nodeContent),
-		Type:      "type",
-		Name:      name,
-		StartLine: startLine,
-		EndLine:   endLine,
-		FilePath:  filePath,
-		Signature: signature,
-		Language:  lang,
+	if typ := node.ChildByFieldName("type"); typ != nil && node.Type() == "impl_item" {
+		return typ.Content(content)
 	}
-}
 
-// extractName extracts the name from a node
-func (c *ASTChunker) extractName(node *sitter.Node, content []byte) string {
 	// Look for identifier or name child node
 	for i := 0; i < int(node.ChildCount()); i++ {
 		child := node.Child(i)
 		if child.Type() == "identifier" || child.Type() == "name" {
-			return string(content[child.StartByte():child.EndByte()])
+			return child.Content(content)
+		}
+	}
+
+	// Go: type_declaration wraps one or more type_specs
+	if child := node.NamedChild(0); child != nil {
+		if name := child.ChildByFieldName("name"); name != nil {
+			return name.Content(content)
 		}
 	}
 	return ""
@@ -396,8 +458,14 @@ func DetectLanguage(filePath string) string {
 		return "java"
 	case strings.HasSuffix(ext, ".c"), strings.HasSuffix(ext, ".h"):
 		return "c"
-	case strings.HasSuffix(ext, ".cpp"), strings.HasSuffix(ext, ".hpp"):
+	case strings.HasSuffix(ext, ".cpp"), strings.HasSuffix(ext, ".hpp"),
+		strings.HasSuffix(ext, ".cc"), strings.HasSuffix(ext, ".cxx"),
+		strings.HasSuffix(ext, ".hh"), strings.HasSuffix(ext, ".hxx"):
 		return "cpp"
+	case strings.HasSuffix(ext, ".cs"):
+		return "csharp"
+	case strings.HasSuffix(ext, ".rb"):
+		return "ruby"
 	default:
 		return "unknown"
 	}
@@ -416,7 +484,12 @@ func (c *ASTChunker) Close() error {
 
 // GetSupportedLanguages returns the list of languages supported by AST chunking
 func (c *ASTChunker) GetSupportedLanguages() []string {
-	return []string{"go", "python", "typescript", "tsx", "javascript", "jsx"}
+	languages := make([]string, 0, len(languageSpecs))
+	for lang := range languageSpecs {
+		languages = append(languages, lang)
+	}
+	sort.Strings(languages)
+	return languages
 }
 
 // validateChunk checks if a chunk is valid
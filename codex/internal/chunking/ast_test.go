@@ -0,0 +1,385 @@
+package chunking
+
+import (
+	"reflect"
+	"testing"
+)
+
+// chunkSummary is a chunk's type and name, as "type name".
+func chunkSummary(chunks []CodeChunk) []string {
+	out := make([]string, len(chunks))
+	for i, ch := range chunks {
+		out[i] = ch.Type + " " + ch.Name
+	}
+	return out
+}
+
+func TestASTChunker_ChunkFile(t *testing.T) {
+	tests := []struct {
+		name   string
+		lang   string
+		source string
+		want   []string
+	}{
+		{
+			name: "Given Go source, When chunking, Then functions, methods and types are extracted",
+			lang: "go",
+			source: `package payments
+
+type Service struct{}
+
+func (s *Service) Refund(id string) error { return nil }
+
+func New() *Service { return &Service{} }
+`,
+			want: []string{"type Service", "method Refund", "function New"},
+		},
+		{
+			name: "Given Python source, When chunking, Then class functions are methods",
+			lang: "python",
+			source: `class Service:
+    def refund(self, id):
+        return None
+
+def new():
+    return Service()
+`,
+			want: []string{"class Service", "method refund", "function new"},
+		},
+		{
+			name: "Given TSX source, When chunking, Then JSX parses and components are extracted",
+			lang: "tsx",
+			source: `interface Props { id: string }
+
+export function Refund(props: Props) {
+  return <button>{props.id}</button>;
+}
+`,
+			want: []string{"type Props", "function Refund"},
+		},
+		{
+			name: "Given JavaScript source, When chunking, Then the JavaScript grammar extracts classes and methods",
+			lang: "javascript",
+			source: `class Service {
+  refund(id) { return null; }
+}
+
+function* ids() { yield 1; }
+`,
+			want: []string{"class Service", "method refund", "function ids"},
+		},
+		{
+			name: "Given Rust source, When chunking, Then impl functions are methods",
+			lang: "rust",
+			source: `struct Service {}
+
+enum State { Open, Closed }
+
+trait Refunder {
+    fn refund(&self, id: &str) -> bool;
+}
+
+impl Service {
+    fn refund(&self, id: &str) -> bool { true }
+}
+
+fn new() -> Service { Service {} }
+`,
+			want: []string{"struct Service", "enum State", "interface Refunder", "impl Service", "method refund", "function new"},
+		},
+		{
+			name: "Given Java source, When chunking, Then classes, constructors and methods are extracted",
+			lang: "java",
+			source: `interface Refunder { boolean refund(String id); }
+
+class Service implements Refunder {
+    Service() {}
+    public boolean refund(String id) { return true; }
+}
+`,
+			want: []string{"interface Refunder", "method refund", "class Service", "method Service", "method refund"},
+		},
+		{
+			name: "Given C source, When chunking, Then struct references are skipped and pointer declarators named",
+			lang: "c",
+			source: `struct point { int x; int y; };
+
+struct point *origin(void) {
+    static struct point p;
+    return &p;
+}
+`,
+			want: []string{"struct point", "function origin"},
+		},
+		{
+			name: "Given C++ source, When chunking, Then class functions are methods and qualified names kept",
+			lang: "cpp",
+			source: `class Service {
+public:
+    bool refund(int id) { return true; }
+};
+
+bool Service::cancel(int id) { return false; }
+`,
+			want: []string{"class Service", "method refund", "function Service::cancel"},
+		},
+		{
+			name: "Given C# source, When chunking, Then types and methods are extracted",
+			lang: "csharp",
+			source: `interface IRefunder { bool Refund(string id); }
+
+struct Money { public int Cents; }
+
+class Service : IRefunder {
+    public bool Refund(string id) { return true; }
+}
+`,
+			want: []string{"interface IRefunder", "method Refund", "struct Money", "class Service", "method Refund"},
+		},
+		{
+			name: "Given Ruby source, When chunking, Then modules, classes and methods are extracted",
+			lang: "ruby",
+			source: `module Payments
+  class Service
+    def self.build
+      new
+    end
+
+    def refund(id)
+      true
+    end
+  end
+end
+`,
+			want: []string{"module Payments", "class Service", "method build", "method refund"},
+		},
+	}
+
+	chunker := NewASTChunker()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks, err := chunker.ChunkFile([]byte(tt.source), tt.lang, "src/file")
+			if err != nil {
+				t.Fatalf("ChunkFile: %v", err)
+			}
+			if got := chunkSummary(chunks); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("chunks = %q, want %q", got, tt.want)
+			}
+			for _, ch := range chunks {
+				if ch.Language != tt.lang {
+					t.Errorf("chunk %s language = %q, want %q", ch.Name, ch.Language, tt.lang)
+				}
+				if err := validateChunk(&ch); err != nil {
+					t.Errorf("chunk %s invalid: %v", ch.Name, err)
+				}
+			}
+		})
+	}
+
+	t.Run("Given an unsupported language, When chunking, Then lines are chunked", func(t *testing.T) {
+		chunks, err := chunker.ChunkFile([]byte("<?php echo 1;\n"), "php", "index.php")
+		if err != nil {
+			t.Fatalf("ChunkFile: %v", err)
+		}
+		if len(chunks) != 1 || chunks[0].Type != "chunk" {
+			t.Errorf("chunks = %q, want one fallback chunk", chunkSummary(chunks))
+		}
+	})
+
+	t.Run("Given a Rust function, When chunking, Then line range and signature are recorded", func(t *testing.T) {
+		src := "// refunds\nfn refund(id: &str) -> bool {\n    true\n}\n"
+		chunks, _ := chunker.ChunkFile([]byte(src), "rust", "lib.rs")
+		if len(chunks) != 1 {
+			t.Fatalf("chunks = %q, want one", chunkSummary(chunks))
+		}
+		ch := chunks[0]
+		if ch.StartLine != 2 || ch.EndLine != 4 {
+			t.Errorf("lines = %d-%d, want 2-4", ch.StartLine, ch.EndLine)
+		}
+		if ch.Signature != "fn refund(id: &str) -> bool {" {
+			t.Errorf("signature = %q", ch.Signature)
+		}
+	})
+}
+
+func TestASTChunker_Structure(t *testing.T) {
+	chunker := NewASTChunker()
+
+	t.Run("Given a Go method, When chunking, Then receiver, symbol, doc, imports and calls are recorded", func(t *testing.T) {
+		src := `package payments
+
+import (
+	"fmt"
+	store "github.com/acme/store"
+)
+
+// Refund reverses a charge.
+// It is idempotent.
+func (s *Service[T]) Refund(id string) error {
+	if err := s.store.Save(id); err != nil {
+		return fmt.Errorf("refund: %w", err)
+	}
+	return store.Flush()
+}
+`
+		chunks, _ := chunker.ChunkFile([]byte(src), "go", "payments/service.go")
+		if len(chunks) != 1 {
+			t.Fatalf("chunks = %q, want one", chunkSummary(chunks))
+		}
+		ch := chunks[0]
+		if ch.Receiver != "Service" || ch.Symbol != "payments.Service.Refund" {
+			t.Errorf("receiver, symbol = %q, %q, want Service, payments.Service.Refund", ch.Receiver, ch.Symbol)
+		}
+		if ch.Doc != "Refund reverses a charge.\nIt is idempotent." {
+			t.Errorf("doc = %q", ch.Doc)
+		}
+		if want := []string{"fmt", "github.com/acme/store"}; !reflect.DeepEqual(ch.Imports, want) {
+			t.Errorf("imports = %q, want %q", ch.Imports, want)
+		}
+		if want := []string{"Save", "Errorf", "Flush"}; !reflect.DeepEqual(ch.Calls, want) {
+			t.Errorf("calls = %q, want %q", ch.Calls, want)
+		}
+	})
+
+	t.Run("Given a Python class, When chunking, Then methods are qualified by it and docstrings kept", func(t *testing.T) {
+		src := `from payments import store
+
+class Service:
+    @retry
+    def refund(self, id):
+        """Reverse a charge.
+
+        Idempotent."""
+        store.save(id)
+`
+		chunks, _ := chunker.ChunkFile([]byte(src), "python", "service.py")
+		if len(chunks) != 2 {
+			t.Fatalf("chunks = %q, want two", chunkSummary(chunks))
+		}
+		m := chunks[1]
+		if m.Symbol != "Service.refund" {
+			t.Errorf("symbol = %q, want Service.refund", m.Symbol)
+		}
+		if m.Doc != "Reverse a charge.\n\nIdempotent." {
+			t.Errorf("doc = %q", m.Doc)
+		}
+		if want := []string{"from payments import store"}; !reflect.DeepEqual(m.Imports, want) {
+			t.Errorf("imports = %q, want %q", m.Imports, want)
+		}
+		if want := []string{"save"}; !reflect.DeepEqual(m.Calls, want) {
+			t.Errorf("calls = %q, want %q", m.Calls, want)
+		}
+	})
+
+	t.Run("Given a commented Rust impl in a module, When chunking, Then attributes are skipped and symbols nested", func(t *testing.T) {
+		src := `use std::io;
+
+mod payments {
+    /// A payment service.
+    #[derive(Debug)]
+    struct Service {}
+
+    impl Service {
+        fn refund(&self) -> io::Result<()> { self.store.save() }
+    }
+}
+`
+		chunks, _ := chunker.ChunkFile([]byte(src), "rust", "lib.rs")
+		var symbols []string
+		for _, ch := range chunks {
+			symbols = append(symbols, ch.Symbol)
+		}
+		if want := []string{"payments.Service", "payments.Service", "payments.Service.refund"}; !reflect.DeepEqual(symbols, want) {
+			t.Fatalf("symbols = %q, want %q", symbols, want)
+		}
+		if chunks[0].Doc != "A payment service." {
+			t.Errorf("doc = %q", chunks[0].Doc)
+		}
+		if want := []string{"use std::io"}; !reflect.DeepEqual(chunks[0].Imports, want) {
+			t.Errorf("imports = %q, want %q", chunks[0].Imports, want)
+		}
+		if want := []string{"save"}; !reflect.DeepEqual(chunks[2].Calls, want) {
+			t.Errorf("calls = %q, want %q", chunks[2].Calls, want)
+		}
+	})
+
+	t.Run("Given a Java class in a package, When chunking, Then Javadoc and package are recorded", func(t *testing.T) {
+		src := `package com.acme.payments;
+
+import java.util.List;
+
+class Service {
+    int count; // trailing
+    /**
+     * Reverses a charge.
+     */
+    boolean refund(String id) { return store.save(id); }
+}
+`
+		chunks, _ := chunker.ChunkFile([]byte(src), "java", "Service.java")
+		if len(chunks) != 2 {
+			t.Fatalf("chunks = %q, want two", chunkSummary(chunks))
+		}
+		m := chunks[1]
+		if m.Symbol != "com.acme.payments.Service.refund" {
+			t.Errorf("symbol = %q", m.Symbol)
+		}
+		if m.Doc != "Reverses a charge." {
+			t.Errorf("doc = %q", m.Doc)
+		}
+		if want := []string{"import java.util.List"}; !reflect.DeepEqual(m.Imports, want) {
+			t.Errorf("imports = %q, want %q", m.Imports, want)
+		}
+	})
+
+	t.Run("Given a trailing comment before a declaration, When chunking, Then it is not the doc", func(t *testing.T) {
+		src := "package p\n\nvar x = 1 // counter\nfunc f() {}\n"
+		chunks, _ := chunker.ChunkFile([]byte(src), "go", "p.go")
+		if len(chunks) != 1 || chunks[0].Doc != "" {
+			t.Errorf("chunks = %+v, want one without doc", chunks)
+		}
+	})
+}
+
+func TestCalleeName(t *testing.T) {
+	tests := map[string]string{
+		"fmt.Errorf":      "Errorf",
+		"s.store.Save":    "Save",
+		"Vec::<u8>::new":  "new",
+		"conn->close":     "close",
+		"Map[int]":        "Map",
+		"valid?":          "valid?",
+		"handlers[0]":     "handlers",
+		"(func() {})":     "",
+		"getStore().save": "save",
+	}
+	for expr, want := range tests {
+		t.Run("Given "+expr+", When naming the callee, Then "+want, func(t *testing.T) {
+			if got := calleeName(expr); got != want {
+				t.Errorf("calleeName(%q) = %q, want %q", expr, got, want)
+			}
+		})
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	tests := map[string]string{
+		"main.go":      "go",
+		"app.jsx":      "jsx",
+		"lib.rs":       "rust",
+		"Service.java": "java",
+		"point.h":      "c",
+		"engine.cc":    "cpp",
+		"engine.hh":    "cpp",
+		"Service.cs":   "csharp",
+		"service.rb":   "ruby",
+		"README":       "unknown",
+	}
+	for path, want := range tests {
+		t.Run("Given "+path+", When detecting, Then "+want, func(t *testing.T) {
+			if got := DetectLanguage(path); got != want {
+				t.Errorf("DetectLanguage(%q) = %q, want %q", path, got, want)
+			}
+		})
+	}
+}
@@ -2,42 +2,110 @@ package chunking
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"log"
 	"strings"
+	"sync"
+
+	"github.com/anthropics/aef/codex/internal/llm"
 )
 
+const (
+	// enrichMaxTokens bounds the generated context (1-2 sentences).
+	enrichMaxTokens = 100
+
+	// defaultEnrichConcurrency is the number of enrichment requests in flight
+	// at once, shared across all documents chunked by one ContextualChunker.
+	defaultEnrichConcurrency = 4
+)
+
+const enrichPromptTemplate = `<document>
+%s
+</document>
+Here is a chunk from the document:
+<chunk>
+%s
+</chunk>
+Please provide a short, succinct context (1-2 sentences) to situate this chunk within the overall document. Focus on what makes this chunk findable. Answer only with the context and nothing else.`
+
+// EnrichmentCache stores generated chunk contexts keyed by content hash, so
+// re-indexing unchanged chunks does not call the model again.
+// Implementations: storage.MetadataStore (persistent), memoryEnrichmentCache (default)
+type EnrichmentCache interface {
+	GetEnrichment(key string) (string, bool, error)
+	SaveEnrichment(key, context string) error
+}
+
 // ContextualChunker enriches document chunks with contextual descriptions
-// TODO: Implement Anthropic SDK integration for Claude Haiku
+// generated by an LLM (Anthropic Messages API or Ollama).
 type ContextualChunker struct {
-	apiKey string
-	// client will be *anthropic.Client when SDK integration is complete
+	client llm.Client
+	cache  EnrichmentCache
+	sem    chan struct{}
+}
+
+// ContextualOption configures a ContextualChunker.
+type ContextualOption func(*ContextualChunker)
+
+// WithEnrichmentCache sets the cache for generated contexts.
+// Defaults to an in-memory cache.
+func WithEnrichmentCache(cache EnrichmentCache) ContextualOption {
+	return func(c *ContextualChunker) { c.cache = cache }
+}
+
+// WithMaxConcurrency bounds the number of concurrent enrichment requests.
+func WithMaxConcurrency(n int) ContextualOption {
+	return func(c *ContextualChunker) {
+		if n > 0 {
+			c.sem = make(chan struct{}, n)
+		}
+	}
 }
 
 // NewContextualChunker creates a new contextual chunker
-func NewContextualChunker(apiKey string) (*ContextualChunker, error) {
-	if apiKey == "" {
-		return nil, fmt.Errorf("ANTHROPIC_API_KEY not set")
+func NewContextualChunker(client llm.Client, opts ...ContextualOption) (*ContextualChunker, error) {
+	if client == nil {
+		return nil, fmt.Errorf("completion client is required")
 	}
 
-	return &ContextualChunker{
-		apiKey: apiKey,
-	}, nil
+	c := &ContextualChunker{
+		client: client,
+		cache:  newMemoryEnrichmentCache(),
+		sem:    make(chan struct{}, defaultEnrichConcurrency),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
 }
 
-// EnrichChunk generates a contextual description for a chunk using Claude 3 Haiku
+// EnrichChunk generates a contextual description situating chunk within its
+// document. Results are cached by model, document context and chunk content.
 func (c *ContextualChunker) EnrichChunk(ctx context.Context, chunk, documentContext string) (string, error) {
-	// TODO: Implement with Anthropic SDK
-	// Prompt template:
-	// <document>{documentContext}</document>
-	// Here is a chunk from the document:
-	// <chunk>{chunk}</chunk>
-	// Please provide a short, succinct context (1-2 sentences) to situate this chunk
-	// within the overall document. Focus on what makes this chunk findable.
-	//
-	// Use Claude 3 Haiku (claude-3-haiku-20240307) with max_tokens=100
-	// Include retry logic with exponential backoff for rate limits
-
-	return "", fmt.Errorf("contextual enrichment not implemented")
+	key := enrichmentKey(c.client.Model(), chunk, documentContext)
+	if cached, ok, err := c.cache.GetEnrichment(key); err != nil {
+		log.Printf("Warning: failed to read enrichment cache: %v", err)
+	} else if ok {
+		return cached, nil
+	}
+
+	select {
+	case c.sem <- struct{}{}:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	contextStr, err := c.client.Complete(ctx, fmt.Sprintf(enrichPromptTemplate, documentContext, chunk), enrichMaxTokens)
+	<-c.sem
+	if err != nil {
+		return "", fmt.Errorf("failed to enrich chunk: %w", err)
+	}
+
+	if err := c.cache.SaveEnrichment(key, contextStr); err != nil {
+		log.Printf("Warning: failed to write enrichment cache: %v", err)
+	}
+	return contextStr, nil
 }
 
 // ChunkDocument chunks a document and enriches each chunk
@@ -48,13 +116,41 @@ func (c *ContextualChunker) ChunkDocument(ctx context.Context, content, filePath
 	// Get document context (title, TOC, first few paragraphs)
 	docContext := extractDocumentContext(content)
 
-	var chunks []DocChunk
+	// Enrich sections with a fixed pool of workers, one per request the
+	// semaphore allows, so long documents do not start a goroutine per section
+	contexts := make([]string, len(sections))
+	errs := make([]error, len(sections))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(cap(c.sem), len(sections)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				contexts[i], errs[i] = c.EnrichChunk(ctx, sections[i].Content, docContext)
+			}
+		}()
+	}
+	for i := range sections {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
 
-	for _, section := range sections {
-		// Enrich with Haiku (when implemented)
-		contextStr, err := c.EnrichChunk(ctx, section.Content, docContext)
-		if err != nil {
-			// Fall back to no context on error
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	chunks := make([]DocChunk, 0, len(sections))
+	failed := 0
+	for i, section := range sections {
+		// Fall back to no context on error
+		contextStr := contexts[i]
+		if errs[i] != nil {
+			if failed == 0 {
+				log.Printf("Warning: contextual enrichment failed for %s: %v", filePath, errs[i])
+			}
+			failed++
 			contextStr = ""
 		}
 
@@ -68,15 +164,29 @@ func (c *ContextualChunker) ChunkDocument(ctx context.Context, content, filePath
 			Context:         contextStr,
 			EnrichedContent: enriched,
 			FilePath:        filePath,
-			Section:         section.Title,
+			Section:         section.Breadcrumb(),
+			Headings:        section.Headings,
 			StartLine:       section.StartLine,
 			EndLine:         section.EndLine,
 		})
 	}
+	if failed > 1 {
+		log.Printf("Warning: %d of %d chunks in %s indexed without context", failed, len(sections), filePath)
+	}
 
 	return chunks, nil
 }
 
+// enrichmentKey hashes everything that determines a generated context.
+func enrichmentKey(model, chunk, documentContext string) string {
+	h := sha256.New()
+	for _, part := range []string{model, documentContext, chunk} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
 // extractDocumentContext extracts context from the beginning of a document
 func extractDocumentContext(content string) string {
 	lines := strings.Split(content, "\n")
@@ -92,3 +202,27 @@ func extractDocumentContext(content string) string {
 
 	return strings.Join(contextLines, "\n")
 }
+
+// memoryEnrichmentCache is a process-local EnrichmentCache.
+type memoryEnrichmentCache struct {
+	mu      sync.RWMutex
+	entries map[string]string
+}
+
+func newMemoryEnrichmentCache() *memoryEnrichmentCache {
+	return &memoryEnrichmentCache{entries: make(map[string]string)}
+}
+
+func (m *memoryEnrichmentCache) GetEnrichment(key string) (string, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	v, ok := m.entries[key]
+	return v, ok, nil
+}
+
+func (m *memoryEnrichmentCache) SaveEnrichment(key, context string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[key] = context
+	return nil
+}
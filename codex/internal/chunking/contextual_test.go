@@ -0,0 +1,225 @@
+package chunking
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthropics/aef/codex/internal/llm"
+	"github.com/anthropics/aef/codex/internal/llm/llmtest"
+)
+
+const testDoc = `# Payments
+
+Overview of the payment service.
+
+## Retries
+
+Retries use exponential backoff.
+
+## Idempotency
+
+Keys are stored for 24 hours.
+
+## Webhooks
+
+Webhooks are signed with HMAC.
+`
+
+// chunkSection returns the prompt's chunk text, so responses are per-section.
+func chunkSection(prompt string) string {
+	start := strings.Index(prompt, "<chunk>\n")
+	end := strings.Index(prompt, "\n</chunk>")
+	if start < 0 || end < 0 {
+		return "?"
+	}
+	firstLine := strings.SplitN(prompt[start+len("<chunk>\n"):end], "\n", 2)[0]
+	return "Context: " + firstLine
+}
+
+func TestContextualChunker_ChunkDocument(t *testing.T) {
+	t.Run("Given stand-in LLM, When chunking, Then each section is enriched", func(t *testing.T) {
+		srv := llmtest.NewServer(chunkSection)
+		defer srv.Close()
+
+		client, _ := llm.NewAnthropicClient("test-key", llm.WithAnthropicURL(srv.AnthropicURL()))
+		chunker, err := NewContextualChunker(client)
+		if err != nil {
+			t.Fatalf("NewContextualChunker: %v", err)
+		}
+
+		chunks, err := chunker.ChunkDocument(context.Background(), testDoc, "docs/payments.md")
+		if err != nil {
+			t.Fatalf("ChunkDocument: %v", err)
+		}
+		if len(chunks) != 4 {
+			t.Fatalf("expected 4 chunks, got %d", len(chunks))
+		}
+		for _, c := range chunks {
+			if c.Context == "" {
+				t.Errorf("section %q has no context", c.Section)
+				continue
+			}
+			if !strings.HasPrefix(c.EnrichedContent, c.Context+"\n\n") {
+				t.Errorf("section %q: enriched content does not start with context", c.Section)
+			}
+			if !strings.HasSuffix(c.EnrichedContent, c.OriginalContent) {
+				t.Errorf("section %q: enriched content does not end with original", c.Section)
+			}
+		}
+		if chunks[1].Context != "Context: Retries use exponential backoff." {
+			t.Errorf("expected per-section context in order, got %q", chunks[1].Context)
+		}
+		if !strings.Contains(srv.Prompts()[0], "<document>\n# Payments") {
+			t.Error("prompt should include the document context")
+		}
+	})
+
+	t.Run("Given cached enrichments, When re-chunking, Then no LLM calls are made", func(t *testing.T) {
+		srv := llmtest.NewServer(chunkSection)
+		defer srv.Close()
+
+		client := llm.NewOllamaClient(llm.WithOllamaURL(srv.OllamaURL()))
+		chunker, _ := NewContextualChunker(client)
+
+		ctx := context.Background()
+		if _, err := chunker.ChunkDocument(ctx, testDoc, "a.md"); err != nil {
+			t.Fatalf("first ChunkDocument: %v", err)
+		}
+		first := srv.Calls()
+
+		chunks, err := chunker.ChunkDocument(ctx, testDoc, "a.md")
+		if err != nil {
+			t.Fatalf("second ChunkDocument: %v", err)
+		}
+		if srv.Calls() != first {
+			t.Errorf("expected no new calls, got %d", srv.Calls()-first)
+		}
+		if chunks[0].Context == "" {
+			t.Error("cached context should be applied")
+		}
+	})
+
+	t.Run("Given concurrency limit, When chunking, Then in-flight requests are bounded", func(t *testing.T) {
+		srv := llmtest.NewServer(func(p string) string {
+			time.Sleep(20 * time.Millisecond)
+			return chunkSection(p)
+		})
+		defer srv.Close()
+
+		client := llm.NewOllamaClient(llm.WithOllamaURL(srv.OllamaURL()))
+		chunker, _ := NewContextualChunker(client, WithMaxConcurrency(2))
+
+		if _, err := chunker.ChunkDocument(context.Background(), testDoc, "a.md"); err != nil {
+			t.Fatalf("ChunkDocument: %v", err)
+		}
+		if srv.MaxInFlight() > 2 {
+			t.Errorf("expected at most 2 concurrent requests, got %d", srv.MaxInFlight())
+		}
+	})
+
+	t.Run("Given a long document, When chunking, Then goroutines are bounded by the concurrency limit", func(t *testing.T) {
+		var doc strings.Builder
+		for i := 0; i < 500; i++ {
+			fmt.Fprintf(&doc, "## Section %d\n\nText %d.\n\n", i, i)
+		}
+		client := &blockingClient{started: make(chan struct{}, 500), release: make(chan struct{})}
+		chunker, _ := NewContextualChunker(client, WithMaxConcurrency(2))
+		before := runtime.NumGoroutine()
+
+		done := make(chan error)
+		go func() {
+			_, err := chunker.ChunkDocument(context.Background(), doc.String(), "long.md")
+			done <- err
+		}()
+		<-client.started
+		<-client.started
+		during := runtime.NumGoroutine()
+		close(client.release)
+
+		if err := <-done; err != nil {
+			t.Fatalf("ChunkDocument: %v", err)
+		}
+		if during-before > 10 {
+			t.Errorf("expected a few goroutines for 500 sections, got %d more", during-before)
+		}
+	})
+
+	t.Run("Given failing LLM, When chunking, Then falls back to no context", func(t *testing.T) {
+		srv := llmtest.NewServer(chunkSection)
+		srv.Close() // connection refused
+
+		client, _ := llm.NewAnthropicClient("k", llm.WithAnthropicURL(srv.AnthropicURL()))
+		chunker, _ := NewContextualChunker(&failFastClient{client})
+
+		chunks, err := chunker.ChunkDocument(context.Background(), testDoc, "a.md")
+		if err != nil {
+			t.Fatalf("ChunkDocument: %v", err)
+		}
+		for _, c := range chunks {
+			if c.Context != "" || c.EnrichedContent != c.OriginalContent {
+				t.Errorf("section %q should have no context", c.Section)
+			}
+		}
+	})
+}
+
+func TestContextualChunker_PersistentCache(t *testing.T) {
+	srv := llmtest.NewServer(chunkSection)
+	defer srv.Close()
+
+	cache := newMemoryEnrichmentCache()
+	newChunker := func() *ContextualChunker {
+		client := llm.NewOllamaClient(llm.WithOllamaURL(srv.OllamaURL()))
+		c, _ := NewContextualChunker(client, WithEnrichmentCache(cache))
+		return c
+	}
+
+	ctx := context.Background()
+	newChunker().ChunkDocument(ctx, testDoc, "a.md")
+	first := srv.Calls()
+
+	// A new chunker sharing the cache (as after a restart) makes no calls
+	newChunker().ChunkDocument(ctx, testDoc, "a.md")
+	if srv.Calls() != first {
+		t.Errorf("expected cache hits across chunkers, got %d new calls", srv.Calls()-first)
+	}
+
+	// Editing a section past the document context (first 50 lines)
+	// re-enriches only that section
+	long := testDoc + "\n## Appendix\n\n" + strings.Repeat("filler\n", 60) + "Last line.\n"
+	newChunker().ChunkDocument(ctx, long, "a.md")
+	before := srv.Calls()
+	edited := strings.Replace(long, "Last line.", "Final line.", 1)
+	newChunker().ChunkDocument(ctx, edited, "a.md")
+	if got := srv.Calls() - before; got != 1 {
+		t.Errorf("expected 1 new call for the edited section, got %d", got)
+	}
+}
+
+// failFastClient wraps a client with a context that expires quickly, so
+// retries against an unreachable server do not slow the test down.
+type failFastClient struct{ llm.Client }
+
+func (f *failFastClient) Complete(ctx context.Context, prompt string, maxTokens int) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	return f.Client.Complete(ctx, prompt, maxTokens)
+}
+
+// blockingClient holds every completion until release is closed.
+type blockingClient struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingClient) Complete(ctx context.Context, prompt string, maxTokens int) (string, error) {
+	b.started <- struct{}{}
+	<-b.release
+	return chunkSection(prompt), nil
+}
+
+func (b *blockingClient) Model() string { return "blocking" }
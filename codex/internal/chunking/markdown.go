@@ -1,141 +1,303 @@
 package chunking
 
 import (
-	"regexp"
+	"sort"
 	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/text"
+	"gopkg.in/yaml.v3"
 )
 
-var headerRegex = regexp.MustCompile(`^(#{1,6})\s+(.+)$`)
+// BreadcrumbSeparator joins the headings of a section's breadcrumb.
+const BreadcrumbSeparator = " > "
 
-// SplitMarkdown splits markdown content into sections based on headers
-func SplitMarkdown(content string) []MarkdownSection {
-	lines := strings.Split(content, "\n")
+// markdownParser parses CommonMark with GitHub tables, so a table is one block.
+var markdownParser = goldmark.New(goldmark.WithExtensions(extension.Table)).Parser()
+
+// mdBlock is a top-level block of a Markdown document: a paragraph, list,
+// fenced code block, table and so on. Blocks are never split.
+type mdBlock struct {
+	start, end int // byte range in the document, trailing blank lines excluded
+}
+
+// mdSection is a MarkdownSection with the blocks of its body.
+type mdSection struct {
+	MarkdownSection
+	blocks []mdBlock
+}
 
+// SplitMarkdown splits markdown content into sections at its top-level
+// headings. Each section carries the path of headings above it, so
+// "## Rollback" under "# Runbook" and "## Payments" has the breadcrumb
+// "Runbook > Payments > Rollback". Content before the first heading is the
+// "(Introduction)" section. Front matter is left out; see SplitFrontMatter.
+// Headings inside fenced code, lists and block quotes do not start sections.
+func SplitMarkdown(content string) []MarkdownSection {
 	var sections []MarkdownSection
-	var currentSection *MarkdownSection
-	var currentLines []string
-
-	for i, line := range lines {
-		lineNum := i + 1
-
-		// Check if this line is a header
-		if matches := headerRegex.FindStringSubmatch(line); matches != nil {
-			// Save previous section if exists
-			if currentSection != nil {
-				currentSection.Content = strings.TrimSpace(strings.Join(currentLines, "\n"))
-				currentSection.EndLine = lineNum - 1
-				if currentSection.Content != "" {
-					sections = append(sections, *currentSection)
-				}
-			}
+	for _, s := range parseMarkdownSections(content) {
+		sections = append(sections, s.MarkdownSection)
+	}
+	return sections
+}
 
-			// Start new section
-			level := len(matches[1])
-			title := matches[2]
-			currentSection = &MarkdownSection{
-				Title:     title,
-				Level:     level,
-				StartLine: lineNum,
-			}
-			currentLines = []string{}
-		} else if currentSection != nil {
-			currentLines = append(currentLines, line)
-		} else {
-			// Content before first header - create implicit section
-			if strings.TrimSpace(line) != "" {
-				currentSection = &MarkdownSection{
-					Title:     "(Introduction)",
-					Level:     0,
-					StartLine: 1,
-				}
-				currentLines = []string{line}
+// ChunkMarkdown chunks markdown content into sections, splitting sections
+// longer than maxChunkSize bytes between top-level blocks. Fenced code
+// blocks, tables and lists are never split, so a chunk holding one of them
+// may exceed maxChunkSize. Parts keep the section's title and breadcrumb.
+func ChunkMarkdown(content string, maxChunkSize int) []MarkdownSection {
+	sections := parseMarkdownSections(content)
+	lines := newLineIndex(content)
+
+	var result []MarkdownSection
+	for _, section := range sections {
+		if maxChunkSize <= 0 || len(section.Content) <= maxChunkSize {
+			result = append(result, section.MarkdownSection)
+			continue
+		}
+
+		first := 0
+		for i := 1; i <= len(section.blocks); i++ {
+			if i < len(section.blocks) && section.blocks[i].end-section.blocks[first].start <= maxChunkSize {
+				continue
 			}
+			part := section.MarkdownSection
+			start, end := section.blocks[first].start, section.blocks[i-1].end
+			part.Content = content[start:end]
+			part.StartLine = lines.line(start)
+			part.EndLine = lines.line(end - 1)
+			result = append(result, part)
+			first = i
 		}
 	}
+	return result
+}
 
-	// Save last section
-	if currentSection != nil {
-		currentSection.Content = strings.TrimSpace(strings.Join(currentLines, "\n"))
-		currentSection.EndLine = len(lines)
-		if currentSection.Content != "" {
-			sections = append(sections, *currentSection)
+// parseMarkdownSections parses content and groups its top-level blocks by
+// the heading they follow.
+func parseMarkdownSections(content string) []mdSection {
+	_, offset := SplitFrontMatter(content)
+	source := []byte(content[offset:])
+	doc := markdownParser.Parse(text.NewReader(source))
+	lines := newLineIndex(content)
+
+	// Top-level blocks in order; each ends where the next starts
+	type block struct {
+		start   int
+		heading *ast.Heading
+	}
+	var blocks []block
+	for n := doc.FirstChild(); n != nil; n = n.NextSibling() {
+		start := blockStart(n)
+		if start < 0 {
+			continue
 		}
+		h, _ := n.(*ast.Heading)
+		blocks = append(blocks, block{start: offset + start, heading: h})
 	}
 
-	// If no sections found, treat entire content as one section
-	if len(sections) == 0 && strings.TrimSpace(content) != "" {
-		sections = append(sections, MarkdownSection{
-			Title:     "(Document)",
-			Content:   strings.TrimSpace(content),
-			Level:     0,
-			StartLine: 1,
-			EndLine:   len(lines),
-		})
+	var sections []mdSection
+	var current *mdSection
+	var path []string // titles of the enclosing headings by level, "" for skipped levels
+	sawHeading := false
+	flush := func() {
+		if current != nil && len(current.blocks) > 0 {
+			first, last := current.blocks[0], current.blocks[len(current.blocks)-1]
+			current.Content = content[first.start:last.end]
+			current.EndLine = lines.line(last.end - 1)
+			sections = append(sections, *current)
+		}
 	}
 
+	for i, b := range blocks {
+		end := len(content)
+		if i+1 < len(blocks) {
+			end = blocks[i+1].start
+		}
+		end = b.start + len(strings.TrimRightFunc(content[b.start:end], isMarkdownSpace))
+
+		if b.heading != nil {
+			flush()
+			sawHeading = true
+			title := headingText(b.heading, source)
+			level := b.heading.Level
+			if len(path) >= level {
+				path = path[:level-1]
+			}
+			for len(path) < level-1 {
+				path = append(path, "")
+			}
+			path = append(path, title)
+			current = &mdSection{MarkdownSection: MarkdownSection{
+				Title:     title,
+				Headings:  compactHeadings(path),
+				Level:     level,
+				StartLine: lines.line(b.start),
+			}}
+			continue
+		}
+		if current == nil {
+			current = &mdSection{MarkdownSection: MarkdownSection{
+				Title:     "(Introduction)",
+				StartLine: lines.line(b.start),
+			}}
+		}
+		current.blocks = append(current.blocks, mdBlock{start: b.start, end: end})
+	}
+	flush()
+
+	// Without headings, or with nothing but headings, the whole document
+	// is one section
+	switch {
+	case !sawHeading && len(sections) == 1:
+		sections[0].Title = "(Document)"
+	case len(sections) == 0 && len(blocks) > 0:
+		start := blocks[0].start
+		end := start + len(strings.TrimRightFunc(content[start:], isMarkdownSpace))
+		sections = append(sections, mdSection{
+			MarkdownSection: MarkdownSection{
+				Title:     "(Document)",
+				Content:   content[start:end],
+				StartLine: lines.line(start),
+				EndLine:   lines.line(end - 1),
+			},
+			blocks: []mdBlock{{start: start, end: end}},
+		})
+	}
 	return sections
 }
 
-// ChunkMarkdown chunks markdown content with optional size limits
-func ChunkMarkdown(content string, maxChunkSize int) []MarkdownSection {
-	sections := SplitMarkdown(content)
-
-	if maxChunkSize <= 0 {
-		return sections
+// blockStart returns the byte offset where a block starts, or -1 for an
+// empty block.
+func blockStart(n ast.Node) int {
+	if pos := n.Pos(); pos >= 0 {
+		return pos
+	}
+	if n.Type() == ast.TypeBlock && n.Lines().Len() > 0 {
+		return n.Lines().At(0).Start
 	}
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		if start := blockStart(c); start >= 0 {
+			return start
+		}
+	}
+	return -1
+}
 
-	// Further split sections that are too large
-	var result []MarkdownSection
-	for _, section := range sections {
-		if len(section.Content) <= maxChunkSize {
-			result = append(result, section)
-		} else {
-			// Split large sections by paragraphs
-			chunks := splitByParagraphs(section.Content, maxChunkSize)
-			for i, chunk := range chunks {
-				result = append(result, MarkdownSection{
-					Title:     section.Title,
-					Content:   chunk,
-					Level:     section.Level,
-					StartLine: section.StartLine, // Approximate
-					EndLine:   section.EndLine,
-				})
-				_ = i // suppress unused warning
-			}
+// headingText returns the raw text of a heading, joining setext lines.
+func headingText(h *ast.Heading, source []byte) string {
+	var parts []string
+	for i := 0; i < h.Lines().Len(); i++ {
+		seg := h.Lines().At(i)
+		if s := strings.TrimSpace(string(seg.Value(source))); s != "" {
+			parts = append(parts, s)
 		}
 	}
+	return strings.Join(parts, " ")
+}
 
-	return result
+// compactHeadings copies a heading path, leaving out skipped levels.
+func compactHeadings(path []string) []string {
+	var headings []string
+	for _, h := range path {
+		if h != "" {
+			headings = append(headings, h)
+		}
+	}
+	return headings
 }
 
-// splitByParagraphs splits content into chunks at paragraph boundaries
-func splitByParagraphs(content string, maxSize int) []string {
-	paragraphs := strings.Split(content, "\n\n")
+func isMarkdownSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r'
+}
 
-	var chunks []string
-	var current strings.Builder
+// lineIndex maps byte offsets to 1-based line numbers.
+type lineIndex []int
 
-	for _, para := range paragraphs {
-		para = strings.TrimSpace(para)
-		if para == "" {
-			continue
+func newLineIndex(content string) lineIndex {
+	starts := lineIndex{0}
+	for i := 0; i < len(content); i++ {
+		if content[i] == '\n' {
+			starts = append(starts, i+1)
 		}
+	}
+	return starts
+}
 
-		// Check if adding this paragraph would exceed max size
-		if current.Len() > 0 && current.Len()+len(para)+2 > maxSize {
-			chunks = append(chunks, current.String())
-			current.Reset()
-		}
+func (l lineIndex) line(offset int) int {
+	return sort.Search(len(l), func(i int) bool { return l[i] > offset })
+}
+
+// SplitFrontMatter parses the YAML front matter that opens a Markdown
+// document between "---" lines. It returns the fields and the byte offset
+// where the body starts, or nil and 0 when there is no valid front matter.
+func SplitFrontMatter(content string) (map[string]any, int) {
+	rest, ok := strings.CutPrefix(content, "---\n")
+	if !ok {
+		rest, ok = strings.CutPrefix(content, "---\r\n")
+	}
+	if !ok {
+		return nil, 0
+	}
 
-		if current.Len() > 0 {
-			current.WriteString("\n\n")
+	var end, next int
+	for pos := 0; ; {
+		i := strings.IndexByte(rest[pos:], '\n')
+		line := rest[pos:]
+		if i >= 0 {
+			line = rest[pos : pos+i]
+		}
+		if t := strings.TrimRight(line, " \t\r"); t == "---" || t == "..." {
+			end = pos
+			next = len(rest)
+			if i >= 0 {
+				next = pos + i + 1
+			}
+			break
+		}
+		if i < 0 {
+			return nil, 0
 		}
-		current.WriteString(para)
+		pos += i + 1
 	}
 
-	if current.Len() > 0 {
-		chunks = append(chunks, current.String())
+	var fields map[string]any
+	if err := yaml.Unmarshal([]byte(rest[:end]), &fields); err != nil {
+		return nil, 0
 	}
+	if fields == nil {
+		fields = map[string]any{}
+	}
+	return fields, len(content) - len(rest) + next
+}
 
-	return chunks
+// FrontMatterTags returns the tags listed in front matter under "tags" or
+// "keywords", given as a list or a comma-separated string.
+func FrontMatterTags(fields map[string]any) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	add := func(tag string) {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	for _, key := range []string{"tags", "keywords"} {
+		switch v := fields[key].(type) {
+		case string:
+			for _, tag := range strings.Split(v, ",") {
+				add(tag)
+			}
+		case []any:
+			for _, tag := range v {
+				if s, ok := tag.(string); ok {
+					add(s)
+				}
+			}
+		}
+	}
+	return tags
 }
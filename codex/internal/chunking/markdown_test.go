@@ -0,0 +1,145 @@
+package chunking
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const runbookDoc = `---
+title: Payments runbook
+tags: [payments, oncall]
+keywords: rollback, payments
+owner:
+  team: billing
+---
+Read this first.
+
+# Runbook
+
+## Payments
+
+Check the dashboard.
+
+` + "```bash" + `
+# not a heading
+kubectl rollout status deploy/payments
+` + "```" + `
+
+### Rollback
+
+| Step | Command |
+|------|---------|
+| 1    | undo    |
+
+## Refunds
+Refund text.
+`
+
+func TestSplitMarkdown(t *testing.T) {
+	t.Run("Given nested headings and a fence, When splitting, Then sections carry breadcrumbs and fenced comments stay code", func(t *testing.T) {
+		sections := SplitMarkdown(runbookDoc)
+
+		var got []string
+		for _, s := range sections {
+			got = append(got, s.Breadcrumb())
+		}
+		want := []string{"(Introduction)", "Runbook > Payments", "Runbook > Payments > Rollback", "Runbook > Refunds"}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("breadcrumbs = %q, want %q", got, want)
+		}
+
+		intro, payments, rollback := sections[0], sections[1], sections[2]
+		if intro.Content != "Read this first." || intro.StartLine != 8 {
+			t.Errorf("intro = %q at line %d, want front matter left out", intro.Content, intro.StartLine)
+		}
+		if !strings.Contains(payments.Content, "# not a heading\nkubectl") || !strings.HasSuffix(payments.Content, "```") {
+			t.Errorf("payments content = %q, want the whole fence", payments.Content)
+		}
+		if payments.Title != "Payments" || payments.Level != 2 || payments.StartLine != 12 || payments.EndLine != 19 {
+			t.Errorf("payments = %q level %d lines %d-%d", payments.Title, payments.Level, payments.StartLine, payments.EndLine)
+		}
+		if rollback.StartLine != 21 || rollback.EndLine != 25 {
+			t.Errorf("rollback lines = %d-%d, want 21-25", rollback.StartLine, rollback.EndLine)
+		}
+	})
+
+	t.Run("Given a skipped heading level, When splitting, Then the breadcrumb leaves the gap out", func(t *testing.T) {
+		sections := SplitMarkdown("# Guide\n\n### Deep\ntext\n\n## Next\nmore\n")
+		if len(sections) != 2 || sections[0].Breadcrumb() != "Guide > Deep" || sections[1].Breadcrumb() != "Guide > Next" {
+			t.Errorf("sections = %+v", sections)
+		}
+	})
+
+	t.Run("Given no headings, When splitting, Then the document is one section", func(t *testing.T) {
+		sections := SplitMarkdown("just text\n\nmore text\n")
+		if len(sections) != 1 || sections[0].Title != "(Document)" || sections[0].EndLine != 3 {
+			t.Errorf("sections = %+v", sections)
+		}
+		sections = SplitMarkdown("# Title only\n")
+		if len(sections) != 1 || sections[0].Content != "# Title only" {
+			t.Errorf("headings only = %+v", sections)
+		}
+	})
+}
+
+func TestChunkMarkdown(t *testing.T) {
+	t.Run("Given a long section, When chunking, Then it is split between blocks and never inside a fence or table", func(t *testing.T) {
+		fence := "```go\n" + strings.Repeat("fmt.Println(\"x\")\n\n", 20) + "```"
+		table := "| a | b |\n|---|---|\n" + strings.Repeat("| 1 | 2 |\n", 20)
+		doc := "# Guide\n\n" + strings.Repeat("word ", 20) + "\n\n" + fence + "\n\n" + table + "\n" + strings.Repeat("tail ", 20) + "\n"
+
+		chunks := ChunkMarkdown(doc, 120)
+		if len(chunks) != 4 {
+			t.Fatalf("got %d chunks, want paragraph, fence, table and paragraph: %+v", len(chunks), chunks)
+		}
+		if chunks[1].Content != fence {
+			t.Errorf("fence chunk = %q", chunks[1].Content)
+		}
+		if chunks[2].Content != strings.TrimSpace(table) {
+			t.Errorf("table chunk = %q", chunks[2].Content)
+		}
+		for _, c := range chunks {
+			if c.Breadcrumb() != "Guide" {
+				t.Errorf("chunk breadcrumb = %q, want Guide", c.Breadcrumb())
+			}
+			lines := strings.Split(doc, "\n")
+			if first := lines[c.StartLine-1]; !strings.HasPrefix(c.Content, strings.TrimSpace(first)) {
+				t.Errorf("chunk starts at line %d %q, content starts %q", c.StartLine, first, c.Content[:10])
+			}
+		}
+	})
+
+	t.Run("Given small sections, When chunking, Then they are kept whole", func(t *testing.T) {
+		chunks := ChunkMarkdown(runbookDoc, 2000)
+		if len(chunks) != 4 {
+			t.Errorf("got %d chunks, want 4", len(chunks))
+		}
+	})
+}
+
+func TestSplitFrontMatter(t *testing.T) {
+	t.Run("Given front matter, When splitting, Then fields and the body offset are returned", func(t *testing.T) {
+		fields, offset := SplitFrontMatter(runbookDoc)
+		if fields["title"] != "Payments runbook" {
+			t.Errorf("title = %v", fields["title"])
+		}
+		if owner, _ := fields["owner"].(map[string]any); owner["team"] != "billing" {
+			t.Errorf("owner = %v", fields["owner"])
+		}
+		if !strings.HasPrefix(runbookDoc[offset:], "Read this first.") {
+			t.Errorf("body = %q", runbookDoc[offset:offset+20])
+		}
+		if tags := FrontMatterTags(fields); !reflect.DeepEqual(tags, []string{"payments", "oncall", "rollback"}) {
+			t.Errorf("tags = %q", tags)
+		}
+	})
+
+	t.Run("Given no or broken front matter, When splitting, Then nothing is returned", func(t *testing.T) {
+		for _, doc := range []string{"# Title\n", "---\ntitle: x\n", "---\n: : bad\n---\n", "----\nx\n"} {
+			if fields, offset := SplitFrontMatter(doc); fields != nil || offset != 0 {
+				t.Errorf("SplitFrontMatter(%q) = %v, %d", doc, fields, offset)
+			}
+		}
+	})
+}
@@ -0,0 +1,279 @@
+package chunking
+
+import (
+	"sort"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// DefaultSplitOverlapLines is the number of lines a split part repeats from
+// the end of the part before it when SizeOptions.OverlapLines is unset.
+const DefaultSplitOverlapLines = 3
+
+// bytesPerToken approximates tokenizer output for code. BERT-style
+// tokenizers split code finely, so this errs towards smaller chunks.
+const bytesPerToken = 3
+
+// SizeOptions bounds code chunk sizes in estimated tokens (see
+// EstimateTokens).
+type SizeOptions struct {
+	// MaxTokens is the largest chunk. Larger declarations are split on
+	// statement or block boundaries; adjacent siblings are merged up to
+	// it. 0 disables sizing.
+	MaxTokens int
+
+	// MinTokens marks chunks as tiny: adjacent sibling chunks of the same
+	// type that are each smaller are merged. 0 uses MaxTokens/8.
+	MinTokens int
+
+	// OverlapLines is the number of lines each split part repeats from the
+	// part before it. 0 uses DefaultSplitOverlapLines; negative disables.
+	OverlapLines int
+}
+
+func (o SizeOptions) minTokens() int {
+	if o.MinTokens > 0 {
+		return o.MinTokens
+	}
+	return o.MaxTokens / 8
+}
+
+func (o SizeOptions) overlapLines() int {
+	switch {
+	case o.OverlapLines < 0:
+		return 0
+	case o.OverlapLines == 0:
+		return DefaultSplitOverlapLines
+	default:
+		return o.OverlapLines
+	}
+}
+
+// EstimateTokens approximates the number of tokens an embedding model
+// reads for text, without a model-specific tokenizer.
+func EstimateTokens(text string) int {
+	return estimateBytes(len(text))
+}
+
+func estimateBytes(n int) int {
+	return (n + bytesPerToken - 1) / bytesPerToken
+}
+
+// sizeChunks merges tiny siblings and splits oversized chunks. Without a
+// size budget it returns the chunks as extracted.
+func (c *ASTChunker) sizeChunks(found []foundChunk, content []byte) []CodeChunk {
+	if c.size.MaxTokens <= 0 {
+		chunks := make([]CodeChunk, len(found))
+		for i, f := range found {
+			chunks[i] = f.chunk
+		}
+		return chunks
+	}
+
+	var chunks []CodeChunk
+	for _, f := range c.mergeSiblings(found, content) {
+		chunks = append(chunks, c.splitChunk(f)...)
+	}
+	return chunks
+}
+
+// mergeSiblings merges runs of adjacent tiny sibling chunks of one type,
+// such as a block of getters, into a chunk spanning them, while it stays
+// within MaxTokens. Merged chunks have no node and keep the position of
+// their first member.
+func (c *ASTChunker) mergeSiblings(found []foundChunk, content []byte) []foundChunk {
+	tiny := func(f foundChunk) bool {
+		return f.node != nil && EstimateTokens(f.chunk.Content) < c.size.minTokens()
+	}
+
+	// next[i] is the index of the next chunk with the same parent
+	next := make([]int, len(found))
+	last := make(map[int]int)
+	for i := len(found) - 1; i >= 0; i-- {
+		next[i] = -1
+		if j, ok := last[found[i].parent]; ok {
+			next[i] = j
+		}
+		last[found[i].parent] = i
+	}
+
+	removed := make([]bool, len(found))
+	var merged []foundChunk
+	for i, f := range found {
+		if removed[i] {
+			continue
+		}
+		run := []foundChunk{f}
+		for j := next[i]; j >= 0 && tiny(f) && tiny(found[j]) && found[j].chunk.Type == f.chunk.Type; j = next[j] {
+			span := found[j].node.EndByte() - f.node.StartByte()
+			if estimateBytes(int(span)) > c.size.MaxTokens {
+				break
+			}
+			run = append(run, found[j])
+			removed[j] = true
+		}
+		if len(run) == 1 {
+			merged = append(merged, f)
+			continue
+		}
+		merged = append(merged, foundChunk{chunk: mergeChunks(run, content), parent: f.parent})
+	}
+	return merged
+}
+
+// mergeChunks combines sibling chunks into one spanning them, including
+// any code between them.
+func mergeChunks(run []foundChunk, content []byte) CodeChunk {
+	first, last := run[0], run[len(run)-1]
+	merged := first.chunk
+	merged.Content = string(content[first.node.StartByte():last.node.EndByte()])
+	merged.EndLine = last.chunk.EndLine
+	merged.Symbol = ""
+
+	var names, signatures, docs []string
+	seenCalls := make(map[string]bool)
+	merged.Calls = nil
+	for _, f := range run {
+		ch := f.chunk
+		names = append(names, ch.Name)
+		signatures = append(signatures, ch.Signature)
+		if ch.Doc != "" {
+			docs = append(docs, ch.Doc)
+		}
+		if ch.Receiver != merged.Receiver {
+			merged.Receiver = ""
+		}
+		symbol := ch.Symbol
+		if symbol == "" {
+			symbol = ch.Name
+		}
+		merged.Merged = append(merged.Merged, symbol)
+		for _, call := range ch.Calls {
+			if !seenCalls[call] {
+				seenCalls[call] = true
+				merged.Calls = append(merged.Calls, call)
+			}
+		}
+	}
+	merged.Name = strings.Join(names, ", ")
+	merged.Signature = strings.Join(signatures, "\n")
+	merged.Doc = strings.Join(docs, "\n\n")
+	return merged
+}
+
+// splitChunk splits a chunk over MaxTokens into parts that end on
+// statement or block boundaries where possible. Parts after the first
+// repeat the signature as a header and overlap the previous part by
+// OverlapLines.
+func (c *ASTChunker) splitChunk(f foundChunk) []CodeChunk {
+	chunk := f.chunk
+	if f.node == nil || EstimateTokens(chunk.Content) <= c.size.MaxTokens {
+		return []CodeChunk{chunk}
+	}
+
+	lines := strings.Split(chunk.Content, "\n")
+	// offsets[i] is the byte length of lines[:i] joined
+	offsets := make([]int, len(lines)+1)
+	for i, line := range lines {
+		offsets[i+1] = offsets[i] + len(line) + 1
+	}
+	tokens := func(start, end int) int {
+		return estimateBytes(offsets[end] - offsets[start] - 1)
+	}
+
+	// A signature taking half the budget leaves too little room to repeat
+	header := chunk.Signature
+	headerTokens := EstimateTokens(header) + 1
+	if headerTokens >= c.size.MaxTokens/2 {
+		header, headerTokens = "", 0
+	}
+
+	boundaries := c.splitBoundaries(f.node)
+	overlap := c.size.overlapLines()
+
+	var parts []CodeChunk
+	for start := 0; start < len(lines); {
+		budget := c.size.MaxTokens
+		if start > 0 {
+			budget -= headerTokens
+		}
+
+		// Furthest boundary that fits, else as many lines as fit
+		end := -1
+		if tokens(start, len(lines)) <= budget {
+			end = len(lines)
+		} else {
+			for _, b := range boundaries {
+				if b <= start {
+					continue
+				}
+				if tokens(start, b) > budget {
+					break
+				}
+				end = b
+			}
+			if end < 0 {
+				end = start + 1
+				for end < len(lines) && tokens(start, end+1) <= budget {
+					end++
+				}
+			}
+		}
+
+		part := chunk
+		part.Content = strings.Join(lines[start:end], "\n")
+		if start > 0 && header != "" {
+			part.Content = header + "\n" + part.Content
+		}
+		part.StartLine = chunk.StartLine + start
+		part.EndLine = chunk.StartLine + end - 1
+		parts = append(parts, part)
+
+		if end == len(lines) {
+			break
+		}
+		if next := end - overlap; next > start+1 {
+			start = next
+		} else {
+			start = end
+		}
+	}
+
+	for i := range parts {
+		parts[i].Part = i + 1
+		parts[i].Parts = len(parts)
+	}
+	return parts
+}
+
+// splitBoundaries returns the line offsets within node where its
+// statements or members start. Statements too large for a part contribute
+// the boundaries of their own blocks, so a long loop can still be split.
+func (c *ASTChunker) splitBoundaries(node *sitter.Node) []int {
+	seen := make(map[int]bool)
+	var rows []int
+	origin := int(node.StartPoint().Row)
+
+	var collect func(n *sitter.Node)
+	collect = func(n *sitter.Node) {
+		body := n.ChildByFieldName("body")
+		if body == nil {
+			body = n
+		}
+		for i := 0; i < int(body.NamedChildCount()); i++ {
+			child := body.NamedChild(i)
+			if row := int(child.StartPoint().Row) - origin; row > 0 && !seen[row] {
+				seen[row] = true
+				rows = append(rows, row)
+			}
+			if estimateBytes(int(child.EndByte()-child.StartByte())) > c.size.MaxTokens {
+				collect(child)
+			}
+		}
+	}
+	collect(node)
+
+	sort.Ints(rows)
+	return rows
+}
@@ -0,0 +1,139 @@
+package chunking
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// longGoFunc returns a Go function with n statements, optionally inside a
+// single loop.
+func longGoFunc(n int, inLoop bool) string {
+	var b strings.Builder
+	b.WriteString("package p\n\nfunc Handle(req Request) error {\n")
+	indent := "\t"
+	if inLoop {
+		b.WriteString("\tfor _, item := range req.Items {\n")
+		indent = "\t\t"
+	}
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "%sstep%02d := process(item, %d, \"some padding text\")\n", indent, i, i)
+	}
+	if inLoop {
+		b.WriteString("\t}\n")
+	}
+	b.WriteString("\treturn nil\n}\n")
+	return b.String()
+}
+
+func TestASTChunker_SizeOptions(t *testing.T) {
+	t.Run("Given a function over budget, When chunking, Then it is split on statements with header and overlap", func(t *testing.T) {
+		chunker := NewASTChunker(WithSizeOptions(SizeOptions{MaxTokens: 100, OverlapLines: 1}))
+		chunks, err := chunker.ChunkFile([]byte(longGoFunc(40, false)), "go", "p.go")
+		if err != nil {
+			t.Fatalf("ChunkFile: %v", err)
+		}
+		if len(chunks) < 3 {
+			t.Fatalf("got %d chunks, want the function split into several", len(chunks))
+		}
+
+		for i, ch := range chunks {
+			if ch.Part != i+1 || ch.Parts != len(chunks) {
+				t.Errorf("chunk %d part = %d/%d, want %d/%d", i, ch.Part, ch.Parts, i+1, len(chunks))
+			}
+			if got := EstimateTokens(ch.Content); got > 100 {
+				t.Errorf("part %d has %d tokens, over budget", ch.Part, got)
+			}
+			if ch.Symbol != "p.Handle" || ch.Type != "function" {
+				t.Errorf("part %d = %s %s, want function p.Handle", ch.Part, ch.Type, ch.Symbol)
+			}
+			if i == 0 {
+				continue
+			}
+			lines := strings.Split(ch.Content, "\n")
+			if lines[0] != "func Handle(req Request) error {" {
+				t.Errorf("part %d header = %q, want signature", ch.Part, lines[0])
+			}
+			if !strings.HasPrefix(lines[1], "\tstep") {
+				t.Errorf("part %d starts mid-statement: %q", ch.Part, lines[1])
+			}
+			if prev := chunks[i-1]; ch.StartLine != prev.EndLine {
+				t.Errorf("part %d starts at line %d, want one line overlap with part ending at %d", ch.Part, ch.StartLine, prev.EndLine)
+			}
+		}
+		if first, last := chunks[0], chunks[len(chunks)-1]; first.StartLine != 3 || last.EndLine != 45 {
+			t.Errorf("parts cover lines %d-%d, want 3-45", first.StartLine, last.EndLine)
+		}
+	})
+
+	t.Run("Given one oversized loop, When chunking, Then it is split inside the loop", func(t *testing.T) {
+		chunker := NewASTChunker(WithSizeOptions(SizeOptions{MaxTokens: 100, OverlapLines: -1}))
+		chunks, _ := chunker.ChunkFile([]byte(longGoFunc(40, true)), "go", "p.go")
+		if len(chunks) < 3 {
+			t.Fatalf("got %d chunks, want the loop split", len(chunks))
+		}
+		for _, ch := range chunks[1:] {
+			if lines := strings.Split(ch.Content, "\n"); !strings.HasPrefix(lines[1], "\t\tstep") && !strings.HasPrefix(lines[1], "\t}") {
+				t.Errorf("part %d starts mid-statement: %q", ch.Part, lines[1])
+			}
+		}
+		for i := 1; i < len(chunks); i++ {
+			if chunks[i].StartLine != chunks[i-1].EndLine+1 {
+				t.Errorf("part %d starts at %d, want %d without overlap", chunks[i].Part, chunks[i].StartLine, chunks[i-1].EndLine+1)
+			}
+		}
+	})
+
+	t.Run("Given tiny sibling methods, When chunking, Then they are merged into one chunk", func(t *testing.T) {
+		src := `class Point {
+    int x, y;
+
+    int getX() { return x; }
+    int getY() { return y; }
+    void reset() { clear(); }
+}
+`
+		chunker := NewASTChunker(WithSizeOptions(SizeOptions{MaxTokens: 400, MinTokens: 20}))
+		chunks, _ := chunker.ChunkFile([]byte(src), "java", "Point.java")
+		if got, want := chunkSummary(chunks), []string{"class Point", "method getX, getY, reset"}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("chunks = %q, want %q", got, want)
+		}
+		m := chunks[1]
+		if want := []string{"Point.getX", "Point.getY", "Point.reset"}; !reflect.DeepEqual(m.Merged, want) {
+			t.Errorf("merged = %q, want %q", m.Merged, want)
+		}
+		if m.StartLine != 4 || m.EndLine != 6 || m.Symbol != "" {
+			t.Errorf("merged chunk lines %d-%d symbol %q, want 4-6 and no symbol", m.StartLine, m.EndLine, m.Symbol)
+		}
+		if want := []string{"clear"}; !reflect.DeepEqual(m.Calls, want) {
+			t.Errorf("calls = %q, want %q", m.Calls, want)
+		}
+	})
+
+	t.Run("Given tiny siblings of different types or over the budget together, When chunking, Then they stay apart", func(t *testing.T) {
+		src := `package p
+
+type ID string
+
+func a() int { return 1 }
+func b() int { return 2 }
+func c() int { return 3 }
+`
+		chunker := NewASTChunker(WithSizeOptions(SizeOptions{MaxTokens: 20, MinTokens: 15}))
+		chunks, _ := chunker.ChunkFile([]byte(src), "go", "p.go")
+		if got, want := chunkSummary(chunks), []string{"type ID", "function a, b", "function c"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("chunks = %q, want %q", got, want)
+		}
+	})
+}
+
+func TestEstimateTokens(t *testing.T) {
+	t.Run("Given text, When estimating, Then rounds bytes per token up", func(t *testing.T) {
+		for text, want := range map[string]int{"": 0, "a": 1, "abc": 1, "abcd": 2} {
+			if got := EstimateTokens(text); got != want {
+				t.Errorf("EstimateTokens(%q) = %d, want %d", text, got, want)
+			}
+		}
+	})
+}
@@ -0,0 +1,225 @@
+package chunking
+
+import (
+	"strings"
+	"unicode"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// wrapperTypes are nodes that wrap a declaration without being part of
+// it, so its doc comment precedes the wrapper.
+var wrapperTypes = map[string]bool{
+	"decorated_definition": true, // Python decorators
+	"export_statement":     true, // TypeScript/JavaScript export
+}
+
+// attributeTypes may sit between a doc comment and its declaration.
+var attributeTypes = map[string]bool{
+	"attribute_item": true, // Rust #[derive(...)]
+}
+
+// packageName returns the package a file declares (Go package clause, Java
+// package, C# file-scoped namespace), or "".
+func packageName(root *sitter.Node, spec languageSpec, content []byte) string {
+	for i := 0; i < int(root.NamedChildCount()); i++ {
+		child := root.NamedChild(i)
+		if !spec.packages[child.Type()] {
+			continue
+		}
+		if name := child.ChildByFieldName("name"); name != nil {
+			return name.Content(content)
+		}
+		if child.NamedChildCount() > 0 {
+			return child.NamedChild(0).Content(content)
+		}
+	}
+	return ""
+}
+
+// extractImports returns the file's imports in source order. Imports with
+// a path field (Go import specs) are recorded as the bare path; others as
+// their statement text, e.g. "use std::io" or "#include <stdio.h>".
+func extractImports(root *sitter.Node, spec languageSpec, content []byte) []string {
+	if len(spec.imports) == 0 {
+		return nil
+	}
+
+	var imports []string
+	seen := make(map[string]bool)
+	var walk func(*sitter.Node)
+	walk = func(n *sitter.Node) {
+		if spec.imports[n.Type()] && n.IsNamed() {
+			var imp string
+			if path := n.ChildByFieldName("path"); path != nil {
+				imp = strings.Trim(path.Content(content), "\"`")
+			} else {
+				imp = strings.TrimSuffix(strings.Join(strings.Fields(n.Content(content)), " "), ";")
+			}
+			if imp != "" && !seen[imp] {
+				seen[imp] = true
+				imports = append(imports, imp)
+			}
+			return
+		}
+		for i := 0; i < int(n.NamedChildCount()); i++ {
+			walk(n.NamedChild(i))
+		}
+	}
+	walk(root)
+	return imports
+}
+
+// receiverType returns a Go method's receiver type without pointer or type
+// parameters ("Service" for `func (s *Service[T]) Refund()`), or "" for
+// other nodes.
+func receiverType(node *sitter.Node, content []byte) string {
+	if node.Type() != "method_declaration" {
+		return ""
+	}
+	recv := node.ChildByFieldName("receiver")
+	if recv == nil || recv.NamedChildCount() == 0 {
+		return ""
+	}
+	typ := recv.NamedChild(0).ChildByFieldName("type")
+	if typ == nil {
+		return ""
+	}
+	name := strings.TrimLeft(typ.Content(content), "*")
+	if i := strings.Index(name, "["); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// docComment returns the documentation of a declaration: a Python-style
+// docstring, or the comment lines directly above it with comment markers
+// stripped. Attributes between the comment and the declaration are
+// skipped; a blank line or a trailing comment on code ends the comment.
+func docComment(node *sitter.Node, spec languageSpec, content []byte) string {
+	if spec.docstrings {
+		if doc := docstring(node, content); doc != "" {
+			return doc
+		}
+	}
+
+	anchor := node
+	for p := anchor.Parent(); p != nil && wrapperTypes[p.Type()]; p = p.Parent() {
+		anchor = p
+	}
+
+	var comments []string
+	row := anchor.StartPoint().Row
+	for s := anchor.PrevNamedSibling(); s != nil; s = s.PrevNamedSibling() {
+		if s.EndPoint().Row+1 < row {
+			break
+		}
+		if attributeTypes[s.Type()] {
+			row = s.StartPoint().Row
+			continue
+		}
+		if !strings.Contains(s.Type(), "comment") {
+			break
+		}
+		// a comment trailing code on the same line belongs to that code
+		if prev := s.PrevNamedSibling(); prev != nil && prev.EndPoint().Row == s.StartPoint().Row && !strings.Contains(prev.Type(), "comment") {
+			break
+		}
+		comments = append([]string{cleanComment(s.Content(content))}, comments...)
+		row = s.StartPoint().Row
+	}
+	return strings.TrimSpace(strings.Join(comments, "\n"))
+}
+
+// docstring returns the string literal opening a definition's body.
+func docstring(node *sitter.Node, content []byte) string {
+	body := node.ChildByFieldName("body")
+	if body == nil || body.NamedChildCount() == 0 {
+		return ""
+	}
+	first := body.NamedChild(0)
+	if first.Type() != "expression_statement" || first.NamedChildCount() == 0 || first.NamedChild(0).Type() != "string" {
+		return ""
+	}
+	text := strings.TrimLeft(first.NamedChild(0).Content(content), "rRbBuUfF")
+	text = strings.Trim(text, `"'`)
+
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// cleanComment strips comment markers (//, ///, #, /* */ and the leading *
+// of block comment lines) from one comment.
+func cleanComment(comment string) string {
+	block := strings.HasPrefix(strings.TrimSpace(comment), "/*")
+	lines := strings.Split(strings.TrimSpace(comment), "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		for _, marker := range []string{"///", "//!", "//", "/**", "/*", "#"} {
+			if strings.HasPrefix(line, marker) {
+				line = line[len(marker):]
+				break
+			}
+		}
+		if block {
+			line = strings.TrimPrefix(strings.TrimSpace(strings.TrimSuffix(line, "*/")), "*")
+		}
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// extractCalls returns the names of the functions and methods called
+// within a node, in order of first call. Only the last component of a
+// qualified callee is kept: fmt.Errorf and s.store.Save record Errorf and
+// Save.
+func extractCalls(node *sitter.Node, spec languageSpec, content []byte) []string {
+	if len(spec.calls) == 0 {
+		return nil
+	}
+
+	var calls []string
+	seen := make(map[string]bool)
+	var walk func(*sitter.Node)
+	walk = func(n *sitter.Node) {
+		if field, ok := spec.calls[n.Type()]; ok {
+			if callee := n.ChildByFieldName(field); callee != nil {
+				if name := calleeName(callee.Content(content)); name != "" && !seen[name] {
+					seen[name] = true
+					calls = append(calls, name)
+				}
+			}
+		}
+		for i := 0; i < int(n.NamedChildCount()); i++ {
+			walk(n.NamedChild(i))
+		}
+	}
+	walk(node)
+	return calls
+}
+
+// calleeName reduces a callee expression to the identifier being called,
+// or "" if it has none (a call through an index or a closure).
+func calleeName(expr string) string {
+	for _, sep := range []string{"->", "::", "."} {
+		if i := strings.LastIndex(expr, sep); i >= 0 {
+			expr = expr[i+len(sep):]
+		}
+	}
+	if i := strings.IndexAny(expr, "<[("); i >= 0 {
+		expr = expr[:i]
+	}
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return ""
+	}
+	for _, r := range expr {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("_$!?", r) {
+			return ""
+		}
+	}
+	return expr
+}
@@ -1,34 +1,54 @@
 package chunking
 
+import "strings"
+
 // CodeChunk represents an AST-extracted code chunk
 type CodeChunk struct {
 	Content   string   `json:"content"`
-	Type      string   `json:"type"`      // function, class, method, type
+	Type      string   `json:"type"`      // function, method, class, struct, interface, impl, enum, module, type
 	Name      string   `json:"name"`
 	StartLine int      `json:"start_line"`
 	EndLine   int      `json:"end_line"`
 	FilePath  string   `json:"file_path"`
 	Signature string   `json:"signature,omitempty"` // Function signature
-	Imports   []string `json:"imports,omitempty"`
+	Imports   []string `json:"imports,omitempty"`   // The file's imports
+	Symbol    string   `json:"symbol,omitempty"`    // Qualified name, e.g. payments.Service.Refund
+	Receiver  string   `json:"receiver,omitempty"`  // Go method receiver type
+	Doc       string   `json:"doc,omitempty"`       // Doc comment or docstring
+	Calls     []string `json:"calls,omitempty"`     // Names of called functions and methods
+	Part      int      `json:"part,omitempty"`      // 1-based part of a split declaration; 0 if whole
+	Parts     int      `json:"parts,omitempty"`     // Number of parts the declaration was split into
+	Merged    []string `json:"merged,omitempty"`    // Symbols of the tiny siblings merged into this chunk
 	Language  string   `json:"language"`
 }
 
 // DocChunk represents a contextually enriched document chunk
 type DocChunk struct {
-	OriginalContent string `json:"original_content"`
-	Context         string `json:"context"`         // Generated by Haiku
-	EnrichedContent string `json:"enriched_content"` // Context + Original
-	FilePath        string `json:"file_path"`
-	Section         string `json:"section"`
-	StartLine       int    `json:"start_line"`
-	EndLine         int    `json:"end_line"`
+	OriginalContent string   `json:"original_content"`
+	Context         string   `json:"context"`          // Generated by Haiku
+	EnrichedContent string   `json:"enriched_content"` // Context + Original
+	FilePath        string   `json:"file_path"`
+	Section         string   `json:"section"`            // breadcrumb, e.g. "Runbook > Payments > Rollback"
+	Headings        []string `json:"headings,omitempty"` // headings the breadcrumb joins
+	StartLine       int      `json:"start_line"`
+	EndLine         int      `json:"end_line"`
 }
 
 // MarkdownSection represents a section extracted from markdown
 type MarkdownSection struct {
 	Title     string
+	Headings  []string // headings from the top level down to Title; empty before the first heading
 	Content   string
 	Level     int
 	StartLine int
 	EndLine   int
 }
+
+// Breadcrumb returns the section's headings joined by BreadcrumbSeparator,
+// e.g. "Runbook > Payments > Rollback", or its title outside any heading.
+func (s MarkdownSection) Breadcrumb() string {
+	if len(s.Headings) == 0 {
+		return s.Title
+	}
+	return strings.Join(s.Headings, BreadcrumbSeparator)
+}
@@ -0,0 +1,531 @@
+package codegraph
+
+import (
+	"fmt"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/golang"
+)
+
+// predeclared are Go's predeclared identifiers, which never refer to a
+// package's symbols.
+var predeclared = map[string]bool{
+	"any": true, "bool": true, "byte": true, "comparable": true,
+	"complex64": true, "complex128": true, "error": true, "float32": true,
+	"float64": true, "int": true, "int8": true, "int16": true, "int32": true,
+	"int64": true, "rune": true, "string": true, "uint": true, "uint8": true,
+	"uint16": true, "uint32": true, "uint64": true, "uintptr": true,
+	"true": true, "false": true, "iota": true, "nil": true,
+	"append": true, "cap": true, "clear": true, "close": true, "complex": true,
+	"copy": true, "delete": true, "imag": true, "len": true, "make": true,
+	"max": true, "min": true, "new": true, "panic": true, "print": true,
+	"println": true, "real": true, "recover": true,
+}
+
+// ExtractGo parses a Go source file and returns its symbol graph. filePath
+// locates the go.mod that determines the package's import path (see
+// PackagePath).
+func ExtractGo(content []byte, filePath string) (*File, error) {
+	parser := sitter.NewParser()
+	defer parser.Close()
+	parser.SetLanguage(golang.GetLanguage())
+
+	tree := parser.Parse(nil, content)
+	if tree == nil {
+		return nil, fmt.Errorf("parse %s: no syntax tree", filePath)
+	}
+	defer tree.Close()
+	root := tree.RootNode()
+
+	x := &extractor{content: content, imports: make(map[string]string)}
+	for i := 0; i < int(root.NamedChildCount()); i++ {
+		child := root.NamedChild(i)
+		switch child.Type() {
+		case "package_clause":
+			if child.NamedChildCount() > 0 {
+				x.file.Package = PackagePath(filePath, x.text(child.NamedChild(0)))
+			}
+		case "import_declaration":
+			x.addImports(child)
+		}
+	}
+	if x.file.Package == "" {
+		return nil, fmt.Errorf("%s has no package clause", filePath)
+	}
+
+	for i := 0; i < int(root.NamedChildCount()); i++ {
+		x.declaration(root.NamedChild(i))
+	}
+	return &x.file, nil
+}
+
+// extractor accumulates the graph of one file.
+type extractor struct {
+	content []byte
+	file    File
+	// imports maps the names packages are used by to their import paths.
+	imports map[string]string
+}
+
+// scope holds the names declared within one declaration, which shadow
+// package-level names, with the type of those whose type is written in
+// the source ("" otherwise). Nested blocks share their function's scope.
+type scope struct {
+	from   string
+	locals map[string]string
+}
+
+func newScope(from string) *scope {
+	return &scope{from: from, locals: make(map[string]string)}
+}
+
+func (x *extractor) text(n *sitter.Node) string {
+	if n == nil {
+		return ""
+	}
+	return n.Content(x.content)
+}
+
+func (x *extractor) qualify(name string) string {
+	return x.file.Package + "." + name
+}
+
+func (x *extractor) addImports(n *sitter.Node) {
+	if n.Type() != "import_spec" {
+		for i := 0; i < int(n.NamedChildCount()); i++ {
+			x.addImports(n.NamedChild(i))
+		}
+		return
+	}
+
+	importPath := strings.Trim(x.text(n.ChildByFieldName("path")), "\"`")
+	alias := x.text(n.ChildByFieldName("name"))
+	x.file.Imports = append(x.file.Imports, Import{Path: importPath, Alias: alias})
+	switch alias {
+	case ".", "_":
+	case "":
+		x.imports[importName(importPath)] = importPath
+	default:
+		x.imports[alias] = importPath
+	}
+}
+
+// declaration records a top-level declaration and the references in it.
+func (x *extractor) declaration(n *sitter.Node) {
+	switch n.Type() {
+	case "function_declaration":
+		name := x.text(n.ChildByFieldName("name"))
+		id := x.qualify(name)
+		x.addSymbol(n, id, name, KindFunction, "")
+		x.function(n, id)
+
+	case "method_declaration":
+		name := x.text(n.ChildByFieldName("name"))
+		parent := x.qualify(x.receiverType(n.ChildByFieldName("receiver")))
+		id := parent + "." + name
+		x.addSymbol(n, id, name, KindMethod, parent)
+		x.function(n, id)
+
+	case "type_declaration":
+		for i := 0; i < int(n.NamedChildCount()); i++ {
+			if spec := n.NamedChild(i); spec.Type() == "type_spec" || spec.Type() == "type_alias" {
+				x.typeSpec(spec)
+			}
+		}
+
+	case "const_declaration", "var_declaration":
+		kind := KindVar
+		if n.Type() == "const_declaration" {
+			kind = KindConst
+		}
+		for i := 0; i < int(n.NamedChildCount()); i++ {
+			spec := n.NamedChild(i)
+			if spec.Type() != "const_spec" && spec.Type() != "var_spec" {
+				continue
+			}
+			var from string
+			for _, name := range x.fieldNodes(spec, "name") {
+				id := x.qualify(x.text(name))
+				x.addSymbol(spec, id, x.text(name), kind, "")
+				if from == "" {
+					from = id
+				}
+			}
+			sc := newScope(from)
+			x.refs(spec.ChildByFieldName("type"), sc)
+			x.refs(spec.ChildByFieldName("value"), sc)
+		}
+	}
+}
+
+// function records the references in a function or method, with its
+// parameters in scope.
+func (x *extractor) function(n *sitter.Node, id string) {
+	sc := newScope(id)
+	x.params(n.ChildByFieldName("receiver"), sc)
+	x.params(n.ChildByFieldName("type_parameters"), sc)
+	x.params(n.ChildByFieldName("parameters"), sc)
+	if result := n.ChildByFieldName("result"); result != nil && result.Type() == "parameter_list" {
+		x.params(result, sc)
+	} else {
+		x.refs(result, sc)
+	}
+	x.refs(n.ChildByFieldName("body"), sc)
+}
+
+// params declares the parameters in a parameter or type parameter list.
+func (x *extractor) params(list *sitter.Node, sc *scope) {
+	if list == nil {
+		return
+	}
+	for i := 0; i < int(list.NamedChildCount()); i++ {
+		param := list.NamedChild(i)
+		typ := param.ChildByFieldName("type")
+		typeID := x.typeID(typ, sc)
+		for _, name := range x.fieldNodes(param, "name") {
+			sc.locals[x.text(name)] = typeID
+		}
+		x.refs(typ, sc)
+	}
+}
+
+func (x *extractor) typeSpec(spec *sitter.Node) {
+	name := x.text(spec.ChildByFieldName("name"))
+	id := x.qualify(name)
+	typ := spec.ChildByFieldName("type")
+
+	kind := KindType
+	switch typ.Type() {
+	case "struct_type":
+		kind = KindStruct
+	case "interface_type":
+		kind = KindInterface
+	}
+	x.addSymbol(spec, id, name, kind, "")
+
+	sc := newScope(id)
+	x.params(spec.ChildByFieldName("type_parameters"), sc)
+	switch kind {
+	case KindStruct:
+		x.structFields(typ, sc)
+	case KindInterface:
+		x.interfaceElems(typ, id, sc)
+	default:
+		x.refs(typ, sc)
+	}
+}
+
+// structFields records field types, with embedded fields as embeds.
+func (x *extractor) structFields(typ *sitter.Node, sc *scope) {
+	for i := 0; i < int(typ.NamedChildCount()); i++ {
+		list := typ.NamedChild(i)
+		for j := 0; j < int(list.NamedChildCount()); j++ {
+			field := list.NamedChild(j)
+			fieldType := field.ChildByFieldName("type")
+			if field.ChildByFieldName("name") == nil {
+				if x.embed(fieldType, sc) {
+					continue
+				}
+			}
+			x.refs(fieldType, sc)
+		}
+	}
+}
+
+// interfaceElems records an interface's methods and embedded interfaces.
+func (x *extractor) interfaceElems(typ *sitter.Node, id string, sc *scope) {
+	for i := 0; i < int(typ.NamedChildCount()); i++ {
+		elem := typ.NamedChild(i)
+		switch elem.Type() {
+		case "method_elem", "method_spec":
+			name := x.text(elem.ChildByFieldName("name"))
+			x.addSymbol(elem, id+"."+name, name, KindMethod, id)
+			x.refs(elem.ChildByFieldName("parameters"), sc)
+			x.refs(elem.ChildByFieldName("result"), sc)
+		case "type_elem", "constraint_elem":
+			// a union such as ~int | ~string constrains rather than embeds
+			if elem.NamedChildCount() == 1 && x.embed(elem.NamedChild(0), sc) {
+				continue
+			}
+			x.refs(elem, sc)
+		default:
+			x.refs(elem, sc)
+		}
+	}
+}
+
+// embed records an embedded type, reporting false if it does not name a
+// package-level type.
+func (x *extractor) embed(typ *sitter.Node, sc *scope) bool {
+	id := x.typeID(typ, sc)
+	if id == "" {
+		return false
+	}
+	x.addRef(typ, id, id[strings.LastIndex(id, ".")+1:], RefEmbed, sc)
+	return true
+}
+
+// receiverType returns the name of a method's receiver type without
+// pointer or type parameters.
+func (x *extractor) receiverType(recv *sitter.Node) string {
+	if recv == nil || recv.NamedChildCount() == 0 {
+		return ""
+	}
+	typ := recv.NamedChild(0).ChildByFieldName("type")
+	for typ != nil {
+		switch typ.Type() {
+		case "pointer_type", "parenthesized_type":
+			typ = typ.NamedChild(0)
+		case "generic_type":
+			typ = typ.ChildByFieldName("type")
+		default:
+			return x.text(typ)
+		}
+	}
+	return ""
+}
+
+// typeID returns the ID of the named type a type expression refers to,
+// ignoring pointers and type arguments, or "" for predeclared types, type
+// parameters and type literals.
+func (x *extractor) typeID(typ *sitter.Node, sc *scope) string {
+	if typ == nil {
+		return ""
+	}
+	switch typ.Type() {
+	case "type_identifier":
+		name := x.text(typ)
+		if _, local := sc.locals[name]; local || predeclared[name] {
+			return ""
+		}
+		return x.qualify(name)
+	case "qualified_type":
+		if importPath, ok := x.imports[x.text(typ.ChildByFieldName("package"))]; ok {
+			return importPath + "." + x.text(typ.ChildByFieldName("name"))
+		}
+	case "pointer_type", "parenthesized_type", "variadic_parameter_declaration":
+		return x.typeID(typ.NamedChild(0), sc)
+	case "generic_type":
+		return x.typeID(typ.ChildByFieldName("type"), sc)
+	}
+	return ""
+}
+
+// refs records the references within a node.
+func (x *extractor) refs(n *sitter.Node, sc *scope) {
+	if n == nil {
+		return
+	}
+
+	switch n.Type() {
+	case "identifier":
+		x.nameRef(n, RefValue, sc)
+		return
+	case "type_identifier":
+		x.nameRef(n, RefType, sc)
+		return
+	case "field_identifier", "package_identifier", "label_name":
+		return
+	case "qualified_type":
+		if id := x.typeID(n, sc); id != "" {
+			x.addRef(n.ChildByFieldName("name"), id, x.text(n.ChildByFieldName("name")), RefType, sc)
+		}
+		return
+
+	case "call_expression":
+		switch fn := n.ChildByFieldName("function"); fn.Type() {
+		case "identifier":
+			x.nameRef(fn, RefCall, sc)
+		case "selector_expression":
+			x.selectorRef(fn, RefCall, sc)
+		default:
+			x.refs(fn, sc)
+		}
+		x.refs(n.ChildByFieldName("type_arguments"), sc)
+		x.refs(n.ChildByFieldName("arguments"), sc)
+		return
+	case "selector_expression":
+		x.selectorRef(n, RefValue, sc)
+		return
+
+	case "short_var_declaration", "receive_statement", "range_clause":
+		right := n.ChildByFieldName("right")
+		x.refs(right, sc)
+		if left := n.ChildByFieldName("left"); left != nil && (n.Type() != "receive_statement" || strings.Contains(x.text(n), ":=")) {
+			x.declare(left, right, sc)
+		}
+		return
+	case "var_spec", "const_spec":
+		typ := n.ChildByFieldName("type")
+		x.refs(typ, sc)
+		x.refs(n.ChildByFieldName("value"), sc)
+		typeID := x.typeID(typ, sc)
+		for _, name := range x.fieldNodes(n, "name") {
+			sc.locals[x.text(name)] = typeID
+		}
+		return
+	case "type_switch_statement":
+		for _, alias := range x.fieldNodes(n, "alias") {
+			x.declare(alias, nil, sc)
+		}
+	case "parameter_list", "type_parameter_list":
+		x.params(n, sc)
+		return
+	case "keyed_element":
+		// a bare identifier key names a struct field
+		if key := n.NamedChild(0); key != nil && key.NamedChildCount() == 1 && key.NamedChild(0).Type() == "identifier" {
+			for i := 1; i < int(n.NamedChildCount()); i++ {
+				x.refs(n.NamedChild(i), sc)
+			}
+			return
+		}
+	}
+
+	for i := 0; i < int(n.NamedChildCount()); i++ {
+		child := n.NamedChild(i)
+		if n.Type() == "type_switch_statement" && n.FieldNameForChild(i) == "alias" {
+			continue
+		}
+		x.refs(child, sc)
+	}
+}
+
+// declare adds the identifiers in an assignment's left-hand side to scope,
+// typed when the matching right-hand value is a composite literal.
+func (x *extractor) declare(left, right *sitter.Node, sc *scope) {
+	names := []*sitter.Node{left}
+	if left.Type() == "expression_list" {
+		names = x.namedChildren(left)
+	}
+	var values []*sitter.Node
+	if right != nil && right.Type() == "expression_list" {
+		values = x.namedChildren(right)
+	}
+
+	for i, name := range names {
+		if name.Type() != "identifier" {
+			continue
+		}
+		var typeID string
+		if i < len(values) {
+			value := values[i]
+			if value.Type() == "unary_expression" {
+				value = value.ChildByFieldName("operand")
+			}
+			if value != nil && value.Type() == "composite_literal" {
+				typeID = x.typeID(value.ChildByFieldName("type"), sc)
+			}
+		}
+		if prev, ok := sc.locals[x.text(name)]; !ok || typeID != "" || prev == "" {
+			sc.locals[x.text(name)] = typeID
+		}
+	}
+}
+
+// nameRef records an unqualified name as a reference to a package-level
+// symbol unless it is local or predeclared.
+func (x *extractor) nameRef(n *sitter.Node, kind string, sc *scope) {
+	name := x.text(n)
+	if _, local := sc.locals[name]; local || predeclared[name] || name == "_" {
+		return
+	}
+	x.addRef(n, x.qualify(name), name, kind, sc)
+}
+
+// selectorRef records x.Name: a member of an imported package, a method or
+// field of a variable whose type is known, or else a method called by
+// name alone.
+func (x *extractor) selectorRef(n *sitter.Node, kind string, sc *scope) {
+	operand := n.ChildByFieldName("operand")
+	field := n.ChildByFieldName("field")
+	name := x.text(field)
+
+	if operand.Type() == "identifier" {
+		ident := x.text(operand)
+		if typeID, local := sc.locals[ident]; local {
+			if typeID != "" {
+				x.addRef(field, typeID+"."+name, name, kind, sc)
+			} else if kind == RefCall {
+				x.addRef(field, "", name, kind, sc)
+			}
+			return
+		}
+		if importPath, ok := x.imports[ident]; ok {
+			x.addRef(field, importPath+"."+name, name, kind, sc)
+			return
+		}
+	}
+
+	x.refs(operand, sc)
+	if kind == RefCall {
+		x.addRef(field, "", name, kind, sc)
+	}
+}
+
+func (x *extractor) addSymbol(n *sitter.Node, id, name, kind, parent string) {
+	x.file.Symbols = append(x.file.Symbols, Symbol{
+		ID:        id,
+		Package:   x.file.Package,
+		Name:      name,
+		Kind:      kind,
+		Parent:    parent,
+		Signature: x.signature(n),
+		StartLine: int(n.StartPoint().Row) + 1,
+		EndLine:   int(n.EndPoint().Row) + 1,
+	})
+}
+
+func (x *extractor) addRef(n *sitter.Node, symbol, name, kind string, sc *scope) {
+	x.file.Refs = append(x.file.Refs, Reference{
+		Symbol: symbol,
+		Name:   name,
+		Kind:   kind,
+		From:   sc.from,
+		Line:   int(n.StartPoint().Row) + 1,
+		Column: int(n.StartPoint().Column) + 1,
+	})
+}
+
+// signature returns a declaration up to its body, or its first line,
+// prefixed by the keyword of the declaration wrapping a spec.
+func (x *extractor) signature(n *sitter.Node) string {
+	end := n.EndByte()
+	if body := n.ChildByFieldName("body"); body != nil {
+		end = body.StartByte()
+	}
+	sig := string(x.content[n.StartByte():end])
+	if i := strings.Index(sig, "\n"); i >= 0 {
+		sig = sig[:i]
+	}
+	sig = strings.TrimSpace(sig)
+
+	switch n.Type() {
+	case "type_spec", "type_alias":
+		sig = "type " + sig
+	case "const_spec":
+		sig = "const " + sig
+	case "var_spec":
+		sig = "var " + sig
+	}
+	return sig
+}
+
+// fieldNodes returns the children of n in field, which may repeat.
+func (x *extractor) fieldNodes(n *sitter.Node, field string) []*sitter.Node {
+	var nodes []*sitter.Node
+	for i := 0; i < int(n.ChildCount()); i++ {
+		if n.FieldNameForChild(i) == field {
+			nodes = append(nodes, n.Child(i))
+		}
+	}
+	return nodes
+}
+
+func (x *extractor) namedChildren(n *sitter.Node) []*sitter.Node {
+	nodes := make([]*sitter.Node, n.NamedChildCount())
+	for i := range nodes {
+		nodes[i] = n.NamedChild(i)
+	}
+	return nodes
+}
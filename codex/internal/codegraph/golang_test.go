@@ -0,0 +1,184 @@
+package codegraph
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeModule creates a module named example.com/shop in a temp dir and
+// returns the path of file within it.
+func writeModule(t *testing.T, file string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/shop\n\ngo 1.22\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, file)
+}
+
+const ledgerSrc = `package ledger
+
+import (
+	"fmt"
+	st "example.com/shop/store"
+)
+
+// MaxEntries bounds a ledger.
+const MaxEntries = 100
+
+type Poster interface {
+	fmt.Stringer
+	Post(e Entry) error
+}
+
+type Ledger struct {
+	*Base
+	store st.Store
+	n     int
+}
+
+func (l *Ledger) Post(e Entry) error {
+	if l.n >= MaxEntries {
+		return fmt.Errorf("full")
+	}
+	l.store.Save(e)
+	entry := &Entry{Amount: 1}
+	entry.Validate()
+	return validate(e)
+}
+
+func New[T any](opts ...T) *Ledger {
+	return &Ledger{}
+}
+`
+
+func TestExtractGo(t *testing.T) {
+	path := writeModule(t, "ledger/ledger.go")
+	file, err := ExtractGo([]byte(ledgerSrc), path)
+	if err != nil {
+		t.Fatalf("ExtractGo: %v", err)
+	}
+
+	t.Run("Given a file in a module, When extracting, Then the package is its import path", func(t *testing.T) {
+		if file.Package != "example.com/shop/ledger" {
+			t.Errorf("package = %q", file.Package)
+		}
+		want := []Import{{Path: "fmt"}, {Path: "example.com/shop/store", Alias: "st"}}
+		if !reflect.DeepEqual(file.Imports, want) {
+			t.Errorf("imports = %+v, want %+v", file.Imports, want)
+		}
+	})
+
+	t.Run("Given declarations, When extracting, Then symbols are qualified with kinds and parents", func(t *testing.T) {
+		var got []string
+		for _, s := range file.Symbols {
+			got = append(got, s.Kind+" "+s.ID+" <"+s.Parent+">")
+		}
+		want := []string{
+			"const example.com/shop/ledger.MaxEntries <>",
+			"interface example.com/shop/ledger.Poster <>",
+			"method example.com/shop/ledger.Poster.Post <example.com/shop/ledger.Poster>",
+			"struct example.com/shop/ledger.Ledger <>",
+			"method example.com/shop/ledger.Ledger.Post <example.com/shop/ledger.Ledger>",
+			"function example.com/shop/ledger.New <>",
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("symbols =\n%q\nwant\n%q", got, want)
+		}
+
+		post := file.Symbols[4]
+		if post.Signature != "func (l *Ledger) Post(e Entry) error" || post.StartLine != 22 || post.EndLine != 30 {
+			t.Errorf("Ledger.Post = %q lines %d-%d", post.Signature, post.StartLine, post.EndLine)
+		}
+	})
+
+	t.Run("Given references, When extracting, Then they resolve through imports, scope and declared types", func(t *testing.T) {
+		got := make(map[string]bool)
+		for _, r := range file.Refs {
+			got[r.Kind+" "+r.Symbol+" "+r.Name+" from "+r.From] = true
+		}
+		const pkg = "example.com/shop/ledger"
+		want := []string{
+			"embed fmt.Stringer Stringer from " + pkg + ".Poster",
+			"type " + pkg + ".Entry Entry from " + pkg + ".Poster",
+			"embed " + pkg + ".Base Base from " + pkg + ".Ledger",
+			"type example.com/shop/store.Store Store from " + pkg + ".Ledger",
+			"value " + pkg + ".Ledger.n n from " + pkg + ".Ledger.Post",
+			"value " + pkg + ".MaxEntries MaxEntries from " + pkg + ".Ledger.Post",
+			"call fmt.Errorf Errorf from " + pkg + ".Ledger.Post",
+			"call  Save from " + pkg + ".Ledger.Post",
+			"call " + pkg + ".Entry.Validate Validate from " + pkg + ".Ledger.Post",
+			"call " + pkg + ".validate validate from " + pkg + ".Ledger.Post",
+			"type " + pkg + ".Ledger Ledger from " + pkg + ".New",
+		}
+		for _, w := range want {
+			if !got[w] {
+				t.Errorf("missing reference %q", w)
+			}
+		}
+		for ref := range got {
+			for _, local := range []string{".e ", ".l ", ".entry ", ".T ", ".opts ", ".Amount ", ".any "} {
+				if strings.Contains(ref, local) {
+					t.Errorf("local or field recorded as reference: %q", ref)
+				}
+			}
+		}
+	})
+
+	t.Run("Given a reference, When extracting, Then its position is recorded", func(t *testing.T) {
+		for _, r := range file.Refs {
+			if r.Name == "Errorf" && (r.Line != 24 || r.Column != 14) {
+				t.Errorf("Errorf at %d:%d, want 24:14", r.Line, r.Column)
+			}
+		}
+	})
+}
+
+func TestExtractGo_NoPackage(t *testing.T) {
+	t.Run("Given source without a package clause, When extracting, Then it fails", func(t *testing.T) {
+		if _, err := ExtractGo([]byte("func f() {}\n"), "f.go"); err == nil {
+			t.Error("want error")
+		}
+	})
+}
+
+func TestPackagePath(t *testing.T) {
+	path := writeModule(t, "internal/core/engine.go")
+
+	tests := []struct {
+		name, file, pkg, want string
+	}{
+		{"Given a nested package, When resolving, Then the module path is joined", path, "core", "example.com/shop/internal/core"},
+		{"Given an external test package, When resolving, Then _test is appended", path, "core_test", "example.com/shop/internal/core_test"},
+		{"Given the module root, When resolving, Then it is the module path", filepath.Join(filepath.Dir(filepath.Dir(filepath.Dir(path))), "main.go"), "main", "example.com/shop"},
+		{"Given no module, When resolving, Then the package name is used", filepath.Join(t.TempDir(), "x.go"), "x", "x"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PackagePath(tt.file, tt.pkg); got != tt.want {
+				t.Errorf("PackagePath = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestImportName(t *testing.T) {
+	tests := map[string]string{
+		"fmt":                          "fmt",
+		"github.com/mattn/go-sqlite3":  "sqlite3",
+		"github.com/google/uuid":       "uuid",
+		"gopkg.in/yaml.v3":             "yaml",
+		"github.com/jackc/pgx/v5":      "pgx",
+		"github.com/anthropics/aef-go": "aef",
+	}
+	for path, want := range tests {
+		t.Run("Given "+path+", When naming, Then "+want, func(t *testing.T) {
+			if got := importName(path); got != want {
+				t.Errorf("importName(%q) = %q, want %q", path, got, want)
+			}
+		})
+	}
+}
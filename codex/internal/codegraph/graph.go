@@ -0,0 +1,72 @@
+// Package codegraph extracts a symbol graph from Go source: the
+// declarations each file defines, the references it makes to other symbols
+// and the packages it imports.
+//
+// Files are parsed one at a time with Tree-sitter rather than loaded with
+// go/packages, so indexing needs neither a Go toolchain nor a module that
+// builds. The cost is that references are resolved syntactically: package
+// qualifiers, same-package names and variables of a declared type resolve
+// to a symbol, while other method calls are kept by name only.
+package codegraph
+
+// Symbol kinds.
+const (
+	KindFunction  = "function"
+	KindMethod    = "method" // methods and interface method declarations
+	KindType      = "type"   // named types other than structs and interfaces, and aliases
+	KindStruct    = "struct"
+	KindInterface = "interface"
+	KindConst     = "const"
+	KindVar       = "var"
+)
+
+// Reference kinds.
+const (
+	RefCall  = "call"  // function or method call
+	RefType  = "type"  // use as a type
+	RefValue = "value" // other use, such as a function passed as a value
+	RefEmbed = "embed" // type embedded in a struct or interface
+)
+
+// Symbol is a package-level declaration, or a method of a type or
+// interface.
+type Symbol struct {
+	// ID qualifies Name by its package import path and, for methods, the
+	// receiver type or interface: "github.com/acme/ledger.Ledger.Post".
+	ID      string
+	Package string // import path
+	Name    string
+	Kind    string
+	// Parent is the ID of the type declaring a method, or "".
+	Parent    string
+	Signature string
+	StartLine int
+	EndLine   int
+}
+
+// Reference is a use of a symbol.
+type Reference struct {
+	// Symbol is the ID of the referenced symbol, or "" for a method call
+	// whose receiver type could not be determined.
+	Symbol string
+	Name   string // referenced name without qualifier
+	Kind   string
+	// From is the ID of the declaration the reference appears in.
+	From   string
+	Line   int
+	Column int
+}
+
+// Import is an import declaration.
+type Import struct {
+	Path  string
+	Alias string // explicit name, "." or "_"; "" when imported by its own name
+}
+
+// File is the graph extracted from one source file.
+type File struct {
+	Package string // import path
+	Symbols []Symbol
+	Refs    []Reference
+	Imports []Import
+}
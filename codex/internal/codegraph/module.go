@@ -0,0 +1,86 @@
+package codegraph
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// modules caches the module declared by each go.mod file, keyed by the
+// directory holding it. A directory without a go.mod maps to "".
+var modules sync.Map
+
+// PackagePath returns the import path of the package a Go file belongs to,
+// from the nearest go.mod above it. External test packages get the "_test"
+// suffix of their package clause. Outside a module the package name is
+// used as is.
+func PackagePath(filePath, pkgName string) string {
+	abs, err := filepath.Abs(filePath)
+	if err != nil {
+		return pkgName
+	}
+	dir := filepath.Dir(abs)
+	for d := dir; ; d = filepath.Dir(d) {
+		if module := modulePath(d); module != "" {
+			rel, err := filepath.Rel(d, dir)
+			if err != nil {
+				return pkgName
+			}
+			importPath := module
+			if rel != "." {
+				importPath = path.Join(module, filepath.ToSlash(rel))
+			}
+			if strings.HasSuffix(pkgName, "_test") {
+				importPath += "_test"
+			}
+			return importPath
+		}
+		if filepath.Dir(d) == d {
+			return pkgName
+		}
+	}
+}
+
+// modulePath returns the module path declared by dir/go.mod, or "".
+func modulePath(dir string) string {
+	if cached, ok := modules.Load(dir); ok {
+		return cached.(string)
+	}
+
+	var module string
+	if f, err := os.Open(filepath.Join(dir, "go.mod")); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if fields := strings.Fields(scanner.Text()); len(fields) >= 2 && fields[0] == "module" {
+				module = strings.Trim(fields[1], `"`)
+				break
+			}
+		}
+		f.Close()
+	}
+	modules.Store(dir, module)
+	return module
+}
+
+// majorVersion matches a module major version path element, such as v2.
+var majorVersion = regexp.MustCompile(`^v[0-9]+$`)
+
+// importName guesses the package name an import path is used by: its last
+// element without a major version, "go-" prefix, "-go" suffix or gopkg.in
+// ".vN" suffix. "github.com/mattn/go-sqlite3" is used as sqlite3.
+func importName(importPath string) string {
+	parts := strings.Split(importPath, "/")
+	name := parts[len(parts)-1]
+	if majorVersion.MatchString(name) && len(parts) > 1 {
+		name = parts[len(parts)-2]
+	}
+	if i := strings.Index(name, ".v"); i > 0 && majorVersion.MatchString(name[i+1:]) {
+		name = name[:i]
+	}
+	name = strings.TrimSuffix(strings.TrimPrefix(name, "go-"), "-go")
+	return strings.ReplaceAll(name, "-", "_")
+}
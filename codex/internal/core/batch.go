@@ -0,0 +1,65 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// Embedding batch defaults, used when Config or IndexerConfig leave them unset.
+const (
+	DefaultEmbedBatchSize   = 32
+	DefaultEmbedParallelism = 4
+)
+
+// embedAll embeds texts for indexing in batches of idx.embedBatchSize, with
+// up to idx.embedParallelism batches in flight. It returns a vector or an
+// error for each text. A batch that fails is retried one text at a time via
+// EmbedDocument, so a single bad text fails only itself.
+func (idx *Indexer) embedAll(ctx context.Context, texts []string) ([][]float32, []error) {
+	vecs := make([][]float32, len(texts))
+	errs := make([]error, len(texts))
+
+	sem := make(chan struct{}, idx.embedParallelism)
+	var wg sync.WaitGroup
+	for start := 0; start < len(texts); start += idx.embedBatchSize {
+		end := min(start+idx.embedBatchSize, len(texts))
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			idx.embedBatch(ctx, texts[start:end], vecs[start:end], errs[start:end])
+		}()
+	}
+	wg.Wait()
+
+	return vecs, errs
+}
+
+// embedBatch fills vecs and errs for one batch of texts.
+func (idx *Indexer) embedBatch(ctx context.Context, texts []string, vecs [][]float32, errs []error) {
+	batch, err := idx.embedder.EmbedDocuments(ctx, texts)
+	if err == nil && len(batch) != len(texts) {
+		err = fmt.Errorf("expected %d embeddings, got %d", len(texts), len(batch))
+	}
+	if err == nil {
+		copy(vecs, batch)
+		return
+	}
+
+	log.Printf("Warning: batch embedding of %d texts failed, embedding individually: %v", len(texts), err)
+	for i, text := range texts {
+		vecs[i], errs[i] = idx.embedder.EmbedDocument(ctx, text)
+	}
+}
+
+// orDefault returns n, or def when n is not positive.
+func orDefault(n, def int) int {
+	if n <= 0 {
+		return def
+	}
+	return n
+}
@@ -0,0 +1,518 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/anthropics/aef/codex/internal/chunking"
+	"github.com/anthropics/aef/codex/internal/codegraph"
+	"github.com/anthropics/aef/codex/internal/storage"
+)
+
+// maxEmbedDepth bounds how deep method sets are followed through embedded
+// types.
+const maxEmbedDepth = 8
+
+// CodeSymbol is a Go declaration in the code graph.
+type CodeSymbol struct {
+	ID        string `json:"id"` // import-path qualified, e.g. github.com/acme/ledger.Ledger.Post
+	Package   string `json:"package"`
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`             // function, method, type, struct, interface, const, var
+	Parent    string `json:"parent,omitempty"` // type or interface declaring a method
+	Signature string `json:"signature,omitempty"`
+	File      string `json:"file"`
+	StartLine int    `json:"start_line"`
+	EndLine   int    `json:"end_line"`
+	ItemID    string `json:"item_id,omitempty"` // code chunk holding the definition
+}
+
+// CodeReference is a use of a symbol in Go code.
+type CodeReference struct {
+	Symbol string `json:"symbol,omitempty"`
+	Name   string `json:"name"`
+	Kind   string `json:"kind"`           // call, type, value, embed
+	From   string `json:"from,omitempty"` // declaration the reference appears in
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	// Resolved is false for method calls matched by name alone, because
+	// the receiver's type was not written where the call was indexed.
+	Resolved bool `json:"resolved"`
+}
+
+// CodeImplementation pairs an interface with a type whose method set
+// covers it.
+type CodeImplementation struct {
+	Interface CodeSymbol `json:"interface"`
+	Type      CodeSymbol `json:"type"`
+}
+
+// PackageImports lists the packages a Go package imports and those
+// importing it.
+type PackageImports struct {
+	Package    string   `json:"package"`
+	Imports    []string `json:"imports"`
+	ImportedBy []string `json:"imported_by"`
+}
+
+// CodeDefinition returns the Go symbols named symbol: a full ID, or a
+// suffix such as "Ledger.Post" or "core.Embedder".
+func (e *SearchEngine) CodeDefinition(ctx context.Context, symbol string) ([]CodeSymbol, error) {
+	if e.graph == nil {
+		return nil, fmt.Errorf("code graph not available")
+	}
+	records, err := e.graph.FindCodeSymbols(storage.CodeSymbolQuery{Symbol: symbol})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find symbol: %w", err)
+	}
+	symbols := make([]CodeSymbol, len(records))
+	for i, r := range records {
+		symbols[i] = codeSymbolFromRecord(r)
+	}
+	return symbols, nil
+}
+
+// CodeReferences returns the references to the symbols named symbol,
+// ordered by location. Calls to a method on a receiver of unknown type are
+// included as unresolved. limit <= 0 returns all.
+func (e *SearchEngine) CodeReferences(ctx context.Context, symbol string, limit int) ([]CodeReference, error) {
+	defs, err := e.lookupSymbols(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var refs []CodeReference
+	for _, def := range defs {
+		q := storage.CodeRefQuery{Symbol: def.ID}
+		if def.Kind == codegraph.KindMethod {
+			q.Name = def.Name
+		}
+		records, err := e.graph.FindCodeRefs(q)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find references: %w", err)
+		}
+		for _, r := range records {
+			key := fmt.Sprintf("%s:%d:%d", r.File, r.Line, r.Column)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			refs = append(refs, CodeReference{
+				Symbol:   r.Symbol,
+				Name:     r.Name,
+				Kind:     r.Kind,
+				From:     r.From,
+				File:     r.File,
+				Line:     r.Line,
+				Column:   r.Column,
+				Resolved: r.Symbol != "",
+			})
+		}
+	}
+
+	sort.SliceStable(refs, func(i, j int) bool {
+		a, b := refs[i], refs[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	if limit > 0 && len(refs) > limit {
+		refs = refs[:limit]
+	}
+	return refs, nil
+}
+
+// CodeImplementations relates the types named symbol to interfaces: for
+// an interface, the types implementing it; for another type, the
+// interfaces it implements. Method sets are compared by method name,
+// including methods promoted through embedding, without checking
+// signatures or pointer receivers.
+func (e *SearchEngine) CodeImplementations(ctx context.Context, symbol string) ([]CodeImplementation, error) {
+	defs, err := e.lookupSymbols(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	sets := &methodSets{graph: e.graph, sets: make(map[string]map[string]bool)}
+	var impls []CodeImplementation
+	for _, def := range defs {
+		var found []CodeImplementation
+		switch def.Kind {
+		case codegraph.KindInterface:
+			found, err = sets.implementers(def)
+		case codegraph.KindStruct, codegraph.KindType:
+			found, err = sets.implemented(def)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to find implementations: %w", err)
+		}
+		impls = append(impls, found...)
+	}
+
+	sort.SliceStable(impls, func(i, j int) bool {
+		if impls[i].Interface.ID != impls[j].Interface.ID {
+			return impls[i].Interface.ID < impls[j].Interface.ID
+		}
+		return impls[i].Type.ID < impls[j].Type.ID
+	})
+	return impls, nil
+}
+
+// CodeImports returns the imports and importers of the Go packages whose
+// import path is pkg or ends in "/" + pkg.
+func (e *SearchEngine) CodeImports(ctx context.Context, pkg string) ([]PackageImports, error) {
+	if e.graph == nil {
+		return nil, fmt.Errorf("code graph not available")
+	}
+	records, err := e.graph.FindCodeImports(storage.CodeImportQuery{Package: pkg, Path: pkg})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find imports: %w", err)
+	}
+
+	byPackage := make(map[string]*PackageImports)
+	get := func(name string) *PackageImports {
+		if p, ok := byPackage[name]; ok {
+			return p
+		}
+		p := &PackageImports{Package: name, Imports: []string{}, ImportedBy: []string{}}
+		byPackage[name] = p
+		return p
+	}
+	for _, r := range records {
+		if matchesPackage(r.Package, pkg) {
+			p := get(r.Package)
+			p.Imports = appendUnique(p.Imports, r.Path)
+		}
+		if matchesPackage(r.Path, pkg) {
+			p := get(r.Path)
+			p.ImportedBy = appendUnique(p.ImportedBy, r.Package)
+		}
+	}
+
+	result := make([]PackageImports, 0, len(byPackage))
+	for _, p := range byPackage {
+		sort.Strings(p.Imports)
+		sort.Strings(p.ImportedBy)
+		result = append(result, *p)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Package < result[j].Package })
+	return result, nil
+}
+
+// lookupSymbols finds the symbols named symbol, failing if there are none.
+func (e *SearchEngine) lookupSymbols(symbol string) ([]storage.CodeSymbolRecord, error) {
+	if e.graph == nil {
+		return nil, fmt.Errorf("code graph not available")
+	}
+	defs, err := e.graph.FindCodeSymbols(storage.CodeSymbolQuery{Symbol: symbol})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find symbol: %w", err)
+	}
+	if len(defs) == 0 {
+		return nil, fmt.Errorf("no Go symbol %q in the code graph", symbol)
+	}
+	return defs, nil
+}
+
+// methodSets computes and caches the method names of types, including
+// those promoted from embedded types.
+type methodSets struct {
+	graph CodeGraph
+	sets  map[string]map[string]bool
+}
+
+func (m *methodSets) of(typeID string) (map[string]bool, error) {
+	return m.resolve(typeID, 0)
+}
+
+func (m *methodSets) resolve(typeID string, depth int) (map[string]bool, error) {
+	if set, ok := m.sets[typeID]; ok {
+		return set, nil
+	}
+	set := make(map[string]bool)
+	// Placeholder breaks embedding cycles
+	m.sets[typeID] = set
+
+	methods, err := m.graph.FindCodeSymbols(storage.CodeSymbolQuery{Parent: typeID, Kind: codegraph.KindMethod})
+	if err != nil {
+		return nil, err
+	}
+	for _, method := range methods {
+		set[method.Name] = true
+	}
+	if depth >= maxEmbedDepth {
+		return set, nil
+	}
+
+	embeds, err := m.graph.FindCodeRefs(storage.CodeRefQuery{From: typeID, Kind: codegraph.RefEmbed})
+	if err != nil {
+		return nil, err
+	}
+	for _, embed := range embeds {
+		promoted, err := m.resolve(embed.Symbol, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		for name := range promoted {
+			set[name] = true
+		}
+	}
+	return set, nil
+}
+
+// embedders returns ids and, transitively, the types embedding them.
+func (m *methodSets) embedders(ids []string) ([]string, error) {
+	seen := make(map[string]bool)
+	queue := append([]string(nil), ids...)
+	var all []string
+	for depth := 0; len(queue) > 0 && depth <= maxEmbedDepth; depth++ {
+		var next []string
+		for _, id := range queue {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			all = append(all, id)
+			refs, err := m.graph.FindCodeRefs(storage.CodeRefQuery{Symbol: id, Kind: codegraph.RefEmbed})
+			if err != nil {
+				return nil, err
+			}
+			for _, r := range refs {
+				next = append(next, r.From)
+			}
+		}
+		queue = next
+	}
+	return all, nil
+}
+
+// implementers returns the types and interfaces whose method set covers
+// iface's. Candidates declare, or embed a type declaring, one of its
+// methods. Empty interfaces have no listed implementers.
+func (m *methodSets) implementers(iface storage.CodeSymbolRecord) ([]CodeImplementation, error) {
+	want, err := m.of(iface.ID)
+	if err != nil || len(want) == 0 {
+		return nil, err
+	}
+
+	methods, err := m.graph.FindCodeSymbols(storage.CodeSymbolQuery{Names: setKeys(want), Kind: codegraph.KindMethod})
+	if err != nil {
+		return nil, err
+	}
+	var declaring []string
+	for _, method := range methods {
+		declaring = append(declaring, method.Parent)
+	}
+	candidates, err := m.embedders(declaring)
+	if err != nil {
+		return nil, err
+	}
+
+	var impls []CodeImplementation
+	for _, id := range candidates {
+		if id == iface.ID {
+			continue
+		}
+		have, err := m.of(id)
+		if err != nil {
+			return nil, err
+		}
+		if !covers(have, want) {
+			continue
+		}
+		typ, err := m.symbol(id)
+		if err != nil {
+			return nil, err
+		}
+		if typ == nil {
+			continue
+		}
+		impls = append(impls, CodeImplementation{Interface: codeSymbolFromRecord(iface), Type: *typ})
+	}
+	return impls, nil
+}
+
+// implemented returns the interfaces whose method set typ's covers.
+// Candidates declare, or embed an interface declaring, one of its methods.
+func (m *methodSets) implemented(typ storage.CodeSymbolRecord) ([]CodeImplementation, error) {
+	have, err := m.of(typ.ID)
+	if err != nil || len(have) == 0 {
+		return nil, err
+	}
+
+	methods, err := m.graph.FindCodeSymbols(storage.CodeSymbolQuery{Names: setKeys(have), Kind: codegraph.KindMethod})
+	if err != nil {
+		return nil, err
+	}
+	var declaring []string
+	for _, method := range methods {
+		declaring = append(declaring, method.Parent)
+	}
+	candidates, err := m.embedders(declaring)
+	if err != nil {
+		return nil, err
+	}
+
+	var impls []CodeImplementation
+	for _, id := range candidates {
+		if id == typ.ID {
+			continue
+		}
+		iface, err := m.symbol(id)
+		if err != nil {
+			return nil, err
+		}
+		if iface == nil || iface.Kind != codegraph.KindInterface {
+			continue
+		}
+		want, err := m.of(id)
+		if err != nil {
+			return nil, err
+		}
+		if len(want) > 0 && covers(have, want) {
+			impls = append(impls, CodeImplementation{Interface: *iface, Type: codeSymbolFromRecord(typ)})
+		}
+	}
+	return impls, nil
+}
+
+// symbol returns the declaration with exactly the given ID, or nil.
+func (m *methodSets) symbol(id string) (*CodeSymbol, error) {
+	records, err := m.graph.FindCodeSymbols(storage.CodeSymbolQuery{Symbol: id})
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range records {
+		if r.ID == id {
+			sym := codeSymbolFromRecord(r)
+			return &sym, nil
+		}
+	}
+	return nil, nil
+}
+
+// indexCodeGraph extracts the symbol graph of a Go file and links it with
+// the file's chunks. It returns the graph to store and, per chunk, the ID
+// of the innermost symbol spanning it (or ""). Files that fail to parse get
+// an empty graph so that stale rows are still replaced.
+func indexCodeGraph(req IndexRequest, chunks []chunking.CodeChunk, parentID string) (*storage.CodeGraphFile, []string) {
+	graph := &storage.CodeGraphFile{Path: fileKey(req.FilePath)}
+	file, err := codegraph.ExtractGo([]byte(req.Content), req.FilePath)
+	if err != nil {
+		log.Printf("Warning: code graph not extracted for %s: %v\n", req.FilePath, err)
+		return graph, make([]string, len(chunks))
+	}
+
+	chunkSymbols := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		if len(chunk.Merged) > 0 {
+			continue
+		}
+		best := -1
+		for j, sym := range file.Symbols {
+			if sym.StartLine <= chunk.StartLine && chunk.EndLine <= sym.EndLine &&
+				(best < 0 || sym.EndLine-sym.StartLine < file.Symbols[best].EndLine-file.Symbols[best].StartLine) {
+				best = j
+			}
+		}
+		if best >= 0 {
+			chunkSymbols[i] = file.Symbols[best].ID
+		}
+	}
+
+	for _, sym := range file.Symbols {
+		rec := storage.CodeSymbolRecord{
+			ID:        sym.ID,
+			Package:   sym.Package,
+			Name:      sym.Name,
+			Kind:      sym.Kind,
+			Parent:    sym.Parent,
+			Signature: sym.Signature,
+			StartLine: sym.StartLine,
+			EndLine:   sym.EndLine,
+		}
+		// The innermost chunk holding the declaration's first line
+		best := -1
+		for i, chunk := range chunks {
+			if chunk.StartLine <= sym.StartLine && sym.StartLine <= chunk.EndLine &&
+				(best < 0 || chunk.EndLine-chunk.StartLine < chunks[best].EndLine-chunks[best].StartLine) {
+				best = i
+			}
+		}
+		if best >= 0 {
+			rec.ItemID = chunkItemID(parentID, best)
+		}
+		graph.Symbols = append(graph.Symbols, rec)
+	}
+	for _, ref := range file.Refs {
+		graph.Refs = append(graph.Refs, storage.CodeRefRecord{
+			Symbol: ref.Symbol,
+			Name:   ref.Name,
+			Kind:   ref.Kind,
+			From:   ref.From,
+			Line:   ref.Line,
+			Column: ref.Column,
+		})
+	}
+	for _, imp := range file.Imports {
+		graph.Imports = append(graph.Imports, storage.CodeImportRecord{Package: file.Package, Path: imp.Path, Alias: imp.Alias})
+	}
+	return graph, chunkSymbols
+}
+
+func codeSymbolFromRecord(r storage.CodeSymbolRecord) CodeSymbol {
+	return CodeSymbol{
+		ID:        r.ID,
+		Package:   r.Package,
+		Name:      r.Name,
+		Kind:      r.Kind,
+		Parent:    r.Parent,
+		Signature: r.Signature,
+		File:      r.File,
+		StartLine: r.StartLine,
+		EndLine:   r.EndLine,
+		ItemID:    r.ItemID,
+	}
+}
+
+// matchesPackage reports whether importPath is pkg or ends in "/" + pkg.
+func matchesPackage(importPath, pkg string) bool {
+	return importPath == pkg || strings.HasSuffix(importPath, "/"+pkg)
+}
+
+// covers reports whether have contains every name in want.
+func covers(have, want map[string]bool) bool {
+	for name := range want {
+		if !have[name] {
+			return false
+		}
+	}
+	return true
+}
+
+func setKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, v := range list {
+		if v == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
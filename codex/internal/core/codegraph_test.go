@@ -0,0 +1,243 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/anthropics/aef/codex/internal/chunking"
+	"github.com/anthropics/aef/codex/internal/storage"
+)
+
+const graphLedgerSrc = `package ledger
+
+import "fmt"
+
+// Poster records entries.
+type Poster interface {
+	Post(amount int) error
+}
+
+type Ledger struct {
+	total int
+}
+
+func (l *Ledger) Post(amount int) error {
+	l.total += amount
+	return nil
+}
+
+func (l *Ledger) String() string {
+	return fmt.Sprint(l.total)
+}
+
+type AuditedLedger struct {
+	*Ledger
+}
+`
+
+const graphAPISrc = `package api
+
+import "example.com/shop/ledger"
+
+func Charge(l *ledger.Ledger, amount int) error {
+	return l.Post(amount)
+}
+
+func Refund(amount int) error {
+	return current().Post(-amount)
+}
+`
+
+// newGraphEngine indexes a small Go module into a mock code graph and
+// returns an engine over it, with the indexer and module directory.
+func newGraphEngine(t *testing.T) (*SearchEngine, *Indexer, *MockCodeGraph, *MockMetadataStorage, string) {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":           "module example.com/shop\n",
+		"ledger/ledger.go": graphLedgerSrc,
+		"api/api.go":       graphAPISrc,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	graph := NewMockCodeGraph()
+	metaStore := NewMockMetadataStorage()
+	idx, err := NewIndexerWithConfig(IndexerConfig{
+		Embedder:    NewMockEmbedder(),
+		VectorStore: NewMockVectorStorage(),
+		MetaStore:   metaStore,
+		CodeChunker: chunking.NewASTChunker(),
+		FileIndex:   NewMockFileIndex(),
+		CodeGraph:   graph,
+		IDGenerator: NewMockIDGenerator("file"),
+	})
+	if err != nil {
+		t.Fatalf("NewIndexerWithConfig: %v", err)
+	}
+	if _, err := idx.IndexDirectory(context.Background(), dir, "project"); err != nil {
+		t.Fatalf("IndexDirectory: %v", err)
+	}
+
+	engine := NewSearchEngineWithDeps(SearchEngineDeps{Metadata: metaStore, Graph: graph})
+	return engine, idx, graph, metaStore, dir
+}
+
+func TestIndexer_CodeGraph(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Given a Go file, When indexed, Then its graph is stored and symbols link to chunks", func(t *testing.T) {
+		_, _, graph, metaStore, dir := newGraphEngine(t)
+		file := graph.Files[filepath.Join(dir, "ledger", "ledger.go")]
+		if file == nil {
+			t.Fatalf("no graph for ledger.go; have %d files", len(graph.Files))
+		}
+
+		var post storage.CodeSymbolRecord
+		for _, sym := range file.Symbols {
+			if sym.ID == "example.com/shop/ledger.Ledger.Post" {
+				post = sym
+			}
+		}
+		if post.ItemID == "" {
+			t.Fatalf("Ledger.Post = %+v, want a symbol linked to a chunk", post)
+		}
+
+		item, err := metaStore.GetItem(post.ItemID)
+		if err != nil || item == nil {
+			t.Fatalf("GetItem(%s) = %v, %v", post.ItemID, item, err)
+		}
+		if item.Metadata["symbol_id"] != post.ID {
+			t.Errorf("chunk symbol_id = %v, want %s", item.Metadata["symbol_id"], post.ID)
+		}
+		want := []storage.CodeImportRecord{{Package: "example.com/shop/ledger", Path: "fmt"}}
+		if !reflect.DeepEqual(file.Imports, want) {
+			t.Errorf("imports = %+v, want %+v", file.Imports, want)
+		}
+	})
+
+	t.Run("Given an indexed Go file, When removed, Then its graph is dropped", func(t *testing.T) {
+		_, idx, graph, _, dir := newGraphEngine(t)
+		path := filepath.Join(dir, "api", "api.go")
+		if removed, err := idx.RemoveFile(ctx, path); err != nil || !removed {
+			t.Fatalf("RemoveFile = %v, %v", removed, err)
+		}
+		if _, ok := graph.Files[path]; ok {
+			t.Error("graph still has the removed file")
+		}
+	})
+
+	t.Run("Given a non-Go file, When indexed, Then no graph is stored", func(t *testing.T) {
+		graph := NewMockCodeGraph()
+		idx, _ := NewIndexerWithConfig(IndexerConfig{
+			Embedder:    NewMockEmbedder(),
+			VectorStore: NewMockVectorStorage(),
+			MetaStore:   NewMockMetadataStorage(),
+			CodeChunker: chunking.NewASTChunker(),
+			CodeGraph:   graph,
+		})
+		if _, err := idx.IndexFile(ctx, IndexRequest{Content: "def f():\n    pass\n", FilePath: "f.py"}); err != nil {
+			t.Fatalf("IndexFile: %v", err)
+		}
+		if len(graph.Files) != 0 {
+			t.Errorf("graph files = %d, want none", len(graph.Files))
+		}
+	})
+}
+
+func TestSearchEngine_CodeGraph(t *testing.T) {
+	ctx := context.Background()
+	engine, _, _, _, dir := newGraphEngine(t)
+
+	t.Run("Given a type-qualified name, When finding the definition, Then the method is returned", func(t *testing.T) {
+		defs, err := engine.CodeDefinition(ctx, "Ledger.Post")
+		if err != nil {
+			t.Fatalf("CodeDefinition: %v", err)
+		}
+		if len(defs) != 1 || defs[0].Signature != "func (l *Ledger) Post(amount int) error" || defs[0].File != filepath.Join(dir, "ledger", "ledger.go") {
+			t.Errorf("defs = %+v", defs)
+		}
+	})
+
+	t.Run("Given a method, When finding references, Then typed calls resolve and others match by name", func(t *testing.T) {
+		refs, err := engine.CodeReferences(ctx, "Ledger.Post", 0)
+		if err != nil {
+			t.Fatalf("CodeReferences: %v", err)
+		}
+		var got []string
+		for _, r := range refs {
+			got = append(got, r.From)
+			if r.Resolved != (r.From == "example.com/shop/api.Charge") {
+				t.Errorf("ref from %s resolved = %v", r.From, r.Resolved)
+			}
+		}
+		if want := []string{"example.com/shop/api.Charge", "example.com/shop/api.Refund"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("refs from %q, want %q", got, want)
+		}
+	})
+
+	t.Run("Given a limit, When finding references, Then it caps the results", func(t *testing.T) {
+		refs, _ := engine.CodeReferences(ctx, "Ledger.Post", 1)
+		if len(refs) != 1 {
+			t.Errorf("refs = %+v, want one", refs)
+		}
+	})
+
+	t.Run("Given an interface, When finding implementations, Then direct and promoted implementers are returned", func(t *testing.T) {
+		impls, err := engine.CodeImplementations(ctx, "ledger.Poster")
+		if err != nil {
+			t.Fatalf("CodeImplementations: %v", err)
+		}
+		var got []string
+		for _, impl := range impls {
+			got = append(got, impl.Type.Name)
+		}
+		if want := []string{"AuditedLedger", "Ledger"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("implementers = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("Given a struct, When finding implementations, Then the interfaces it implements are returned", func(t *testing.T) {
+		impls, err := engine.CodeImplementations(ctx, "ledger.Ledger")
+		if err != nil {
+			t.Fatalf("CodeImplementations: %v", err)
+		}
+		if len(impls) != 1 || impls[0].Interface.Name != "Poster" {
+			t.Errorf("implemented = %+v, want Poster", impls)
+		}
+	})
+
+	t.Run("Given a package, When listing imports, Then imports and importers are returned", func(t *testing.T) {
+		pkgs, err := engine.CodeImports(ctx, "ledger")
+		if err != nil {
+			t.Fatalf("CodeImports: %v", err)
+		}
+		want := []PackageImports{{Package: "example.com/shop/ledger", Imports: []string{"fmt"}, ImportedBy: []string{"example.com/shop/api"}}}
+		if !reflect.DeepEqual(pkgs, want) {
+			t.Errorf("imports = %+v, want %+v", pkgs, want)
+		}
+	})
+
+	t.Run("Given an unknown symbol, When finding references, Then it fails", func(t *testing.T) {
+		if _, err := engine.CodeReferences(ctx, "Ledger.Missing", 0); err == nil {
+			t.Error("want error")
+		}
+	})
+
+	t.Run("Given no code graph, When finding a definition, Then it fails", func(t *testing.T) {
+		bare := NewSearchEngineWithDeps(SearchEngineDeps{})
+		if _, err := bare.CodeDefinition(ctx, "Ledger"); err == nil {
+			t.Error("want error")
+		}
+	})
+}
@@ -4,9 +4,12 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sort"
 	"time"
 
 	"github.com/anthropics/aef/codex/internal/embedding"
+	"github.com/anthropics/aef/codex/internal/llm"
+	"github.com/anthropics/aef/codex/internal/project"
 	"github.com/anthropics/aef/codex/internal/reranking"
 	"github.com/anthropics/aef/codex/internal/storage"
 )
@@ -17,8 +20,16 @@ type SearchEngine struct {
 	vecStore VectorStorage
 	metadata MetadataStorage
 	keywords KeywordSearcher
+	files    FileIndex
 	embedder Embedder
 	reranker Reranker
+
+	graph CodeGraph // optional - enables the code graph APIs
+
+	models         VectorModels // optional - enables model checks and re-embedding
+	embeddingModel string       // model ID tagged on stored vectors
+
+	completer llm.Client // optional - generates query expansions
 }
 
 // SearchEngineDeps holds dependencies for constructing a SearchEngine.
@@ -27,34 +38,78 @@ type SearchEngineDeps struct {
 	VecStore VectorStorage
 	Metadata MetadataStorage
 	Keywords KeywordSearcher
+	Files    FileIndex
 	Embedder Embedder
 	Reranker Reranker
+
+	Graph CodeGraph // optional
+
+	Models         VectorModels // optional
+	EmbeddingModel string
+
+	Completer llm.Client // optional
 }
 
 // NewSearchEngine creates a new search engine with SQLite-backed vector storage.
 func NewSearchEngine(ctx context.Context, config Config) (*SearchEngine, error) {
+	switch config.FeedbackRanking {
+	case "", FeedbackRankingOff, FeedbackRankingRRF, FeedbackRankingPrior:
+	default:
+		return nil, fmt.Errorf("unknown feedback ranking %q (want off, rrf or prior)", config.FeedbackRanking)
+	}
+	if _, err := NewFusion(config.Fusion, config.FusionK); err != nil {
+		return nil, err
+	}
+	if err := config.FusionWeights.Validate(); err != nil {
+		return nil, err
+	}
+	if err := config.CodeFusionWeights.Validate(); err != nil {
+		return nil, err
+	}
+
 	// Initialize metadata store
-	metadata, err := storage.NewMetadataStore(config.MetadataDBPath)
+	metadata, err := storage.NewMetadataStore(config.MetadataDBPath, storage.WithProjectResolver(project.Resolve))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open metadata store: %w", err)
 	}
 
+	// Initialize embedding client for the configured backend
+	embed, err := embedding.New(embedding.Config{
+		Backend:        config.EmbeddingBackend,
+		URL:            config.LocalEmbeddingURL,
+		Model:          config.LocalEmbeddingModel,
+		APIKey:         config.EmbeddingAPIKey,
+		QueryPrefix:    config.EmbeddingQueryPrefix,
+		DocumentPrefix: config.EmbeddingDocumentPrefix,
+		Dimensions:     config.EmbeddingDimensions,
+		MaxTokens:      config.EmbeddingMaxTokens,
+	})
+	if err != nil {
+		metadata.Close()
+		return nil, err
+	}
+
 	// Initialize vector store sharing the same SQLite database
-	vecStore, err := storage.NewVecStore(metadata.DB())
+	vecOpts := []storage.VecStoreOption{storage.WithModel(embed.Model())}
+	switch config.VectorIndex {
+	case "", "hnsw":
+		vecOpts = append(vecOpts, storage.WithHNSW(storage.DefaultHNSWConfig()))
+	case "exact":
+	default:
+		metadata.Close()
+		return nil, fmt.Errorf("unknown vector index %q (want hnsw or exact)", config.VectorIndex)
+	}
+	vecStore, err := storage.NewVecStore(metadata.DB(), vecOpts...)
 	if err != nil {
 		metadata.Close()
 		return nil, fmt.Errorf("failed to initialize vector store: %w", err)
 	}
 
-	// Initialize embedding client (single local Ollama model)
-	var opts []embedding.LocalClientOption
-	if config.LocalEmbeddingURL != "" {
-		opts = append(opts, embedding.WithLocalBaseURL(config.LocalEmbeddingURL))
-	}
-	if config.LocalEmbeddingModel != "" {
-		opts = append(opts, embedding.WithLocalModel(config.LocalEmbeddingModel))
+	// Refuse to mix vectors from different embedding models
+	if err := checkEmbeddingModel(vecStore, embed.Model(), config.EmbeddingModelCheck); err != nil {
+		metadata.Close()
+		return nil, err
 	}
-	embed := embedding.NewLocalClient(opts...)
 
 	// Initialize reranker (optional - may fail if models not present)
 	var reranker Reranker
@@ -67,13 +122,28 @@ func NewSearchEngine(ctx context.Context, config Config) (*SearchEngine, error)
 		}
 	}
 
+	// Initialize query expansion LLM (optional)
+	completer, err := newExpansionClient(config)
+	if err != nil {
+		log.Printf("Warning: query expansion LLM not available: %v\n", err)
+		completer = nil
+	}
+
 	return &SearchEngine{
 		config:   config,
 		vecStore: vecStore,
 		metadata: metadata,
 		keywords: metadata,
+		files:    metadata,
 		embedder: embed,
 		reranker: reranker,
+
+		graph: metadata,
+
+		models:         vecStore,
+		embeddingModel: embed.Model(),
+
+		completer: completer,
 	}, nil
 }
 
@@ -84,8 +154,16 @@ func NewSearchEngineWithDeps(deps SearchEngineDeps) *SearchEngine {
 		vecStore: deps.VecStore,
 		metadata: deps.Metadata,
 		keywords: deps.Keywords,
+		files:    deps.Files,
 		embedder: deps.Embedder,
 		reranker: deps.Reranker,
+
+		graph: deps.Graph,
+
+		models:         deps.Models,
+		embeddingModel: deps.EmbeddingModel,
+
+		completer: deps.Completer,
 	}
 }
 
@@ -108,29 +186,72 @@ func (e *SearchEngine) Search(ctx context.Context, req SearchRequest) ([]SearchR
 	}
 
 	candidateLimit := 50
-	if e.reranker == nil && req.Limit < candidateLimit {
+	if !e.rerankerActive() && !req.GroupByParent && req.Limit < candidateLimit {
 		candidateLimit = req.Limit * 3 // over-fetch for fusion but not too much
 		if candidateLimit < 20 {
 			candidateLimit = 20
 		}
 	}
 
+	fusion, weights, err := e.fusionFor(req)
+	if err != nil {
+		return nil, err
+	}
+
 	// 1. Embed query
 	queryVec, err := e.embedder.EmbedQuery(ctx, req.Query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to embed query: %w", err)
 	}
 
-	// 2. Vector search
-	vectorResults, err := e.vecStore.Search(ctx, queryVec, candidateLimit)
+	// 2. Resolve filters to an allow-list so both retrievers only rank
+	// matching items. Without this, selective filters applied after fusion
+	// can leave fewer than req.Limit results even when enough matches exist.
+	// Unfiltered searches instead exclude the git ref snapshots by ID, as
+	// listing every other item would cost a scan of the store per query.
+	project := req.Project
+	if project == "" {
+		project = e.config.Project
+	}
+	filter := req.filter(project)
+	var allowed, excluded map[string]bool
+	switch {
+	case e.metadata == nil:
+	case filter.IsEmpty():
+		ids, err := e.metadata.GitRefItemIDs()
+		if err != nil {
+			return nil, fmt.Errorf("failed to find git ref snapshots: %w", err)
+		}
+		if len(ids) > 0 {
+			excluded = idSet(ids)
+		}
+	default:
+		ids, err := e.metadata.FilterItemIDs(filter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply search filters: %w", err)
+		}
+		if len(ids) == 0 {
+			return nil, nil
+		}
+		allowed = idSet(ids)
+	}
+
+	// 3. Vector search, plus one per query variant when expanding
+	vectorOpts := storage.VectorSearchOptions{Allowed: allowed, Excluded: excluded, Exact: req.Exact}
+	vectorResults, err := e.vecStore.SearchWithOptions(ctx, queryVec, candidateLimit, vectorOpts)
 	if err != nil {
 		return nil, fmt.Errorf("vector search failed: %w", err)
 	}
+	var variantResults [][]storage.ScoredResult
+	if req.Expand || req.HyDE {
+		variants := e.expandQuery(ctx, req.Query, req.HyDE)
+		variantResults = e.searchVariants(ctx, variants, candidateLimit, vectorOpts)
+	}
 
-	// 3. Keyword search (FTS5 BM25)
+	// 4. Keyword search (FTS5 BM25)
 	var keywordResults []SearchResult
 	if e.keywords != nil {
-		kwResults, err := e.keywords.KeywordSearch(req.Query, candidateLimit)
+		kwResults, err := e.keywords.KeywordSearchFiltered(req.Query, candidateLimit, filter)
 		if err != nil {
 			// Log but don't fail -- vector results are still valid
 			log.Printf("Warning: keyword search failed: %v\n", err)
@@ -151,10 +272,30 @@ func (e *SearchEngine) Search(ctx context.Context, req SearchRequest) ([]SearchR
 		}
 	}
 
-	// 4. 2-way RRF fusion (vector + keywords)
-	results := reciprocalRankFusion(vectorResults, keywordResults, 60)
+	// 5. Fuse vector, query variant and keyword rankings, plus a feedback
+	// ranking of the same candidates when enabled
+	lists := []RankedList{
+		{Name: ListVector, Weight: weights.Weight(ListVector), Results: vectorResults},
+		{Name: ListKeyword, Weight: weights.Weight(ListKeyword), Results: keywordRanking(keywordResults)},
+	}
+	for _, vr := range variantResults {
+		lists = append(lists, RankedList{Name: ListExpansion, Weight: weights.Weight(ListExpansion), Results: vr})
+	}
+	var feedback map[string]*FeedbackStats
+	if e.config.FeedbackRanking == FeedbackRankingRRF {
+		feedback, err = e.loadFeedback(candidateIDs(lists), project)
+		if err != nil {
+			log.Printf("Warning: failed to load feedback: %v\n", err)
+		} else {
+			lists = append(lists, RankedList{Name: ListFeedback, Weight: weights.Weight(ListFeedback), Results: feedbackRanking(feedback)})
+		}
+	}
+	results := fuseResults(fusion, lists, keywordResults)
+	if req.Explain {
+		explainFusion(results, lists, fusion.Name())
+	}
 
-	// 5. Hydrate metadata for vector-only results (those missing Title/Content)
+	// 6. Hydrate metadata for vector-only results (those missing Title/Content)
 	for i := range results {
 		if results[i].Title == "" && results[i].Content == "" && e.metadata != nil {
 			record, err := e.metadata.GetItem(results[i].ID)
@@ -164,36 +305,58 @@ func (e *SearchEngine) Search(ctx context.Context, req SearchRequest) ([]SearchR
 		}
 	}
 
-	// 6. Apply type/scope filters
-	if len(req.Types) > 0 || req.Scope != "" {
-		typeSet := make(map[string]bool, len(req.Types))
-		for _, t := range req.Types {
-			typeSet[t] = true
-		}
-		var filtered []SearchResult
-		for _, r := range results {
-			if len(typeSet) > 0 && !typeSet[r.Type] {
-				continue
+	// 7. Apply reranking if available. Every candidate is kept: thresholds
+	// and grouping by parent come after, and req.Limit is applied last.
+	if e.rerankerActive() && len(results) > 0 {
+		reranked, err := e.reranker.Rerank(req.Query, toDocuments(results), len(results))
+		if err != nil {
+			log.Printf("Warning: reranking failed: %v\n", err)
+		} else {
+			results = applyRerankScores(results, reranked)
+			if req.Explain {
+				for i := range results {
+					score := results[i].Score
+					results[i].Explanation.RerankScore = &score
+				}
 			}
-			if req.Scope != "" && r.Scope != req.Scope {
-				continue
+		}
+	}
+
+	// 8. Boost current-project results when blending with global items.
+	// The filter guarantees every project-scoped result is the current project's.
+	if req.Scope == ScopeBoth && project != "" && e.config.ProjectBoost > 1 {
+		results = boostScope(results, ScopeProject, e.config.ProjectBoost)
+		if req.Explain {
+			for i := range results {
+				if results[i].Scope == ScopeProject {
+					results[i].Explanation.ProjectBoost = e.config.ProjectBoost
+				}
 			}
-			filtered = append(filtered, r)
 		}
-		results = filtered
 	}
 
-	// 7. Apply reranking if available
-	if e.reranker != nil && len(results) > 0 {
-		reranked, err := e.reranker.Rerank(req.Query, toDocuments(results), req.Limit)
+	// 9. Adjust scores by feedback when used as a prior
+	if e.config.FeedbackRanking == FeedbackRankingPrior && len(results) > 0 {
+		feedback, err = e.loadFeedback(resultIDs(results), project)
 		if err != nil {
-			log.Printf("Warning: reranking failed: %v\n", err)
+			log.Printf("Warning: failed to load feedback: %v\n", err)
 		} else {
-			results = applyRerankScores(results, reranked)
+			weight := orDefaultFloat(e.config.FeedbackWeight, DefaultFeedbackWeight)
+			results = applyFeedbackPrior(results, feedback, weight)
+			if req.Explain {
+				for i := range results {
+					if s, ok := feedback[results[i].ID]; ok {
+						results[i].Explanation.FeedbackBoost = 1 + weight*s.Score
+					}
+				}
+			}
 		}
 	}
 
-	// 8. Score threshold cutoff — drop results below ratio of top score
+	// 10. Score threshold cutoff — drop results below ratio of top score
+	if req.Explain {
+		explainThreshold(results, e.config.ScoreThreshold)
+	}
 	if e.config.ScoreThreshold > 0 && len(results) > 0 {
 		minScore := results[0].Score * e.config.ScoreThreshold
 		cutoff := len(results)
@@ -206,14 +369,85 @@ func (e *SearchEngine) Search(ctx context.Context, req SearchRequest) ([]SearchR
 		results = results[:cutoff]
 	}
 
-	// 9. Limit results
+	// 11. Keep the best chunk per document, then limit results
+	if req.GroupByParent {
+		results = groupByParent(results)
+	}
 	if len(results) > req.Limit {
 		results = results[:req.Limit]
 	}
 
+	// 12. Report feedback stats, loading them if ranking did not
+	if feedback == nil && len(results) > 0 {
+		if feedback, err = e.loadFeedback(resultIDs(results), project); err != nil {
+			log.Printf("Warning: failed to load feedback: %v\n", err)
+		}
+	}
+	for i := range results {
+		results[i].Feedback = feedback[results[i].ID]
+	}
+
+	// 13. Cite results, expanding them with neighbouring chunks when asked
+	e.citeAndExpand(results, req)
+
 	return results, nil
 }
 
+// idSet returns ids as a set.
+func idSet(ids []string) map[string]bool {
+	set := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
+
+// filter converts the request's filter fields into a storage filter.
+// Scope "all" means no scope restriction; project resolves the "project"
+// and "both" scopes to a single repository.
+func (r SearchRequest) filter(project string) storage.SearchFilter {
+	scope := r.Scope
+	if scope == ScopeAll {
+		scope = ""
+	}
+	return storage.SearchFilter{
+		Types:         r.Types,
+		Scope:         scope,
+		Project:       project,
+		Tags:          r.Tags,
+		SourcePrefix:  r.SourcePrefix,
+		GitRef:        r.GitRef,
+		CreatedAfter:  r.CreatedAfter,
+		CreatedBefore: r.CreatedBefore,
+		UpdatedAfter:  r.UpdatedAfter,
+		UpdatedBefore: r.UpdatedBefore,
+	}
+}
+
+// rerankerActive reports whether a real reranking model is loaded. A
+// fallback reranker (no models or no ONNX runtime) is never consulted, so
+// fusion scores are kept rather than replaced by placeholder scores.
+func (e *SearchEngine) rerankerActive() bool {
+	return e.reranker != nil && e.reranker.IsAvailable()
+}
+
+// RerankerStatus reports whether search results are reranked, with a
+// description of the active model or the reason reranking is off.
+func (e *SearchEngine) RerankerStatus() (active bool, detail string) {
+	if e.reranker == nil {
+		return false, "not configured (set CODEX_MODELS_PATH)"
+	}
+	return e.reranker.IsAvailable(), e.reranker.Status()
+}
+
+// VectorIndexStatus describes the vector search mode (exact or HNSW).
+func (e *SearchEngine) VectorIndexStatus() string {
+	if s, ok := e.vecStore.(interface{ IndexStatus() string }); ok {
+		return s.IndexStatus()
+	}
+	return "unknown"
+}
+
 // Get retrieves an item by ID
 func (e *SearchEngine) Get(ctx context.Context, id string) (*Item, error) {
 	record, err := e.metadata.GetItem(id)
@@ -225,6 +459,10 @@ func (e *SearchEngine) Get(ctx context.Context, id string) (*Item, error) {
 
 // Add adds a new item to the knowledge base
 func (e *SearchEngine) Add(ctx context.Context, item *Item) error {
+	if item.Project == "" {
+		item.Project = e.config.Project
+	}
+
 	vec, err := e.embedder.EmbedDocument(ctx, item.Content)
 	if err != nil {
 		return fmt.Errorf("embedding failed: %w", err)
@@ -245,6 +483,9 @@ func (e *SearchEngine) Add(ctx context.Context, item *Item) error {
 
 // RecordFeedback records user feedback on a search result
 func (e *SearchEngine) RecordFeedback(feedback *Feedback) error {
+	if feedback.Project == "" {
+		feedback.Project = e.config.Project
+	}
 	return e.metadata.RecordFeedback(feedbackToRecord(feedback))
 }
 
@@ -296,6 +537,42 @@ func (e *SearchEngine) IndexDirectory(ctx context.Context, dirPath string, scope
 	return indexer.IndexDirectory(ctx, dirPath, scope)
 }
 
+// IndexDirectoryWithProgress indexes all files in a directory, reporting
+// progress and returning a summary with per-file errors
+func (e *SearchEngine) IndexDirectoryWithProgress(ctx context.Context, dirPath string, scope string, progress IndexProgressCallback) (*IndexStats, error) {
+	indexer, err := NewIndexer(e)
+	if err != nil {
+		return nil, err
+	}
+	defer indexer.Close()
+
+	return indexer.IndexDirectoryWithProgress(ctx, dirPath, scope, progress)
+}
+
+// WatchDirectory indexes a directory and keeps it in sync with file changes
+// until ctx is cancelled
+func (e *SearchEngine) WatchDirectory(ctx context.Context, dirPath string, scope string, opts WatchOptions) error {
+	indexer, err := NewIndexer(e)
+	if err != nil {
+		return err
+	}
+	defer indexer.Close()
+
+	return indexer.WatchDirectory(ctx, dirPath, scope, opts)
+}
+
+// IndexGit indexes the files of a git repository that changed since the
+// last indexed commit, recording their provenance
+func (e *SearchEngine) IndexGit(ctx context.Context, req GitIndexRequest, progress IndexProgressCallback) (*IndexStats, error) {
+	indexer, err := NewIndexer(e)
+	if err != nil {
+		return nil, err
+	}
+	defer indexer.Close()
+
+	return indexer.IndexGit(ctx, req, progress)
+}
+
 // NewIndexer creates an indexer for this engine (for advanced use cases)
 func (e *SearchEngine) NewIndexer() (*Indexer, error) {
 	return NewIndexer(e)
@@ -329,11 +606,16 @@ func (e *SearchEngine) List(ctx context.Context, itemType, scope string, limit,
 // Update updates an existing item
 func (e *SearchEngine) Update(ctx context.Context, item *Item) error {
 	// Verify item exists
-	_, err := e.metadata.GetItem(item.ID)
+	existing, err := e.metadata.GetItem(item.ID)
 	if err != nil {
 		return fmt.Errorf("item not found: %w", err)
 	}
 
+	// Keep project attribution when the caller doesn't supply it
+	if item.Project == "" {
+		item.Project = existing.Project
+	}
+
 	// Update timestamp
 	item.UpdatedAt = time.Now()
 
@@ -408,6 +690,19 @@ func applyRerankScores(results []SearchResult, reranked []reranking.RerankResult
 	return newResults
 }
 
+// boostScope multiplies the score of results in scope by factor and re-sorts.
+func boostScope(results []SearchResult, scope string, factor float64) []SearchResult {
+	for i := range results {
+		if results[i].Scope == scope {
+			results[i].Score *= factor
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	return results
+}
+
 // Type conversion helpers
 
 func itemFromRecord(r *storage.ItemRecord) *Item {
@@ -418,6 +713,7 @@ func itemFromRecord(r *storage.ItemRecord) *Item {
 		Content:   r.Content,
 		Tags:      r.Tags,
 		Scope:     r.Scope,
+		Project:   r.Project,
 		Source:    r.Source,
 		Metadata:  r.Metadata,
 		CreatedAt: r.CreatedAt,
@@ -433,6 +729,7 @@ func itemToRecord(i *Item) *storage.ItemRecord {
 		Content:   i.Content,
 		Tags:      i.Tags,
 		Scope:     i.Scope,
+		Project:   i.Project,
 		Source:    i.Source,
 		Metadata:  i.Metadata,
 		CreatedAt: i.CreatedAt,
@@ -445,6 +742,7 @@ func feedbackToRecord(f *Feedback) *storage.FeedbackRecord {
 		ID:        storage.GenerateID(),
 		ItemID:    f.ItemID,
 		SessionID: f.SessionID,
+		Project:   f.Project,
 		Useful:    f.Useful,
 		Context:   f.Context,
 		Timestamp: f.Timestamp,
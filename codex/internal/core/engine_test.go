@@ -3,10 +3,12 @@ package core
 import (
 	"context"
 	"errors"
+	"math"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/anthropics/aef/codex/internal/reranking"
 	"github.com/anthropics/aef/codex/internal/storage"
 )
 
@@ -937,6 +939,369 @@ func TestSearchEngine_Search(t *testing.T) {
 			t.Errorf("expected at most 5 results, got %d", len(results))
 		}
 	})
+
+	t.Run("Given a narrow type filter When non-matching items outnumber candidates Then the match is still found", func(t *testing.T) {
+		// Given
+		embed := NewMockEmbedder()
+		vectorStore := NewMockVectorStorage()
+		metaStore := NewMockMetadataStorage()
+		for i := 0; i < 40; i++ {
+			id := "pattern-" + string(rune('a'+i))
+			metaStore.Items[id] = &storage.ItemRecord{ID: id, Type: "pattern", Title: id}
+			vectorStore.Vectors[id] = []float32{1.0}
+		}
+		metaStore.Items["failure-1"] = &storage.ItemRecord{ID: "failure-1", Type: "failure", Title: "F1"}
+		vectorStore.Vectors["failure-1"] = []float32{1.0}
+
+		engine := &SearchEngine{
+			embedder: embed,
+			vecStore: vectorStore,
+			metadata: metaStore,
+		}
+
+		// When
+		results, err := engine.Search(ctx, SearchRequest{
+			Query: "test",
+			Types: []string{"failure"},
+			Limit: 5,
+		})
+
+		// Then
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		if len(results) != 1 || results[0].ID != "failure-1" {
+			t.Errorf("expected only failure-1, got %+v", results)
+		}
+	})
+
+	t.Run("Given tag and date filters When Search called Then they are pushed into both retrievers", func(t *testing.T) {
+		// Given
+		embed := NewMockEmbedder()
+		vectorStore := NewMockVectorStorage()
+		keywords := NewMockKeywordSearcher()
+		metaStore := NewMockMetadataStorage()
+		recent := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+		old := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+		metaStore.Items["new-tagged"] = &storage.ItemRecord{ID: "new-tagged", Tags: []string{"payments"}, Source: "internal/pay.go", CreatedAt: recent, UpdatedAt: recent}
+		metaStore.Items["old-tagged"] = &storage.ItemRecord{ID: "old-tagged", Tags: []string{"payments"}, Source: "internal/pay.go", CreatedAt: old, UpdatedAt: old}
+		metaStore.Items["new-untagged"] = &storage.ItemRecord{ID: "new-untagged", Source: "internal/pay.go", CreatedAt: recent, UpdatedAt: recent}
+		for id := range metaStore.Items {
+			vectorStore.Vectors[id] = []float32{1.0}
+		}
+
+		engine := &SearchEngine{
+			embedder: embed,
+			vecStore: vectorStore,
+			keywords: keywords,
+			metadata: metaStore,
+		}
+		after := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		// When
+		results, err := engine.Search(ctx, SearchRequest{
+			Query:        "test",
+			Scope:        "all",
+			Tags:         []string{"payments"},
+			SourcePrefix: "internal/",
+			UpdatedAfter: after,
+			Limit:        10,
+		})
+
+		// Then
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		if len(results) != 1 || results[0].ID != "new-tagged" {
+			t.Errorf("expected only new-tagged, got %+v", results)
+		}
+		if len(vectorStore.LastAllowed) != 1 || !vectorStore.LastAllowed["new-tagged"] {
+			t.Errorf("expected vector allow-list {new-tagged}, got %v", vectorStore.LastAllowed)
+		}
+		f := keywords.LastFilter
+		if f.Scope != "" || len(f.Tags) != 1 || f.SourcePrefix != "internal/" || !f.UpdatedAfter.Equal(after) {
+			t.Errorf("unexpected keyword filter: %+v", f)
+		}
+	})
+
+	t.Run("Given scope project When items from several projects exist Then only the current project's items are returned", func(t *testing.T) {
+		// Given
+		embed := NewMockEmbedder()
+		vectorStore := NewMockVectorStorage()
+		metaStore := NewMockMetadataStorage()
+		metaStore.Items["a-1"] = &storage.ItemRecord{ID: "a-1", Scope: "project", Project: "/src/a", Title: "A"}
+		metaStore.Items["b-1"] = &storage.ItemRecord{ID: "b-1", Scope: "project", Project: "/src/b", Title: "B"}
+		metaStore.Items["g-1"] = &storage.ItemRecord{ID: "g-1", Scope: "global", Title: "G"}
+		for id := range metaStore.Items {
+			vectorStore.Vectors[id] = []float32{1.0}
+		}
+
+		engine := &SearchEngine{
+			config:   Config{Project: "/src/a"},
+			embedder: embed,
+			vecStore: vectorStore,
+			metadata: metaStore,
+		}
+
+		// When
+		results, err := engine.Search(ctx, SearchRequest{Query: "test", Scope: ScopeProject})
+
+		// Then
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		if len(results) != 1 || results[0].ID != "a-1" {
+			t.Errorf("expected only a-1, got %+v", results)
+		}
+	})
+
+	t.Run("Given scope both with project boost When Search called Then current project results rank first", func(t *testing.T) {
+		// Given
+		embed := NewMockEmbedder()
+		vectorStore := NewMockVectorStorage()
+		vectorStore.SearchFunc = func(ctx context.Context, queryVec []float32, limit int) ([]storage.ScoredResult, error) {
+			return []storage.ScoredResult{
+				{ID: "g-1", Score: 0.9},
+				{ID: "a-1", Score: 0.8},
+				{ID: "b-1", Score: 0.7},
+			}, nil
+		}
+		metaStore := NewMockMetadataStorage()
+		metaStore.Items["a-1"] = &storage.ItemRecord{ID: "a-1", Scope: "project", Project: "/src/a", Title: "A"}
+		metaStore.Items["b-1"] = &storage.ItemRecord{ID: "b-1", Scope: "project", Project: "/src/b", Title: "B"}
+		metaStore.Items["g-1"] = &storage.ItemRecord{ID: "g-1", Scope: "global", Title: "G"}
+
+		engine := &SearchEngine{
+			config:   Config{Project: "/src/a", ProjectBoost: 1.5},
+			embedder: embed,
+			vecStore: vectorStore,
+			metadata: metaStore,
+		}
+
+		// When
+		results, err := engine.Search(ctx, SearchRequest{Query: "test", Scope: ScopeBoth})
+
+		// Then
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		if len(results) != 2 {
+			t.Fatalf("expected 2 results (global + current project), got %+v", results)
+		}
+		if results[0].ID != "a-1" || results[1].ID != "g-1" {
+			t.Errorf("expected [a-1 g-1], got [%s %s]", results[0].ID, results[1].ID)
+		}
+	})
+
+	t.Run("Given exact requested When Search called Then vector store is asked for exact search", func(t *testing.T) {
+		// Given
+		vectorStore := NewMockVectorStorage()
+		engine := &SearchEngine{
+			embedder: NewMockEmbedder(),
+			vecStore: vectorStore,
+			metadata: NewMockMetadataStorage(),
+		}
+
+		// When
+		if _, err := engine.Search(ctx, SearchRequest{Query: "test", Exact: true}); err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+
+		// Then
+		if !vectorStore.LastExact {
+			t.Error("expected exact vector search")
+		}
+	})
+
+	t.Run("Given a filter matching nothing When Search called Then returns no results without searching", func(t *testing.T) {
+		// Given
+		embed := NewMockEmbedder()
+		vectorStore := NewMockVectorStorage()
+		metaStore := NewMockMetadataStorage()
+		metaStore.Items["item-1"] = &storage.ItemRecord{ID: "item-1", Type: "pattern"}
+		vectorStore.Vectors["item-1"] = []float32{1.0}
+
+		engine := &SearchEngine{
+			embedder: embed,
+			vecStore: vectorStore,
+			metadata: metaStore,
+		}
+
+		// When
+		results, err := engine.Search(ctx, SearchRequest{Query: "test", Types: []string{"runbook"}})
+
+		// Then
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		if len(results) != 0 {
+			t.Errorf("expected no results, got %d", len(results))
+		}
+		if vectorStore.SearchCount != 0 {
+			t.Errorf("expected vector search to be skipped, got %d calls", vectorStore.SearchCount)
+		}
+	})
+
+	t.Run("Given a git ref snapshot When Search called without filters Then it is excluded without an allow-list", func(t *testing.T) {
+		// Given
+		vectorStore := NewMockVectorStorage()
+		metaStore := NewMockMetadataStorage()
+		metaStore.Items["live"] = &storage.ItemRecord{ID: "live", Content: "func a() {}"}
+		metaStore.Items["old"] = &storage.ItemRecord{ID: "old", Content: "func a() {}", Metadata: map[string]any{"git_ref": "v1.0"}}
+		vectorStore.Vectors["live"] = []float32{1.0}
+		vectorStore.Vectors["old"] = []float32{1.0}
+
+		engine := &SearchEngine{
+			embedder: NewMockEmbedder(),
+			vecStore: vectorStore,
+			metadata: metaStore,
+		}
+
+		// When
+		results, err := engine.Search(ctx, SearchRequest{Query: "test"})
+
+		// Then
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		if len(results) != 1 || results[0].ID != "live" {
+			t.Errorf("expected only live, got %+v", results)
+		}
+		if vectorStore.LastAllowed != nil || len(vectorStore.LastExcluded) != 1 || !vectorStore.LastExcluded["old"] {
+			t.Errorf("expected no allow-list and old excluded, got allowed %v, excluded %v", vectorStore.LastAllowed, vectorStore.LastExcluded)
+		}
+	})
+}
+
+// =============================================================================
+// Test: Reranking
+// =============================================================================
+
+func TestSearchEngine_Search_Reranking(t *testing.T) {
+	ctx := context.Background()
+
+	newEngine := func(reranker Reranker) *SearchEngine {
+		vectorStore := NewMockVectorStorage()
+		vectorStore.SearchFunc = func(ctx context.Context, queryVec []float32, limit int) ([]storage.ScoredResult, error) {
+			return []storage.ScoredResult{
+				{ID: "first", Score: 0.9},
+				{ID: "second", Score: 0.8},
+				{ID: "third", Score: 0.7},
+			}, nil
+		}
+		metaStore := NewMockMetadataStorage()
+		for _, id := range []string{"first", "second", "third"} {
+			metaStore.Items[id] = &storage.ItemRecord{ID: id, Title: id, Content: "content " + id}
+		}
+		return &SearchEngine{
+			embedder: NewMockEmbedder(),
+			vecStore: vectorStore,
+			metadata: metaStore,
+			reranker: reranker,
+		}
+	}
+
+	t.Run("Given a fallback reranker When Search called Then fusion order and scores are kept", func(t *testing.T) {
+		// Given
+		reranker := &MockReranker{Available: false}
+		engine := newEngine(reranker)
+
+		// When
+		results, err := engine.Search(ctx, SearchRequest{Query: "test", Limit: 10})
+
+		// Then
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		if reranker.CallCount != 0 {
+			t.Errorf("expected fallback reranker not to be called, got %d calls", reranker.CallCount)
+		}
+		if len(results) != 3 || results[0].ID != "first" || results[2].ID != "third" {
+			t.Fatalf("expected fusion order [first second third], got %+v", results)
+		}
+		if want := 1.0 / 61.0; math.Abs(results[0].Score-want) > 1e-9 {
+			t.Errorf("expected RRF score %f, got %f", want, results[0].Score)
+		}
+	})
+
+	t.Run("Given an available reranker When Search called Then results follow rerank scores", func(t *testing.T) {
+		// Given
+		reranker := &MockReranker{Available: true}
+		engine := newEngine(reranker)
+
+		// When
+		results, err := engine.Search(ctx, SearchRequest{Query: "test", Limit: 10})
+
+		// Then
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		if reranker.CallCount != 1 {
+			t.Errorf("expected 1 rerank call, got %d", reranker.CallCount)
+		}
+		if len(results) != 3 || results[0].ID != "third" || results[2].ID != "first" {
+			t.Errorf("expected reranked order [third second first], got %+v", results)
+		}
+	})
+
+	t.Run("Given an available reranker and GroupByParent When the top chunks share a parent Then the limit is filled after grouping", func(t *testing.T) {
+		// Given
+		ids := []string{chunkItemID("doc", 0), chunkItemID("doc", 1), chunkItemID("other", 0)}
+		vectorStore := NewMockVectorStorage()
+		vectorStore.SearchFunc = func(ctx context.Context, queryVec []float32, limit int) ([]storage.ScoredResult, error) {
+			var results []storage.ScoredResult
+			for i, id := range ids {
+				results = append(results, storage.ScoredResult{ID: id, Score: 0.9 - float64(i)*0.1})
+			}
+			return results, nil
+		}
+		metaStore := NewMockMetadataStorage()
+		for _, id := range ids {
+			parent, _, _ := strings.Cut(id, "-chunk-")
+			metaStore.Items[id] = &storage.ItemRecord{ID: id, Title: id, Content: "content " + id, Metadata: map[string]any{"parent_id": parent}}
+		}
+		scores := map[string]float64{ids[0]: 3, ids[1]: 2, ids[2]: 1}
+		engine := &SearchEngine{
+			embedder: NewMockEmbedder(),
+			vecStore: vectorStore,
+			metadata: metaStore,
+			reranker: &MockReranker{Available: true, ScoreFunc: func(doc reranking.Document) float64 { return scores[doc.ID] }},
+		}
+
+		// When
+		results, err := engine.Search(ctx, SearchRequest{Query: "test", Limit: 2, GroupByParent: true})
+
+		// Then
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		if len(results) != 2 || results[0].ID != ids[0] || results[0].ParentHits != 2 || results[1].ID != ids[2] {
+			t.Errorf("expected [%s (2 hits) %s], got %+v", ids[0], ids[2], results)
+		}
+	})
+}
+
+func TestSearchEngine_RerankerStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		reranker   Reranker
+		wantActive bool
+		wantDetail string
+	}{
+		{name: "Given no reranker Then inactive and not configured", reranker: nil, wantActive: false, wantDetail: "not configured (set CODEX_MODELS_PATH)"},
+		{name: "Given fallback reranker Then inactive with its reason", reranker: &MockReranker{Available: false}, wantActive: false, wantDetail: "mock fallback"},
+		{name: "Given available reranker Then active with its model", reranker: &MockReranker{Available: true}, wantActive: true, wantDetail: "mock model"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := &SearchEngine{reranker: tt.reranker}
+			active, detail := engine.RerankerStatus()
+			if active != tt.wantActive || detail != tt.wantDetail {
+				t.Errorf("RerankerStatus() = (%v, %q), want (%v, %q)", active, detail, tt.wantActive, tt.wantDetail)
+			}
+		})
+	}
 }
 
 // =============================================================================
@@ -1044,6 +1409,28 @@ func TestSearchEngine_Get(t *testing.T) {
 func TestSearchEngine_Add(t *testing.T) {
 	ctx := context.Background()
 
+	t.Run("Given engine with a project When Add called without project Then item is attributed to it", func(t *testing.T) {
+		// Given
+		metaStore := NewMockMetadataStorage()
+		engine := &SearchEngine{
+			config:   Config{Project: "/src/repo-a"},
+			embedder: NewMockEmbedder(),
+			vecStore: NewMockVectorStorage(),
+			metadata: metaStore,
+		}
+
+		// When
+		err := engine.Add(ctx, &Item{ID: "p-1", Type: "pattern", Content: "c", Scope: "project"})
+
+		// Then
+		if err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+		if got := metaStore.Items["p-1"].Project; got != "/src/repo-a" {
+			t.Errorf("expected project /src/repo-a, got %q", got)
+		}
+	})
+
 	t.Run("Given valid pattern item When Add called Then stores in both vector store and metadata", func(t *testing.T) {
 		// Given
 		embed := NewMockEmbedder()
@@ -0,0 +1,189 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/anthropics/aef/codex/internal/llm"
+	"github.com/anthropics/aef/codex/internal/storage"
+)
+
+// DefaultExpansionParaphrases is the number of paraphrases requested when
+// Config.ExpansionParaphrases is unset.
+const DefaultExpansionParaphrases = 3
+
+// Query variant kinds produced by expansion.
+const (
+	VariantParaphrase  = "paraphrase"
+	VariantIdentifiers = "identifiers"
+	VariantHyDE        = "hyde"
+)
+
+// expansionMaxTokens bounds the completion; paraphrases and a short
+// hypothetical passage fit well within it.
+const expansionMaxTokens = 600
+
+// QueryVariant is an alternative form of a search query.
+type QueryVariant struct {
+	Kind string `json:"kind"`
+	Text string `json:"text"`
+}
+
+// expansionResponse is the JSON the expansion prompt asks for.
+type expansionResponse struct {
+	Paraphrases  []string `json:"paraphrases"`
+	Identifiers  []string `json:"identifiers"`
+	Hypothetical string   `json:"hypothetical"`
+}
+
+// expandQuery returns variants of query: paraphrases, the identifiers it
+// mentions, and with hyde a hypothetical passage answering it. Identifiers
+// are also extracted locally, so expansion still yields them when no
+// completion client is configured or the completion fails.
+func (e *SearchEngine) expandQuery(ctx context.Context, query string, hyde bool) []QueryVariant {
+	var resp expansionResponse
+	if e.completer != nil {
+		completion, err := e.completer.Complete(ctx, expansionPrompt(query, e.expansionParaphrases(), hyde), expansionMaxTokens)
+		if err != nil {
+			log.Printf("Warning: query expansion failed: %v\n", err)
+		} else if resp, err = parseExpansion(completion); err != nil {
+			log.Printf("Warning: query expansion failed: %v\n", err)
+		}
+	}
+
+	var variants []QueryVariant
+	seen := map[string]bool{normalizeVariant(query): true}
+	add := func(kind, text string) {
+		text = strings.TrimSpace(text)
+		if key := normalizeVariant(text); text != "" && !seen[key] {
+			seen[key] = true
+			variants = append(variants, QueryVariant{Kind: kind, Text: text})
+		}
+	}
+
+	for i, p := range resp.Paraphrases {
+		if i == e.expansionParaphrases() {
+			break
+		}
+		add(VariantParaphrase, p)
+	}
+	add(VariantIdentifiers, strings.Join(mergeIdentifiers(extractIdentifiers(query), resp.Identifiers), " "))
+	if hyde {
+		add(VariantHyDE, resp.Hypothetical)
+	}
+	return variants
+}
+
+func (e *SearchEngine) expansionParaphrases() int {
+	return orDefault(e.config.ExpansionParaphrases, DefaultExpansionParaphrases)
+}
+
+// expansionPrompt asks for paraphrases, identifiers and, with hyde, a
+// hypothetical answer as one JSON object, so expansion costs one call.
+func expansionPrompt(query string, paraphrases int, hyde bool) string {
+	var b strings.Builder
+	b.WriteString("You help search a software team's knowledge base of code, docs, decisions and past failures.\n\n")
+	fmt.Fprintf(&b, "Search query: %q\n\n", query)
+	b.WriteString("Respond with only a JSON object with these fields:\n")
+	fmt.Fprintf(&b, "- \"paraphrases\": up to %d rewordings of the query using different terms a document might use\n", paraphrases)
+	b.WriteString("- \"identifiers\": code identifiers, file names, commands or error codes the query refers to or implies (may be empty)\n")
+	if hyde {
+		b.WriteString("- \"hypothetical\": a short passage (under 120 words) from a document that would answer the query, written as that document would be\n")
+	}
+	return b.String()
+}
+
+// parseExpansion extracts the JSON object from a completion, tolerating
+// surrounding prose or code fences.
+func parseExpansion(completion string) (expansionResponse, error) {
+	var resp expansionResponse
+	start, end := strings.Index(completion, "{"), strings.LastIndex(completion, "}")
+	if start < 0 || end < start {
+		return resp, fmt.Errorf("no JSON object in expansion response")
+	}
+	if err := json.Unmarshal([]byte(completion[start:end+1]), &resp); err != nil {
+		return resp, fmt.Errorf("parse expansion response: %w", err)
+	}
+	return resp, nil
+}
+
+// extractIdentifiers returns the tokens of query that look like code.
+func extractIdentifiers(query string) []string {
+	var ids []string
+	for _, token := range strings.Fields(query) {
+		token = strings.Trim(token, "`.,;:!?\"'()")
+		if isCodeToken(token) {
+			ids = append(ids, token)
+		}
+	}
+	return ids
+}
+
+func mergeIdentifiers(lists ...[]string) []string {
+	seen := make(map[string]bool)
+	var merged []string
+	for _, list := range lists {
+		for _, id := range list {
+			id = strings.TrimSpace(id)
+			if id != "" && !seen[id] {
+				seen[id] = true
+				merged = append(merged, id)
+			}
+		}
+	}
+	return merged
+}
+
+func normalizeVariant(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// searchVariants embeds each variant and runs a vector search for it,
+// concurrently. Hypothetical passages are embedded as documents, since
+// they stand in for one; the rest as queries. A variant that fails is
+// logged and skipped.
+func (e *SearchEngine) searchVariants(ctx context.Context, variants []QueryVariant, limit int, opts storage.VectorSearchOptions) [][]storage.ScoredResult {
+	results := make([][]storage.ScoredResult, len(variants))
+	var wg sync.WaitGroup
+	for i, v := range variants {
+		wg.Add(1)
+		go func(i int, v QueryVariant) {
+			defer wg.Done()
+			var vec []float32
+			var err error
+			if v.Kind == VariantHyDE {
+				vec, err = e.embedder.EmbedDocument(ctx, v.Text)
+			} else {
+				vec, err = e.embedder.EmbedQuery(ctx, v.Text)
+			}
+			if err != nil {
+				log.Printf("Warning: failed to embed %s variant: %v\n", v.Kind, err)
+				return
+			}
+			if results[i], err = e.vecStore.SearchWithOptions(ctx, vec, limit, opts); err != nil {
+				log.Printf("Warning: vector search for %s variant failed: %v\n", v.Kind, err)
+			}
+		}(i, v)
+	}
+	wg.Wait()
+	return results
+}
+
+// newExpansionClient selects the LLM used for query expansion. With no
+// ExpansionProvider it reuses the enrichment client settings. Returns nil
+// (and no error) when neither is configured.
+func newExpansionClient(cfg Config) (llm.Client, error) {
+	if cfg.ExpansionProvider == "" {
+		return newEnrichmentClient(cfg)
+	}
+	return newEnrichmentClient(Config{
+		AnthropicAPIKey:    cfg.AnthropicAPIKey,
+		EnrichmentProvider: cfg.ExpansionProvider,
+		EnrichmentModel:    cfg.ExpansionModel,
+		EnrichmentURL:      cfg.ExpansionURL,
+	})
+}
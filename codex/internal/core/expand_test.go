@@ -0,0 +1,172 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/anthropics/aef/codex/internal/storage"
+)
+
+func TestSearchEngine_ExpandQuery(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		completer *MockCompleter
+		query     string
+		hyde      bool
+		want      []QueryVariant
+	}{
+		{
+			name: "Given an LLM response When expanding Then returns paraphrases and merged identifiers",
+			completer: &MockCompleter{Response: "Sure!\n```json\n" + `{"paraphrases": ["retry payment webhooks", "Retry payment webhooks", "webhook redelivery"],
+				"identifiers": ["retryWebhook", "WEBHOOK_MAX_ATTEMPTS"], "hypothetical": "Webhooks are retried..."}` + "\n```"},
+			query: "how does retryWebhook back off",
+			want: []QueryVariant{
+				{Kind: VariantParaphrase, Text: "retry payment webhooks"},
+				{Kind: VariantParaphrase, Text: "webhook redelivery"},
+				{Kind: VariantIdentifiers, Text: "retryWebhook WEBHOOK_MAX_ATTEMPTS"},
+			},
+		},
+		{
+			name:      "Given HyDE When expanding Then adds the hypothetical passage",
+			completer: &MockCompleter{Response: `{"paraphrases": [], "identifiers": [], "hypothetical": "Webhooks are retried with exponential backoff."}`},
+			query:     "webhook retries",
+			hyde:      true,
+			want:      []QueryVariant{{Kind: VariantHyDE, Text: "Webhooks are retried with exponential backoff."}},
+		},
+		{
+			name:      "Given a failing LLM When expanding Then falls back to local identifiers",
+			completer: &MockCompleter{Err: errors.New("unavailable")},
+			query:     "where is parse_config called",
+			want:      []QueryVariant{{Kind: VariantIdentifiers, Text: "parse_config"}},
+		},
+		{
+			name:      "Given malformed output When expanding Then yields no variants",
+			completer: &MockCompleter{Response: "I cannot help with that"},
+			query:     "webhook retries",
+			want:      nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := NewSearchEngineWithDeps(SearchEngineDeps{Completer: tt.completer})
+
+			got := engine.expandQuery(ctx, tt.query, tt.hyde)
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("expandQuery() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("variant %d = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+			if strings.Contains(tt.completer.Prompts[0], "hypothetical") != tt.hyde {
+				t.Errorf("prompt should ask for a hypothetical passage only with HyDE:\n%s", tt.completer.Prompts[0])
+			}
+		})
+	}
+
+	t.Run("Given no LLM When expanding Then extracts identifiers locally", func(t *testing.T) {
+		engine := NewSearchEngineWithDeps(SearchEngineDeps{})
+		got := engine.expandQuery(ctx, "why does NewSearchEngine fail", true)
+		if len(got) != 1 || got[0].Text != "NewSearchEngine" {
+			t.Errorf("expandQuery() = %v, want the NewSearchEngine identifier", got)
+		}
+	})
+}
+
+func TestSearchEngine_Search_Expand(t *testing.T) {
+	ctx := context.Background()
+
+	// Only the paraphrase's vector finds "target"; the HyDE passage finds "hyde-hit"
+	newEngine := func() *SearchEngine {
+		embedder := NewMockEmbedder()
+		var mu sync.Mutex
+		vecFor := map[string][]float32{"original": {1, 0, 0}, "reworded": {0, 1, 0}}
+		embedder.QueryFunc = func(ctx context.Context, query string) ([]float32, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			return vecFor[query], nil
+		}
+		embedder.EmbedFunc = func(ctx context.Context, text string) ([]float32, error) {
+			return []float32{0, 0, 1}, nil
+		}
+		vectorStore := NewMockVectorStorage()
+		vectorStore.SearchFunc = func(ctx context.Context, queryVec []float32, limit int) ([]storage.ScoredResult, error) {
+			switch {
+			case queryVec[1] == 1:
+				return []storage.ScoredResult{{ID: "target", Score: 0.9}}, nil
+			case queryVec[2] == 1:
+				return []storage.ScoredResult{{ID: "hyde-hit", Score: 0.9}}, nil
+			}
+			return []storage.ScoredResult{{ID: "literal", Score: 0.9}}, nil
+		}
+		return NewSearchEngineWithDeps(SearchEngineDeps{
+			VecStore: vectorStore,
+			Embedder: embedder,
+			Completer: &MockCompleter{
+				Response: `{"paraphrases": ["reworded"], "identifiers": [], "hypothetical": "A passage."}`,
+			},
+		})
+	}
+
+	tests := []struct {
+		name    string
+		req     SearchRequest
+		wantIDs []string
+	}{
+		{name: "Given no expansion When searching Then only the literal query is searched", req: SearchRequest{Query: "original"}, wantIDs: []string{"literal"}},
+		{name: "Given Expand When searching Then paraphrase results are fused in", req: SearchRequest{Query: "original", Expand: true}, wantIDs: []string{"literal", "target"}},
+		{name: "Given HyDE When searching Then the hypothetical passage is searched as a document", req: SearchRequest{Query: "original", HyDE: true}, wantIDs: []string{"hyde-hit", "literal", "target"}},
+		{name: "Given a zero expansion weight When searching Then variants are left out", req: SearchRequest{Query: "original", Expand: true, FusionWeights: FusionWeights{ListExpansion: 0}}, wantIDs: []string{"literal"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := newEngine().Search(ctx, tt.req)
+
+			if err != nil {
+				t.Fatalf("Search failed: %v", err)
+			}
+			got := resultIDs(results)
+			if strings.Join(got, ",") != strings.Join(tt.wantIDs, ",") {
+				t.Errorf("results = %v, want %v", got, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestNewExpansionClient(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       Config
+		wantModel string
+	}{
+		{name: "Given nothing configured Then no client", cfg: Config{}},
+		{name: "Given only enrichment Then reuses it", cfg: Config{EnrichmentProvider: "ollama", EnrichmentModel: "qwen2.5"}, wantModel: "qwen2.5"},
+		{name: "Given an expansion provider Then it overrides enrichment", cfg: Config{EnrichmentProvider: "ollama", EnrichmentModel: "qwen2.5", ExpansionProvider: "ollama", ExpansionModel: "llama3.2:1b"}, wantModel: "llama3.2:1b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := newExpansionClient(tt.cfg)
+			if err != nil {
+				t.Fatalf("newExpansionClient: %v", err)
+			}
+			if tt.wantModel == "" {
+				if client != nil {
+					t.Errorf("expected no client, got %T", client)
+				}
+				return
+			}
+			if client == nil || client.Model() != tt.wantModel {
+				t.Errorf("expected client with model %q, got %v", tt.wantModel, client)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package core
+
+// nearThresholdMargin is how far above Config.ScoreThreshold a result's
+// share of the top score can be and still be reported as near the cutoff.
+const nearThresholdMargin = 0.1
+
+// SearchExplanation records how a result got its rank, for requests with
+// SearchRequest.Explain. Scores are in the units of the stage that
+// produced them, so only compare them within a stage.
+type SearchExplanation struct {
+	// Signals holds the result's rank and raw score in each ranked list
+	// it appeared in: vector (cosine), keyword (BM25), expansion, feedback.
+	Signals []SignalScore `json:"signals"`
+
+	Fusion     string  `json:"fusion"`      // strategy that merged the signals
+	FusedScore float64 `json:"fused_score"` // score after fusion
+
+	// Later adjustments; zero values mean the stage did not apply.
+	RerankScore   *float64 `json:"rerank_score,omitempty"`
+	ProjectBoost  float64  `json:"project_boost,omitempty"`  // score multiplier
+	FeedbackBoost float64  `json:"feedback_boost,omitempty"` // score multiplier ("prior" feedback ranking)
+
+	// TopRatio is the final score over the top result's. With a score
+	// threshold, NearThreshold marks results within nearThresholdMargin
+	// of being cut.
+	TopRatio      float64 `json:"top_ratio"`
+	Threshold     float64 `json:"threshold,omitempty"`
+	NearThreshold bool    `json:"near_threshold,omitempty"`
+}
+
+// SignalScore is a result's position in one ranked list.
+type SignalScore struct {
+	List   string  `json:"list"`
+	Rank   int     `json:"rank"` // 1-based
+	Score  float64 `json:"score"`
+	Weight float64 `json:"weight"`
+}
+
+// explainFusion attaches explanations recording each result's signals in
+// the fused lists and its fused score. Lists left out of fusion are skipped.
+func explainFusion(results []SearchResult, lists []RankedList, fusion string) {
+	signals := make(map[string][]SignalScore)
+	for _, l := range lists {
+		if l.Weight <= 0 {
+			continue
+		}
+		for rank, r := range l.Results {
+			signals[r.ID] = append(signals[r.ID], SignalScore{List: l.Name, Rank: rank + 1, Score: r.Score, Weight: l.Weight})
+		}
+	}
+	for i := range results {
+		results[i].Explanation = &SearchExplanation{
+			Signals:    signals[results[i].ID],
+			Fusion:     fusion,
+			FusedScore: results[i].Score,
+		}
+	}
+}
+
+// explainThreshold records each result's share of the top score and whether
+// the threshold nearly cut it. results must be sorted by score.
+func explainThreshold(results []SearchResult, threshold float64) {
+	if len(results) == 0 || results[0].Score <= 0 {
+		return
+	}
+	top := results[0].Score
+	for i := range results {
+		x := results[i].Explanation
+		if x == nil {
+			continue
+		}
+		x.TopRatio = results[i].Score / top
+		if threshold > 0 {
+			x.Threshold = threshold
+			x.NearThreshold = x.TopRatio >= threshold && x.TopRatio < threshold+nearThresholdMargin
+		}
+	}
+}
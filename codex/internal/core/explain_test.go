@@ -0,0 +1,125 @@
+package core
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/anthropics/aef/codex/internal/reranking"
+	"github.com/anthropics/aef/codex/internal/storage"
+)
+
+func TestSearchEngine_Search_Explain(t *testing.T) {
+	ctx := context.Background()
+
+	// a: vector rank 1, keyword rank 2; b: keyword only; c: vector only, ranked last
+	newDeps := func() SearchEngineDeps {
+		vectorStore := NewMockVectorStorage()
+		vectorStore.SearchFunc = func(ctx context.Context, queryVec []float32, limit int) ([]storage.ScoredResult, error) {
+			return []storage.ScoredResult{{ID: "a", Score: 0.91}, {ID: "c", Score: 0.4}}, nil
+		}
+		keywords := NewMockKeywordSearcher()
+		keywords.Results = []storage.KeywordResult{
+			{ID: "b", Title: "B", Content: "b", Scope: ScopeProject, Score: 7.5},
+			{ID: "a", Title: "A", Content: "a", Scope: ScopeGlobal, Score: 3.2},
+		}
+		metaStore := NewMockMetadataStorage()
+		metaStore.Items["c"] = &storage.ItemRecord{ID: "c", Title: "C", Content: "c", Scope: ScopeGlobal}
+		return SearchEngineDeps{
+			VecStore: vectorStore,
+			Keywords: keywords,
+			Metadata: metaStore,
+			Embedder: NewMockEmbedder(),
+		}
+	}
+
+	t.Run("Given Explain When searching Then results carry per-signal scores", func(t *testing.T) {
+		// Given
+		engine := NewSearchEngineWithDeps(newDeps())
+
+		// When
+		results, err := engine.Search(ctx, SearchRequest{Query: "q", Explain: true})
+
+		// Then
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		byID := make(map[string]*SearchExplanation)
+		for _, r := range results {
+			if r.Explanation == nil {
+				t.Fatalf("result %s has no explanation", r.ID)
+			}
+			byID[r.ID] = r.Explanation
+		}
+
+		a := byID["a"]
+		if a.Fusion != FusionRRF || len(a.Signals) != 2 {
+			t.Fatalf("unexpected explanation for a: %+v", a)
+		}
+		if s := a.Signals[0]; s.List != ListVector || s.Rank != 1 || s.Score != 0.91 || s.Weight != 1 {
+			t.Errorf("vector signal = %+v", s)
+		}
+		if s := a.Signals[1]; s.List != ListKeyword || s.Rank != 2 || s.Score != 3.2 {
+			t.Errorf("keyword signal = %+v", s)
+		}
+		if math.Abs(a.FusedScore-(1.0/61+1.0/62)) > 1e-9 || a.TopRatio != 1 {
+			t.Errorf("fused score = %v, top ratio = %v", a.FusedScore, a.TopRatio)
+		}
+		if len(byID["b"].Signals) != 1 || byID["b"].Signals[0].List != ListKeyword {
+			t.Errorf("expected b to have only a keyword signal, got %+v", byID["b"].Signals)
+		}
+		if a.RerankScore != nil || a.ProjectBoost != 0 || a.FeedbackBoost != 0 {
+			t.Errorf("expected no later adjustments, got %+v", a)
+		}
+	})
+
+	t.Run("Given no Explain When searching Then results carry no explanation", func(t *testing.T) {
+		results, err := NewSearchEngineWithDeps(newDeps()).Search(ctx, SearchRequest{Query: "q"})
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		for _, r := range results {
+			if r.Explanation != nil {
+				t.Errorf("unexpected explanation on %s", r.ID)
+			}
+		}
+	})
+
+	t.Run("Given reranking, boosts and a threshold When explaining Then records each stage", func(t *testing.T) {
+		// Given
+		deps := newDeps()
+		deps.Config = Config{
+			Project:         "/src/here",
+			ProjectBoost:    1.5,
+			FeedbackRanking: FeedbackRankingPrior,
+			ScoreThreshold:  0.3,
+		}
+		deps.Reranker = &MockReranker{Available: true, ScoreFunc: func(doc reranking.Document) float64 {
+			return map[string]float64{"a": 0.5, "b": 0.4, "c": 0.2}[doc.ID]
+		}}
+		deps.Metadata.(*MockMetadataStorage).Feedback = []*storage.FeedbackRecord{{ItemID: "a", Useful: true, Timestamp: time.Now()}}
+		engine := NewSearchEngineWithDeps(deps)
+
+		// When
+		results, err := engine.Search(ctx, SearchRequest{Query: "q", Scope: ScopeBoth, Explain: true})
+
+		// Then
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		if len(results) != 3 || results[0].ID != "b" {
+			t.Fatalf("expected boosted b first of 3, got %v", resultIDs(results))
+		}
+		b, a, c := results[0].Explanation, results[1].Explanation, results[2].Explanation
+		if b.RerankScore == nil || *b.RerankScore != 0.4 || b.ProjectBoost != 1.5 {
+			t.Errorf("explanation for b = %+v", b)
+		}
+		if a.ProjectBoost != 0 || a.FeedbackBoost <= 1 {
+			t.Errorf("expected a feedback boost but no project boost on a, got %+v", a)
+		}
+		if b.NearThreshold || !c.NearThreshold || c.Threshold != 0.3 {
+			t.Errorf("expected only c near the 0.3 threshold: b=%+v c=%+v", b, c)
+		}
+	})
+}
@@ -0,0 +1,170 @@
+package core
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/anthropics/aef/codex/internal/storage"
+)
+
+// Feedback ranking modes for Config.FeedbackRanking.
+const (
+	FeedbackRankingOff   = "off"
+	FeedbackRankingRRF   = "rrf"
+	FeedbackRankingPrior = "prior"
+)
+
+// Feedback ranking defaults, used when Config leaves them unset.
+const (
+	DefaultFeedbackWeight        = 0.2
+	DefaultFeedbackHalfLife      = 30 * 24 * time.Hour
+	DefaultFeedbackProjectWeight = 2.0
+)
+
+// FeedbackStats aggregates the feedback recorded on an item.
+type FeedbackStats struct {
+	Useful    int       `json:"useful"`
+	NotUseful int       `json:"not_useful"`
+	LastAt    time.Time `json:"last_at"`
+
+	// Score is the net feedback in (-1, 1): useful minus not-useful votes,
+	// each weighted by age and project, over the total weight plus one.
+	// The extra one keeps a single vote from counting as a verdict.
+	Score float64 `json:"score"`
+}
+
+// feedbackConfig holds the resolved feedback weighting settings.
+type feedbackConfig struct {
+	halfLife      time.Duration
+	projectWeight float64
+}
+
+func (c Config) feedbackConfig() feedbackConfig {
+	fc := feedbackConfig{halfLife: c.FeedbackHalfLife, projectWeight: c.FeedbackProjectWeight}
+	if fc.halfLife <= 0 {
+		fc.halfLife = DefaultFeedbackHalfLife
+	}
+	if fc.projectWeight <= 0 {
+		fc.projectWeight = DefaultFeedbackProjectWeight
+	}
+	return fc
+}
+
+// aggregateFeedback computes per-item stats from feedback records. Each
+// vote's weight halves every halfLife, and votes given in project are
+// weighted by projectWeight.
+func aggregateFeedback(records []*storage.FeedbackRecord, project string, now time.Time, cfg feedbackConfig) map[string]*FeedbackStats {
+	stats := make(map[string]*FeedbackStats)
+	net := make(map[string]float64)
+	total := make(map[string]float64)
+
+	for _, r := range records {
+		s := stats[r.ItemID]
+		if s == nil {
+			s = &FeedbackStats{}
+			stats[r.ItemID] = s
+		}
+		if r.Timestamp.After(s.LastAt) {
+			s.LastAt = r.Timestamp
+		}
+
+		age := now.Sub(r.Timestamp)
+		if age < 0 {
+			age = 0
+		}
+		w := math.Pow(0.5, float64(age)/float64(cfg.halfLife))
+		if project != "" && r.Project == project {
+			w *= cfg.projectWeight
+		}
+
+		if r.Useful {
+			s.Useful++
+			net[r.ItemID] += w
+		} else {
+			s.NotUseful++
+			net[r.ItemID] -= w
+		}
+		total[r.ItemID] += w
+	}
+
+	for id, s := range stats {
+		s.Score = net[id] / (total[id] + 1)
+	}
+	return stats
+}
+
+// feedbackRanking ranks the items with positive feedback scores, for use
+// as an extra RRF signal. Negative feedback does not demote items here; it
+// only keeps them out of this ranking.
+func feedbackRanking(stats map[string]*FeedbackStats) []storage.ScoredResult {
+	var ranked []storage.ScoredResult
+	for id, s := range stats {
+		if s.Score > 0 {
+			ranked = append(ranked, storage.ScoredResult{ID: id, Score: s.Score})
+		}
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].ID < ranked[j].ID
+	})
+	return ranked
+}
+
+// applyFeedbackPrior multiplies each result's score by 1 + weight*score
+// and re-sorts.
+func applyFeedbackPrior(results []SearchResult, stats map[string]*FeedbackStats, weight float64) []SearchResult {
+	for i := range results {
+		if s, ok := stats[results[i].ID]; ok {
+			results[i].Score *= 1 + weight*s.Score
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	return results
+}
+
+// loadFeedback returns the feedback stats of the given items.
+func (e *SearchEngine) loadFeedback(ids []string, project string) (map[string]*FeedbackStats, error) {
+	if e.metadata == nil {
+		return nil, nil
+	}
+	records, err := e.metadata.ListFeedback(ids)
+	if err != nil {
+		return nil, err
+	}
+	return aggregateFeedback(records, project, time.Now(), e.config.feedbackConfig()), nil
+}
+
+// candidateIDs returns the distinct IDs in the ranked lists.
+func candidateIDs(lists []RankedList) []string {
+	seen := make(map[string]bool)
+	var ids []string
+	for _, l := range lists {
+		for _, r := range l.Results {
+			if !seen[r.ID] {
+				seen[r.ID] = true
+				ids = append(ids, r.ID)
+			}
+		}
+	}
+	return ids
+}
+
+func resultIDs(results []SearchResult) []string {
+	ids := make([]string, len(results))
+	for i, r := range results {
+		ids[i] = r.ID
+	}
+	return ids
+}
+
+func orDefaultFloat(x, def float64) float64 {
+	if x <= 0 {
+		return def
+	}
+	return x
+}
@@ -0,0 +1,150 @@
+package core
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/anthropics/aef/codex/internal/storage"
+)
+
+func TestAggregateFeedback(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	cfg := feedbackConfig{halfLife: 24 * time.Hour, projectWeight: 3}
+
+	t.Run("Given mixed votes When aggregating Then counts and nets them", func(t *testing.T) {
+		records := []*storage.FeedbackRecord{
+			{ItemID: "a", Useful: true, Timestamp: now},
+			{ItemID: "a", Useful: true, Timestamp: now},
+			{ItemID: "a", Useful: false, Timestamp: now.Add(-time.Hour)},
+		}
+		stats := aggregateFeedback(records, "", now, cfg)
+
+		s := stats["a"]
+		if s == nil || s.Useful != 2 || s.NotUseful != 1 || !s.LastAt.Equal(now) {
+			t.Fatalf("unexpected stats %+v", s)
+		}
+		if s.Score <= 0 || s.Score >= 1 {
+			t.Errorf("expected a positive score below 1, got %v", s.Score)
+		}
+	})
+
+	t.Run("Given old feedback When aggregating Then it counts less", func(t *testing.T) {
+		records := []*storage.FeedbackRecord{
+			{ItemID: "fresh", Useful: true, Timestamp: now},
+			{ItemID: "stale", Useful: true, Timestamp: now.Add(-24 * time.Hour)},
+		}
+		stats := aggregateFeedback(records, "", now, cfg)
+
+		// One vote of weight w scores w/(w+1): 1/2 fresh, 0.5/1.5 after one half-life
+		if math.Abs(stats["fresh"].Score-0.5) > 1e-9 || math.Abs(stats["stale"].Score-1.0/3) > 1e-9 {
+			t.Errorf("fresh = %v, stale = %v; want 0.5 and 0.333", stats["fresh"].Score, stats["stale"].Score)
+		}
+	})
+
+	t.Run("Given feedback from two projects When aggregating Then the current project dominates", func(t *testing.T) {
+		records := []*storage.FeedbackRecord{
+			{ItemID: "a", Project: "/src/here", Useful: true, Timestamp: now},
+			{ItemID: "a", Project: "/src/other", Useful: false, Timestamp: now},
+		}
+
+		if s := aggregateFeedback(records, "/src/here", now, cfg)["a"].Score; s <= 0 {
+			t.Errorf("expected positive score in /src/here, got %v", s)
+		}
+		if s := aggregateFeedback(records, "/src/other", now, cfg)["a"].Score; s >= 0 {
+			t.Errorf("expected negative score in /src/other, got %v", s)
+		}
+	})
+}
+
+func TestFeedbackRanking(t *testing.T) {
+	stats := map[string]*FeedbackStats{
+		"low":      {Score: 0.2},
+		"high":     {Score: 0.6},
+		"negative": {Score: -0.5},
+	}
+	ranked := feedbackRanking(stats)
+	if len(ranked) != 2 || ranked[0].ID != "high" || ranked[1].ID != "low" {
+		t.Errorf("feedbackRanking() = %v, want [high low]", ranked)
+	}
+}
+
+func TestSearchEngine_Search_Feedback(t *testing.T) {
+	ctx := context.Background()
+
+	// a outranks b on retrieval; b has three recent useful votes
+	newEngine := func(mode string) *SearchEngine {
+		vectorStore := NewMockVectorStorage()
+		vectorStore.SearchFunc = func(ctx context.Context, queryVec []float32, limit int) ([]storage.ScoredResult, error) {
+			return []storage.ScoredResult{{ID: "a", Score: 0.9}, {ID: "b", Score: 0.8}}, nil
+		}
+		metaStore := NewMockMetadataStorage()
+		metaStore.Items["a"] = &storage.ItemRecord{ID: "a", Title: "A", Content: "a"}
+		metaStore.Items["b"] = &storage.ItemRecord{ID: "b", Title: "B", Content: "b"}
+		for i := 0; i < 3; i++ {
+			metaStore.Feedback = append(metaStore.Feedback, &storage.FeedbackRecord{ItemID: "b", Useful: true, Timestamp: time.Now()})
+		}
+		return NewSearchEngineWithDeps(SearchEngineDeps{
+			Config:   Config{FeedbackRanking: mode},
+			VecStore: vectorStore,
+			Metadata: metaStore,
+			Embedder: NewMockEmbedder(),
+		})
+	}
+
+	tests := []struct {
+		name    string
+		mode    string
+		wantTop string
+	}{
+		{name: "Given ranking off When searching Then order is unchanged", mode: "", wantTop: "a"},
+		{name: "Given rrf ranking When searching Then useful item rises", mode: FeedbackRankingRRF, wantTop: "b"},
+		{name: "Given prior ranking When searching Then useful item rises", mode: FeedbackRankingPrior, wantTop: "b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// When
+			results, err := newEngine(tt.mode).Search(ctx, SearchRequest{Query: "q", Limit: 10})
+
+			// Then
+			if err != nil {
+				t.Fatalf("Search failed: %v", err)
+			}
+			if len(results) != 2 || results[0].ID != tt.wantTop {
+				t.Fatalf("expected %s first, got %v", tt.wantTop, resultIDs(results))
+			}
+			for _, r := range results {
+				switch r.ID {
+				case "a":
+					if r.Feedback != nil {
+						t.Errorf("expected no feedback on a, got %+v", r.Feedback)
+					}
+				case "b":
+					if r.Feedback == nil || r.Feedback.Useful != 3 {
+						t.Errorf("expected 3 useful votes on b, got %+v", r.Feedback)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSearchEngine_RecordFeedback_Project(t *testing.T) {
+	metaStore := NewMockMetadataStorage()
+	engine := NewSearchEngineWithDeps(SearchEngineDeps{
+		Config:   Config{Project: "/src/here"},
+		Metadata: metaStore,
+	})
+
+	if err := engine.RecordFeedback(&Feedback{ItemID: "a", Useful: true}); err != nil {
+		t.Fatalf("RecordFeedback: %v", err)
+	}
+	if err := engine.RecordFeedback(&Feedback{ItemID: "a", Project: "/src/other", Useful: true}); err != nil {
+		t.Fatalf("RecordFeedback: %v", err)
+	}
+	if metaStore.Feedback[0].Project != "/src/here" || metaStore.Feedback[1].Project != "/src/other" {
+		t.Errorf("projects = %q, %q", metaStore.Feedback[0].Project, metaStore.Feedback[1].Project)
+	}
+}
@@ -1,40 +1,183 @@
 package core
 
 import (
+	"fmt"
 	"sort"
+	"strconv"
+	"strings"
+	"unicode"
 
 	"github.com/anthropics/aef/codex/internal/storage"
 )
 
-// reciprocalRankFusion merges vector and keyword search results using RRF.
-// k is the standard RRF constant (typically 60).
-// Each result's fused score is sum(1 / (k + rank)) across all result lists it appears in.
-func reciprocalRankFusion(vectorResults []storage.ScoredResult, keywordResults []SearchResult, k float64) []SearchResult {
-	return reciprocalRankFusionMulti([][]storage.ScoredResult{vectorResults}, keywordResults, k)
+// Fusion strategies for Config.Fusion and SearchRequest.Fusion.
+const (
+	FusionRRF     = "rrf"
+	FusionCombSUM = "combsum"
+	FusionCombMNZ = "combmnz"
+	FusionConvex  = "convex"
+)
+
+// DefaultFusionK is the RRF rank constant used when Config.FusionK is unset.
+const DefaultFusionK = 60.0
+
+// Names of the ranked lists that search fuses, as used in FusionWeights.
+const (
+	ListVector    = "vector"
+	ListKeyword   = "keyword"
+	ListFeedback  = "feedback"
+	ListExpansion = "expansion" // vector results of each query variant
+)
+
+// RankedList is one retriever's candidates, best first.
+type RankedList struct {
+	Name    string
+	Weight  float64
+	Results []storage.ScoredResult
 }
 
-// reciprocalRankFusionMulti merges multiple vector result lists and keyword results using RRF.
-// Each vector result list and the keyword result list contribute 1/(k+rank) to a document's score.
-func reciprocalRankFusionMulti(vectorResultSets [][]storage.ScoredResult, keywordResults []SearchResult, k float64) []SearchResult {
+// Fusion merges ranked lists into one score per item. Higher is better.
+type Fusion interface {
+	Name() string
+	Fuse(lists []RankedList) map[string]float64
+}
+
+// NewFusion returns the named fusion strategy. k is the RRF rank constant;
+// 0 uses DefaultFusionK. An empty name selects RRF.
+func NewFusion(name string, k float64) (Fusion, error) {
+	switch name {
+	case "", FusionRRF:
+		return WeightedRRF{K: orDefaultFloat(k, DefaultFusionK)}, nil
+	case FusionCombSUM:
+		return CombSUM{}, nil
+	case FusionCombMNZ:
+		return CombMNZ{}, nil
+	case FusionConvex:
+		return Convex{}, nil
+	default:
+		return nil, fmt.Errorf("unknown fusion %q (want rrf, combsum, combmnz or convex)", name)
+	}
+}
+
+// WeightedRRF scores each item sum(weight / (K + rank)) over the lists it
+// appears in. It uses ranks only, so it needs no score calibration.
+type WeightedRRF struct {
+	K float64
+}
+
+func (WeightedRRF) Name() string { return FusionRRF }
+
+func (f WeightedRRF) Fuse(lists []RankedList) map[string]float64 {
 	scores := make(map[string]float64)
-	meta := make(map[string]SearchResult)
+	for _, l := range lists {
+		for rank, r := range l.Results {
+			scores[r.ID] += l.Weight / (f.K + float64(rank+1))
+		}
+	}
+	return scores
+}
 
-	// Score each vector result set by rank position
-	for _, vectorResults := range vectorResultSets {
-		for rank, r := range vectorResults {
-			scores[r.ID] += 1.0 / (k + float64(rank+1))
+// CombSUM scores each item by the weighted sum of its min-max normalized
+// scores. Unlike RRF it keeps how far ahead a result is within its list.
+type CombSUM struct{}
+
+func (CombSUM) Name() string { return FusionCombSUM }
+
+func (CombSUM) Fuse(lists []RankedList) map[string]float64 {
+	scores := make(map[string]float64)
+	for _, l := range lists {
+		for id, s := range normalizeScores(l.Results) {
+			scores[id] += l.Weight * s
+		}
+	}
+	return scores
+}
+
+// CombMNZ is CombSUM multiplied by the number of lists an item appears in,
+// favouring items that several retrievers agree on.
+type CombMNZ struct{}
+
+func (CombMNZ) Name() string { return FusionCombMNZ }
+
+func (CombMNZ) Fuse(lists []RankedList) map[string]float64 {
+	scores := CombSUM{}.Fuse(lists)
+	hits := make(map[string]int, len(scores))
+	for _, l := range lists {
+		for _, r := range l.Results {
+			hits[r.ID]++
+		}
+	}
+	for id := range scores {
+		scores[id] *= float64(hits[id])
+	}
+	return scores
+}
+
+// Convex is CombSUM with the weights rescaled to sum to one, so weights read
+// as mixing proportions (vector:0.7,keyword:0.3) and fused scores lie in
+// [0, 1]. It ranks like CombSUM with the same weights.
+type Convex struct{}
+
+func (Convex) Name() string { return FusionConvex }
+
+func (Convex) Fuse(lists []RankedList) map[string]float64 {
+	var total float64
+	for _, l := range lists {
+		total += l.Weight
+	}
+	if total == 0 {
+		return map[string]float64{}
+	}
+	scaled := make([]RankedList, len(lists))
+	for i, l := range lists {
+		scaled[i] = RankedList{Name: l.Name, Weight: l.Weight / total, Results: l.Results}
+	}
+	return CombSUM{}.Fuse(scaled)
+}
+
+// normalizeScores min-max normalizes a list's scores to [0, 1]. A list whose
+// scores are all equal normalizes to 1.
+func normalizeScores(results []storage.ScoredResult) map[string]float64 {
+	norm := make(map[string]float64, len(results))
+	if len(results) == 0 {
+		return norm
+	}
+	lo, hi := results[0].Score, results[0].Score
+	for _, r := range results[1:] {
+		if r.Score < lo {
+			lo = r.Score
+		}
+		if r.Score > hi {
+			hi = r.Score
+		}
+	}
+	for _, r := range results {
+		if hi == lo {
+			norm[r.ID] = 1
+		} else {
+			norm[r.ID] = (r.Score - lo) / (hi - lo)
 		}
 	}
+	return norm
+}
 
-	// Score keyword results by rank position
-	for rank, r := range keywordResults {
-		scores[r.ID] += 1.0 / (k + float64(rank+1))
+// fuseResults fuses the lists and returns results sorted by fused score,
+// carrying keyword results' metadata. Lists with zero weight are left out.
+func fuseResults(f Fusion, lists []RankedList, keywordResults []SearchResult) []SearchResult {
+	active := lists[:0:0]
+	for _, l := range lists {
+		if l.Weight > 0 {
+			active = append(active, l)
+		}
+	}
+
+	meta := make(map[string]SearchResult, len(keywordResults))
+	for _, r := range keywordResults {
 		meta[r.ID] = r
 	}
 
-	// Build merged results
 	var merged []SearchResult
-	for id, score := range scores {
+	for id, score := range f.Fuse(active) {
 		result, ok := meta[id]
 		if !ok {
 			// Vector-only result; caller must hydrate metadata separately
@@ -45,8 +188,185 @@ func reciprocalRankFusionMulti(vectorResultSets [][]storage.ScoredResult, keywor
 	}
 
 	sort.Slice(merged, func(i, j int) bool {
-		return merged[i].Score > merged[j].Score
+		if merged[i].Score != merged[j].Score {
+			return merged[i].Score > merged[j].Score
+		}
+		return merged[i].ID < merged[j].ID
 	})
-
 	return merged
 }
+
+// keywordRanking converts keyword results to a ranked list's results.
+func keywordRanking(keywordResults []SearchResult) []storage.ScoredResult {
+	ranked := make([]storage.ScoredResult, len(keywordResults))
+	for i, r := range keywordResults {
+		ranked[i] = storage.ScoredResult{ID: r.ID, Score: r.Score}
+	}
+	return ranked
+}
+
+// reciprocalRankFusion merges vector and keyword search results using RRF.
+// k is the standard RRF constant (typically 60).
+// Each result's fused score is sum(1 / (k + rank)) across all result lists it appears in.
+func reciprocalRankFusion(vectorResults []storage.ScoredResult, keywordResults []SearchResult, k float64) []SearchResult {
+	return reciprocalRankFusionMulti([][]storage.ScoredResult{vectorResults}, keywordResults, k)
+}
+
+// reciprocalRankFusionMulti merges multiple vector result lists and keyword results using RRF.
+// Each vector result list and the keyword result list contribute 1/(k+rank) to a document's score.
+func reciprocalRankFusionMulti(vectorResultSets [][]storage.ScoredResult, keywordResults []SearchResult, k float64) []SearchResult {
+	var lists []RankedList
+	for _, vectorResults := range vectorResultSets {
+		lists = append(lists, RankedList{Name: ListVector, Weight: 1, Results: vectorResults})
+	}
+	lists = append(lists, RankedList{Name: ListKeyword, Weight: 1, Results: keywordRanking(keywordResults)})
+	return fuseResults(WeightedRRF{K: k}, lists, keywordResults)
+}
+
+// FusionWeights maps ranked list names (vector, keyword, feedback,
+// expansion) to their
+// fusion weights. Lists not named weigh 1; a weight of 0 leaves a list out.
+type FusionWeights map[string]float64
+
+// Weight returns the weight of the named list.
+func (w FusionWeights) Weight(list string) float64 {
+	if v, ok := w[list]; ok {
+		return v
+	}
+	return 1
+}
+
+// Validate checks that every list name is known and no weight is negative.
+func (w FusionWeights) Validate() error {
+	for name, v := range w {
+		switch name {
+		case ListVector, ListKeyword, ListFeedback, ListExpansion:
+		default:
+			return fmt.Errorf("unknown fusion list %q (want vector, keyword, feedback or expansion)", name)
+		}
+		if v < 0 {
+			return fmt.Errorf("fusion weight for %s must not be negative, got %v", name, v)
+		}
+	}
+	return nil
+}
+
+// String formats the weights as ParseFusionWeights accepts them.
+func (w FusionWeights) String() string {
+	names := make([]string, 0, len(w))
+	for name := range w {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + ":" + strconv.FormatFloat(w[name], 'g', -1, 64)
+	}
+	return strings.Join(parts, ",")
+}
+
+// ParseFusionWeights parses weights given as "vector:1,keyword:2". An empty
+// string returns nil weights.
+func ParseFusionWeights(s string) (FusionWeights, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	w := make(FusionWeights)
+	for _, part := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid fusion weight %q: expected list:weight", part)
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fusion weight %q: %w", part, err)
+		}
+		w[strings.TrimSpace(name)] = v
+	}
+	if err := w.Validate(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// fusionFor resolves the fusion strategy and list weights for a request.
+// Request settings override the config; without request weights, queries
+// that look like code use Config.CodeFusionWeights when set.
+func (e *SearchEngine) fusionFor(req SearchRequest) (Fusion, FusionWeights, error) {
+	name := req.Fusion
+	if name == "" {
+		name = e.config.Fusion
+	}
+	fusion, err := NewFusion(name, e.config.FusionK)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	weights := req.FusionWeights
+	if weights == nil {
+		weights = e.config.FusionWeights
+		if e.config.CodeFusionWeights != nil && looksLikeCode(req.Query) {
+			weights = e.config.CodeFusionWeights
+		}
+	}
+	if err := weights.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return fusion, weights, nil
+}
+
+// looksLikeCode reports whether a query reads as code rather than prose:
+// call or operator syntax, snake_case or camelCase identifiers, qualified
+// names like os.Getenv, or file paths.
+func looksLikeCode(query string) bool {
+	for _, marker := range []string{"()", "::", "->", ":=", "=>", "{", "}", "[]", "`"} {
+		if strings.Contains(query, marker) {
+			return true
+		}
+	}
+	for _, token := range strings.Fields(query) {
+		token = strings.Trim(token, `.,;:!?"'()`)
+		if isCodeToken(token) {
+			return true
+		}
+	}
+	return false
+}
+
+func isCodeToken(token string) bool {
+	if strings.Count(token, "/") >= 2 {
+		return true
+	}
+	if i := strings.Index(token, "_"); i > 0 && i < len(token)-1 {
+		return true
+	}
+
+	// Qualified names and file names: every dotted part at least two
+	// characters, which skips abbreviations like "e.g".
+	if parts := strings.Split(token, "."); len(parts) > 1 {
+		qualified := true
+		for _, p := range parts {
+			if len(p) < 2 {
+				qualified = false
+				break
+			}
+		}
+		if qualified {
+			return true
+		}
+	}
+
+	// camelCase has a lower-to-upper transition; PascalCase needs two so
+	// that words like "JavaScript" read as prose.
+	runes := []rune(token)
+	transitions := 0
+	for i := 1; i < len(runes); i++ {
+		if unicode.IsLower(runes[i-1]) && unicode.IsUpper(runes[i]) {
+			transitions++
+		}
+	}
+	if len(runes) > 0 && unicode.IsLower(runes[0]) {
+		return transitions >= 1
+	}
+	return transitions >= 2
+}
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"context"
 	"math"
 	"testing"
 
@@ -168,3 +169,172 @@ func TestReciprocalRankFusion_SortedByScore(t *testing.T) {
 		}
 	}
 }
+
+func TestFusionStrategies(t *testing.T) {
+	// "a" leads vector search by a wide margin; "b" leads keyword search narrowly
+	vector := []storage.ScoredResult{{ID: "a", Score: 0.9}, {ID: "b", Score: 0.2}, {ID: "c", Score: 0.1}}
+	keyword := []storage.ScoredResult{{ID: "b", Score: 10}, {ID: "a", Score: 9.5}, {ID: "d", Score: 1}}
+	lists := func(vw, kw float64) []RankedList {
+		return []RankedList{
+			{Name: ListVector, Weight: vw, Results: vector},
+			{Name: ListKeyword, Weight: kw, Results: keyword},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		fusion  string
+		vw, kw  float64
+		wantTop string
+	}{
+		{name: "Given equal weights When fusing with rrf Then the tie breaks by ID", fusion: FusionRRF, vw: 1, kw: 1, wantTop: "a"},
+		{name: "Given keyword-heavy weights When fusing with rrf Then keyword leader wins", fusion: FusionRRF, vw: 1, kw: 2, wantTop: "b"},
+		{name: "Given equal weights When fusing with combsum Then score margins count", fusion: FusionCombSUM, vw: 1, kw: 1, wantTop: "a"},
+		{name: "Given keyword-heavy weights When fusing with combmnz Then keyword leader wins", fusion: FusionCombMNZ, vw: 1, kw: 20, wantTop: "b"},
+		{name: "Given vector-heavy proportions When fusing convexly Then vector leader wins", fusion: FusionConvex, vw: 0.7, kw: 0.3, wantTop: "a"},
+		{name: "Given the vector list disabled When fusing Then keyword order remains", fusion: FusionCombSUM, vw: 0, kw: 1, wantTop: "b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewFusion(tt.fusion, 0)
+			if err != nil {
+				t.Fatalf("NewFusion: %v", err)
+			}
+			merged := fuseResults(f, lists(tt.vw, tt.kw), nil)
+			if merged[0].ID != tt.wantTop {
+				t.Errorf("expected %s first, got %v", tt.wantTop, resultIDs(merged))
+			}
+			for _, r := range merged {
+				if tt.vw == 0 && r.ID == "c" {
+					t.Error("expected the disabled list's items to be left out")
+				}
+			}
+		})
+	}
+
+	t.Run("Given an item in both lists When fusing with combmnz Then its combsum score doubles", func(t *testing.T) {
+		sum := CombSUM{}.Fuse(lists(1, 1))
+		mnz := CombMNZ{}.Fuse(lists(1, 1))
+		if math.Abs(mnz["a"]-2*sum["a"]) > 1e-9 || math.Abs(mnz["c"]-sum["c"]) > 1e-9 {
+			t.Errorf("combsum = %v, combmnz = %v", sum, mnz)
+		}
+	})
+
+	t.Run("Given any weights When fusing convexly Then scores stay within [0, 1]", func(t *testing.T) {
+		for id, s := range (Convex{}).Fuse(lists(3, 5)) {
+			if s < 0 || s > 1 {
+				t.Errorf("score of %s = %v, want within [0, 1]", id, s)
+			}
+		}
+	})
+
+	t.Run("Given an unknown name When creating a fusion Then errors", func(t *testing.T) {
+		if _, err := NewFusion("borda", 0); err == nil {
+			t.Error("expected error for unknown fusion")
+		}
+	})
+}
+
+func TestParseFusionWeights(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "", want: ""},
+		{in: "vector:1,keyword:2.5", want: "keyword:2.5,vector:1"},
+		{in: " vector : 0 , feedback:0.5 ", want: "feedback:0.5,vector:0"},
+		{in: "vector=1", wantErr: true},
+		{in: "vector:x", wantErr: true},
+		{in: "graph:1", wantErr: true},
+		{in: "keyword:-1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			w, err := ParseFusionWeights(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseFusionWeights(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if err == nil && w.String() != tt.want {
+				t.Errorf("ParseFusionWeights(%q) = %q, want %q", tt.in, w.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestLooksLikeCode(t *testing.T) {
+	tests := map[string]bool{
+		"how do we handle retries when the API is down": false,
+		"JavaScript build setup, e.g. bundling":         false,
+		"where is NewSearchEngine called":               true,
+		"parseConfig error handling":                    true,
+		"max_retries default":                           true,
+		"os.Getenv usage":                               true,
+		"internal/core/fusion.go":                       true,
+		"func() returns nil":                            true,
+	}
+	for query, want := range tests {
+		if got := looksLikeCode(query); got != want {
+			t.Errorf("looksLikeCode(%q) = %v, want %v", query, got, want)
+		}
+	}
+}
+
+func TestSearchEngine_Search_Fusion(t *testing.T) {
+	ctx := context.Background()
+
+	// Vector search prefers "prose"; keyword search prefers "code"
+	newEngine := func(cfg Config) *SearchEngine {
+		vectorStore := NewMockVectorStorage()
+		vectorStore.SearchFunc = func(ctx context.Context, queryVec []float32, limit int) ([]storage.ScoredResult, error) {
+			return []storage.ScoredResult{{ID: "prose", Score: 0.9}, {ID: "code", Score: 0.8}}, nil
+		}
+		keywords := NewMockKeywordSearcher()
+		keywords.Results = []storage.KeywordResult{
+			{ID: "code", Title: "Code", Content: "code", Score: 5},
+			{ID: "prose", Title: "Prose", Content: "prose", Score: 4},
+		}
+		return NewSearchEngineWithDeps(SearchEngineDeps{
+			Config:   cfg,
+			VecStore: vectorStore,
+			Keywords: keywords,
+			Embedder: NewMockEmbedder(),
+		})
+	}
+	codeHeavy := Config{
+		FusionWeights:     FusionWeights{ListVector: 2},
+		CodeFusionWeights: FusionWeights{ListVector: 1, ListKeyword: 2},
+	}
+
+	tests := []struct {
+		name    string
+		cfg     Config
+		req     SearchRequest
+		wantTop string
+	}{
+		{name: "Given configured keyword weight When searching Then keyword leader wins", cfg: Config{FusionWeights: FusionWeights{ListKeyword: 2}}, req: SearchRequest{Query: "q"}, wantTop: "code"},
+		{name: "Given code weights and a prose query When searching Then prose weights apply", cfg: codeHeavy, req: SearchRequest{Query: "how are retries done"}, wantTop: "prose"},
+		{name: "Given code weights and a code query When searching Then code weights apply", cfg: codeHeavy, req: SearchRequest{Query: "retryWithBackoff"}, wantTop: "code"},
+		{name: "Given request weights When searching Then they override the config", cfg: codeHeavy, req: SearchRequest{Query: "retryWithBackoff", FusionWeights: FusionWeights{ListKeyword: 0}}, wantTop: "prose"},
+		{name: "Given a request fusion When searching Then it is used", cfg: Config{}, req: SearchRequest{Query: "q", Fusion: FusionCombSUM, FusionWeights: FusionWeights{ListVector: 0.5}}, wantTop: "code"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := newEngine(tt.cfg).Search(ctx, tt.req)
+			if err != nil {
+				t.Fatalf("Search failed: %v", err)
+			}
+			if len(results) == 0 || results[0].ID != tt.wantTop {
+				t.Errorf("expected %s first, got %v", tt.wantTop, resultIDs(results))
+			}
+		})
+	}
+
+	t.Run("Given an unknown request fusion When searching Then errors", func(t *testing.T) {
+		if _, err := newEngine(Config{}).Search(ctx, SearchRequest{Query: "q", Fusion: "borda"}); err == nil {
+			t.Error("expected error for unknown fusion")
+		}
+	})
+}
@@ -0,0 +1,331 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/anthropics/aef/codex/internal/gitrepo"
+	"github.com/anthropics/aef/codex/internal/storage"
+	"github.com/anthropics/aef/codex/internal/walk"
+)
+
+// GitIndexRequest selects the repository, revision and commit range
+// IndexGit indexes.
+type GitIndexRequest struct {
+	// Repo is a directory inside the repository.
+	Repo string
+
+	// Ref, when set, is indexed from the object database without checking
+	// it out, e.g. "release/2.3" or "v2.3.0". Its chunks are tagged with
+	// metadata git_ref and are found with SearchRequest.GitRef. "" indexes
+	// the work tree, including uncommitted changes.
+	Ref string
+
+	// Since is the commit to diff against. "" uses the commit recorded by
+	// the last run for Repo and Ref, and indexes every file when there is none.
+	Since string
+
+	Scope string
+}
+
+// IndexGit indexes the files of a git repository that changed between a
+// base commit and Ref, or the work tree, through the directory pipeline and
+// its ignore rules. Files deleted since the base lose their chunks.
+//
+// Every chunk records the provenance of its file in metadata: git_commit,
+// git_author and git_modified describe the last commit that changed it;
+// work tree files with uncommitted changes have git_uncommitted set and
+// their modification time as git_modified.
+//
+// The indexed commit is recorded per repository and ref once a run
+// completes without failed files, so the next run picks up from there.
+func (idx *Indexer) IndexGit(ctx context.Context, req GitIndexRequest, progress IndexProgressCallback) (*IndexStats, error) {
+	if idx.fileIndex == nil {
+		return nil, fmt.Errorf("git indexing requires a file index")
+	}
+
+	repo, err := gitrepo.Open(ctx, req.Repo)
+	if err != nil {
+		return nil, err
+	}
+	head := req.Ref
+	if head == "" {
+		head = "HEAD"
+	}
+	commit, err := repo.ResolveCommit(ctx, head)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := idx.fileIndex.GetGitIndexState(repo.Dir, req.Ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load git index state: %w", err)
+	}
+	var base string
+	switch {
+	case req.Since != "":
+		if base, err = repo.ResolveCommit(ctx, req.Since); err != nil {
+			return nil, err
+		}
+	case state != nil:
+		// The recorded commit is gone after a force push and garbage collection
+		if base, err = repo.ResolveCommit(ctx, state.Commit); err != nil {
+			log.Printf("Warning: last indexed commit %s of %s is gone, indexing every file\n", state.Commit, repo.Dir)
+			base = ""
+		}
+	}
+
+	filter, err := idx.newFilter(repo.Dir)
+	if err != nil {
+		return nil, err
+	}
+	g := &gitIndex{idx: idx, repo: repo, filter: filter, ref: req.Ref, commit: commit}
+
+	var stats *IndexStats
+	var uncommitted []string
+	if req.Ref != "" {
+		stats, err = g.indexRef(ctx, base, req.Scope, progress)
+	} else {
+		stats, uncommitted, err = g.indexWorkTree(ctx, base, state, req.Scope, progress)
+	}
+	if stats != nil {
+		stats.GitCommit = commit
+		stats.GitBase = base
+	}
+	// Keep the old base so failed files are retried on the next run
+	if err != nil || stats.FilesFailed > 0 {
+		return stats, err
+	}
+
+	err = idx.fileIndex.SaveGitIndexState(&storage.GitIndexState{
+		Repo:        repo.Dir,
+		Ref:         req.Ref,
+		Commit:      commit,
+		Uncommitted: uncommitted,
+		IndexedAt:   time.Now(),
+	})
+	if err != nil {
+		return stats, fmt.Errorf("failed to save git index state: %w", err)
+	}
+	return stats, nil
+}
+
+// gitIndex is one IndexGit run.
+type gitIndex struct {
+	idx    *Indexer
+	repo   *gitrepo.Repo
+	filter *walk.Filter
+	ref    string // "" for the work tree
+	commit string
+}
+
+// indexRef indexes the files of the ref's commit, or those that changed
+// since base, reading them from the object database.
+func (g *gitIndex) indexRef(ctx context.Context, base, scope string, progress IndexProgressCallback) (*IndexStats, error) {
+	blobs, err := g.repo.Blobs(ctx, g.commit)
+	if err != nil {
+		return nil, err
+	}
+	defer blobs.Close()
+
+	if base == "" {
+		files, err := g.repo.Files(ctx, g.commit)
+		if err != nil {
+			return nil, err
+		}
+		src, err := g.refSource(ctx, blobs, files)
+		if err != nil {
+			return nil, err
+		}
+		return g.idx.indexTree(ctx, src, trackKey(g.repo.Dir, g.ref), scope, progress)
+	}
+
+	changes, err := g.repo.Diff(ctx, base, g.commit)
+	if err != nil {
+		return nil, err
+	}
+	var changed, removed []string
+	for _, c := range changes {
+		if c.Deleted {
+			removed = append(removed, g.abs(c.Path))
+		} else {
+			changed = append(changed, c.Path)
+		}
+	}
+	src, err := g.refSource(ctx, blobs, changed)
+	if err != nil {
+		return nil, err
+	}
+	return g.idx.syncFiles(ctx, src, removed, scope, progress)
+}
+
+// refSource lists files of the ref's commit that pass the filter's ignore
+// rules and reads them through blobs.
+func (g *gitIndex) refSource(ctx context.Context, blobs *gitrepo.Blobs, files []string) (pipelineSource, error) {
+	commits, err := g.repo.LastCommits(ctx, g.commit, files)
+	if err != nil {
+		return pipelineSource{}, err
+	}
+
+	list := func(fn func(path string) error, skipped func(walk.Skipped)) error {
+		for _, rel := range files {
+			path := g.abs(rel)
+			if g.filter.Skip(path, false) {
+				continue
+			}
+			if err := fn(path); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	read := func(path string) ([]byte, string, error) {
+		content, err := blobs.Read(g.rel(path))
+		if err != nil {
+			return nil, "", err
+		}
+		if g.filter.TooLarge(int64(len(content))) {
+			return nil, walk.SkipTooLarge, nil
+		}
+		if reason := g.filter.CheckContent(path, content); reason != "" {
+			return nil, reason, nil
+		}
+		return content, "", nil
+	}
+	return pipelineSource{
+		files:    list,
+		read:     read,
+		revision: g.ref,
+		request:  g.provenance(commits, nil),
+	}, nil
+}
+
+// indexWorkTree indexes the work tree, or the files that changed since
+// base plus those that were uncommitted in the last run. It returns the
+// files that now differ from the commit.
+func (g *gitIndex) indexWorkTree(ctx context.Context, base string, state *storage.GitIndexState, scope string, progress IndexProgressCallback) (*IndexStats, []string, error) {
+	dirtyChanges, err := g.repo.Diff(ctx, g.commit, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	dirty := make(map[string]bool)
+	var uncommitted []string
+	for _, c := range dirtyChanges {
+		if !c.Deleted {
+			dirty[c.Path] = true
+			uncommitted = append(uncommitted, c.Path)
+		}
+	}
+
+	// Only committed files have a last commit; asking for others would
+	// walk the whole history
+	tracked, err := g.repo.Files(ctx, g.commit)
+	if err != nil {
+		return nil, nil, err
+	}
+	isTracked := make(map[string]bool, len(tracked))
+	for _, rel := range tracked {
+		isTracked[rel] = true
+	}
+	lastCommits := func(files []string) (map[string]gitrepo.Commit, error) {
+		var query []string
+		for _, rel := range files {
+			if isTracked[rel] {
+				query = append(query, rel)
+			}
+		}
+		return g.repo.LastCommits(ctx, g.commit, query)
+	}
+
+	if base == "" {
+		commits, err := lastCommits(tracked)
+		if err != nil {
+			return nil, nil, err
+		}
+		src := dirSource(g.filter, g.filter.Files)
+		src.request = g.provenance(commits, dirty)
+		stats, err := g.idx.indexTree(ctx, src, fileKey(g.repo.Dir), scope, progress)
+		return stats, uncommitted, err
+	}
+
+	changes, err := g.repo.Diff(ctx, base, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	if state != nil {
+		// Files reverted or committed since the last run
+		for _, rel := range state.Uncommitted {
+			changes = append(changes, gitrepo.Change{Path: rel})
+		}
+	}
+
+	listed := make(map[string]bool)
+	var changed, paths, removed []string
+	for _, c := range changes {
+		if listed[c.Path] {
+			continue
+		}
+		listed[c.Path] = true
+		path := g.abs(c.Path)
+		if _, err := os.Lstat(path); c.Deleted || err != nil {
+			removed = append(removed, path)
+			continue
+		}
+		changed = append(changed, c.Path)
+		paths = append(paths, path)
+	}
+
+	commits, err := lastCommits(changed)
+	if err != nil {
+		return nil, nil, err
+	}
+	files := func(fn func(path string) error, skipped func(walk.Skipped)) error {
+		return g.filter.Paths(paths, fn, skipped)
+	}
+	src := dirSource(g.filter, files)
+	src.request = g.provenance(commits, dirty)
+	stats, err := g.idx.syncFiles(ctx, src, removed, scope, progress)
+	return stats, uncommitted, err
+}
+
+// provenance returns the request hook that records where each file comes
+// from: its last commit and, for dirty work tree files, its modification time.
+func (g *gitIndex) provenance(commits map[string]gitrepo.Commit, dirty map[string]bool) func(*IndexRequest) {
+	return func(req *IndexRequest) {
+		rel := g.rel(req.FilePath)
+		meta := make(map[string]any)
+		if g.ref != "" {
+			meta["git_ref"] = g.ref
+		}
+		if c, ok := commits[rel]; ok {
+			meta["git_commit"] = c.SHA
+			meta["git_author"] = c.Author
+			meta["git_modified"] = c.Time.UTC().Format(time.RFC3339)
+		}
+		if dirty[rel] {
+			meta["git_uncommitted"] = true
+			if info, err := os.Stat(req.FilePath); err == nil {
+				meta["git_modified"] = info.ModTime().UTC().Format(time.RFC3339)
+			}
+		}
+		req.Metadata = meta
+	}
+}
+
+// abs converts a repository path to an absolute path.
+func (g *gitIndex) abs(rel string) string {
+	return filepath.Join(g.repo.Dir, filepath.FromSlash(rel))
+}
+
+// rel converts an absolute path to a repository path.
+func (g *gitIndex) rel(path string) string {
+	rel, err := filepath.Rel(g.repo.Dir, path)
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
@@ -0,0 +1,151 @@
+package core
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// gitTestRepo creates a repository in a temporary directory and returns
+// its path and a function running git in it.
+func gitTestRepo(t *testing.T) (string, func(args ...string)) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir, _ := filepath.EvalSymlinks(t.TempDir())
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=Ada", "GIT_AUTHOR_EMAIL=ada@example.com",
+			"GIT_COMMITTER_NAME=Ada", "GIT_COMMITTER_EMAIL=ada@example.com",
+			"GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	git("init", "-q", "-b", "main")
+	return dir, git
+}
+
+// itemMetadata returns the metadata of the first item stored for path
+// whose git_ref is ref.
+func itemMetadata(metaStore *MockMetadataStorage, path, ref string) map[string]any {
+	metaStore.mu.Lock()
+	defer metaStore.mu.Unlock()
+	for _, item := range metaStore.Items {
+		if gitRef, _ := item.Metadata["git_ref"].(string); item.Source == path && gitRef == ref {
+			return item.Metadata
+		}
+	}
+	return nil
+}
+
+func TestIndexer_IndexGit(t *testing.T) {
+	ctx := context.Background()
+	dir, git := gitTestRepo(t)
+	write := func(name, content string) {
+		os.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+	}
+	write("a.go", "a1")
+	write("b.go", "b1")
+	git("add", "-A")
+	git("commit", "-q", "-m", "first")
+	git("tag", "v1")
+
+	idx, _, _, metaStore, files := newIncrementalIndexer()
+
+	t.Run("Given a repository never indexed, When indexing the work tree, Then every file is indexed with its last commit", func(t *testing.T) {
+		stats, err := idx.IndexGit(ctx, GitIndexRequest{Repo: dir, Scope: "project"}, nil)
+		if err != nil {
+			t.Fatalf("IndexGit failed: %v", err)
+		}
+		if stats.FilesIndexed != 2 || stats.GitBase != "" || stats.GitCommit == "" {
+			t.Fatalf("stats = %+v, want 2 files indexed from no base", stats)
+		}
+		meta := itemMetadata(metaStore, filepath.Join(dir, "a.go"), "")
+		if meta["git_commit"] != stats.GitCommit || meta["git_author"] != "Ada <ada@example.com>" || meta["git_modified"] == nil {
+			t.Errorf("metadata = %v, want provenance of %s", meta, stats.GitCommit)
+		}
+		st, _ := files.GetGitIndexState(dir, "")
+		if st == nil || st.Commit != stats.GitCommit {
+			t.Errorf("state = %+v, want commit %s", st, stats.GitCommit)
+		}
+	})
+
+	t.Run("Given new commits and uncommitted changes, When indexing again, Then only the changes are indexed", func(t *testing.T) {
+		first, _ := files.GetGitIndexState(dir, "")
+		write("a.go", "a1\na2")
+		os.Remove(filepath.Join(dir, "b.go"))
+		git("add", "-A")
+		git("commit", "-q", "-m", "second")
+		write("c.go", "c1")
+
+		stats, err := idx.IndexGit(ctx, GitIndexRequest{Repo: dir, Scope: "project"}, nil)
+		if err != nil {
+			t.Fatalf("IndexGit failed: %v", err)
+		}
+		if stats.GitBase != first.Commit || stats.FilesIndexed != 2 || stats.FilesRemoved != 1 {
+			t.Errorf("stats = %+v, want a.go and c.go indexed and b.go removed since %s", stats, first.Commit)
+		}
+		if _, ok := files.Files[fileKey(filepath.Join(dir, "b.go"))]; ok {
+			t.Error("b.go still tracked")
+		}
+		if meta := itemMetadata(metaStore, filepath.Join(dir, "c.go"), ""); meta["git_uncommitted"] != true || meta["git_commit"] != nil {
+			t.Errorf("c.go metadata = %v, want uncommitted without a commit", meta)
+		}
+		if st, _ := files.GetGitIndexState(dir, ""); len(st.Uncommitted) != 1 || st.Uncommitted[0] != "c.go" {
+			t.Errorf("uncommitted = %v, want [c.go]", st.Uncommitted)
+		}
+	})
+
+	t.Run("Given a file committed since the last run, When indexing again, Then its provenance is updated", func(t *testing.T) {
+		git("add", "-A")
+		git("commit", "-q", "-m", "third")
+
+		stats, err := idx.IndexGit(ctx, GitIndexRequest{Repo: dir, Scope: "project"}, nil)
+		if err != nil {
+			t.Fatalf("IndexGit failed: %v", err)
+		}
+		if stats.FilesIndexed != 1 {
+			t.Errorf("stats = %+v, want c.go re-indexed", stats)
+		}
+		meta := itemMetadata(metaStore, filepath.Join(dir, "c.go"), "")
+		if meta["git_uncommitted"] != nil || meta["git_commit"] != stats.GitCommit {
+			t.Errorf("c.go metadata = %v, want commit %s", meta, stats.GitCommit)
+		}
+	})
+
+	t.Run("Given a ref, When indexing it, Then its files are read without a checkout and tracked apart from the work tree", func(t *testing.T) {
+		stats, err := idx.IndexGit(ctx, GitIndexRequest{Repo: dir, Ref: "v1", Scope: "project"}, nil)
+		if err != nil {
+			t.Fatalf("IndexGit failed: %v", err)
+		}
+		if stats.FilesIndexed != 2 {
+			t.Fatalf("stats = %+v, want a.go and b.go of v1", stats)
+		}
+		if _, ok := files.Files[trackKey(filepath.Join(dir, "b.go"), "v1")]; !ok {
+			t.Error("b.go of v1 not tracked")
+		}
+		if _, ok := files.Files[fileKey(filepath.Join(dir, "a.go"))]; !ok {
+			t.Error("work tree a.go no longer tracked")
+		}
+		if meta := itemMetadata(metaStore, filepath.Join(dir, "b.go"), "v1"); meta == nil {
+			t.Error("b.go of v1 has no chunk tagged git_ref v1")
+		}
+
+		stats, err = idx.IndexGit(ctx, GitIndexRequest{Repo: dir, Ref: "v1", Scope: "project"}, nil)
+		if err != nil || stats.FilesFound != 0 || stats.GitBase != stats.GitCommit {
+			t.Errorf("re-index of unchanged ref = %+v, %v, want nothing to do", stats, err)
+		}
+	})
+
+	t.Run("Given an unknown since, When indexing, Then it fails", func(t *testing.T) {
+		if _, err := idx.IndexGit(ctx, GitIndexRequest{Repo: dir, Since: "no-such-ref"}, nil); err == nil {
+			t.Error("want error")
+		}
+	})
+}
@@ -2,14 +2,23 @@ package core
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"log"
-	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/anthropics/aef/codex/internal/chunking"
+	"github.com/anthropics/aef/codex/internal/embedding"
+	"github.com/anthropics/aef/codex/internal/llm"
+	"github.com/anthropics/aef/codex/internal/loader"
+	"github.com/anthropics/aef/codex/internal/storage"
+	"github.com/anthropics/aef/codex/internal/walk"
 )
 
 // Indexer handles content indexing through the appropriate pipeline
@@ -20,7 +29,15 @@ type Indexer struct {
 	metaStore   MetadataStorage
 	codeChunker CodeChunker
 	docChunker  DocChunker // optional
+	fileIndex   FileIndex  // optional
+	codeGraph   CodeGraph  // optional
+	loaders     *loader.Registry
 	idGen       IDGenerator
+	project     string // attributed to indexed items unless the request overrides it
+	walk        walk.Options
+
+	embedBatchSize   int // chunks per EmbedDocuments call
+	embedParallelism int // concurrent EmbedDocuments calls
 }
 
 // IndexerConfig holds configuration for creating an Indexer
@@ -29,20 +46,41 @@ type IndexerConfig struct {
 	VectorStore VectorStorage
 	MetaStore   MetadataStorage
 	CodeChunker CodeChunker
-	DocChunker  DocChunker // optional - for contextual enrichment
+	DocChunker  DocChunker       // optional - for contextual enrichment
+	FileIndex   FileIndex        // optional - enables incremental re-indexing
+	CodeGraph   CodeGraph        // optional - records the symbol graph of Go files
+	Loaders     *loader.Registry // optional - defaults to loader.Default()
 	IDGenerator IDGenerator
+	Project     string       // optional - project identity for indexed items
+	Walk        walk.Options // optional - file selection for IndexDirectory
+
+	EmbedBatchSize   int // optional - defaults to DefaultEmbedBatchSize
+	EmbedParallelism int // optional - defaults to DefaultEmbedParallelism
 }
 
+// DefaultChunkMaxTokens caps code chunks when Config.ChunkMaxTokens is
+// unset. Retrieval favours chunks well below most embedders' input limits.
+const DefaultChunkMaxTokens = 512
+
 // NewIndexer creates a new indexer from a SearchEngine (convenience constructor)
 func NewIndexer(engine *SearchEngine) (*Indexer, error) {
-	astChunker := chunking.NewASTChunker()
+	astChunker := chunking.NewASTChunker(chunking.WithSizeOptions(chunking.SizeOptions{
+		MaxTokens: chunkTokenBudget(engine.config, engine.embedder),
+	}))
 
-	// Contextual chunker is optional - requires Anthropic API key
+	// Contextual chunker is optional - requires an enrichment LLM
 	var ctxChunker DocChunker
-	if engine.config.AnthropicAPIKey != "" {
-		chunker, err := chunking.NewContextualChunker(engine.config.AnthropicAPIKey)
+	client, err := newEnrichmentClient(engine.config)
+	if err != nil {
+		// Log but continue - contextual enrichment is optional
+		log.Printf("Warning: contextual chunker not available: %v\n", err)
+	} else if client != nil {
+		var opts []chunking.ContextualOption
+		if cache, ok := engine.metadata.(chunking.EnrichmentCache); ok {
+			opts = append(opts, chunking.WithEnrichmentCache(cache))
+		}
+		chunker, err := chunking.NewContextualChunker(client, opts...)
 		if err != nil {
-			// Log but continue - contextual enrichment is optional
 			log.Printf("Warning: contextual chunker not available: %v\n", err)
 		} else {
 			ctxChunker = chunker
@@ -55,10 +93,72 @@ func NewIndexer(engine *SearchEngine) (*Indexer, error) {
 		metaStore:   engine.metadata,
 		codeChunker: astChunker,
 		docChunker:  ctxChunker,
+		fileIndex:   engine.files,
+		codeGraph:   engine.graph,
+		loaders:     loader.Default(),
 		idGen:       NewIDGenerator(),
+		project:     engine.config.Project,
+		walk:        engine.config.Walk,
+
+		embedBatchSize:   orDefault(engine.config.EmbedBatchSize, DefaultEmbedBatchSize),
+		embedParallelism: orDefault(engine.config.EmbedParallelism, DefaultEmbedParallelism),
 	}, nil
 }
 
+// chunkTokenBudget returns the largest code chunk to index: the configured
+// cap, lowered to what the embedder reads after its document prefix.
+// Returns 0 (no sizing) when the cap is negative.
+func chunkTokenBudget(cfg Config, embedder Embedder) int {
+	if cfg.ChunkMaxTokens < 0 {
+		return 0
+	}
+	budget := orDefault(cfg.ChunkMaxTokens, DefaultChunkMaxTokens)
+	if p, ok := embedder.(interface{ Profile() embedding.ModelProfile }); ok {
+		if profile := p.Profile(); profile.MaxTokens > 0 {
+			budget = min(budget, profile.MaxTokens-chunking.EstimateTokens(profile.DocumentPrefix))
+		}
+	}
+	return max(budget, 1)
+}
+
+// newEnrichmentClient selects the LLM used for contextual enrichment.
+// Returns nil (and no error) when enrichment is not configured.
+func newEnrichmentClient(cfg Config) (llm.Client, error) {
+	provider := cfg.EnrichmentProvider
+	if provider == "" && cfg.AnthropicAPIKey != "" {
+		provider = "anthropic"
+	}
+
+	switch provider {
+	case "":
+		return nil, nil
+	case "anthropic":
+		var opts []llm.AnthropicOption
+		if cfg.EnrichmentModel != "" {
+			opts = append(opts, llm.WithAnthropicModel(cfg.EnrichmentModel))
+		}
+		if cfg.EnrichmentURL != "" {
+			opts = append(opts, llm.WithAnthropicURL(cfg.EnrichmentURL))
+		}
+		client, err := llm.NewAnthropicClient(cfg.AnthropicAPIKey, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return client, nil
+	case "ollama":
+		var opts []llm.OllamaOption
+		if cfg.EnrichmentModel != "" {
+			opts = append(opts, llm.WithOllamaModel(cfg.EnrichmentModel))
+		}
+		if cfg.EnrichmentURL != "" {
+			opts = append(opts, llm.WithOllamaURL(cfg.EnrichmentURL))
+		}
+		return llm.NewOllamaClient(opts...), nil
+	default:
+		return nil, fmt.Errorf("unknown enrichment provider %q", provider)
+	}
+}
+
 // NewIndexerWithConfig creates an Indexer with explicit dependencies (for testing)
 func NewIndexerWithConfig(cfg IndexerConfig) (*Indexer, error) {
 	if cfg.Embedder == nil {
@@ -78,6 +178,10 @@ func NewIndexerWithConfig(cfg IndexerConfig) (*Indexer, error) {
 	if idGen == nil {
 		idGen = NewIDGenerator()
 	}
+	loaders := cfg.Loaders
+	if loaders == nil {
+		loaders = loader.Default()
+	}
 
 	return &Indexer{
 		embedder:    cfg.Embedder,
@@ -85,81 +189,287 @@ func NewIndexerWithConfig(cfg IndexerConfig) (*Indexer, error) {
 		metaStore:   cfg.MetaStore,
 		codeChunker: cfg.CodeChunker,
 		docChunker:  cfg.DocChunker,
+		fileIndex:   cfg.FileIndex,
+		codeGraph:   cfg.CodeGraph,
+		loaders:     loaders,
 		idGen:       idGen,
+		project:     cfg.Project,
+		walk:        cfg.Walk,
+
+		embedBatchSize:   orDefault(cfg.EmbedBatchSize, DefaultEmbedBatchSize),
+		embedParallelism: orDefault(cfg.EmbedParallelism, DefaultEmbedParallelism),
 	}, nil
 }
 
 // IndexFile indexes a file, routing through the appropriate pipeline
 func (idx *Indexer) IndexFile(ctx context.Context, req IndexRequest) (*IndexResult, error) {
+	if req.Project == "" {
+		req.Project = idx.project
+	}
+
 	// Detect content type and route appropriately
 	if req.Type == "" {
-		req.Type = detectContentType(req.FilePath, req.Content)
+		req.Type = idx.contentType(req.FilePath, req.Content)
 	}
 
 	switch req.Type {
-	case TypeCode:
-		return idx.indexCode(ctx, req)
-	case TypeDoc:
-		return idx.indexDoc(ctx, req)
+	case TypeCode, TypeDoc:
+		return idx.indexSourceFile(ctx, req)
 	default:
 		return idx.indexManual(ctx, req)
 	}
 }
 
-// IndexDirectory recursively indexes all files in a directory
-func (idx *Indexer) IndexDirectory(ctx context.Context, dirPath string, scope string) ([]IndexResult, error) {
-	var results []IndexResult
+// indexSourceFile indexes a code or doc file. When a FileIndex is configured,
+// files are tracked by path and content hash: unchanged files are skipped, and
+// changed files reuse their parent ID so chunks are replaced rather than duplicated.
+func (idx *Indexer) indexSourceFile(ctx context.Context, req IndexRequest) (*IndexResult, error) {
+	f, err := idx.prepareSourceFile(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if f.skipped != nil {
+		return f.skipped, nil
+	}
 
-	err := filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
+	vecs, errs := idx.embedAll(ctx, f.texts)
+	for i, err := range errs {
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("failed to embed %s %d: %w", f.kind, i, err)
 		}
+	}
+	f.vecs = vecs
+
+	return idx.writeSourceFile(ctx, f)
+}
+
+// sourceFile is a code or doc file between chunking and storage: its chunk
+// items, the texts to embed for them and the version it replaces. The
+// directory pipeline moves it between stages; IndexFile runs them in turn.
+type sourceFile struct {
+	req      IndexRequest
+	key      string // trackKey of the path, "" when the file is not tracked
+	hash     string
+	prev     *storage.IndexedFileRecord
+	parentID string
+	kind     string // "chunk" or "doc chunk", for error messages
+	items    []*Item
+	texts    []string
+	vecs     [][]float32
+	graph    *storage.CodeGraphFile
+
+	skipped *IndexResult // set when the file is unchanged since it was last indexed
+}
+
+// prepareSourceFile looks up a file's previous version and chunks it.
+func (idx *Indexer) prepareSourceFile(ctx context.Context, req IndexRequest) (*sourceFile, error) {
+	f := &sourceFile{req: req}
 
-		// Skip hidden files and directories
-		if strings.HasPrefix(info.Name(), ".") {
-			if info.IsDir() {
-				return filepath.SkipDir
+	if idx.fileIndex != nil && req.FilePath != "" {
+		f.key = trackKey(req.FilePath, req.Revision)
+		f.hash = requestHash(req)
+
+		prev, err := idx.fileIndex.GetIndexedFile(f.key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up indexed file: %w", err)
+		}
+		if prev != nil && prev.ContentHash == f.hash && prev.Scope == req.Scope {
+			f.skipped = &IndexResult{
+				ItemID:      prev.ParentID,
+				ChunksCount: len(prev.ChunkIDs),
+				FilePath:    req.FilePath,
+				Skipped:     true,
 			}
-			return nil
+			return f, nil
 		}
+		f.prev = prev
+	}
+
+	// Reuse the previous parent ID so chunk IDs are overwritten in place
+	f.parentID = idx.idGen.GenerateID()
+	if f.prev != nil {
+		f.parentID = f.prev.ParentID
+	}
+
+	if req.Type == TypeCode {
+		return f, idx.chunkCode(f)
+	}
+	return f, idx.chunkDoc(ctx, f)
+}
 
-		// Skip directories
-		if info.IsDir() {
-			return nil
+// writeSourceFile stores the chunks of an embedded file, removes what is
+// left of its previous version and records it in the FileIndex.
+func (idx *Indexer) writeSourceFile(ctx context.Context, f *sourceFile) (*IndexResult, error) {
+	for i, item := range f.items {
+		// Store metadata first
+		if err := idx.metaStore.SaveItem(itemToRecord(item)); err != nil {
+			return nil, fmt.Errorf("failed to save %s %d metadata: %w", f.kind, i, err)
 		}
 
-		// Skip non-indexable files
-		if !isIndexable(path) {
-			return nil
+		// Store in vector storage
+		if err := idx.vectorStore.Upsert(ctx, item.ID, f.vecs[i]); err != nil {
+			return nil, fmt.Errorf("failed to store %s %d: %w", f.kind, i, err)
 		}
+	}
 
-		// Read file content
-		content, err := os.ReadFile(path)
-		if err != nil {
-			log.Printf("Warning: failed to read %s: %v\n", path, err)
-			return nil
+	if f.graph != nil {
+		if err := idx.codeGraph.ReplaceCodeGraph(f.graph); err != nil {
+			log.Printf("Warning: failed to record code graph for %s: %v\n", f.req.FilePath, err)
 		}
+	}
 
-		// Index the file
-		result, err := idx.IndexFile(ctx, IndexRequest{
-			Content:  string(content),
-			FilePath: path,
-			Scope:    scope,
-		})
-		if err != nil {
-			log.Printf("Warning: failed to index %s: %v\n", path, err)
-			return nil
+	result := &IndexResult{
+		ItemID:      f.parentID,
+		ChunksCount: len(f.items),
+		FilePath:    f.req.FilePath,
+	}
+	if f.key == "" {
+		return result, nil
+	}
+
+	chunkIDs := make([]string, len(f.items))
+	for i := range chunkIDs {
+		chunkIDs[i] = chunkItemID(f.parentID, i)
+	}
+
+	// Remove chunks from the previous version that were not overwritten
+	if f.prev != nil {
+		if err := idx.deleteChunks(ctx, staleChunkIDs(f.prev.ChunkIDs, chunkIDs)); err != nil {
+			return nil, fmt.Errorf("failed to remove stale chunks: %w", err)
 		}
+	}
 
-		results = append(results, *result)
-		return nil
-	})
+	if err := idx.fileIndex.SaveIndexedFile(&storage.IndexedFileRecord{
+		Path:        f.key,
+		ContentHash: f.hash,
+		ParentID:    f.parentID,
+		ChunkIDs:    chunkIDs,
+		Scope:       f.req.Scope,
+		IndexedAt:   time.Now(),
+	}); err != nil {
+		return nil, fmt.Errorf("failed to record indexed file: %w", err)
+	}
+
+	return result, nil
+}
+
+// RemoveFile deletes all chunks previously indexed for a file and forgets it.
+// Returns false if the file was not tracked.
+func (idx *Indexer) RemoveFile(ctx context.Context, filePath string) (bool, error) {
+	if idx.fileIndex == nil {
+		return false, nil
+	}
+
+	path := fileKey(filePath)
+	rec, err := idx.fileIndex.GetIndexedFile(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up indexed file: %w", err)
+	}
+	if rec == nil {
+		return false, nil
+	}
+
+	if err := idx.deleteChunks(ctx, rec.ChunkIDs); err != nil {
+		return false, fmt.Errorf("failed to remove chunks for %s: %w", filePath, err)
+	}
+	if err := idx.fileIndex.DeleteIndexedFile(path); err != nil {
+		return false, fmt.Errorf("failed to forget indexed file %s: %w", filePath, err)
+	}
+	idx.forgetCodeGraph(path)
+	return true, nil
+}
+
+// forgetCodeGraph drops the code graph rows of a removed file.
+func (idx *Indexer) forgetCodeGraph(path string) {
+	if idx.codeGraph == nil {
+		return
+	}
+	if err := idx.codeGraph.DeleteCodeGraph(path); err != nil {
+		log.Printf("Warning: failed to remove code graph for %s: %v\n", path, err)
+	}
+}
+
+// deleteChunks removes chunk items from both stores. Vector deletion is
+// best-effort, matching SearchEngine.Delete — metadata is the source of truth.
+func (idx *Indexer) deleteChunks(ctx context.Context, ids []string) error {
+	for _, id := range ids {
+		if err := idx.vectorStore.Delete(ctx, id); err != nil {
+			log.Printf("Warning: failed to delete vector for %s: %v", id, err)
+		}
+		if err := idx.metaStore.DeleteItem(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pruneDeletedFiles removes chunks for tracked files under the root key that
+// not seen during the latest walk (deleted from disk or no longer indexable).
+func (idx *Indexer) pruneDeletedFiles(ctx context.Context, root string, seen map[string]bool) ([]IndexResult, error) {
+	prefix := root
+	if !strings.HasSuffix(prefix, string(filepath.Separator)) {
+		prefix += string(filepath.Separator)
+	}
+
+	records, err := idx.fileIndex.ListIndexedFiles(prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list indexed files: %w", err)
+	}
+
+	var gone []*storage.IndexedFileRecord
+	for _, rec := range records {
+		if !seen[rec.Path] {
+			gone = append(gone, rec)
+		}
+	}
+	return idx.removeRecords(ctx, gone), nil
+}
+
+// removeTracked removes the chunks of the file tracked under key, or of
+// every tracked file below it when key is a directory.
+func (idx *Indexer) removeTracked(ctx context.Context, key string) ([]IndexResult, error) {
+	records, err := idx.fileIndex.ListIndexedFiles(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list indexed files: %w", err)
+	}
+
+	var gone []*storage.IndexedFileRecord
+	for _, rec := range records {
+		if rec.Path == key || strings.HasPrefix(rec.Path, key+string(filepath.Separator)) {
+			gone = append(gone, rec)
+		}
+	}
+	return idx.removeRecords(ctx, gone), nil
+}
 
-	return results, err
+// removeRecords deletes the chunks of tracked files and forgets them.
+// Failures are logged and the file is left out of the results.
+func (idx *Indexer) removeRecords(ctx context.Context, records []*storage.IndexedFileRecord) []IndexResult {
+	var removed []IndexResult
+	for _, rec := range records {
+		if err := idx.deleteChunks(ctx, rec.ChunkIDs); err != nil {
+			log.Printf("Warning: failed to remove chunks for %s: %v\n", rec.Path, err)
+			continue
+		}
+		if err := idx.fileIndex.DeleteIndexedFile(rec.Path); err != nil {
+			log.Printf("Warning: failed to forget indexed file %s: %v\n", rec.Path, err)
+			continue
+		}
+		idx.forgetCodeGraph(rec.Path)
+		removed = append(removed, IndexResult{
+			ItemID:   rec.ParentID,
+			FilePath: rec.Path,
+			Removed:  true,
+		})
+	}
+	return removed
 }
 
-// indexCode processes code files through AST chunking
-func (idx *Indexer) indexCode(ctx context.Context, req IndexRequest) (*IndexResult, error) {
+// chunkCode splits a code file into chunk items through AST chunking
+func (idx *Indexer) chunkCode(f *sourceFile) error {
+	req := f.req
+	f.kind = "chunk"
+
 	// Detect language if not specified
 	lang := req.Language
 	if lang == "" {
@@ -169,32 +479,31 @@ func (idx *Indexer) indexCode(ctx context.Context, req IndexRequest) (*IndexResu
 	// Chunk the code using AST parser
 	chunks, err := idx.codeChunker.ChunkFile([]byte(req.Content), lang, req.FilePath)
 	if err != nil {
-		return nil, fmt.Errorf("AST chunking failed: %w", err)
+		return fmt.Errorf("AST chunking failed: %w", err)
+	}
+
+	// Go files also feed the code graph; chunks link to their symbol
+	var chunkSymbols []string
+	if idx.codeGraph != nil && lang == "go" && req.FilePath != "" && req.Revision == "" {
+		f.graph, chunkSymbols = indexCodeGraph(req, chunks, f.parentID)
 	}
 
-	// Generate a parent item ID
-	parentID := idx.idGen.GenerateID()
 	now := time.Now()
 
 	// Process each chunk
 	for i, chunk := range chunks {
-		// Generate embedding
-		vec, err := idx.embedder.EmbedDocument(ctx, chunk.Content)
-		if err != nil {
-			return nil, fmt.Errorf("failed to embed chunk %d: %w", i, err)
-		}
-
 		// Create item for this chunk
 		item := &Item{
-			ID:      fmt.Sprintf("%s-chunk-%d", parentID, i),
+			ID:      chunkItemID(f.parentID, i),
 			Type:    TypeCode,
 			Title:   buildCodeTitle(chunk),
 			Content: chunk.Content,
 			Tags:    req.Tags,
 			Scope:   req.Scope,
+			Project: req.Project,
 			Source:  req.FilePath,
 			Metadata: map[string]any{
-				"parent_id":  parentID,
+				"parent_id":  f.parentID,
 				"chunk_type": chunk.Type,
 				"name":       chunk.Name,
 				"signature":  chunk.Signature,
@@ -205,26 +514,35 @@ func (idx *Indexer) indexCode(ctx context.Context, req IndexRequest) (*IndexResu
 			CreatedAt: now,
 			UpdatedAt: now,
 		}
-
-		// Store metadata first
-		if err := idx.metaStore.SaveItem(itemToRecord(item)); err != nil {
-			return nil, fmt.Errorf("failed to save chunk %d metadata: %w", i, err)
+		addStructureMetadata(item.Metadata, chunk)
+		for k, v := range req.Metadata {
+			item.Metadata[k] = v
 		}
-
-		// Store in vector storage
-		if err := idx.vectorStore.Upsert(ctx, item.ID, vec); err != nil {
-			return nil, fmt.Errorf("failed to store chunk %d: %w", i, err)
+		if f.graph != nil && chunkSymbols[i] != "" {
+			item.Metadata["symbol_id"] = chunkSymbols[i]
 		}
-	}
 
-	return &IndexResult{
-		ItemID:      parentID,
-		ChunksCount: len(chunks),
-	}, nil
+		f.items = append(f.items, item)
+		f.texts = append(f.texts, chunk.Content)
+	}
+	return nil
 }
 
-// indexDoc processes documentation through markdown chunking
-func (idx *Indexer) indexDoc(ctx context.Context, req IndexRequest) (*IndexResult, error) {
+// chunkDoc splits documentation into chunk items through markdown chunking,
+// or through the document loader that takes the file
+func (idx *Indexer) chunkDoc(ctx context.Context, f *sourceFile) error {
+	req := f.req
+	f.kind = "doc chunk"
+
+	if l := idx.loaders.Lookup(req.FilePath, []byte(req.Content)); l != nil {
+		doc, err := l.Load(req.FilePath, []byte(req.Content))
+		if err != nil {
+			return fmt.Errorf("%s loader: %w", l.Name(), err)
+		}
+		idx.addDocChunks(f, loadedChunks(doc), slices.Clip(req.Tags), loaderMetadata(l, doc))
+		return nil
+	}
+
 	var chunks []docChunkData
 
 	// Use contextual chunker if available, otherwise basic markdown chunking
@@ -239,6 +557,7 @@ func (idx *Indexer) indexDoc(ctx context.Context, req IndexRequest) (*IndexResul
 				chunks = append(chunks, docChunkData{
 					content:   dc.EnrichedContent,
 					section:   dc.Section,
+					headings:  dc.Headings,
 					startLine: dc.StartLine,
 					endLine:   dc.EndLine,
 				})
@@ -248,29 +567,42 @@ func (idx *Indexer) indexDoc(ctx context.Context, req IndexRequest) (*IndexResul
 		chunks = basicDocChunking(req.Content, req.FilePath)
 	}
 
-	// Generate a parent item ID
-	parentID := idx.idGen.GenerateID()
+	// Front matter adds its tags, and its fields go into each chunk's metadata
+	frontMatter, _ := chunking.SplitFrontMatter(req.Content)
+	tags := slices.Clip(req.Tags) // appending must not write into the caller's slice
+	for _, tag := range chunking.FrontMatterTags(frontMatter) {
+		tags = appendUnique(tags, tag)
+	}
+	var docMeta map[string]any
+	if frontMatter != nil {
+		docMeta = map[string]any{"front_matter": frontMatter}
+		if title, _ := frontMatter["title"].(string); title != "" {
+			docMeta["document_title"] = title
+		}
+	}
+	idx.addDocChunks(f, chunks, tags, docMeta)
+	return nil
+}
+
+// addDocChunks adds the items and embedding texts of a document's chunks.
+// docMeta goes into the metadata of every chunk.
+func (idx *Indexer) addDocChunks(f *sourceFile, chunks []docChunkData, tags []string, docMeta map[string]any) {
+	req := f.req
 	now := time.Now()
 
-	// Process each chunk
 	for i, chunk := range chunks {
-		// Generate embedding
-		vec, err := idx.embedder.EmbedDocument(ctx, chunk.content)
-		if err != nil {
-			return nil, fmt.Errorf("failed to embed doc chunk %d: %w", i, err)
-		}
-
 		// Create item for this chunk
 		item := &Item{
-			ID:      fmt.Sprintf("%s-chunk-%d", parentID, i),
+			ID:      chunkItemID(f.parentID, i),
 			Type:    TypeDoc,
 			Title:   chunk.section,
 			Content: chunk.content,
-			Tags:    req.Tags,
+			Tags:    tags,
 			Scope:   req.Scope,
+			Project: req.Project,
 			Source:  req.FilePath,
 			Metadata: map[string]any{
-				"parent_id":  parentID,
+				"parent_id":  f.parentID,
 				"section":    chunk.section,
 				"start_line": chunk.startLine,
 				"end_line":   chunk.endLine,
@@ -278,38 +610,104 @@ func (idx *Indexer) indexDoc(ctx context.Context, req IndexRequest) (*IndexResul
 			CreatedAt: now,
 			UpdatedAt: now,
 		}
-
-		// Store metadata first
-		if err := idx.metaStore.SaveItem(itemToRecord(item)); err != nil {
-			return nil, fmt.Errorf("failed to save doc chunk %d metadata: %w", i, err)
+		if len(chunk.headings) > 0 {
+			item.Metadata["headings"] = chunk.headings
 		}
-
-		// Store in vector storage
-		if err := idx.vectorStore.Upsert(ctx, item.ID, vec); err != nil {
-			return nil, fmt.Errorf("failed to store doc chunk %d: %w", i, err)
+		if chunk.page > 0 {
+			item.Metadata["page"] = chunk.page
+		}
+		for k, v := range docMeta {
+			item.Metadata[k] = v
 		}
+		for k, v := range chunk.meta {
+			item.Metadata[k] = v
+		}
+		for k, v := range req.Metadata {
+			item.Metadata[k] = v
+		}
+
+		f.items = append(f.items, item)
+		f.texts = append(f.texts, embeddingText(item))
 	}
+}
 
-	return &IndexResult{
-		ItemID:      parentID,
-		ChunksCount: len(chunks),
-	}, nil
+// embeddingText returns the text embedded for an item: its content, led
+// for document chunks by the breadcrumb of headings above the chunk, or
+// by the document title outside any heading, to situate the chunk in the
+// document for the embedder. It reads only stored fields, so re-embedding
+// reproduces the text indexing embedded.
+func embeddingText(item *Item) string {
+	if item.Type != TypeDoc {
+		return item.Content
+	}
+	if hasElements(item.Metadata["headings"]) {
+		section, _ := item.Metadata["section"].(string)
+		return section + "\n\n" + item.Content
+	}
+	if title, _ := item.Metadata["document_title"].(string); title != "" {
+		return title + "\n\n" + item.Content
+	}
+	return item.Content
+}
+
+// hasElements reports whether v is a non-empty list, as built ([]string)
+// or read back from JSON ([]any).
+func hasElements(v any) bool {
+	switch l := v.(type) {
+	case []string:
+		return len(l) > 0
+	case []any:
+		return len(l) > 0
+	}
+	return false
 }
 
 // indexManual processes manually added items (patterns, failures, decisions, etc.)
 func (idx *Indexer) indexManual(ctx context.Context, req IndexRequest) (*IndexResult, error) {
-	now := time.Now()
-	itemID := idx.idGen.GenerateID()
+	vec, err := idx.embedder.EmbedDocument(ctx, req.Content)
+	if err != nil {
+		return nil, fmt.Errorf("embed failed for type %q: %w", manualType(req), err)
+	}
+	return idx.saveManual(ctx, req, vec)
+}
 
-	itemType := req.Type
-	if itemType == "" {
-		itemType = TypeContext
+// indexManualBatch indexes several manual items, embedding them in batches.
+// It returns a result or an error for each request, so one failing item does
+// not fail the others.
+func (idx *Indexer) indexManualBatch(ctx context.Context, reqs []IndexRequest) ([]*IndexResult, []error) {
+	texts := make([]string, len(reqs))
+	for i, req := range reqs {
+		texts[i] = req.Content
 	}
+	vecs, errs := idx.embedAll(ctx, texts)
 
-	vec, err := idx.embedder.EmbedDocument(ctx, req.Content)
-	if err != nil {
-		return nil, fmt.Errorf("embed failed for type %q: %w", itemType, err)
+	results := make([]*IndexResult, len(reqs))
+	for i, req := range reqs {
+		if errs[i] != nil {
+			errs[i] = fmt.Errorf("embed failed for type %q: %w", manualType(req), errs[i])
+			continue
+		}
+		if req.Project == "" {
+			req.Project = idx.project
+		}
+		results[i], errs[i] = idx.saveManual(ctx, req, vecs[i])
 	}
+	return results, errs
+}
+
+// manualType returns the item type for a manual request.
+func manualType(req IndexRequest) string {
+	if req.Type == "" {
+		return TypeContext
+	}
+	return req.Type
+}
+
+// saveManual stores a manual item and its embedding.
+func (idx *Indexer) saveManual(ctx context.Context, req IndexRequest, vec []float32) (*IndexResult, error) {
+	now := time.Now()
+	itemID := idx.idGen.GenerateID()
+	itemType := manualType(req)
 
 	// Extract title from content if not provided
 	title := extractTitle(req.Content)
@@ -321,7 +719,9 @@ func (idx *Indexer) indexManual(ctx context.Context, req IndexRequest) (*IndexRe
 		Content:   req.Content,
 		Tags:      req.Tags,
 		Scope:     req.Scope,
+		Project:   req.Project,
 		Source:    "manual",
+		Metadata:  req.Metadata,
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
@@ -354,9 +754,89 @@ func (idx *Indexer) Close() error {
 
 type docChunkData struct {
 	content   string
-	section   string
+	section   string   // breadcrumb
+	headings  []string // headings of the breadcrumb; empty outside any heading
 	startLine int
 	endLine   int
+	page      int            // PDF page, 0 otherwise
+	meta      map[string]any // loader metadata of the section
+}
+
+// chunkItemID returns the item ID of the i-th chunk of a parent.
+func chunkItemID(parentID string, i int) string {
+	return fmt.Sprintf("%s-chunk-%d", parentID, i)
+}
+
+// splitChunkID returns the parent ID and index of a chunk item ID, or
+// false for IDs chunkItemID did not produce.
+func splitChunkID(id string) (parentID string, i int, ok bool) {
+	at := strings.LastIndex(id, "-chunk-")
+	if at <= 0 {
+		return "", 0, false
+	}
+	i, err := strconv.Atoi(id[at+len("-chunk-"):])
+	if err != nil || i < 0 {
+		return "", 0, false
+	}
+	return id[:at], i, true
+}
+
+// staleChunkIDs returns the IDs in prev that are not in current.
+func staleChunkIDs(prev, current []string) []string {
+	keep := make(map[string]bool, len(current))
+	for _, id := range current {
+		keep[id] = true
+	}
+	var stale []string
+	for _, id := range prev {
+		if !keep[id] {
+			stale = append(stale, id)
+		}
+	}
+	return stale
+}
+
+// fileKey normalizes a file path for tracking in the FileIndex.
+func fileKey(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+	return abs
+}
+
+// trackKey is the FileIndex key of a file: its absolute path, prefixed
+// with the revision for files read from a git ref.
+func trackKey(path, revision string) string {
+	if revision == "" {
+		return fileKey(path)
+	}
+	return revision + ":" + fileKey(path)
+}
+
+func contentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// requestHash is the hash a tracked file is compared by: its content and,
+// when set, its extra metadata, so a file whose provenance changed is
+// re-indexed even if its content did not.
+func requestHash(req IndexRequest) string {
+	if len(req.Metadata) == 0 {
+		return contentHash(req.Content)
+	}
+	meta, _ := json.Marshal(req.Metadata) // map keys are sorted
+	return contentHash(req.Content + "\x00" + string(meta))
+}
+
+// contentType detects the type of a file: doc for files a document loader
+// takes, otherwise by extension (see detectContentType).
+func (idx *Indexer) contentType(filePath, content string) string {
+	if idx.loaders.Lookup(filePath, []byte(content)) != nil {
+		return TypeDoc
+	}
+	return detectContentType(filePath, content)
 }
 
 func detectContentType(filePath, content string) string {
@@ -367,7 +847,8 @@ func detectContentType(filePath, content string) string {
 		".go": true, ".py": true, ".js": true, ".ts": true, ".tsx": true,
 		".jsx": true, ".rs": true, ".java": true, ".c": true, ".cpp": true,
 		".h": true, ".hpp": true, ".rb": true, ".php": true, ".swift": true,
-		".kt": true, ".scala": true, ".cs": true,
+		".kt": true, ".scala": true, ".cs": true, ".cc": true, ".cxx": true,
+		".hh": true, ".hxx": true,
 	}
 	if codeExts[ext] {
 		return TypeCode
@@ -394,7 +875,8 @@ func isIndexable(path string) bool {
 		".go": true, ".py": true, ".js": true, ".ts": true, ".tsx": true,
 		".jsx": true, ".rs": true, ".java": true, ".c": true, ".cpp": true,
 		".h": true, ".hpp": true, ".rb": true, ".php": true, ".swift": true,
-		".kt": true, ".scala": true, ".cs": true,
+		".kt": true, ".scala": true, ".cs": true, ".cc": true, ".cxx": true,
+		".hh": true, ".hxx": true,
 		// Docs
 		".md": true, ".mdx": true, ".txt": true, ".rst": true,
 	}
@@ -402,13 +884,79 @@ func isIndexable(path string) bool {
 	return indexableExts[ext]
 }
 
+// addStructureMetadata records a chunk's structural metadata, leaving out
+// what the chunker could not determine.
+func addStructureMetadata(meta map[string]any, chunk chunking.CodeChunk) {
+	if chunk.Symbol != "" {
+		meta["symbol"] = chunk.Symbol
+	}
+	if chunk.Receiver != "" {
+		meta["receiver"] = chunk.Receiver
+	}
+	if chunk.Doc != "" {
+		meta["doc"] = chunk.Doc
+	}
+	if len(chunk.Imports) > 0 {
+		meta["imports"] = chunk.Imports
+	}
+	if len(chunk.Calls) > 0 {
+		meta["calls"] = chunk.Calls
+	}
+	if chunk.Parts > 0 {
+		meta["part"] = chunk.Part
+		meta["parts"] = chunk.Parts
+	}
+	if len(chunk.Merged) > 0 {
+		meta["merged"] = chunk.Merged
+	}
+}
+
+// buildCodeTitle names a chunk by its qualified symbol when known, so
+// keyword search matches the enclosing type and package too.
 func buildCodeTitle(chunk chunking.CodeChunk) string {
-	if chunk.Name != "" {
-		return fmt.Sprintf("%s %s in %s", chunk.Type, chunk.Name, filepath.Base(chunk.FilePath))
+	name := chunk.Symbol
+	if name == "" {
+		name = chunk.Name
+	}
+	if name != "" {
+		if chunk.Parts > 0 {
+			name += fmt.Sprintf(" (part %d/%d)", chunk.Part, chunk.Parts)
+		}
+		return fmt.Sprintf("%s %s in %s", chunk.Type, name, filepath.Base(chunk.FilePath))
 	}
 	return fmt.Sprintf("%s chunk in %s:%d-%d", chunk.Type, filepath.Base(chunk.FilePath), chunk.StartLine, chunk.EndLine)
 }
 
+// loadedChunks converts the sections of a loaded document to chunks.
+func loadedChunks(doc *loader.Document) []docChunkData {
+	var chunks []docChunkData
+	for _, s := range doc.Sections {
+		chunks = append(chunks, docChunkData{
+			content:   s.Content,
+			section:   s.Breadcrumb(),
+			headings:  s.Headings,
+			startLine: s.StartLine,
+			endLine:   s.EndLine,
+			page:      s.Page,
+			meta:      s.Metadata,
+		})
+	}
+	return chunks
+}
+
+// loaderMetadata is the metadata every chunk of a loaded document gets:
+// the loader, the document's title and the document's own metadata.
+func loaderMetadata(l loader.Loader, doc *loader.Document) map[string]any {
+	meta := map[string]any{"loader": l.Name()}
+	if doc.Title != "" {
+		meta["document_title"] = doc.Title
+	}
+	for k, v := range doc.Metadata {
+		meta[k] = v
+	}
+	return meta
+}
+
 func basicDocChunking(content, filePath string) []docChunkData {
 	sections := chunking.ChunkMarkdown(content, 2000) // ~2000 char chunks
 
@@ -416,7 +964,8 @@ func basicDocChunking(content, filePath string) []docChunkData {
 	for _, section := range sections {
 		chunks = append(chunks, docChunkData{
 			content:   section.Content,
-			section:   section.Title,
+			section:   section.Breadcrumb(),
+			headings:  section.Headings,
 			startLine: section.StartLine,
 			endLine:   section.EndLine,
 		})
@@ -3,11 +3,15 @@ package core
 import (
 	"context"
 	"errors"
+	"fmt"
+	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
 	"github.com/anthropics/aef/codex/internal/chunking"
+	"github.com/anthropics/aef/codex/internal/embedding"
 )
 
 func TestDetectContentType(t *testing.T) {
@@ -126,6 +130,28 @@ func TestBuildCodeTitle(t *testing.T) {
 			},
 			want: "class UserService in user.py",
 		},
+		{
+			name: "Method with symbol",
+			chunk: chunking.CodeChunk{
+				Type:     "method",
+				Name:     "Refund",
+				Symbol:   "payments.Service.Refund",
+				FilePath: "/src/payments/service.go",
+			},
+			want: "method payments.Service.Refund in service.go",
+		},
+		{
+			name: "Part of a split function",
+			chunk: chunking.CodeChunk{
+				Type:     "function",
+				Name:     "Handle",
+				Symbol:   "api.Handle",
+				FilePath: "/src/api/handler.go",
+				Part:     2,
+				Parts:    3,
+			},
+			want: "function api.Handle (part 2/3) in handler.go",
+		},
 	}
 
 	for _, tt := range tests {
@@ -286,6 +312,37 @@ func TestNewIndexerWithConfig(t *testing.T) {
 	})
 }
 
+// profiledEmbedder is a MockEmbedder reporting a model profile.
+type profiledEmbedder struct {
+	*MockEmbedder
+	profile embedding.ModelProfile
+}
+
+func (e profiledEmbedder) Profile() embedding.ModelProfile { return e.profile }
+
+func TestChunkTokenBudget(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      Config
+		embedder Embedder
+		want     int
+	}{
+		{"Given no profile, When budgeting, Then uses the default cap", Config{}, NewMockEmbedder(), DefaultChunkMaxTokens},
+		{"Given a large input limit, When budgeting, Then uses the cap", Config{ChunkMaxTokens: 1024},
+			profiledEmbedder{NewMockEmbedder(), embedding.ModelProfile{MaxTokens: 8192}}, 1024},
+		{"Given a small input limit, When budgeting, Then subtracts the document prefix from it", Config{},
+			profiledEmbedder{NewMockEmbedder(), embedding.ModelProfile{MaxTokens: 256, DocumentPrefix: "passage: "}}, 253},
+		{"Given a negative cap, When budgeting, Then sizing is off", Config{ChunkMaxTokens: -1}, NewMockEmbedder(), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := chunkTokenBudget(tt.cfg, tt.embedder); got != tt.want {
+				t.Errorf("chunkTokenBudget() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
 // TestIndexer_IndexCode tests code indexing with mocks
 func TestIndexer_IndexCode(t *testing.T) {
 	ctx := context.Background()
@@ -332,6 +389,59 @@ func TestIndexer_IndexCode(t *testing.T) {
 		}
 	})
 
+	t.Run("Given chunks with structure, When indexing, Then it is saved in metadata", func(t *testing.T) {
+		metaStore := NewMockMetadataStorage()
+		codeChunker := NewMockCodeChunker()
+		codeChunker.ChunkFunc = func(content []byte, lang, filePath string) ([]chunking.CodeChunk, error) {
+			return []chunking.CodeChunk{
+				{Content: string(content), Type: "method", Name: "Refund", StartLine: 1, EndLine: 3, FilePath: filePath, Language: lang,
+					Symbol: "payments.Service.Refund", Receiver: "Service", Doc: "Refund reverses a charge.",
+					Imports: []string{"fmt"}, Calls: []string{"Errorf"}},
+				{Content: "var x = 1", Type: "chunk", StartLine: 4, EndLine: 4, FilePath: filePath, Language: lang},
+			}, nil
+		}
+
+		idx, _ := NewIndexerWithConfig(IndexerConfig{
+			Embedder:    NewMockEmbedder(),
+			VectorStore: NewMockVectorStorage(),
+			MetaStore:   metaStore,
+			CodeChunker: codeChunker,
+			IDGenerator: NewMockIDGenerator("test"),
+		})
+		if _, err := idx.IndexFile(ctx, IndexRequest{Content: "func (s *Service) Refund() {}", Type: "code", FilePath: "service.go"}); err != nil {
+			t.Fatalf("IndexFile failed: %v", err)
+		}
+
+		method, _ := metaStore.GetItem(chunkItemID("test-1", 0))
+		if method == nil {
+			t.Fatal("expected method chunk to be saved")
+		}
+		if method.Title != "method payments.Service.Refund in service.go" {
+			t.Errorf("title = %q", method.Title)
+		}
+		for key, want := range map[string]any{
+			"symbol":   "payments.Service.Refund",
+			"receiver": "Service",
+			"doc":      "Refund reverses a charge.",
+			"imports":  []string{"fmt"},
+			"calls":    []string{"Errorf"},
+		} {
+			if got := method.Metadata[key]; !reflect.DeepEqual(got, want) {
+				t.Errorf("metadata[%s] = %v, want %v", key, got, want)
+			}
+		}
+
+		plain, _ := metaStore.GetItem(chunkItemID("test-1", 1))
+		if plain == nil {
+			t.Fatal("expected plain chunk to be saved")
+		}
+		for _, key := range []string{"symbol", "receiver", "doc", "imports", "calls"} {
+			if _, ok := plain.Metadata[key]; ok {
+				t.Errorf("plain chunk has metadata[%s]", key)
+			}
+		}
+	})
+
 	t.Run("embedding failure", func(t *testing.T) {
 		codeEmbed := NewMockEmbedder()
 		codeEmbed.FailOnCall = 1 // Fail on first call
@@ -496,6 +606,43 @@ func TestIndexer_IndexDoc(t *testing.T) {
 		}
 	})
 
+	t.Run("Given front matter and nested headings, When indexing, Then chunks carry tags, metadata and breadcrumbs", func(t *testing.T) {
+		embed := NewMockEmbedder()
+		metaStore := NewMockMetadataStorage()
+		idx, _ := NewIndexerWithConfig(IndexerConfig{
+			Embedder:    embed,
+			VectorStore: NewMockVectorStorage(),
+			MetaStore:   metaStore,
+			CodeChunker: NewMockCodeChunker(),
+		})
+
+		content := "---\ntitle: Ops\ntags: [payments]\n---\n# Runbook\n\n## Rollback\n\nUndo the deploy.\n"
+		if _, err := idx.IndexFile(ctx, IndexRequest{Content: content, Type: "doc", FilePath: "runbook.md", Tags: []string{"ops"}}); err != nil {
+			t.Fatalf("IndexFile failed: %v", err)
+		}
+
+		if len(metaStore.Items) != 1 {
+			t.Fatalf("got %d items, want 1", len(metaStore.Items))
+		}
+		for _, item := range metaStore.Items {
+			if item.Title != "Runbook > Rollback" || item.Content != "Undo the deploy." {
+				t.Errorf("item = %q: %q", item.Title, item.Content)
+			}
+			if !reflect.DeepEqual(item.Tags, []string{"ops", "payments"}) {
+				t.Errorf("tags = %v, want request and front matter tags", item.Tags)
+			}
+			if fm, _ := item.Metadata["front_matter"].(map[string]any); fm["title"] != "Ops" {
+				t.Errorf("front_matter = %v", item.Metadata["front_matter"])
+			}
+			if item.Metadata["start_line"] != 7 {
+				t.Errorf("start_line = %v, want 7", item.Metadata["start_line"])
+			}
+		}
+		if embed.LastText != "Runbook > Rollback\n\nUndo the deploy." {
+			t.Errorf("embedded %q, want the breadcrumb before the content", embed.LastText)
+		}
+	})
+
 	t.Run("embedding failure", func(t *testing.T) {
 		embed := NewMockEmbedder()
 		embed.FailOnCall = 1
@@ -1027,3 +1174,353 @@ func TestFilepathBase(t *testing.T) {
 		}
 	}
 }
+
+// =============================================================================
+// Incremental re-indexing
+// =============================================================================
+
+// newIncrementalIndexer builds an Indexer whose chunker emits one chunk per line,
+// backed by mocks including a FileIndex.
+func newIncrementalIndexer() (*Indexer, *MockEmbedder, *MockVectorStorage, *MockMetadataStorage, *MockFileIndex) {
+	embed := NewMockEmbedder()
+	vectorStore := NewMockVectorStorage()
+	metaStore := NewMockMetadataStorage()
+	files := NewMockFileIndex()
+
+	codeChunker := NewMockCodeChunker()
+	codeChunker.ChunkFunc = func(content []byte, lang, filePath string) ([]chunking.CodeChunk, error) {
+		var chunks []chunking.CodeChunk
+		for i, line := range strings.Split(string(content), "\n") {
+			chunks = append(chunks, chunking.CodeChunk{
+				Content: line, Type: "function", Name: line,
+				StartLine: i + 1, EndLine: i + 1, FilePath: filePath,
+			})
+		}
+		return chunks, nil
+	}
+
+	idx, _ := NewIndexerWithConfig(IndexerConfig{
+		Embedder:    embed,
+		VectorStore: vectorStore,
+		MetaStore:   metaStore,
+		CodeChunker: codeChunker,
+		FileIndex:   files,
+		IDGenerator: NewMockIDGenerator("file"),
+	})
+	return idx, embed, vectorStore, metaStore, files
+}
+
+func TestIndexer_IncrementalReindex(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Given unchanged file When re-indexed Then it is skipped", func(t *testing.T) {
+		idx, embed, _, metaStore, _ := newIncrementalIndexer()
+		req := IndexRequest{Content: "a\nb", Type: "code", FilePath: "/repo/main.go", Scope: "project"}
+
+		first, err := idx.IndexFile(ctx, req)
+		if err != nil {
+			t.Fatalf("first IndexFile failed: %v", err)
+		}
+		second, err := idx.IndexFile(ctx, req)
+		if err != nil {
+			t.Fatalf("second IndexFile failed: %v", err)
+		}
+
+		if !second.Skipped {
+			t.Error("expected second index to be skipped")
+		}
+		if second.ItemID != first.ItemID || second.ChunksCount != 2 {
+			t.Errorf("skipped result = %+v, want parent %s with 2 chunks", second, first.ItemID)
+		}
+		if embed.CallCount != 2 {
+			t.Errorf("expected 2 embed calls total, got %d", embed.CallCount)
+		}
+		if len(metaStore.Items) != 2 {
+			t.Errorf("expected 2 items, got %d", len(metaStore.Items))
+		}
+	})
+
+	t.Run("Given changed file When re-indexed Then old chunks are replaced", func(t *testing.T) {
+		idx, _, vectorStore, metaStore, files := newIncrementalIndexer()
+
+		first, _ := idx.IndexFile(ctx, IndexRequest{Content: "a\nb\nc", Type: "code", FilePath: "/repo/main.go"})
+		second, err := idx.IndexFile(ctx, IndexRequest{Content: "x", Type: "code", FilePath: "/repo/main.go"})
+		if err != nil {
+			t.Fatalf("re-index failed: %v", err)
+		}
+
+		if second.Skipped {
+			t.Error("changed file should not be skipped")
+		}
+		if second.ItemID != first.ItemID {
+			t.Errorf("expected parent ID to be reused, got %s want %s", second.ItemID, first.ItemID)
+		}
+		if len(metaStore.Items) != 1 || len(vectorStore.Vectors) != 1 {
+			t.Errorf("expected 1 item and 1 vector, got %d and %d", len(metaStore.Items), len(vectorStore.Vectors))
+		}
+		if got := metaStore.Items[first.ItemID+"-chunk-0"]; got == nil || got.Content != "x" {
+			t.Errorf("chunk 0 not overwritten: %+v", got)
+		}
+		if rec := files.Files["/repo/main.go"]; rec == nil || len(rec.ChunkIDs) != 1 {
+			t.Errorf("file record not updated: %+v", rec)
+		}
+	})
+
+	t.Run("Given tracked file When RemoveFile called Then chunks and record are deleted", func(t *testing.T) {
+		idx, _, vectorStore, metaStore, files := newIncrementalIndexer()
+		idx.IndexFile(ctx, IndexRequest{Content: "a\nb", Type: "code", FilePath: "/repo/main.go"})
+
+		removed, err := idx.RemoveFile(ctx, "/repo/main.go")
+		if err != nil {
+			t.Fatalf("RemoveFile failed: %v", err)
+		}
+		if !removed {
+			t.Error("expected RemoveFile to report removal")
+		}
+		if len(metaStore.Items) != 0 || len(vectorStore.Vectors) != 0 || len(files.Files) != 0 {
+			t.Errorf("expected everything removed, got %d items, %d vectors, %d files",
+				len(metaStore.Items), len(vectorStore.Vectors), len(files.Files))
+		}
+	})
+}
+
+func TestIndexer_IndexDirectory_Incremental(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("keep.go", "a\nb")
+	write("gone.go", "c")
+
+	idx, _, _, metaStore, files := newIncrementalIndexer()
+
+	if _, err := idx.IndexDirectory(ctx, dir, "project"); err != nil {
+		t.Fatalf("first IndexDirectory failed: %v", err)
+	}
+	if len(metaStore.Items) != 3 {
+		t.Fatalf("expected 3 items after first run, got %d", len(metaStore.Items))
+	}
+
+	if err := os.Remove(filepath.Join(dir, "gone.go")); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := idx.IndexDirectory(ctx, dir, "project")
+	if err != nil {
+		t.Fatalf("second IndexDirectory failed: %v", err)
+	}
+
+	var skipped, removed int
+	for _, r := range results {
+		if r.Skipped {
+			skipped++
+		}
+		if r.Removed {
+			removed++
+		}
+	}
+	if skipped != 1 || removed != 1 {
+		t.Errorf("expected 1 skipped and 1 removed, got %d and %d", skipped, removed)
+	}
+	if len(metaStore.Items) != 2 {
+		t.Errorf("expected 2 items after removal, got %d", len(metaStore.Items))
+	}
+	if len(files.Files) != 1 {
+		t.Errorf("expected 1 tracked file, got %d", len(files.Files))
+	}
+}
+
+func TestIndexer_IndexDirectory_Filtering(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		".gitignore":        "vendor/\n",
+		"main.go":           "a",
+		"vendor/lib/lib.go": "b",
+		"gen.pb.go":         "// Code generated by protoc-gen-go. DO NOT EDIT.\npackage gen",
+		"logo.png":          "\x89PNG\x00",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("Given ignore files and generated code, When indexing a directory, Then only source files are indexed", func(t *testing.T) {
+		idx, _, _, _, files := newIncrementalIndexer()
+		results, err := idx.IndexDirectory(ctx, dir, "project")
+		if err != nil {
+			t.Fatalf("IndexDirectory failed: %v", err)
+		}
+
+		var indexed []string
+		ignored := make(map[string]string)
+		for _, r := range results {
+			if r.Ignored != "" {
+				ignored[filepath.Base(r.FilePath)] = r.Ignored
+			} else {
+				indexed = append(indexed, filepath.Base(r.FilePath))
+			}
+		}
+		if !reflect.DeepEqual(indexed, []string{"main.go"}) {
+			t.Errorf("indexed = %v, want [main.go]", indexed)
+		}
+		if !reflect.DeepEqual(ignored, map[string]string{"gen.pb.go": "generated"}) {
+			t.Errorf("ignored = %v, want gen.pb.go as generated", ignored)
+		}
+		if len(files.Files) != 1 {
+			t.Errorf("tracked files = %d, want 1", len(files.Files))
+		}
+	})
+
+	t.Run("Given an exclude glob added later, When re-indexing, Then the excluded file's chunks are removed", func(t *testing.T) {
+		idx, _, _, metaStore, _ := newIncrementalIndexer()
+		if _, err := idx.IndexDirectory(ctx, dir, "project"); err != nil {
+			t.Fatalf("IndexDirectory failed: %v", err)
+		}
+		idx.walk.Exclude = []string{"main.go"}
+		results, err := idx.IndexDirectory(ctx, dir, "project")
+		if err != nil {
+			t.Fatalf("IndexDirectory failed: %v", err)
+		}
+		if len(metaStore.Items) != 0 || len(results) != 2 || !results[1].Removed {
+			t.Errorf("results = %+v, items = %d, want main.go removed", results, len(metaStore.Items))
+		}
+	})
+
+	t.Run("Given an unknown symlink policy, When indexing a directory, Then it fails", func(t *testing.T) {
+		idx, _, _, _, _ := newIncrementalIndexer()
+		idx.walk.Symlinks = "sometimes"
+		if _, err := idx.IndexDirectory(ctx, dir, "project"); err == nil {
+			t.Error("want error")
+		}
+	})
+}
+
+func TestStaleChunkIDs(t *testing.T) {
+	got := staleChunkIDs([]string{"p-chunk-0", "p-chunk-1", "p-chunk-2"}, []string{"p-chunk-0"})
+	if len(got) != 2 || got[0] != "p-chunk-1" || got[1] != "p-chunk-2" {
+		t.Errorf("staleChunkIDs = %v, want [p-chunk-1 p-chunk-2]", got)
+	}
+}
+
+func TestNewEnrichmentClient(t *testing.T) {
+	tests := []struct {
+		name      string
+		cfg       Config
+		wantModel string // "" means no client
+		wantErr   bool
+	}{
+		{"no key, no provider", Config{}, "", false},
+		{"anthropic key selects anthropic", Config{AnthropicAPIKey: "k"}, "claude-haiku-4-5", false},
+		{"explicit ollama with model", Config{EnrichmentProvider: "ollama", EnrichmentModel: "qwen2.5"}, "qwen2.5", false},
+		{"anthropic without key", Config{EnrichmentProvider: "anthropic"}, "", true},
+		{"unknown provider", Config{EnrichmentProvider: "bogus"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := newEnrichmentClient(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantModel == "" {
+				if client != nil {
+					t.Errorf("expected no client, got %T", client)
+				}
+				return
+			}
+			if client == nil || client.Model() != tt.wantModel {
+				t.Errorf("expected client with model %q, got %v", tt.wantModel, client)
+			}
+		})
+	}
+}
+
+func TestIndexer_BatchEmbedding(t *testing.T) {
+	ctx := context.Background()
+
+	chunker := NewMockCodeChunker()
+	chunker.ChunkFunc = func(content []byte, lang, filePath string) ([]chunking.CodeChunk, error) {
+		var chunks []chunking.CodeChunk
+		for i := 0; i < 5; i++ {
+			chunks = append(chunks, chunking.CodeChunk{Content: fmt.Sprintf("chunk%d", i), Type: "function"})
+		}
+		return chunks, nil
+	}
+
+	newIndexer := func(embed *MockEmbedder, vectorStore *MockVectorStorage) *Indexer {
+		idx, _ := NewIndexerWithConfig(IndexerConfig{
+			Embedder:         embed,
+			VectorStore:      vectorStore,
+			MetaStore:        NewMockMetadataStorage(),
+			CodeChunker:      chunker,
+			IDGenerator:      NewMockIDGenerator("batch"),
+			EmbedBatchSize:   2,
+			EmbedParallelism: 2,
+		})
+		return idx
+	}
+
+	t.Run("Given batch size 2, When indexing 5 chunks, Then embeds in 3 batches", func(t *testing.T) {
+		embed := NewMockEmbedder()
+		embed.EmbedFunc = func(ctx context.Context, text string) ([]float32, error) {
+			return []float32{float32(len(text)), float32(text[len(text)-1])}, nil
+		}
+		vectorStore := NewMockVectorStorage()
+
+		result, err := newIndexer(embed, vectorStore).IndexFile(ctx, IndexRequest{Content: "x", Type: TypeCode, FilePath: "a.go"})
+		if err != nil {
+			t.Fatalf("IndexFile failed: %v", err)
+		}
+		if result.ChunksCount != 5 || embed.BatchCalls != 3 || embed.CallCount != 5 {
+			t.Errorf("expected 5 chunks in 3 batches, got %d chunks, %d batches, %d texts", result.ChunksCount, embed.BatchCalls, embed.CallCount)
+		}
+		// Vectors stay aligned with their chunks despite concurrent batches
+		for i := 0; i < 5; i++ {
+			vec := vectorStore.Vectors[chunkItemID("batch-1", i)]
+			if len(vec) != 2 || vec[1] != float32('0'+i) {
+				t.Errorf("chunk %d got vector %v", i, vec)
+			}
+		}
+	})
+
+	t.Run("Given failing batch requests, When indexing, Then falls back to single embeds", func(t *testing.T) {
+		embed := NewMockEmbedder()
+		embed.FailBatch = true
+		vectorStore := NewMockVectorStorage()
+
+		if _, err := newIndexer(embed, vectorStore).IndexFile(ctx, IndexRequest{Content: "x", Type: TypeCode, FilePath: "a.go"}); err != nil {
+			t.Fatalf("IndexFile failed: %v", err)
+		}
+		if embed.CallCount != 5 || vectorStore.UpsertCount != 5 {
+			t.Errorf("expected 5 single embeds and upserts, got %d and %d", embed.CallCount, vectorStore.UpsertCount)
+		}
+	})
+
+	t.Run("Given one text that cannot be embedded, When indexing manual items, Then only it fails", func(t *testing.T) {
+		embed := NewMockEmbedder()
+		embed.EmbedFunc = func(ctx context.Context, text string) ([]float32, error) {
+			if text == "bad" {
+				return nil, ErrMockEmbedding
+			}
+			return []float32{1}, nil
+		}
+
+		reqs := []IndexRequest{{Content: "good"}, {Content: "bad"}, {Content: "also good", Type: TypePattern}}
+		results, errs := newIndexer(embed, NewMockVectorStorage()).indexManualBatch(ctx, reqs)
+		if errs[0] != nil || errs[2] != nil || results[0] == nil || results[2] == nil {
+			t.Errorf("expected good items to succeed, got %v", errs)
+		}
+		if !errors.Is(errs[1], ErrMockEmbedding) || results[1] != nil {
+			t.Errorf("expected bad item to fail with embedding error, got %v", errs[1])
+		}
+	})
+}
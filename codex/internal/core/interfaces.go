@@ -9,11 +9,17 @@ import (
 )
 
 // Embedder generates vector embeddings for text content.
-// Implementations: LocalClient (nomic-embed-text via Ollama)
+// Implementations: embedding.LocalClient (Ollama), embedding.OpenAIClient
+// (OpenAI-compatible servers and llama.cpp)
 type Embedder interface {
 	// EmbedDocument embeds a text for storage/indexing.
 	EmbedDocument(ctx context.Context, text string) ([]float32, error)
 
+	// EmbedDocuments embeds several texts for storage/indexing, returning
+	// one vector per text in order. It fails as a whole; the Indexer falls
+	// back to EmbedDocument per text when a batch fails.
+	EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error)
+
 	// EmbedQuery embeds a search query (may use different prefix/settings).
 	EmbedQuery(ctx context.Context, query string) ([]float32, error)
 }
@@ -27,14 +33,44 @@ type VectorStorage interface {
 	// Search returns the top-K items by cosine similarity.
 	Search(ctx context.Context, queryVec []float32, limit int) ([]storage.ScoredResult, error)
 
+	// SearchWithOptions is Search with an optional allow-list and exact/approximate choice.
+	SearchWithOptions(ctx context.Context, queryVec []float32, limit int, opts storage.VectorSearchOptions) ([]storage.ScoredResult, error)
+
 	// Delete removes an item by ID.
 	Delete(ctx context.Context, itemID string) error
 }
 
+// VectorModels records which embedding model produced the stored vectors
+// and stages re-embedded vectors for an atomic switch to a new model.
+// Implementations: VecStore
+type VectorModels interface {
+	// ActiveModel returns the recorded model, or "" if none is recorded.
+	ActiveModel() (string, error)
+
+	// SetActiveModel records the model, attributing untagged vectors to it.
+	SetActiveModel(model string) error
+
+	// ModelCounts returns the number of stored vectors per model.
+	ModelCounts() (map[string]int, error)
+
+	// StagedIDs returns the items already staged for model.
+	StagedIDs(ctx context.Context, model string) (map[string]bool, error)
+
+	// StageVectors stores vectors for model without affecting search.
+	StageVectors(ctx context.Context, model string, vectors map[string][]float32) error
+
+	// SwapStaged replaces the live vectors with the staged ones in one
+	// transaction and makes model active.
+	SwapStaged(ctx context.Context, model string) (int, error)
+}
+
 // KeywordSearcher performs full-text keyword search.
 // Implementations: MetadataStore (FTS5)
 type KeywordSearcher interface {
 	KeywordSearch(query string, limit int) ([]storage.KeywordResult, error)
+
+	// KeywordSearchFiltered is KeywordSearch restricted to items matching filter.
+	KeywordSearchFiltered(query string, limit int, filter storage.SearchFilter) ([]storage.KeywordResult, error)
 }
 
 // MetadataStorage stores item metadata and auxiliary data.
@@ -43,18 +79,62 @@ type MetadataStorage interface {
 	SaveItem(item *storage.ItemRecord) error
 	GetItem(id string) (*storage.ItemRecord, error)
 	ListItems(itemType, scope string, limit, offset int) ([]*storage.ItemRecord, error)
+	// ListItemsAfter returns up to limit items with IDs after afterID, in ID order.
+	ListItemsAfter(afterID string, limit int) ([]*storage.ItemRecord, error)
 	DeleteItem(id string) error
+	// FilterItemIDs returns the IDs of all items matching filter.
+	FilterItemIDs(filter storage.SearchFilter) ([]string, error)
+	// GitRefItemIDs returns the IDs of items indexed from a git ref.
+	GitRefItemIDs() ([]string, error)
 	CountItemsByType() (map[string]int, error)
 	RecordFeedback(feedback *storage.FeedbackRecord) error
+	// ListFeedback returns all feedback recorded on the given items.
+	ListFeedback(itemIDs []string) ([]*storage.FeedbackRecord, error)
 	LogFlightRecorder(entry *storage.FlightRecorderRecord) error
 	GetFlightRecorderEntries(sessionID string) ([]*storage.FlightRecorderRecord, error)
 	Close() error
 }
 
+// FileIndex tracks indexed source files by path and content hash so that
+// re-indexing is incremental and idempotent, and the last commit indexed
+// from each git repository.
+// Implementations: MetadataStore (SQLite)
+type FileIndex interface {
+	// GetIndexedFile returns the record for path, or nil if it was never indexed.
+	GetIndexedFile(path string) (*storage.IndexedFileRecord, error)
+	SaveIndexedFile(rec *storage.IndexedFileRecord) error
+	DeleteIndexedFile(path string) error
+	// ListIndexedFiles returns records whose path starts with pathPrefix.
+	ListIndexedFiles(pathPrefix string) ([]*storage.IndexedFileRecord, error)
+
+	// GetGitIndexState returns the state of repo and ref, or nil if never indexed.
+	GetGitIndexState(repo, ref string) (*storage.GitIndexState, error)
+	SaveGitIndexState(st *storage.GitIndexState) error
+}
+
+// CodeGraph stores the symbol graph of indexed Go files, keyed by file so
+// re-indexing replaces a file's rows.
+// Implementations: MetadataStore (SQLite)
+type CodeGraph interface {
+	ReplaceCodeGraph(g *storage.CodeGraphFile) error
+	DeleteCodeGraph(path string) error
+	FindCodeSymbols(q storage.CodeSymbolQuery) ([]storage.CodeSymbolRecord, error)
+	FindCodeRefs(q storage.CodeRefQuery) ([]storage.CodeRefRecord, error)
+	FindCodeImports(q storage.CodeImportQuery) ([]storage.CodeImportRecord, error)
+}
+
 // Reranker reorders search results using a cross-encoder model.
 // Implementations: reranking.Reranker (BGE/ONNX)
 type Reranker interface {
 	Rerank(query string, docs []reranking.Document, topK int) ([]reranking.RerankResult, error)
+
+	// IsAvailable reports whether a model is loaded. When false, the engine
+	// keeps fusion scores instead of calling Rerank.
+	IsAvailable() bool
+
+	// Status describes the active model, or why reranking is unavailable.
+	Status() string
+
 	Close()
 }
 
@@ -67,9 +67,9 @@ func MigrateV0ToV1(ctx context.Context, v0DBPath string, engine *SearchEngine) (
 	}
 	defer rows.Close()
 
-	// Process items in batches
+	// Process items in batches large enough to keep every embedding worker busy
 	var batch []V0Item
-	const batchSize = 50
+	batchSize := indexer.embedBatchSize * indexer.embedParallelism
 
 	for rows.Next() {
 		var item V0Item
@@ -107,27 +107,36 @@ func MigrateV0ToV1(ctx context.Context, v0DBPath string, engine *SearchEngine) (
 	return stats, nil
 }
 
-// migrateBatch processes a batch of V0 items
+// migrateBatch processes a batch of V0 items, embedding them together.
+// Items that fail are recorded in stats without affecting the rest.
 func migrateBatch(ctx context.Context, items []V0Item, indexer *Indexer, stats *MigrationStats) {
+	var reqs []IndexRequest
+	var migrating []V0Item
 	for _, v0Item := range items {
-		result, err := migrateItem(ctx, v0Item, indexer)
-		if err != nil {
-			stats.Errors = append(stats.Errors, fmt.Sprintf("item %s: %v", v0Item.ID, err))
+		// Skip empty content
+		if strings.TrimSpace(v0Item.Content) == "" {
+			stats.MigratedItems++
+			continue
+		}
+		reqs = append(reqs, v0IndexRequest(v0Item))
+		migrating = append(migrating, v0Item)
+	}
+
+	results, errs := indexer.indexManualBatch(ctx, reqs)
+	for i, v0Item := range migrating {
+		if errs[i] != nil {
+			stats.Errors = append(stats.Errors, fmt.Sprintf("item %s: %v", v0Item.ID, errs[i]))
 			stats.FailedItems++
 			continue
 		}
 
 		stats.MigratedItems++
-		stats.TotalChunks += result.ChunksCount
+		stats.TotalChunks += results[i].ChunksCount
 	}
 }
 
-// migrateItem migrates a single V0 item to V1
-func migrateItem(ctx context.Context, v0Item V0Item, indexer *Indexer) (*IndexResult, error) {
-	// Skip empty content
-	if strings.TrimSpace(v0Item.Content) == "" {
-		return &IndexResult{ChunksCount: 0}, nil
-	}
+// v0IndexRequest builds the index request for a V0 item
+func v0IndexRequest(v0Item V0Item) IndexRequest {
 
 	// Parse tags
 	var tags []string
@@ -140,16 +149,13 @@ func migrateItem(ctx context.Context, v0Item V0Item, indexer *Indexer) (*IndexRe
 	// Determine content type for routing
 	contentType := mapV0TypeToV1Type(v0Item.Type)
 
-	// Create index request
-	req := IndexRequest{
+	return IndexRequest{
 		Content:  v0Item.Content,
 		Type:     contentType,
 		Tags:     tags,
 		Scope:    v0Item.Scope,
 		FilePath: "", // V0 items may not have file paths
 	}
-
-	return indexer.IndexFile(ctx, req)
 }
 
 // mapV0TypeToV1Type maps RECALL v0 types to Codex v1 types
@@ -212,6 +218,9 @@ func MigrateV0ToV1WithProgress(ctx context.Context, v0DBPath string, engine *Sea
 	}
 	defer rows.Close()
 
+	var batch []V0Item
+	batchSize := indexer.embedBatchSize * indexer.embedParallelism
+
 	current := 0
 	for rows.Next() {
 		var item V0Item
@@ -237,19 +246,19 @@ func MigrateV0ToV1WithProgress(ctx context.Context, v0DBPath string, engine *Sea
 			progress(current, total, item.Title)
 		}
 
-		// Migrate item
-		result, err := migrateItem(ctx, item, indexer)
-		if err != nil {
-			stats.Errors = append(stats.Errors, fmt.Sprintf("item %s: %v", item.ID, err))
-			stats.FailedItems++
-		} else {
-			stats.MigratedItems++
-			stats.TotalChunks += result.ChunksCount
+		batch = append(batch, item)
+		if len(batch) >= batchSize {
+			migrateBatch(ctx, batch, indexer, stats)
+			batch = batch[:0]
 		}
 
 		current++
 	}
 
+	if len(batch) > 0 {
+		migrateBatch(ctx, batch, indexer, stats)
+	}
+
 	stats.EndTime = time.Now()
 	return stats, nil
 }
@@ -656,3 +656,58 @@ func TestMigrationStats_SingleThreadedUsage(t *testing.T) {
 		t.Errorf("expected %d errors, got %d", stats.FailedItems, len(stats.Errors))
 	}
 }
+
+func TestMigrateV0ToV1_Batched(t *testing.T) {
+	dbPath, cleanup := createTestV0Database(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	newEngine := func(embed *MockEmbedder) *SearchEngine {
+		return NewSearchEngineWithDeps(SearchEngineDeps{
+			Config:   Config{EmbedBatchSize: 2, EmbedParallelism: 2},
+			VecStore: NewMockVectorStorage(),
+			Metadata: NewMockMetadataStorage(),
+			Embedder: embed,
+		})
+	}
+
+	t.Run("Given v0 items, When migrating, Then embeds them in batches", func(t *testing.T) {
+		embed := NewMockEmbedder()
+		stats, err := MigrateV0ToV1(ctx, dbPath, newEngine(embed))
+		if err != nil {
+			t.Fatalf("MigrateV0ToV1 failed: %v", err)
+		}
+		if stats.FailedItems != 0 || stats.MigratedItems != stats.TotalItems {
+			t.Errorf("expected all %d items migrated, got %+v", stats.TotalItems, stats)
+		}
+		if embed.BatchCalls == 0 || embed.BatchCalls >= stats.TotalItems {
+			t.Errorf("expected fewer batches than items, got %d batches for %d items", embed.BatchCalls, stats.TotalItems)
+		}
+	})
+
+	t.Run("Given one item that cannot be embedded, When migrating with progress, Then only it fails", func(t *testing.T) {
+		embed := NewMockEmbedder()
+		embed.EmbedFunc = func(ctx context.Context, text string) ([]float32, error) {
+			if text == "Check for nil before dereferencing" {
+				return nil, ErrMockEmbedding
+			}
+			return []float32{1}, nil
+		}
+		// Batches containing the bad item fail and fall back to single embeds
+		embed.FailBatch = true
+
+		calls := 0
+		stats, err := MigrateV0ToV1WithProgress(ctx, dbPath, newEngine(embed), func(current, total int, item string) {
+			calls++
+		})
+		if err != nil {
+			t.Fatalf("MigrateV0ToV1WithProgress failed: %v", err)
+		}
+		if stats.FailedItems != 1 || stats.MigratedItems != stats.TotalItems-1 {
+			t.Errorf("expected exactly one failed item, got %+v", stats)
+		}
+		if calls != stats.TotalItems {
+			t.Errorf("expected progress for each of %d items, got %d", stats.TotalItems, calls)
+		}
+	})
+}
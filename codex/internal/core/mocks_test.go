@@ -4,9 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/anthropics/aef/codex/internal/chunking"
+	"github.com/anthropics/aef/codex/internal/reranking"
 	"github.com/anthropics/aef/codex/internal/storage"
 )
 
@@ -23,6 +26,8 @@ type MockEmbedder struct {
 	EmbedFunc   func(ctx context.Context, text string) ([]float32, error)
 	QueryFunc   func(ctx context.Context, query string) ([]float32, error)
 	CallCount   int
+	BatchCalls  int
+	FailBatch   bool // fail every EmbedDocuments call
 	LastText    string
 	FailOnCall  int // Fail on Nth call (0 = never fail)
 	FixedVector []float32
@@ -52,6 +57,28 @@ func (m *MockEmbedder) EmbedDocument(ctx context.Context, text string) ([]float3
 	return m.FixedVector, nil
 }
 
+// EmbedDocuments embeds each text via EmbedDocument, so CallCount and
+// FailOnCall count texts rather than batches.
+func (m *MockEmbedder) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	m.mu.Lock()
+	m.BatchCalls++
+	fail := m.FailBatch
+	m.mu.Unlock()
+
+	if fail {
+		return nil, ErrMockEmbedding
+	}
+	vecs := make([][]float32, len(texts))
+	for i, text := range texts {
+		vec, err := m.EmbedDocument(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		vecs[i] = vec
+	}
+	return vecs, nil
+}
+
 func (m *MockEmbedder) EmbedQuery(ctx context.Context, query string) ([]float32, error) {
 	if m.QueryFunc != nil {
 		return m.QueryFunc(ctx, query)
@@ -69,6 +96,9 @@ type MockVectorStorage struct {
 	SearchCount   int
 	FailOnUpsert  int
 	FailOnSearch  bool
+	LastAllowed   map[string]bool
+	LastExcluded  map[string]bool
+	LastExact     bool
 }
 
 func NewMockVectorStorage() *MockVectorStorage {
@@ -96,22 +126,49 @@ func (m *MockVectorStorage) Upsert(ctx context.Context, itemID string, vector []
 }
 
 func (m *MockVectorStorage) Search(ctx context.Context, queryVec []float32, limit int) ([]storage.ScoredResult, error) {
+	return m.SearchWithOptions(ctx, queryVec, limit, storage.VectorSearchOptions{})
+}
+
+func (m *MockVectorStorage) SearchWithOptions(ctx context.Context, queryVec []float32, limit int, opts storage.VectorSearchOptions) ([]storage.ScoredResult, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	m.SearchCount++
+	m.LastAllowed = opts.Allowed
+	m.LastExcluded = opts.Excluded
+	m.LastExact = opts.Exact
 
 	if m.FailOnSearch {
 		return nil, ErrMockStorage
 	}
 
+	accept := func(id string) bool {
+		if opts.Allowed != nil {
+			return opts.Allowed[id]
+		}
+		return !opts.Excluded[id]
+	}
+
 	if m.SearchFunc != nil {
-		return m.SearchFunc(ctx, queryVec, limit)
+		results, err := m.SearchFunc(ctx, queryVec, limit)
+		if err != nil {
+			return nil, err
+		}
+		var filtered []storage.ScoredResult
+		for _, r := range results {
+			if accept(r.ID) {
+				filtered = append(filtered, r)
+			}
+		}
+		return filtered, nil
 	}
 
 	// Return stored items as search results
 	var results []storage.ScoredResult
 	for id := range m.Vectors {
+		if !accept(id) {
+			continue
+		}
 		results = append(results, storage.ScoredResult{
 			ID:    id,
 			Score: 0.9,
@@ -131,6 +188,76 @@ func (m *MockVectorStorage) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+// MockVectorModels implements VectorModels for testing. Swapped vectors
+// replace the contents of Live.
+type MockVectorModels struct {
+	mu         sync.Mutex
+	Active     string
+	Staged     map[string]map[string][]float32 // model -> item ID -> vector
+	Live       *MockVectorStorage
+	SwapCount  int
+	StageCalls int
+}
+
+func NewMockVectorModels(live *MockVectorStorage) *MockVectorModels {
+	return &MockVectorModels{
+		Staged: make(map[string]map[string][]float32),
+		Live:   live,
+	}
+}
+
+func (m *MockVectorModels) ActiveModel() (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Active, nil
+}
+
+func (m *MockVectorModels) SetActiveModel(model string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Active = model
+	return nil
+}
+
+func (m *MockVectorModels) ModelCounts() (map[string]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return map[string]int{m.Active: len(m.Live.Vectors)}, nil
+}
+
+func (m *MockVectorModels) StagedIDs(ctx context.Context, model string) (map[string]bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ids := make(map[string]bool)
+	for id := range m.Staged[model] {
+		ids[id] = true
+	}
+	return ids, nil
+}
+
+func (m *MockVectorModels) StageVectors(ctx context.Context, model string, vectors map[string][]float32) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.StageCalls++
+	if m.Staged[model] == nil {
+		m.Staged[model] = make(map[string][]float32)
+	}
+	for id, vec := range vectors {
+		m.Staged[model][id] = vec
+	}
+	return nil
+}
+
+func (m *MockVectorModels) SwapStaged(ctx context.Context, model string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.SwapCount++
+	m.Live.Vectors = m.Staged[model]
+	delete(m.Staged, model)
+	m.Active = model
+	return len(m.Live.Vectors), nil
+}
+
 // MockKeywordSearcher implements KeywordSearcher for testing
 type MockKeywordSearcher struct {
 	mu           sync.Mutex
@@ -138,6 +265,7 @@ type MockKeywordSearcher struct {
 	CallCount    int
 	FailOnSearch bool
 	Results      []storage.KeywordResult
+	LastFilter   storage.SearchFilter
 }
 
 func NewMockKeywordSearcher() *MockKeywordSearcher {
@@ -145,10 +273,16 @@ func NewMockKeywordSearcher() *MockKeywordSearcher {
 }
 
 func (m *MockKeywordSearcher) KeywordSearch(query string, limit int) ([]storage.KeywordResult, error) {
+	return m.KeywordSearchFiltered(query, limit, storage.SearchFilter{})
+}
+
+// KeywordSearchFiltered records the filter; canned results are returned unfiltered.
+func (m *MockKeywordSearcher) KeywordSearchFiltered(query string, limit int, filter storage.SearchFilter) ([]storage.KeywordResult, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	m.CallCount++
+	m.LastFilter = filter
 
 	if m.FailOnSearch {
 		return nil, ErrMockStorage
@@ -215,6 +349,23 @@ func (m *MockMetadataStorage) GetItem(id string) (*storage.ItemRecord, error) {
 	return item, nil
 }
 
+func (m *MockMetadataStorage) ListFeedback(itemIDs []string) ([]*storage.FeedbackRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	want := make(map[string]bool, len(itemIDs))
+	for _, id := range itemIDs {
+		want[id] = true
+	}
+	var records []*storage.FeedbackRecord
+	for _, f := range m.Feedback {
+		if want[f.ItemID] {
+			records = append(records, f)
+		}
+	}
+	return records, nil
+}
+
 func (m *MockMetadataStorage) RecordFeedback(feedback *storage.FeedbackRecord) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -270,6 +421,8 @@ func (m *MockMetadataStorage) ListItems(itemType, scope string, limit, offset in
 		}
 		result = append(result, item)
 	}
+	// Stable order so that paging visits every item once
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
 
 	if offset >= len(result) {
 		return []*storage.ItemRecord{}, nil
@@ -282,6 +435,61 @@ func (m *MockMetadataStorage) ListItems(itemType, scope string, limit, offset in
 	return result, nil
 }
 
+func (m *MockMetadataStorage) ListItemsAfter(afterID string, limit int) ([]*storage.ItemRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.FailOnList {
+		return nil, ErrMockStorage
+	}
+
+	var result []*storage.ItemRecord
+	for _, item := range m.Items {
+		if item.ID > afterID {
+			result = append(result, item)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+func (m *MockMetadataStorage) FilterItemIDs(filter storage.SearchFilter) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.FailOnList {
+		return nil, ErrMockStorage
+	}
+
+	var ids []string
+	for id, item := range m.Items {
+		if filter.Matches(item) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (m *MockMetadataStorage) GitRefItemIDs() ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.FailOnList {
+		return nil, ErrMockStorage
+	}
+
+	var ids []string
+	for id, item := range m.Items {
+		if ref, _ := item.Metadata["git_ref"].(string); ref != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
 func (m *MockMetadataStorage) DeleteItem(id string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -417,3 +625,235 @@ func (m *MockIDGenerator) GenerateID() string {
 	}
 	return fmt.Sprintf("mock-id-%d", m.Counter)
 }
+
+// MockFileIndex implements FileIndex for testing
+type MockFileIndex struct {
+	mu        sync.Mutex
+	Files     map[string]*storage.IndexedFileRecord
+	GitStates map[string]*storage.GitIndexState // keyed by repo + "@" + ref
+}
+
+func NewMockFileIndex() *MockFileIndex {
+	return &MockFileIndex{
+		Files:     make(map[string]*storage.IndexedFileRecord),
+		GitStates: make(map[string]*storage.GitIndexState),
+	}
+}
+
+func (m *MockFileIndex) GetIndexedFile(path string) (*storage.IndexedFileRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.Files[path], nil
+}
+
+func (m *MockFileIndex) SaveIndexedFile(rec *storage.IndexedFileRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Files[rec.Path] = rec
+	return nil
+}
+
+func (m *MockFileIndex) DeleteIndexedFile(path string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.Files, path)
+	return nil
+}
+
+func (m *MockFileIndex) ListIndexedFiles(pathPrefix string) ([]*storage.IndexedFileRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var records []*storage.IndexedFileRecord
+	for p, rec := range m.Files {
+		if strings.HasPrefix(p, pathPrefix) {
+			records = append(records, rec)
+		}
+	}
+	return records, nil
+}
+
+func (m *MockFileIndex) GetGitIndexState(repo, ref string) (*storage.GitIndexState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.GitStates[repo+"@"+ref], nil
+}
+
+func (m *MockFileIndex) SaveGitIndexState(st *storage.GitIndexState) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.GitStates[st.Repo+"@"+st.Ref] = st
+	return nil
+}
+
+// MockCodeGraph implements CodeGraph for testing, matching symbols and
+// imports by suffix like MetadataStore.
+type MockCodeGraph struct {
+	mu    sync.Mutex
+	Files map[string]*storage.CodeGraphFile
+}
+
+func NewMockCodeGraph() *MockCodeGraph {
+	return &MockCodeGraph{Files: make(map[string]*storage.CodeGraphFile)}
+}
+
+func (m *MockCodeGraph) ReplaceCodeGraph(g *storage.CodeGraphFile) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Files[g.Path] = g
+	return nil
+}
+
+func (m *MockCodeGraph) DeleteCodeGraph(path string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.Files, path)
+	return nil
+}
+
+// sortedFiles returns the stored files in path order.
+func (m *MockCodeGraph) sortedFiles() []*storage.CodeGraphFile {
+	var paths []string
+	for p := range m.Files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	files := make([]*storage.CodeGraphFile, len(paths))
+	for i, p := range paths {
+		files[i] = m.Files[p]
+	}
+	return files
+}
+
+func (m *MockCodeGraph) FindCodeSymbols(q storage.CodeSymbolQuery) ([]storage.CodeSymbolRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make(map[string]bool)
+	for _, name := range q.Names {
+		names[name] = true
+	}
+	var symbols []storage.CodeSymbolRecord
+	for _, f := range m.sortedFiles() {
+		for _, sym := range f.Symbols {
+			if q.Symbol != "" && sym.ID != q.Symbol && !strings.HasSuffix(sym.ID, "/"+q.Symbol) && !strings.HasSuffix(sym.ID, "."+q.Symbol) {
+				continue
+			}
+			if len(names) > 0 && !names[sym.Name] {
+				continue
+			}
+			if (q.Kind != "" && sym.Kind != q.Kind) || (q.Parent != "" && sym.Parent != q.Parent) {
+				continue
+			}
+			sym.File = f.Path
+			symbols = append(symbols, sym)
+		}
+	}
+	sort.SliceStable(symbols, func(i, j int) bool { return symbols[i].ID < symbols[j].ID })
+	return symbols, nil
+}
+
+func (m *MockCodeGraph) FindCodeRefs(q storage.CodeRefQuery) ([]storage.CodeRefRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var refs []storage.CodeRefRecord
+	for _, f := range m.sortedFiles() {
+		for _, ref := range f.Refs {
+			match := (q.Symbol != "" && ref.Symbol == q.Symbol) ||
+				(q.Name != "" && ref.Symbol == "" && ref.Name == q.Name) ||
+				(q.From != "" && ref.From == q.From)
+			if !match || (q.Kind != "" && ref.Kind != q.Kind) {
+				continue
+			}
+			ref.File = f.Path
+			refs = append(refs, ref)
+		}
+	}
+	return refs, nil
+}
+
+func (m *MockCodeGraph) FindCodeImports(q storage.CodeImportQuery) ([]storage.CodeImportRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	matches := func(path, want string) bool {
+		return want != "" && (path == want || strings.HasSuffix(path, "/"+want))
+	}
+	var imports []storage.CodeImportRecord
+	for _, f := range m.sortedFiles() {
+		for _, imp := range f.Imports {
+			if matches(imp.Package, q.Package) || matches(imp.Path, q.Path) {
+				imp.File = f.Path
+				imports = append(imports, imp)
+			}
+		}
+	}
+	return imports, nil
+}
+
+// MockReranker implements Reranker for testing.
+// Rerank scores documents by ScoreFunc (default: reverse input order) and
+// returns them highest score first, like the real reranker.
+type MockReranker struct {
+	mu        sync.Mutex
+	Available bool
+	ScoreFunc func(doc reranking.Document) float64
+	CallCount int
+	Closed    bool
+}
+
+func (m *MockReranker) Rerank(query string, docs []reranking.Document, topK int) ([]reranking.RerankResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.CallCount++
+	if !m.Available {
+		return nil, reranking.ErrUnavailable
+	}
+
+	results := make([]reranking.RerankResult, 0, len(docs))
+	for i := len(docs) - 1; i >= 0; i-- {
+		score := float64(i + 1)
+		if m.ScoreFunc != nil {
+			score = m.ScoreFunc(docs[i])
+		}
+		results = append(results, reranking.RerankResult{ID: docs[i].ID, Score: score})
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	if topK > 0 && len(results) > topK {
+		results = results[:topK]
+	}
+	return results, nil
+}
+
+func (m *MockReranker) IsAvailable() bool {
+	return m.Available
+}
+
+func (m *MockReranker) Status() string {
+	if m.Available {
+		return "mock model"
+	}
+	return "mock fallback"
+}
+
+func (m *MockReranker) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Closed = true
+}
+
+// MockCompleter implements llm.Client for testing
+type MockCompleter struct {
+	mu        sync.Mutex
+	Response  string
+	Err       error
+	Prompts   []string
+	ModelName string
+}
+
+func (m *MockCompleter) Complete(ctx context.Context, prompt string, maxTokens int) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Prompts = append(m.Prompts, prompt)
+	return m.Response, m.Err
+}
+
+func (m *MockCompleter) Model() string { return m.ModelName }
@@ -0,0 +1,290 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// maxParentChunks bounds the chunks read for one parent document.
+const maxParentChunks = 10000
+
+// Citation locates a search result in its source: a line range, or a page
+// for documents without lines such as PDFs.
+type Citation struct {
+	Source    string `json:"source"`
+	StartLine int    `json:"start_line,omitempty"`
+	EndLine   int    `json:"end_line,omitempty"`
+	Page      int    `json:"page,omitempty"`
+}
+
+// String formats the citation as "path:12-40", "path:12", "path (page 3)"
+// or "path".
+func (c Citation) String() string {
+	switch {
+	case c.StartLine > 0 && c.EndLine > c.StartLine:
+		return fmt.Sprintf("%s:%d-%d", c.Source, c.StartLine, c.EndLine)
+	case c.StartLine > 0:
+		return fmt.Sprintf("%s:%d", c.Source, c.StartLine)
+	case c.Page > 0:
+		return fmt.Sprintf("%s (page %d)", c.Source, c.Page)
+	}
+	return c.Source
+}
+
+// ParentDocument is a source file reassembled from its indexed chunks.
+type ParentDocument struct {
+	ParentID string   `json:"parent_id"`
+	Type     string   `json:"type"`
+	Citation Citation `json:"citation"` // the lines the chunks cover
+	Content  string   `json:"content"`
+	ChunkIDs []string `json:"chunk_ids"` // in document order
+
+	// Complete is false when some lines from line 1 to the last chunk's
+	// are in no chunk, e.g. front matter or code between declarations the
+	// chunker left out. Those lines are blank in Content.
+	Complete bool `json:"complete"`
+}
+
+// GetParentDocument reassembles the source file of a chunk, given the ID
+// of any of its chunks or its parent ID. Chunks with line numbers are
+// placed at their lines; other chunks, such as PDF pages, are joined in
+// order. Items that are not chunks of a file have no parent document.
+func (e *SearchEngine) GetParentDocument(ctx context.Context, id string) (*ParentDocument, error) {
+	parentID := id
+	if p, _, ok := splitChunkID(id); ok {
+		parentID = p
+	}
+
+	var chunks []Item
+	for i := 0; i < maxParentChunks; i++ {
+		record, err := e.metadata.GetItem(chunkItemID(parentID, i))
+		if err != nil {
+			if i == 0 {
+				return nil, fmt.Errorf("%s is not a chunk of an indexed file: %w", id, err)
+			}
+			break
+		}
+		chunks = append(chunks, *itemFromRecord(record))
+	}
+
+	content, start, end, complete := joinChunks(chunks)
+	doc := &ParentDocument{
+		ParentID: parentID,
+		Type:     chunks[0].Type,
+		Citation: Citation{Source: chunks[0].Source, StartLine: start, EndLine: end},
+		Content:  content,
+		Complete: complete && start <= 1,
+	}
+	for _, c := range chunks {
+		doc.ChunkIDs = append(doc.ChunkIDs, c.ID)
+	}
+	return doc, nil
+}
+
+// citeAndExpand sets the citation of each result and, when the request
+// asks for it, joins each chunk with its neighbours or its section.
+// Results found only by keyword search get their source and metadata first.
+func (e *SearchEngine) citeAndExpand(results []SearchResult, req SearchRequest) {
+	if e.metadata == nil {
+		return
+	}
+	for i := range results {
+		r := &results[i]
+		if r.Source == "" && r.Metadata == nil {
+			if record, err := e.metadata.GetItem(r.ID); err == nil {
+				r.Source = record.Source
+				r.Project = record.Project
+				r.Metadata = record.Metadata
+			}
+		}
+		r.Citation = citationOf(r.Item)
+
+		if req.Neighbors > 0 || req.ExpandSection {
+			e.expandResult(r, req.Neighbors, req.ExpandSection)
+		}
+	}
+}
+
+// expandResult replaces the content of a chunk result with the chunk and
+// up to neighbors chunks on each side, or with every chunk of its section
+// when section is set. Results that are not chunks are left as they are,
+// and so are chunks whose neighbours cannot be read.
+func (e *SearchEngine) expandResult(r *SearchResult, neighbors int, section bool) {
+	parentID, i, ok := splitChunkID(r.ID)
+	if !ok {
+		return
+	}
+	key := sectionKey(r.Item)
+	if section && key == "" {
+		return
+	}
+
+	// get returns chunk j when it belongs to the expansion
+	get := func(j int) *Item {
+		record, err := e.metadata.GetItem(chunkItemID(parentID, j))
+		if err != nil {
+			return nil
+		}
+		c := itemFromRecord(record)
+		if section && sectionKey(*c) != key {
+			return nil
+		}
+		return c
+	}
+
+	var before, after []Item
+	for j := i - 1; j >= 0 && i-j < maxParentChunks; j-- {
+		if !section && i-j > neighbors {
+			break
+		}
+		c := get(j)
+		if c == nil {
+			break
+		}
+		before = append([]Item{*c}, before...)
+	}
+	for j := i + 1; j-i < maxParentChunks; j++ {
+		if !section && j-i > neighbors {
+			break
+		}
+		c := get(j)
+		if c == nil {
+			break
+		}
+		after = append(after, *c)
+	}
+	if len(before) == 0 && len(after) == 0 {
+		return
+	}
+
+	chunks := append(append(before, r.Item), after...)
+	content, start, end, _ := joinChunks(chunks)
+	r.Content = content
+	r.Expanded = nil
+	for _, c := range chunks {
+		r.Expanded = append(r.Expanded, c.ID)
+	}
+	if r.Citation != nil && start > 0 {
+		r.Citation.StartLine, r.Citation.EndLine = start, end
+	}
+}
+
+// sectionKey identifies the section a chunk belongs to: its breadcrumb for
+// doc chunks, and its symbol for the parts of a code chunk split for size.
+// Other code chunks are a section of their own and have no key.
+func sectionKey(item Item) string {
+	if section, ok := item.Metadata["section"].(string); ok && section != "" {
+		return "section:" + section
+	}
+	if metaInt(item.Metadata, "parts") > 0 {
+		symbol, _ := item.Metadata["symbol"].(string)
+		return "symbol:" + symbol
+	}
+	return ""
+}
+
+// groupByParent keeps the best-ranked result of each parent document, in
+// order, counting the results folded into it. Results that are not chunks
+// are kept as they are.
+func groupByParent(results []SearchResult) []SearchResult {
+	first := make(map[string]int)
+	var grouped []SearchResult
+	for _, r := range results {
+		parent := parentOf(r.Item)
+		if parent == "" {
+			grouped = append(grouped, r)
+			continue
+		}
+		if i, ok := first[parent]; ok {
+			grouped[i].ParentHits++
+			continue
+		}
+		first[parent] = len(grouped)
+		r.ParentHits = 1
+		grouped = append(grouped, r)
+	}
+	return grouped
+}
+
+// parentOf returns the parent document of a chunk item, from its metadata
+// or its ID, or "" for items that are not chunks.
+func parentOf(item Item) string {
+	if parent, ok := item.Metadata["parent_id"].(string); ok && parent != "" {
+		return parent
+	}
+	if parent, _, ok := splitChunkID(item.ID); ok {
+		return parent
+	}
+	return ""
+}
+
+// citationOf returns where an item comes from, or nil for items without
+// a source.
+func citationOf(item Item) *Citation {
+	if item.Source == "" {
+		return nil
+	}
+	return &Citation{
+		Source:    item.Source,
+		StartLine: metaInt(item.Metadata, "start_line"),
+		EndLine:   metaInt(item.Metadata, "end_line"),
+		Page:      metaInt(item.Metadata, "page"),
+	}
+}
+
+// joinChunks joins chunks of one document. When every chunk has line
+// numbers, each is placed at its lines, so overlapping chunks are not
+// repeated, and it returns the line range and whether every line in it is
+// covered; uncovered lines are left blank. Otherwise the chunks are joined
+// in order with blank lines between them, and the range is 0.
+func joinChunks(chunks []Item) (content string, start, end int, complete bool) {
+	for _, c := range chunks {
+		s, e := metaInt(c.Metadata, "start_line"), metaInt(c.Metadata, "end_line")
+		if s <= 0 || e < s {
+			parts := make([]string, len(chunks))
+			for i, c := range chunks {
+				parts[i] = c.Content
+			}
+			return strings.Join(parts, "\n\n"), 0, 0, true
+		}
+		if start == 0 || s < start {
+			start = s
+		}
+		end = max(end, e)
+	}
+
+	lines := make([]string, end-start+1)
+	covered := make([]bool, len(lines))
+	for _, c := range chunks {
+		s, e := metaInt(c.Metadata, "start_line"), metaInt(c.Metadata, "end_line")
+		text := strings.Split(c.Content, "\n")
+		// Contextual enrichment puts its text before the chunk's lines
+		if n := e - s + 1; len(text) > n {
+			text = text[len(text)-n:]
+		}
+		for k, line := range text {
+			lines[s-start+k] = line
+			covered[s-start+k] = true
+		}
+	}
+	complete = true
+	for _, ok := range covered {
+		complete = complete && ok
+	}
+	return strings.Join(lines, "\n"), start, end, complete
+}
+
+// metaInt reads an integer from item metadata, where it is an int when set
+// by the indexer and a float64 once loaded from storage.
+func metaInt(meta map[string]any, key string) int {
+	switch v := meta[key].(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	}
+	return 0
+}
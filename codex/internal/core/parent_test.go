@@ -0,0 +1,213 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/anthropics/aef/codex/internal/storage"
+)
+
+func TestSearchEngine_GetParentDocument(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Given an indexed file When getting its parent document Then the chunks are reassembled", func(t *testing.T) {
+		// Given
+		idx, _, _, metaStore, _ := newIncrementalIndexer()
+		content := "package main\n\nfunc a() {}\nfunc b() {}"
+		res, err := idx.IndexFile(ctx, IndexRequest{Content: content, Type: "code", FilePath: "/repo/main.go", Scope: "project"})
+		if err != nil {
+			t.Fatalf("IndexFile failed: %v", err)
+		}
+		engine := NewSearchEngineWithDeps(SearchEngineDeps{Metadata: metaStore})
+
+		for _, id := range []string{res.ItemID, chunkItemID(res.ItemID, 2)} {
+			// When
+			doc, err := engine.GetParentDocument(ctx, id)
+
+			// Then
+			if err != nil {
+				t.Fatalf("GetParentDocument(%s) failed: %v", id, err)
+			}
+			if doc.Content != content || !doc.Complete {
+				t.Errorf("document = %q (complete %v), want %q", doc.Content, doc.Complete, content)
+			}
+			if doc.ParentID != res.ItemID || len(doc.ChunkIDs) != 4 {
+				t.Errorf("document = %+v, want parent %s with 4 chunks", doc, res.ItemID)
+			}
+			if got := doc.Citation.String(); got != "/repo/main.go:1-4" {
+				t.Errorf("citation = %q, want /repo/main.go:1-4", got)
+			}
+		}
+	})
+
+	t.Run("Given an item that is not a chunk When getting its parent document Then it fails", func(t *testing.T) {
+		// Given
+		metaStore := NewMockMetadataStorage()
+		metaStore.Items["note"] = &storage.ItemRecord{ID: "note", Content: "remember this"}
+		engine := NewSearchEngineWithDeps(SearchEngineDeps{Metadata: metaStore})
+
+		// When
+		_, err := engine.GetParentDocument(ctx, "note")
+
+		// Then
+		if err == nil {
+			t.Error("expected an error for an item without chunks")
+		}
+	})
+}
+
+func TestSearchEngine_Search_ParentDocuments(t *testing.T) {
+	ctx := context.Background()
+
+	// doc has five one-line chunks under two sections; other has one chunk
+	newDeps := func(hits ...string) SearchEngineDeps {
+		metaStore := NewMockMetadataStorage()
+		sections := []string{"Guide > Setup", "Guide > Setup", "Guide > Setup", "Guide > Usage", "Guide > Usage"}
+		for i, section := range sections {
+			id := chunkItemID("doc", i)
+			metaStore.Items[id] = &storage.ItemRecord{
+				ID: id, Type: "doc", Scope: ScopeProject, Source: "/repo/guide.md",
+				Content: strings.Repeat(string(rune('a'+i)), 3),
+				Metadata: map[string]any{
+					"parent_id": "doc", "section": section,
+					"start_line": float64(i + 1), "end_line": float64(i + 1),
+				},
+			}
+		}
+		other := chunkItemID("other", 0)
+		metaStore.Items[other] = &storage.ItemRecord{
+			ID: other, Type: "code", Scope: ScopeProject, Source: "/repo/other.go", Content: "x",
+			Metadata: map[string]any{"parent_id": "other", "start_line": float64(7), "end_line": float64(9)},
+		}
+
+		vectorStore := NewMockVectorStorage()
+		vectorStore.SearchFunc = func(ctx context.Context, queryVec []float32, limit int) ([]storage.ScoredResult, error) {
+			var results []storage.ScoredResult
+			for i, id := range hits {
+				results = append(results, storage.ScoredResult{ID: id, Score: 0.9 - float64(i)*0.1})
+			}
+			return results, nil
+		}
+		return SearchEngineDeps{
+			VecStore: vectorStore,
+			Keywords: NewMockKeywordSearcher(),
+			Metadata: metaStore,
+			Embedder: NewMockEmbedder(),
+		}
+	}
+
+	t.Run("Given chunk results When searching Then each result cites its lines", func(t *testing.T) {
+		// Given
+		engine := NewSearchEngineWithDeps(newDeps(chunkItemID("other", 0)))
+
+		// When
+		results, err := engine.Search(ctx, SearchRequest{Query: "q"})
+
+		// Then
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		if len(results) != 1 || results[0].Citation == nil {
+			t.Fatalf("expected one cited result, got %+v", results)
+		}
+		if got := results[0].Citation.String(); got != "/repo/other.go:7-9" {
+			t.Errorf("citation = %q, want /repo/other.go:7-9", got)
+		}
+	})
+
+	t.Run("Given several chunks of one file When grouping by parent Then the file appears once", func(t *testing.T) {
+		// Given
+		engine := NewSearchEngineWithDeps(newDeps(chunkItemID("doc", 1), chunkItemID("doc", 3), chunkItemID("other", 0), chunkItemID("doc", 0)))
+
+		// When
+		results, err := engine.Search(ctx, SearchRequest{Query: "q", GroupByParent: true})
+
+		// Then
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		if len(results) != 2 {
+			t.Fatalf("expected 2 grouped results, got %d", len(results))
+		}
+		if results[0].ID != chunkItemID("doc", 1) || results[0].ParentHits != 3 {
+			t.Errorf("first result = %s with %d hits, want doc-chunk-1 with 3", results[0].ID, results[0].ParentHits)
+		}
+		if results[1].ID != chunkItemID("other", 0) || results[1].ParentHits != 1 {
+			t.Errorf("second result = %s with %d hits, want other-chunk-0 with 1", results[1].ID, results[1].ParentHits)
+		}
+	})
+
+	t.Run("Given Neighbors When searching Then the hit is joined with adjacent chunks", func(t *testing.T) {
+		// Given
+		engine := NewSearchEngineWithDeps(newDeps(chunkItemID("doc", 2)))
+
+		// When
+		results, err := engine.Search(ctx, SearchRequest{Query: "q", Neighbors: 1})
+
+		// Then
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		r := results[0]
+		if r.Content != "bbb\nccc\nddd" {
+			t.Errorf("content = %q, want the chunk and its neighbours", r.Content)
+		}
+		if len(r.Expanded) != 3 || r.Expanded[0] != chunkItemID("doc", 1) {
+			t.Errorf("expanded = %v, want chunks 1-3", r.Expanded)
+		}
+		if got := r.Citation.String(); got != "/repo/guide.md:2-4" {
+			t.Errorf("citation = %q, want /repo/guide.md:2-4", got)
+		}
+	})
+
+	t.Run("Given ExpandSection When searching Then the hit is joined with the rest of its section", func(t *testing.T) {
+		// Given
+		engine := NewSearchEngineWithDeps(newDeps(chunkItemID("doc", 1)))
+
+		// When
+		results, err := engine.Search(ctx, SearchRequest{Query: "q", ExpandSection: true})
+
+		// Then
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		r := results[0]
+		if r.Content != "aaa\nbbb\nccc" {
+			t.Errorf("content = %q, want the Setup section", r.Content)
+		}
+		if got := r.Citation.String(); got != "/repo/guide.md:1-3" {
+			t.Errorf("citation = %q, want /repo/guide.md:1-3", got)
+		}
+	})
+}
+
+func TestJoinChunks(t *testing.T) {
+	lines := func(content string, start, end int) Item {
+		return Item{Content: content, Metadata: map[string]any{"start_line": start, "end_line": end}}
+	}
+
+	t.Run("Given overlapping chunks When joining Then shared lines appear once", func(t *testing.T) {
+		content, start, end, complete := joinChunks([]Item{lines("a\nb\nc", 1, 3), lines("c\nd", 3, 4)})
+
+		if content != "a\nb\nc\nd" || start != 1 || end != 4 || !complete {
+			t.Errorf("joinChunks = %q %d-%d %v", content, start, end, complete)
+		}
+	})
+
+	t.Run("Given enriched chunks and a gap When joining Then context is dropped and the gap is blank", func(t *testing.T) {
+		content, _, _, complete := joinChunks([]Item{lines("In main.go:\na", 1, 1), lines("c", 3, 3)})
+
+		if content != "a\n\nc" || complete {
+			t.Errorf("joinChunks = %q (complete %v), want %q incomplete", content, complete, "a\n\nc")
+		}
+	})
+
+	t.Run("Given chunks without lines When joining Then they are joined in order", func(t *testing.T) {
+		content, start, _, _ := joinChunks([]Item{{Content: "page one"}, {Content: "page two"}})
+
+		if content != "page one\n\npage two" || start != 0 {
+			t.Errorf("joinChunks = %q from line %d", content, start)
+		}
+	})
+}
@@ -0,0 +1,434 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/anthropics/aef/codex/internal/walk"
+)
+
+// IndexStats summarises a directory indexing run
+type IndexStats struct {
+	FilesFound   int // files selected by the walk
+	FilesIndexed int
+	FilesSkipped int // unchanged since the last run
+	FilesIgnored int // binary, minified, generated, too large or unsupported
+	FilesFailed  int
+	FilesRemoved int // deleted or no longer selected; their chunks were removed
+	TotalChunks  int // chunks written in this run
+	StartTime    time.Time
+	EndTime      time.Time
+	Errors       []string
+	Results      []IndexResult
+
+	// Set by IndexGit: the commit indexed and the commit it was diffed
+	// against, "" when every file was indexed.
+	GitCommit string
+	GitBase   string
+}
+
+// IndexProgressCallback is called as each file finishes, in the order files
+// finish. found is the number of files the walk has selected so far; it
+// stops growing once the walk completes.
+type IndexProgressCallback func(done, found int, file string)
+
+// indexFile is a file moving through the directory pipeline.
+type indexFile struct {
+	path    string
+	key     string // trackKey of the file
+	source  *sourceFile
+	ignored string // walk.Skip* reason
+	err     error
+
+	embedErrs []error
+	pending   atomic.Int32 // chunks still being embedded
+}
+
+// embedJob is one batch of chunk texts, possibly from several files.
+type embedJob struct {
+	texts []string
+	refs  []chunkRef
+}
+
+// chunkRef locates a text of an embedJob in its file.
+type chunkRef struct {
+	file  *indexFile
+	chunk int
+}
+
+// IndexDirectory recursively indexes all files in a directory.
+// See IndexDirectoryWithProgress. Files that fail are logged and left out
+// of the results.
+func (idx *Indexer) IndexDirectory(ctx context.Context, dirPath string, scope string) ([]IndexResult, error) {
+	stats, err := idx.IndexDirectoryWithProgress(ctx, dirPath, scope, nil)
+	if stats == nil {
+		return nil, err
+	}
+	for _, msg := range stats.Errors {
+		log.Printf("Warning: failed to index %s\n", msg)
+	}
+	return stats.Results, err
+}
+
+// IndexDirectoryWithProgress recursively indexes all files in a directory
+// through a pipeline: the walk feeds workers that read and chunk files in
+// parallel, chunks from several files are embedded together in batches of
+// EmbedBatchSize with EmbedParallelism batches in flight, and a single
+// writer stores each file once all its chunks are embedded. Bounded
+// channels between the stages hold back the walk when embedding falls behind.
+//
+// Files are selected by the Indexer's walk options: ignore files, globs, the
+// size limit and the symlink policy. Hidden, binary, minified and generated
+// files are left out; those skipped for their content are reported as Ignored.
+// With a FileIndex configured, unchanged files are skipped and files that
+// disappeared from the directory since the last run have their chunks removed.
+//
+// A file that fails is counted and reported in Errors without stopping the
+// run. When ctx is cancelled the pipeline drains, files already written stay
+// indexed, nothing is pruned, and the partial stats are returned with ctx's error.
+func (idx *Indexer) IndexDirectoryWithProgress(ctx context.Context, dirPath string, scope string, progress IndexProgressCallback) (*IndexStats, error) {
+	filter, err := idx.newFilter(dirPath)
+	if err != nil {
+		return nil, err
+	}
+	return idx.indexTree(ctx, dirSource(filter, filter.Files), fileKey(filter.Root()), scope, progress)
+}
+
+// newFilter selects the indexable files under dirPath.
+func (idx *Indexer) newFilter(dirPath string) (*walk.Filter, error) {
+	opts := idx.walk
+	opts.Accept = func(path string) bool {
+		return isIndexable(path) || idx.loaders.Handles(path)
+	}
+	opts.CheckContent = idx.checkContent
+	return walk.NewFilter(dirPath, opts)
+}
+
+// checkContent returns why a file is not indexed for its content, or "".
+// Files a document loader takes are not checked, so PDFs are not ignored as
+// binary; other files with a loader's extension, such as YAML files that are
+// not OpenAPI specs, are ignored as unsupported.
+func (idx *Indexer) checkContent(path string, content []byte) string {
+	if idx.loaders.Lookup(path, content) != nil {
+		return ""
+	}
+	if !isIndexable(path) {
+		return walk.SkipUnsupported
+	}
+	return walk.ContentSkip(path, content)
+}
+
+// indexTree indexes every file of src and prunes the files tracked under
+// the root key that src no longer lists.
+func (idx *Indexer) indexTree(ctx context.Context, src pipelineSource, root string, scope string, progress IndexProgressCallback) (*IndexStats, error) {
+	stats, seen, err := idx.runPipeline(ctx, src, scope, progress)
+	if err != nil || idx.fileIndex == nil {
+		stats.EndTime = time.Now()
+		return stats, err
+	}
+
+	removed, err := idx.pruneDeletedFiles(ctx, root, seen)
+	if err != nil {
+		stats.EndTime = time.Now()
+		return stats, err
+	}
+	stats.FilesRemoved = len(removed)
+	stats.Results = append(stats.Results, removed...)
+	stats.EndTime = time.Now()
+	return stats, nil
+}
+
+// syncFiles indexes the files of src, such as the changes reported by a
+// watcher, and removes the tracked files at removed paths and those src
+// now skips for their content.
+func (idx *Indexer) syncFiles(ctx context.Context, src pipelineSource, removed []string, scope string, progress IndexProgressCallback) (*IndexStats, error) {
+	stats, _, err := idx.runPipeline(ctx, src, scope, progress)
+	if err != nil || idx.fileIndex == nil {
+		stats.EndTime = time.Now()
+		return stats, err
+	}
+
+	gone := removed
+	for _, r := range stats.Results {
+		if r.Ignored != "" {
+			gone = append(gone, r.FilePath)
+		}
+	}
+	for _, path := range gone {
+		results, err := idx.removeTracked(ctx, trackKey(path, src.revision))
+		if err != nil {
+			stats.Errors = append(stats.Errors, path+": "+err.Error())
+			continue
+		}
+		stats.FilesRemoved += len(results)
+		stats.Results = append(stats.Results, results...)
+	}
+
+	stats.EndTime = time.Now()
+	return stats, nil
+}
+
+// fileSource lists the files for the pipeline, like walk.Filter.Files.
+type fileSource func(fn func(path string) error, skipped func(walk.Skipped)) error
+
+// pipelineSource is what the pipeline indexes: the files to list, how to
+// read them and, optionally, how to complete their requests.
+type pipelineSource struct {
+	files    fileSource
+	read     func(path string) (content []byte, reason string, err error)
+	revision string // see IndexRequest.Revision
+
+	// request, when set, completes the IndexRequest of each file, e.g.
+	// with its revision and provenance.
+	request func(req *IndexRequest)
+}
+
+// dirSource indexes files from disk, read and checked by filter.
+func dirSource(filter *walk.Filter, files fileSource) pipelineSource {
+	return pipelineSource{files: files, read: filter.ReadFile}
+}
+
+// runPipeline indexes the files of src and returns the stats and the
+// FileIndex keys of the files it saw. Results are sorted by path.
+func (idx *Indexer) runPipeline(ctx context.Context, src pipelineSource, scope string, progress IndexProgressCallback) (*IndexStats, map[string]bool, error) {
+	stats := &IndexStats{StartTime: time.Now()}
+	workers := max(runtime.GOMAXPROCS(0), 2)
+
+	paths := make(chan string, workers)
+	prepared := make(chan *indexFile, workers)
+	batches := make(chan embedJob, idx.embedParallelism)
+	done := make(chan *indexFile, workers)
+
+	var found atomic.Int32
+	var walkErr error
+	go func() {
+		defer close(paths)
+		walkErr = src.files(func(path string) error {
+			found.Add(1)
+			select {
+			case paths <- path:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}, func(s walk.Skipped) {
+			found.Add(1)
+			select {
+			case done <- &indexFile{path: s.Path, ignored: s.Reason}:
+			case <-ctx.Done():
+			}
+		})
+	}()
+
+	// Read and chunk files in parallel
+	var prepWG sync.WaitGroup
+	for range workers {
+		prepWG.Add(1)
+		go func() {
+			defer prepWG.Done()
+			for path := range paths {
+				if ctx.Err() != nil {
+					continue
+				}
+				f := idx.prepareDirFile(ctx, src, path, scope)
+				next := done
+				if f.source != nil && len(f.source.texts) > 0 {
+					next = prepared
+				}
+				select {
+				case next <- f:
+				case <-ctx.Done():
+				}
+			}
+		}()
+	}
+	go func() {
+		prepWG.Wait()
+		close(prepared)
+	}()
+
+	// Batch chunks across files
+	go func() {
+		defer close(batches)
+		var job embedJob
+		flush := func() {
+			if len(job.texts) == 0 {
+				return
+			}
+			select {
+			case batches <- job:
+			case <-ctx.Done():
+			}
+			job = embedJob{}
+		}
+		for f := range prepared {
+			if ctx.Err() != nil {
+				continue
+			}
+			for i, text := range f.source.texts {
+				job.texts = append(job.texts, text)
+				job.refs = append(job.refs, chunkRef{file: f, chunk: i})
+				if len(job.texts) == idx.embedBatchSize {
+					flush()
+				}
+			}
+		}
+		flush()
+	}()
+
+	// Embed batches; a file moves on once its last chunk is embedded
+	var embedWG sync.WaitGroup
+	for range idx.embedParallelism {
+		embedWG.Add(1)
+		go func() {
+			defer embedWG.Done()
+			for job := range batches {
+				if ctx.Err() != nil {
+					continue
+				}
+				vecs := make([][]float32, len(job.texts))
+				errs := make([]error, len(job.texts))
+				idx.embedBatch(ctx, job.texts, vecs, errs)
+				for i, ref := range job.refs {
+					ref.file.source.vecs[ref.chunk] = vecs[i]
+					ref.file.embedErrs[ref.chunk] = errs[i]
+					if ref.file.pending.Add(-1) == 0 {
+						done <- ref.file
+					}
+				}
+			}
+		}()
+	}
+	go func() {
+		embedWG.Wait()
+		close(done)
+	}()
+
+	// Single writer: stores files, keeps the stats and reports progress
+	seen := make(map[string]bool)
+	finished := 0
+	for f := range done {
+		if ctx.Err() != nil {
+			continue
+		}
+		idx.writeDirFile(ctx, f, stats, seen)
+		finished++
+		if progress != nil {
+			progress(finished, int(found.Load()), f.path)
+		}
+	}
+
+	stats.FilesFound = int(found.Load())
+	sort.Slice(stats.Results, func(i, j int) bool {
+		return stats.Results[i].FilePath < stats.Results[j].FilePath
+	})
+	if err := ctx.Err(); err != nil {
+		return stats, seen, err
+	}
+	return stats, seen, walkErr
+}
+
+// prepareDirFile reads and chunks one file of a directory. The returned
+// file is ignored, failed, unchanged or ready for embedding.
+func (idx *Indexer) prepareDirFile(ctx context.Context, src pipelineSource, path, scope string) *indexFile {
+	req := IndexRequest{
+		FilePath: path,
+		Scope:    scope,
+		Project:  idx.project,
+		Revision: src.revision,
+	}
+	if src.request != nil {
+		src.request(&req)
+	}
+	f := &indexFile{path: path, key: trackKey(path, req.Revision)}
+
+	content, reason, err := src.read(path)
+	if err != nil {
+		f.err = fmt.Errorf("failed to read: %w", err)
+		return f
+	}
+	if reason != "" {
+		f.ignored = reason
+		return f
+	}
+
+	req.Content = string(content)
+	req.Type = idx.contentType(path, req.Content)
+	f.source, f.err = idx.prepareSourceFile(ctx, req)
+	if f.err != nil || f.source.skipped != nil {
+		return f
+	}
+
+	n := len(f.source.texts)
+	f.source.vecs = make([][]float32, n)
+	f.embedErrs = make([]error, n)
+	f.pending.Store(int32(n))
+	return f
+}
+
+// writeDirFile stores a finished file and accounts for it in stats.
+func (idx *Indexer) writeDirFile(ctx context.Context, f *indexFile, stats *IndexStats, seen map[string]bool) {
+	if f.ignored != "" {
+		stats.FilesIgnored++
+		stats.Results = append(stats.Results, IndexResult{FilePath: f.path, Ignored: f.ignored})
+		return
+	}
+
+	// Files that fail keep their previous chunks rather than being pruned
+	seen[f.key] = true
+
+	if f.err == nil && f.source.skipped != nil {
+		stats.FilesSkipped++
+		stats.Results = append(stats.Results, *f.source.skipped)
+		return
+	}
+
+	var result *IndexResult
+	err := f.err
+	if err == nil {
+		for i, embedErr := range f.embedErrs {
+			if embedErr != nil {
+				err = fmt.Errorf("failed to embed %s %d: %w", f.source.kind, i, embedErr)
+				break
+			}
+		}
+	}
+	if err == nil {
+		result, err = idx.writeSourceFile(ctx, f.source)
+	}
+	if err != nil {
+		stats.FilesFailed++
+		stats.Errors = append(stats.Errors, fmt.Sprintf("%s: %v", f.path, err))
+		return
+	}
+
+	stats.FilesIndexed++
+	stats.TotalChunks += result.ChunksCount
+	stats.Results = append(stats.Results, *result)
+}
+
+// PrintIndexStats prints a directory indexing summary and its errors
+func PrintIndexStats(stats *IndexStats) {
+	duration := stats.EndTime.Sub(stats.StartTime)
+
+	fmt.Printf("Indexed %d files, %d total chunks in %v (%d unchanged, %d ignored, %d removed, %d failed)\n",
+		stats.FilesIndexed, stats.TotalChunks, duration.Round(time.Millisecond),
+		stats.FilesSkipped, stats.FilesIgnored, stats.FilesRemoved, stats.FilesFailed)
+
+	if len(stats.Errors) > 0 {
+		fmt.Printf("\nErrors (%d):\n", len(stats.Errors))
+		for i, err := range stats.Errors {
+			if i >= 10 {
+				fmt.Printf("  ... and %d more errors\n", len(stats.Errors)-10)
+				break
+			}
+			fmt.Printf("  - %s\n", err)
+		}
+	}
+}
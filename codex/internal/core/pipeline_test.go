@@ -0,0 +1,185 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/anthropics/aef/codex/internal/walk"
+)
+
+// writeFiles writes n Go files of three one-line chunks each (with the
+// incremental indexer's line chunker) and returns the directory.
+func writeFiles(t *testing.T, n int, version string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for i := range n {
+		content := fmt.Sprintf("f%d a %s\nf%d b\nf%d c", i, version, i, i)
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("f%02d.go", i)), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestIndexer_IndexDirectoryWithProgress(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Given many small files, When indexing, Then chunks from several files share embedding batches", func(t *testing.T) {
+		dir := writeFiles(t, 10, "v1")
+		idx, embed, vectors, _, _ := newIncrementalIndexer()
+		idx.embedBatchSize = 8
+
+		stats, err := idx.IndexDirectoryWithProgress(ctx, dir, "project", nil)
+		if err != nil {
+			t.Fatalf("IndexDirectoryWithProgress: %v", err)
+		}
+		if embed.BatchCalls != 4 {
+			t.Errorf("batch calls = %d, want 4 for 30 chunks in batches of 8", embed.BatchCalls)
+		}
+		if len(vectors.Vectors) != 30 || stats.TotalChunks != 30 {
+			t.Errorf("vectors = %d, chunks = %d, want 30", len(vectors.Vectors), stats.TotalChunks)
+		}
+	})
+
+	t.Run("Given a directory, When indexing twice, Then progress and stats count every file", func(t *testing.T) {
+		dir := writeFiles(t, 10, "v1")
+		idx, _, _, _, _ := newIncrementalIndexer()
+
+		var calls, lastDone, lastFound int
+		stats, err := idx.IndexDirectoryWithProgress(ctx, dir, "project", func(done, found int, file string) {
+			calls++
+			if done != calls || !strings.HasPrefix(file, dir) {
+				t.Errorf("progress(%d, %d, %s) on call %d", done, found, file, calls)
+			}
+			lastDone, lastFound = done, found
+		})
+		if err != nil {
+			t.Fatalf("IndexDirectoryWithProgress: %v", err)
+		}
+		if calls != 10 || lastDone != 10 || lastFound != 10 {
+			t.Errorf("progress calls = %d, last = %d/%d, want 10/10", calls, lastDone, lastFound)
+		}
+		if stats.FilesFound != 10 || stats.FilesIndexed != 10 || len(stats.Results) != 10 {
+			t.Errorf("stats = %+v", stats)
+		}
+		if stats.Results[0].FilePath != filepath.Join(dir, "f00.go") {
+			t.Errorf("results not sorted by path: first is %s", stats.Results[0].FilePath)
+		}
+
+		stats, _ = idx.IndexDirectoryWithProgress(ctx, dir, "project", nil)
+		if stats.FilesSkipped != 10 || stats.FilesIndexed != 0 || stats.TotalChunks != 0 {
+			t.Errorf("second run stats = %+v, want all unchanged", stats)
+		}
+	})
+
+	t.Run("Given a chunk that cannot be embedded, When indexing, Then only its file fails and is reported", func(t *testing.T) {
+		dir := writeFiles(t, 10, "v1")
+		idx, embed, _, metaStore, files := newIncrementalIndexer()
+		embed.EmbedFunc = func(ctx context.Context, text string) ([]float32, error) {
+			if text == "f3 b" {
+				return nil, errors.New("input rejected")
+			}
+			return make([]float32, 768), nil
+		}
+
+		stats, err := idx.IndexDirectoryWithProgress(ctx, dir, "project", nil)
+		if err != nil {
+			t.Fatalf("IndexDirectoryWithProgress: %v", err)
+		}
+		if stats.FilesFailed != 1 || stats.FilesIndexed != 9 || len(stats.Errors) != 1 {
+			t.Fatalf("stats = %+v", stats)
+		}
+		if want := filepath.Join(dir, "f03.go") + ": failed to embed chunk 1: input rejected"; stats.Errors[0] != want {
+			t.Errorf("error = %q, want %q", stats.Errors[0], want)
+		}
+		if len(metaStore.Items) != 27 || len(files.Files) != 9 {
+			t.Errorf("items = %d, tracked files = %d, want 27 and 9", len(metaStore.Items), len(files.Files))
+		}
+	})
+
+	t.Run("Given a cancelled run, When indexing, Then it stops early and prunes nothing", func(t *testing.T) {
+		dir := writeFiles(t, 10, "v1")
+		idx, _, _, _, files := newIncrementalIndexer()
+		if _, err := idx.IndexDirectoryWithProgress(ctx, dir, "project", nil); err != nil {
+			t.Fatalf("first run: %v", err)
+		}
+
+		// Change every file and delete one, then cancel after the first write
+		newDir := writeFiles(t, 10, "v2")
+		for i := range 10 {
+			content, _ := os.ReadFile(filepath.Join(newDir, fmt.Sprintf("f%02d.go", i)))
+			os.WriteFile(filepath.Join(dir, fmt.Sprintf("f%02d.go", i)), content, 0644)
+		}
+		os.Remove(filepath.Join(dir, "f09.go"))
+
+		cctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		stats, err := idx.IndexDirectoryWithProgress(cctx, dir, "project", func(done, found int, file string) {
+			cancel()
+		})
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("err = %v, want context.Canceled", err)
+		}
+		if stats == nil || stats.FilesIndexed != 1 || stats.FilesRemoved != 0 {
+			t.Errorf("stats = %+v, want one file indexed and none removed", stats)
+		}
+		if _, ok := files.Files[fileKey(filepath.Join(dir, "f09.go"))]; !ok {
+			t.Error("deleted file was pruned by a cancelled run")
+		}
+	})
+
+	t.Run("Given documents a loader takes, When indexing, Then they are loaded and other files with their extensions are ignored", func(t *testing.T) {
+		dir := t.TempDir()
+		pdf, err := os.ReadFile("../loader/testdata/handbook.pdf")
+		if err != nil {
+			t.Fatal(err)
+		}
+		os.WriteFile(filepath.Join(dir, "handbook.pdf"), pdf, 0644)
+		os.WriteFile(filepath.Join(dir, "api.yaml"), []byte("openapi: 3.0.0\ninfo:\n  title: Pets\npaths:\n  /pets:\n    get:\n      summary: List pets\n"), 0644)
+		os.WriteFile(filepath.Join(dir, "values.yaml"), []byte("replicas: 3\n"), 0644)
+		idx, _, _, metaStore, _ := newIncrementalIndexer()
+
+		stats, err := idx.IndexDirectoryWithProgress(ctx, dir, "project", nil)
+		if err != nil {
+			t.Fatalf("IndexDirectoryWithProgress: %v", err)
+		}
+		if stats.FilesIndexed != 2 || stats.FilesIgnored != 1 || stats.TotalChunks != 4 {
+			t.Fatalf("stats = %+v, want the PDF and the spec indexed", stats)
+		}
+		if r := stats.Results[2]; r.FilePath != filepath.Join(dir, "values.yaml") || r.Ignored != walk.SkipUnsupported {
+			t.Errorf("result = %+v, want values.yaml unsupported", r)
+		}
+
+		pages := map[any]string{}
+		for _, item := range metaStore.Items {
+			switch item.Metadata["loader"] {
+			case "pdf":
+				pages[item.Metadata["page"]] = item.Title
+				if item.Type != TypeDoc || item.Metadata["document_title"] != "Service handbook" {
+					t.Errorf("PDF chunk = %q %v", item.Type, item.Metadata)
+				}
+			case "openapi":
+				if item.Title == "Pets > GET /pets" && item.Metadata["http_method"] != "GET" {
+					t.Errorf("endpoint metadata = %v", item.Metadata)
+				}
+			default:
+				t.Errorf("chunk %q has loader %v", item.Title, item.Metadata["loader"])
+			}
+		}
+		if pages[1] != "Service handbook > Page 1" || pages[2] != "Service handbook > Page 2" {
+			t.Errorf("PDF pages = %v", pages)
+		}
+	})
+
+	t.Run("Given a missing directory, When indexing, Then it fails", func(t *testing.T) {
+		idx, _, _, _, _ := newIncrementalIndexer()
+		if _, err := idx.IndexDirectoryWithProgress(ctx, filepath.Join(t.TempDir(), "missing"), "project", nil); err == nil {
+			t.Error("want error")
+		}
+	})
+}
@@ -0,0 +1,163 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/anthropics/aef/codex/internal/chunking"
+)
+
+// Embedding model check modes for Config.EmbeddingModelCheck.
+const (
+	ModelCheckStrict = "strict"
+	ModelCheckWarn   = "warn"
+)
+
+// ReembedStats summarizes a re-embedding run.
+type ReembedStats struct {
+	Model    string // the model items were re-embedded with
+	Total    int    // items in the knowledge base
+	Resumed  int    // items staged by an earlier, interrupted run
+	Embedded int    // items embedded by this run
+	Failed   int
+	Swapped  int // vectors swapped in; 0 if the swap did not happen
+}
+
+// ReembedProgressCallback is called after each batch with the number of
+// items staged so far and the total.
+type ReembedProgressCallback func(done, total int)
+
+// checkEmbeddingModel compares the configured embedding model with the
+// one recorded for the stored vectors. A store with no recorded model
+// adopts the configured one. On a mismatch it returns an error in strict
+// mode and logs a warning in warn mode.
+func checkEmbeddingModel(models VectorModels, model, mode string) error {
+	switch mode {
+	case "", ModelCheckStrict, ModelCheckWarn:
+	default:
+		return fmt.Errorf("unknown embedding model check %q (want %s or %s)", mode, ModelCheckStrict, ModelCheckWarn)
+	}
+
+	active, err := models.ActiveModel()
+	if err != nil {
+		return fmt.Errorf("read active embedding model: %w", err)
+	}
+	if active == "" {
+		return models.SetActiveModel(model)
+	}
+	if active == model {
+		return nil
+	}
+
+	mismatch := fmt.Errorf("embedding model %q does not match %q, which embedded the stored vectors; run `codex-cli reembed` to switch models", model, active)
+	if mode == ModelCheckWarn {
+		log.Printf("Warning: %v", mismatch)
+		return nil
+	}
+	return mismatch
+}
+
+// EmbeddingModelStatus returns the configured embedding model, the model
+// recorded for the stored vectors, and the number of vectors per model.
+func (e *SearchEngine) EmbeddingModelStatus() (configured, active string, counts map[string]int, err error) {
+	if e.models == nil {
+		return e.embeddingModel, "", nil, nil
+	}
+	active, err = e.models.ActiveModel()
+	if err != nil {
+		return e.embeddingModel, "", nil, err
+	}
+	counts, err = e.models.ModelCounts()
+	return e.embeddingModel, active, counts, err
+}
+
+// Reembed re-embeds every item with the configured embedding model and
+// then switches the index to it in one transaction. New vectors are staged
+// alongside the live ones, so search keeps working meanwhile, and a run
+// that is interrupted or has failures resumes where it left off. The swap
+// happens only once every item is staged.
+func (e *SearchEngine) Reembed(ctx context.Context, progress ReembedProgressCallback) (*ReembedStats, error) {
+	if e.models == nil {
+		return nil, fmt.Errorf("vector store does not support re-embedding")
+	}
+	stats := &ReembedStats{Model: e.embeddingModel}
+
+	staged, err := e.models.StagedIDs(ctx, e.embeddingModel)
+	if err != nil {
+		return nil, fmt.Errorf("read staged vectors: %w", err)
+	}
+
+	// Only the indexer's batched embedding is used
+	indexer, err := NewIndexerWithConfig(IndexerConfig{
+		Embedder:         e.embedder,
+		VectorStore:      e.vecStore,
+		MetaStore:        e.metadata,
+		CodeChunker:      chunking.NewASTChunker(),
+		EmbedBatchSize:   e.config.EmbedBatchSize,
+		EmbedParallelism: e.config.EmbedParallelism,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create indexer: %w", err)
+	}
+	pageSize := indexer.embedBatchSize * indexer.embedParallelism
+
+	// Page by ID so items saved during the run do not shift later pages
+	for after := ""; ; {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		records, err := e.metadata.ListItemsAfter(after, pageSize)
+		if err != nil {
+			return stats, fmt.Errorf("list items: %w", err)
+		}
+		if len(records) == 0 {
+			break
+		}
+		after = records[len(records)-1].ID
+		stats.Total += len(records)
+
+		var ids, texts []string
+		for _, r := range records {
+			if staged[r.ID] {
+				stats.Resumed++
+				continue
+			}
+			ids = append(ids, r.ID)
+			texts = append(texts, embeddingText(itemFromRecord(r)))
+		}
+
+		vecs, errs := indexer.embedAll(ctx, texts)
+		batch := make(map[string][]float32, len(ids))
+		for i, id := range ids {
+			if errs[i] != nil {
+				log.Printf("Warning: failed to re-embed %s: %v", id, errs[i])
+				stats.Failed++
+				continue
+			}
+			batch[id] = vecs[i]
+		}
+		if err := e.models.StageVectors(ctx, e.embeddingModel, batch); err != nil {
+			return stats, fmt.Errorf("stage vectors: %w", err)
+		}
+		stats.Embedded += len(batch)
+
+		if progress != nil {
+			progress(stats.Resumed+stats.Embedded, stats.Total)
+		}
+		if len(records) < pageSize {
+			break
+		}
+	}
+
+	if stats.Failed > 0 {
+		return stats, fmt.Errorf("%d items failed to re-embed; run reembed again to retry them before switching models", stats.Failed)
+	}
+
+	swapped, err := e.models.SwapStaged(ctx, e.embeddingModel)
+	if err != nil {
+		return stats, fmt.Errorf("swap re-embedded vectors: %w", err)
+	}
+	stats.Swapped = swapped
+	return stats, nil
+}
@@ -0,0 +1,190 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/anthropics/aef/codex/internal/storage"
+)
+
+func TestCheckEmbeddingModel(t *testing.T) {
+	tests := []struct {
+		name       string
+		active     string
+		mode       string
+		wantErr    bool
+		wantActive string
+	}{
+		{name: "Given no recorded model Then adopts the configured one", active: "", wantActive: "new-model"},
+		{name: "Given the same model Then passes", active: "new-model", wantActive: "new-model"},
+		{name: "Given another model in strict mode Then refuses", active: "old-model", mode: ModelCheckStrict, wantErr: true, wantActive: "old-model"},
+		{name: "Given another model by default Then refuses", active: "old-model", wantErr: true, wantActive: "old-model"},
+		{name: "Given another model in warn mode Then continues", active: "old-model", mode: ModelCheckWarn, wantActive: "old-model"},
+		{name: "Given an unknown mode Then errors", active: "new-model", mode: "lenient", wantErr: true, wantActive: "new-model"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			models := NewMockVectorModels(NewMockVectorStorage())
+			models.Active = tt.active
+
+			err := checkEmbeddingModel(models, "new-model", tt.mode)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkEmbeddingModel() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if models.Active != tt.wantActive {
+				t.Errorf("active model = %q, want %q", models.Active, tt.wantActive)
+			}
+		})
+	}
+}
+
+func newReembedEngine(n int) (*SearchEngine, *MockEmbedder, *MockVectorModels) {
+	metaStore := NewMockMetadataStorage()
+	vecStore := NewMockVectorStorage()
+	for i := 0; i < n; i++ {
+		id := fmt.Sprintf("item-%02d", i)
+		metaStore.Items[id] = &storage.ItemRecord{ID: id, Type: "pattern", Content: "content " + id}
+		vecStore.Vectors[id] = []float32{1}
+	}
+	models := NewMockVectorModels(vecStore)
+	models.Active = "old-model"
+
+	embedder := NewMockEmbedder()
+	embedder.FixedVector = []float32{0.5, 0.5}
+	engine := NewSearchEngineWithDeps(SearchEngineDeps{
+		Config:         Config{EmbedBatchSize: 2, EmbedParallelism: 1},
+		VecStore:       vecStore,
+		Metadata:       metaStore,
+		Embedder:       embedder,
+		Models:         models,
+		EmbeddingModel: "new-model",
+	})
+	return engine, embedder, models
+}
+
+func TestSearchEngine_Reembed(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Given items from an old model When re-embedding Then stages all and swaps", func(t *testing.T) {
+		// Given
+		engine, _, models := newReembedEngine(5)
+		var progress []int
+
+		// When
+		stats, err := engine.Reembed(ctx, func(done, total int) { progress = append(progress, done) })
+
+		// Then
+		if err != nil {
+			t.Fatalf("Reembed: %v", err)
+		}
+		if stats.Total != 5 || stats.Embedded != 5 || stats.Swapped != 5 || stats.Resumed != 0 {
+			t.Errorf("unexpected stats %+v", stats)
+		}
+		if models.Active != "new-model" || models.SwapCount != 1 {
+			t.Errorf("expected one swap to new-model, got %q after %d swaps", models.Active, models.SwapCount)
+		}
+		if len(models.Live.Vectors["item-00"]) != 2 {
+			t.Errorf("expected re-embedded vector, got %v", models.Live.Vectors["item-00"])
+		}
+		if fmt.Sprint(progress) != "[2 4 5]" {
+			t.Errorf("progress = %v, want [2 4 5]", progress)
+		}
+	})
+
+	t.Run("Given embedding failures When re-embedding Then does not swap", func(t *testing.T) {
+		// Given
+		engine, embedder, models := newReembedEngine(4)
+		embedder.FailBatch = true
+		embedder.EmbedFunc = func(ctx context.Context, text string) ([]float32, error) {
+			if strings.HasSuffix(text, "item-02") {
+				return nil, ErrMockEmbedding
+			}
+			return []float32{0.5, 0.5}, nil
+		}
+
+		// When
+		stats, err := engine.Reembed(ctx, nil)
+
+		// Then
+		if err == nil {
+			t.Fatal("expected error for failed items")
+		}
+		if stats.Failed != 1 || stats.Embedded != 3 || stats.Swapped != 0 {
+			t.Errorf("unexpected stats %+v", stats)
+		}
+		if models.SwapCount != 0 || models.Active != "old-model" {
+			t.Errorf("expected no swap, got %d swaps and active %q", models.SwapCount, models.Active)
+		}
+		if len(models.Live.Vectors["item-00"]) != 1 {
+			t.Error("live vectors should be untouched")
+		}
+	})
+
+	t.Run("Given an interrupted run When re-embedding again Then resumes and swaps", func(t *testing.T) {
+		// Given
+		engine, embedder, models := newReembedEngine(4)
+		models.Staged["new-model"] = map[string][]float32{
+			"item-00": {0.5, 0.5},
+			"item-01": {0.5, 0.5},
+		}
+
+		// When
+		stats, err := engine.Reembed(ctx, nil)
+
+		// Then
+		if err != nil {
+			t.Fatalf("Reembed: %v", err)
+		}
+		if stats.Resumed != 2 || stats.Embedded != 2 || stats.Swapped != 4 {
+			t.Errorf("unexpected stats %+v", stats)
+		}
+		if embedder.CallCount != 2 {
+			t.Errorf("expected 2 embeddings, got %d", embedder.CallCount)
+		}
+	})
+
+	t.Run("Given document chunks When re-embedding Then embeds the texts indexing embedded", func(t *testing.T) {
+		// Given
+		idx, embedder, vecStore, metaStore, _ := newIncrementalIndexer()
+		var texts []string
+		embedder.EmbedFunc = func(ctx context.Context, text string) ([]float32, error) {
+			texts = append(texts, text)
+			return []float32{0.5, 0.5}, nil
+		}
+		doc := "---\ntitle: Handbook\n---\nIntro text.\n\n# Deploys\n\nShip on Tuesdays."
+		if _, err := idx.IndexFile(ctx, IndexRequest{Content: doc, Type: "doc", FilePath: "/repo/handbook.md", Scope: "project"}); err != nil {
+			t.Fatalf("IndexFile failed: %v", err)
+		}
+		indexed := texts
+		texts = nil
+
+		models := NewMockVectorModels(vecStore)
+		engine := NewSearchEngineWithDeps(SearchEngineDeps{
+			VecStore: vecStore, Metadata: metaStore, Embedder: embedder,
+			Models: models, EmbeddingModel: "new-model",
+		})
+
+		// When
+		if _, err := engine.Reembed(ctx, nil); err != nil {
+			t.Fatalf("Reembed: %v", err)
+		}
+
+		// Then
+		if len(indexed) != 2 || !strings.HasPrefix(indexed[0], "Handbook\n\n") || !strings.HasPrefix(indexed[1], "Deploys\n\n") {
+			t.Fatalf("indexing embedded %q, want the title and section breadcrumbs", indexed)
+		}
+		if fmt.Sprint(texts) != fmt.Sprint(indexed) {
+			t.Errorf("re-embedded %q, want %q", texts, indexed)
+		}
+	})
+
+	t.Run("Given no model store When re-embedding Then errors", func(t *testing.T) {
+		engine := NewSearchEngineWithDeps(SearchEngineDeps{Embedder: NewMockEmbedder()})
+		if _, err := engine.Reembed(ctx, nil); err == nil {
+			t.Error("expected error without a model store")
+		}
+	})
+}
@@ -1,7 +1,10 @@
 package core
 
 import (
+	"fmt"
 	"time"
+
+	"github.com/anthropics/aef/codex/internal/walk"
 )
 
 // Item type constants
@@ -16,6 +19,15 @@ const (
 	TypeManual   = "manual"
 )
 
+// Scope constants. Items are stored as global or project; searches may also
+// use both (global plus the current project) or all (no scope restriction).
+const (
+	ScopeGlobal  = "global"
+	ScopeProject = "project"
+	ScopeBoth    = "both"
+	ScopeAll     = "all"
+)
+
 // Flight recorder entry type constants
 const (
 	FlightTypeDecision          = "decision"
@@ -32,14 +44,114 @@ type Config struct {
 	ModelsPath      string
 	MetadataDBPath  string
 
-	// Embedder (nomic-embed-text via Ollama)
-	LocalEmbeddingURL   string // e.g. "http://localhost:11434/api/embed"
+	// Embedder. EmbeddingBackend is "ollama" (default), "openai-compatible"
+	// or "llama.cpp"; the URL and model apply to whichever is selected.
+	EmbeddingBackend    string
+	LocalEmbeddingURL   string // e.g. "http://localhost:11434/api/embed", "http://gpu:8000/v1/embeddings"
 	LocalEmbeddingModel string // e.g. "nomic-embed-text"
+	EmbeddingAPIKey     string // bearer token for OpenAI-compatible servers (optional)
+
+	// Overrides for the model profile's query/document prefixes, vector
+	// dimensions and input token limit, for models the profile registry
+	// does not know. Empty prefixes and zero numbers keep the profile's values.
+	EmbeddingQueryPrefix    string
+	EmbeddingDocumentPrefix string
+	EmbeddingDimensions     int
+	EmbeddingMaxTokens      int
+
+	// EmbeddingModelCheck sets what happens when the configured model
+	// differs from the one that embedded the stored vectors: "strict"
+	// (default) refuses to open the engine, "warn" logs and continues.
+	// Either way, `codex-cli reembed` switches the index to the new model.
+	EmbeddingModelCheck string
+
+	// Contextual enrichment of document chunks. Provider is "anthropic"
+	// (requires AnthropicAPIKey) or "ollama"; empty selects anthropic when
+	// AnthropicAPIKey is set and disables enrichment otherwise.
+	EnrichmentProvider string
+	EnrichmentModel    string // e.g. "claude-haiku-4-5", "llama3.2"
+	EnrichmentURL      string // e.g. "http://localhost:11434/api/generate"
 
 	// ScoreThreshold sets the minimum score as a ratio of the top result's score.
 	// Results scoring below topScore * ScoreThreshold are dropped.
 	// 0 disables thresholding. Typical value: 0.5
 	ScoreThreshold float64
+
+	// Project identifies the current project (typically the repository root
+	// path). Scope "project" searches only this project's items, and new
+	// items without a project are attributed to it.
+	Project string
+
+	// EmbedBatchSize is the number of chunks embedded per request during
+	// indexing and migration. 0 uses DefaultEmbedBatchSize.
+	EmbedBatchSize int
+
+	// EmbedParallelism is the number of embedding requests in flight at
+	// once. 0 uses DefaultEmbedParallelism.
+	EmbedParallelism int
+
+	// ChunkMaxTokens caps code chunks, in estimated tokens: larger
+	// declarations are split and tiny adjacent siblings merged up to it.
+	// The embedder's input limit lowers it further. 0 uses
+	// DefaultChunkMaxTokens; negative keeps whole declarations.
+	ChunkMaxTokens int
+
+	// Walk selects the files IndexDirectory indexes: include and exclude
+	// globs, a size limit and the symlink policy. .gitignore,
+	// .git/info/exclude and .codexignore files apply unless disabled.
+	Walk walk.Options
+
+	// VectorIndex selects vector search: "hnsw" (default) maintains an
+	// approximate nearest neighbour index, used once the store holds 10K
+	// or more vectors; "exact" always scans every vector.
+	VectorIndex string
+
+	// ProjectBoost multiplies the scores of current-project results in
+	// scope "both" searches. Values <= 1 disable boosting. Typical value: 1.2
+	ProjectBoost float64
+
+	// FeedbackRanking feeds recorded feedback into ranking: "" or "off"
+	// (default) ignores it, "rrf" adds the candidates ranked by feedback
+	// score as an extra fusion signal, and "prior" multiplies final scores
+	// by 1 + FeedbackWeight*score. Results report feedback stats regardless.
+	FeedbackRanking string
+
+	// FeedbackWeight scales the "prior" adjustment. 0 uses DefaultFeedbackWeight.
+	FeedbackWeight float64
+
+	// FeedbackHalfLife is the age at which feedback counts half as much.
+	// 0 uses DefaultFeedbackHalfLife.
+	FeedbackHalfLife time.Duration
+
+	// FeedbackProjectWeight multiplies the weight of feedback given in the
+	// current project relative to other projects'. 0 uses
+	// DefaultFeedbackProjectWeight; 1 weighs all projects equally.
+	FeedbackProjectWeight float64
+
+	// Fusion merges the vector, keyword and feedback rankings: "rrf"
+	// (default), "combsum", "combmnz" or "convex". See fusion.go.
+	Fusion string
+
+	// FusionK is the RRF rank constant. 0 uses DefaultFusionK.
+	FusionK float64
+
+	// FusionWeights weights each ranking in fusion; unnamed lists weigh 1.
+	FusionWeights FusionWeights
+
+	// CodeFusionWeights, when set, replaces FusionWeights for queries that
+	// look like code. Typical value: vector:1,keyword:2
+	CodeFusionWeights FusionWeights
+
+	// Query expansion LLM for SearchRequest.Expand. An empty provider
+	// reuses the enrichment settings; without either, expansion only adds
+	// the identifiers found in the query.
+	ExpansionProvider string
+	ExpansionModel    string
+	ExpansionURL      string
+
+	// ExpansionParaphrases is the number of paraphrases to search with.
+	// 0 uses DefaultExpansionParaphrases.
+	ExpansionParaphrases int
 }
 
 // Item represents a knowledge item in Codex
@@ -50,6 +162,7 @@ type Item struct {
 	Content   string            `json:"content"`
 	Tags      []string          `json:"tags,omitempty"`
 	Scope     string            `json:"scope"`     // global, project
+	Project   string            `json:"project,omitempty"` // repository path the item belongs to
 	Source    string            `json:"source,omitempty"` // file path or manual
 	Metadata  map[string]any    `json:"metadata,omitempty"`
 	CreatedAt time.Time         `json:"created_at"`
@@ -60,16 +173,86 @@ type Item struct {
 type SearchRequest struct {
 	Query     string   `json:"query"`
 	Types     []string `json:"types,omitempty"`
-	Scope     string   `json:"scope,omitempty"`
+	Scope     string   `json:"scope,omitempty"` // global, project, both, all
 	Limit     int      `json:"limit,omitempty"`
 	UseHybrid bool     `json:"use_hybrid,omitempty"`
+
+	// Project overrides Config.Project for scopes "project" and "both".
+	Project string `json:"project,omitempty"`
+
+	// Exact forces brute-force vector search when an ANN index is enabled.
+	Exact bool `json:"exact,omitempty"`
+
+	// Fusion and FusionWeights override Config.Fusion and the configured
+	// weights for this search. Lists missing from FusionWeights weigh 1.
+	Fusion        string        `json:"fusion,omitempty"`
+	FusionWeights FusionWeights `json:"fusion_weights,omitempty"`
+
+	// Expand adds a vector search per query variant: LLM paraphrases and
+	// the identifiers the query mentions. HyDE also searches with a
+	// hypothetical answer passage, and implies Expand.
+	Expand bool `json:"expand,omitempty"`
+	HyDE   bool `json:"hyde,omitempty"`
+
+	// Explain attaches a SearchExplanation to each result.
+	Explain bool `json:"explain,omitempty"`
+
+	// GroupByParent keeps only the best chunk of each parent document
+	// (metadata parent_id), so results cover distinct files.
+	GroupByParent bool `json:"group_by_parent,omitempty"`
+
+	// Neighbors joins each chunk result with up to this many chunks before
+	// and after it in its document. ExpandSection joins it with the other
+	// chunks of its section instead: the parts of a long Markdown section,
+	// or of a code symbol split for size. Citations span the joined chunks.
+	Neighbors     int  `json:"neighbors,omitempty"`
+	ExpandSection bool `json:"expand_section,omitempty"`
+
+	// Optional filters, applied inside vector and keyword retrieval.
+	// Zero values are ignored.
+	Tags          []string  `json:"tags,omitempty"` // item must carry every tag
+	SourcePrefix  string    `json:"source_prefix,omitempty"`
+	GitRef        string    `json:"git_ref,omitempty"` // items indexed from this ref by IndexGit
+	CreatedAfter  time.Time `json:"created_after,omitempty"`
+	CreatedBefore time.Time `json:"created_before,omitempty"`
+	UpdatedAfter  time.Time `json:"updated_after,omitempty"`
+	UpdatedBefore time.Time `json:"updated_before,omitempty"`
+}
+
+
+// ParseFilterTime parses a date filter value given as RFC 3339
+// ("2024-05-01T12:00:00Z") or as a plain date ("2024-05-01", UTC midnight).
+func ParseFilterTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q: expected YYYY-MM-DD or RFC 3339", s)
+	}
+	return t, nil
 }
 
 // SearchResult represents a single search result
 type SearchResult struct {
 	Item
-	Score      float64 `json:"score"`
-	Highlights []string `json:"highlights,omitempty"`
+	Score      float64        `json:"score"`
+	Highlights []string       `json:"highlights,omitempty"`
+	Feedback   *FeedbackStats `json:"feedback,omitempty"` // nil if the item has no feedback
+
+	// Explanation is set when the request asks for it (SearchRequest.Explain).
+	Explanation *SearchExplanation `json:"explanation,omitempty"`
+
+	// Citation locates the result in its source; nil for items without one.
+	Citation *Citation `json:"citation,omitempty"`
+
+	// ParentHits counts the results of the same document that
+	// SearchRequest.GroupByParent folded into this one, itself included.
+	ParentHits int `json:"parent_hits,omitempty"`
+
+	// Expanded lists the IDs of the chunks joined into Content, in order,
+	// when the request expands results.
+	Expanded []string `json:"expanded,omitempty"`
 }
 
 // IndexRequest represents a request to index content
@@ -80,12 +263,26 @@ type IndexRequest struct {
 	Language string   `json:"language,omitempty"` // for code: go, python, typescript
 	Tags     []string `json:"tags,omitempty"`
 	Scope    string   `json:"scope,omitempty"`
+	Project  string   `json:"project,omitempty"` // overrides the indexer's project
+
+	// Revision names the git ref the content was read from, for files
+	// indexed without a checkout. Each revision of a path is tracked
+	// separately, and only work tree files feed the code graph.
+	Revision string `json:"revision,omitempty"`
+
+	// Metadata is added to the metadata of every item created, e.g. git
+	// provenance.
+	Metadata map[string]any `json:"metadata,omitempty"`
 }
 
 // IndexResult represents the result of an indexing operation
 type IndexResult struct {
 	ItemID      string `json:"item_id"`
 	ChunksCount int    `json:"chunks_count"`
+	FilePath    string `json:"file_path,omitempty"`
+	Skipped     bool   `json:"skipped,omitempty"` // file unchanged since last index
+	Removed     bool   `json:"removed,omitempty"` // file deleted from disk, chunks removed
+	Ignored     string `json:"ignored,omitempty"` // why the file was left out: binary, minified, generated, too large or unsupported
 }
 
 // FlightRecorderEntry represents a log entry from the flight recorder
@@ -103,6 +300,7 @@ type FlightRecorderEntry struct {
 type Feedback struct {
 	ItemID    string    `json:"item_id"`
 	SessionID string    `json:"session_id"`
+	Project   string    `json:"project,omitempty"` // defaults to the engine's project
 	Useful    bool      `json:"useful"`
 	Context   string    `json:"context,omitempty"`
 	Timestamp time.Time `json:"timestamp"`
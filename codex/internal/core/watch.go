@@ -0,0 +1,76 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	"github.com/anthropics/aef/codex/internal/walk"
+	"github.com/anthropics/aef/codex/internal/watch"
+)
+
+// WatchOptions configures WatchDirectory
+type WatchOptions struct {
+	// Debounce is how long the directory must be quiet before changes are
+	// indexed (default watch.DefaultDebounce).
+	Debounce time.Duration
+
+	// Progress reports the initial index of the directory.
+	Progress IndexProgressCallback
+
+	// OnSync is called after the initial index and after each batch of
+	// changes, with the stats of that run and its error, if any.
+	OnSync func(stats *IndexStats, err error)
+}
+
+// WatchDirectory indexes a directory and then keeps the index in sync with
+// it until ctx is cancelled. Changed files are re-indexed through the same
+// pipeline and ignore rules as IndexDirectory; deleted and renamed paths
+// lose their chunks. A change to an ignore file, or lost file events, cause
+// a full re-index. Cancelling ctx lets the current batch drain and returns nil.
+func (idx *Indexer) WatchDirectory(ctx context.Context, dirPath string, scope string, opts WatchOptions) error {
+	filter, err := idx.newFilter(dirPath)
+	if err != nil {
+		return err
+	}
+
+	// Watch before the initial index so no change slips in between
+	w, err := watch.New(filter, watch.WithDebounce(opts.Debounce))
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	report := func(stats *IndexStats, err error) {
+		if opts.OnSync != nil && stats != nil {
+			opts.OnSync(stats, err)
+		}
+	}
+
+	tree := dirSource(filter, filter.Files)
+	root := fileKey(filter.Root())
+	stats, err := idx.indexTree(ctx, tree, root, scope, opts.Progress)
+	report(stats, err)
+	if ctx.Err() != nil {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return w.Run(ctx, func(ctx context.Context, b watch.Batch) {
+		if b.Rescan {
+			report(idx.indexTree(ctx, tree, root, scope, nil))
+			return
+		}
+		report(idx.syncBatch(ctx, filter, b, scope))
+	})
+}
+
+// syncBatch re-indexes the changed files of a batch and removes the chunks
+// of removed paths and of files that are now skipped for their content.
+func (idx *Indexer) syncBatch(ctx context.Context, filter *walk.Filter, b watch.Batch, scope string) (*IndexStats, error) {
+	files := func(fn func(path string) error, skipped func(walk.Skipped)) error {
+		return filter.Paths(b.Changed, fn, skipped)
+	}
+	return idx.syncFiles(ctx, dirSource(filter, files), b.Removed, scope, nil)
+}
@@ -0,0 +1,86 @@
+package core
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestIndexer_WatchDirectory(t *testing.T) {
+	t.Run("Given a watched directory, When files change, are deleted and renamed, Then the index follows", func(t *testing.T) {
+		dir := t.TempDir()
+		os.WriteFile(filepath.Join(dir, "a.go"), []byte("a1\na2"), 0644)
+		os.WriteFile(filepath.Join(dir, "b.go"), []byte("b1"), 0644)
+		os.MkdirAll(filepath.Join(dir, "lib"), 0755)
+		os.WriteFile(filepath.Join(dir, "lib", "c.go"), []byte("c1"), 0644)
+		idx, _, _, _, files := newIncrementalIndexer()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		syncs := make(chan *IndexStats, 10)
+		done := make(chan error)
+		go func() {
+			done <- idx.WatchDirectory(ctx, dir, "project", WatchOptions{
+				Debounce: 50 * time.Millisecond,
+				OnSync: func(stats *IndexStats, err error) {
+					if err != nil {
+						t.Errorf("sync failed: %v", err)
+					}
+					syncs <- stats
+				},
+			})
+		}()
+		next := func() *IndexStats {
+			t.Helper()
+			select {
+			case stats := <-syncs:
+				return stats
+			case <-time.After(5 * time.Second):
+				t.Fatal("no sync")
+				return nil
+			}
+		}
+
+		if stats := next(); stats.FilesIndexed != 3 {
+			t.Fatalf("initial index = %+v, want 3 files", stats)
+		}
+
+		os.WriteFile(filepath.Join(dir, "a.go"), []byte("a1\na2\na3"), 0644)
+		if stats := next(); stats.FilesIndexed != 1 || stats.TotalChunks != 3 {
+			t.Errorf("after edit = %+v, want a.go re-indexed with 3 chunks", stats)
+		}
+
+		os.Remove(filepath.Join(dir, "b.go"))
+		os.Rename(filepath.Join(dir, "lib"), filepath.Join(dir, "pkg"))
+		stats := next()
+		if stats.FilesRemoved != 2 || stats.FilesIndexed != 1 {
+			t.Errorf("after delete and rename = %+v, want 2 removed and 1 indexed", stats)
+		}
+		for _, path := range []string{"b.go", "lib/c.go"} {
+			if _, ok := files.Files[fileKey(filepath.Join(dir, path))]; ok {
+				t.Errorf("%s still tracked", path)
+			}
+		}
+		if _, ok := files.Files[fileKey(filepath.Join(dir, "pkg", "c.go"))]; !ok {
+			t.Error("pkg/c.go not tracked")
+		}
+
+		cancel()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("WatchDirectory returned %v, want nil on cancel", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("WatchDirectory did not stop")
+		}
+	})
+
+	t.Run("Given a missing directory, When watching, Then it fails", func(t *testing.T) {
+		idx, _, _, _, _ := newIncrementalIndexer()
+		if err := idx.WatchDirectory(context.Background(), filepath.Join(t.TempDir(), "missing"), "project", WatchOptions{}); err == nil {
+			t.Error("want error")
+		}
+	})
+}
@@ -1,31 +1,30 @@
 package embedding
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
-	"math"
 	"net/http"
 	"time"
+
+	"github.com/anthropics/aef/codex/internal/httpretry"
 )
 
 const (
 	defaultLocalBaseURL = "http://localhost:11434/api/embed"
 	defaultLocalModel   = "nomic-embed-text"
-	localMaxRetries     = 5
-	localInitialDelay   = 1 * time.Second
 )
 
 // LocalClient handles embedding via an Ollama-compatible API.
 // It implements core.Embedder using nomic-embed-text by default.
-// Uses nomic task prefixes: "search_document: " for indexing,
-// "search_query: " for queries.
+// Query and document prefixes come from the model's profile, e.g.
+// "search_query: " and "search_document: " for nomic-embed-text.
 type LocalClient struct {
 	baseURL string
 	model   string
+	profile *ModelProfile // nil = LookupProfile(model)
 	client  *http.Client
+	retry   httpretry.Policy
 }
 
 // LocalClientOption configures a LocalClient.
@@ -41,6 +40,11 @@ func WithLocalModel(model string) LocalClientOption {
 	return func(c *LocalClient) { c.model = model }
 }
 
+// WithLocalProfile overrides the profile looked up from the model name.
+func WithLocalProfile(p ModelProfile) LocalClientOption {
+	return func(c *LocalClient) { c.profile = &p }
+}
+
 // NewLocalClient creates a local embedding client that talks to an
 // Ollama-compatible HTTP endpoint. Defaults to localhost:11434 with
 // nomic-embed-text.
@@ -49,17 +53,33 @@ func NewLocalClient(opts ...LocalClientOption) *LocalClient {
 		baseURL: defaultLocalBaseURL,
 		model:   defaultLocalModel,
 		client:  &http.Client{Timeout: 30 * time.Second},
+		retry:   httpretry.Default(),
 	}
 	for _, opt := range opts {
 		opt(c)
 	}
+	if c.profile == nil {
+		p := LookupProfile(c.model)
+		c.profile = &p
+	}
 	return c
 }
 
+// Profile returns the model profile in use.
+func (c *LocalClient) Profile() ModelProfile {
+	return *c.profile
+}
+
+// Model returns the model name sent with each request.
+func (c *LocalClient) Model() string {
+	return c.model
+}
+
 // ollamaEmbedRequest is the Ollama /api/embed request body.
+// Input is a string or, for batches, a []string.
 type ollamaEmbedRequest struct {
 	Model string `json:"model"`
-	Input string `json:"input"`
+	Input any    `json:"input"`
 }
 
 // ollamaEmbedResponse is the Ollama /api/embed response body.
@@ -68,77 +88,74 @@ type ollamaEmbedResponse struct {
 }
 
 // EmbedDocument embeds a text for storage/indexing.
-// Uses "search_document: " prefix for asymmetric retrieval.
+// Uses the profile's document prefix for asymmetric retrieval.
 func (c *LocalClient) EmbedDocument(ctx context.Context, text string) ([]float32, error) {
-	return c.embed(ctx, "search_document: "+text)
+	return c.embed(ctx, c.profile.DocumentPrefix+text)
 }
 
 // EmbedQuery embeds a search query.
-// Uses "search_query: " prefix for asymmetric retrieval.
+// Uses the profile's query prefix for asymmetric retrieval.
 func (c *LocalClient) EmbedQuery(ctx context.Context, query string) ([]float32, error) {
-	return c.embed(ctx, "search_query: "+query)
+	return c.embed(ctx, c.profile.QueryPrefix+query)
+}
+
+// EmbedDocuments embeds several texts for storage/indexing in one request,
+// returning one vector per text in order. The request is retried like a
+// single embedding; callers handle per-item fallback if it still fails.
+func (c *LocalClient) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	inputs := make([]string, len(texts))
+	for i, text := range texts {
+		inputs[i] = c.profile.DocumentPrefix + text
+	}
+
+	embeddings, err := c.post(ctx, inputs)
+	if err != nil {
+		return nil, err
+	}
+	if len(embeddings) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(embeddings))
+	}
+	return embeddings, nil
 }
 
 func (c *LocalClient) embed(ctx context.Context, text string) ([]float32, error) {
-	reqBody := ollamaEmbedRequest{
-		Model: c.model,
-		Input: text,
+	embeddings, err := c.post(ctx, text)
+	if err != nil {
+		return nil, err
 	}
+	return embeddings[0], nil
+}
 
-	body, err := json.Marshal(reqBody)
+// post sends an /api/embed request. It returns an error if no embeddings
+// come back or they do not match the profile's dimensions.
+func (c *LocalClient) post(ctx context.Context, input any) ([][]float32, error) {
+	body, err := json.Marshal(ollamaEmbedRequest{
+		Model: c.model,
+		Input: input,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	var lastErr error
-	for attempt := 0; attempt < localMaxRetries; attempt++ {
-		if attempt > 0 {
-			delay := time.Duration(math.Pow(2, float64(attempt))) * localInitialDelay
-			select {
-			case <-time.After(delay):
-			case <-ctx.Done():
-				return nil, ctx.Err()
-			}
-		}
-
-		httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL, bytes.NewReader(body))
-		if err != nil {
-			return nil, fmt.Errorf("failed to create request: %w", err)
-		}
-		httpReq.Header.Set("Content-Type", "application/json")
-
-		resp, err := c.client.Do(httpReq)
-		if err != nil {
-			lastErr = fmt.Errorf("local embedding request failed: %w", err)
-			continue
-		}
-
-		respBody, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		if err != nil {
-			lastErr = fmt.Errorf("failed to read response body: %w", err)
-			continue
-		}
-
-		if resp.StatusCode != http.StatusOK {
-			lastErr = fmt.Errorf("local embedding error (%d): %s", resp.StatusCode, string(respBody))
-			if resp.StatusCode >= 500 {
-				continue
-			}
-			return nil, lastErr
-		}
-
-		var embedResp ollamaEmbedResponse
-		if err := json.Unmarshal(respBody, &embedResp); err != nil {
-			return nil, fmt.Errorf("failed to decode response: %w", err)
-		}
-
-		if len(embedResp.Embeddings) == 0 {
-			return nil, fmt.Errorf("no embeddings returned")
-		}
-
-		return embedResp.Embeddings[0], nil
+	respBody, err := c.retry.PostJSON(ctx, c.client, c.baseURL, body, nil)
+	if err != nil {
+		return nil, fmt.Errorf("embedding: %w", err)
+	}
+
+	var embedResp ollamaEmbedResponse
+	if err := json.Unmarshal(respBody, &embedResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if len(embedResp.Embeddings) == 0 {
+		return nil, fmt.Errorf("no embeddings returned")
+	}
+	if err := c.profile.checkDimensions(embedResp.Embeddings...); err != nil {
+		return nil, err
 	}
 
-	return nil, fmt.Errorf("max retries (%d) exceeded: %w", localMaxRetries, lastErr)
+	return embedResp.Embeddings, nil
 }
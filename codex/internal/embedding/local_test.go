@@ -0,0 +1,116 @@
+package embedding
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// testProfile uses nomic-style prefixes with unchecked dimensions, since
+// the stand-in servers return one-dimensional vectors.
+var testProfile = ModelProfile{Name: "test", QueryPrefix: "search_query: ", DocumentPrefix: "search_document: "}
+
+// newEmbedServer returns an Ollama /api/embed stand-in that embeds each
+// input as [len(input)] and records the decoded inputs of every request.
+func newEmbedServer(t *testing.T, requests *[][]string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Input json.RawMessage `json:"input"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var inputs []string
+		if err := json.Unmarshal(req.Input, &inputs); err != nil {
+			var single string
+			if err := json.Unmarshal(req.Input, &single); err != nil {
+				http.Error(w, "bad input", http.StatusBadRequest)
+				return
+			}
+			inputs = []string{single}
+		}
+		*requests = append(*requests, inputs)
+
+		embeddings := make([][]float32, len(inputs))
+		for i, in := range inputs {
+			embeddings[i] = []float32{float32(len(in))}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"embeddings": embeddings})
+	}))
+}
+
+func TestLocalClient_EmbedDocuments(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Given several texts, When embedding, Then sends one prefixed request", func(t *testing.T) {
+		var requests [][]string
+		srv := newEmbedServer(t, &requests)
+		defer srv.Close()
+
+		c := NewLocalClient(WithLocalBaseURL(srv.URL), WithLocalProfile(testProfile))
+		vecs, err := c.EmbedDocuments(ctx, []string{"a", "bbb"})
+		if err != nil {
+			t.Fatalf("EmbedDocuments: %v", err)
+		}
+		if len(requests) != 1 || len(requests[0]) != 2 {
+			t.Fatalf("expected one request with 2 inputs, got %v", requests)
+		}
+		for _, in := range requests[0] {
+			if !strings.HasPrefix(in, "search_document: ") {
+				t.Errorf("input %q missing document prefix", in)
+			}
+		}
+		if len(vecs) != 2 || vecs[0][0] != float32(len("search_document: a")) || vecs[1][0] != float32(len("search_document: bbb")) {
+			t.Errorf("unexpected vectors %v", vecs)
+		}
+	})
+
+	t.Run("Given no texts, When embedding, Then sends nothing", func(t *testing.T) {
+		var requests [][]string
+		srv := newEmbedServer(t, &requests)
+		defer srv.Close()
+
+		vecs, err := NewLocalClient(WithLocalBaseURL(srv.URL)).EmbedDocuments(ctx, nil)
+		if err != nil || vecs != nil || len(requests) != 0 {
+			t.Errorf("expected no request, got %v, %v, %v", vecs, err, requests)
+		}
+	})
+
+	t.Run("Given a short response, When embedding, Then returns error", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"embeddings": [[1]]}`))
+		}))
+		defer srv.Close()
+
+		if _, err := NewLocalClient(WithLocalBaseURL(srv.URL)).EmbedDocuments(ctx, []string{"a", "b"}); err == nil {
+			t.Error("expected error for mismatched embedding count")
+		}
+	})
+
+	t.Run("Given a single document, When embedding, Then sends a string input", func(t *testing.T) {
+		var requests [][]string
+		srv := newEmbedServer(t, &requests)
+		defer srv.Close()
+
+		vec, err := NewLocalClient(WithLocalBaseURL(srv.URL), WithLocalProfile(testProfile)).EmbedDocument(ctx, "abc")
+		if err != nil || len(vec) != 1 {
+			t.Fatalf("EmbedDocument: %v, %v", vec, err)
+		}
+	})
+
+	t.Run("Given a profile with other dimensions, When embedding, Then returns error", func(t *testing.T) {
+		var requests [][]string
+		srv := newEmbedServer(t, &requests)
+		defer srv.Close()
+
+		// nomic-embed-text expects 768 dimensions; the stand-in returns 1
+		if _, err := NewLocalClient(WithLocalBaseURL(srv.URL)).EmbedQuery(ctx, "q"); err == nil {
+			t.Error("expected dimension mismatch error")
+		}
+	})
+}
@@ -0,0 +1,176 @@
+package embedding
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/anthropics/aef/codex/internal/httpretry"
+)
+
+const (
+	defaultOpenAIBaseURL   = "http://localhost:8000/v1/embeddings"
+	defaultLlamaCppBaseURL = "http://localhost:8080/v1/embeddings"
+)
+
+// OpenAIClient handles embedding via an OpenAI-compatible /v1/embeddings
+// endpoint, as served by vLLM, llama.cpp's llama-server, LiteLLM and
+// OpenAI itself. Query and document prefixes come from the model's profile.
+type OpenAIClient struct {
+	baseURL string
+	model   string
+	apiKey  string
+	profile *ModelProfile // nil = LookupProfile(model)
+	client  *http.Client
+	retry   httpretry.Policy
+}
+
+// OpenAIClientOption configures an OpenAIClient.
+type OpenAIClientOption func(*OpenAIClient)
+
+// WithOpenAIBaseURL sets the /v1/embeddings endpoint.
+func WithOpenAIBaseURL(url string) OpenAIClientOption {
+	return func(c *OpenAIClient) { c.baseURL = url }
+}
+
+// WithOpenAIModel sets the model name sent with each request.
+func WithOpenAIModel(model string) OpenAIClientOption {
+	return func(c *OpenAIClient) { c.model = model }
+}
+
+// WithOpenAIAPIKey sets the bearer token. Local servers usually need none.
+func WithOpenAIAPIKey(key string) OpenAIClientOption {
+	return func(c *OpenAIClient) { c.apiKey = key }
+}
+
+// WithOpenAIProfile overrides the profile looked up from the model name.
+func WithOpenAIProfile(p ModelProfile) OpenAIClientOption {
+	return func(c *OpenAIClient) { c.profile = &p }
+}
+
+// NewOpenAIClient creates a client for an OpenAI-compatible embedding
+// server. Defaults to localhost:8000 (vLLM) with nomic-embed-text.
+func NewOpenAIClient(opts ...OpenAIClientOption) *OpenAIClient {
+	c := &OpenAIClient{
+		baseURL: defaultOpenAIBaseURL,
+		model:   defaultLocalModel,
+		client:  &http.Client{Timeout: 30 * time.Second},
+		retry:   httpretry.Default(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.profile == nil {
+		p := LookupProfile(c.model)
+		c.profile = &p
+	}
+	return c
+}
+
+// NewLlamaCppClient creates a client for llama.cpp's llama-server, which
+// serves the OpenAI embedding format. Defaults to localhost:8080. The
+// server embeds with whatever model it loaded; the model name here only
+// selects the profile.
+func NewLlamaCppClient(opts ...OpenAIClientOption) *OpenAIClient {
+	return NewOpenAIClient(append([]OpenAIClientOption{WithOpenAIBaseURL(defaultLlamaCppBaseURL)}, opts...)...)
+}
+
+// Profile returns the model profile in use.
+func (c *OpenAIClient) Profile() ModelProfile {
+	return *c.profile
+}
+
+// Model returns the model name sent with each request.
+func (c *OpenAIClient) Model() string {
+	return c.model
+}
+
+// openAIEmbedRequest is the /v1/embeddings request body.
+type openAIEmbedRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// openAIEmbedResponse is the /v1/embeddings response body.
+type openAIEmbedResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+}
+
+// EmbedDocument embeds a text for storage/indexing.
+func (c *OpenAIClient) EmbedDocument(ctx context.Context, text string) ([]float32, error) {
+	vecs, err := c.post(ctx, []string{c.profile.DocumentPrefix + text})
+	if err != nil {
+		return nil, err
+	}
+	return vecs[0], nil
+}
+
+// EmbedQuery embeds a search query.
+func (c *OpenAIClient) EmbedQuery(ctx context.Context, query string) ([]float32, error) {
+	vecs, err := c.post(ctx, []string{c.profile.QueryPrefix + query})
+	if err != nil {
+		return nil, err
+	}
+	return vecs[0], nil
+}
+
+// EmbedDocuments embeds several texts for storage/indexing in one request,
+// returning one vector per text in order.
+func (c *OpenAIClient) EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	inputs := make([]string, len(texts))
+	for i, text := range texts {
+		inputs[i] = c.profile.DocumentPrefix + text
+	}
+	return c.post(ctx, inputs)
+}
+
+// post sends a /v1/embeddings request and returns the embeddings in input
+// order, checked against the profile's dimensions.
+func (c *OpenAIClient) post(ctx context.Context, inputs []string) ([][]float32, error) {
+	body, err := json.Marshal(openAIEmbedRequest{
+		Model: c.model,
+		Input: inputs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	var headers map[string]string
+	if c.apiKey != "" {
+		headers = map[string]string{"Authorization": "Bearer " + c.apiKey}
+	}
+	respBody, err := c.retry.PostJSON(ctx, c.client, c.baseURL, body, headers)
+	if err != nil {
+		return nil, fmt.Errorf("embedding: %w", err)
+	}
+
+	var embedResp openAIEmbedResponse
+	if err := json.Unmarshal(respBody, &embedResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if len(embedResp.Data) != len(inputs) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(inputs), len(embedResp.Data))
+	}
+
+	// The spec orders data by index, but not every server honours it
+	sort.SliceStable(embedResp.Data, func(i, j int) bool {
+		return embedResp.Data[i].Index < embedResp.Data[j].Index
+	})
+	vecs := make([][]float32, len(embedResp.Data))
+	for i, d := range embedResp.Data {
+		vecs[i] = d.Embedding
+	}
+	if err := c.profile.checkDimensions(vecs...); err != nil {
+		return nil, err
+	}
+	return vecs, nil
+}
@@ -0,0 +1,160 @@
+package embedding
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newOpenAIServer returns a /v1/embeddings stand-in that embeds each input
+// as [len(input)], listing results in reverse index order.
+func newOpenAIServer(t *testing.T, lastAuth *string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*lastAuth = r.Header.Get("Authorization")
+		var req openAIEmbedRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		type datum struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		}
+		var data []datum
+		for i := len(req.Input) - 1; i >= 0; i-- {
+			data = append(data, datum{Index: i, Embedding: []float32{float32(len(req.Input[i]))}})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"data": data})
+	}))
+}
+
+func TestOpenAIClient(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Given out-of-order data, When embedding a batch, Then vectors follow input order", func(t *testing.T) {
+		var auth string
+		srv := newOpenAIServer(t, &auth)
+		defer srv.Close()
+
+		c := NewOpenAIClient(WithOpenAIBaseURL(srv.URL), WithOpenAIProfile(testProfile), WithOpenAIAPIKey("secret"))
+		vecs, err := c.EmbedDocuments(ctx, []string{"a", "bbbb"})
+		if err != nil {
+			t.Fatalf("EmbedDocuments: %v", err)
+		}
+		if vecs[0][0] != float32(len("search_document: a")) || vecs[1][0] != float32(len("search_document: bbbb")) {
+			t.Errorf("unexpected vectors %v", vecs)
+		}
+		if auth != "Bearer secret" {
+			t.Errorf("Authorization = %q, want bearer token", auth)
+		}
+	})
+
+	t.Run("Given a query, When embedding, Then applies the query prefix without auth", func(t *testing.T) {
+		var auth string
+		srv := newOpenAIServer(t, &auth)
+		defer srv.Close()
+
+		vec, err := NewLlamaCppClient(WithOpenAIBaseURL(srv.URL), WithOpenAIProfile(testProfile)).EmbedQuery(ctx, "q")
+		if err != nil {
+			t.Fatalf("EmbedQuery: %v", err)
+		}
+		if vec[0] != float32(len("search_query: q")) || auth != "" {
+			t.Errorf("got vector %v and auth %q", vec, auth)
+		}
+	})
+
+	t.Run("Given a rate limit, When embedding, Then retries after the requested delay", func(t *testing.T) {
+		var auth string
+		inner := newOpenAIServer(t, &auth)
+		defer inner.Close()
+		calls := 0
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if calls == 1 {
+				w.Header().Set("Retry-After", "0")
+				http.Error(w, "rate limited", http.StatusTooManyRequests)
+				return
+			}
+			inner.Config.Handler.ServeHTTP(w, r)
+		}))
+		defer srv.Close()
+
+		c := NewOpenAIClient(WithOpenAIBaseURL(srv.URL), WithOpenAIProfile(testProfile))
+		vec, err := c.EmbedQuery(ctx, "q")
+		if err != nil {
+			t.Fatalf("EmbedQuery: %v", err)
+		}
+		if vec[0] != float32(len("search_query: q")) || calls != 2 {
+			t.Errorf("got vector %v after %d calls, want success on the second", vec, calls)
+		}
+	})
+}
+
+func TestLookupProfile(t *testing.T) {
+	tests := []struct {
+		model       string
+		wantDims    int
+		wantQuery   string
+		wantDocPref string
+		wantTokens  int
+	}{
+		{"nomic-embed-text", 768, "search_query: ", "search_document: ", 8192},
+		{"nomic-embed-text:latest", 768, "search_query: ", "search_document: ", 8192},
+		{"nomic-ai/nomic-embed-text-v1.5", 768, "search_query: ", "search_document: ", 8192},
+		{"nomic-embed-text-v1.5.Q8_0.gguf", 768, "search_query: ", "search_document: ", 8192},
+		{"BAAI/bge-small-en-v1.5", 384, bgeQueryPrefix, "", 512},
+		{"intfloat/multilingual-e5-large", 1024, "query: ", "passage: ", 512},
+		{"text-embedding-3-small", 1536, "", "", 8191},
+		{"some-unknown-model", 0, "", "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			p := LookupProfile(tt.model)
+			if p.Dimensions != tt.wantDims || p.QueryPrefix != tt.wantQuery || p.DocumentPrefix != tt.wantDocPref || p.MaxTokens != tt.wantTokens {
+				t.Errorf("LookupProfile(%q) = %+v", tt.model, p)
+			}
+			if p.Name != tt.model {
+				t.Errorf("Name = %q, want %q", p.Name, tt.model)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	t.Run("Given no backend, When creating, Then uses Ollama", func(t *testing.T) {
+		e, err := New(Config{})
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+		if _, ok := e.(*LocalClient); !ok {
+			t.Errorf("expected *LocalClient, got %T", e)
+		}
+	})
+
+	t.Run("Given llama.cpp with overrides, When creating, Then applies them to the profile", func(t *testing.T) {
+		e, err := New(Config{Backend: BackendLlamaCpp, Model: "my-model", QueryPrefix: "Q: ", Dimensions: 512, MaxTokens: 2048})
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+		c, ok := e.(*OpenAIClient)
+		if !ok {
+			t.Fatalf("expected *OpenAIClient, got %T", e)
+		}
+		if c.baseURL != defaultLlamaCppBaseURL {
+			t.Errorf("baseURL = %q, want llama.cpp default", c.baseURL)
+		}
+		if p := c.Profile(); p.QueryPrefix != "Q: " || p.Dimensions != 512 || p.MaxTokens != 2048 || p.Name != "my-model" {
+			t.Errorf("unexpected profile %+v", p)
+		}
+	})
+
+	t.Run("Given an unknown backend, When creating, Then returns error", func(t *testing.T) {
+		if _, err := New(Config{Backend: "bogus"}); err == nil {
+			t.Error("expected error for unknown backend")
+		}
+	})
+}
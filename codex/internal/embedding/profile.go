@@ -0,0 +1,78 @@
+package embedding
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// ModelProfile describes how to use an embedding model: the prefixes it was
+// trained with for asymmetric retrieval, the vector length it produces and
+// the longest input it reads.
+type ModelProfile struct {
+	Name           string
+	QueryPrefix    string // prepended to search queries
+	DocumentPrefix string // prepended to indexed documents
+	Dimensions     int    // expected vector length; 0 = not checked
+	MaxTokens      int    // input tokens the model reads, the rest is truncated; 0 = unknown
+}
+
+// bgeQueryPrefix is the retrieval instruction used by BGE-style English models.
+const bgeQueryPrefix = "Represent this sentence for searching relevant passages: "
+
+// knownProfiles maps model family names to their profiles. Lookup matches
+// the longest family name that prefixes the normalized model name, so
+// "nomic-embed-text:latest" and "nomic-embed-text-v1.5.Q8_0.gguf" both
+// resolve to nomic-embed-text.
+var knownProfiles = map[string]ModelProfile{
+	"nomic-embed-text":       {QueryPrefix: "search_query: ", DocumentPrefix: "search_document: ", Dimensions: 768, MaxTokens: 8192},
+	"mxbai-embed-large":      {QueryPrefix: bgeQueryPrefix, Dimensions: 1024, MaxTokens: 512},
+	"snowflake-arctic-embed": {QueryPrefix: bgeQueryPrefix, Dimensions: 1024, MaxTokens: 512},
+	"bge-small-en":           {QueryPrefix: bgeQueryPrefix, Dimensions: 384, MaxTokens: 512},
+	"bge-base-en":            {QueryPrefix: bgeQueryPrefix, Dimensions: 768, MaxTokens: 512},
+	"bge-large-en":           {QueryPrefix: bgeQueryPrefix, Dimensions: 1024, MaxTokens: 512},
+	"bge-m3":                 {Dimensions: 1024, MaxTokens: 8192},
+	"all-minilm":             {Dimensions: 384, MaxTokens: 256},
+	"e5-small":               {QueryPrefix: "query: ", DocumentPrefix: "passage: ", Dimensions: 384, MaxTokens: 512},
+	"e5-base":                {QueryPrefix: "query: ", DocumentPrefix: "passage: ", Dimensions: 768, MaxTokens: 512},
+	"e5-large":               {QueryPrefix: "query: ", DocumentPrefix: "passage: ", Dimensions: 1024, MaxTokens: 512},
+	"multilingual-e5-small":  {QueryPrefix: "query: ", DocumentPrefix: "passage: ", Dimensions: 384, MaxTokens: 512},
+	"multilingual-e5-base":   {QueryPrefix: "query: ", DocumentPrefix: "passage: ", Dimensions: 768, MaxTokens: 512},
+	"multilingual-e5-large":  {QueryPrefix: "query: ", DocumentPrefix: "passage: ", Dimensions: 1024, MaxTokens: 512},
+	"text-embedding-3-small": {Dimensions: 1536, MaxTokens: 8191},
+	"text-embedding-3-large": {Dimensions: 3072, MaxTokens: 8191},
+	"text-embedding-ada-002": {Dimensions: 1536, MaxTokens: 8191},
+}
+
+// LookupProfile returns the profile for model. Models are matched by family
+// after stripping any organisation ("BAAI/"), tag (":latest") and case.
+// Unknown models get no prefixes and unchecked dimensions.
+func LookupProfile(model string) ModelProfile {
+	name := strings.ToLower(path.Base(model))
+	if i := strings.IndexByte(name, ':'); i >= 0 {
+		name = name[:i]
+	}
+
+	best := ""
+	for family := range knownProfiles {
+		if strings.HasPrefix(name, family) && len(family) > len(best) {
+			best = family
+		}
+	}
+	p := knownProfiles[best]
+	p.Name = model
+	return p
+}
+
+// checkDimensions verifies that every vector has the profile's length.
+func (p ModelProfile) checkDimensions(vecs ...[]float32) error {
+	if p.Dimensions == 0 {
+		return nil
+	}
+	for _, v := range vecs {
+		if len(v) != p.Dimensions {
+			return fmt.Errorf("model %s returned %d dimensions, profile expects %d", p.Name, len(v), p.Dimensions)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Backend names accepted by New.
+const (
+	BackendOllama           = "ollama"
+	BackendOpenAICompatible = "openai-compatible"
+	BackendLlamaCpp         = "llama.cpp"
+)
+
+// Embedder is the interface every backend implements. It matches
+// core.Embedder, plus the model name and the profile the backend applies.
+type Embedder interface {
+	EmbedDocument(ctx context.Context, text string) ([]float32, error)
+	EmbedDocuments(ctx context.Context, texts []string) ([][]float32, error)
+	EmbedQuery(ctx context.Context, query string) ([]float32, error)
+	Model() string
+	Profile() ModelProfile
+}
+
+// Config selects and configures an embedding backend. Zero values use the
+// backend's defaults.
+type Config struct {
+	Backend string // "ollama" (default), "openai-compatible" or "llama.cpp"
+	URL     string
+	Model   string
+	APIKey  string // sent as a bearer token by OpenAI-compatible backends
+
+	// Profile overrides. Prefixes replace the model profile's when
+	// non-empty; Dimensions and MaxTokens replace it when positive.
+	QueryPrefix    string
+	DocumentPrefix string
+	Dimensions     int
+	MaxTokens      int
+}
+
+// Factory creates an Embedder from a Config.
+type Factory func(cfg Config) (Embedder, error)
+
+var backends = map[string]Factory{
+	BackendOllama: func(cfg Config) (Embedder, error) {
+		var opts []LocalClientOption
+		if cfg.URL != "" {
+			opts = append(opts, WithLocalBaseURL(cfg.URL))
+		}
+		if cfg.Model != "" {
+			opts = append(opts, WithLocalModel(cfg.Model))
+		}
+		c := NewLocalClient(opts...)
+		c.profile = cfg.applyOverrides(*c.profile)
+		return c, nil
+	},
+	BackendOpenAICompatible: func(cfg Config) (Embedder, error) {
+		c := NewOpenAIClient(cfg.openAIOptions()...)
+		c.profile = cfg.applyOverrides(*c.profile)
+		return c, nil
+	},
+	BackendLlamaCpp: func(cfg Config) (Embedder, error) {
+		c := NewLlamaCppClient(cfg.openAIOptions()...)
+		c.profile = cfg.applyOverrides(*c.profile)
+		return c, nil
+	},
+}
+
+// Register adds or replaces a backend. It is not safe to call concurrently
+// with New; register backends during initialization.
+func Register(name string, f Factory) {
+	backends[name] = f
+}
+
+// Backends returns the registered backend names, sorted.
+func Backends() []string {
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New creates the embedder for cfg.Backend.
+func New(cfg Config) (Embedder, error) {
+	name := cfg.Backend
+	if name == "" {
+		name = BackendOllama
+	}
+	f, ok := backends[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown embedding backend %q (want %s)", cfg.Backend, strings.Join(Backends(), ", "))
+	}
+	return f(cfg)
+}
+
+func (cfg Config) openAIOptions() []OpenAIClientOption {
+	var opts []OpenAIClientOption
+	if cfg.URL != "" {
+		opts = append(opts, WithOpenAIBaseURL(cfg.URL))
+	}
+	if cfg.Model != "" {
+		opts = append(opts, WithOpenAIModel(cfg.Model))
+	}
+	if cfg.APIKey != "" {
+		opts = append(opts, WithOpenAIAPIKey(cfg.APIKey))
+	}
+	return opts
+}
+
+// applyOverrides returns p with the Config's profile overrides applied.
+func (cfg Config) applyOverrides(p ModelProfile) *ModelProfile {
+	if cfg.QueryPrefix != "" {
+		p.QueryPrefix = cfg.QueryPrefix
+	}
+	if cfg.DocumentPrefix != "" {
+		p.DocumentPrefix = cfg.DocumentPrefix
+	}
+	if cfg.Dimensions > 0 {
+		p.Dimensions = cfg.Dimensions
+	}
+	if cfg.MaxTokens > 0 {
+		p.MaxTokens = cfg.MaxTokens
+	}
+	return &p
+}
@@ -0,0 +1,92 @@
+// Package env reads Codex configuration from environment variables. The
+// codex-cli, recall-mcp and codex-web binaries share it so that a variable
+// means the same thing to each of them. Invalid values log a warning and
+// fall back to the default.
+package env
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anthropics/aef/codex/internal/core"
+	"github.com/anthropics/aef/codex/internal/walk"
+)
+
+// Get returns the value of key, or defaultVal when it is unset or empty.
+func Get(key, defaultVal string) string {
+	if val := os.Getenv(key); val != "" {
+		return val
+	}
+	return defaultVal
+}
+
+// Int reads an integer.
+func Int(key string, defaultVal int) int {
+	val := os.Getenv(key)
+	if val == "" {
+		return defaultVal
+	}
+	n, err := strconv.Atoi(val)
+	if err != nil {
+		log.Printf("Warning: invalid %s=%q, using %v", key, val, defaultVal)
+		return defaultVal
+	}
+	return n
+}
+
+// Float reads a floating-point number.
+func Float(key string, defaultVal float64) float64 {
+	val := os.Getenv(key)
+	if val == "" {
+		return defaultVal
+	}
+	f, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		log.Printf("Warning: invalid %s=%q, using %v", key, val, defaultVal)
+		return defaultVal
+	}
+	return f
+}
+
+// List reads a comma-separated list, e.g. "*.go,docs/**"; nil when unset.
+func List(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// Size reads a byte size such as "2MB"; 0 when unset.
+func Size(key string) int64 {
+	val := os.Getenv(key)
+	if val == "" {
+		return 0
+	}
+	n, err := walk.ParseSize(val)
+	if err != nil {
+		log.Printf("Warning: invalid %s=%q, using 0", key, val)
+		return 0
+	}
+	return n
+}
+
+// Days reads a duration given in days, e.g. "30" or "0.5"; 0 when unset.
+func Days(key string) time.Duration {
+	return time.Duration(Float(key, 0) * float64(24*time.Hour))
+}
+
+// Weights reads fusion weights given as "vector:1,keyword:2"; nil when unset.
+func Weights(key string) core.FusionWeights {
+	w, err := core.ParseFusionWeights(os.Getenv(key))
+	if err != nil {
+		log.Printf("Warning: invalid %s: %v", key, err)
+		return nil
+	}
+	return w
+}
@@ -0,0 +1,76 @@
+package env
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNumbers(t *testing.T) {
+	t.Run("Given valid values When reading Then they are parsed", func(t *testing.T) {
+		t.Setenv("CODEX_TEST_INT", "42")
+		t.Setenv("CODEX_TEST_FLOAT", "0.5")
+
+		if got := Int("CODEX_TEST_INT", 7); got != 42 {
+			t.Errorf("Int = %d, want 42", got)
+		}
+		if got := Float("CODEX_TEST_FLOAT", 1); got != 0.5 {
+			t.Errorf("Float = %v, want 0.5", got)
+		}
+		if got := Days("CODEX_TEST_FLOAT"); got != 12*time.Hour {
+			t.Errorf("Days = %v, want 12h", got)
+		}
+	})
+
+	t.Run("Given unset or invalid values When reading Then the default is used", func(t *testing.T) {
+		t.Setenv("CODEX_TEST_INT", "lots")
+		t.Setenv("CODEX_TEST_FLOAT", "")
+
+		if got := Int("CODEX_TEST_INT", 7); got != 7 {
+			t.Errorf("Int = %d, want default 7", got)
+		}
+		if got := Float("CODEX_TEST_FLOAT", 1); got != 1 {
+			t.Errorf("Float = %v, want default 1", got)
+		}
+		if got := Get("CODEX_TEST_FLOAT", "fallback"); got != "fallback" {
+			t.Errorf("Get = %q, want fallback", got)
+		}
+	})
+}
+
+func TestListAndSize(t *testing.T) {
+	t.Run("Given a list and a size When reading Then they are parsed", func(t *testing.T) {
+		t.Setenv("CODEX_TEST_LIST", " *.go, ,docs/** ")
+		t.Setenv("CODEX_TEST_SIZE", "2MB")
+
+		if got := List("CODEX_TEST_LIST"); len(got) != 2 || got[0] != "*.go" || got[1] != "docs/**" {
+			t.Errorf("List = %q, want [*.go docs/**]", got)
+		}
+		if got := Size("CODEX_TEST_SIZE"); got != 2<<20 {
+			t.Errorf("Size = %d, want %d", got, 2<<20)
+		}
+	})
+
+	t.Run("Given unset or invalid values When reading Then they are empty", func(t *testing.T) {
+		t.Setenv("CODEX_TEST_LIST", "")
+		t.Setenv("CODEX_TEST_SIZE", "huge")
+
+		if got := List("CODEX_TEST_LIST"); got != nil {
+			t.Errorf("List = %q, want nil", got)
+		}
+		if got := Size("CODEX_TEST_SIZE"); got != 0 {
+			t.Errorf("Size = %d, want 0", got)
+		}
+	})
+}
+
+func TestWeights(t *testing.T) {
+	t.Setenv("CODEX_TEST_WEIGHTS", "vector:1,keyword:2")
+	if w := Weights("CODEX_TEST_WEIGHTS"); w["keyword"] != 2 || w["vector"] != 1 {
+		t.Errorf("Weights = %v", w)
+	}
+
+	t.Setenv("CODEX_TEST_WEIGHTS", "vector")
+	if w := Weights("CODEX_TEST_WEIGHTS"); w != nil {
+		t.Errorf("Weights = %v, want nil for an invalid value", w)
+	}
+}
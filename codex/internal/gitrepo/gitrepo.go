@@ -0,0 +1,291 @@
+// Package gitrepo reads files, diffs and history from a git repository by
+// running the git command, so any revision can be indexed without checking
+// it out.
+package gitrepo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Repo is a git work tree.
+type Repo struct {
+	// Dir is the absolute top-level directory of the work tree.
+	Dir string
+}
+
+// Open returns the repository containing dir.
+func Open(ctx context.Context, dir string) (*Repo, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path %s: %w", dir, err)
+	}
+	out, err := (&Repo{Dir: abs}).git(ctx, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("%s is not in a git repository: %w", dir, err)
+	}
+	top := strings.TrimSpace(string(out))
+	if real, err := filepath.EvalSymlinks(top); err == nil {
+		top = real
+	}
+	return &Repo{Dir: filepath.Clean(top)}, nil
+}
+
+// git runs a git command in the repository and returns its output. Errors
+// carry git's message.
+func (r *Repo) git(ctx context.Context, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", r.Dir, "-c", "core.quotePath=false"}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return nil, fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return out, nil
+}
+
+// ResolveCommit returns the full SHA of the commit ref names.
+func (r *Repo) ResolveCommit(ctx context.Context, ref string) (string, error) {
+	out, err := r.git(ctx, "rev-parse", "--verify", "--quiet", "--end-of-options", ref+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("unknown revision %q", ref)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Files lists the regular files of a commit, or, for rev "", the tracked
+// and untracked but not ignored files of the work tree. Paths are relative
+// and slash-separated. Submodules and, in commits, symlinks are left out.
+func (r *Repo) Files(ctx context.Context, rev string) ([]string, error) {
+	if rev == "" {
+		out, err := r.git(ctx, "ls-files", "-z", "--cached", "--others", "--exclude-standard")
+		if err != nil {
+			return nil, err
+		}
+		return splitNUL(out), nil
+	}
+
+	out, err := r.git(ctx, "ls-tree", "-r", "-z", "--full-tree", rev)
+	if err != nil {
+		return nil, err
+	}
+	// "<mode> <type> <object>\t<path>"
+	var files []string
+	for _, entry := range splitNUL(out) {
+		meta, path, ok := strings.Cut(entry, "\t")
+		if !ok || !strings.HasPrefix(meta, "100") {
+			continue
+		}
+		files = append(files, path)
+	}
+	return files, nil
+}
+
+// Change is a path that differs between two versions of the tree.
+type Change struct {
+	Path    string // relative, slash-separated
+	Deleted bool
+}
+
+// Diff lists the paths that changed from commit from to commit to, or, for
+// to "", to the work tree, including untracked files. Renames are reported
+// as a deletion and an addition.
+func (r *Repo) Diff(ctx context.Context, from, to string) ([]Change, error) {
+	args := []string{"diff", "--name-status", "-z", "--no-renames", from}
+	if to != "" {
+		args = append(args, to)
+	}
+	out, err := r.git(ctx, append(args, "--")...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Entries alternate between a status letter and a path
+	fields := splitNUL(out)
+	var changes []Change
+	for i := 0; i+1 < len(fields); i += 2 {
+		changes = append(changes, Change{Path: fields[i+1], Deleted: fields[i] == "D"})
+	}
+
+	if to == "" {
+		untracked, err := r.git(ctx, "ls-files", "-z", "--others", "--exclude-standard")
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range splitNUL(untracked) {
+			changes = append(changes, Change{Path: path})
+		}
+	}
+	return changes, nil
+}
+
+// Commit describes the commit that last changed a file.
+type Commit struct {
+	SHA    string
+	Author string // "Name <email>"
+	Time   time.Time
+}
+
+// LastCommits returns, for each of paths, the most recent commit reachable
+// from rev that changed it. Paths without such a commit, e.g. untracked
+// files, are left out. History is read until every path is found.
+func (r *Repo) LastCommits(ctx context.Context, rev string, paths []string) (map[string]Commit, error) {
+	commits := make(map[string]Commit, len(paths))
+	if len(paths) == 0 {
+		return commits, nil
+	}
+	wanted := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		wanted[p] = true
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	args := []string{"-C", r.Dir, "-c", "core.quotePath=false", "log", "--no-renames", "--name-only",
+		"--format=\x1e%H\x1f%an <%ae>\x1f%cI", rev, "--"}
+	// Narrow the history walk to the paths when there are few of them
+	if len(paths) <= 64 {
+		for _, p := range paths {
+			args = append(args, ":(literal)"+p)
+		}
+	}
+	cmd := exec.CommandContext(ctx, "git", args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("git log: %w", err)
+	}
+
+	var current Commit
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() && len(wanted) > 0 {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "\x1e") {
+			parts := strings.SplitN(line[1:], "\x1f", 3)
+			if len(parts) != 3 {
+				continue
+			}
+			current = Commit{SHA: parts[0], Author: parts[1]}
+			current.Time, _ = time.Parse(time.RFC3339, parts[2])
+			continue
+		}
+		if line != "" && wanted[line] {
+			commits[line] = current
+			delete(wanted, line)
+		}
+	}
+
+	// Stop git once every path is found; its exit status no longer matters
+	cancel()
+	cmd.Wait()
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("git log: %w", err)
+	}
+	return commits, nil
+}
+
+// Blobs reads file contents at one commit through a long-running
+// "git cat-file --batch". It is safe for concurrent use.
+type Blobs struct {
+	rev string
+
+	mu     sync.Mutex
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+}
+
+// ErrNotFound is returned by Blobs.Read for paths missing from the commit.
+var ErrNotFound = errors.New("not found in revision")
+
+// Blobs starts a reader for the files of commit rev. Close it when done.
+func (r *Repo) Blobs(ctx context.Context, rev string) (*Blobs, error) {
+	cmd := exec.CommandContext(ctx, "git", "-C", r.Dir, "cat-file", "--batch")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("git cat-file: %w", err)
+	}
+	return &Blobs{rev: rev, cmd: cmd, stdin: stdin, stdout: bufio.NewReader(stdout)}, nil
+}
+
+// Read returns the content of the file at path (relative, slash-separated).
+func (b *Blobs) Read(path string) ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	size, err := b.header(path)
+	if err != nil {
+		return nil, err
+	}
+	content := make([]byte, size+1) // content and the trailing newline
+	if _, err := io.ReadFull(b.stdout, content); err != nil {
+		return nil, fmt.Errorf("git cat-file: %w", err)
+	}
+	return content[:size], nil
+}
+
+// header requests path and reads the size line that precedes its content.
+func (b *Blobs) header(path string) (int64, error) {
+	if _, err := fmt.Fprintf(b.stdin, "%s:%s\n", b.rev, path); err != nil {
+		return 0, fmt.Errorf("git cat-file: %w", err)
+	}
+	line, err := b.stdout.ReadString('\n')
+	if err != nil {
+		return 0, fmt.Errorf("git cat-file: %w", err)
+	}
+	// "<sha> <type> <size>" or "<object> missing"
+	fields := strings.Fields(line)
+	if len(fields) != 3 {
+		return 0, fmt.Errorf("%s: %w", path, ErrNotFound)
+	}
+	size, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("git cat-file: bad header %q", strings.TrimSpace(line))
+	}
+	if fields[1] != "blob" {
+		if _, err := b.stdout.Discard(int(size) + 1); err != nil {
+			return 0, fmt.Errorf("git cat-file: %w", err)
+		}
+		return 0, fmt.Errorf("%s is a %s, not a file", path, fields[1])
+	}
+	return size, nil
+}
+
+// Close stops the reader.
+func (b *Blobs) Close() error {
+	b.stdin.Close()
+	return b.cmd.Wait()
+}
+
+func splitNUL(out []byte) []string {
+	var fields []string
+	for _, f := range strings.Split(string(out), "\x00") {
+		if f != "" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,192 @@
+package gitrepo
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// testRepo is a temporary repository driven through the git command.
+type testRepo struct {
+	t   *testing.T
+	dir string
+}
+
+func newTestRepo(t *testing.T) *testRepo {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	r := &testRepo{t: t, dir: t.TempDir()}
+	r.git("init", "-q", "-b", "main")
+	return r
+}
+
+func (r *testRepo) git(args ...string) string {
+	r.t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", r.dir}, args...)...)
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=Ada", "GIT_AUTHOR_EMAIL=ada@example.com",
+		"GIT_COMMITTER_NAME=Ada", "GIT_COMMITTER_EMAIL=ada@example.com",
+		"GIT_CONFIG_GLOBAL=/dev/null", "GIT_CONFIG_NOSYSTEM=1")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		r.t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func (r *testRepo) write(name, content string) {
+	r.t.Helper()
+	path := filepath.Join(r.dir, filepath.FromSlash(name))
+	os.MkdirAll(filepath.Dir(path), 0755)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+// commit stages everything and commits, returning the SHA.
+func (r *testRepo) commit(msg string) string {
+	r.t.Helper()
+	r.git("add", "-A")
+	r.git("commit", "-q", "-m", msg)
+	return r.git("rev-parse", "HEAD")
+}
+
+func TestRepo(t *testing.T) {
+	ctx := context.Background()
+	tr := newTestRepo(t)
+	tr.write("a.go", "package a\n")
+	tr.write("pkg/b.go", "package pkg\n")
+	first := tr.commit("first")
+	tr.git("tag", "v1")
+
+	tr.write("a.go", "package a\n\nfunc A() {}\n")
+	tr.write("c.go", "package c\n")
+	os.Remove(filepath.Join(tr.dir, "pkg", "b.go"))
+	os.Symlink("a.go", filepath.Join(tr.dir, "link.go"))
+	second := tr.commit("second")
+
+	repo, err := Open(ctx, filepath.Join(tr.dir, "pkg"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if real, _ := filepath.EvalSymlinks(tr.dir); repo.Dir != real {
+		t.Errorf("Dir = %s, want %s", repo.Dir, real)
+	}
+
+	t.Run("Given a ref, When resolving it, Then the commit SHA is returned", func(t *testing.T) {
+		if sha, err := repo.ResolveCommit(ctx, "v1"); err != nil || sha != first {
+			t.Errorf("ResolveCommit(v1) = %s, %v, want %s", sha, err, first)
+		}
+		if _, err := repo.ResolveCommit(ctx, "no-such-ref"); err == nil {
+			t.Error("want error for unknown ref")
+		}
+	})
+
+	t.Run("Given a commit, When listing files, Then regular files at that commit are returned", func(t *testing.T) {
+		files, err := repo.Files(ctx, first)
+		if err != nil || !reflect.DeepEqual(files, []string{"a.go", "pkg/b.go"}) {
+			t.Errorf("Files(first) = %q, %v", files, err)
+		}
+		files, _ = repo.Files(ctx, second)
+		if !reflect.DeepEqual(files, []string{"a.go", "c.go"}) {
+			t.Errorf("Files(second) = %q, want symlink left out", files)
+		}
+	})
+
+	t.Run("Given two commits, When diffing, Then changed and deleted paths are listed", func(t *testing.T) {
+		changes, err := repo.Diff(ctx, first, second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []Change{{Path: "a.go"}, {Path: "c.go"}, {Path: "link.go"}, {Path: "pkg/b.go", Deleted: true}}
+		if !reflect.DeepEqual(changes, want) {
+			t.Errorf("Diff = %+v, want %+v", changes, want)
+		}
+	})
+
+	t.Run("Given uncommitted changes, When diffing against the work tree, Then they and untracked files are listed", func(t *testing.T) {
+		tr.write("c.go", "package c\n\nvar C = 1\n")
+		tr.write("new.go", "package n\n")
+		t.Cleanup(func() {
+			tr.git("checkout", "-q", "--", "c.go")
+			os.Remove(filepath.Join(tr.dir, "new.go"))
+		})
+
+		changes, err := repo.Diff(ctx, second, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []Change{{Path: "c.go"}, {Path: "new.go"}}
+		if !reflect.DeepEqual(changes, want) {
+			t.Errorf("Diff = %+v, want %+v", changes, want)
+		}
+	})
+
+	t.Run("Given files, When asking for their last commits, Then each gets the commit that last changed it", func(t *testing.T) {
+		commits, err := repo.LastCommits(ctx, "HEAD", []string{"a.go", "pkg/b.go", "untracked.go"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if commits["a.go"].SHA != second || commits["pkg/b.go"].SHA != second {
+			t.Errorf("commits = %+v", commits)
+		}
+		if got := commits["a.go"].Author; got != "Ada <ada@example.com>" {
+			t.Errorf("author = %q", got)
+		}
+		if commits["a.go"].Time.IsZero() {
+			t.Error("commit time not parsed")
+		}
+		if _, ok := commits["untracked.go"]; ok {
+			t.Error("untracked file has a commit")
+		}
+
+		commits, _ = repo.LastCommits(ctx, first, []string{"a.go"})
+		if commits["a.go"].SHA != first {
+			t.Errorf("a.go at first = %s, want %s", commits["a.go"].SHA, first)
+		}
+	})
+
+	t.Run("Given a commit, When reading blobs, Then contents at that commit are returned", func(t *testing.T) {
+		blobs, err := repo.Blobs(ctx, first)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer blobs.Close()
+
+		var got []string
+		for _, path := range []string{"a.go", "pkg/b.go", "a.go"} {
+			content, err := blobs.Read(path)
+			if err != nil {
+				t.Fatalf("Read(%s): %v", path, err)
+			}
+			got = append(got, string(content))
+		}
+		sort.Strings(got)
+		if want := []string{"package a\n", "package a\n", "package pkg\n"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("contents = %q, want %q", got, want)
+		}
+		if _, err := blobs.Read("c.go"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Read(c.go) = %v, want ErrNotFound", err)
+		}
+		if _, err := blobs.Read("pkg"); err == nil {
+			t.Error("want error reading a directory")
+		}
+		if content, err := blobs.Read("a.go"); err != nil || string(content) != "package a\n" {
+			t.Errorf("Read after errors = %q, %v", content, err)
+		}
+	})
+
+	t.Run("Given a directory outside a repository, When opening it, Then it fails", func(t *testing.T) {
+		if _, err := Open(ctx, t.TempDir()); err == nil {
+			t.Error("want error")
+		}
+	})
+}
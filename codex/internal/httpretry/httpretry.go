@@ -0,0 +1,110 @@
+// Package httpretry posts JSON to model-serving APIs (embedding servers,
+// LLM providers), retrying rate limits and transient server errors.
+package httpretry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxDelay caps both computed backoff and server-requested Retry-After.
+const maxDelay = 60 * time.Second
+
+// Policy controls backoff for rate limits and transient server errors.
+type Policy struct {
+	MaxRetries   int
+	InitialDelay time.Duration
+}
+
+// Default returns the policy the embedding and LLM clients use: up to five
+// attempts, waiting 1s, 2s, 4s and 8s between them.
+func Default() Policy {
+	return Policy{MaxRetries: 5, InitialDelay: 1 * time.Second}
+}
+
+// PostJSON sends a JSON body, retrying on network errors, 429 (rate
+// limited), 529 (overloaded) and 5xx responses with exponential backoff. A
+// Retry-After header, when present, overrides the computed delay. Other
+// non-200 responses fail immediately.
+func (p Policy) PostJSON(ctx context.Context, client *http.Client, url string, body []byte, headers map[string]string) ([]byte, error) {
+	var lastErr error
+	retryAfter := time.Duration(-1)
+	for attempt := 0; attempt < p.MaxRetries; attempt++ {
+		if attempt > 0 {
+			delay := retryAfter
+			if delay < 0 {
+				delay = time.Duration(math.Pow(2, float64(attempt-1))) * p.InitialDelay
+			}
+			if delay > maxDelay {
+				delay = maxDelay
+			}
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		retryAfter = -1
+
+		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			lastErr = fmt.Errorf("request failed: %w", err)
+			continue
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			lastErr = fmt.Errorf("failed to read response body: %w", err)
+			continue
+		}
+
+		if resp.StatusCode == http.StatusOK {
+			return respBody, nil
+		}
+
+		lastErr = fmt.Errorf("server error (%d): %s", resp.StatusCode, string(respBody))
+		if !retryable(resp.StatusCode) {
+			return nil, lastErr
+		}
+		retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+	}
+
+	return nil, fmt.Errorf("max retries (%d) exceeded: %w", p.MaxRetries, lastErr)
+}
+
+// retryable reports whether a status code indicates a transient failure.
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status == 529 || status >= 500
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds.
+// Returns -1 if the header is absent or not a number.
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return -1
+	}
+	secs, err := strconv.ParseFloat(v, 64)
+	if err != nil || secs < 0 {
+		return -1
+	}
+	return time.Duration(secs * float64(time.Second))
+}
@@ -0,0 +1,91 @@
+package httpretry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPolicy_PostJSON(t *testing.T) {
+	ctx := context.Background()
+	fast := Policy{MaxRetries: 3, InitialDelay: time.Millisecond}
+
+	// failing answers the first len(statuses) calls with those statuses,
+	// asking for no delay on 429, then succeeds
+	failing := func(calls *int, statuses ...int) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*calls++
+			if *calls <= len(statuses) {
+				if statuses[*calls-1] == http.StatusTooManyRequests {
+					w.Header().Set("Retry-After", "0")
+				}
+				http.Error(w, "try later", statuses[*calls-1])
+				return
+			}
+			w.Write([]byte(`{"ok":true}`))
+		}))
+	}
+
+	t.Run("Given rate limits and server errors, When posting, Then retries until success", func(t *testing.T) {
+		calls := 0
+		srv := failing(&calls, http.StatusTooManyRequests, http.StatusServiceUnavailable)
+		defer srv.Close()
+
+		body, err := fast.PostJSON(ctx, srv.Client(), srv.URL, []byte(`{}`), nil)
+		if err != nil {
+			t.Fatalf("PostJSON: %v", err)
+		}
+		if string(body) != `{"ok":true}` || calls != 3 {
+			t.Errorf("got %s after %d calls, want success on the third", body, calls)
+		}
+	})
+
+	t.Run("Given persistent rate limits, When posting, Then gives up after max retries", func(t *testing.T) {
+		calls := 0
+		srv := failing(&calls, 429, 429, 429, 429)
+		defer srv.Close()
+
+		_, err := fast.PostJSON(ctx, srv.Client(), srv.URL, []byte(`{}`), nil)
+		if err == nil || !strings.Contains(err.Error(), "429") {
+			t.Fatalf("expected a 429 error, got %v", err)
+		}
+		if calls != 3 {
+			t.Errorf("expected 3 calls, got %d", calls)
+		}
+	})
+
+	t.Run("Given client error, When posting, Then does not retry", func(t *testing.T) {
+		calls := 0
+		srv := failing(&calls, http.StatusBadRequest)
+		defer srv.Close()
+
+		_, err := fast.PostJSON(ctx, srv.Client(), srv.URL, []byte(`{}`), nil)
+		if err == nil || !strings.Contains(err.Error(), "400") {
+			t.Fatalf("expected 400 error, got %v", err)
+		}
+		if calls != 1 {
+			t.Errorf("expected 1 call, got %d", calls)
+		}
+	})
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"", -1},
+		{"abc", -1},
+		{"0", 0},
+		{"2", 2 * time.Second},
+		{"0.5", 500 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.in); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/anthropics/aef/codex/internal/httpretry"
+)
+
+const (
+	defaultAnthropicURL   = "https://api.anthropic.com/v1/messages"
+	defaultAnthropicModel = "claude-haiku-4-5"
+	anthropicVersion      = "2023-06-01"
+)
+
+// AnthropicClient completes prompts with the Anthropic Messages API.
+type AnthropicClient struct {
+	apiKey string
+	url    string
+	model  string
+	client *http.Client
+	retry  httpretry.Policy
+}
+
+// AnthropicOption configures an AnthropicClient.
+type AnthropicOption func(*AnthropicClient)
+
+// WithAnthropicURL sets the Messages API endpoint.
+func WithAnthropicURL(url string) AnthropicOption {
+	return func(c *AnthropicClient) { c.url = url }
+}
+
+// WithAnthropicModel sets the model name.
+func WithAnthropicModel(model string) AnthropicOption {
+	return func(c *AnthropicClient) { c.model = model }
+}
+
+// NewAnthropicClient creates a Messages API client. Defaults to Claude Haiku.
+func NewAnthropicClient(apiKey string, opts ...AnthropicOption) (*AnthropicClient, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("ANTHROPIC_API_KEY not set")
+	}
+	c := &AnthropicClient{
+		apiKey: apiKey,
+		url:    defaultAnthropicURL,
+		model:  defaultAnthropicModel,
+		client: &http.Client{Timeout: 60 * time.Second},
+		retry:  httpretry.Default(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicRequest struct {
+	Model     string             `json:"model"`
+	MaxTokens int                `json:"max_tokens"`
+	Messages  []anthropicMessage `json:"messages"`
+}
+
+type anthropicResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+}
+
+// Complete sends prompt as a single user message.
+func (c *AnthropicClient) Complete(ctx context.Context, prompt string, maxTokens int) (string, error) {
+	body, err := json.Marshal(anthropicRequest{
+		Model:     c.model,
+		MaxTokens: maxTokens,
+		Messages:  []anthropicMessage{{Role: "user", Content: prompt}},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	respBody, err := c.retry.PostJSON(ctx, c.client, c.url, body, map[string]string{
+		"x-api-key":         c.apiKey,
+		"anthropic-version": anthropicVersion,
+	})
+	if err != nil {
+		return "", fmt.Errorf("anthropic: %w", err)
+	}
+
+	var resp anthropicResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return "", fmt.Errorf("anthropic: failed to decode response: %w", err)
+	}
+	var sb strings.Builder
+	for _, block := range resp.Content {
+		if block.Type == "text" {
+			sb.WriteString(block.Text)
+		}
+	}
+	return strings.TrimSpace(sb.String()), nil
+}
+
+// Model returns the model name.
+func (c *AnthropicClient) Model() string {
+	return c.model
+}
@@ -0,0 +1,15 @@
+// Package llm provides minimal text-completion clients used for indexing-time
+// enrichment (e.g. contextual chunk descriptions).
+package llm
+
+import "context"
+
+// Client generates text completions.
+// Implementations: AnthropicClient (Messages API), OllamaClient (/api/generate)
+type Client interface {
+	// Complete returns the model's response to a single user prompt.
+	Complete(ctx context.Context, prompt string, maxTokens int) (string, error)
+
+	// Model returns the model name. Callers use it to key cached responses.
+	Model() string
+}
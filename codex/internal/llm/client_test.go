@@ -0,0 +1,138 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthropics/aef/codex/internal/httpretry"
+	"github.com/anthropics/aef/codex/internal/llm/llmtest"
+)
+
+func echoResponder(prompt string) string {
+	return "  context for: " + prompt + "\n"
+}
+
+// =============================================================================
+// AnthropicClient Tests
+// =============================================================================
+
+func TestAnthropicClient_Complete(t *testing.T) {
+	srv := llmtest.NewServer(echoResponder)
+	defer srv.Close()
+
+	t.Run("Given stand-in server, When completing, Then returns trimmed text", func(t *testing.T) {
+		c, err := NewAnthropicClient("test-key", WithAnthropicURL(srv.AnthropicURL()))
+		if err != nil {
+			t.Fatalf("NewAnthropicClient: %v", err)
+		}
+
+		got, err := c.Complete(context.Background(), "hello", 100)
+		if err != nil {
+			t.Fatalf("Complete: %v", err)
+		}
+		if got != "context for: hello" {
+			t.Errorf("got %q, want %q", got, "context for: hello")
+		}
+	})
+
+	t.Run("Given empty API key, When creating client, Then returns error", func(t *testing.T) {
+		if _, err := NewAnthropicClient(""); err == nil {
+			t.Error("expected error for empty API key")
+		}
+	})
+
+	t.Run("Given model option, When reading Model, Then returns it", func(t *testing.T) {
+		c, _ := NewAnthropicClient("k", WithAnthropicModel("claude-test"))
+		if c.Model() != "claude-test" {
+			t.Errorf("Model() = %q, want claude-test", c.Model())
+		}
+	})
+}
+
+// =============================================================================
+// OllamaClient Tests
+// =============================================================================
+
+func TestOllamaClient_Complete(t *testing.T) {
+	srv := llmtest.NewServer(echoResponder)
+	defer srv.Close()
+
+	c := NewOllamaClient(WithOllamaURL(srv.OllamaURL()), WithOllamaModel("tiny"))
+
+	got, err := c.Complete(context.Background(), "hello", 50)
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if got != "context for: hello" {
+		t.Errorf("got %q, want %q", got, "context for: hello")
+	}
+	if c.Model() != "tiny" {
+		t.Errorf("Model() = %q, want tiny", c.Model())
+	}
+}
+
+// =============================================================================
+// Retry Tests
+// =============================================================================
+
+func TestRetryPolicy(t *testing.T) {
+	t.Run("Given rate limits, When completing, Then retries until success", func(t *testing.T) {
+		srv := llmtest.NewServer(echoResponder)
+		defer srv.Close()
+		srv.RateLimitNext(2)
+
+		c := NewOllamaClient(WithOllamaURL(srv.OllamaURL()))
+		c.retry.InitialDelay = time.Millisecond
+
+		got, err := c.Complete(context.Background(), "x", 10)
+		if err != nil {
+			t.Fatalf("Complete: %v", err)
+		}
+		if got != "context for: x" {
+			t.Errorf("got %q", got)
+		}
+		if srv.Calls() != 3 {
+			t.Errorf("expected 3 calls (2 rate limited), got %d", srv.Calls())
+		}
+	})
+
+	t.Run("Given persistent rate limits, When completing, Then gives up after max retries", func(t *testing.T) {
+		srv := llmtest.NewServer(echoResponder)
+		defer srv.Close()
+		srv.RateLimitNext(100)
+
+		c := NewOllamaClient(WithOllamaURL(srv.OllamaURL()))
+		c.retry = httpretry.Policy{MaxRetries: 3, InitialDelay: time.Millisecond}
+
+		if _, err := c.Complete(context.Background(), "x", 10); err == nil {
+			t.Fatal("expected error")
+		}
+		if srv.Calls() != 3 {
+			t.Errorf("expected 3 calls, got %d", srv.Calls())
+		}
+	})
+
+	t.Run("Given client error, When completing, Then does not retry", func(t *testing.T) {
+		calls := 0
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			http.Error(w, "bad request", http.StatusBadRequest)
+		}))
+		defer srv.Close()
+
+		c, _ := NewAnthropicClient("k", WithAnthropicURL(srv.URL))
+		c.retry.InitialDelay = time.Millisecond
+
+		_, err := c.Complete(context.Background(), "x", 10)
+		if err == nil || !strings.Contains(err.Error(), "400") {
+			t.Fatalf("expected 400 error, got %v", err)
+		}
+		if calls != 1 {
+			t.Errorf("expected 1 call, got %d", calls)
+		}
+	})
+}
@@ -0,0 +1,162 @@
+// Package llmtest provides an offline HTTP stand-in for the completion APIs
+// used by package llm, so enrichment can be exercised without network access.
+package llmtest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// Responder returns the completion text for a prompt.
+type Responder func(prompt string) string
+
+// Server serves POST /v1/messages (Anthropic) and POST /api/generate (Ollama).
+type Server struct {
+	*httptest.Server
+
+	respond Responder
+
+	mu          sync.Mutex
+	prompts     []string
+	rateLimited int
+
+	calls     atomic.Int64
+	inFlight  atomic.Int64
+	maxFlight atomic.Int64
+}
+
+// NewServer starts a stand-in server answering each prompt with respond.
+// Call Close when done.
+func NewServer(respond Responder) *Server {
+	s := &Server{respond: respond}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/messages", s.handleMessages)
+	mux.HandleFunc("/api/generate", s.handleGenerate)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// AnthropicURL returns the stand-in Messages API endpoint.
+func (s *Server) AnthropicURL() string { return s.URL + "/v1/messages" }
+
+// OllamaURL returns the stand-in /api/generate endpoint.
+func (s *Server) OllamaURL() string { return s.URL + "/api/generate" }
+
+// RateLimitNext makes the next n requests fail with 429 and Retry-After: 0.
+func (s *Server) RateLimitNext(n int) {
+	s.mu.Lock()
+	s.rateLimited = n
+	s.mu.Unlock()
+}
+
+// Calls returns the number of requests received, including rate-limited ones.
+func (s *Server) Calls() int { return int(s.calls.Load()) }
+
+// MaxInFlight returns the highest number of concurrent requests observed.
+func (s *Server) MaxInFlight() int { return int(s.maxFlight.Load()) }
+
+// Prompts returns the prompts answered so far.
+func (s *Server) Prompts() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.prompts...)
+}
+
+// begin records a request and reports whether it should be rate limited.
+func (s *Server) begin(w http.ResponseWriter) bool {
+	s.calls.Add(1)
+	n := s.inFlight.Add(1)
+	for {
+		max := s.maxFlight.Load()
+		if n <= max || s.maxFlight.CompareAndSwap(max, n) {
+			break
+		}
+	}
+
+	s.mu.Lock()
+	limited := s.rateLimited > 0
+	if limited {
+		s.rateLimited--
+	}
+	s.mu.Unlock()
+
+	if limited {
+		w.Header().Set("Retry-After", strconv.Itoa(0))
+		http.Error(w, `{"type":"error","error":{"type":"rate_limit_error"}}`, http.StatusTooManyRequests)
+		return false
+	}
+	return true
+}
+
+func (s *Server) end() { s.inFlight.Add(-1) }
+
+func (s *Server) answer(prompt string) string {
+	s.mu.Lock()
+	s.prompts = append(s.prompts, prompt)
+	s.mu.Unlock()
+	return s.respond(prompt)
+}
+
+func (s *Server) handleMessages(w http.ResponseWriter, r *http.Request) {
+	defer s.end()
+	if !s.begin(w) {
+		return
+	}
+	if r.Header.Get("x-api-key") == "" || r.Header.Get("anthropic-version") == "" {
+		http.Error(w, `{"type":"error","error":{"type":"authentication_error"}}`, http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		Messages []struct {
+			Content string `json:"content"`
+		} `json:"messages"`
+	}
+	if !decode(w, r.Body, &req) || len(req.Messages) == 0 {
+		return
+	}
+
+	text := s.answer(req.Messages[len(req.Messages)-1].Content)
+	writeJSON(w, map[string]any{
+		"type":    "message",
+		"role":    "assistant",
+		"content": []map[string]string{{"type": "text", "text": text}},
+	})
+}
+
+func (s *Server) handleGenerate(w http.ResponseWriter, r *http.Request) {
+	defer s.end()
+	if !s.begin(w) {
+		return
+	}
+
+	var req struct {
+		Prompt string `json:"prompt"`
+	}
+	if !decode(w, r.Body, &req) {
+		return
+	}
+
+	writeJSON(w, map[string]any{
+		"response": s.answer(req.Prompt),
+		"done":     true,
+	})
+}
+
+func decode(w http.ResponseWriter, body io.Reader, v any) bool {
+	if err := json.NewDecoder(body).Decode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,96 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/anthropics/aef/codex/internal/httpretry"
+)
+
+const (
+	defaultOllamaURL   = "http://localhost:11434/api/generate"
+	defaultOllamaModel = "llama3.2"
+)
+
+// OllamaClient completes prompts with an Ollama-compatible /api/generate endpoint.
+type OllamaClient struct {
+	url    string
+	model  string
+	client *http.Client
+	retry  httpretry.Policy
+}
+
+// OllamaOption configures an OllamaClient.
+type OllamaOption func(*OllamaClient)
+
+// WithOllamaURL sets the /api/generate endpoint.
+func WithOllamaURL(url string) OllamaOption {
+	return func(c *OllamaClient) { c.url = url }
+}
+
+// WithOllamaModel sets the model name.
+func WithOllamaModel(model string) OllamaOption {
+	return func(c *OllamaClient) { c.model = model }
+}
+
+// NewOllamaClient creates a generation client. Defaults to localhost:11434
+// with llama3.2.
+func NewOllamaClient(opts ...OllamaOption) *OllamaClient {
+	c := &OllamaClient{
+		url:    defaultOllamaURL,
+		model:  defaultOllamaModel,
+		client: &http.Client{Timeout: 120 * time.Second},
+		retry:  httpretry.Default(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+type ollamaGenerateRequest struct {
+	Model   string         `json:"model"`
+	Prompt  string         `json:"prompt"`
+	Stream  bool           `json:"stream"`
+	Options map[string]any `json:"options,omitempty"`
+}
+
+type ollamaGenerateResponse struct {
+	Response string `json:"response"`
+}
+
+// Complete generates a non-streaming response to prompt.
+func (c *OllamaClient) Complete(ctx context.Context, prompt string, maxTokens int) (string, error) {
+	req := ollamaGenerateRequest{
+		Model:  c.model,
+		Prompt: prompt,
+		Stream: false,
+	}
+	if maxTokens > 0 {
+		req.Options = map[string]any{"num_predict": maxTokens}
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	respBody, err := c.retry.PostJSON(ctx, c.client, c.url, body, nil)
+	if err != nil {
+		return "", fmt.Errorf("ollama: %w", err)
+	}
+
+	var resp ollamaGenerateResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return "", fmt.Errorf("ollama: failed to decode response: %w", err)
+	}
+	return strings.TrimSpace(resp.Response), nil
+}
+
+// Model returns the model name.
+func (c *OllamaClient) Model() string {
+	return c.model
+}
@@ -0,0 +1,230 @@
+package loader
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+type htmlLoader struct{}
+
+// HTML returns the loader of HTML pages, such as wiki exports and saved
+// documentation. The page is split into sections at its h1-h6 headings,
+// with breadcrumbs like Markdown documents. Scripts, styles, navigation
+// and footers are left out; tables become one row per line.
+func HTML() Loader {
+	return htmlLoader{}
+}
+
+func (htmlLoader) Name() string                           { return "html" }
+func (htmlLoader) Extensions() []string                   { return []string{".html", ".htm"} }
+func (htmlLoader) Match(path string, content []byte) bool { return true }
+
+func (htmlLoader) Load(path string, content []byte) (*Document, error) {
+	root, err := html.Parse(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+	b := &htmlBuilder{}
+	b.walk(root)
+	b.endSection()
+
+	// Without headings the whole page is one section
+	if !b.sawHeading && len(b.sections) > 0 {
+		for i := range b.sections {
+			b.sections[i].Title = "(Document)"
+		}
+	}
+	return &Document{Title: b.title, Sections: b.sections}, nil
+}
+
+// htmlSkip lists the elements whose content is not indexed.
+var htmlSkip = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+	atom.Svg: true, atom.Nav: true, atom.Footer: true, atom.Iframe: true,
+}
+
+// htmlBlocks lists the elements that start and end a block of text.
+var htmlBlocks = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true,
+	atom.Main: true, atom.Header: true, atom.Aside: true, atom.Blockquote: true,
+	atom.Ul: true, atom.Ol: true, atom.Dl: true, atom.Dt: true, atom.Dd: true,
+	atom.Figure: true, atom.Figcaption: true, atom.Form: true, atom.Hr: true,
+	atom.Body: true, atom.Details: true, atom.Summary: true,
+}
+
+// htmlHeadingLevels maps heading elements to their level.
+var htmlHeadingLevels = map[atom.Atom]int{
+	atom.H1: 1, atom.H2: 2, atom.H3: 3, atom.H4: 4, atom.H5: 5, atom.H6: 6,
+}
+
+// htmlBuilder collects the sections of a page as it walks the tree.
+type htmlBuilder struct {
+	title      string
+	sections   []Section
+	current    *Section
+	blocks     []string // blocks of the current section
+	text       strings.Builder
+	path       []string // titles of the enclosing headings by level, "" for skipped levels
+	sawHeading bool
+}
+
+func (b *htmlBuilder) walk(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		b.text.WriteString(strings.ReplaceAll(n.Data, "\n", " "))
+		return
+	case html.ElementNode:
+		if htmlSkip[n.DataAtom] {
+			return
+		}
+		if level, ok := htmlHeadingLevels[n.DataAtom]; ok {
+			b.endBlock()
+			b.startSection(level, strings.Join(strings.Fields(nodeText(n)), " "))
+			return
+		}
+		switch n.DataAtom {
+		case atom.Title:
+			if b.title == "" {
+				b.title = strings.Join(strings.Fields(nodeText(n)), " ")
+			}
+			return
+		case atom.Br:
+			b.text.WriteString("\n")
+			return
+		case atom.Pre:
+			b.endBlock()
+			b.addBlock(strings.Trim(nodeText(n), "\n"))
+			return
+		case atom.Table:
+			b.endBlock()
+			b.addBlock(tableText(n))
+			return
+		case atom.Li:
+			b.endBlock()
+			b.text.WriteString("- ")
+			b.walkChildren(n)
+			b.endBlock()
+			return
+		}
+		if htmlBlocks[n.DataAtom] {
+			b.endBlock()
+			b.walkChildren(n)
+			b.endBlock()
+			return
+		}
+	}
+	b.walkChildren(n)
+}
+
+func (b *htmlBuilder) walkChildren(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.walk(c)
+	}
+}
+
+// endBlock adds the text collected so far as a block.
+func (b *htmlBuilder) endBlock() {
+	text := cleanText(b.text.String())
+	b.text.Reset()
+	if text != "" && text != "-" {
+		b.addBlock(text)
+	}
+}
+
+func (b *htmlBuilder) addBlock(text string) {
+	if strings.TrimSpace(text) == "" {
+		return
+	}
+	if b.current == nil {
+		b.current = &Section{Title: "(Introduction)"}
+	}
+	b.blocks = append(b.blocks, text)
+}
+
+// startSection ends the current section and starts one under a heading.
+func (b *htmlBuilder) startSection(level int, title string) {
+	b.endSection()
+	b.sawHeading = true
+	if len(b.path) >= level {
+		b.path = b.path[:level-1]
+	}
+	for len(b.path) < level-1 {
+		b.path = append(b.path, "")
+	}
+	b.path = append(b.path, title)
+
+	var headings []string
+	for _, h := range b.path {
+		if h != "" {
+			headings = append(headings, h)
+		}
+	}
+	b.current = &Section{
+		Title:    title,
+		Headings: headings,
+		Metadata: map[string]any{"heading_level": level},
+	}
+}
+
+// endSection adds the current section, split into parts when it is long.
+// Headings without text under them are left out.
+func (b *htmlBuilder) endSection() {
+	b.endBlock()
+	if b.current != nil && len(b.blocks) > 0 {
+		b.current.Content = strings.Join(b.blocks, "\n\n")
+		b.sections = append(b.sections, splitSection(*b.current, b.blocks, "\n\n")...)
+	}
+	b.current = nil
+	b.blocks = nil
+}
+
+// nodeText returns the text of an element and its descendants, leaving
+// out skipped elements.
+func nodeText(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		switch {
+		case n.Type == html.TextNode:
+			sb.WriteString(n.Data)
+		case n.Type == html.ElementNode && htmlSkip[n.DataAtom]:
+		case n.Type == html.ElementNode && n.DataAtom == atom.Br:
+			sb.WriteString("\n")
+		default:
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				walk(c)
+			}
+		}
+	}
+	walk(n)
+	return sb.String()
+}
+
+// tableText renders a table one row per line, with cells separated by " | ".
+func tableText(table *html.Node) string {
+	var rows []string
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.Tr {
+			var cells []string
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				if c.Type == html.ElementNode && (c.DataAtom == atom.Td || c.DataAtom == atom.Th) {
+					cells = append(cells, strings.Join(strings.Fields(nodeText(c)), " "))
+				}
+			}
+			if len(cells) > 0 {
+				rows = append(rows, strings.Join(cells, " | "))
+			}
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(table)
+	return strings.Join(rows, "\n")
+}
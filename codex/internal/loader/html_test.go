@@ -0,0 +1,48 @@
+package loader
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHTML(t *testing.T) {
+	t.Run("Given a page with headings, When loading, Then it is split per heading with breadcrumbs", func(t *testing.T) {
+		_, doc := loadFixture(t, "guide.html")
+		if doc.Title != "Payments guide" {
+			t.Errorf("title = %q", doc.Title)
+		}
+		want := []string{"(Introduction)", "Payments", "Payments > Rollback", "Payments > Rollback > Limits", "Payments > Refunds"}
+		if got := breadcrumbs(doc); !reflect.DeepEqual(got, want) {
+			t.Fatalf("breadcrumbs = %q, want %q", got, want)
+		}
+
+		intro, payments, rollback, limits, refunds := doc.Sections[0], doc.Sections[1], doc.Sections[2], doc.Sections[3], doc.Sections[4]
+		if intro.Content != "Read this before going on call." {
+			t.Errorf("intro = %q, want navigation, scripts and styles left out", intro.Content)
+		}
+		if payments.Content != "The payments service charges cards through the gateway." {
+			t.Errorf("payments = %q", payments.Content)
+		}
+		if !strings.Contains(rollback.Content, "- Run make rollback.\n\n- Check the dashboard.") ||
+			!strings.Contains(rollback.Content, "deploy/payments\nkubectl rollout status") {
+			t.Errorf("rollback = %q, want list items and the pre block", rollback.Content)
+		}
+		if limits.Content != "Plan | Limit\nFree | 10/s\nPro | 100/s" {
+			t.Errorf("limits = %q", limits.Content)
+		}
+		if refunds.Content != "Refunds take five days.\nPartial refunds are allowed." || refunds.Metadata["heading_level"] != 2 {
+			t.Errorf("refunds = %q %v, want the footer left out", refunds.Content, refunds.Metadata)
+		}
+	})
+
+	t.Run("Given a page without headings, When loading, Then it is one document section", func(t *testing.T) {
+		doc, err := HTML().Load("page.html", []byte("<p>one</p><p>two</p>"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(doc.Sections) != 1 || doc.Sections[0].Title != "(Document)" || doc.Sections[0].Content != "one\n\ntwo" {
+			t.Errorf("sections = %+v", doc.Sections)
+		}
+	})
+}
@@ -0,0 +1,178 @@
+// Package loader converts documents that are not plain text, such as PDFs,
+// HTML pages, OpenAPI specs and Jupyter notebooks, into text sections the
+// indexer chunks and embeds. Each format has a Loader; a Registry picks the
+// loader for a file by its extension and content.
+package loader
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/anthropics/aef/codex/internal/chunking"
+)
+
+// Section is a piece of a loaded document that is indexed as one chunk:
+// a PDF page, the text under an HTML heading, an API endpoint or a
+// notebook cell.
+type Section struct {
+	Title    string
+	Headings []string // path from the document root down to the section, Title included
+	Content  string
+
+	// Location of the section in the source, where the format has one
+	StartLine int // 1-based, 0 when unknown
+	EndLine   int
+	Page      int // 1-based PDF page, 0 otherwise
+
+	// Metadata is stored with the section's chunk, e.g. "http_method" or "cell_type".
+	Metadata map[string]any
+}
+
+// Breadcrumb returns the section's heading path, or its title without one.
+func (s Section) Breadcrumb() string {
+	if len(s.Headings) == 0 {
+		return s.Title
+	}
+	return strings.Join(s.Headings, chunking.BreadcrumbSeparator)
+}
+
+// Document is the text of a loaded file, split into sections.
+type Document struct {
+	Title    string // "" when the format has none
+	Sections []Section
+	Metadata map[string]any // stored with every chunk of the document
+}
+
+// Loader converts one document format to sections.
+type Loader interface {
+	// Name identifies the loader, e.g. "pdf". It is recorded in the
+	// metadata of the chunks it produced.
+	Name() string
+
+	// Extensions lists the lower-case file extensions the loader may
+	// handle, e.g. ".pdf".
+	Extensions() []string
+
+	// Match reports whether the loader handles a file with one of its
+	// extensions, e.g. whether a .yaml file is an OpenAPI spec.
+	Match(path string, content []byte) bool
+
+	// Load converts the content of a file to sections.
+	Load(path string, content []byte) (*Document, error)
+}
+
+// Registry holds the loaders the indexer uses. When several loaders take
+// the same extension, the first registered that matches a file wins.
+type Registry struct {
+	loaders []Loader
+	byExt   map[string][]Loader
+}
+
+// NewRegistry creates a registry with the given loaders.
+func NewRegistry(loaders ...Loader) *Registry {
+	r := &Registry{byExt: make(map[string][]Loader)}
+	for _, l := range loaders {
+		r.Register(l)
+	}
+	return r
+}
+
+// Default returns a registry with the built-in loaders: PDF, HTML,
+// OpenAPI and Jupyter notebooks.
+func Default() *Registry {
+	return NewRegistry(PDF(), HTML(), OpenAPI(), Notebook())
+}
+
+// Register adds a loader to the registry.
+func (r *Registry) Register(l Loader) {
+	r.loaders = append(r.loaders, l)
+	for _, ext := range l.Extensions() {
+		r.byExt[ext] = append(r.byExt[ext], l)
+	}
+}
+
+// Loaders returns the registered loaders in order.
+func (r *Registry) Loaders() []Loader {
+	return r.loaders
+}
+
+// Handles reports whether a loader takes files with path's extension. The
+// content decides whether one of them actually loads the file; see Lookup.
+func (r *Registry) Handles(path string) bool {
+	return len(r.byExt[ext(path)]) > 0
+}
+
+// Lookup returns the loader for a file, or nil when none handles it.
+func (r *Registry) Lookup(path string, content []byte) Loader {
+	for _, l := range r.byExt[ext(path)] {
+		if l.Match(path, content) {
+			return l
+		}
+	}
+	return nil
+}
+
+// MaxSectionSize is the size in bytes above which a section is split into
+// parts, like the indexer's Markdown chunks.
+const MaxSectionSize = 2000
+
+// splitSection splits a section longer than MaxSectionSize into parts
+// between blocks, e.g. the lines of a PDF page or the paragraphs under an
+// HTML heading, joined by sep. A block is never split. Parts keep the
+// section's title, headings and location and get metadata part and parts.
+func splitSection(s Section, blocks []string, sep string) []Section {
+	if len(s.Content) <= MaxSectionSize {
+		return []Section{s}
+	}
+
+	var contents []string
+	var part strings.Builder
+	for _, block := range blocks {
+		if part.Len() > 0 && part.Len()+len(sep)+len(block) > MaxSectionSize {
+			contents = append(contents, part.String())
+			part.Reset()
+		}
+		if part.Len() > 0 {
+			part.WriteString(sep)
+		}
+		part.WriteString(block)
+	}
+	if part.Len() > 0 {
+		contents = append(contents, part.String())
+	}
+
+	parts := make([]Section, len(contents))
+	for i, content := range contents {
+		parts[i] = s
+		parts[i].Content = content
+		parts[i].Metadata = map[string]any{"part": i + 1, "parts": len(contents)}
+		for k, v := range s.Metadata {
+			parts[i].Metadata[k] = v
+		}
+	}
+	return parts
+}
+
+// cleanText collapses runs of spaces within lines and of blank lines, and
+// trims the text.
+func cleanText(text string) string {
+	var lines []string
+	blank := false
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" {
+			blank = len(lines) > 0
+			continue
+		}
+		if blank {
+			lines = append(lines, "")
+			blank = false
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func ext(path string) string {
+	return strings.ToLower(filepath.Ext(path))
+}
@@ -0,0 +1,96 @@
+package loader
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// loadFixture loads a file of testdata with the loader the default
+// registry picks for it.
+func loadFixture(t *testing.T, name string) (Loader, *Document) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := Default().Lookup(path, content)
+	if l == nil {
+		t.Fatalf("no loader for %s", name)
+	}
+	doc, err := l.Load(path, content)
+	if err != nil {
+		t.Fatalf("Load(%s) failed: %v", name, err)
+	}
+	return l, doc
+}
+
+// breadcrumbs returns the breadcrumbs of a document's sections.
+func breadcrumbs(doc *Document) []string {
+	var got []string
+	for _, s := range doc.Sections {
+		got = append(got, s.Breadcrumb())
+	}
+	return got
+}
+
+func TestRegistry(t *testing.T) {
+	r := Default()
+
+	t.Run("Given the default registry, When looking up files, Then each format gets its loader", func(t *testing.T) {
+		cases := map[string]string{
+			"handbook.pdf":   "pdf",
+			"guide.html":     "html",
+			"payments.yaml":  "openapi",
+			"petstore.json":  "openapi",
+			"analysis.ipynb": "notebook",
+		}
+		for name, want := range cases {
+			content, err := os.ReadFile(filepath.Join("testdata", name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if l := r.Lookup(strings.ToUpper(name), content); l == nil || l.Name() != want {
+				t.Errorf("Lookup(%s) = %v, want %s", name, l, want)
+			}
+		}
+	})
+
+	t.Run("Given an extension a loader takes but content it does not, When looking up, Then no loader is returned", func(t *testing.T) {
+		if !r.Handles("config.yaml") {
+			t.Error("Handles(config.yaml) = false")
+		}
+		if l := r.Lookup("config.yaml", []byte("replicas: 3\n")); l != nil {
+			t.Errorf("Lookup(config.yaml) = %s, want nil", l.Name())
+		}
+		if l := r.Lookup("fake.pdf", []byte("not a pdf")); l != nil {
+			t.Errorf("Lookup(fake.pdf) = %s, want nil", l.Name())
+		}
+		if r.Handles("main.go") {
+			t.Error("Handles(main.go) = true")
+		}
+	})
+}
+
+func TestSplitSection(t *testing.T) {
+	t.Run("Given a long section, When splitting, Then parts stay under the limit between blocks", func(t *testing.T) {
+		block := strings.Repeat("x", 900)
+		blocks := []string{block, block, block, block}
+		s := Section{Title: "Long", Content: strings.Join(blocks, "\n\n"), Metadata: map[string]any{"k": "v"}}
+
+		parts := splitSection(s, blocks, "\n\n")
+		if len(parts) != 2 {
+			t.Fatalf("got %d parts, want 2", len(parts))
+		}
+		for i, p := range parts {
+			if len(p.Content) > MaxSectionSize || p.Title != "Long" || p.Metadata["part"] != i+1 || p.Metadata["parts"] != 2 || p.Metadata["k"] != "v" {
+				t.Errorf("part %d = %q (%d bytes) %v", i, p.Title, len(p.Content), p.Metadata)
+			}
+		}
+		if s.Metadata["part"] != nil {
+			t.Error("section metadata was modified")
+		}
+	})
+}
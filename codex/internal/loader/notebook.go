@@ -0,0 +1,196 @@
+package loader
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// MaxCellOutput caps the output text kept with a notebook code cell.
+const MaxCellOutput = 1000
+
+type notebookLoader struct{}
+
+// Notebook returns the loader of Jupyter notebooks. Each markdown or code
+// cell is a section; code cells carry their text outputs, truncated to
+// MaxCellOutput bytes. Markdown headings give the cells below them a
+// breadcrumb, e.g. "Analysis > Cleaning > Cell 4".
+func Notebook() Loader {
+	return notebookLoader{}
+}
+
+func (notebookLoader) Name() string                           { return "notebook" }
+func (notebookLoader) Extensions() []string                   { return []string{".ipynb"} }
+func (notebookLoader) Match(path string, content []byte) bool { return true }
+
+// notebook holds the fields of an nbformat 4 notebook that are indexed.
+type notebook struct {
+	Cells    []notebookCell `json:"cells"`
+	Metadata struct {
+		Kernelspec struct {
+			Language string `json:"language"`
+		} `json:"kernelspec"`
+		LanguageInfo struct {
+			Name string `json:"name"`
+		} `json:"language_info"`
+		Title string `json:"title"`
+	} `json:"metadata"`
+}
+
+type notebookCell struct {
+	CellType string       `json:"cell_type"`
+	Source   notebookText `json:"source"`
+	Outputs  []cellOutput `json:"outputs"`
+	Count    *int         `json:"execution_count"`
+}
+
+type cellOutput struct {
+	OutputType string                     `json:"output_type"`
+	Text       notebookText               `json:"text"`  // stream
+	Data       map[string]json.RawMessage `json:"data"`  // execute_result, display_data, by MIME type
+	EName      string                     `json:"ename"` // error
+	EValue     string                     `json:"evalue"`
+}
+
+// notebookText is multi-line text, stored as a string or a list of lines.
+type notebookText string
+
+func (t *notebookText) UnmarshalJSON(data []byte) error {
+	var lines []string
+	if err := json.Unmarshal(data, &lines); err == nil {
+		*t = notebookText(strings.Join(lines, ""))
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*t = notebookText(s)
+	return nil
+}
+
+func (notebookLoader) Load(path string, content []byte) (*Document, error) {
+	var nb notebook
+	if err := json.Unmarshal(content, &nb); err != nil {
+		return nil, fmt.Errorf("failed to parse notebook: %w", err)
+	}
+	language := nb.Metadata.LanguageInfo.Name
+	if language == "" {
+		language = nb.Metadata.Kernelspec.Language
+	}
+
+	doc := &Document{Title: nb.Metadata.Title}
+	if language != "" {
+		doc.Metadata = map[string]any{"language": language}
+	}
+
+	var headingPath []string // titles of the enclosing markdown headings by level
+	for i, cell := range nb.Cells {
+		source := strings.TrimSpace(string(cell.Source))
+		if source == "" || (cell.CellType != "markdown" && cell.CellType != "code") {
+			continue
+		}
+
+		if cell.CellType == "markdown" {
+			for _, h := range markdownHeadings(source) {
+				if len(headingPath) >= h.level {
+					headingPath = headingPath[:h.level-1]
+				}
+				for len(headingPath) < h.level-1 {
+					headingPath = append(headingPath, "")
+				}
+				headingPath = append(headingPath, h.title)
+				if doc.Title == "" && h.level == 1 {
+					doc.Title = h.title
+				}
+			}
+		}
+
+		title := fmt.Sprintf("Cell %d", i+1)
+		var headings []string
+		for _, h := range headingPath {
+			if h != "" {
+				headings = append(headings, h)
+			}
+		}
+		meta := map[string]any{"cell_index": i, "cell_type": cell.CellType}
+
+		text := source
+		if cell.CellType == "code" {
+			if language != "" {
+				text = "```" + language + "\n" + source + "\n```"
+			}
+			if out := cellOutputText(cell.Outputs); out != "" {
+				text += "\n\nOutput:\n" + out
+			}
+			if cell.Count != nil {
+				meta["execution_count"] = *cell.Count
+			}
+		}
+		doc.Sections = append(doc.Sections, Section{
+			Title:    title,
+			Headings: append(headings, title),
+			Content:  text,
+			Metadata: meta,
+		})
+	}
+	return doc, nil
+}
+
+// cellOutputText returns the text outputs of a code cell: streams, plain
+// text results and errors, truncated to MaxCellOutput bytes.
+func cellOutputText(outputs []cellOutput) string {
+	var parts []string
+	for _, out := range outputs {
+		var text string
+		switch out.OutputType {
+		case "stream":
+			text = string(out.Text)
+		case "execute_result", "display_data":
+			var plain notebookText
+			if raw, ok := out.Data["text/plain"]; ok && json.Unmarshal(raw, &plain) == nil {
+				text = string(plain)
+			}
+		case "error":
+			text = out.EName + ": " + out.EValue
+		}
+		if text = strings.TrimSpace(text); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	text := strings.Join(parts, "\n")
+	if len(text) > MaxCellOutput {
+		text = strings.ToValidUTF8(text[:MaxCellOutput], "") + "\n..."
+	}
+	return text
+}
+
+type markdownHeading struct {
+	level int
+	title string
+}
+
+// markdownHeadings returns the ATX headings of a markdown cell, outside
+// fenced code.
+func markdownHeadings(source string) []markdownHeading {
+	var headings []markdownHeading
+	fenced := false
+	for _, line := range strings.Split(source, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~") {
+			fenced = !fenced
+			continue
+		}
+		if fenced {
+			continue
+		}
+		level := len(line) - len(strings.TrimLeft(line, "#"))
+		if level == 0 || level > 6 || (len(line) > level && line[level] != ' ') {
+			continue
+		}
+		if title := strings.TrimSpace(strings.TrimRight(line[level:], "#")); title != "" {
+			headings = append(headings, markdownHeading{level: level, title: title})
+		}
+	}
+	return headings
+}
@@ -0,0 +1,51 @@
+package loader
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNotebook(t *testing.T) {
+	t.Run("Given a notebook, When loading, Then each non-empty cell is a section under the markdown headings above it", func(t *testing.T) {
+		_, doc := loadFixture(t, "analysis.ipynb")
+		if doc.Title != "Churn analysis" || doc.Metadata["language"] != "python" {
+			t.Errorf("doc = %q %v", doc.Title, doc.Metadata)
+		}
+		want := []string{
+			"Churn analysis > Cell 1",
+			"Churn analysis > Cell 2",
+			"Churn analysis > Cleaning > Cell 3",
+			"Churn analysis > Cleaning > Cell 4",
+		}
+		if got := breadcrumbs(doc); !reflect.DeepEqual(got, want) {
+			t.Fatalf("breadcrumbs = %q, want %q", got, want)
+		}
+
+		code := doc.Sections[3]
+		if !strings.HasPrefix(code.Content, "```python\ndf = df.dropna()") || !strings.HasSuffix(code.Content, "Output:\nrows: 1200\n0.12") {
+			t.Errorf("code cell = %q", code.Content)
+		}
+		if code.Metadata["cell_type"] != "code" || code.Metadata["cell_index"] != 3 || code.Metadata["execution_count"] != 2 {
+			t.Errorf("code cell metadata = %v", code.Metadata)
+		}
+	})
+
+	t.Run("Given long output, When loading, Then it is truncated", func(t *testing.T) {
+		out := strings.Repeat("x", 2*MaxCellOutput)
+		nb := `{"cells": [{"cell_type": "code", "source": "print(x)", "outputs": [{"output_type": "stream", "text": "` + out + `"}]}]}`
+		doc, err := Notebook().Load("x.ipynb", []byte(nb))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c := doc.Sections[0].Content; len(c) > MaxCellOutput+100 || !strings.HasSuffix(c, "\n...") {
+			t.Errorf("content is %d bytes", len(c))
+		}
+	})
+
+	t.Run("Given invalid JSON, When loading, Then it fails", func(t *testing.T) {
+		if _, err := Notebook().Load("x.ipynb", []byte("{")); err == nil {
+			t.Error("want error")
+		}
+	})
+}
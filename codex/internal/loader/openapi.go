@@ -0,0 +1,465 @@
+package loader
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type openAPILoader struct{}
+
+// OpenAPI returns the loader of OpenAPI 3 and Swagger 2 specs in YAML or
+// JSON. Each operation is a section titled by its method and path, e.g.
+// "GET /payments/{id}", holding its summary, parameters, request body and
+// responses; each schema is a section listing its properties. The spec's
+// info is the first section.
+func OpenAPI() Loader {
+	return openAPILoader{}
+}
+
+func (openAPILoader) Name() string         { return "openapi" }
+func (openAPILoader) Extensions() []string { return []string{".yaml", ".yml", ".json"} }
+
+// openAPIKeys are the top-level keys that mark a spec.
+var openAPIKeys = [][]byte{[]byte("openapi"), []byte("swagger")}
+
+// Match looks for an "openapi" or "swagger" key at the start of a line in
+// the head of the file, and then parses the file to be sure.
+func (openAPILoader) Match(path string, content []byte) bool {
+	head := content[:min(len(content), 4096)]
+	found := false
+	for _, line := range bytes.Split(head, []byte("\n")) {
+		line = bytes.TrimLeft(bytes.TrimSpace(line), `{"'`)
+		for _, key := range openAPIKeys {
+			if bytes.HasPrefix(line, key) {
+				found = true
+			}
+		}
+	}
+	if !found {
+		return false
+	}
+	var spec struct {
+		OpenAPI string `yaml:"openapi"`
+		Swagger string `yaml:"swagger"`
+	}
+	if err := yaml.Unmarshal(content, &spec); err != nil {
+		return false
+	}
+	return spec.OpenAPI != "" || spec.Swagger != ""
+}
+
+// openAPIMethods are the keys of a path item that hold operations.
+var openAPIMethods = map[string]bool{
+	"get": true, "put": true, "post": true, "delete": true,
+	"options": true, "head": true, "patch": true, "trace": true,
+}
+
+// apiInfo is the info object of a spec.
+type apiInfo struct {
+	Title       string `yaml:"title"`
+	Version     string `yaml:"version"`
+	Description string `yaml:"description"`
+}
+
+// apiOperation holds the fields of an operation that are indexed.
+type apiOperation struct {
+	Summary     string         `yaml:"summary"`
+	Description string         `yaml:"description"`
+	OperationID string         `yaml:"operationId"`
+	Tags        []string       `yaml:"tags"`
+	Deprecated  bool           `yaml:"deprecated"`
+	Parameters  []apiParameter `yaml:"parameters"`
+	RequestBody *struct {
+		Description string              `yaml:"description"`
+		Required    bool                `yaml:"required"`
+		Content     map[string]apiMedia `yaml:"content"`
+		Ref         string              `yaml:"$ref"`
+	} `yaml:"requestBody"`
+	Responses map[string]struct {
+		Description string              `yaml:"description"`
+		Content     map[string]apiMedia `yaml:"content"`
+		Schema      *apiSchema          `yaml:"schema"` // Swagger 2
+		Ref         string              `yaml:"$ref"`
+	} `yaml:"responses"`
+}
+
+type apiParameter struct {
+	Name        string     `yaml:"name"`
+	In          string     `yaml:"in"`
+	Description string     `yaml:"description"`
+	Required    bool       `yaml:"required"`
+	Type        string     `yaml:"type"` // Swagger 2
+	Schema      *apiSchema `yaml:"schema"`
+	Ref         string     `yaml:"$ref"`
+}
+
+type apiMedia struct {
+	Schema *apiSchema `yaml:"schema"`
+}
+
+type apiSchema struct {
+	Ref         string                `yaml:"$ref"`
+	Type        string                `yaml:"type"`
+	Format      string                `yaml:"format"`
+	Description string                `yaml:"description"`
+	Items       *apiSchema            `yaml:"items"`
+	Required    []string              `yaml:"required"`
+	Properties  map[string]*apiSchema `yaml:"properties"`
+	Enum        []any                 `yaml:"enum"`
+}
+
+func (openAPILoader) Load(path string, content []byte) (*Document, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI spec: %w", err)
+	}
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("OpenAPI spec is not a mapping")
+	}
+	spec := root.Content[0]
+
+	var info apiInfo
+	if n := mappingValue(spec, "info"); n != nil {
+		if err := n.Decode(&info); err != nil {
+			return nil, fmt.Errorf("invalid info: %w", err)
+		}
+	}
+	version := "openapi " + scalarValue(spec, "openapi")
+	if v := scalarValue(spec, "swagger"); v != "" {
+		version = "swagger " + v
+	}
+
+	doc := &Document{
+		Title:    info.Title,
+		Metadata: map[string]any{"api_version": info.Version, "spec_version": version},
+	}
+	heading := func(title string) []string {
+		if info.Title == "" {
+			return []string{title}
+		}
+		return []string{info.Title, title}
+	}
+
+	// The info section describes the API as a whole
+	var overview []string
+	if info.Title != "" {
+		overview = append(overview, strings.TrimSpace(info.Title+" "+info.Version))
+	}
+	if info.Description != "" {
+		overview = append(overview, strings.TrimSpace(info.Description))
+	}
+	if servers := serverURLs(spec); len(servers) > 0 {
+		overview = append(overview, "Servers: "+strings.Join(servers, ", "))
+	}
+	if len(overview) > 0 {
+		doc.Sections = append(doc.Sections, Section{
+			Title:     "Overview",
+			Headings:  heading("Overview"),
+			Content:   strings.Join(overview, "\n\n"),
+			StartLine: spec.Line,
+			EndLine:   lastLine(mappingValue(spec, "info")),
+		})
+	}
+
+	if paths := mappingValue(spec, "paths"); paths != nil && paths.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(paths.Content); i += 2 {
+			pathKey, item := paths.Content[i], paths.Content[i+1]
+			if item.Kind != yaml.MappingNode {
+				continue
+			}
+			var shared []apiParameter
+			if n := mappingValue(item, "parameters"); n != nil {
+				n.Decode(&shared)
+			}
+			for j := 0; j+1 < len(item.Content); j += 2 {
+				methodKey, opNode := item.Content[j], item.Content[j+1]
+				method := strings.ToLower(methodKey.Value)
+				if !openAPIMethods[method] {
+					continue
+				}
+				var op apiOperation
+				if err := opNode.Decode(&op); err != nil {
+					return nil, fmt.Errorf("invalid operation %s %s: %w", strings.ToUpper(method), pathKey.Value, err)
+				}
+				op.Parameters = append(slices.Clip(shared), op.Parameters...)
+
+				title := strings.ToUpper(method) + " " + pathKey.Value
+				meta := map[string]any{"http_method": strings.ToUpper(method), "http_path": pathKey.Value}
+				if op.OperationID != "" {
+					meta["operation_id"] = op.OperationID
+				}
+				if len(op.Tags) > 0 {
+					meta["api_tags"] = op.Tags
+				}
+				doc.Sections = append(doc.Sections, Section{
+					Title:     title,
+					Headings:  heading(title),
+					Content:   operationText(title, op),
+					StartLine: methodKey.Line,
+					EndLine:   lastLine(opNode),
+					Metadata:  meta,
+				})
+			}
+		}
+	}
+
+	// Schemas live under components in OpenAPI 3 and definitions in Swagger 2
+	schemas := mappingValue(spec, "definitions")
+	if components := mappingValue(spec, "components"); components != nil {
+		schemas = mappingValue(components, "schemas")
+	}
+	if schemas != nil && schemas.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(schemas.Content); i += 2 {
+			nameKey, node := schemas.Content[i], schemas.Content[i+1]
+			var schema apiSchema
+			if err := node.Decode(&schema); err != nil {
+				return nil, fmt.Errorf("invalid schema %s: %w", nameKey.Value, err)
+			}
+			title := "Schema " + nameKey.Value
+			doc.Sections = append(doc.Sections, Section{
+				Title:     title,
+				Headings:  heading(title),
+				Content:   schemaText(nameKey.Value, &schema),
+				StartLine: nameKey.Line,
+				EndLine:   lastLine(node),
+				Metadata:  map[string]any{"schema": nameKey.Value},
+			})
+		}
+	}
+	return doc, nil
+}
+
+// operationText describes an operation in plain text.
+func operationText(title string, op apiOperation) string {
+	var sb strings.Builder
+	sb.WriteString(title)
+	if op.Deprecated {
+		sb.WriteString(" (deprecated)")
+	}
+	if op.Summary != "" {
+		sb.WriteString("\n" + strings.TrimSpace(op.Summary))
+	}
+	if op.Description != "" {
+		sb.WriteString("\n\n" + strings.TrimSpace(op.Description))
+	}
+	if op.OperationID != "" {
+		sb.WriteString("\n\nOperation ID: " + op.OperationID)
+	}
+	if len(op.Tags) > 0 {
+		sb.WriteString("\nTags: " + strings.Join(op.Tags, ", "))
+	}
+
+	if len(op.Parameters) > 0 {
+		sb.WriteString("\n\nParameters:")
+		for _, p := range op.Parameters {
+			if p.Ref != "" {
+				sb.WriteString("\n- " + refName(p.Ref))
+				continue
+			}
+			typ := p.Type
+			if p.Schema != nil {
+				typ = schemaType(p.Schema)
+			}
+			var attrs []string
+			for _, a := range []string{p.In, typ} {
+				if a != "" {
+					attrs = append(attrs, a)
+				}
+			}
+			if p.Required {
+				attrs = append(attrs, "required")
+			}
+			sb.WriteString("\n- " + p.Name)
+			if len(attrs) > 0 {
+				sb.WriteString(" (" + strings.Join(attrs, ", ") + ")")
+			}
+			if p.Description != "" {
+				sb.WriteString(": " + oneLine(p.Description))
+			}
+		}
+	}
+
+	if body := op.RequestBody; body != nil {
+		sb.WriteString("\n\nRequest body")
+		if body.Required {
+			sb.WriteString(" (required)")
+		}
+		sb.WriteString(":")
+		if body.Ref != "" {
+			sb.WriteString(" " + refName(body.Ref))
+		}
+		if body.Description != "" {
+			sb.WriteString(" " + oneLine(body.Description))
+		}
+		for _, media := range sortedKeys(body.Content) {
+			sb.WriteString("\n- " + media)
+			if s := body.Content[media].Schema; s != nil {
+				sb.WriteString(": " + schemaType(s))
+			}
+		}
+	}
+
+	if len(op.Responses) > 0 {
+		sb.WriteString("\n\nResponses:")
+		for _, code := range sortedKeys(op.Responses) {
+			resp := op.Responses[code]
+			sb.WriteString("\n- " + code)
+			if resp.Ref != "" {
+				sb.WriteString(": " + refName(resp.Ref))
+			}
+			if resp.Description != "" {
+				sb.WriteString(": " + oneLine(resp.Description))
+			}
+			var types []string
+			if resp.Schema != nil {
+				types = append(types, schemaType(resp.Schema))
+			}
+			for _, media := range sortedKeys(resp.Content) {
+				if s := resp.Content[media].Schema; s != nil {
+					types = append(types, media+" "+schemaType(s))
+				}
+			}
+			if len(types) > 0 {
+				sb.WriteString(" (" + strings.Join(types, ", ") + ")")
+			}
+		}
+	}
+	return sb.String()
+}
+
+// schemaText describes a schema and its properties in plain text.
+func schemaText(name string, s *apiSchema) string {
+	var sb strings.Builder
+	sb.WriteString("Schema " + name)
+	if t := schemaType(s); t != "" && t != "object" {
+		sb.WriteString(" (" + t + ")")
+	}
+	if s.Description != "" {
+		sb.WriteString("\n" + strings.TrimSpace(s.Description))
+	}
+	if len(s.Enum) > 0 {
+		values := make([]string, len(s.Enum))
+		for i, v := range s.Enum {
+			values[i] = fmt.Sprint(v)
+		}
+		sb.WriteString("\nValues: " + strings.Join(values, ", "))
+	}
+	if len(s.Properties) > 0 {
+		required := make(map[string]bool)
+		for _, r := range s.Required {
+			required[r] = true
+		}
+		sb.WriteString("\n\nProperties:")
+		for _, prop := range sortedKeys(s.Properties) {
+			p := s.Properties[prop]
+			var attrs []string
+			if t := schemaType(p); t != "" {
+				attrs = append(attrs, t)
+			}
+			if required[prop] {
+				attrs = append(attrs, "required")
+			}
+			sb.WriteString("\n- " + prop)
+			if len(attrs) > 0 {
+				sb.WriteString(" (" + strings.Join(attrs, ", ") + ")")
+			}
+			if p != nil && p.Description != "" {
+				sb.WriteString(": " + oneLine(p.Description))
+			}
+		}
+	}
+	return sb.String()
+}
+
+// schemaType names the type of a schema: a referenced schema's name, the
+// item type of an array as "[]Item", or the type and format.
+func schemaType(s *apiSchema) string {
+	switch {
+	case s == nil:
+		return ""
+	case s.Ref != "":
+		return refName(s.Ref)
+	case s.Type == "array" && s.Items != nil:
+		return "[]" + schemaType(s.Items)
+	case s.Format != "":
+		return s.Type + "/" + s.Format
+	}
+	return s.Type
+}
+
+// refName returns the last element of a $ref, e.g. "Payment" for
+// "#/components/schemas/Payment".
+func refName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// serverURLs lists the servers of an OpenAPI 3 spec, or the host and base
+// path of a Swagger 2 spec.
+func serverURLs(spec *yaml.Node) []string {
+	var urls []string
+	if n := mappingValue(spec, "servers"); n != nil {
+		var servers []struct {
+			URL string `yaml:"url"`
+		}
+		n.Decode(&servers)
+		for _, s := range servers {
+			urls = append(urls, s.URL)
+		}
+	}
+	if host := scalarValue(spec, "host"); host != "" {
+		urls = append(urls, host+scalarValue(spec, "basePath"))
+	}
+	return urls
+}
+
+// mappingValue returns the value of key in a mapping node, or nil.
+func mappingValue(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// scalarValue returns the scalar value of key in a mapping node, or "".
+func scalarValue(n *yaml.Node, key string) string {
+	if v := mappingValue(n, key); v != nil && v.Kind == yaml.ScalarNode {
+		return v.Value
+	}
+	return ""
+}
+
+// lastLine returns the last line of a node and its descendants. Multi-line
+// scalars count as their first line.
+func lastLine(n *yaml.Node) int {
+	if n == nil {
+		return 0
+	}
+	last := n.Line
+	for _, c := range n.Content {
+		last = max(last, lastLine(c))
+	}
+	return last
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
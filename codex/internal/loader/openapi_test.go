@@ -0,0 +1,85 @@
+package loader
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOpenAPI(t *testing.T) {
+	t.Run("Given an OpenAPI 3 spec, When loading, Then each operation and schema is a section", func(t *testing.T) {
+		_, doc := loadFixture(t, "payments.yaml")
+		want := []string{
+			"Payments API > Overview",
+			"Payments API > GET /payments/{id}",
+			"Payments API > DELETE /payments/{id}",
+			"Payments API > POST /refunds",
+			"Payments API > Schema Payment",
+			"Payments API > Schema Refund",
+		}
+		if got := breadcrumbs(doc); !reflect.DeepEqual(got, want) {
+			t.Fatalf("breadcrumbs = %q, want %q", got, want)
+		}
+		if doc.Metadata["api_version"] != "2.1.0" || doc.Metadata["spec_version"] != "openapi 3.0.3" {
+			t.Errorf("metadata = %v", doc.Metadata)
+		}
+		if overview := doc.Sections[0].Content; !strings.Contains(overview, "Servers: https://api.example.com/v2") {
+			t.Errorf("overview = %q", overview)
+		}
+
+		get := doc.Sections[1]
+		for _, s := range []string{
+			"Fetch a payment",
+			"- id (path, string, required): The payment ID.",
+			"- 200: The payment. (application/json Payment)",
+			"- 404: No such payment.",
+		} {
+			if !strings.Contains(get.Content, s) {
+				t.Errorf("GET content lacks %q:\n%s", s, get.Content)
+			}
+		}
+		if get.Metadata["http_method"] != "GET" || get.Metadata["http_path"] != "/payments/{id}" || get.Metadata["operation_id"] != "getPayment" {
+			t.Errorf("GET metadata = %v", get.Metadata)
+		}
+		if get.StartLine != 17 || get.EndLine != 29 {
+			t.Errorf("GET lines = %d-%d, want 17-29", get.StartLine, get.EndLine)
+		}
+
+		if del := doc.Sections[2]; !strings.HasPrefix(del.Content, "DELETE /payments/{id} (deprecated)") || !strings.Contains(del.Content, "- id (path") {
+			t.Errorf("DELETE content = %q, want the shared path parameter", del.Content)
+		}
+		if post := doc.Sections[3]; !strings.Contains(post.Content, "Request body (required):\n- application/json: Refund") {
+			t.Errorf("POST content = %q", post.Content)
+		}
+		if schema := doc.Sections[4].Content; !strings.Contains(schema, "- amount (integer/int64, required): Amount in cents.") || !strings.Contains(schema, "- refunds ([]Refund)") {
+			t.Errorf("schema = %q", schema)
+		}
+	})
+
+	t.Run("Given a Swagger 2 spec in JSON, When loading, Then operations and definitions are sections", func(t *testing.T) {
+		_, doc := loadFixture(t, "petstore.json")
+		want := []string{"Petstore > Overview", "Petstore > GET /pets", "Petstore > Schema Pet"}
+		if got := breadcrumbs(doc); !reflect.DeepEqual(got, want) {
+			t.Fatalf("breadcrumbs = %q, want %q", got, want)
+		}
+		get := doc.Sections[1].Content
+		if !strings.Contains(get, "- limit (query, integer): How many pets to return.") || !strings.Contains(get, "- 200: A list of pets. ([]Pet)") {
+			t.Errorf("GET content = %q", get)
+		}
+		if !strings.Contains(doc.Sections[0].Content, "petstore.example.com/v1") {
+			t.Errorf("overview = %q", doc.Sections[0].Content)
+		}
+	})
+
+	t.Run("Given YAML or JSON that is not a spec, When matching, Then it is not taken", func(t *testing.T) {
+		for _, content := range []string{
+			"replicas: 3\n",
+			`{"name": "openapi-tools"}`,
+			"# openapi: 3.0.0\nkind: Deployment\n",
+		} {
+			if OpenAPI().Match("x.yaml", []byte(content)) {
+				t.Errorf("Match(%q) = true", content)
+			}
+		}
+	})
+}
@@ -0,0 +1,69 @@
+package loader
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+type pdfLoader struct{}
+
+// PDF returns the loader of PDF files. Each page with text is a section;
+// scanned pages without a text layer are left out.
+func PDF() Loader {
+	return pdfLoader{}
+}
+
+func (pdfLoader) Name() string         { return "pdf" }
+func (pdfLoader) Extensions() []string { return []string{".pdf"} }
+
+func (pdfLoader) Match(path string, content []byte) bool {
+	return bytes.HasPrefix(content, []byte("%PDF-"))
+}
+
+func (pdfLoader) Load(path string, content []byte) (doc *Document, err error) {
+	// The reader panics on some malformed files
+	defer func() {
+		if r := recover(); r != nil {
+			doc, err = nil, fmt.Errorf("malformed PDF: %v", r)
+		}
+	}()
+
+	r, err := pdf.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PDF: %w", err)
+	}
+
+	doc = &Document{
+		Title:    strings.TrimSpace(r.Trailer().Key("Info").Key("Title").Text()),
+		Metadata: map[string]any{"pages": r.NumPage()},
+	}
+	for i := 1; i <= r.NumPage(); i++ {
+		page := r.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		text, err := page.GetPlainText(nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read page %d: %w", i, err)
+		}
+		text = cleanText(text)
+		if text == "" {
+			continue
+		}
+
+		title := fmt.Sprintf("Page %d", i)
+		section := Section{
+			Title:   title,
+			Content: text,
+			Page:    i,
+		}
+		if doc.Title != "" {
+			section.Headings = []string{doc.Title, title}
+		}
+		doc.Sections = append(doc.Sections, splitSection(section, strings.Split(text, "\n"), "\n")...)
+	}
+	return doc, nil
+}
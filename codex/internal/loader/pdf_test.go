@@ -0,0 +1,34 @@
+package loader
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestPDF(t *testing.T) {
+	t.Run("Given a two page PDF, When loading, Then each page is a section under the document title", func(t *testing.T) {
+		_, doc := loadFixture(t, "handbook.pdf")
+		if doc.Title != "Service handbook" || doc.Metadata["pages"] != 2 {
+			t.Errorf("doc = %q %v", doc.Title, doc.Metadata)
+		}
+		want := []string{"Service handbook > Page 1", "Service handbook > Page 2"}
+		if got := breadcrumbs(doc); !reflect.DeepEqual(got, want) {
+			t.Fatalf("breadcrumbs = %q, want %q", got, want)
+		}
+		page := doc.Sections[1]
+		if page.Page != 2 || page.Content != "Rollback\nUse make rollback to restore the last release." {
+			t.Errorf("page 2 = %d %q", page.Page, page.Content)
+		}
+	})
+
+	t.Run("Given a truncated PDF, When loading, Then it fails without panicking", func(t *testing.T) {
+		content, err := os.ReadFile("testdata/handbook.pdf")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := PDF().Load("broken.pdf", content[:len(content)/2]); err == nil {
+			t.Error("want error")
+		}
+	})
+}
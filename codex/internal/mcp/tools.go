@@ -38,6 +38,14 @@ func (h *ToolHandler) Handle(ctx context.Context, name string, args map[string]i
 		return h.handleFeedback(args)
 	case "flight_recorder_log":
 		return h.handleFlightRecorderLog(args)
+	case "code_definition":
+		return h.handleCodeDefinition(ctx, args)
+	case "code_references":
+		return h.handleCodeReferences(ctx, args)
+	case "code_implementations":
+		return h.handleCodeImplementations(ctx, args)
+	case "code_imports":
+		return h.handleCodeImports(ctx, args)
 	default:
 		return nil, fmt.Errorf("unknown tool: %s", name)
 	}
@@ -72,12 +80,82 @@ func (h *ToolHandler) handleSearch(ctx context.Context, args map[string]interfac
 		limit = int(l)
 	}
 
-	results, err := h.engine.Search(ctx, core.SearchRequest{
-		Query: query,
-		Types: types,
-		Scope: scope,
-		Limit: limit,
-	})
+	var tags []string
+	if t, ok := args["tags"].([]interface{}); ok {
+		for _, v := range t {
+			if str, ok := v.(string); ok {
+				tags = append(tags, str)
+			}
+		}
+	}
+	sourcePrefix, _ := args["source_prefix"].(string)
+	gitRef, _ := args["git_ref"].(string)
+	exact, _ := args["exact"].(bool)
+	fusion, _ := args["fusion"].(string)
+	expand, _ := args["expand"].(bool)
+	hyde, _ := args["hyde"].(bool)
+	explain, _ := args["explain"].(bool)
+	groupByParent, _ := args["group_by_parent"].(bool)
+	expandSection, _ := args["expand_section"].(bool)
+	var neighbors int
+	if n, ok := args["neighbors"].(float64); ok {
+		neighbors = int(n)
+	}
+
+	var weights core.FusionWeights
+	if w, ok := args["fusion_weights"].(map[string]interface{}); ok {
+		weights = make(core.FusionWeights, len(w))
+		for name, v := range w {
+			f, ok := v.(float64)
+			if !ok {
+				return nil, fmt.Errorf("fusion_weights: %s must be a number", name)
+			}
+			weights[name] = f
+		}
+		if err := weights.Validate(); err != nil {
+			return nil, fmt.Errorf("fusion_weights: %w", err)
+		}
+	}
+
+	req := core.SearchRequest{
+		Query:        query,
+		Types:        types,
+		Scope:        scope,
+		Limit:        limit,
+		Tags:         tags,
+		SourcePrefix: sourcePrefix,
+		GitRef:       gitRef,
+		Exact:        exact,
+
+		Fusion:        fusion,
+		FusionWeights: weights,
+		Expand:        expand,
+		HyDE:          hyde,
+		Explain:       explain,
+
+		GroupByParent: groupByParent,
+		Neighbors:     neighbors,
+		ExpandSection: expandSection,
+	}
+	dates := map[string]*time.Time{
+		"created_after":  &req.CreatedAfter,
+		"created_before": &req.CreatedBefore,
+		"updated_after":  &req.UpdatedAfter,
+		"updated_before": &req.UpdatedBefore,
+	}
+	for key, dst := range dates {
+		str, _ := args[key].(string)
+		if str == "" {
+			continue
+		}
+		t, err := core.ParseFilterTime(str)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		*dst = t
+	}
+
+	results, err := h.engine.Search(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -127,6 +205,34 @@ func (h *ToolHandler) handleSearch(ctx context.Context, args map[string]interfac
 			"score":     r.Score,
 			"score_pct": fmt.Sprintf("%.0f%%", scorePct),
 		}
+		// Feedback from earlier sessions shows which items proved useful
+		if r.Feedback != nil {
+			ranked[i]["feedback"] = r.Feedback
+		}
+		if r.Explanation != nil {
+			ranked[i]["explanation"] = r.Explanation
+		}
+		// Chunks cite the lines they come from; grouped and expanded
+		// results say which chunks they stand for
+		if r.Citation != nil {
+			ranked[i]["citation"] = r.Citation.String()
+		}
+		if r.ParentHits > 1 {
+			ranked[i]["parent_hits"] = r.ParentHits
+		}
+		if len(r.Expanded) > 0 {
+			ranked[i]["expanded"] = r.Expanded
+		}
+		// Go code chunks link to their symbol for the code_* tools
+		if symbol, ok := r.Metadata["symbol_id"].(string); ok {
+			ranked[i]["symbol_id"] = symbol
+		}
+		// Files indexed from git carry the commit they were read at
+		for _, key := range []string{"git_ref", "git_commit", "git_author", "git_modified", "git_uncommitted"} {
+			if v, ok := r.Metadata[key]; ok {
+				ranked[i][key] = v
+			}
+		}
 	}
 
 	return map[string]interface{}{
@@ -145,7 +251,15 @@ func (h *ToolHandler) handleGet(ctx context.Context, args map[string]interface{}
 		return nil, fmt.Errorf("id is required")
 	}
 
-	return h.engine.Get(ctx, id)
+	mode, _ := args["mode"].(string)
+	switch mode {
+	case "", "item":
+		return h.engine.Get(ctx, id)
+	case "file":
+		return h.engine.GetParentDocument(ctx, id)
+	default:
+		return nil, fmt.Errorf("unknown mode %q: use item or file", mode)
+	}
 }
 
 func (h *ToolHandler) handleAdd(ctx context.Context, args map[string]interface{}) (interface{}, error) {
@@ -273,6 +387,62 @@ func (h *ToolHandler) handleFlightRecorderLog(args map[string]interface{}) (inte
 	return map[string]string{"status": "logged"}, nil
 }
 
+func (h *ToolHandler) handleCodeDefinition(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	symbol, _ := args["symbol"].(string)
+	if symbol == "" {
+		return nil, fmt.Errorf("symbol is required")
+	}
+
+	defs, err := h.engine.CodeDefinition(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"definitions": defs, "count": len(defs)}, nil
+}
+
+func (h *ToolHandler) handleCodeReferences(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	symbol, _ := args["symbol"].(string)
+	if symbol == "" {
+		return nil, fmt.Errorf("symbol is required")
+	}
+	limit := 100
+	if l, ok := args["limit"].(float64); ok {
+		limit = int(l)
+	}
+
+	refs, err := h.engine.CodeReferences(ctx, symbol, limit)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"references": refs, "count": len(refs)}, nil
+}
+
+func (h *ToolHandler) handleCodeImplementations(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	symbol, _ := args["symbol"].(string)
+	if symbol == "" {
+		return nil, fmt.Errorf("symbol is required")
+	}
+
+	impls, err := h.engine.CodeImplementations(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"implementations": impls, "count": len(impls)}, nil
+}
+
+func (h *ToolHandler) handleCodeImports(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	pkg, _ := args["package"].(string)
+	if pkg == "" {
+		return nil, fmt.Errorf("package is required")
+	}
+
+	pkgs, err := h.engine.CodeImports(ctx, pkg)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"packages": pkgs, "count": len(pkgs)}, nil
+}
+
 func generateID(itemType string) string {
 	prefix := map[string]string{
 		core.TypePattern:  "P",
@@ -296,13 +466,13 @@ func getToolDefinitions() []Tool {
 	return []Tool{
 		{
 			Name:        "recall_search",
-			Description: "Search organizational knowledge for patterns, failures, decisions, and code. After receiving results, apply retrieval-judge skill: evaluate each result for relevance, keep only directly applicable results, and log judgment via flight_recorder_log.",
+			Description: "Search organizational knowledge for patterns, failures, decisions, and code. After receiving results, apply retrieval-judge skill: evaluate each result for relevance, keep only directly applicable results, and log judgment via flight_recorder_log. Results with earlier recall_feedback include its counts and a net score from -1 to 1.",
 			InputSchema: map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
 					"query": map[string]interface{}{
 						"type":        "string",
-						"description": "Search query for knowledge items",
+						"description": "Search query for knowledge items. Optional syntax: \"quoted phrase\", title:term, tags:term, AND/OR between terms",
 					},
 					"types": map[string]interface{}{
 						"type":        "array",
@@ -311,19 +481,85 @@ func getToolDefinitions() []Tool {
 					},
 					"scope": map[string]interface{}{
 						"type":        "string",
-						"description": "Filter by scope: global, project, all",
+						"description": "Filter by scope: global, project (current project only), both (global plus current project), all (every project)",
+					},
+					"tags": map[string]interface{}{
+						"type":        "array",
+						"items":       map[string]interface{}{"type": "string"},
+						"description": "Only items carrying all of these tags",
+					},
+					"source_prefix": map[string]interface{}{
+						"type":        "string",
+						"description": "Only items whose source path starts with this prefix",
+					},
+					"git_ref": map[string]interface{}{
+						"type":        "string",
+						"description": "Only code as of this git ref (e.g. release/2.3), as indexed with codex-cli index --git --ref",
+					},
+					"created_after": map[string]interface{}{
+						"type":        "string",
+						"description": "Only items created on or after this date (YYYY-MM-DD or RFC 3339)",
+					},
+					"created_before": map[string]interface{}{
+						"type":        "string",
+						"description": "Only items created before this date (YYYY-MM-DD or RFC 3339)",
+					},
+					"updated_after": map[string]interface{}{
+						"type":        "string",
+						"description": "Only items updated on or after this date (YYYY-MM-DD or RFC 3339)",
+					},
+					"updated_before": map[string]interface{}{
+						"type":        "string",
+						"description": "Only items updated before this date (YYYY-MM-DD or RFC 3339)",
 					},
 					"limit": map[string]interface{}{
 						"type":        "integer",
 						"description": "Maximum results (default 10)",
 					},
+					"exact": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Use exact instead of approximate vector search (slower on large stores)",
+					},
+					"expand": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Also search with LLM paraphrases and the identifiers the query mentions; helps vague or differently worded queries at the cost of latency",
+					},
+					"hyde": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Like expand, plus a search with a hypothetical answer passage; helps questions whose answers use different vocabulary",
+					},
+					"explain": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Add an explanation to each result: its rank and score from vector and keyword search, fused score, rerank score, boosts, and whether the score threshold nearly cut it",
+					},
+					"group_by_parent": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Return only the best chunk of each file or document, with parent_hits counting its chunks that matched",
+					},
+					"neighbors": map[string]interface{}{
+						"type":        "integer",
+						"description": "Join each chunk result with this many chunks before and after it in its file",
+					},
+					"expand_section": map[string]interface{}{
+						"type":        "boolean",
+						"description": "Join each doc chunk result with the rest of its heading section, and each part of a split function with its other parts",
+					},
+					"fusion": map[string]interface{}{
+						"type":        "string",
+						"description": "How to merge vector and keyword rankings: rrf (default), combsum, combmnz, convex",
+					},
+					"fusion_weights": map[string]interface{}{
+						"type":                 "object",
+						"additionalProperties": map[string]interface{}{"type": "number"},
+						"description":          "Weight per ranking, e.g. {\"vector\": 1, \"keyword\": 2}; unnamed rankings weigh 1, 0 leaves one out. Keyword-heavy suits identifiers, vector-heavy suits questions",
+					},
 				},
 				"required": []string{"query"},
 			},
 		},
 		{
 			Name:        "recall_get",
-			Description: "Get a specific knowledge item by ID",
+			Description: "Get a specific knowledge item by ID, or with mode file the complete source file a code or doc chunk comes from, reassembled from its chunks",
 			InputSchema: map[string]interface{}{
 				"type": "object",
 				"properties": map[string]interface{}{
@@ -331,6 +567,10 @@ func getToolDefinitions() []Tool {
 						"type":        "string",
 						"description": "ID of the knowledge item to retrieve",
 					},
+					"mode": map[string]interface{}{
+						"type":        "string",
+						"description": "item (default): the item itself; file: the file of a chunk, given the chunk or parent ID",
+					},
 				},
 				"required": []string{"id"},
 			},
@@ -414,5 +654,65 @@ func getToolDefinitions() []Tool {
 				"required": []string{"type", "content"},
 			},
 		},
+		{
+			Name:        "code_definition",
+			Description: "Find where a Go symbol is defined in indexed code: functions, methods, types, interfaces, constants and variables. Returns file, lines, signature and the item_id of the code chunk to fetch with recall_get",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"symbol": map[string]interface{}{
+						"type":        "string",
+						"description": "Symbol name, optionally qualified: Post, Ledger.Post, ledger.Ledger.Post or a full import path such as github.com/acme/shop/ledger.Ledger.Post",
+					},
+				},
+				"required": []string{"symbol"},
+			},
+		},
+		{
+			Name:        "code_references",
+			Description: "Find where a Go symbol is used in indexed code: calls, type uses, values and embeddings, with the enclosing function. Method calls on receivers of unknown type match by name and are marked resolved=false",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"symbol": map[string]interface{}{
+						"type":        "string",
+						"description": "Symbol name, optionally qualified, as for code_definition",
+					},
+					"limit": map[string]interface{}{
+						"type":        "integer",
+						"description": "Maximum references (default 100)",
+					},
+				},
+				"required": []string{"symbol"},
+			},
+		},
+		{
+			Name:        "code_implementations",
+			Description: "For a Go interface, find the types implementing it; for a type, find the interfaces it implements. Matches method names, including methods promoted by embedding",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"symbol": map[string]interface{}{
+						"type":        "string",
+						"description": "Interface or type name, optionally qualified, e.g. core.Embedder",
+					},
+				},
+				"required": []string{"symbol"},
+			},
+		},
+		{
+			Name:        "code_imports",
+			Description: "List the packages a Go package imports and the indexed packages importing it",
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"package": map[string]interface{}{
+						"type":        "string",
+						"description": "Import path or a suffix of one, e.g. internal/core",
+					},
+				},
+				"required": []string{"package"},
+			},
+		},
 	}
 }
@@ -0,0 +1,46 @@
+// Package project identifies the project a tool works in, so that items
+// indexed or added from anywhere in a repository are scoped to it.
+package project
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Resolve returns the project identity for dir: the nearest enclosing
+// git repository root, or dir itself when it is not inside a repository.
+// The result is an absolute, cleaned path so that the same repository yields
+// the same identity regardless of which subdirectory a tool was started in.
+// Returns "" if dir is empty or cannot be made absolute.
+func Resolve(dir string) string {
+	if dir == "" {
+		return ""
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for d := abs; ; {
+		if _, err := os.Stat(filepath.Join(d, ".git")); err == nil {
+			return d
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			return abs
+		}
+		d = parent
+	}
+}
+
+// Dir returns the directory identifying the current project:
+// CODEX_PROJECT_PATH or EDI_PROJECT_PATH if set, otherwise the working
+// directory.
+func Dir() string {
+	for _, key := range []string{"CODEX_PROJECT_PATH", "EDI_PROJECT_PATH"} {
+		if dir := os.Getenv(key); dir != "" {
+			return dir
+		}
+	}
+	dir, _ := os.Getwd()
+	return dir
+}
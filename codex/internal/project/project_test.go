@@ -0,0 +1,65 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	root := t.TempDir()
+	repo := filepath.Join(root, "repo")
+	sub := filepath.Join(repo, "internal", "pkg")
+	plain := filepath.Join(root, "plain")
+	for _, dir := range []string{filepath.Join(repo, ".git"), sub, plain} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		dir  string
+		want string
+	}{
+		{name: "Given repository root Then returns root", dir: repo, want: repo},
+		{name: "Given subdirectory of repository Then returns repository root", dir: sub, want: repo},
+		{name: "Given directory outside any repository Then returns the directory", dir: plain, want: plain},
+		{name: "Given empty directory Then returns empty", dir: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Resolve(tt.dir); got != tt.want {
+				t.Errorf("Resolve(%q) = %q, want %q", tt.dir, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDir(t *testing.T) {
+	t.Run("Given CODEX_PROJECT_PATH Then it wins over EDI_PROJECT_PATH", func(t *testing.T) {
+		t.Setenv("CODEX_PROJECT_PATH", "/src/codex")
+		t.Setenv("EDI_PROJECT_PATH", "/src/edi")
+		if got := Dir(); got != "/src/codex" {
+			t.Errorf("Dir() = %q, want /src/codex", got)
+		}
+	})
+
+	t.Run("Given only EDI_PROJECT_PATH Then it is used", func(t *testing.T) {
+		t.Setenv("CODEX_PROJECT_PATH", "")
+		t.Setenv("EDI_PROJECT_PATH", "/src/edi")
+		if got := Dir(); got != "/src/edi" {
+			t.Errorf("Dir() = %q, want /src/edi", got)
+		}
+	})
+
+	t.Run("Given neither Then the working directory is used", func(t *testing.T) {
+		t.Setenv("CODEX_PROJECT_PATH", "")
+		t.Setenv("EDI_PROJECT_PATH", "")
+		wd, _ := os.Getwd()
+		if got := Dir(); got != wd {
+			t.Errorf("Dir() = %q, want %q", got, wd)
+		}
+	})
+}
@@ -0,0 +1,223 @@
+package reranking
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	ort "github.com/yalue/onnxruntime_go"
+)
+
+const (
+	// maxSequenceLength caps query+document tokens per pair. Both BGE
+	// rerankers accept at least 512; longer inputs are slow on CPU.
+	maxSequenceLength = 512
+
+	// scoreBatchSize is the number of pairs scored per ONNX run.
+	scoreBatchSize = 8
+)
+
+// crossEncoder scores (query, document) pairs with a BGE reranker ONNX model.
+type crossEncoder struct {
+	name      string
+	tokenizer *unigramTokenizer
+	session   *ort.DynamicAdvancedSession
+	inputs    []string // model input names, in session order
+	mu        sync.Mutex
+}
+
+// newCrossEncoder loads model.onnx and tokenizer.json from dir.
+// The ONNX runtime environment must already be initialized.
+func newCrossEncoder(dir string) (*crossEncoder, error) {
+	modelPath := filepath.Join(dir, "model.onnx")
+	tok, err := loadUnigramTokenizer(filepath.Join(dir, "tokenizer.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tokenizer: %w", err)
+	}
+
+	inputInfo, outputInfo, err := ort.GetInputOutputInfo(modelPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect %s: %w", modelPath, err)
+	}
+	if len(outputInfo) == 0 {
+		return nil, fmt.Errorf("model %s has no outputs", modelPath)
+	}
+	var inputs []string
+	for _, in := range inputInfo {
+		switch in.Name {
+		case "input_ids", "attention_mask", "token_type_ids":
+			inputs = append(inputs, in.Name)
+		default:
+			return nil, fmt.Errorf("unexpected model input %q", in.Name)
+		}
+	}
+
+	opts, err := ort.NewSessionOptions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session options: %w", err)
+	}
+	defer opts.Destroy()
+	threads := runtime.NumCPU()
+	if threads > 4 {
+		threads = 4 // leave CPU for embedding and SQLite
+	}
+	if err := opts.SetIntraOpNumThreads(threads); err != nil {
+		return nil, fmt.Errorf("failed to set thread count: %w", err)
+	}
+
+	session, err := ort.NewDynamicAdvancedSession(modelPath, inputs, []string{outputInfo[0].Name}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s: %w", modelPath, err)
+	}
+
+	return &crossEncoder{
+		name:      filepath.Base(dir),
+		tokenizer: tok,
+		session:   session,
+		inputs:    inputs,
+	}, nil
+}
+
+// score returns a relevance score in (0, 1) for each document, in input order.
+func (c *crossEncoder) score(query string, docs []Document) ([]float64, error) {
+	scores := make([]float64, 0, len(docs))
+	for start := 0; start < len(docs); start += scoreBatchSize {
+		end := start + scoreBatchSize
+		if end > len(docs) {
+			end = len(docs)
+		}
+		batch, err := c.scoreBatch(query, docs[start:end])
+		if err != nil {
+			return nil, err
+		}
+		scores = append(scores, batch...)
+	}
+	return scores, nil
+}
+
+func (c *crossEncoder) scoreBatch(query string, docs []Document) ([]float64, error) {
+	encoded := make([][]int, len(docs))
+	seqLen := 0
+	for i, d := range docs {
+		encoded[i] = c.tokenizer.encodePair(query, d.Content, maxSequenceLength)
+		if len(encoded[i]) > seqLen {
+			seqLen = len(encoded[i])
+		}
+	}
+
+	// Pad every pair to the longest in the batch
+	n := len(docs) * seqLen
+	ids := make([]int64, n)
+	mask := make([]int64, n)
+	for i, enc := range encoded {
+		row := i * seqLen
+		for j := 0; j < seqLen; j++ {
+			if j < len(enc) {
+				ids[row+j] = int64(enc[j])
+				mask[row+j] = 1
+			} else {
+				ids[row+j] = int64(c.tokenizer.padID)
+			}
+		}
+	}
+
+	shape := ort.NewShape(int64(len(docs)), int64(seqLen))
+	inputs := make([]ort.Value, len(c.inputs))
+	defer func() {
+		for _, v := range inputs {
+			if v != nil {
+				v.Destroy()
+			}
+		}
+	}()
+	for i, name := range c.inputs {
+		var data []int64
+		switch name {
+		case "input_ids":
+			data = ids
+		case "attention_mask":
+			data = mask
+		default:
+			data = make([]int64, n) // token_type_ids are all zero
+		}
+		t, err := ort.NewTensor(shape, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s tensor: %w", name, err)
+		}
+		inputs[i] = t
+	}
+
+	outputs := []ort.Value{nil}
+	c.mu.Lock()
+	err := c.session.Run(inputs, outputs)
+	c.mu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("%s inference failed: %w", c.name, err)
+	}
+	defer outputs[0].Destroy()
+
+	logits, ok := outputs[0].(*ort.Tensor[float32])
+	if !ok {
+		return nil, fmt.Errorf("%s: unexpected output type %T", c.name, outputs[0])
+	}
+	data := logits.GetData()
+	if len(data) != len(docs) {
+		return nil, fmt.Errorf("%s: expected %d logits, got %d", c.name, len(docs), len(data))
+	}
+
+	scores := make([]float64, len(docs))
+	for i, logit := range data {
+		scores[i] = sigmoid(float64(logit))
+	}
+	return scores, nil
+}
+
+func (c *crossEncoder) close() {
+	if c.session != nil {
+		c.session.Destroy()
+	}
+}
+
+func sigmoid(x float64) float64 {
+	return 1 / (1 + math.Exp(-x))
+}
+
+// ortLibraryNames are the ONNX runtime shared library names per platform.
+var ortLibraryNames = map[string]string{
+	"linux":   "libonnxruntime.so",
+	"darwin":  "libonnxruntime.dylib",
+	"windows": "onnxruntime.dll",
+}
+
+var (
+	ortOnce sync.Once
+	ortErr  error
+)
+
+// initONNXRuntime loads the ONNX runtime shared library once per process.
+// The library is located via ONNXRUNTIME_LIB, then <modelsPath>/lib/, then
+// the system library search path.
+func initONNXRuntime(modelsPath string) error {
+	ortOnce.Do(func() {
+		lib := os.Getenv("ONNXRUNTIME_LIB")
+		if lib == "" {
+			name := ortLibraryNames[runtime.GOOS]
+			if name == "" {
+				ortErr = fmt.Errorf("unsupported platform %s", runtime.GOOS)
+				return
+			}
+			lib = name
+			if local := filepath.Join(modelsPath, "lib", name); fileExists(local) {
+				lib = local
+			}
+		}
+		ort.SetSharedLibraryPath(lib)
+		if err := ort.InitializeEnvironment(); err != nil {
+			ortErr = fmt.Errorf("failed to load ONNX runtime from %s: %w", lib, err)
+		}
+	})
+	return ortErr
+}
@@ -1,154 +1,188 @@
 package reranking
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
 	"sort"
 )
 
-// Reranker provides multi-stage reranking using BGE models
-// TODO: Integrate with Hugot library when available for ONNX inference
+// ErrUnavailable is returned by Rerank when no cross-encoder model is loaded.
+var ErrUnavailable = errors.New("reranker unavailable")
+
+// stage1Candidates is how many documents the first stage passes to the second.
+const stage1Candidates = 20
+
+// Reranker provides multi-stage cross-encoder reranking using BGE models
+// running on the ONNX runtime (CPU).
+//
+// Stage 1 uses bge-reranker-base; stage 2, when bge-reranker-v2-m3 is also
+// present, re-scores the stage 1 top candidates. Either model alone is used
+// as a single stage. Each model directory under ModelsPath must contain
+// model.onnx and tokenizer.json.
 type Reranker struct {
 	modelsPath string
-	available  bool
-	// Future: Hugot session and pipelines
-	// session *hugot.Session
-	// stage1  hugot.FeatureExtractionPipeline
-	// stage2  hugot.FeatureExtractionPipeline
+	stage1     scorer
+	stage2     scorer
+	status     string
 }
 
-// NewReranker creates a new reranker instance
+// scorer scores documents against a query; crossEncoder is the real one.
+type scorer interface {
+	score(query string, docs []Document) ([]float64, error)
+	close()
+}
+
+// NewReranker creates a new reranker instance. Missing models or a missing
+// ONNX runtime are not errors: the returned Reranker reports IsAvailable()
+// false and Status() explains why, so callers can keep their own scores.
 func NewReranker(modelsPath string) (*Reranker, error) {
-	// Check if models exist
-	stage1Path := filepath.Join(modelsPath, "bge-reranker-base", "model.onnx")
-	stage2Path := filepath.Join(modelsPath, "bge-reranker-v2-m3", "model.onnx")
+	stage1Dir := filepath.Join(modelsPath, "bge-reranker-base")
+	stage2Dir := filepath.Join(modelsPath, "bge-reranker-v2-m3")
 
-	stage1Exists := fileExists(stage1Path)
-	stage2Exists := fileExists(stage2Path)
+	stage1Exists := fileExists(filepath.Join(stage1Dir, "model.onnx"))
+	stage2Exists := fileExists(filepath.Join(stage2Dir, "model.onnx"))
 
-	reranker := &Reranker{
-		modelsPath: modelsPath,
-		available:  false,
-	}
+	reranker := &Reranker{modelsPath: modelsPath}
 
 	if !stage1Exists && !stage2Exists {
-		log.Printf("INFO: No reranker models found at %s, using fallback scoring", modelsPath)
+		reranker.status = fmt.Sprintf("no reranker models found at %s", modelsPath)
+		log.Printf("INFO: %s, keeping fusion scores", reranker.status)
 		return reranker, nil
 	}
 
-	// TODO: Initialize Hugot session when library is stable
-	// For now, log that models exist but we're using fallback
-	log.Printf("INFO: Reranker models found (stage1: %v, stage2: %v) but Hugot integration pending", stage1Exists, stage2Exists)
-	log.Printf("INFO: Using fallback score-based reranking")
-
-	// When Hugot is available:
-	// session, err := hugot.NewSession()
-	// pipeline, err := session.NewPipeline("featureExtraction", stage1Dir)
-	// reranker.stage1 = pipeline.(hugot.FeatureExtractionPipeline)
-	// reranker.available = true
-
-	return reranker, nil
-}
+	if err := initONNXRuntime(modelsPath); err != nil {
+		reranker.status = err.Error()
+		log.Printf("INFO: Reranker models found but %s, keeping fusion scores", reranker.status)
+		return reranker, nil
+	}
 
-// Rerank performs multi-stage reranking
-func (r *Reranker) Rerank(query string, documents []Document, limit int) ([]RerankResult, error) {
-	if !r.available {
-		// Fallback: return documents in original order with placeholder scores
-		results := make([]RerankResult, len(documents))
-		for i, doc := range documents {
-			results[i] = RerankResult{
-				ID:    doc.ID,
-				Score: 1.0 - float64(i)*0.01, // Decreasing scores
-			}
+	var loadErrs []string
+	if stage1Exists {
+		enc, err := newCrossEncoder(stage1Dir)
+		if err != nil {
+			loadErrs = append(loadErrs, fmt.Sprintf("bge-reranker-base: %v", err))
+		} else {
+			reranker.stage1 = enc
 		}
-		if len(results) > limit {
-			results = results[:limit]
+	}
+	if stage2Exists {
+		enc, err := newCrossEncoder(stage2Dir)
+		if err != nil {
+			loadErrs = append(loadErrs, fmt.Sprintf("bge-reranker-v2-m3: %v", err))
+		} else {
+			reranker.stage2 = enc
 		}
-		return results, nil
 	}
-
-	// When Hugot is available, implement two-stage reranking:
-	// Stage 1: Fast rerank with BGE-base (50 -> 20)
-	stage1Results := r.rerankStage1(query, documents)
-	sort.Slice(stage1Results, func(i, j int) bool {
-		return stage1Results[i].Score > stage1Results[j].Score
-	})
-
-	// Take top 20 for stage 2
-	top20 := stage1Results
-	if len(top20) > 20 {
-		top20 = top20[:20]
+	for _, e := range loadErrs {
+		log.Printf("Warning: failed to load reranker model %s", e)
 	}
 
-	// Get documents for stage 2
-	top20Docs := make([]Document, len(top20))
-	docMap := make(map[string]Document)
-	for _, d := range documents {
-		docMap[d.ID] = d
-	}
-	for i, res := range top20 {
-		top20Docs[i] = docMap[res.ID]
+	switch {
+	case reranker.stage1 != nil && reranker.stage2 != nil:
+		reranker.status = "bge-reranker-base + bge-reranker-v2-m3 (ONNX, CPU)"
+	case reranker.stage1 != nil:
+		reranker.status = "bge-reranker-base (ONNX, CPU)"
+	case reranker.stage2 != nil:
+		reranker.status = "bge-reranker-v2-m3 (ONNX, CPU)"
+	default:
+		reranker.status = "failed to load reranker models"
 	}
 
-	// Stage 2: Precise rerank with BGE-v2-m3 (20 -> limit)
-	stage2Results := r.rerankStage2(query, top20Docs)
-	sort.Slice(stage2Results, func(i, j int) bool {
-		return stage2Results[i].Score > stage2Results[j].Score
-	})
+	return reranker, nil
+}
 
-	if len(stage2Results) > limit {
-		stage2Results = stage2Results[:limit]
+// Rerank scores documents against the query and returns the top limit,
+// highest score first. Scores are cross-encoder relevance in (0, 1).
+// With two stages, the documents the second stage does not re-score
+// follow its results in stage 1 order, so none is lost.
+func (r *Reranker) Rerank(query string, documents []Document, limit int) ([]RerankResult, error) {
+	if !r.IsAvailable() {
+		return nil, ErrUnavailable
+	}
+	if len(documents) == 0 {
+		return nil, nil
 	}
 
-	return stage2Results, nil
-}
+	first, second := r.stage1, r.stage2
+	if first == nil {
+		first, second = second, nil
+	}
 
-// rerankStage1 uses BGE-reranker-base
-func (r *Reranker) rerankStage1(query string, docs []Document) []RerankResult {
-	// TODO: Implement with Hugot when available
-	// Format inputs as "query [SEP] document" pairs
-	// Run through BGE-reranker-base model
+	// Stage 1: score all candidates
+	results, err := scoreAndSort(first, query, documents)
+	if err != nil {
+		return nil, err
+	}
 
-	// Fallback: return original order with decreasing scores
-	results := make([]RerankResult, len(docs))
-	for i, doc := range docs {
-		results[i] = RerankResult{
-			ID:    doc.ID,
-			Score: 1.0 - float64(i)*0.01,
+	// Stage 2: re-score the stage 1 top candidates with the larger model.
+	// Stage 1 scores of the rest are scaled by the lowest stage 2 score, which
+	// ranks them below the re-scored head in their stage 1 order.
+	if second != nil {
+		head, tail := results, []RerankResult(nil)
+		if len(results) > stage1Candidates {
+			head, tail = results[:stage1Candidates], results[stage1Candidates:]
+		}
+		docMap := make(map[string]Document, len(documents))
+		for _, d := range documents {
+			docMap[d.ID] = d
+		}
+		topDocs := make([]Document, len(head))
+		for i, res := range head {
+			topDocs[i] = docMap[res.ID]
+		}
+		if head, err = scoreAndSort(second, query, topDocs); err != nil {
+			return nil, err
+		}
+		floor := head[len(head)-1].Score
+		for i := range tail {
+			tail[i].Score *= floor
 		}
+		results = append(head, tail...)
 	}
-	return results
-}
 
-// rerankStage2 uses BGE-reranker-v2-m3
-func (r *Reranker) rerankStage2(query string, docs []Document) []RerankResult {
-	// TODO: Implement with Hugot when available
-	// Format inputs as "query [SEP] document" pairs
-	// Run through BGE-reranker-v2-m3 model
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
 
-	// Fallback: return original order with decreasing scores
+// scoreAndSort scores docs with enc and returns them by descending score.
+func scoreAndSort(enc scorer, query string, docs []Document) ([]RerankResult, error) {
+	scores, err := enc.score(query, docs)
+	if err != nil {
+		return nil, err
+	}
 	results := make([]RerankResult, len(docs))
-	for i, doc := range docs {
-		results[i] = RerankResult{
-			ID:    doc.ID,
-			Score: 1.0 - float64(i)*0.01,
-		}
+	for i, d := range docs {
+		results[i] = RerankResult{ID: d.ID, Score: scores[i]}
 	}
-	return results
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	return results, nil
 }
 
 // Close releases resources
 func (r *Reranker) Close() {
-	// TODO: Close Hugot session when implemented
-	// if r.session != nil {
-	//     r.session.Destroy()
-	// }
+	if r.stage1 != nil {
+		r.stage1.close()
+	}
+	if r.stage2 != nil {
+		r.stage2.close()
+	}
 }
 
 // IsAvailable returns whether reranking is available
 func (r *Reranker) IsAvailable() bool {
-	return r.available
+	return r.stage1 != nil || r.stage2 != nil
+}
+
+// Status describes the active models, or why reranking is unavailable.
+func (r *Reranker) Status() string {
+	return r.status
 }
 
 func fileExists(path string) bool {
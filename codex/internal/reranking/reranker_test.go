@@ -0,0 +1,92 @@
+package reranking
+
+import (
+	"fmt"
+	"testing"
+)
+
+// fakeScorer scores documents by ID.
+type fakeScorer struct {
+	scores map[string]float64
+	calls  [][]string // IDs scored per call
+}
+
+func (f *fakeScorer) score(query string, docs []Document) ([]float64, error) {
+	var ids []string
+	scores := make([]float64, len(docs))
+	for i, d := range docs {
+		ids = append(ids, d.ID)
+		scores[i] = f.scores[d.ID]
+	}
+	f.calls = append(f.calls, ids)
+	return scores, nil
+}
+
+func (f *fakeScorer) close() {}
+
+func TestNewReranker_WithoutModels(t *testing.T) {
+	r, err := NewReranker(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewReranker failed: %v", err)
+	}
+	defer r.Close()
+
+	if r.IsAvailable() {
+		t.Error("expected reranker to be unavailable without models")
+	}
+	if r.Status() == "" {
+		t.Error("expected a status explaining why reranking is unavailable")
+	}
+	if _, err := r.Rerank("q", []Document{{ID: "a"}}, 1); err != ErrUnavailable {
+		t.Errorf("expected ErrUnavailable, got %v", err)
+	}
+}
+
+func TestReranker_TwoStages(t *testing.T) {
+	// Stage 1 ranks d00..d29 in order; stage 2 reverses its top 20
+	var docs []Document
+	stage1 := &fakeScorer{scores: map[string]float64{}}
+	stage2 := &fakeScorer{scores: map[string]float64{}}
+	for i := 0; i < 30; i++ {
+		id := fmt.Sprintf("d%02d", i)
+		docs = append(docs, Document{ID: id, Content: id})
+		stage1.scores[id] = 0.9 - float64(i)*0.01
+		stage2.scores[id] = 0.2 + float64(i)*0.01
+	}
+	r := &Reranker{stage1: stage1, stage2: stage2}
+
+	t.Run("Given more candidates than stage 2 re-scores, When reranking, Then none are lost", func(t *testing.T) {
+		results, err := r.Rerank("q", docs, len(docs))
+		if err != nil {
+			t.Fatalf("Rerank failed: %v", err)
+		}
+		if len(results) != len(docs) {
+			t.Fatalf("expected %d results, got %d", len(docs), len(results))
+		}
+		if got := len(stage2.calls[len(stage2.calls)-1]); got != stage1Candidates {
+			t.Errorf("stage 2 scored %d documents, want %d", got, stage1Candidates)
+		}
+		for i, res := range results {
+			want := fmt.Sprintf("d%02d", stage1Candidates-1-i) // re-scored head, reversed
+			if i >= stage1Candidates {
+				want = fmt.Sprintf("d%02d", i) // tail in stage 1 order
+			}
+			if res.ID != want {
+				t.Errorf("result %d = %s, want %s", i, res.ID, want)
+			}
+			if i > 0 && res.Score >= results[i-1].Score {
+				t.Errorf("result %d scores %f, not below %f", i, res.Score, results[i-1].Score)
+			}
+		}
+	})
+
+	t.Run("Given a limit, When reranking, Then the top limit are returned", func(t *testing.T) {
+		results, err := r.Rerank("q", docs, 5)
+		if err != nil {
+			t.Fatalf("Rerank failed: %v", err)
+		}
+		if len(results) != 5 || results[0].ID != "d19" {
+			t.Errorf("expected 5 results led by d19, got %+v", results)
+		}
+	})
+}
@@ -0,0 +1,225 @@
+package reranking
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// metaspace marks word starts in SentencePiece vocabularies.
+const metaspace = "▁"
+
+// unigramTokenizer implements the SentencePiece Unigram tokenizer used by
+// XLM-RoBERTa based cross-encoders (bge-reranker-base, bge-reranker-v2-m3).
+// It reads the Hugging Face tokenizer.json exported alongside the ONNX model.
+//
+// The Precompiled normalizer in tokenizer.json is approximated with NFKC,
+// which matches it for the text Codex indexes (code, markdown, prose).
+type unigramTokenizer struct {
+	pieces      map[string]int // piece -> token ID
+	scores      []float64      // token ID -> log probability
+	unkID       int
+	unkScore    float64 // penalty for characters not covered by the vocab
+	maxPieceLen int     // longest piece in runes
+
+	bosID int // <s>
+	eosID int // </s>
+	padID int // <pad>
+}
+
+// tokenizerFile is the subset of tokenizer.json needed for Unigram models.
+type tokenizerFile struct {
+	Model struct {
+		Type  string               `json:"type"`
+		UnkID *int                 `json:"unk_id"`
+		Vocab [][2]json.RawMessage `json:"vocab"`
+	} `json:"model"`
+	AddedTokens []struct {
+		ID      int    `json:"id"`
+		Content string `json:"content"`
+	} `json:"added_tokens"`
+}
+
+// loadUnigramTokenizer reads a tokenizer.json file.
+func loadUnigramTokenizer(path string) (*unigramTokenizer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var tf tokenizerFile
+	if err := json.Unmarshal(data, &tf); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if tf.Model.Type != "Unigram" {
+		return nil, fmt.Errorf("unsupported tokenizer model %q (want Unigram)", tf.Model.Type)
+	}
+	if len(tf.Model.Vocab) == 0 {
+		return nil, fmt.Errorf("tokenizer vocab is empty")
+	}
+
+	t := &unigramTokenizer{
+		pieces: make(map[string]int, len(tf.Model.Vocab)),
+		scores: make([]float64, len(tf.Model.Vocab)),
+	}
+	minScore := 0.0
+	for id, entry := range tf.Model.Vocab {
+		var piece string
+		var score float64
+		if err := json.Unmarshal(entry[0], &piece); err != nil {
+			return nil, fmt.Errorf("vocab entry %d: %w", id, err)
+		}
+		if err := json.Unmarshal(entry[1], &score); err != nil {
+			return nil, fmt.Errorf("vocab entry %d: %w", id, err)
+		}
+		t.pieces[piece] = id
+		t.scores[id] = score
+		if score < minScore {
+			minScore = score
+		}
+		if n := utf8.RuneCountInString(piece); n > t.maxPieceLen {
+			t.maxPieceLen = n
+		}
+	}
+	// SentencePiece scores unknown characters well below any real piece
+	t.unkScore = minScore - 10
+
+	special := make(map[string]int, len(tf.AddedTokens))
+	for _, at := range tf.AddedTokens {
+		special[at.Content] = at.ID
+	}
+	lookup := func(token string) (int, error) {
+		if id, ok := special[token]; ok {
+			return id, nil
+		}
+		if id, ok := t.pieces[token]; ok {
+			return id, nil
+		}
+		return 0, fmt.Errorf("tokenizer has no %s token", token)
+	}
+	if t.bosID, err = lookup("<s>"); err != nil {
+		return nil, err
+	}
+	if t.eosID, err = lookup("</s>"); err != nil {
+		return nil, err
+	}
+	if t.padID, err = lookup("<pad>"); err != nil {
+		return nil, err
+	}
+	if tf.Model.UnkID != nil {
+		t.unkID = *tf.Model.UnkID
+	} else if t.unkID, err = lookup("<unk>"); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// tokenize converts text to token IDs without special tokens.
+func (t *unigramTokenizer) tokenize(text string) []int {
+	text = norm.NFKC.String(text)
+
+	var ids []int
+	for _, word := range strings.FieldsFunc(text, unicode.IsSpace) {
+		ids = append(ids, t.segment(metaspace+word)...)
+	}
+	return ids
+}
+
+// segment finds the most probable segmentation of a single word (Viterbi).
+// Runs of characters not covered by the vocabulary become a single <unk>.
+func (t *unigramTokenizer) segment(word string) []int {
+	rs := []rune(word)
+	n := len(rs)
+
+	best := make([]float64, n+1) // best score of a segmentation of rs[:i]
+	start := make([]int, n+1)    // start of the last piece in that segmentation
+	tokenID := make([]int, n+1)  // ID of that last piece
+	for i := 1; i <= n; i++ {
+		best[i] = math.Inf(-1)
+	}
+
+	for end := 1; end <= n; end++ {
+		lo := end - t.maxPieceLen
+		if lo < 0 {
+			lo = 0
+		}
+		for s := lo; s < end; s++ {
+			if math.IsInf(best[s], -1) {
+				continue
+			}
+			id, ok := t.pieces[string(rs[s:end])]
+			if !ok {
+				continue
+			}
+			if score := best[s] + t.scores[id]; score > best[end] {
+				best[end], start[end], tokenID[end] = score, s, id
+			}
+		}
+		// Fall back to a single unknown character
+		if math.IsInf(best[end], -1) {
+			best[end], start[end], tokenID[end] = best[end-1]+t.unkScore, end-1, t.unkID
+		}
+	}
+
+	var reversed []int
+	for end := n; end > 0; end = start[end] {
+		id := tokenID[end]
+		if id == t.unkID && len(reversed) > 0 && reversed[len(reversed)-1] == t.unkID {
+			continue // fuse consecutive unknowns
+		}
+		reversed = append(reversed, id)
+	}
+
+	ids := make([]int, len(reversed))
+	for i, id := range reversed {
+		ids[len(reversed)-1-i] = id
+	}
+	return ids
+}
+
+// encodePair builds the cross-encoder input "<s> query </s></s> doc </s>",
+// truncating the longer side first so the result fits in maxLen tokens.
+func (t *unigramTokenizer) encodePair(query, doc string, maxLen int) []int {
+	// Tokens average a few characters, so text far beyond maxLen*16 runes
+	// would be truncated anyway; skip tokenizing it.
+	q := t.tokenize(truncateRunes(query, maxLen*16))
+	d := t.tokenize(truncateRunes(doc, maxLen*16))
+
+	budget := maxLen - 4 // <s> </s> </s> </s>
+	for len(q)+len(d) > budget {
+		if len(d) >= len(q) {
+			d = d[:len(d)-1]
+		} else {
+			q = q[:len(q)-1]
+		}
+	}
+
+	ids := make([]int, 0, len(q)+len(d)+4)
+	ids = append(ids, t.bosID)
+	ids = append(ids, q...)
+	ids = append(ids, t.eosID, t.eosID)
+	ids = append(ids, d...)
+	ids = append(ids, t.eosID)
+	return ids
+}
+
+func truncateRunes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	i := 0
+	for pos := range s {
+		if i == n {
+			return s[:pos]
+		}
+		i++
+	}
+	return s
+}
@@ -0,0 +1,90 @@
+package reranking
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// testTokenizerJSON is a minimal XLM-RoBERTa style tokenizer.json.
+const testTokenizerJSON = `{
+  "added_tokens": [
+    {"id": 0, "content": "<s>"},
+    {"id": 1, "content": "<pad>"},
+    {"id": 2, "content": "</s>"},
+    {"id": 3, "content": "<unk>"}
+  ],
+  "model": {
+    "type": "Unigram",
+    "unk_id": 3,
+    "vocab": [
+      ["<s>", 0.0], ["<pad>", 0.0], ["</s>", 0.0], ["<unk>", 0.0],
+      ["▁", -2.0],
+      ["▁retry", -3.0],
+      ["▁re", -4.0],
+      ["try", -4.0],
+      ["▁policy", -3.5],
+      ["ing", -3.0],
+      ["▁x", -5.0]
+    ]
+  }
+}`
+
+func loadTestTokenizer(t *testing.T) *unigramTokenizer {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "tokenizer.json")
+	if err := os.WriteFile(path, []byte(testTokenizerJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tok, err := loadUnigramTokenizer(path)
+	if err != nil {
+		t.Fatalf("loadUnigramTokenizer failed: %v", err)
+	}
+	return tok
+}
+
+func TestUnigramTokenizer_Tokenize(t *testing.T) {
+	tok := loadTestTokenizer(t)
+
+	tests := []struct {
+		name string
+		text string
+		want []int
+	}{
+		{name: "Given a whole-word piece Then prefers it over sub-pieces", text: "retry", want: []int{5}},
+		{name: "Given multiple words Then each starts with a metaspace piece", text: "retry  policy", want: []int{5, 8}},
+		{name: "Given a suffix Then segments into known pieces", text: "retrying", want: []int{5, 9}},
+		{name: "Given unknown characters Then consecutive unknowns fuse into one", text: "x??", want: []int{10, 3}},
+		{name: "Given full-width text Then NFKC normalizes it", text: "ｒｅｔｒｙ", want: []int{5}},
+		{name: "Given empty text Then no tokens", text: "   ", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tok.tokenize(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tokenize(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnigramTokenizer_EncodePair(t *testing.T) {
+	tok := loadTestTokenizer(t)
+
+	t.Run("Given a short pair Then wraps it in special tokens", func(t *testing.T) {
+		got := tok.encodePair("retry", "policy", 512)
+		want := []int{0, 5, 2, 2, 8, 2}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("encodePair = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Given a long document Then truncates the document first", func(t *testing.T) {
+		got := tok.encodePair("retry policy", "retry retry retry retry retry", 8)
+		want := []int{0, 5, 8, 2, 2, 5, 5, 2}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("encodePair = %v, want %v", got, want)
+		}
+	})
+}
@@ -0,0 +1,364 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// CodeSymbolRecord is a declaration in the code graph (see package
+// codegraph). Symbol IDs are import-path qualified, e.g.
+// "github.com/acme/ledger.Ledger.Post".
+type CodeSymbolRecord struct {
+	ID        string
+	Package   string
+	Name      string
+	Kind      string
+	Parent    string // declaring type of a method; "" otherwise
+	Signature string
+	File      string
+	StartLine int
+	EndLine   int
+	ItemID    string // chunk item holding the definition; "" if unknown
+}
+
+// CodeRefRecord is a reference to a symbol. Symbol is "" when only the
+// referenced name is known, such as a method call on an untyped receiver.
+type CodeRefRecord struct {
+	Symbol string
+	Name   string
+	Kind   string
+	From   string // symbol the reference appears in
+	File   string
+	Line   int
+	Column int
+}
+
+// CodeImportRecord is a package import made by one file.
+type CodeImportRecord struct {
+	File    string
+	Package string // importing package
+	Path    string // imported package
+	Alias   string
+}
+
+// CodeGraphFile is the code graph of one source file. The File fields of
+// its records are ignored in favour of Path.
+type CodeGraphFile struct {
+	Path    string
+	Symbols []CodeSymbolRecord
+	Refs    []CodeRefRecord
+	Imports []CodeImportRecord
+}
+
+// CodeSymbolQuery selects code symbols. Empty fields do not filter.
+type CodeSymbolQuery struct {
+	// Symbol matches IDs equal to it or ending in it after a "/" or ".",
+	// so "Ledger.Post", "ledger.Ledger.Post" and the full ID all match.
+	Symbol string
+	Names  []string // exact names
+	Kind   string
+	Parent string
+	Limit  int
+}
+
+// CodeRefQuery selects references to Symbol, plus unresolved references
+// named Name, plus references made from From. At least one must be set.
+type CodeRefQuery struct {
+	Symbol string
+	Name   string
+	From   string
+	Kind   string
+	Limit  int
+}
+
+// CodeImportQuery selects imports made by Package or of Path. Both match
+// exactly or as a path suffix after "/"; at least one must be set.
+type CodeImportQuery struct {
+	Package string
+	Path    string
+}
+
+// codeGraphSchema creates the code graph tables. Rows are keyed by file so
+// that re-indexing a file replaces its rows.
+const codeGraphSchema = `
+	CREATE TABLE IF NOT EXISTS code_symbols (
+		id TEXT NOT NULL,
+		package TEXT NOT NULL,
+		name TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		parent TEXT NOT NULL DEFAULT '',
+		signature TEXT NOT NULL DEFAULT '',
+		file TEXT NOT NULL,
+		start_line INTEGER NOT NULL,
+		end_line INTEGER NOT NULL,
+		item_id TEXT NOT NULL DEFAULT ''
+	);
+
+	CREATE TABLE IF NOT EXISTS code_refs (
+		symbol TEXT NOT NULL,
+		name TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		from_symbol TEXT NOT NULL DEFAULT '',
+		file TEXT NOT NULL,
+		line INTEGER NOT NULL,
+		col INTEGER NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS code_imports (
+		file TEXT NOT NULL,
+		package TEXT NOT NULL,
+		path TEXT NOT NULL,
+		alias TEXT NOT NULL DEFAULT ''
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_code_symbols_id ON code_symbols(id);
+	CREATE INDEX IF NOT EXISTS idx_code_symbols_name ON code_symbols(name);
+	CREATE INDEX IF NOT EXISTS idx_code_symbols_parent ON code_symbols(parent);
+	CREATE INDEX IF NOT EXISTS idx_code_symbols_file ON code_symbols(file);
+	CREATE INDEX IF NOT EXISTS idx_code_refs_symbol ON code_refs(symbol);
+	CREATE INDEX IF NOT EXISTS idx_code_refs_name ON code_refs(name);
+	CREATE INDEX IF NOT EXISTS idx_code_refs_from ON code_refs(from_symbol);
+	CREATE INDEX IF NOT EXISTS idx_code_refs_file ON code_refs(file);
+	CREATE INDEX IF NOT EXISTS idx_code_imports_path ON code_imports(path);
+	CREATE INDEX IF NOT EXISTS idx_code_imports_package ON code_imports(package);
+	CREATE INDEX IF NOT EXISTS idx_code_imports_file ON code_imports(file);
+`
+
+// createCodeGraphTables creates the code graph tables. Go files indexed
+// before the tables existed have their content hash cleared, so the next
+// index run re-indexes them instead of skipping them as unchanged.
+func (s *MetadataStore) createCodeGraphTables() error {
+	var existing int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'code_symbols'").Scan(&existing); err != nil {
+		return fmt.Errorf("inspect code graph tables: %w", err)
+	}
+	if _, err := s.db.Exec(codeGraphSchema); err != nil {
+		return fmt.Errorf("create code graph tables: %w", err)
+	}
+	if existing == 0 {
+		if _, err := s.db.Exec("UPDATE indexed_files SET content_hash = '' WHERE path LIKE '%.go'"); err != nil {
+			return fmt.Errorf("mark Go files for re-indexing: %w", err)
+		}
+	}
+	return nil
+}
+
+// ReplaceCodeGraph replaces the code graph rows of a file in one
+// transaction.
+func (s *MetadataStore) ReplaceCodeGraph(g *CodeGraphFile) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := deleteCodeGraph(tx, g.Path); err != nil {
+		return err
+	}
+
+	symbols, err := tx.Prepare(`
+		INSERT INTO code_symbols (id, package, name, kind, parent, signature, file, start_line, end_line, item_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer symbols.Close()
+	for _, sym := range g.Symbols {
+		if _, err := symbols.Exec(sym.ID, sym.Package, sym.Name, sym.Kind, sym.Parent, sym.Signature, g.Path, sym.StartLine, sym.EndLine, sym.ItemID); err != nil {
+			return fmt.Errorf("insert symbol %s: %w", sym.ID, err)
+		}
+	}
+
+	refs, err := tx.Prepare(`
+		INSERT INTO code_refs (symbol, name, kind, from_symbol, file, line, col)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return err
+	}
+	defer refs.Close()
+	for _, ref := range g.Refs {
+		if _, err := refs.Exec(ref.Symbol, ref.Name, ref.Kind, ref.From, g.Path, ref.Line, ref.Column); err != nil {
+			return fmt.Errorf("insert reference to %s: %w", ref.Name, err)
+		}
+	}
+
+	imports, err := tx.Prepare("INSERT INTO code_imports (file, package, path, alias) VALUES (?, ?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer imports.Close()
+	for _, imp := range g.Imports {
+		if _, err := imports.Exec(g.Path, imp.Package, imp.Path, imp.Alias); err != nil {
+			return fmt.Errorf("insert import %s: %w", imp.Path, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// DeleteCodeGraph removes the code graph rows of a file.
+func (s *MetadataStore) DeleteCodeGraph(path string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := deleteCodeGraph(tx, path); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func deleteCodeGraph(tx *sql.Tx, path string) error {
+	for _, table := range []string{"code_symbols", "code_refs", "code_imports"} {
+		if _, err := tx.Exec("DELETE FROM "+table+" WHERE file = ?", path); err != nil {
+			return fmt.Errorf("clear %s for %s: %w", table, path, err)
+		}
+	}
+	return nil
+}
+
+// FindCodeSymbols returns the symbols matching q, ordered by ID.
+func (s *MetadataStore) FindCodeSymbols(q CodeSymbolQuery) ([]CodeSymbolRecord, error) {
+	var where []string
+	var args []any
+	if q.Symbol != "" {
+		// the name condition lets the lookup use idx_code_symbols_name
+		where = append(where, "name = ? AND (id = ? OR substr(id, -length(?) - 1) IN ('/' || ?, '.' || ?))")
+		args = append(args, lastSegment(q.Symbol), q.Symbol, q.Symbol, q.Symbol, q.Symbol)
+	}
+	if len(q.Names) > 0 {
+		where = append(where, "name IN ("+strings.TrimSuffix(strings.Repeat("?,", len(q.Names)), ",")+")")
+		for _, name := range q.Names {
+			args = append(args, name)
+		}
+	}
+	if q.Kind != "" {
+		where = append(where, "kind = ?")
+		args = append(args, q.Kind)
+	}
+	if q.Parent != "" {
+		where = append(where, "parent = ?")
+		args = append(args, q.Parent)
+	}
+
+	query := `SELECT id, package, name, kind, parent, signature, file, start_line, end_line, item_id FROM code_symbols`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY id, file, start_line"
+	if q.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", q.Limit)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var symbols []CodeSymbolRecord
+	for rows.Next() {
+		var sym CodeSymbolRecord
+		if err := rows.Scan(&sym.ID, &sym.Package, &sym.Name, &sym.Kind, &sym.Parent, &sym.Signature, &sym.File, &sym.StartLine, &sym.EndLine, &sym.ItemID); err != nil {
+			return nil, err
+		}
+		symbols = append(symbols, sym)
+	}
+	return symbols, rows.Err()
+}
+
+// FindCodeRefs returns the references matching q, ordered by location.
+func (s *MetadataStore) FindCodeRefs(q CodeRefQuery) ([]CodeRefRecord, error) {
+	var match []string
+	var args []any
+	if q.Symbol != "" {
+		match = append(match, "symbol = ?")
+		args = append(args, q.Symbol)
+	}
+	if q.Name != "" {
+		match = append(match, "(symbol = '' AND name = ?)")
+		args = append(args, q.Name)
+	}
+	if q.From != "" {
+		match = append(match, "from_symbol = ?")
+		args = append(args, q.From)
+	}
+	if len(match) == 0 {
+		return nil, fmt.Errorf("code reference query needs a symbol, name or from")
+	}
+
+	query := `SELECT symbol, name, kind, from_symbol, file, line, col FROM code_refs WHERE (` + strings.Join(match, " OR ") + ")"
+	if q.Kind != "" {
+		query += " AND kind = ?"
+		args = append(args, q.Kind)
+	}
+	query += " ORDER BY file, line, col"
+	if q.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", q.Limit)
+	}
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var refs []CodeRefRecord
+	for rows.Next() {
+		var ref CodeRefRecord
+		if err := rows.Scan(&ref.Symbol, &ref.Name, &ref.Kind, &ref.From, &ref.File, &ref.Line, &ref.Column); err != nil {
+			return nil, err
+		}
+		refs = append(refs, ref)
+	}
+	return refs, rows.Err()
+}
+
+// FindCodeImports returns the imports matching q, ordered by importing
+// package and imported path.
+func (s *MetadataStore) FindCodeImports(q CodeImportQuery) ([]CodeImportRecord, error) {
+	var match []string
+	var args []any
+	for _, c := range []struct{ column, value string }{{"package", q.Package}, {"path", q.Path}} {
+		if c.value != "" {
+			match = append(match, fmt.Sprintf("(%[1]s = ? OR substr(%[1]s, -length(?) - 1) = '/' || ?)", c.column))
+			args = append(args, c.value, c.value, c.value)
+		}
+	}
+	if len(match) == 0 {
+		return nil, fmt.Errorf("code import query needs a package or path")
+	}
+
+	rows, err := s.db.Query(`
+		SELECT file, package, path, alias FROM code_imports
+		WHERE `+strings.Join(match, " OR ")+`
+		ORDER BY package, path, file
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var imports []CodeImportRecord
+	for rows.Next() {
+		var imp CodeImportRecord
+		if err := rows.Scan(&imp.File, &imp.Package, &imp.Path, &imp.Alias); err != nil {
+			return nil, err
+		}
+		imports = append(imports, imp)
+	}
+	return imports, rows.Err()
+}
+
+// lastSegment returns the unqualified name in a symbol ID.
+func lastSegment(symbol string) string {
+	if i := strings.LastIndexAny(symbol, "./"); i >= 0 {
+		return symbol[i+1:]
+	}
+	return symbol
+}
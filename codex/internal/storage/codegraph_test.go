@@ -0,0 +1,233 @@
+package storage
+
+import (
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func seedCodeGraph(t *testing.T, store *MetadataStore) {
+	t.Helper()
+	files := []*CodeGraphFile{
+		{
+			Path: "/repo/ledger/ledger.go",
+			Symbols: []CodeSymbolRecord{
+				{ID: "example.com/shop/ledger.Ledger", Package: "example.com/shop/ledger", Name: "Ledger", Kind: "struct", StartLine: 3, EndLine: 5, ItemID: "p-chunk-0"},
+				{ID: "example.com/shop/ledger.Ledger.Post", Package: "example.com/shop/ledger", Name: "Post", Kind: "method", Parent: "example.com/shop/ledger.Ledger", StartLine: 7, EndLine: 9},
+			},
+			Imports: []CodeImportRecord{{Package: "example.com/shop/ledger", Path: "fmt"}},
+		},
+		{
+			Path: "/repo/api/handler.go",
+			Symbols: []CodeSymbolRecord{
+				{ID: "example.com/shop/api.Post", Package: "example.com/shop/api", Name: "Post", Kind: "function", StartLine: 10, EndLine: 20},
+			},
+			Refs: []CodeRefRecord{
+				{Symbol: "example.com/shop/ledger.Ledger.Post", Name: "Post", Kind: "call", From: "example.com/shop/api.Post", Line: 12, Column: 4},
+				{Name: "Post", Kind: "call", From: "example.com/shop/api.Post", Line: 15, Column: 4},
+				{Name: "Save", Kind: "call", From: "example.com/shop/api.Post", Line: 16, Column: 4},
+			},
+			Imports: []CodeImportRecord{{Package: "example.com/shop/api", Path: "example.com/shop/ledger", Alias: "l"}},
+		},
+	}
+	for _, f := range files {
+		if err := store.ReplaceCodeGraph(f); err != nil {
+			t.Fatalf("ReplaceCodeGraph(%s): %v", f.Path, err)
+		}
+	}
+}
+
+func symbolIDs(symbols []CodeSymbolRecord) []string {
+	var ids []string
+	for _, s := range symbols {
+		ids = append(ids, s.ID)
+	}
+	return ids
+}
+
+func TestMetadataStore_FindCodeSymbols(t *testing.T) {
+	store, cleanup := createTestMetadataStore(t)
+	defer cleanup()
+	seedCodeGraph(t, store)
+
+	tests := []struct {
+		name  string
+		query CodeSymbolQuery
+		want  []string
+	}{
+		{"Given a full ID, When finding, Then it matches exactly", CodeSymbolQuery{Symbol: "example.com/shop/ledger.Ledger.Post"}, []string{"example.com/shop/ledger.Ledger.Post"}},
+		{"Given a package-qualified name, When finding, Then the path suffix matches", CodeSymbolQuery{Symbol: "ledger.Ledger.Post"}, []string{"example.com/shop/ledger.Ledger.Post"}},
+		{"Given a type-qualified name, When finding, Then the dotted suffix matches", CodeSymbolQuery{Symbol: "Ledger.Post"}, []string{"example.com/shop/ledger.Ledger.Post"}},
+		{"Given a bare name, When finding, Then every symbol with it matches", CodeSymbolQuery{Symbol: "Post"}, []string{"example.com/shop/api.Post", "example.com/shop/ledger.Ledger.Post"}},
+		{"Given a partial name, When finding, Then nothing matches", CodeSymbolQuery{Symbol: "dger.Post"}, nil},
+		{"Given a parent and kind, When finding, Then its methods match", CodeSymbolQuery{Parent: "example.com/shop/ledger.Ledger", Kind: "method"}, []string{"example.com/shop/ledger.Ledger.Post"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.FindCodeSymbols(tt.query)
+			if err != nil {
+				t.Fatalf("FindCodeSymbols: %v", err)
+			}
+			if ids := symbolIDs(got); !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("symbols = %q, want %q", ids, tt.want)
+			}
+		})
+	}
+
+	t.Run("Given a stored symbol, When finding, Then all fields round-trip", func(t *testing.T) {
+		got, _ := store.FindCodeSymbols(CodeSymbolQuery{Symbol: "ledger.Ledger"})
+		want := CodeSymbolRecord{ID: "example.com/shop/ledger.Ledger", Package: "example.com/shop/ledger", Name: "Ledger", Kind: "struct", File: "/repo/ledger/ledger.go", StartLine: 3, EndLine: 5, ItemID: "p-chunk-0"}
+		if len(got) != 1 || !reflect.DeepEqual(got[0], want) {
+			t.Errorf("symbols = %+v, want %+v", got, want)
+		}
+	})
+}
+
+func TestMetadataStore_FindCodeRefs(t *testing.T) {
+	store, cleanup := createTestMetadataStore(t)
+	defer cleanup()
+	seedCodeGraph(t, store)
+
+	t.Run("Given a symbol and its name, When finding, Then resolved and unresolved references match", func(t *testing.T) {
+		refs, err := store.FindCodeRefs(CodeRefQuery{Symbol: "example.com/shop/ledger.Ledger.Post", Name: "Post"})
+		if err != nil {
+			t.Fatalf("FindCodeRefs: %v", err)
+		}
+		if len(refs) != 2 || refs[0].Line != 12 || refs[1].Line != 15 || refs[1].Symbol != "" {
+			t.Errorf("refs = %+v, want lines 12 and 15", refs)
+		}
+		if refs[0].File != "/repo/api/handler.go" || refs[0].From != "example.com/shop/api.Post" {
+			t.Errorf("ref = %+v", refs[0])
+		}
+	})
+
+	t.Run("Given only a symbol, When finding, Then unresolved references are left out", func(t *testing.T) {
+		refs, _ := store.FindCodeRefs(CodeRefQuery{Symbol: "example.com/shop/ledger.Ledger.Post"})
+		if len(refs) != 1 {
+			t.Errorf("refs = %+v, want one", refs)
+		}
+	})
+
+	t.Run("Given a from symbol and kind, When finding, Then the references made there match", func(t *testing.T) {
+		refs, _ := store.FindCodeRefs(CodeRefQuery{From: "example.com/shop/api.Post", Kind: "call"})
+		if len(refs) != 3 {
+			t.Errorf("refs = %+v, want three", refs)
+		}
+	})
+
+	t.Run("Given an empty query, When finding, Then it fails", func(t *testing.T) {
+		if _, err := store.FindCodeRefs(CodeRefQuery{Kind: "call"}); err == nil {
+			t.Error("want error")
+		}
+	})
+}
+
+func TestMetadataStore_FindCodeImports(t *testing.T) {
+	store, cleanup := createTestMetadataStore(t)
+	defer cleanup()
+	seedCodeGraph(t, store)
+
+	t.Run("Given a package suffix, When finding, Then its imports and importers match", func(t *testing.T) {
+		imports, err := store.FindCodeImports(CodeImportQuery{Package: "shop/ledger", Path: "shop/ledger"})
+		if err != nil {
+			t.Fatalf("FindCodeImports: %v", err)
+		}
+		want := []CodeImportRecord{
+			{File: "/repo/api/handler.go", Package: "example.com/shop/api", Path: "example.com/shop/ledger", Alias: "l"},
+			{File: "/repo/ledger/ledger.go", Package: "example.com/shop/ledger", Path: "fmt"},
+		}
+		if !reflect.DeepEqual(imports, want) {
+			t.Errorf("imports = %+v, want %+v", imports, want)
+		}
+	})
+}
+
+func TestMetadataStore_ReplaceCodeGraph(t *testing.T) {
+	store, cleanup := createTestMetadataStore(t)
+	defer cleanup()
+	seedCodeGraph(t, store)
+
+	t.Run("Given a re-indexed file, When replacing, Then its old rows are dropped", func(t *testing.T) {
+		err := store.ReplaceCodeGraph(&CodeGraphFile{
+			Path:    "/repo/ledger/ledger.go",
+			Symbols: []CodeSymbolRecord{{ID: "example.com/shop/ledger.Book", Package: "example.com/shop/ledger", Name: "Book", Kind: "struct"}},
+		})
+		if err != nil {
+			t.Fatalf("ReplaceCodeGraph: %v", err)
+		}
+		got, _ := store.FindCodeSymbols(CodeSymbolQuery{Kind: "struct"})
+		if ids := symbolIDs(got); !reflect.DeepEqual(ids, []string{"example.com/shop/ledger.Book"}) {
+			t.Errorf("structs = %q, want only Book", ids)
+		}
+		imports, _ := store.FindCodeImports(CodeImportQuery{Path: "fmt"})
+		if len(imports) != 0 {
+			t.Errorf("imports = %+v, want none", imports)
+		}
+	})
+
+	t.Run("Given a deleted file, When deleting its graph, Then its rows are gone", func(t *testing.T) {
+		if err := store.DeleteCodeGraph("/repo/api/handler.go"); err != nil {
+			t.Fatalf("DeleteCodeGraph: %v", err)
+		}
+		refs, _ := store.FindCodeRefs(CodeRefQuery{Name: "Save"})
+		symbols, _ := store.FindCodeSymbols(CodeSymbolQuery{Symbol: "api.Post"})
+		if len(refs) != 0 || len(symbols) != 0 {
+			t.Errorf("refs = %+v, symbols = %+v, want none", refs, symbols)
+		}
+	})
+}
+
+func TestMetadataStore_UpgradeMarksGoFilesForReindex(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "old.db")
+
+	// Given a database indexed before the code graph existed
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	_, err = db.Exec(`
+		CREATE TABLE indexed_files (
+			path TEXT PRIMARY KEY, content_hash TEXT NOT NULL, parent_id TEXT NOT NULL,
+			chunk_ids TEXT NOT NULL, scope TEXT NOT NULL DEFAULT 'project', indexed_at DATETIME NOT NULL
+		);
+		INSERT INTO indexed_files VALUES ('/repo/main.go', 'h1', 'p1', '[]', 'project', '2024-01-01 00:00:00');
+		INSERT INTO indexed_files VALUES ('/repo/README.md', 'h2', 'p2', '[]', 'project', '2024-01-01 00:00:00');
+	`)
+	db.Close()
+	if err != nil {
+		t.Fatalf("seed old schema: %v", err)
+	}
+
+	t.Run("Given Go files indexed before the code graph, When opening, Then only their hashes are cleared", func(t *testing.T) {
+		store, err := NewMetadataStore(dbPath)
+		if err != nil {
+			t.Fatalf("NewMetadataStore: %v", err)
+		}
+		defer store.Close()
+
+		goFile, _ := store.GetIndexedFile("/repo/main.go")
+		doc, _ := store.GetIndexedFile("/repo/README.md")
+		if goFile.ContentHash != "" || doc.ContentHash != "h2" {
+			t.Errorf("hashes = %q, %q, want Go cleared and doc kept", goFile.ContentHash, doc.ContentHash)
+		}
+
+		goFile.ContentHash, goFile.IndexedAt = "h3", time.Now()
+		if err := store.SaveIndexedFile(goFile); err != nil {
+			t.Fatalf("SaveIndexedFile: %v", err)
+		}
+	})
+
+	t.Run("Given the code graph tables exist, When reopening, Then hashes are kept", func(t *testing.T) {
+		store, err := NewMetadataStore(dbPath)
+		if err != nil {
+			t.Fatalf("NewMetadataStore: %v", err)
+		}
+		defer store.Close()
+
+		if goFile, _ := store.GetIndexedFile("/repo/main.go"); goFile.ContentHash != "h3" {
+			t.Errorf("hash = %q, want h3", goFile.ContentHash)
+		}
+	})
+}
@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"strings"
+	"time"
+)
+
+// SearchFilter restricts retrieval to items matching every set field.
+// Zero-valued fields are ignored, except GitRef: items indexed from a git
+// ref by IndexGit are snapshots of older code, so the zero SearchFilter
+// matches every item but them.
+//
+// Scope is matched exactly, except that when Project is set:
+//   - "project" matches only project items belonging to Project
+//   - "both" matches global items plus project items belonging to Project
+type SearchFilter struct {
+	Types         []string  // item type must be one of these
+	Scope         string    // global, project, or both
+	Project       string    // current project identity for project/both scopes
+	Tags          []string  // item must carry every tag
+	SourcePrefix  string    // item source must start with this prefix
+	GitRef        string    // item was indexed from this git ref (metadata git_ref); "" excludes them
+	CreatedAfter  time.Time // inclusive
+	CreatedBefore time.Time // exclusive
+	UpdatedAfter  time.Time // inclusive
+	UpdatedBefore time.Time // exclusive
+}
+
+// IsEmpty reports whether the filter has no constraints but the default
+// one, leaving out git ref snapshots.
+func (f SearchFilter) IsEmpty() bool {
+	return len(f.Types) == 0 && f.Scope == "" && len(f.Tags) == 0 && f.SourcePrefix == "" && f.GitRef == "" &&
+		f.CreatedAfter.IsZero() && f.CreatedBefore.IsZero() &&
+		f.UpdatedAfter.IsZero() && f.UpdatedBefore.IsZero()
+}
+
+// Matches reports whether an item satisfies the filter.
+// It mirrors the SQL produced by whereClause.
+func (f SearchFilter) Matches(item *ItemRecord) bool {
+	if len(f.Types) > 0 && !containsString(f.Types, item.Type) {
+		return false
+	}
+	switch {
+	case f.Scope == "both":
+		if item.Scope != "global" && !(item.Scope == "project" && (f.Project == "" || item.Project == f.Project)) {
+			return false
+		}
+	case f.Scope == "project" && f.Project != "":
+		if item.Scope != "project" || item.Project != f.Project {
+			return false
+		}
+	case f.Scope != "":
+		if item.Scope != f.Scope {
+			return false
+		}
+	}
+	for _, tag := range f.Tags {
+		if !containsString(item.Tags, tag) {
+			return false
+		}
+	}
+	if f.SourcePrefix != "" && !strings.HasPrefix(item.Source, f.SourcePrefix) {
+		return false
+	}
+	if ref, _ := item.Metadata["git_ref"].(string); ref != f.GitRef {
+		return false
+	}
+	if !f.CreatedAfter.IsZero() && item.CreatedAt.Before(f.CreatedAfter) {
+		return false
+	}
+	if !f.CreatedBefore.IsZero() && !item.CreatedAt.Before(f.CreatedBefore) {
+		return false
+	}
+	if !f.UpdatedAfter.IsZero() && item.UpdatedAt.Before(f.UpdatedAfter) {
+		return false
+	}
+	if !f.UpdatedBefore.IsZero() && !item.UpdatedAt.Before(f.UpdatedBefore) {
+		return false
+	}
+	return true
+}
+
+// whereClause renders the filter as SQL conditions on the items table
+// aliased as alias. Each condition is prefixed with " AND ", so the result
+// can be appended to an existing WHERE clause.
+func (f SearchFilter) whereClause(alias string) (string, []any) {
+	var sb strings.Builder
+	var args []any
+	col := alias + "."
+
+	if len(f.Types) > 0 {
+		sb.WriteString(" AND " + col + "type IN (?" + strings.Repeat(", ?", len(f.Types)-1) + ")")
+		for _, t := range f.Types {
+			args = append(args, t)
+		}
+	}
+	switch {
+	case f.Scope == "both" && f.Project != "":
+		sb.WriteString(" AND (" + col + "scope = 'global' OR (" + col + "scope = 'project' AND " + col + "project = ?))")
+		args = append(args, f.Project)
+	case f.Scope == "both":
+		sb.WriteString(" AND " + col + "scope IN ('global', 'project')")
+	case f.Scope == "project" && f.Project != "":
+		sb.WriteString(" AND " + col + "scope = 'project' AND " + col + "project = ?")
+		args = append(args, f.Project)
+	case f.Scope != "":
+		sb.WriteString(" AND " + col + "scope = ?")
+		args = append(args, f.Scope)
+	}
+	for _, tag := range f.Tags {
+		sb.WriteString(" AND EXISTS (SELECT 1 FROM json_each(" + col + "tags) WHERE json_each.value = ?)")
+		args = append(args, tag)
+	}
+	if f.SourcePrefix != "" {
+		sb.WriteString(" AND instr(" + col + "source, ?) = 1")
+		args = append(args, f.SourcePrefix)
+	}
+	if f.GitRef != "" {
+		sb.WriteString(" AND " + col + "git_ref = ?")
+		args = append(args, f.GitRef)
+	} else {
+		sb.WriteString(" AND " + col + "git_ref IS NULL")
+	}
+
+	// Timestamps are compared via julianday() so differing UTC offsets in the
+	// stored text still order correctly.
+	timeConds := []struct {
+		t    time.Time
+		expr string
+	}{
+		{f.CreatedAfter, "julianday(" + col + "created_at) >= julianday(?)"},
+		{f.CreatedBefore, "julianday(" + col + "created_at) < julianday(?)"},
+		{f.UpdatedAfter, "julianday(" + col + "updated_at) >= julianday(?)"},
+		{f.UpdatedBefore, "julianday(" + col + "updated_at) < julianday(?)"},
+	}
+	for _, c := range timeConds {
+		if c.t.IsZero() {
+			continue
+		}
+		sb.WriteString(" AND " + c.expr)
+		args = append(args, c.t.UTC().Format("2006-01-02 15:04:05.999999999"))
+	}
+
+	return sb.String(), args
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,211 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+)
+
+// filterTestItems covers every filterable field with distinct values.
+func filterTestItems() []*ItemRecord {
+	day := func(d int) time.Time {
+		return time.Date(2024, 3, d, 12, 0, 0, 0, time.UTC)
+	}
+	items := []*ItemRecord{
+		{ID: "f1", Type: "failure", Title: "Webhook timeout", Content: "retry webhook delivery", Tags: []string{"payments", "webhooks"}, Scope: "project", Source: "internal/payments/webhook.go", CreatedAt: day(1), UpdatedAt: day(10)},
+		{ID: "f2", Type: "failure", Title: "Cache stampede", Content: "retry with jitter", Tags: []string{"infra"}, Scope: "global", Source: "internal/cache/cache.go", CreatedAt: day(5), UpdatedAt: day(5)},
+		{ID: "p1", Type: "pattern", Title: "Retry policy", Content: "retry with backoff", Tags: []string{"payments"}, Scope: "project", Source: "docs/retry.md", Metadata: map[string]any{"git_ref": "release/2.3"}, CreatedAt: day(8), UpdatedAt: day(20)},
+		{ID: "d1", Type: "decision", Title: "Use queues", Content: "retry through queues", Tags: nil, Scope: "global", Source: "", CreatedAt: day(15), UpdatedAt: day(15)},
+	}
+	// Mixed offsets must compare by instant, not by text
+	items[3].CreatedAt = items[3].CreatedAt.In(time.FixedZone("UTC+9", 9*3600))
+	return items
+}
+
+func TestSearchFilter_SQLMatchesPredicate(t *testing.T) {
+	store, cleanup := createTestMetadataStore(t)
+	defer cleanup()
+
+	items := filterTestItems()
+	seedTestItems(t, store, items)
+
+	tests := []struct {
+		name   string
+		filter SearchFilter
+		want   []string
+	}{
+		{name: "Given empty filter, Then all items but git ref snapshots match", filter: SearchFilter{}, want: []string{"d1", "f1", "f2"}},
+		{name: "Given types, Then only those types match", filter: SearchFilter{Types: []string{"failure", "decision"}}, want: []string{"d1", "f1", "f2"}},
+		{name: "Given scope, Then only that scope matches", filter: SearchFilter{Scope: "global"}, want: []string{"d1", "f2"}},
+		{name: "Given one tag, Then items carrying it match", filter: SearchFilter{Tags: []string{"payments"}}, want: []string{"f1"}},
+		{name: "Given two tags, Then items must carry both", filter: SearchFilter{Tags: []string{"payments", "webhooks"}}, want: []string{"f1"}},
+		{name: "Given source prefix, Then matching sources match", filter: SearchFilter{SourcePrefix: "internal/"}, want: []string{"f1", "f2"}},
+		{name: "Given git ref, Then items indexed from it match", filter: SearchFilter{GitRef: "release/2.3"}, want: []string{"p1"}},
+		{name: "Given git ref and tag, Then only that ref's items carrying it match", filter: SearchFilter{GitRef: "release/2.3", Tags: []string{"payments"}}, want: []string{"p1"}},
+		{name: "Given another git ref, Then nothing matches", filter: SearchFilter{GitRef: "release/2.4"}, want: nil},
+		{name: "Given created range, Then bounds are [after, before)", filter: SearchFilter{CreatedAfter: time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC), CreatedBefore: time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)}, want: []string{"f2"}},
+		{name: "Given updated after, Then later updates match", filter: SearchFilter{UpdatedAfter: time.Date(2024, 3, 12, 0, 0, 0, 0, time.UTC)}, want: []string{"d1"}},
+		{name: "Given updated before, Then earlier updates match", filter: SearchFilter{UpdatedBefore: time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)}, want: []string{"f2"}},
+		{name: "Given combined filters, Then all must hold", filter: SearchFilter{Types: []string{"failure"}, Tags: []string{"payments"}, Scope: "project"}, want: []string{"f1"}},
+		{name: "Given unmatched filter, Then nothing matches", filter: SearchFilter{Types: []string{"runbook"}}, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.FilterItemIDs(tt.filter)
+			if err != nil {
+				t.Fatalf("FilterItemIDs failed: %v", err)
+			}
+			sort.Strings(got)
+			if !equalStrings(got, tt.want) {
+				t.Errorf("FilterItemIDs = %v, want %v", got, tt.want)
+			}
+
+			var matched []string
+			for _, item := range items {
+				if tt.filter.Matches(item) {
+					matched = append(matched, item.ID)
+				}
+			}
+			sort.Strings(matched)
+			if !equalStrings(matched, tt.want) {
+				t.Errorf("Matches = %v, want %v", matched, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchFilter_ProjectScope(t *testing.T) {
+	store, cleanup := createTestMetadataStore(t)
+	defer cleanup()
+
+	items := []*ItemRecord{
+		{ID: "g1", Type: "pattern", Title: "Global", Content: "shared", Scope: "global"},
+		{ID: "a1", Type: "pattern", Title: "Repo A", Content: "local", Scope: "project", Project: "/src/a"},
+		{ID: "b1", Type: "pattern", Title: "Repo B", Content: "local", Scope: "project", Project: "/src/b"},
+		{ID: "u1", Type: "pattern", Title: "Unattributed", Content: "local", Scope: "project"},
+	}
+	seedTestItems(t, store, items)
+
+	tests := []struct {
+		name   string
+		filter SearchFilter
+		want   []string
+	}{
+		{name: "Given scope project with project, Then only that project's items match", filter: SearchFilter{Scope: "project", Project: "/src/a"}, want: []string{"a1"}},
+		{name: "Given scope project without project, Then every project item matches", filter: SearchFilter{Scope: "project"}, want: []string{"a1", "b1", "u1"}},
+		{name: "Given scope both with project, Then global and that project's items match", filter: SearchFilter{Scope: "both", Project: "/src/a"}, want: []string{"a1", "g1"}},
+		{name: "Given scope both without project, Then global and all project items match", filter: SearchFilter{Scope: "both"}, want: []string{"a1", "b1", "g1", "u1"}},
+		{name: "Given scope global with project, Then project is ignored", filter: SearchFilter{Scope: "global", Project: "/src/a"}, want: []string{"g1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := store.FilterItemIDs(tt.filter)
+			if err != nil {
+				t.Fatalf("FilterItemIDs failed: %v", err)
+			}
+			sort.Strings(got)
+			if !equalStrings(got, tt.want) {
+				t.Errorf("FilterItemIDs = %v, want %v", got, tt.want)
+			}
+
+			var matched []string
+			for _, item := range items {
+				if tt.filter.Matches(item) {
+					matched = append(matched, item.ID)
+				}
+			}
+			sort.Strings(matched)
+			if !equalStrings(matched, tt.want) {
+				t.Errorf("Matches = %v, want %v", matched, tt.want)
+			}
+		})
+	}
+}
+
+func TestMetadataStore_KeywordSearchFiltered(t *testing.T) {
+	store, cleanup := createTestMetadataStore(t)
+	defer cleanup()
+
+	seedTestItems(t, store, filterTestItems())
+
+	t.Run("Given a type filter, When limit is 1, Then the match is not crowded out", func(t *testing.T) {
+		results, err := store.KeywordSearchFiltered("retry", 1, SearchFilter{Types: []string{"decision"}})
+		if err != nil {
+			t.Fatalf("KeywordSearchFiltered failed: %v", err)
+		}
+		if len(results) != 1 || results[0].ID != "d1" {
+			t.Errorf("expected only d1, got %+v", results)
+		}
+	})
+
+	t.Run("Given a tag filter, Then only tagged items outside git ref snapshots are returned", func(t *testing.T) {
+		results, err := store.KeywordSearchFiltered("retry", 10, SearchFilter{Tags: []string{"payments"}})
+		if err != nil {
+			t.Fatalf("KeywordSearchFiltered failed: %v", err)
+		}
+		var ids []string
+		for _, r := range results {
+			ids = append(ids, r.ID)
+		}
+		sort.Strings(ids)
+		if !equalStrings(ids, []string{"f1"}) {
+			t.Errorf("expected [f1], got %v", ids)
+		}
+	})
+}
+
+func TestVecStore_SearchWithOptions_Allowed(t *testing.T) {
+	vs, cleanup := createTestVecStore(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	vs.Upsert(ctx, "near", []float32{1.0, 0.0, 0.0})
+	vs.Upsert(ctx, "mid", []float32{0.7, 0.7, 0.0})
+	vs.Upsert(ctx, "far", []float32{0.0, 0.0, 1.0})
+
+	t.Run("Given an allow-list, Then only allowed IDs are ranked", func(t *testing.T) {
+		results, err := vs.SearchWithOptions(ctx, []float32{1.0, 0.0, 0.0}, 1, VectorSearchOptions{Allowed: map[string]bool{"far": true, "missing": true}})
+		if err != nil {
+			t.Fatalf("SearchWithOptions failed: %v", err)
+		}
+		if len(results) != 1 || results[0].ID != "far" {
+			t.Errorf("expected only far, got %+v", results)
+		}
+	})
+
+	t.Run("Given an empty allow-list, Then nothing is returned", func(t *testing.T) {
+		results, _ := vs.SearchWithOptions(ctx, []float32{1.0, 0.0, 0.0}, 10, VectorSearchOptions{Allowed: map[string]bool{}})
+		if len(results) != 0 {
+			t.Errorf("expected no results, got %+v", results)
+		}
+	})
+
+	t.Run("Given excluded IDs, Then the rest are ranked", func(t *testing.T) {
+		results, _ := vs.SearchWithOptions(ctx, []float32{1.0, 0.0, 0.0}, 10, VectorSearchOptions{Excluded: map[string]bool{"near": true}})
+		if len(results) != 2 || results[0].ID != "mid" {
+			t.Errorf("expected mid and far, got %+v", results)
+		}
+	})
+
+	t.Run("Given a nil allow-list, Then all vectors are ranked", func(t *testing.T) {
+		results, _ := vs.SearchWithOptions(ctx, []float32{1.0, 0.0, 0.0}, 10, VectorSearchOptions{})
+		if len(results) != 3 || results[0].ID != "near" {
+			t.Errorf("expected 3 results led by near, got %+v", results)
+		}
+	})
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
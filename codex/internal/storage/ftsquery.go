@@ -0,0 +1,236 @@
+package storage
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ftsColumns are the items_fts columns that may be targeted with column:term syntax.
+var ftsColumns = map[string]bool{
+	"title":   true,
+	"content": true,
+	"tags":    true,
+}
+
+// ftsStopWords are dropped from plain-text queries so that OR-joined queries
+// like "how did we handle auth" rank on the meaningful terms.
+var ftsStopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "did": true, "do": true, "does": true, "for": true,
+	"from": true, "how": true, "i": true, "in": true, "is": true, "it": true,
+	"of": true, "on": true, "or": true, "that": true, "the": true, "this": true,
+	"to": true, "was": true, "we": true, "what": true, "when": true,
+	"where": true, "which": true, "who": true, "why": true, "with": true,
+}
+
+// ftsTerm is one lexed element of a user query.
+type ftsTerm struct {
+	column string // optional column filter (title, content, tags)
+	text   string
+	phrase bool   // from a "quoted phrase"
+	op     string // explicit AND/OR connector; text is empty when set
+}
+
+// BuildFTSQuery converts free-form user input into a safe FTS5 MATCH expression.
+//
+// Every term is emitted as a quoted FTS5 string, so user input can never inject
+// FTS5 syntax. Plain words are joined with OR, letting BM25 rank documents that
+// contain more of the terms higher instead of requiring the exact phrase.
+// Identifier-like terms (snake_case, camelCase, dotted.paths) use prefix matching.
+//
+// Users may opt in to a small amount of syntax:
+//   - "quoted phrase"              exact phrase match
+//   - title:term, tags:"a phrase"  restrict a term to one column
+//   - AND / OR (upper case)        override the connector between two terms
+//
+// Returns "" when the input contains nothing searchable.
+func BuildFTSQuery(query string) string {
+	terms := lexFTSQuery(query)
+
+	// Drop stop words unless that would leave nothing to search for
+	keepStopWords := true
+	for _, t := range terms {
+		if t.op == "" && !isFTSStopWord(t) && ftsTermExpr(t) != "" {
+			keepStopWords = false
+			break
+		}
+	}
+
+	var parts []string
+	pendingOp := ""
+	for _, t := range terms {
+		if t.op != "" {
+			if len(parts) > 0 {
+				pendingOp = t.op
+			}
+			continue
+		}
+		if !keepStopWords && isFTSStopWord(t) {
+			continue
+		}
+		expr := ftsTermExpr(t)
+		if expr == "" {
+			continue
+		}
+		if len(parts) > 0 {
+			op := "OR"
+			if pendingOp != "" {
+				op = pendingOp
+			}
+			parts = append(parts, op)
+		}
+		parts = append(parts, expr)
+		pendingOp = ""
+	}
+
+	return strings.Join(parts, " ")
+}
+
+// lexFTSQuery splits a query into terms, phrases, column filters and operators.
+// Unbalanced quotes are ignored rather than treated as errors.
+func lexFTSQuery(query string) []ftsTerm {
+	rs := []rune(query)
+	var terms []ftsTerm
+
+	i := 0
+	for i < len(rs) {
+		if unicode.IsSpace(rs[i]) {
+			i++
+			continue
+		}
+
+		// Optional column:term prefix (only for known columns)
+		column := ""
+		if j := columnPrefixEnd(rs, i); j > 0 {
+			name := strings.ToLower(string(rs[i:j]))
+			if ftsColumns[name] && j+1 < len(rs) && !unicode.IsSpace(rs[j+1]) {
+				column = name
+				i = j + 1
+			}
+		}
+
+		if rs[i] == '"' {
+			if end := indexRuneFrom(rs, i+1, '"'); end >= 0 {
+				terms = append(terms, ftsTerm{column: column, text: string(rs[i+1 : end]), phrase: true})
+				i = end + 1
+				continue
+			}
+			i++ // unbalanced quote: drop it and read the rest as plain text
+		}
+
+		start := i
+		for i < len(rs) && !unicode.IsSpace(rs[i]) {
+			i++
+		}
+		word := string(rs[start:i])
+		if word == "" {
+			continue
+		}
+		if column == "" && (word == "AND" || word == "OR") {
+			terms = append(terms, ftsTerm{op: word})
+			continue
+		}
+		terms = append(terms, ftsTerm{column: column, text: word})
+	}
+
+	return terms
+}
+
+// ftsTermExpr renders a single term as a quoted FTS5 string.
+func ftsTermExpr(t ftsTerm) string {
+	text := t.text
+	if !t.phrase {
+		text = strings.TrimFunc(text, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+	}
+	if !hasSearchableRune(text) {
+		return ""
+	}
+
+	prefix := !t.phrase && isIdentifierLike(text)
+	if prefix {
+		text = trimStemSuffix(text)
+	}
+
+	expr := `"` + strings.ReplaceAll(text, `"`, `""`) + `"`
+	if prefix {
+		expr += "*"
+	}
+	if t.column != "" {
+		expr = t.column + " : " + expr
+	}
+	return expr
+}
+
+func isFTSStopWord(t ftsTerm) bool {
+	return !t.phrase && t.column == "" && ftsStopWords[strings.ToLower(t.text)]
+}
+
+// isIdentifierLike reports whether a term looks like a code identifier:
+// snake_case, dotted.path, kebab-case, camelCase, or letters mixed with digits.
+func isIdentifierLike(s string) bool {
+	var hasLetter, hasDigit, hasLower bool
+	for i, r := range s {
+		switch {
+		case r == '_' || r == '.' || r == '-' || r == ':':
+			if i > 0 {
+				return true
+			}
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsUpper(r):
+			if hasLower {
+				return true // camelCase boundary
+			}
+			hasLetter = true
+		case unicode.IsLetter(r):
+			hasLetter = true
+			hasLower = true
+		}
+	}
+	return hasLetter && hasDigit
+}
+
+// trimStemSuffix strips trailing e, y and s from a prefix term. The porter
+// tokenizer stems query tokens too (e.g. "key" -> "kei"), which would otherwise
+// stop "IdempotencyKey"* from matching the indexed token "idempotencykeystor".
+func trimStemSuffix(s string) string {
+	rs := []rune(s)
+	end := len(rs)
+	for end > 3 && strings.ContainsRune("eysEYS", rs[end-1]) {
+		end--
+	}
+	return string(rs[:end])
+}
+
+func hasSearchableRune(s string) bool {
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// columnPrefixEnd returns the index of the ':' ending a leading run of letters
+// at position i, or -1 if there is none.
+func columnPrefixEnd(rs []rune, i int) int {
+	j := i
+	for j < len(rs) && unicode.IsLetter(rs[j]) {
+		j++
+	}
+	if j > i && j < len(rs) && rs[j] == ':' {
+		return j
+	}
+	return -1
+}
+
+func indexRuneFrom(rs []rune, from int, target rune) int {
+	for k := from; k < len(rs); k++ {
+		if rs[k] == target {
+			return k
+		}
+	}
+	return -1
+}
@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"testing"
+)
+
+func TestBuildFTSQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"single word", "authentication", `"authentication"`},
+		{"words joined with OR", "retry payment webhook", `"retry" OR "payment" OR "webhook"`},
+		{"stop words dropped", "how did we handle auth", `"handle" OR "auth"`},
+		{"only stop words kept", "how to", `"how" OR "to"`},
+		{"snake_case prefix", "handle_payment", `"handle_payment"*`},
+		{"camelCase prefix", "IdempotencyKey", `"IdempotencyK"*`},
+		{"camelCase prefix keeps consonant ending", "handlePayment", `"handlePayment"*`},
+		{"dotted path prefix", "Ledger.Post", `"Ledger.Post"*`},
+		{"letters and digits prefix", "sha256", `"sha256"*`},
+		{"trailing punctuation trimmed", "webhook?", `"webhook"`},
+		{"quoted phrase", `"idempotency key" retry`, `"idempotency key" OR "retry"`},
+		{"column filter", "title:webhook", `title : "webhook"`},
+		{"column filter phrase", `tags:"payment flow"`, `tags : "payment flow"`},
+		{"column filter case insensitive", "Title:webhook", `title : "webhook"`},
+		{"unknown column is plain text", "http:foo", `"http:foo"*`},
+		{"explicit AND", "retry AND webhook", `"retry" AND "webhook"`},
+		{"leading operator ignored", "OR retry", `"retry"`},
+		{"lowercase operator is a stop word", "retry and webhook", `"retry" OR "webhook"`},
+		{"unbalanced quote ignored", `"retry webhook`, `"retry" OR "webhook"`},
+		{"embedded quote escaped", `foo"bar`, `"foo""bar"`},
+		{"FTS5 syntax neutralised", "NEAR(a b) NOT *", `"NEAR(a" OR "b" OR "NOT"`},
+		{"punctuation only", "* - ()", ``},
+		{"empty", "   ", ``},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := BuildFTSQuery(tt.query)
+			if got != tt.want {
+				t.Errorf("BuildFTSQuery(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMetadataStore_KeywordSearch_TermsApart(t *testing.T) {
+	store, cleanup := createTestMetadataStore(t)
+	defer cleanup()
+
+	seedTestItems(t, store, []*ItemRecord{
+		{ID: "apart", Type: "pattern", Title: "Webhook delivery", Content: "We retry each payment notification with backoff", Scope: "global"},
+		{ID: "partial", Type: "pattern", Title: "Payment ledger", Content: "Double-entry bookkeeping", Scope: "global"},
+		{ID: "none", Type: "pattern", Title: "Logging", Content: "Structured logs", Scope: "global"},
+	})
+
+	results, err := store.KeywordSearch("retry payment webhook", 10)
+	if err != nil {
+		t.Fatalf("KeywordSearch failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	if results[0].ID != "apart" {
+		t.Errorf("expected document containing all terms first, got %s", results[0].ID)
+	}
+}
+
+func TestMetadataStore_KeywordSearch_IdentifierPrefix(t *testing.T) {
+	store, cleanup := createTestMetadataStore(t)
+	defer cleanup()
+
+	seedTestItems(t, store, []*ItemRecord{
+		{ID: "x1", Type: "code", Title: "type IdempotencyKeyStore", Content: "stores keys", Scope: "project"},
+	})
+
+	results, err := store.KeywordSearch("IdempotencyKey", 10)
+	if err != nil {
+		t.Fatalf("KeywordSearch failed: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected prefix match on identifier, got %d results", len(results))
+	}
+}
+
+func TestMetadataStore_KeywordSearch_ColumnAndPhrase(t *testing.T) {
+	store, cleanup := createTestMetadataStore(t)
+	defer cleanup()
+
+	seedTestItems(t, store, []*ItemRecord{
+		{ID: "in-title", Type: "pattern", Title: "Webhook retries", Content: "backoff policy", Tags: []string{"payments"}, Scope: "global"},
+		{ID: "in-content", Type: "pattern", Title: "Backoff", Content: "used for webhook retries", Tags: []string{"infra"}, Scope: "global"},
+	})
+
+	results, err := store.KeywordSearch("title:webhook", 10)
+	if err != nil {
+		t.Fatalf("KeywordSearch failed: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != "in-title" {
+		t.Errorf("title filter: expected only in-title, got %+v", results)
+	}
+
+	results, err = store.KeywordSearch("tags:payments", 10)
+	if err != nil {
+		t.Fatalf("KeywordSearch failed: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != "in-title" {
+		t.Errorf("tags filter: expected only in-title, got %+v", results)
+	}
+
+	results, err = store.KeywordSearch(`"backoff policy"`, 10)
+	if err != nil {
+		t.Fatalf("KeywordSearch failed: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != "in-title" {
+		t.Errorf("phrase: expected only in-title, got %+v", results)
+	}
+}
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// GitIndexState records the last commit of a repository indexed from one
+// ref, so the next run only indexes what changed since.
+type GitIndexState struct {
+	Repo   string // top-level directory of the work tree
+	Ref    string // ref indexed without a checkout; "" for the work tree
+	Commit string // full SHA
+
+	// Uncommitted lists the work tree files that differed from Commit when
+	// indexed; the next run re-indexes them even if Commit is unchanged.
+	Uncommitted []string
+	IndexedAt   time.Time
+}
+
+const gitStateSchema = `
+	CREATE TABLE IF NOT EXISTS git_index_state (
+		repo TEXT NOT NULL,
+		ref TEXT NOT NULL,
+		commit_sha TEXT NOT NULL,
+		uncommitted TEXT NOT NULL DEFAULT '[]',
+		indexed_at DATETIME NOT NULL,
+		PRIMARY KEY (repo, ref)
+	);
+`
+
+// GetGitIndexState returns the state of repo and ref, or nil if it was
+// never indexed.
+func (s *MetadataStore) GetGitIndexState(repo, ref string) (*GitIndexState, error) {
+	row := s.db.QueryRow(`
+		SELECT repo, ref, commit_sha, uncommitted, indexed_at
+		FROM git_index_state WHERE repo = ? AND ref = ?
+	`, repo, ref)
+
+	var st GitIndexState
+	var uncommitted string
+	if err := row.Scan(&st.Repo, &st.Ref, &st.Commit, &uncommitted, &st.IndexedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(uncommitted), &st.Uncommitted); err != nil {
+		return nil, fmt.Errorf("unmarshal uncommitted files for %s: %w", repo, err)
+	}
+	return &st, nil
+}
+
+// SaveGitIndexState creates or replaces the state of st.Repo and st.Ref.
+func (s *MetadataStore) SaveGitIndexState(st *GitIndexState) error {
+	uncommitted, err := json.Marshal(st.Uncommitted)
+	if err != nil {
+		return fmt.Errorf("marshal uncommitted files: %w", err)
+	}
+	if st.Uncommitted == nil {
+		uncommitted = []byte("[]")
+	}
+
+	_, err = s.db.Exec(`
+		INSERT INTO git_index_state (repo, ref, commit_sha, uncommitted, indexed_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(repo, ref) DO UPDATE SET
+			commit_sha=excluded.commit_sha, uncommitted=excluded.uncommitted,
+			indexed_at=excluded.indexed_at
+	`, st.Repo, st.Ref, st.Commit, string(uncommitted), st.IndexedAt)
+	return err
+}
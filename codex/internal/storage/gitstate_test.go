@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMetadataStore_GitIndexState(t *testing.T) {
+	store, cleanup := createTestMetadataStore(t)
+	defer cleanup()
+
+	t.Run("Given no state, When getting it, Then nil is returned", func(t *testing.T) {
+		st, err := store.GetGitIndexState("/repo", "")
+		if err != nil || st != nil {
+			t.Errorf("GetGitIndexState = %+v, %v, want nil", st, err)
+		}
+	})
+
+	t.Run("Given states for two refs, When saving and updating, Then each round-trips separately", func(t *testing.T) {
+		now := time.Now().UTC().Truncate(time.Second)
+		work := &GitIndexState{Repo: "/repo", Commit: "aaa", Uncommitted: []string{"a.go"}, IndexedAt: now}
+		release := &GitIndexState{Repo: "/repo", Ref: "release/2.3", Commit: "bbb", IndexedAt: now}
+		for _, st := range []*GitIndexState{work, release} {
+			if err := store.SaveGitIndexState(st); err != nil {
+				t.Fatalf("SaveGitIndexState: %v", err)
+			}
+		}
+		work.Commit, work.Uncommitted = "ccc", nil
+		if err := store.SaveGitIndexState(work); err != nil {
+			t.Fatal(err)
+		}
+
+		got, _ := store.GetGitIndexState("/repo", "")
+		if got.Commit != "ccc" || len(got.Uncommitted) != 0 || !got.IndexedAt.Equal(now) {
+			t.Errorf("work tree state = %+v", got)
+		}
+		got, _ = store.GetGitIndexState("/repo", "release/2.3")
+		release.Uncommitted = []string{}
+		if !reflect.DeepEqual(got, release) {
+			t.Errorf("release state = %+v, want %+v", got, release)
+		}
+	})
+}
@@ -0,0 +1,849 @@
+package storage
+
+import (
+	"container/heap"
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HNSWConfig tunes the approximate nearest neighbour index.
+type HNSWConfig struct {
+	// M is the number of links per node on upper layers (layer 0 keeps 2*M).
+	M int
+	// EfConstruction is the candidate list size used while inserting.
+	EfConstruction int
+	// EfSearch is the candidate list size used while querying. Raising it
+	// trades latency for recall.
+	EfSearch int
+	// ExactBelow makes queries use exact search while the store (or the
+	// query's allow-list) holds fewer vectors than this. Brute force over
+	// a few thousand vectors is already sub-millisecond and exact.
+	ExactBelow int
+}
+
+// DefaultHNSWConfig returns parameters suited to 768-dim text embeddings.
+// See BenchmarkVecStoreSearch for the latency/recall trade-off.
+func DefaultHNSWConfig() HNSWConfig {
+	return HNSWConfig{
+		M:              16,
+		EfConstruction: 200,
+		EfSearch:       100,
+		ExactBelow:     10000,
+	}
+}
+
+// hnswNode is one vector in the graph. friends[l] lists neighbour node
+// numbers on layer l, for l in [0, level]. The vector itself is read
+// through the store's vector cache.
+type hnswNode struct {
+	id      string
+	level   int
+	friends [][]uint32
+}
+
+// hnswMaxDeadRatio is the share of node numbers left by deleted nodes
+// above which flush renumbers the live nodes. Every re-upsert deletes a
+// node, so without compaction reindexing grows the graph without bound.
+const hnswMaxDeadRatio = 0.25
+
+// hnswGraph is a Hierarchical Navigable Small World graph (Malkov &
+// Yashunin, 2016) over normalized vectors, scored by dot product.
+//
+// Node numbers are not reused until compaction, so links to deleted nodes
+// are harmless: they are skipped during traversal and dropped when the
+// owning node's neighbour list is next rewritten, or when compact
+// renumbers the graph. Mutations record the touched nodes so the caller
+// can persist only what changed.
+//
+// The graph holds only links in memory. Node vectors come from vecs, so
+// traversal can fail when they have to be read from the database.
+type hnswGraph struct {
+	cfg      HNSWConfig
+	dims     int         // vector length; 0 until the first insert
+	nodes    []*hnswNode // node number -> node; nil once deleted
+	byID     map[string]uint32
+	entry    uint32
+	maxLevel int // -1 when the graph is empty
+	levelMul float64
+	rng      *rand.Rand
+	vecs     *vectorCache
+
+	dirty   map[uint32]bool      // nodes to write on next flush
+	removed map[uint32]bool      // nodes to delete on next flush
+	pending map[string][]float32 // vectors inserted since the last flush, not yet committed
+
+	visited sync.Pool
+}
+
+func newHNSWGraph(cfg HNSWConfig, vecs *vectorCache) *hnswGraph {
+	g := &hnswGraph{
+		cfg:      cfg,
+		byID:     make(map[string]uint32),
+		maxLevel: -1,
+		levelMul: 1 / math.Log(float64(cfg.M)),
+		rng:      rand.New(rand.NewSource(time.Now().UnixNano())),
+		vecs:     vecs,
+		dirty:    make(map[uint32]bool),
+		removed:  make(map[uint32]bool),
+		pending:  make(map[string][]float32),
+	}
+	g.visited.New = func() any { return &visitedSet{} }
+	return g
+}
+
+// Len returns the number of live nodes.
+func (g *hnswGraph) Len() int {
+	return len(g.byID)
+}
+
+func (g *hnswGraph) maxFriends(level int) int {
+	if level == 0 {
+		return 2 * g.cfg.M
+	}
+	return g.cfg.M
+}
+
+func (g *hnswGraph) randomLevel() int {
+	return int(math.Floor(-math.Log(1-g.rng.Float64()) * g.levelMul))
+}
+
+// vectorsOf appends the vectors of live nodes to out[:0], in order.
+func (g *hnswGraph) vectorsOf(nodes []uint32, out [][]float32) ([][]float32, error) {
+	ids := make([]string, 0, len(nodes))
+	var at []int // positions of ids in out, when some vectors are pending
+	out = out[:0]
+	for i, n := range nodes {
+		id := g.nodes[n].id
+		if len(g.pending) > 0 {
+			if vec, ok := g.pending[id]; ok {
+				out = append(out, vec)
+				continue
+			}
+			at = append(at, i)
+		}
+		out = append(out, nil)
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return out, nil
+	}
+
+	vecs := out
+	if at != nil {
+		vecs = nil
+	}
+	vecs, err := g.vecs.get(ids, vecs)
+	if err != nil {
+		return nil, fmt.Errorf("read graph vectors: %w", err)
+	}
+	for j, vec := range vecs {
+		if len(vec) != g.dims {
+			return nil, fmt.Errorf("graph node %s has no %d-dim vector", ids[j], g.dims)
+		}
+		if at != nil {
+			out[at[j]] = vec
+		}
+	}
+	return out, nil
+}
+
+// live filters out deleted nodes.
+func (g *hnswGraph) live(nodes []uint32) []uint32 {
+	out := make([]uint32, 0, len(nodes))
+	for _, n := range nodes {
+		if g.nodes[n] != nil {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// insert adds a normalized vector. An existing id is replaced. Vectors
+// whose length differs from the graph's are not indexed; insert reports
+// whether the vector was added.
+func (g *hnswGraph) insert(id string, vec []float32) (bool, error) {
+	if _, ok := g.byID[id]; ok {
+		if err := g.remove(id); err != nil {
+			return false, err
+		}
+	}
+	if g.dims == 0 {
+		g.dims = len(vec)
+	}
+	if len(vec) != g.dims {
+		return false, nil
+	}
+
+	n := uint32(len(g.nodes))
+	level := g.randomLevel()
+	node := &hnswNode{id: id, level: level, friends: make([][]uint32, level+1)}
+	g.nodes = append(g.nodes, node)
+	g.byID[id] = n
+	g.pending[id] = vec
+	g.dirty[n] = true
+
+	if g.maxLevel < 0 {
+		g.entry, g.maxLevel = n, level
+		return true, nil
+	}
+
+	// Greedy descent through layers above the new node's level
+	ep := g.entry
+	var err error
+	for l := g.maxLevel; l > level; l-- {
+		if ep, err = g.greedyClosest(vec, ep, l); err != nil {
+			return false, err
+		}
+	}
+
+	eps := []uint32{ep}
+	for l := min(level, g.maxLevel); l >= 0; l-- {
+		candidates, err := g.searchLayer(vec, eps, g.cfg.EfConstruction, l, nil)
+		if err != nil {
+			return false, err
+		}
+		if node.friends[l], err = g.selectNeighbors(vec, candidates, g.cfg.M); err != nil {
+			return false, err
+		}
+
+		for _, f := range node.friends[l] {
+			if err := g.link(f, n, l); err != nil {
+				return false, err
+			}
+		}
+
+		eps = eps[:0]
+		for _, c := range candidates {
+			eps = append(eps, c.node)
+		}
+	}
+
+	if level > g.maxLevel {
+		g.entry, g.maxLevel = n, level
+	}
+	return true, nil
+}
+
+// link adds a directed edge from -> to on layer l, pruning from's
+// neighbour list if it overflows.
+func (g *hnswGraph) link(from, to uint32, l int) error {
+	fn := g.nodes[from]
+	fn.friends[l] = append(fn.friends[l], to)
+	g.dirty[from] = true
+	if len(fn.friends[l]) <= g.maxFriends(l) {
+		return nil
+	}
+	return g.prune(from, fn.friends[l], l)
+}
+
+// prune sets node n's neighbours on layer l to the best of pool.
+func (g *hnswGraph) prune(n uint32, pool []uint32, l int) error {
+	vecs, err := g.vectorsOf([]uint32{n}, nil)
+	if err != nil {
+		return err
+	}
+	scored, err := g.scoreNodes(vecs[0], pool)
+	if err != nil {
+		return err
+	}
+	friends, err := g.selectNeighbors(vecs[0], scored, g.maxFriends(l))
+	if err != nil {
+		return err
+	}
+	g.nodes[n].friends[l] = friends
+	g.dirty[n] = true
+	return nil
+}
+
+// remove deletes id from the graph, reconnecting its neighbours to each
+// other so the graph stays navigable.
+func (g *hnswGraph) remove(id string) error {
+	n, ok := g.byID[id]
+	if !ok {
+		return nil
+	}
+	node := g.nodes[n]
+	delete(g.byID, id)
+	delete(g.pending, id)
+	g.nodes[n] = nil
+	delete(g.dirty, n)
+	g.removed[n] = true
+
+	for l := 0; l <= node.level; l++ {
+		for _, f := range node.friends[l] {
+			fn := g.nodes[f]
+			if fn == nil || l > fn.level {
+				continue
+			}
+			// Candidates: f's current links plus the removed node's links
+			seen := map[uint32]bool{f: true, n: true}
+			var pool []uint32
+			for _, c := range append(append([]uint32{}, fn.friends[l]...), node.friends[l]...) {
+				if !seen[c] && g.nodes[c] != nil {
+					seen[c] = true
+					pool = append(pool, c)
+				}
+			}
+			if err := g.prune(f, pool, l); err != nil {
+				return err
+			}
+		}
+	}
+
+	if n == g.entry {
+		g.chooseEntry()
+	}
+	return nil
+}
+
+// chooseEntry picks the highest-level node (lowest number on ties) as the
+// entry point. The same rule is applied when loading a persisted graph.
+func (g *hnswGraph) chooseEntry() {
+	g.maxLevel = -1
+	for i, node := range g.nodes {
+		if node != nil && node.level > g.maxLevel {
+			g.entry, g.maxLevel = uint32(i), node.level
+		}
+	}
+}
+
+// search returns up to k nearest live nodes to the normalized query,
+// restricted to the item IDs accept returns true for when non-nil.
+func (g *hnswGraph) search(query []float32, k, ef int, accept func(id string) bool) ([]ScoredResult, error) {
+	if g.maxLevel < 0 || k <= 0 || len(query) != g.dims {
+		return nil, nil
+	}
+	if ef < k {
+		ef = k
+	}
+
+	ep := g.entry
+	var err error
+	for l := g.maxLevel; l > 0; l-- {
+		if ep, err = g.greedyClosest(query, ep, l); err != nil {
+			return nil, err
+		}
+	}
+
+	found, err := g.searchLayer(query, []uint32{ep}, ef, 0, accept)
+	if err != nil {
+		return nil, err
+	}
+	if len(found) > k {
+		found = found[:k]
+	}
+	nodes := make([]uint32, len(found))
+	for i, c := range found {
+		nodes[i] = c.node
+	}
+	vecs, err := g.vectorsOf(nodes, nil)
+	if err != nil {
+		return nil, err
+	}
+	// Rescore in float64 so scores match exact search
+	results := make([]ScoredResult, len(found))
+	for i, n := range nodes {
+		results[i] = ScoredResult{ID: g.nodes[n].id, Score: dotProduct(query, vecs[i])}
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	return results, nil
+}
+
+// greedyClosest walks layer l from ep towards query, returning the local best.
+func (g *hnswGraph) greedyClosest(query []float32, ep uint32, l int) (uint32, error) {
+	vecs, err := g.vectorsOf([]uint32{ep}, nil)
+	if err != nil {
+		return 0, err
+	}
+	best := dot32(query, vecs[0])
+	for changed := true; changed; {
+		changed = false
+		friends := g.live(g.nodes[ep].friends[l])
+		if vecs, err = g.vectorsOf(friends, vecs); err != nil {
+			return 0, err
+		}
+		for i, f := range friends {
+			if s := dot32(query, vecs[i]); s > best {
+				best, ep, changed = s, f, true
+			}
+		}
+	}
+	return ep, nil
+}
+
+type hnswCandidate struct {
+	node  uint32
+	score float64
+}
+
+// searchLayer is the beam search of the HNSW paper on a single layer.
+// Traversal visits every node, but only nodes passing accept (when
+// non-nil) enter the result set. Results are returned best first.
+func (g *hnswGraph) searchLayer(query []float32, eps []uint32, ef, l int, accept func(id string) bool) ([]hnswCandidate, error) {
+	visited := g.visited.Get().(*visitedSet)
+	visited.reset(len(g.nodes))
+	defer g.visited.Put(visited)
+
+	accepted := func(n uint32) bool {
+		return accept == nil || accept(g.nodes[n].id)
+	}
+	// unvisited marks the live nodes not seen yet and appends them to out[:0]
+	unvisited := func(nodes, out []uint32) []uint32 {
+		out = out[:0]
+		for _, n := range nodes {
+			if visited.test(n) {
+				continue
+			}
+			visited.set(n)
+			if g.nodes[n] != nil {
+				out = append(out, n)
+			}
+		}
+		return out
+	}
+
+	candidates := &candidateMaxHeap{}
+	results := &candidateMinHeap{}
+	friends := unvisited(eps, nil)
+	vecs, err := g.vectorsOf(friends, nil)
+	if err != nil {
+		return nil, err
+	}
+	for i, ep := range friends {
+		c := hnswCandidate{node: ep, score: dot32(query, vecs[i])}
+		heap.Push(candidates, c)
+		if accepted(ep) {
+			heap.Push(results, c)
+		}
+	}
+	for results.Len() > ef {
+		heap.Pop(results)
+	}
+
+	for candidates.Len() > 0 {
+		c := heap.Pop(candidates).(hnswCandidate)
+		if results.Len() >= ef && c.score < (*results)[0].score {
+			break
+		}
+		node := g.nodes[c.node]
+		if l > node.level {
+			continue
+		}
+		friends = unvisited(node.friends[l], friends)
+		if vecs, err = g.vectorsOf(friends, vecs); err != nil {
+			return nil, err
+		}
+		for i, f := range friends {
+			s := dot32(query, vecs[i])
+			if results.Len() < ef || s > (*results)[0].score {
+				heap.Push(candidates, hnswCandidate{node: f, score: s})
+				if accepted(f) {
+					heap.Push(results, hnswCandidate{node: f, score: s})
+					if results.Len() > ef {
+						heap.Pop(results)
+					}
+				}
+			}
+		}
+	}
+
+	out := make([]hnswCandidate, results.Len())
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = heap.Pop(results).(hnswCandidate)
+	}
+	return out, nil
+}
+
+// scoreNodes scores live nodes against vec, best first.
+func (g *hnswGraph) scoreNodes(vec []float32, nodes []uint32) ([]hnswCandidate, error) {
+	nodes = g.live(nodes)
+	vecs, err := g.vectorsOf(nodes, nil)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]hnswCandidate, len(nodes))
+	for i, n := range nodes {
+		out[i] = hnswCandidate{node: n, score: dot32(vec, vecs[i])}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].score > out[j].score })
+	return out, nil
+}
+
+// selectNeighbors applies the neighbour-selection heuristic: a candidate is
+// kept only if it is closer to the base vector than to any neighbour kept so
+// far, which spreads links across clusters. Remaining slots are filled with
+// the best pruned candidates. candidates must be sorted best first.
+func (g *hnswGraph) selectNeighbors(vec []float32, candidates []hnswCandidate, m int) ([]uint32, error) {
+	nodes := make([]uint32, len(candidates))
+	for i, c := range candidates {
+		nodes[i] = c.node
+	}
+	if len(candidates) <= m {
+		return nodes, nil
+	}
+	vecs, err := g.vectorsOf(nodes, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// selected and pruned hold indexes into candidates
+	selected := make([]int, 0, m)
+	var pruned []int
+	for i, c := range candidates {
+		if len(selected) >= m {
+			break
+		}
+		good := true
+		for _, s := range selected {
+			if dot32(vecs[i], vecs[s]) > c.score {
+				good = false
+				break
+			}
+		}
+		if good {
+			selected = append(selected, i)
+		} else {
+			pruned = append(pruned, i)
+		}
+	}
+	for _, p := range pruned {
+		if len(selected) >= m {
+			break
+		}
+		selected = append(selected, p)
+	}
+	out := make([]uint32, len(selected))
+	for i, s := range selected {
+		out[i] = nodes[s]
+	}
+	return out, nil
+}
+
+// --- persistence ---
+
+func migrateHNSW(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS vector_graph (
+			node      INTEGER PRIMARY KEY,
+			item_id   TEXT NOT NULL,
+			level     INTEGER NOT NULL,
+			neighbors BLOB NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS vector_graph_meta (
+			key   TEXT PRIMARY KEY,
+			value INTEGER NOT NULL
+		);
+	`)
+	return err
+}
+
+// compact renumbers the live nodes 0..Len()-1 in their current order and
+// drops links to deleted nodes.
+func (g *hnswGraph) compact() {
+	renumber := make([]uint32, len(g.nodes))
+	live := make([]*hnswNode, 0, len(g.byID))
+	for n, node := range g.nodes {
+		if node != nil {
+			renumber[n] = uint32(len(live))
+			live = append(live, node)
+		}
+	}
+	for _, node := range live {
+		for l, fs := range node.friends {
+			kept := fs[:0]
+			for _, f := range fs {
+				if g.nodes[f] != nil {
+					kept = append(kept, renumber[f])
+				}
+			}
+			node.friends[l] = kept
+		}
+	}
+	for id, n := range g.byID {
+		g.byID[id] = renumber[n]
+	}
+	g.nodes = live
+	g.chooseEntry()
+}
+
+// needsCompaction reports whether deleted nodes hold more than
+// hnswMaxDeadRatio of the node numbers.
+func (g *hnswGraph) needsCompaction() bool {
+	dead := len(g.nodes) - len(g.byID)
+	return dead > 0 && float64(dead) > hnswMaxDeadRatio*float64(len(g.nodes))
+}
+
+// flush writes nodes touched since the last flush. When deleted nodes
+// have piled up it compacts the graph and rewrites it whole.
+func (g *hnswGraph) flush(tx *sql.Tx) error {
+	if g.needsCompaction() {
+		g.compact()
+		if _, err := tx.Exec("DELETE FROM vector_graph"); err != nil {
+			return fmt.Errorf("clear graph for compaction: %w", err)
+		}
+		g.clearChanges()
+		for n := range g.nodes {
+			g.dirty[uint32(n)] = true
+		}
+	}
+	for n := range g.removed {
+		if _, err := tx.Exec("DELETE FROM vector_graph WHERE node = ?", n); err != nil {
+			return fmt.Errorf("delete graph node: %w", err)
+		}
+	}
+	if len(g.dirty) > 0 {
+		stmt, err := tx.Prepare(`
+			INSERT INTO vector_graph (node, item_id, level, neighbors) VALUES (?, ?, ?, ?)
+			ON CONFLICT(node) DO UPDATE SET
+				item_id=excluded.item_id, level=excluded.level, neighbors=excluded.neighbors
+		`)
+		if err != nil {
+			return fmt.Errorf("prepare graph upsert: %w", err)
+		}
+		defer stmt.Close()
+		for n := range g.dirty {
+			node := g.nodes[n]
+			if _, err := stmt.Exec(n, node.id, node.level, encodeFriends(node.friends)); err != nil {
+				return fmt.Errorf("write graph node: %w", err)
+			}
+		}
+	}
+	// Persist the node counter so numbers stay unique across restarts
+	if _, err := tx.Exec(`
+		INSERT INTO vector_graph_meta (key, value) VALUES ('next', ?), ('dims', ?)
+		ON CONFLICT(key) DO UPDATE SET value=excluded.value
+	`, len(g.nodes), g.dims); err != nil {
+		return fmt.Errorf("write graph meta: %w", err)
+	}
+	g.clearChanges()
+	return nil
+}
+
+// clearChanges forgets pending mutations (after flushing, or after a
+// rollback when the graph will be rebuilt). Vectors inserted since the
+// last flush are committed by then, or gone, so the cache can serve them.
+func (g *hnswGraph) clearChanges() {
+	g.dirty = make(map[uint32]bool)
+	g.removed = make(map[uint32]bool)
+	g.pending = make(map[string][]float32)
+}
+
+// saveMeta records the parameters the persisted graph was built with.
+func (g *hnswGraph) saveMeta(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		INSERT INTO vector_graph_meta (key, value) VALUES ('m', ?)
+		ON CONFLICT(key) DO UPDATE SET value=excluded.value
+	`, g.cfg.M)
+	return err
+}
+
+// loadHNSWGraph reads the persisted graph; node vectors are read through
+// vecs when traversal needs them. It returns nil (and no error) when the
+// persisted graph is missing, was built with different parameters, or
+// does not cover exactly the stored vectors of its dimension; the caller
+// then rebuilds. The check is by item set, so it also catches most writes
+// from processes without the index.
+func loadHNSWGraph(db *sql.DB, cfg HNSWConfig, vecs *vectorCache) (*hnswGraph, error) {
+	meta := map[string]int{}
+	rows, err := db.Query("SELECT key, value FROM vector_graph_meta")
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var k string
+		var v int
+		if err := rows.Scan(&k, &v); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		meta[k] = v
+	}
+	rows.Close()
+	if meta["m"] != cfg.M {
+		return nil, nil
+	}
+
+	rows, err = db.Query("SELECT node, item_id, level, neighbors FROM vector_graph ORDER BY node")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	g := newHNSWGraph(cfg, vecs)
+	g.dims = meta["dims"]
+	loaded := 0
+	for rows.Next() {
+		loaded++
+		var n uint32
+		var id string
+		var level int
+		var blob []byte
+		if err := rows.Scan(&n, &id, &level, &blob); err != nil {
+			return nil, err
+		}
+		friends, err := decodeFriends(blob, level)
+		if err != nil {
+			return nil, nil
+		}
+		for int(n) >= len(g.nodes) {
+			g.nodes = append(g.nodes, nil)
+		}
+		g.nodes[n] = &hnswNode{id: id, level: level, friends: friends}
+		g.byID[id] = n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// Duplicate item rows mean another process wrote to the graph concurrently
+	if loaded != len(g.byID) {
+		return nil, nil
+	}
+	// Every node must have a stored vector of the graph's dimension, and
+	// every such vector a node
+	var stored, covered int
+	if err := db.QueryRow("SELECT COUNT(*) FROM vectors WHERE dimensions = ?", g.dims).Scan(&stored); err != nil {
+		return nil, err
+	}
+	if err := db.QueryRow(`
+		SELECT COUNT(*) FROM vector_graph g JOIN vectors v ON v.item_id = g.item_id
+		WHERE v.dimensions = ?
+	`, g.dims).Scan(&covered); err != nil {
+		return nil, err
+	}
+	if stored != loaded || covered != loaded {
+		return nil, nil
+	}
+	for len(g.nodes) < meta["next"] {
+		g.nodes = append(g.nodes, nil)
+	}
+
+	g.chooseEntry()
+	return g, nil
+}
+
+// commonDims returns the most frequent stored vector length (0 if there
+// are no vectors), preferring the longer on ties.
+func commonDims(db *sql.DB) (int, error) {
+	var dims int
+	err := db.QueryRow(`
+		SELECT dimensions FROM vectors GROUP BY dimensions
+		ORDER BY COUNT(*) DESC, dimensions DESC LIMIT 1
+	`).Scan(&dims)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return dims, err
+}
+
+// encodeFriends serializes neighbour lists: per layer, a uint32 count
+// followed by that many uint32 node numbers (little endian).
+func encodeFriends(friends [][]uint32) []byte {
+	size := 0
+	for _, fs := range friends {
+		size += 4 + 4*len(fs)
+	}
+	buf := make([]byte, 0, size)
+	for _, fs := range friends {
+		buf = binary.LittleEndian.AppendUint32(buf, uint32(len(fs)))
+		for _, f := range fs {
+			buf = binary.LittleEndian.AppendUint32(buf, f)
+		}
+	}
+	return buf
+}
+
+func decodeFriends(b []byte, level int) ([][]uint32, error) {
+	friends := make([][]uint32, level+1)
+	for l := 0; l <= level; l++ {
+		if len(b) < 4 {
+			return nil, fmt.Errorf("neighbor blob truncated at layer %d", l)
+		}
+		count := int(binary.LittleEndian.Uint32(b))
+		b = b[4:]
+		if len(b) < 4*count {
+			return nil, fmt.Errorf("neighbor blob truncated at layer %d", l)
+		}
+		friends[l] = make([]uint32, count)
+		for i := range friends[l] {
+			friends[l][i] = binary.LittleEndian.Uint32(b[4*i:])
+		}
+		b = b[4*count:]
+	}
+	return friends, nil
+}
+
+// --- search helpers ---
+
+// dot32 is a float32 dot product for graph traversal, unrolled by four.
+// Rankings only need relative order, so float32 accumulation is enough.
+func dot32(a, b []float32) float64 {
+	var s0, s1, s2, s3 float32
+	n := len(a) &^ 3
+	b = b[:len(a)]
+	for i := 0; i < n; i += 4 {
+		s0 += a[i] * b[i]
+		s1 += a[i+1] * b[i+1]
+		s2 += a[i+2] * b[i+2]
+		s3 += a[i+3] * b[i+3]
+	}
+	for i := n; i < len(a); i++ {
+		s0 += a[i] * b[i]
+	}
+	return float64(s0 + s1 + s2 + s3)
+}
+
+// visitedSet marks visited nodes using an epoch counter, so resetting
+// between searches is O(1).
+type visitedSet struct {
+	marks []uint32
+	epoch uint32
+}
+
+func (v *visitedSet) reset(n int) {
+	if len(v.marks) < n {
+		v.marks = make([]uint32, n+n/4)
+		v.epoch = 0
+	}
+	v.epoch++
+	if v.epoch == 0 { // wrapped
+		clear(v.marks)
+		v.epoch = 1
+	}
+}
+
+func (v *visitedSet) test(n uint32) bool { return v.marks[n] == v.epoch }
+func (v *visitedSet) set(n uint32)       { v.marks[n] = v.epoch }
+
+// candidateMaxHeap pops the best-scoring candidate first.
+type candidateMaxHeap []hnswCandidate
+
+func (h candidateMaxHeap) Len() int           { return len(h) }
+func (h candidateMaxHeap) Less(i, j int) bool { return h[i].score > h[j].score }
+func (h candidateMaxHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *candidateMaxHeap) Push(x any)        { *h = append(*h, x.(hnswCandidate)) }
+func (h *candidateMaxHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// candidateMinHeap keeps the worst of the current results at the root.
+type candidateMinHeap []hnswCandidate
+
+func (h candidateMinHeap) Len() int           { return len(h) }
+func (h candidateMinHeap) Less(i, j int) bool { return h[i].score < h[j].score }
+func (h candidateMinHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *candidateMinHeap) Push(x any)        { *h = append(*h, x.(hnswCandidate)) }
+func (h *candidateMinHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,534 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// clusteredVectors generates n normalized vectors around `clusters` random
+// centres, which resembles embedding data better than uniform noise.
+func clusteredVectors(rng *rand.Rand, n, dims, clusters int) [][]float32 {
+	centres := make([][]float32, clusters)
+	for i := range centres {
+		centres[i] = make([]float32, dims)
+		for d := range centres[i] {
+			centres[i][d] = float32(rng.NormFloat64())
+		}
+	}
+	out := make([][]float32, n)
+	for i := range out {
+		c := centres[rng.Intn(clusters)]
+		v := make([]float32, dims)
+		for d := range v {
+			v[d] = c[d] + 0.6*float32(rng.NormFloat64())
+		}
+		out[i] = normalize(v)
+	}
+	return out
+}
+
+// recallAt returns the fraction of want IDs present in got.
+func recallAt(got, want []ScoredResult) float64 {
+	ids := make(map[string]bool, len(want))
+	for _, r := range want {
+		ids[r.ID] = true
+	}
+	hits := 0
+	for _, r := range got {
+		if ids[r.ID] {
+			hits++
+		}
+	}
+	return float64(hits) / float64(len(want))
+}
+
+// newMemoryVecStore builds a VecStore and its index in memory, skipping
+// SQLite, for graph-quality tests and benchmarks.
+func newMemoryVecStore(vectors [][]float32, cfg HNSWConfig) *VecStore {
+	vs := &VecStore{vecs: newVectorCache(nil, 0), count: len(vectors), hnswCfg: &cfg}
+	vs.index = newHNSWGraph(cfg, vs.vecs)
+	for i, v := range vectors {
+		id := fmt.Sprintf("v%06d", i)
+		vs.vecs.put(id, v)
+		vs.index.insert(id, v)
+	}
+	vs.index.clearChanges()
+	return vs
+}
+
+func openHNSWStore(t *testing.T, dbPath string, opts ...VecStoreOption) (*VecStore, *sql.DB) {
+	t.Helper()
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open DB: %v", err)
+	}
+	vs, err := NewVecStore(db, opts...)
+	if err != nil {
+		db.Close()
+		t.Fatalf("Failed to create VecStore: %v", err)
+	}
+	return vs, db
+}
+
+// =============================================================================
+// Graph quality tests
+// =============================================================================
+
+func TestHNSW_RecallAgainstExact(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	data := clusteredVectors(rng, 3050, 64, 30)
+	data, queries := data[:3000], data[3000:]
+
+	cfg := DefaultHNSWConfig()
+	cfg.ExactBelow = 0
+	vs := newMemoryVecStore(data, cfg)
+	ctx := context.Background()
+
+	t.Run("Given clustered data, When searching approximately, Then recall@10 is high", func(t *testing.T) {
+		var total float64
+		for _, q := range queries {
+			approx, _ := vs.SearchWithOptions(ctx, q, 10, VectorSearchOptions{})
+			exact, _ := vs.SearchWithOptions(ctx, q, 10, VectorSearchOptions{Exact: true})
+			total += recallAt(approx, exact)
+		}
+		if recall := total / float64(len(queries)); recall < 0.95 {
+			t.Errorf("recall@10 = %.3f, want >= 0.95", recall)
+		}
+	})
+
+	t.Run("Given deletions, When searching, Then deleted items never appear and recall holds", func(t *testing.T) {
+		deleted := map[string]bool{}
+		for i := 0; i < len(data); i += 2 {
+			id := fmt.Sprintf("v%06d", i)
+			if err := vs.index.remove(id); err != nil {
+				t.Fatalf("remove: %v", err)
+			}
+			vs.vecs.drop(id)
+			vs.count--
+			deleted[id] = true
+		}
+
+		var total float64
+		for _, q := range queries {
+			approx, _ := vs.SearchWithOptions(ctx, q, 10, VectorSearchOptions{})
+			for _, r := range approx {
+				if deleted[r.ID] {
+					t.Fatalf("deleted item %s returned", r.ID)
+				}
+			}
+			exact, _ := vs.SearchWithOptions(ctx, q, 10, VectorSearchOptions{Exact: true})
+			total += recallAt(approx, exact)
+		}
+		if recall := total / float64(len(queries)); recall < 0.9 {
+			t.Errorf("recall@10 after deletes = %.3f, want >= 0.9", recall)
+		}
+	})
+}
+
+func TestHNSW_EncodeDecodeFriends(t *testing.T) {
+	friends := [][]uint32{{1, 2, 3}, {}, {42}}
+	got, err := decodeFriends(encodeFriends(friends), 2)
+	if err != nil {
+		t.Fatalf("decodeFriends: %v", err)
+	}
+	if fmt.Sprint(got) != fmt.Sprint(friends) {
+		t.Errorf("round trip = %v, want %v", got, friends)
+	}
+	if _, err := decodeFriends([]byte{1, 0}, 0); err == nil {
+		t.Error("expected error for truncated blob")
+	}
+}
+
+// =============================================================================
+// VecStore index integration tests
+// =============================================================================
+
+func TestVecStore_HNSWPersistence(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	ctx := context.Background()
+	cfg := DefaultHNSWConfig()
+	cfg.ExactBelow = 0
+
+	rng := rand.New(rand.NewSource(2))
+	data := clusteredVectors(rng, 300, 16, 5)
+	query := data[7]
+
+	var before []ScoredResult
+	var graph map[string][]byte
+	{
+		vs, db := openHNSWStore(t, dbPath, WithHNSW(cfg))
+		for i, v := range data {
+			if err := vs.Upsert(ctx, fmt.Sprintf("v%03d", i), v); err != nil {
+				t.Fatalf("Upsert: %v", err)
+			}
+		}
+		if err := vs.Delete(ctx, "v010"); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+		before, _ = vs.Search(ctx, query, 5)
+		graph = snapshotGraph(vs.index)
+		db.Close()
+	}
+
+	t.Run("Given persisted graph, When reopening, Then it is loaded unchanged", func(t *testing.T) {
+		vs, db := openHNSWStore(t, dbPath, WithHNSW(cfg))
+		defer db.Close()
+
+		if vs.index.Len() != len(data)-1 {
+			t.Fatalf("expected %d nodes, got %d", len(data)-1, vs.index.Len())
+		}
+		after := snapshotGraph(vs.index)
+		for id, friends := range graph {
+			if !bytes.Equal(after[id], friends) {
+				t.Fatalf("neighbours of %s changed across reopen", id)
+			}
+		}
+		got, _ := vs.Search(ctx, query, 5)
+		if fmt.Sprint(got) != fmt.Sprint(before) {
+			t.Errorf("results changed across reopen: %v vs %v", got, before)
+		}
+	})
+
+	t.Run("Given writes without the index, When reopening with it, Then the graph is rebuilt", func(t *testing.T) {
+		vs, db := openHNSWStore(t, dbPath)
+		if vs.index != nil {
+			t.Fatal("index should be disabled without WithHNSW")
+		}
+		if err := vs.Upsert(ctx, "late", data[0]); err != nil {
+			t.Fatalf("Upsert: %v", err)
+		}
+		db.Close()
+
+		vs, db = openHNSWStore(t, dbPath, WithHNSW(cfg))
+		defer db.Close()
+		if _, ok := vs.index.byID["late"]; !ok {
+			t.Error("rebuilt graph should include item written without the index")
+		}
+		if vs.index.Len() != vs.Count() {
+			t.Errorf("graph has %d nodes, store has %d vectors", vs.index.Len(), vs.Count())
+		}
+	})
+
+	t.Run("Given different graph parameters, When reopening, Then the graph is rebuilt", func(t *testing.T) {
+		other := cfg
+		other.M = 8
+		vs, db := openHNSWStore(t, dbPath, WithHNSW(other))
+		defer db.Close()
+		if vs.index.cfg.M != 8 || vs.index.Len() != vs.Count() {
+			t.Errorf("expected rebuilt graph with M=8 covering all vectors")
+		}
+	})
+}
+
+func TestVecStore_VectorCache(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	ctx := context.Background()
+	cfg := DefaultHNSWConfig()
+	cfg.ExactBelow = 0
+
+	rng := rand.New(rand.NewSource(5))
+	data := clusteredVectors(rng, 300, 16, 5)
+	var want [][]ScoredResult
+	{
+		vs, db := openHNSWStore(t, dbPath, WithHNSW(cfg))
+		for i, v := range data {
+			if err := vs.Upsert(ctx, fmt.Sprintf("v%03d", i), v); err != nil {
+				t.Fatalf("Upsert: %v", err)
+			}
+		}
+		for _, q := range data[:20] {
+			got, _ := vs.SearchWithOptions(ctx, q, 5, VectorSearchOptions{Exact: true})
+			want = append(want, got)
+		}
+		db.Close()
+	}
+
+	t.Run("Given a stored index, When reopening, Then no vectors are loaded", func(t *testing.T) {
+		vs, db := openHNSWStore(t, dbPath, WithHNSW(cfg))
+		defer db.Close()
+
+		if n := len(vs.vecs.entries); n != 0 {
+			t.Errorf("expected an empty vector cache after open, got %d vectors", n)
+		}
+		if vs.Count() != len(data) {
+			t.Errorf("Count = %d, want %d", vs.Count(), len(data))
+		}
+	})
+
+	t.Run("Given a cache smaller than the store, When searching, Then results match and the cache stays bounded", func(t *testing.T) {
+		vs, db := openHNSWStore(t, dbPath, WithHNSW(cfg), WithVectorCache(50))
+		defer db.Close()
+
+		for i, q := range data[:20] {
+			exact, err := vs.SearchWithOptions(ctx, q, 5, VectorSearchOptions{Exact: true})
+			if err != nil {
+				t.Fatalf("exact search: %v", err)
+			}
+			if fmt.Sprint(exact) != fmt.Sprint(want[i]) {
+				t.Errorf("query %d: exact results %v, want %v", i, exact, want[i])
+			}
+			approx, err := vs.SearchWithOptions(ctx, q, 5, VectorSearchOptions{})
+			if err != nil {
+				t.Fatalf("approximate search: %v", err)
+			}
+			if recallAt(approx, want[i]) < 0.8 {
+				t.Errorf("query %d: approximate results %v, want about %v", i, approx, want[i])
+			}
+		}
+		if n := len(vs.vecs.entries); n > 50 {
+			t.Errorf("cache holds %d vectors, capacity is 50", n)
+		}
+	})
+}
+
+func TestVecStore_HNSWCompaction(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	ctx := context.Background()
+	cfg := DefaultHNSWConfig()
+	cfg.ExactBelow = 0
+
+	rng := rand.New(rand.NewSource(4))
+	data := clusteredVectors(rng, 100, 16, 4)
+	var nodes int
+
+	t.Run("Given the same IDs re-upserted, When reindexing repeatedly, Then the graph does not grow", func(t *testing.T) {
+		vs, db := openHNSWStore(t, dbPath, WithHNSW(cfg))
+		defer db.Close()
+
+		// When
+		for round := 0; round < 5; round++ {
+			for i, v := range data {
+				if err := vs.Upsert(ctx, fmt.Sprintf("v%03d", i), v); err != nil {
+					t.Fatalf("Upsert: %v", err)
+				}
+			}
+		}
+
+		// Then
+		if max := int(float64(len(data)) / (1 - hnswMaxDeadRatio)); len(vs.index.nodes) > max+1 {
+			t.Errorf("graph holds %d node numbers for %d vectors", len(vs.index.nodes), len(data))
+		}
+		var rows int
+		var next uint32
+		db.QueryRow("SELECT COUNT(*) FROM vector_graph").Scan(&rows)
+		db.QueryRow("SELECT value FROM vector_graph_meta WHERE key = 'next'").Scan(&next)
+		if rows != len(data) || int(next) != len(vs.index.nodes) {
+			t.Errorf("persisted %d nodes with next=%d, want %d nodes and next=%d", rows, next, len(data), len(vs.index.nodes))
+		}
+		got, _ := vs.Search(ctx, data[3], 1)
+		if len(got) != 1 || got[0].ID != "v003" {
+			t.Errorf("search after compaction = %v, want v003", got)
+		}
+		nodes = len(vs.index.nodes)
+	})
+
+	t.Run("Given a compacted graph, When reopening, Then it is loaded without a rebuild", func(t *testing.T) {
+		vs, db := openHNSWStore(t, dbPath, WithHNSW(cfg))
+		defer db.Close()
+
+		if vs.index.Len() != len(data) || len(vs.index.nodes) != nodes {
+			t.Errorf("reopened graph has %d live of %d nodes, want %d of %d", vs.index.Len(), len(vs.index.nodes), len(data), nodes)
+		}
+		got, _ := vs.Search(ctx, data[42], 1)
+		if len(got) != 1 || got[0].ID != "v042" {
+			t.Errorf("search after reopen = %v, want v042", got)
+		}
+	})
+}
+
+func TestVecStore_SearchModes(t *testing.T) {
+	ctx := context.Background()
+	rng := rand.New(rand.NewSource(3))
+	data := clusteredVectors(rng, 500, 16, 5)
+
+	cfg := DefaultHNSWConfig()
+	cfg.ExactBelow = 100
+	vs := newMemoryVecStore(data, cfg)
+
+	t.Run("Given a narrow allow-list, When searching, Then only allowed items are returned", func(t *testing.T) {
+		allowed := map[string]bool{"v000001": true, "v000002": true, "v000003": true}
+		results, _ := vs.SearchWithOptions(ctx, data[0], 10, VectorSearchOptions{Allowed: allowed})
+		if len(results) != 3 {
+			t.Fatalf("expected 3 results, got %d", len(results))
+		}
+		for _, r := range results {
+			if !allowed[r.ID] {
+				t.Errorf("unexpected result %s", r.ID)
+			}
+		}
+	})
+
+	t.Run("Given a broad allow-list, When searching approximately, Then results respect it", func(t *testing.T) {
+		allowed := map[string]bool{}
+		for i := 0; i < len(data); i += 2 {
+			allowed[fmt.Sprintf("v%06d", i)] = true
+		}
+		results, _ := vs.SearchWithOptions(ctx, data[1], 10, VectorSearchOptions{Allowed: allowed})
+		if len(results) != 10 {
+			t.Fatalf("expected 10 results, got %d", len(results))
+		}
+		for _, r := range results {
+			if !allowed[r.ID] {
+				t.Errorf("unexpected result %s", r.ID)
+			}
+		}
+	})
+
+	t.Run("Given excluded IDs, When searching approximately, Then they are skipped", func(t *testing.T) {
+		excluded := map[string]bool{"v000001": true, "v000006": true, "v000011": true}
+		results, _ := vs.SearchWithOptions(ctx, data[1], 10, VectorSearchOptions{Excluded: excluded})
+		if len(results) != 10 {
+			t.Fatalf("expected 10 results, got %d", len(results))
+		}
+		for _, r := range results {
+			if excluded[r.ID] {
+				t.Errorf("excluded result %s", r.ID)
+			}
+		}
+	})
+
+	t.Run("Given exact requested, When searching, Then results match brute force order", func(t *testing.T) {
+		exact, _ := vs.SearchWithOptions(ctx, data[5], 10, VectorSearchOptions{Exact: true})
+		brute, _ := vs.exactSearch(normalize(data[5]), 10, nil, nil)
+		if fmt.Sprint(exact) != fmt.Sprint(brute) {
+			t.Errorf("exact search differs from brute force")
+		}
+		if exact[0].ID != "v000005" {
+			t.Errorf("expected self match first, got %s", exact[0].ID)
+		}
+	})
+
+	t.Run("Given mismatched query dimensions, When searching, Then falls back to exact", func(t *testing.T) {
+		results, err := vs.SearchWithOptions(ctx, []float32{1, 0, 0}, 5, VectorSearchOptions{})
+		if err != nil || len(results) != 0 {
+			t.Errorf("expected no results and no error, got %v, %v", results, err)
+		}
+	})
+}
+
+func TestVecStore_UseIndex(t *testing.T) {
+	ids := func(n int) map[string]bool {
+		m := make(map[string]bool, n)
+		for i := 0; i < n; i++ {
+			m[fmt.Sprintf("v%06d", i)] = true
+		}
+		return m
+	}
+	cfg := DefaultHNSWConfig()
+	vs := &VecStore{index: &hnswGraph{}, count: 100000, hnswCfg: &cfg}
+
+	tests := []struct {
+		name string
+		opts VectorSearchOptions
+		want bool
+	}{
+		{name: "Given no filter, Then the graph is used", opts: VectorSearchOptions{}, want: true},
+		{name: "Given exact requested, Then the graph is not used", opts: VectorSearchOptions{Exact: true}, want: false},
+		{name: "Given a broad allow-list, Then the graph is used", opts: VectorSearchOptions{Allowed: ids(50000)}, want: true},
+		{name: "Given an allow-list below ExactBelow, Then the graph is not used", opts: VectorSearchOptions{Allowed: ids(5000)}, want: false},
+		{name: "Given an allow-list that is a small share of the store, Then the graph is not used", opts: VectorSearchOptions{Allowed: ids(12000)}, want: false},
+		{name: "Given a few exclusions, Then the graph is used", opts: VectorSearchOptions{Excluded: ids(1000)}, want: true},
+		{name: "Given exclusions of most of the store, Then the graph is not used", opts: VectorSearchOptions{Excluded: ids(90000)}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := vs.useIndex(tt.opts); got != tt.want {
+				t.Errorf("useIndex = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// snapshotGraph encodes every node's neighbour lists keyed by item ID.
+func snapshotGraph(g *hnswGraph) map[string][]byte {
+	out := make(map[string][]byte, g.Len())
+	for id, n := range g.byID {
+		out[id] = encodeFriends(g.nodes[n].friends)
+	}
+	return out
+}
+
+// =============================================================================
+// Benchmarks: exact vs HNSW
+//
+//	go test -tags fts5 -run '^$' -bench VecStoreSearch ./internal/storage/
+// =============================================================================
+
+type benchData struct {
+	vs      *VecStore
+	queries [][]float32
+}
+
+var benchStores = map[int]benchData{}
+
+// benchStore builds (once per size) an index over n 768-dim vectors, with
+// held-out queries drawn from the same distribution.
+func benchStore(b *testing.B, n int) benchData {
+	b.Helper()
+	if d, ok := benchStores[n]; ok {
+		return d
+	}
+	if n > 20000 && os.Getenv("CODEX_BENCH_LARGE") == "" {
+		b.Skipf("set CODEX_BENCH_LARGE=1 to build a %d-vector index", n)
+	}
+	const numQueries = 100
+	data := clusteredVectors(rand.New(rand.NewSource(int64(n))), n+numQueries, 768, n/50)
+	cfg := DefaultHNSWConfig()
+	cfg.ExactBelow = 0
+	d := benchData{vs: newMemoryVecStore(data[:n], cfg), queries: data[n:]}
+	benchStores[n] = d
+	return d
+}
+
+func BenchmarkVecStoreSearch(b *testing.B) {
+	ctx := context.Background()
+	for _, n := range []int{10000, 100000} {
+		// "selective" allows a tenth of the store, which SearchWithOptions
+		// searches exactly; "selective-graph" forces the graph for comparison
+		for _, mode := range []string{"exact", "hnsw", "selective", "selective-graph"} {
+			b.Run(fmt.Sprintf("n=%d/%s", n, mode), func(b *testing.B) {
+				d := benchStore(b, n)
+				vs, queries := d.vs, d.queries
+				opts := VectorSearchOptions{Exact: mode == "exact"}
+				if strings.HasPrefix(mode, "selective") {
+					opts.Allowed = make(map[string]bool, n/10)
+					for i := 0; i < n; i += 10 {
+						opts.Allowed[fmt.Sprintf("v%06d", i)] = true
+					}
+				}
+				search := func(q []float32) {
+					vs.SearchWithOptions(ctx, q, 10, opts)
+				}
+				if mode == "selective-graph" {
+					accept := opts.accept()
+					search = func(q []float32) {
+						vs.index.search(normalize(q), 10, vs.hnswCfg.EfSearch, accept)
+					}
+				}
+
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					search(queries[i%len(queries)])
+				}
+				b.StopTimer()
+
+				if mode == "hnsw" {
+					var total float64
+					for _, q := range queries {
+						approx, _ := vs.SearchWithOptions(ctx, q, 10, opts)
+						exact, _ := vs.SearchWithOptions(ctx, q, 10, VectorSearchOptions{Exact: true})
+						total += recallAt(approx, exact)
+					}
+					b.ReportMetric(total/float64(len(queries)), "recall@10")
+				}
+			})
+		}
+	}
+}
@@ -20,7 +20,18 @@ func GenerateID() string {
 
 // MetadataStore handles SQLite metadata storage
 type MetadataStore struct {
-	db *sql.DB
+	db             *sql.DB
+	resolveProject func(dir string) string
+}
+
+// MetadataStoreOption configures a MetadataStore.
+type MetadataStoreOption func(*MetadataStore)
+
+// WithProjectResolver sets how the migration that adds items.project turns
+// the directories recorded in item metadata into project identities. By
+// default directories are kept as recorded.
+func WithProjectResolver(resolve func(dir string) string) MetadataStoreOption {
+	return func(s *MetadataStore) { s.resolveProject = resolve }
 }
 
 // ItemRecord represents an item in the metadata store
@@ -31,6 +42,7 @@ type ItemRecord struct {
 	Content   string
 	Tags      []string
 	Scope     string
+	Project   string // project identity (repository path); empty if unknown
 	Source    string
 	Metadata  map[string]any
 	CreatedAt time.Time
@@ -42,6 +54,7 @@ type FeedbackRecord struct {
 	ID        string
 	ItemID    string
 	SessionID string
+	Project   string // project the feedback was given in; "" if unknown
 	Useful    bool
 	Context   string
 	Timestamp time.Time
@@ -58,8 +71,19 @@ type FlightRecorderRecord struct {
 	Metadata  map[string]any
 }
 
+// IndexedFileRecord tracks a source file that has been indexed, so that
+// re-indexing can skip unchanged files and replace or remove stale chunks.
+type IndexedFileRecord struct {
+	Path        string
+	ContentHash string
+	ParentID    string
+	ChunkIDs    []string
+	Scope       string
+	IndexedAt   time.Time
+}
+
 // NewMetadataStore creates a new metadata store
-func NewMetadataStore(dbPath string) (*MetadataStore, error) {
+func NewMetadataStore(dbPath string, opts ...MetadataStoreOption) (*MetadataStore, error) {
 	// Expand ~ in path
 	if strings.HasPrefix(dbPath, "~") {
 		home, err := os.UserHomeDir()
@@ -80,6 +104,9 @@ func NewMetadataStore(dbPath string) (*MetadataStore, error) {
 	}
 
 	store := &MetadataStore{db: db}
+	for _, opt := range opts {
+		opt(store)
+	}
 	if err := store.migrate(); err != nil {
 		db.Close()
 		return nil, err
@@ -140,16 +167,19 @@ func (s *MetadataStore) migrate() error {
 			content TEXT NOT NULL,
 			tags TEXT,
 			scope TEXT NOT NULL DEFAULT 'project',
+			project TEXT NOT NULL DEFAULT '',
 			source TEXT,
 			metadata TEXT,
 			created_at DATETIME NOT NULL,
-			updated_at DATETIME NOT NULL
+			updated_at DATETIME NOT NULL,
+			` + itemsGitRefColumn + `
 		);
 
 		CREATE TABLE IF NOT EXISTS feedback (
 			id TEXT PRIMARY KEY,
 			item_id TEXT NOT NULL,
 			session_id TEXT NOT NULL,
+			project TEXT NOT NULL DEFAULT '',
 			useful INTEGER NOT NULL,
 			context TEXT,
 			timestamp DATETIME NOT NULL,
@@ -166,6 +196,21 @@ func (s *MetadataStore) migrate() error {
 			metadata TEXT
 		);
 
+		CREATE TABLE IF NOT EXISTS indexed_files (
+			path TEXT PRIMARY KEY,
+			content_hash TEXT NOT NULL,
+			parent_id TEXT NOT NULL,
+			chunk_ids TEXT NOT NULL,
+			scope TEXT NOT NULL DEFAULT 'project',
+			indexed_at DATETIME NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS enrichments (
+			key TEXT PRIMARY KEY,
+			context TEXT NOT NULL,
+			created_at DATETIME NOT NULL
+		);
+
 		CREATE INDEX IF NOT EXISTS idx_items_type ON items(type);
 		CREATE INDEX IF NOT EXISTS idx_items_scope ON items(scope);
 		CREATE INDEX IF NOT EXISTS idx_feedback_item ON feedback(item_id);
@@ -183,6 +228,18 @@ func (s *MetadataStore) migrate() error {
 	if err != nil {
 		return err
 	}
+	if err := s.createCodeGraphTables(); err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(gitStateSchema); err != nil {
+		return fmt.Errorf("create git state table: %w", err)
+	}
+	if err := s.upgradeItemsTable(); err != nil {
+		return err
+	}
+	if err := s.upgradeFeedbackTable(); err != nil {
+		return err
+	}
 
 	// Create triggers to keep FTS in sync with items table
 	triggers := `
@@ -223,6 +280,129 @@ func (s *MetadataStore) migrate() error {
 	return nil
 }
 
+// upgradeItemsTable adds columns introduced after the items table was first
+// created. CREATE TABLE IF NOT EXISTS leaves existing tables untouched, so
+// older databases are altered in place.
+func (s *MetadataStore) upgradeItemsTable() error {
+	hasProject, err := hasColumn(s.db, "items", "project")
+	if err != nil {
+		return err
+	}
+	if !hasProject {
+		if err := s.addItemProjects(); err != nil {
+			return err
+		}
+	}
+
+	if _, err := s.db.Exec("CREATE INDEX IF NOT EXISTS idx_items_scope_project ON items(scope, project)"); err != nil {
+		return err
+	}
+
+	hasGitRef, err := hasColumn(s.db, "items", "git_ref")
+	if err != nil {
+		return err
+	}
+	if !hasGitRef {
+		if _, err := s.db.Exec("ALTER TABLE items ADD COLUMN " + itemsGitRefColumn); err != nil {
+			return fmt.Errorf("add items.git_ref column: %w", err)
+		}
+	}
+	_, err = s.db.Exec("CREATE INDEX IF NOT EXISTS idx_items_git_ref ON items(git_ref) WHERE git_ref IS NOT NULL")
+	return err
+}
+
+// itemsGitRefColumn defines items.git_ref, the metadata git_ref of items
+// IndexGit took from a ref and NULL for all others. It is generated from
+// metadata, so writers never set it. A partial index over the snapshots
+// lets searches find them without reading every item.
+const itemsGitRefColumn = `git_ref TEXT GENERATED ALWAYS AS (
+			CASE WHEN json_valid(metadata) THEN json_extract(metadata, '$.git_ref') END
+		) VIRTUAL`
+
+// addItemProjects adds the project column and backfills it from the
+// project_path that MCP recall_add stores in metadata, resolved the way new
+// items are, so they match scope=project searches. Each directory is
+// resolved once, before writing; the column and its values are then added
+// in one transaction, so an interrupted upgrade is redone on the next open.
+func (s *MetadataStore) addItemProjects() error {
+	rows, err := s.db.Query(`
+		SELECT id, json_extract(metadata, '$.project_path') FROM items
+		WHERE json_valid(metadata) AND json_type(metadata, '$.project_path') = 'text'
+	`)
+	if err != nil {
+		return fmt.Errorf("read item project paths: %w", err)
+	}
+	projects := make(map[string]string)
+	resolved := make(map[string]string)
+	for rows.Next() {
+		var id, dir string
+		if err := rows.Scan(&id, &dir); err != nil {
+			rows.Close()
+			return fmt.Errorf("read item project paths: %w", err)
+		}
+		p, ok := resolved[dir]
+		if !ok {
+			p = dir
+			if s.resolveProject != nil {
+				p = s.resolveProject(dir)
+			}
+			resolved[dir] = p
+		}
+		projects[id] = p
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("read item project paths: %w", err)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`ALTER TABLE items ADD COLUMN project TEXT NOT NULL DEFAULT ''`); err != nil {
+		return fmt.Errorf("add items.project column: %w", err)
+	}
+	stmt, err := tx.Prepare("UPDATE items SET project = ? WHERE id = ?")
+	if err != nil {
+		return fmt.Errorf("backfill items.project: %w", err)
+	}
+	defer stmt.Close()
+	for id, p := range projects {
+		if _, err := stmt.Exec(p, id); err != nil {
+			return fmt.Errorf("backfill items.project: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// upgradeFeedbackTable adds the project column to feedback tables created
+// before feedback was attributed to projects.
+func (s *MetadataStore) upgradeFeedbackTable() error {
+	hasProject, err := hasColumn(s.db, "feedback", "project")
+	if err != nil {
+		return err
+	}
+	if !hasProject {
+		if _, err := s.db.Exec(`ALTER TABLE feedback ADD COLUMN project TEXT NOT NULL DEFAULT ''`); err != nil {
+			return fmt.Errorf("add feedback.project column: %w", err)
+		}
+	}
+	return nil
+}
+
+// hasColumn reports whether table has a column with the given name,
+// generated columns included.
+func hasColumn(db *sql.DB, table, column string) (bool, error) {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM pragma_table_xinfo(?) WHERE name = ?", table, column).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("inspect %s columns: %w", table, err)
+	}
+	return count > 0, nil
+}
+
 // DB returns the underlying database connection.
 // Used to share the SQLite connection with VecStore.
 func (s *MetadataStore) DB() *sql.DB {
@@ -246,13 +426,13 @@ func (s *MetadataStore) SaveItem(item *ItemRecord) error {
 	}
 
 	_, err = s.db.Exec(`
-		INSERT INTO items (id, type, title, content, tags, scope, source, metadata, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO items (id, type, title, content, tags, scope, project, source, metadata, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(id) DO UPDATE SET
 			type=excluded.type, title=excluded.title, content=excluded.content,
-			tags=excluded.tags, scope=excluded.scope, source=excluded.source,
-			metadata=excluded.metadata, updated_at=excluded.updated_at
-	`, item.ID, item.Type, item.Title, item.Content, string(tagsJSON), item.Scope, item.Source, string(metaJSON), item.CreatedAt, item.UpdatedAt)
+			tags=excluded.tags, scope=excluded.scope, project=excluded.project,
+			source=excluded.source, metadata=excluded.metadata, updated_at=excluded.updated_at
+	`, item.ID, item.Type, item.Title, item.Content, string(tagsJSON), item.Scope, item.Project, item.Source, string(metaJSON), item.CreatedAt, item.UpdatedAt)
 
 	return err
 }
@@ -260,14 +440,14 @@ func (s *MetadataStore) SaveItem(item *ItemRecord) error {
 // GetItem retrieves an item by ID
 func (s *MetadataStore) GetItem(id string) (*ItemRecord, error) {
 	row := s.db.QueryRow(`
-		SELECT id, type, title, content, tags, scope, source, metadata, created_at, updated_at
+		SELECT id, type, title, content, tags, scope, project, source, metadata, created_at, updated_at
 		FROM items WHERE id = ?
 	`, id)
 
 	var item ItemRecord
 	var tagsJSON, metaJSON string
 
-	err := row.Scan(&item.ID, &item.Type, &item.Title, &item.Content, &tagsJSON, &item.Scope, &item.Source, &metaJSON, &item.CreatedAt, &item.UpdatedAt)
+	err := row.Scan(&item.ID, &item.Type, &item.Title, &item.Content, &tagsJSON, &item.Scope, &item.Project, &item.Source, &metaJSON, &item.CreatedAt, &item.UpdatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("item not found: %s", id)
@@ -292,13 +472,45 @@ func (s *MetadataStore) GetItem(id string) (*ItemRecord, error) {
 // RecordFeedback records feedback on an item
 func (s *MetadataStore) RecordFeedback(feedback *FeedbackRecord) error {
 	_, err := s.db.Exec(`
-		INSERT INTO feedback (id, item_id, session_id, useful, context, timestamp)
-		VALUES (?, ?, ?, ?, ?, ?)
-	`, feedback.ID, feedback.ItemID, feedback.SessionID, feedback.Useful, feedback.Context, feedback.Timestamp)
+		INSERT INTO feedback (id, item_id, session_id, project, useful, context, timestamp)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, feedback.ID, feedback.ItemID, feedback.SessionID, feedback.Project, feedback.Useful, feedback.Context, feedback.Timestamp)
 
 	return err
 }
 
+// ListFeedback returns all feedback recorded on the given items, oldest first.
+func (s *MetadataStore) ListFeedback(itemIDs []string) ([]*FeedbackRecord, error) {
+	if len(itemIDs) == 0 {
+		return nil, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(itemIDs)), ",")
+	args := make([]any, len(itemIDs))
+	for i, id := range itemIDs {
+		args[i] = id
+	}
+
+	rows, err := s.db.Query(`
+		SELECT id, item_id, session_id, project, useful, COALESCE(context, ''), timestamp
+		FROM feedback WHERE item_id IN (`+placeholders+`)
+		ORDER BY timestamp
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []*FeedbackRecord
+	for rows.Next() {
+		var r FeedbackRecord
+		if err := rows.Scan(&r.ID, &r.ItemID, &r.SessionID, &r.Project, &r.Useful, &r.Context, &r.Timestamp); err != nil {
+			return nil, err
+		}
+		records = append(records, &r)
+	}
+	return records, rows.Err()
+}
+
 // LogFlightRecorder logs an entry to the flight recorder
 func (s *MetadataStore) LogFlightRecorder(entry *FlightRecorderRecord) error {
 	metaJSON, err := json.Marshal(entry.Metadata)
@@ -354,7 +566,7 @@ func (s *MetadataStore) ListItems(itemType, scope string, limit, offset int) ([]
 		limit = 50
 	}
 
-	query := "SELECT id, type, title, content, tags, scope, source, metadata, created_at, updated_at FROM items WHERE 1=1"
+	query := "SELECT id, type, title, content, tags, scope, project, source, metadata, created_at, updated_at FROM items WHERE 1=1"
 	args := []any{}
 
 	if itemType != "" {
@@ -375,13 +587,35 @@ func (s *MetadataStore) ListItems(itemType, scope string, limit, offset int) ([]
 		return nil, err
 	}
 	defer rows.Close()
+	return scanItems(rows)
+}
 
+// ListItemsAfter returns up to limit items whose ID sorts after afterID, in
+// ID order. Paging by the last ID seen visits every item exactly once even
+// while items are added or updated, which offset paging by recency does not.
+func (s *MetadataStore) ListItemsAfter(afterID string, limit int) ([]*ItemRecord, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	rows, err := s.db.Query(`
+		SELECT id, type, title, content, tags, scope, project, source, metadata, created_at, updated_at
+		FROM items WHERE id > ? ORDER BY id LIMIT ?
+	`, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanItems(rows)
+}
+
+// scanItems reads item rows selected in ListItems column order.
+func scanItems(rows *sql.Rows) ([]*ItemRecord, error) {
 	var items []*ItemRecord
 	for rows.Next() {
 		var item ItemRecord
 		var tagsJSON, metaJSON string
 
-		err := rows.Scan(&item.ID, &item.Type, &item.Title, &item.Content, &tagsJSON, &item.Scope, &item.Source, &metaJSON, &item.CreatedAt, &item.UpdatedAt)
+		err := rows.Scan(&item.ID, &item.Type, &item.Title, &item.Content, &tagsJSON, &item.Scope, &item.Project, &item.Source, &metaJSON, &item.CreatedAt, &item.UpdatedAt)
 		if err != nil {
 			return nil, err
 		}
@@ -403,8 +637,16 @@ func (s *MetadataStore) ListItems(itemType, scope string, limit, offset int) ([]
 }
 
 // KeywordSearch performs FTS5 full-text search on items.
+// The query is parsed by BuildFTSQuery (OR-joined terms, prefix matching for
+// identifiers, opt-in phrases and column filters).
 // Returns results ranked by BM25 relevance score.
 func (s *MetadataStore) KeywordSearch(query string, limit int) ([]KeywordResult, error) {
+	return s.KeywordSearchFiltered(query, limit, SearchFilter{})
+}
+
+// KeywordSearchFiltered is KeywordSearch restricted to items matching filter.
+// The filter is applied in SQL so that limit counts only matching items.
+func (s *MetadataStore) KeywordSearchFiltered(query string, limit int, filter SearchFilter) ([]KeywordResult, error) {
 	if strings.TrimSpace(query) == "" {
 		return nil, nil
 	}
@@ -412,22 +654,26 @@ func (s *MetadataStore) KeywordSearch(query string, limit int) ([]KeywordResult,
 		limit = 50
 	}
 
-	// Sanitize query for FTS5: escape double quotes and wrap in quotes
-	// to prevent FTS5 syntax errors from special characters.
-	// NOTE: This disables FTS5 advanced syntax (AND, OR, NEAR, *) by design —
-	// the query is treated as a literal phrase match for safety.
-	sanitized := strings.ReplaceAll(query, `"`, `""`)
-	sanitized = `"` + sanitized + `"`
+	// Translate the query into a token-level FTS5 expression. Every term is
+	// quoted by BuildFTSQuery, so raw FTS5 syntax in user input is never executed.
+	match := BuildFTSQuery(query)
+	if match == "" {
+		return nil, nil
+	}
+
+	where, filterArgs := filter.whereClause("i")
+	args := append([]any{match}, filterArgs...)
+	args = append(args, limit)
 
 	rows, err := s.db.Query(`
 		SELECT i.id, i.type, i.title, i.content, i.tags, i.scope,
 		       -rank AS score
 		FROM items_fts f
 		JOIN items i ON i.rowid = f.rowid
-		WHERE items_fts MATCH ?
+		WHERE items_fts MATCH ?`+where+`
 		ORDER BY rank
 		LIMIT ?
-	`, sanitized, limit)
+	`, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -462,6 +708,40 @@ type KeywordResult struct {
 	Score   float64
 }
 
+// FilterItemIDs returns the IDs of all items matching filter.
+// Used to build an allow-list for filtered vector search.
+func (s *MetadataStore) FilterItemIDs(filter SearchFilter) ([]string, error) {
+	where, args := filter.whereClause("i")
+	return s.queryIDs("SELECT i.id FROM items i WHERE 1=1"+where, args...)
+}
+
+// GitRefItemIDs returns the IDs of the items IndexGit took from a git ref.
+// Used to leave ref snapshots out of unfiltered vector search. It finds
+// them through the git_ref index, so its cost follows their number, not
+// the size of the store.
+func (s *MetadataStore) GitRefItemIDs() ([]string, error) {
+	return s.queryIDs("SELECT id FROM items WHERE git_ref IS NOT NULL")
+}
+
+// queryIDs runs a query selecting one ID column.
+func (s *MetadataStore) queryIDs(query string, args ...any) ([]string, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
 // DeleteItem removes an item from the metadata store
 func (s *MetadataStore) DeleteItem(id string) error {
 	_, err := s.db.Exec("DELETE FROM items WHERE id = ?", id)
@@ -499,3 +779,103 @@ func (s *MetadataStore) CountItemsByType() (map[string]int, error) {
 	}
 	return counts, rows.Err()
 }
+
+// GetIndexedFile returns the tracking record for a source file path.
+// Returns nil (and no error) if the file has never been indexed.
+func (s *MetadataStore) GetIndexedFile(path string) (*IndexedFileRecord, error) {
+	row := s.db.QueryRow(`
+		SELECT path, content_hash, parent_id, chunk_ids, scope, indexed_at
+		FROM indexed_files WHERE path = ?
+	`, path)
+
+	var rec IndexedFileRecord
+	var chunkIDsJSON string
+	err := row.Scan(&rec.Path, &rec.ContentHash, &rec.ParentID, &chunkIDsJSON, &rec.Scope, &rec.IndexedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(chunkIDsJSON), &rec.ChunkIDs); err != nil {
+		return nil, fmt.Errorf("unmarshal chunk ids for %s: %w", path, err)
+	}
+	return &rec, nil
+}
+
+// SaveIndexedFile creates or replaces the tracking record for a source file.
+func (s *MetadataStore) SaveIndexedFile(rec *IndexedFileRecord) error {
+	chunkIDsJSON, err := json.Marshal(rec.ChunkIDs)
+	if err != nil {
+		return fmt.Errorf("marshal chunk ids: %w", err)
+	}
+
+	_, err = s.db.Exec(`
+		INSERT INTO indexed_files (path, content_hash, parent_id, chunk_ids, scope, indexed_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(path) DO UPDATE SET
+			content_hash=excluded.content_hash, parent_id=excluded.parent_id,
+			chunk_ids=excluded.chunk_ids, scope=excluded.scope, indexed_at=excluded.indexed_at
+	`, rec.Path, rec.ContentHash, rec.ParentID, string(chunkIDsJSON), rec.Scope, rec.IndexedAt)
+	return err
+}
+
+// DeleteIndexedFile removes the tracking record for a source file.
+// It does not touch the file's chunk items.
+func (s *MetadataStore) DeleteIndexedFile(path string) error {
+	_, err := s.db.Exec("DELETE FROM indexed_files WHERE path = ?", path)
+	return err
+}
+
+// ListIndexedFiles returns tracking records for all files whose path starts
+// with pathPrefix. An empty prefix returns every tracked file.
+func (s *MetadataStore) ListIndexedFiles(pathPrefix string) ([]*IndexedFileRecord, error) {
+	rows, err := s.db.Query(`
+		SELECT path, content_hash, parent_id, chunk_ids, scope, indexed_at
+		FROM indexed_files
+		WHERE instr(path, ?) = 1
+		ORDER BY path
+	`, pathPrefix)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []*IndexedFileRecord
+	for rows.Next() {
+		var rec IndexedFileRecord
+		var chunkIDsJSON string
+		if err := rows.Scan(&rec.Path, &rec.ContentHash, &rec.ParentID, &chunkIDsJSON, &rec.Scope, &rec.IndexedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(chunkIDsJSON), &rec.ChunkIDs); err != nil {
+			return nil, fmt.Errorf("unmarshal chunk ids for %s: %w", rec.Path, err)
+		}
+		records = append(records, &rec)
+	}
+	return records, rows.Err()
+}
+
+// GetEnrichment returns the cached contextual description for a chunk key.
+// The boolean is false if no enrichment has been cached for the key.
+func (s *MetadataStore) GetEnrichment(key string) (string, bool, error) {
+	var context string
+	err := s.db.QueryRow("SELECT context FROM enrichments WHERE key = ?", key).Scan(&context)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return context, true, nil
+}
+
+// SaveEnrichment caches the contextual description for a chunk key.
+func (s *MetadataStore) SaveEnrichment(key, context string) error {
+	_, err := s.db.Exec(`
+		INSERT INTO enrichments (key, context, created_at) VALUES (?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET context=excluded.context, created_at=excluded.created_at
+	`, key, context, time.Now())
+	return err
+}
@@ -1,11 +1,16 @@
 package storage
 
 import (
+	"database/sql"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/anthropics/aef/codex/internal/project"
 )
 
 // createTestMetadataStore creates an in-memory SQLite database for testing
@@ -300,6 +305,49 @@ func TestListItems_OrderByUpdatedAt(t *testing.T) {
 	}
 }
 
+func TestListItemsAfter(t *testing.T) {
+	t.Run("Given items updated between pages When paging by ID Then every item is listed once", func(t *testing.T) {
+		// Given
+		store, cleanup := createTestMetadataStore(t)
+		defer cleanup()
+		seedTestItems(t, store, []*ItemRecord{
+			makeTestItem("a", "pattern", "project"),
+			makeTestItem("b", "pattern", "project"),
+			makeTestItem("c", "pattern", "project"),
+			makeTestItem("d", "pattern", "project"),
+			makeTestItem("e", "pattern", "project"),
+		})
+
+		// When
+		var seen []string
+		for after := ""; ; {
+			page, err := store.ListItemsAfter(after, 2)
+			if err != nil {
+				t.Fatalf("ListItemsAfter() error = %v", err)
+			}
+			if len(page) == 0 {
+				break
+			}
+			for _, item := range page {
+				seen = append(seen, item.ID)
+			}
+			after = page[len(page)-1].ID
+
+			// An update moves the item to the front of recency order
+			updated := makeTestItem("e", "pattern", "project")
+			updated.UpdatedAt = time.Now().Add(time.Duration(len(seen)) * time.Hour)
+			if err := store.SaveItem(updated); err != nil {
+				t.Fatalf("SaveItem() error = %v", err)
+			}
+		}
+
+		// Then
+		if got := strings.Join(seen, ","); got != "a,b,c,d,e" {
+			t.Errorf("listed %s, want a,b,c,d,e", got)
+		}
+	})
+}
+
 func TestListItems_PreservesAllFields(t *testing.T) {
 	// Given - an item with all fields populated
 	store, cleanup := createTestMetadataStore(t)
@@ -1104,3 +1152,360 @@ func TestMetadataStore_KeywordSearch_SpecialChars(t *testing.T) {
 	}
 	_ = results
 }
+
+// =============================================================================
+// Indexed file tracking tests
+// =============================================================================
+
+func TestMetadataStore_IndexedFiles_RoundTrip(t *testing.T) {
+	store, cleanup := createTestMetadataStore(t)
+	defer cleanup()
+
+	// Unknown path returns nil without error
+	rec, err := store.GetIndexedFile("/repo/missing.go")
+	if err != nil {
+		t.Fatalf("GetIndexedFile failed: %v", err)
+	}
+	if rec != nil {
+		t.Fatalf("expected nil record for untracked file, got %+v", rec)
+	}
+
+	want := &IndexedFileRecord{
+		Path:        "/repo/main.go",
+		ContentHash: "abc123",
+		ParentID:    "parent-1",
+		ChunkIDs:    []string{"parent-1-chunk-0", "parent-1-chunk-1"},
+		Scope:       "project",
+		IndexedAt:   time.Now(),
+	}
+	if err := store.SaveIndexedFile(want); err != nil {
+		t.Fatalf("SaveIndexedFile failed: %v", err)
+	}
+
+	got, err := store.GetIndexedFile(want.Path)
+	if err != nil {
+		t.Fatalf("GetIndexedFile failed: %v", err)
+	}
+	if got == nil || got.ContentHash != "abc123" || got.ParentID != "parent-1" || len(got.ChunkIDs) != 2 {
+		t.Fatalf("unexpected record: %+v", got)
+	}
+
+	// Upsert replaces hash and chunk list
+	want.ContentHash = "def456"
+	want.ChunkIDs = []string{"parent-1-chunk-0"}
+	if err := store.SaveIndexedFile(want); err != nil {
+		t.Fatalf("SaveIndexedFile (update) failed: %v", err)
+	}
+	got, _ = store.GetIndexedFile(want.Path)
+	if got.ContentHash != "def456" || len(got.ChunkIDs) != 1 {
+		t.Errorf("update not applied: %+v", got)
+	}
+
+	if err := store.DeleteIndexedFile(want.Path); err != nil {
+		t.Fatalf("DeleteIndexedFile failed: %v", err)
+	}
+	got, _ = store.GetIndexedFile(want.Path)
+	if got != nil {
+		t.Errorf("expected record to be deleted, got %+v", got)
+	}
+}
+
+func TestMetadataStore_ListIndexedFiles_Prefix(t *testing.T) {
+	store, cleanup := createTestMetadataStore(t)
+	defer cleanup()
+
+	for _, p := range []string{"/repo/a.go", "/repo/sub/b.go", "/repository/c.go", "/other/d.go"} {
+		if err := store.SaveIndexedFile(&IndexedFileRecord{
+			Path:        p,
+			ContentHash: "h",
+			ParentID:    "p",
+			ChunkIDs:    []string{},
+			Scope:       "project",
+			IndexedAt:   time.Now(),
+		}); err != nil {
+			t.Fatalf("SaveIndexedFile(%s) failed: %v", p, err)
+		}
+	}
+
+	records, err := store.ListIndexedFiles("/repo/")
+	if err != nil {
+		t.Fatalf("ListIndexedFiles failed: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records under /repo/, got %d", len(records))
+	}
+	if records[0].Path != "/repo/a.go" || records[1].Path != "/repo/sub/b.go" {
+		t.Errorf("unexpected paths: %s, %s", records[0].Path, records[1].Path)
+	}
+
+	all, err := store.ListIndexedFiles("")
+	if err != nil {
+		t.Fatalf("ListIndexedFiles(\"\") failed: %v", err)
+	}
+	if len(all) != 4 {
+		t.Errorf("expected 4 records with empty prefix, got %d", len(all))
+	}
+}
+
+func TestMetadataStore_UpgradeAddsProjectColumn(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "old.db")
+	repo := filepath.Join(tmpDir, "repo")
+	if err := os.MkdirAll(filepath.Join(repo, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(repo, "services", "api"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Given a database created before items had a project column, with one
+	// item added from a subdirectory of a repository
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	_, err = db.Exec(`
+		CREATE TABLE items (
+			id TEXT PRIMARY KEY, type TEXT NOT NULL, title TEXT NOT NULL,
+			content TEXT NOT NULL, tags TEXT, scope TEXT NOT NULL DEFAULT 'project',
+			source TEXT, metadata TEXT, created_at DATETIME NOT NULL, updated_at DATETIME NOT NULL
+		);
+		INSERT INTO items VALUES ('with-path', 'pattern', 't', 'c', '[]', 'project', 'manual',
+			'{"project_path":"/src/a"}', '2024-01-01 00:00:00', '2024-01-01 00:00:00');
+		INSERT INTO items VALUES ('no-path', 'pattern', 't', 'c', '[]', 'global', 'manual',
+			'null', '2024-01-01 00:00:00', '2024-01-01 00:00:00');
+		INSERT INTO items VALUES ('in-repo', 'pattern', 't', 'c', '[]', 'project', 'manual',
+			json_object('project_path', ?), '2024-01-01 00:00:00', '2024-01-01 00:00:00');
+		INSERT INTO items VALUES ('in-repo-too', 'pattern', 't', 'c', '[]', 'project', 'manual',
+			json_object('project_path', ?), '2024-01-01 00:00:00', '2024-01-01 00:00:00');
+	`, filepath.Join(repo, "services", "api"), filepath.Join(repo, "services", "api"))
+	db.Close()
+	if err != nil {
+		t.Fatalf("seed old schema: %v", err)
+	}
+
+	// When the store is opened with a project resolver
+	resolved := make(map[string]int)
+	resolve := func(dir string) string {
+		resolved[dir]++
+		return project.Resolve(dir)
+	}
+	store, err := NewMetadataStore(dbPath, WithProjectResolver(resolve))
+	if err != nil {
+		t.Fatalf("NewMetadataStore failed on old schema: %v", err)
+	}
+	defer store.Close()
+
+	// Then project is backfilled from metadata, resolving each directory once
+	if len(resolved) != 2 || resolved[filepath.Join(repo, "services", "api")] != 1 {
+		t.Errorf("resolver calls = %v, want one per directory", resolved)
+	}
+	item, err := store.GetItem("with-path")
+	if err != nil {
+		t.Fatalf("GetItem failed: %v", err)
+	}
+	if item.Project != "/src/a" {
+		t.Errorf("expected backfilled project /src/a, got %q", item.Project)
+	}
+	item, err = store.GetItem("in-repo")
+	if err != nil {
+		t.Fatalf("GetItem failed: %v", err)
+	}
+	if item.Project != repo {
+		t.Errorf("expected backfilled project %s (the repository root), got %q", repo, item.Project)
+	}
+	item, err = store.GetItem("no-path")
+	if err != nil {
+		t.Fatalf("GetItem failed: %v", err)
+	}
+	if item.Project != "" {
+		t.Errorf("expected empty project, got %q", item.Project)
+	}
+}
+
+func TestMetadataStore_UpgradeAddsGitRefColumn(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "old.db")
+
+	// Given a database created before items had a git_ref column, holding a
+	// ref snapshot, a work tree item and an item with unparsable metadata
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	_, err = db.Exec(`
+		CREATE TABLE items (
+			id TEXT PRIMARY KEY, type TEXT NOT NULL, title TEXT NOT NULL,
+			content TEXT NOT NULL, tags TEXT, scope TEXT NOT NULL DEFAULT 'project',
+			project TEXT NOT NULL DEFAULT '',
+			source TEXT, metadata TEXT, created_at DATETIME NOT NULL, updated_at DATETIME NOT NULL
+		);
+		INSERT INTO items VALUES ('snapshot', 'code', 't', 'c', '[]', 'project', '', 'v1:main.go',
+			'{"git_ref":"v1.0"}', '2024-01-01 00:00:00', '2024-01-01 00:00:00');
+		INSERT INTO items VALUES ('live', 'code', 't', 'c', '[]', 'project', '', 'main.go',
+			'{"git_commit":"abc"}', '2024-01-01 00:00:00', '2024-01-01 00:00:00');
+		INSERT INTO items VALUES ('legacy', 'pattern', 't', 'c', '[]', 'global', '', 'manual',
+			'', '2024-01-01 00:00:00', '2024-01-01 00:00:00');
+	`)
+	db.Close()
+	if err != nil {
+		t.Fatalf("seed old schema: %v", err)
+	}
+
+	// When the store is opened
+	store, err := NewMetadataStore(dbPath)
+	if err != nil {
+		t.Fatalf("NewMetadataStore failed on old schema: %v", err)
+	}
+	defer store.Close()
+
+	// Then snapshots are found through the new column and its index
+	ids, err := store.GitRefItemIDs()
+	if err != nil {
+		t.Fatalf("GitRefItemIDs failed: %v", err)
+	}
+	if !equalStrings(ids, []string{"snapshot"}) {
+		t.Errorf("GitRefItemIDs = %v, want [snapshot]", ids)
+	}
+	ids, err = store.FilterItemIDs(SearchFilter{Types: []string{"code", "pattern"}})
+	if err != nil {
+		t.Fatalf("FilterItemIDs failed: %v", err)
+	}
+	sort.Strings(ids)
+	if !equalStrings(ids, []string{"legacy", "live"}) {
+		t.Errorf("FilterItemIDs = %v, want [legacy live]", ids)
+	}
+
+	var plan strings.Builder
+	rows, err := store.DB().Query("EXPLAIN QUERY PLAN SELECT id FROM items WHERE git_ref IS NOT NULL")
+	if err != nil {
+		t.Fatalf("explain: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, parent, unused int
+		var detail string
+		if err := rows.Scan(&id, &parent, &unused, &detail); err != nil {
+			t.Fatalf("scan plan: %v", err)
+		}
+		plan.WriteString(detail)
+	}
+	if !strings.Contains(plan.String(), "idx_items_git_ref") {
+		t.Errorf("query plan %q does not use idx_items_git_ref", plan.String())
+	}
+}
+
+// =============================================================================
+// Enrichment cache tests
+// =============================================================================
+
+func TestMetadataStore_EnrichmentCache(t *testing.T) {
+	store, cleanup := createTestMetadataStore(t)
+	defer cleanup()
+
+	// Unknown key is a miss without error
+	if _, ok, err := store.GetEnrichment("k1"); err != nil || ok {
+		t.Fatalf("expected miss, got ok=%v err=%v", ok, err)
+	}
+
+	if err := store.SaveEnrichment("k1", "first"); err != nil {
+		t.Fatalf("SaveEnrichment failed: %v", err)
+	}
+	if err := store.SaveEnrichment("k1", "second"); err != nil {
+		t.Fatalf("SaveEnrichment overwrite failed: %v", err)
+	}
+
+	got, ok, err := store.GetEnrichment("k1")
+	if err != nil || !ok {
+		t.Fatalf("expected hit, got ok=%v err=%v", ok, err)
+	}
+	if got != "second" {
+		t.Errorf("expected overwritten context %q, got %q", "second", got)
+	}
+}
+
+// =============================================================================
+// Feedback tests
+// =============================================================================
+
+func TestMetadataStore_ListFeedback(t *testing.T) {
+	store, cleanup := createTestMetadataStore(t)
+	defer cleanup()
+	seedTestItems(t, store, []*ItemRecord{
+		makeTestItem("a", "pattern", "project"),
+		makeTestItem("b", "pattern", "project"),
+		makeTestItem("c", "pattern", "project"),
+	})
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	records := []*FeedbackRecord{
+		{ID: "f2", ItemID: "a", SessionID: "s", Project: "/src/a", Useful: false, Timestamp: base.Add(time.Hour)},
+		{ID: "f1", ItemID: "a", SessionID: "s", Project: "/src/a", Useful: true, Context: "helped", Timestamp: base},
+		{ID: "f3", ItemID: "b", SessionID: "s", Useful: true, Timestamp: base},
+		{ID: "f4", ItemID: "c", SessionID: "s", Useful: true, Timestamp: base},
+	}
+	for _, r := range records {
+		if err := store.RecordFeedback(r); err != nil {
+			t.Fatalf("RecordFeedback: %v", err)
+		}
+	}
+
+	got, err := store.ListFeedback([]string{"a", "b"})
+	if err != nil {
+		t.Fatalf("ListFeedback: %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("expected 3 records for a and b, got %d", len(got))
+	}
+	if got[0].ID != "f1" && got[0].ID != "f3" {
+		t.Errorf("expected oldest feedback first, got %s", got[0].ID)
+	}
+	for _, r := range got {
+		if r.ID == "f1" && (r.Project != "/src/a" || !r.Useful || r.Context != "helped") {
+			t.Errorf("f1 round-tripped as %+v", r)
+		}
+		if r.ItemID == "c" {
+			t.Error("feedback on c should not be listed")
+		}
+	}
+
+	if got, err := store.ListFeedback(nil); err != nil || got != nil {
+		t.Errorf("ListFeedback(nil) = %v, %v", got, err)
+	}
+}
+
+func TestMetadataStore_UpgradeAddsFeedbackProject(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "old.db")
+
+	// Given a feedback table created before feedback had a project
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	_, err = db.Exec(`
+		CREATE TABLE feedback (
+			id TEXT PRIMARY KEY, item_id TEXT NOT NULL, session_id TEXT NOT NULL,
+			useful INTEGER NOT NULL, context TEXT, timestamp DATETIME NOT NULL
+		);
+		INSERT INTO feedback VALUES ('f1', 'a', 's', 1, NULL, '2024-01-01 00:00:00');
+	`)
+	db.Close()
+	if err != nil {
+		t.Fatalf("seed old schema: %v", err)
+	}
+
+	// When the store is opened
+	store, err := NewMetadataStore(dbPath)
+	if err != nil {
+		t.Fatalf("NewMetadataStore failed on old schema: %v", err)
+	}
+	defer store.Close()
+
+	// Then old feedback reads back without a project
+	got, err := store.ListFeedback([]string{"a"})
+	if err != nil {
+		t.Fatalf("ListFeedback: %v", err)
+	}
+	if len(got) != 1 || got[0].Project != "" || !got[0].Useful {
+		t.Errorf("unexpected feedback %+v", got)
+	}
+}
@@ -0,0 +1,234 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// DefaultVectorCache is the number of vectors a VecStore keeps in memory
+// unless WithVectorCache says otherwise: about 150 MB of 768-dim vectors.
+const DefaultVectorCache = 50000
+
+// vectorLoadBatch bounds the number of item IDs per SQL lookup.
+const vectorLoadBatch = 500
+
+// vectorCache reads normalized vectors from the vectors table on demand
+// and keeps recently used ones in memory, up to capacity, evicting with
+// the CLOCK algorithm. While the store holds no more vectors than that,
+// the cache ends up holding all of them and exact search stops reading
+// SQLite.
+type vectorCache struct {
+	db *sql.DB // nil for memory-only stores in tests
+
+	mu       sync.Mutex
+	entries  map[string]*cachedVector
+	ring     []*cachedVector // eviction order; nil slots are free
+	hand     int             // next ring slot to consider for eviction
+	capacity int             // <= 0 means unbounded
+	complete bool            // entries hold every stored vector
+}
+
+type cachedVector struct {
+	id   string
+	vec  []float32
+	slot int
+	used bool // read since the hand last passed
+}
+
+func newVectorCache(db *sql.DB, capacity int) *vectorCache {
+	return &vectorCache{
+		db:       db,
+		entries:  make(map[string]*cachedVector),
+		capacity: capacity,
+	}
+}
+
+// get appends the vectors of ids to out[:0] in order, nil for IDs with no
+// stored vector. Misses are read from the database and cached.
+func (c *vectorCache) get(ids []string, out [][]float32) ([][]float32, error) {
+	out = out[:0]
+	var missing []string
+	c.mu.Lock()
+	for _, id := range ids {
+		cv := c.entries[id]
+		if cv != nil {
+			cv.used = true
+			out = append(out, cv.vec)
+			continue
+		}
+		out = append(out, nil)
+		if !c.complete && c.db != nil {
+			missing = append(missing, id)
+		}
+	}
+	c.mu.Unlock()
+	if len(missing) == 0 {
+		return out, nil
+	}
+
+	loaded := make(map[string][]float32, len(missing))
+	for start := 0; start < len(missing); start += vectorLoadBatch {
+		batch := missing[start:min(start+vectorLoadBatch, len(missing))]
+		args := make([]any, len(batch))
+		for i, id := range batch {
+			args[i] = id
+		}
+		query := "SELECT item_id, embedding, dimensions FROM vectors WHERE item_id IN (?" +
+			strings.Repeat(", ?", len(batch)-1) + ")"
+		err := scanVectors(c.db, query, args, func(id string, vec []float32) error {
+			loaded[id] = vec
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	c.mu.Lock()
+	for id, vec := range loaded {
+		c.putLocked(id, vec)
+	}
+	c.mu.Unlock()
+	for i, id := range ids {
+		if out[i] == nil {
+			out[i] = loaded[id]
+		}
+	}
+	return out, nil
+}
+
+// scan calls fn for every stored vector. The first scan of a store that
+// fits in the cache fills it, so later scans stay in memory.
+func (c *vectorCache) scan(fn func(id string, vec []float32)) error {
+	c.mu.Lock()
+	if c.complete || c.db == nil {
+		all := make([]*cachedVector, 0, len(c.entries))
+		for _, cv := range c.entries {
+			all = append(all, cv)
+		}
+		c.mu.Unlock()
+		for _, cv := range all {
+			fn(cv.id, cv.vec)
+		}
+		return nil
+	}
+	c.mu.Unlock()
+
+	seen := 0
+	err := scanVectors(c.db, "SELECT item_id, embedding, dimensions FROM vectors", nil, func(id string, vec []float32) error {
+		seen++
+		if c.capacity <= 0 || seen <= c.capacity {
+			c.put(id, vec)
+		}
+		fn(id, vec)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if c.capacity <= 0 || seen <= c.capacity {
+		c.mu.Lock()
+		c.complete = len(c.entries) == seen
+		c.mu.Unlock()
+	}
+	return nil
+}
+
+// put caches a vector that was just written.
+func (c *vectorCache) put(id string, vec []float32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.putLocked(id, vec)
+}
+
+func (c *vectorCache) putLocked(id string, vec []float32) {
+	if cv, ok := c.entries[id]; ok {
+		cv.vec, cv.used = vec, true
+		return
+	}
+	if c.capacity > 0 && len(c.entries) >= c.capacity {
+		c.evictLocked()
+	}
+	cv := &cachedVector{id: id, vec: vec, slot: len(c.ring)}
+	if len(c.entries) < len(c.ring) {
+		// Reuse a slot freed by drop or eviction
+		for c.ring[c.hand] != nil {
+			c.hand = (c.hand + 1) % len(c.ring)
+		}
+		cv.slot = c.hand
+		c.ring[c.hand] = cv
+	} else {
+		c.ring = append(c.ring, cv)
+	}
+	c.entries[id] = cv
+}
+
+// evictLocked frees one slot: the hand clears used flags until it finds
+// an entry that was not read since it last passed.
+func (c *vectorCache) evictLocked() {
+	for {
+		cv := c.ring[c.hand]
+		if cv != nil && !cv.used {
+			c.removeLocked(cv)
+			c.complete = false
+			return
+		}
+		if cv != nil {
+			cv.used = false
+		}
+		c.hand = (c.hand + 1) % len(c.ring)
+	}
+}
+
+func (c *vectorCache) removeLocked(cv *cachedVector) {
+	c.ring[cv.slot] = nil
+	delete(c.entries, cv.id)
+}
+
+// drop forgets a deleted vector.
+func (c *vectorCache) drop(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cv, ok := c.entries[id]; ok {
+		c.removeLocked(cv)
+	}
+}
+
+// reset empties the cache. complete says whether the store is now empty.
+func (c *vectorCache) reset(complete bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*cachedVector)
+	c.ring = nil
+	c.hand = 0
+	c.complete = complete
+}
+
+// scanVectors runs a query selecting (item_id, embedding, dimensions) and
+// decodes each row, stopping at the first error from fn.
+func scanVectors(db *sql.DB, query string, args []any, fn func(id string, vec []float32) error) error {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		var blob []byte
+		var dims int
+		if err := rows.Scan(&id, &blob, &dims); err != nil {
+			return err
+		}
+		vec, err := blobToFloat32(blob, dims)
+		if err != nil {
+			return fmt.Errorf("corrupted vector for %s: %w", id, err)
+		}
+		if err := fn(id, vec); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
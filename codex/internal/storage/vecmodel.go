@@ -0,0 +1,193 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// Vectors from different embedding models are not comparable, so the store
+// records which model is active and tags every vector with its model.
+// Switching models re-embeds every item into vector_staging, keyed by the
+// new model so an interrupted run can resume, then swaps the staged vectors
+// in with SwapStaged.
+
+// migrateModels creates the model bookkeeping tables.
+func migrateModels(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS vector_meta (
+			key   TEXT PRIMARY KEY,
+			value TEXT NOT NULL
+		);
+
+		CREATE TABLE IF NOT EXISTS vector_staging (
+			item_id    TEXT NOT NULL,
+			model      TEXT NOT NULL,
+			embedding  BLOB NOT NULL,
+			dimensions INTEGER NOT NULL,
+			PRIMARY KEY (item_id, model)
+		);
+	`)
+	return err
+}
+
+// ActiveModel returns the embedding model recorded for the stored vectors,
+// or "" if none has been recorded yet.
+func (vs *VecStore) ActiveModel() (string, error) {
+	var model string
+	err := vs.db.QueryRow("SELECT value FROM vector_meta WHERE key = 'active_model'").Scan(&model)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return model, err
+}
+
+// SetActiveModel records model as the active embedding model. Untagged
+// vectors, written before models were recorded, are attributed to it.
+func (vs *VecStore) SetActiveModel(model string) error {
+	tx, err := vs.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := setActiveModel(tx, model); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE vectors SET model = ? WHERE model = ''", model); err != nil {
+		return fmt.Errorf("tag untagged vectors: %w", err)
+	}
+	return tx.Commit()
+}
+
+func setActiveModel(tx *sql.Tx, model string) error {
+	_, err := tx.Exec(`
+		INSERT INTO vector_meta (key, value) VALUES ('active_model', ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value
+	`, model)
+	return err
+}
+
+// ModelCounts returns the number of stored vectors per embedding model.
+// Untagged vectors are counted under "".
+func (vs *VecStore) ModelCounts() (map[string]int, error) {
+	rows, err := vs.db.Query("SELECT model, COUNT(*) FROM vectors GROUP BY model")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var model string
+		var n int
+		if err := rows.Scan(&model, &n); err != nil {
+			return nil, err
+		}
+		counts[model] = n
+	}
+	return counts, rows.Err()
+}
+
+// StagedIDs returns the IDs of items already staged for model.
+func (vs *VecStore) StagedIDs(ctx context.Context, model string) (map[string]bool, error) {
+	rows, err := vs.db.QueryContext(ctx, "SELECT item_id FROM vector_staging WHERE model = ?", model)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make(map[string]bool)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids[id] = true
+	}
+	return ids, rows.Err()
+}
+
+// StageVectors stores vectors produced by model without affecting search.
+// They replace the live vectors when SwapStaged is called.
+func (vs *VecStore) StageVectors(ctx context.Context, model string, vectors map[string][]float32) error {
+	tx, err := vs.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO vector_staging (item_id, model, embedding, dimensions)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(item_id, model) DO UPDATE SET
+			embedding=excluded.embedding, dimensions=excluded.dimensions
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for id, vec := range vectors {
+		normalized := normalize(vec)
+		if _, err := stmt.ExecContext(ctx, id, model, float32ToBlob(normalized), len(normalized)); err != nil {
+			return fmt.Errorf("stage vector for %s: %w", id, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// SwapStaged atomically replaces the live vectors with those staged for
+// model and makes model the active model. Live vectors from other models
+// that have no staged replacement are dropped. It returns the number of
+// vectors swapped in.
+func (vs *VecStore) SwapStaged(ctx context.Context, model string) (int, error) {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
+	tx, err := vs.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		INSERT OR REPLACE INTO vectors (item_id, embedding, dimensions, model)
+		SELECT item_id, embedding, dimensions, model FROM vector_staging WHERE model = ?
+	`, model)
+	if err != nil {
+		return 0, fmt.Errorf("swap in staged vectors: %w", err)
+	}
+	swapped, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM vectors WHERE model != ?", model); err != nil {
+		return 0, fmt.Errorf("drop stale vectors: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM vector_staging WHERE model = ?", model); err != nil {
+		return 0, fmt.Errorf("clear staged vectors: %w", err)
+	}
+	if err := setActiveModel(tx, model); err != nil {
+		return 0, err
+	}
+	// The persisted graph indexes the old vectors
+	if _, err := tx.ExecContext(ctx, "DELETE FROM vector_graph_meta"); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	if err := vs.recount(); err != nil {
+		return 0, fmt.Errorf("recount vectors: %w", err)
+	}
+	vs.dimsWarned.Store(false)
+	if vs.hnswCfg != nil {
+		if err := vs.rebuildIndex(); err != nil {
+			return 0, fmt.Errorf("rebuild vector index: %w", err)
+		}
+	}
+	return int(swapped), nil
+}
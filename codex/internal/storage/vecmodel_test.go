@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func TestVecStore_ModelTracking(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Given a pre-model database When opened Then tags its vectors on SetActiveModel", func(t *testing.T) {
+		// Given: a vectors table without the model column
+		db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		_, err = db.Exec(`CREATE TABLE vectors (item_id TEXT PRIMARY KEY, embedding BLOB NOT NULL, dimensions INTEGER NOT NULL)`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Exec(`INSERT INTO vectors VALUES ('old', ?, 2)`, float32ToBlob([]float32{1, 0})); err != nil {
+			t.Fatal(err)
+		}
+
+		// When
+		vs, err := NewVecStore(db, WithModel("nomic-embed-text"))
+		if err != nil {
+			t.Fatalf("NewVecStore: %v", err)
+		}
+		active, err := vs.ActiveModel()
+		if err != nil || active != "" {
+			t.Fatalf("ActiveModel() = %q, %v; want none recorded", active, err)
+		}
+		if err := vs.SetActiveModel("nomic-embed-text"); err != nil {
+			t.Fatalf("SetActiveModel: %v", err)
+		}
+		if err := vs.Upsert(ctx, "new", []float32{0, 1}); err != nil {
+			t.Fatalf("Upsert: %v", err)
+		}
+
+		// Then
+		counts, err := vs.ModelCounts()
+		if err != nil {
+			t.Fatalf("ModelCounts: %v", err)
+		}
+		if len(counts) != 1 || counts["nomic-embed-text"] != 2 {
+			t.Errorf("ModelCounts() = %v, want both vectors tagged nomic-embed-text", counts)
+		}
+	})
+
+	t.Run("Given staged vectors When swapping Then replaces live vectors atomically", func(t *testing.T) {
+		// Given
+		db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		vs, err := NewVecStore(db, WithModel("old-model"), WithHNSW(DefaultHNSWConfig()))
+		if err != nil {
+			t.Fatalf("NewVecStore: %v", err)
+		}
+		if err := vs.SetActiveModel("old-model"); err != nil {
+			t.Fatal(err)
+		}
+		for _, id := range []string{"a", "b", "deleted"} {
+			if err := vs.Upsert(ctx, id, []float32{1, 0}); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// When: stage a and b in two batches, as a resumed run would
+		if err := vs.StageVectors(ctx, "new-model", map[string][]float32{"a": {0, 0, 1}}); err != nil {
+			t.Fatalf("StageVectors: %v", err)
+		}
+		staged, err := vs.StagedIDs(ctx, "new-model")
+		if err != nil || len(staged) != 1 || !staged["a"] {
+			t.Fatalf("StagedIDs() = %v, %v; want [a]", staged, err)
+		}
+		if results, _ := vs.Search(ctx, []float32{0, 0, 1}, 10); len(results) != 0 {
+			t.Errorf("staged vectors should not be searchable before the swap, got %v", results)
+		}
+		if err := vs.StageVectors(ctx, "new-model", map[string][]float32{"b": {0, 1, 0}}); err != nil {
+			t.Fatalf("StageVectors: %v", err)
+		}
+		swapped, err := vs.SwapStaged(ctx, "new-model")
+
+		// Then
+		if err != nil || swapped != 2 {
+			t.Fatalf("SwapStaged() = %d, %v; want 2", swapped, err)
+		}
+		if active, _ := vs.ActiveModel(); active != "new-model" {
+			t.Errorf("active model = %q, want new-model", active)
+		}
+		if vs.Count() != 2 {
+			t.Errorf("expected the unstaged vector to be dropped, have %d", vs.Count())
+		}
+		results, _ := vs.Search(ctx, []float32{0, 0, 1}, 1)
+		if len(results) != 1 || results[0].ID != "a" {
+			t.Errorf("expected a as top result, got %v", results)
+		}
+		if staged, _ := vs.StagedIDs(ctx, "new-model"); len(staged) != 0 {
+			t.Errorf("expected staging to be cleared, got %v", staged)
+		}
+		if counts, _ := vs.ModelCounts(); counts["new-model"] != 2 || len(counts) != 1 {
+			t.Errorf("ModelCounts() = %v", counts)
+		}
+	})
+}
@@ -6,18 +6,67 @@ import (
 	"database/sql"
 	"encoding/binary"
 	"fmt"
+	"log"
 	"math"
 	"sync"
+	"sync/atomic"
 )
 
-// VecStore provides brute-force vector search backed by SQLite BLOBs.
-// Vectors are loaded into memory for fast cosine similarity computation.
-// At <10K documents this is sub-millisecond and returns exact (not approximate) results.
+// VecStore provides vector search backed by SQLite BLOBs.
+// Vectors are read on demand and the most recently used are cached in
+// memory (see WithVectorCache), so startup does not load the whole table.
+// Search is exact (brute force) by default. With WithHNSW, an HNSW graph
+// persisted in the same database serves approximate queries once the store
+// outgrows HNSWConfig.ExactBelow; callers can still request exact search.
+// Each vector is tagged with the embedding model that produced it; see
+// vecmodel.go for the active-model record and re-embedding support.
 type VecStore struct {
-	db *sql.DB
+	db    *sql.DB
+	model string // tagged on upserted vectors
 
-	mu      sync.RWMutex
-	vectors map[string][]float32 // item_id -> normalized embedding
+	mu        sync.RWMutex
+	vecs      *vectorCache // normalized embeddings by item_id
+	count     int          // stored vectors
+	cacheSize int
+
+	hnswCfg *HNSWConfig // nil = exact search only
+	index   *hnswGraph
+
+	dimsWarned atomic.Bool // a dimension-mismatch warning was logged
+}
+
+// VecStoreOption configures a VecStore.
+type VecStoreOption func(*VecStore)
+
+// WithHNSW enables the approximate nearest neighbour index.
+func WithHNSW(cfg HNSWConfig) VecStoreOption {
+	return func(vs *VecStore) { vs.hnswCfg = &cfg }
+}
+
+// WithVectorCache sets how many vectors are kept in memory (default
+// DefaultVectorCache). Zero or less keeps every vector read.
+func WithVectorCache(n int) VecStoreOption {
+	return func(vs *VecStore) { vs.cacheSize = n }
+}
+
+// WithModel sets the embedding model ID tagged on upserted vectors.
+func WithModel(model string) VecStoreOption {
+	return func(vs *VecStore) { vs.model = model }
+}
+
+// VectorSearchOptions controls a single vector query.
+type VectorSearchOptions struct {
+	// Allowed restricts results to these item IDs. A nil map means no
+	// restriction; an empty non-nil one matches nothing.
+	Allowed map[string]bool
+
+	// Excluded drops these item IDs from results when Allowed is nil.
+	// It suits lists that are small next to the store, which an
+	// allow-list of everything else would not be.
+	Excluded map[string]bool
+
+	// Exact forces brute-force search even when an ANN index is available.
+	Exact bool
 }
 
 // ScoredResult pairs an item ID with a similarity score.
@@ -27,19 +76,31 @@ type ScoredResult struct {
 }
 
 // NewVecStore creates a vector store using the given SQLite database.
-// It creates the vectors table if needed and loads existing vectors into memory.
-func NewVecStore(db *sql.DB) (*VecStore, error) {
+// It creates the vectors table if needed; vectors are read as searches
+// need them. With WithHNSW it also loads the persisted graph, rebuilding it
+// if it is missing or out of date.
+func NewVecStore(db *sql.DB, opts ...VecStoreOption) (*VecStore, error) {
 	vs := &VecStore{
-		db:      db,
-		vectors: make(map[string][]float32),
+		db:        db,
+		cacheSize: DefaultVectorCache,
+	}
+	for _, opt := range opts {
+		opt(vs)
 	}
+	vs.vecs = newVectorCache(db, vs.cacheSize)
 
 	if err := vs.migrate(); err != nil {
 		return nil, fmt.Errorf("vecstore migrate: %w", err)
 	}
 
-	if err := vs.loadAll(); err != nil {
-		return nil, fmt.Errorf("vecstore load: %w", err)
+	if err := vs.recount(); err != nil {
+		return nil, fmt.Errorf("vecstore count: %w", err)
+	}
+
+	if vs.hnswCfg != nil {
+		if err := vs.loadIndex(); err != nil {
+			return nil, fmt.Errorf("vecstore index: %w", err)
+		}
 	}
 
 	return vs, nil
@@ -50,35 +111,133 @@ func (vs *VecStore) migrate() error {
 		CREATE TABLE IF NOT EXISTS vectors (
 			item_id    TEXT PRIMARY KEY,
 			embedding  BLOB NOT NULL,
-			dimensions INTEGER NOT NULL
+			dimensions INTEGER NOT NULL,
+			model      TEXT NOT NULL DEFAULT ''
 		)
 	`)
-	return err
+	if err != nil {
+		return err
+	}
+	hasModel, err := hasColumn(vs.db, "vectors", "model")
+	if err != nil {
+		return err
+	}
+	if !hasModel {
+		// Vectors written before models were tracked stay untagged until
+		// SetActiveModel attributes them
+		if _, err := vs.db.Exec(`ALTER TABLE vectors ADD COLUMN model TEXT NOT NULL DEFAULT ''`); err != nil {
+			return fmt.Errorf("add vectors.model column: %w", err)
+		}
+	}
+	if err := migrateModels(vs.db); err != nil {
+		return err
+	}
+	// The graph tables exist even when the index is disabled, so writes
+	// can invalidate a persisted graph that would otherwise go stale.
+	return migrateHNSW(vs.db)
 }
 
-func (vs *VecStore) loadAll() error {
-	rows, err := vs.db.Query("SELECT item_id, embedding, dimensions FROM vectors")
+// recount reads the number of stored vectors. An empty store's cache is
+// complete from the start.
+func (vs *VecStore) recount() error {
+	if err := vs.db.QueryRow("SELECT COUNT(*) FROM vectors").Scan(&vs.count); err != nil {
+		return err
+	}
+	vs.vecs.reset(vs.count == 0)
+	return nil
+}
+
+// loadIndex loads the persisted HNSW graph, or builds and persists a new
+// one when it is missing or does not match the stored vectors.
+func (vs *VecStore) loadIndex() error {
+	g, err := loadHNSWGraph(vs.db, *vs.hnswCfg, vs.vecs)
 	if err != nil {
 		return err
 	}
-	defer rows.Close()
+	if g != nil {
+		vs.index = g
+		return nil
+	}
+	return vs.rebuildIndex()
+}
 
-	for rows.Next() {
-		var id string
-		var blob []byte
-		var dims int
+// rebuildIndex builds the HNSW graph from the stored vectors and replaces
+// the persisted graph. The graph holds every vector until it is flushed,
+// so a rebuild needs memory for the whole table while it runs.
+func (vs *VecStore) rebuildIndex() error {
+	g := newHNSWGraph(*vs.hnswCfg, vs.vecs)
+	dims, err := commonDims(vs.db)
+	if err != nil {
+		return err
+	}
+	g.dims = dims
 
-		if err := rows.Scan(&id, &blob, &dims); err != nil {
+	if vs.count > 0 {
+		log.Printf("Building vector index for %d vectors...", vs.count)
+	}
+	err = scanVectors(vs.db, "SELECT item_id, embedding, dimensions FROM vectors WHERE dimensions = ? ORDER BY item_id", []any{dims},
+		func(id string, vec []float32) error {
+			_, err := g.insert(id, vec)
 			return err
+		})
+	if err != nil {
+		return err
+	}
+
+	tx, err := vs.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("DELETE FROM vector_graph"); err != nil {
+		return err
+	}
+	if err := g.flush(tx); err != nil {
+		return err
+	}
+	if err := g.saveMeta(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	vs.index = g
+	return nil
+}
+
+// writeTx runs a vectors-table write in a transaction together with the
+// graph changes it caused. If the write fails after the in-memory graph
+// was mutated, the graph is reloaded from the database.
+func (vs *VecStore) writeTx(ctx context.Context, write func(tx *sql.Tx) error) error {
+	tx, err := vs.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = write(tx)
+	if err == nil {
+		if vs.index != nil {
+			err = vs.index.flush(tx)
+		} else {
+			// Invalidate any graph persisted by an index-enabled process
+			_, err = tx.Exec("DELETE FROM vector_graph_meta")
 		}
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
 
-		vec, err := blobToFloat32(blob, dims)
-		if err != nil {
-			return fmt.Errorf("corrupted vector for %s: %w", id, err)
+	if err != nil && vs.index != nil {
+		vs.index.clearChanges()
+		if g, loadErr := loadHNSWGraph(vs.db, *vs.hnswCfg, vs.vecs); loadErr == nil && g != nil {
+			vs.index = g
+		} else if rebuildErr := vs.rebuildIndex(); rebuildErr != nil {
+			log.Printf("Warning: failed to rebuild vector index: %v", rebuildErr)
 		}
-		vs.vectors[id] = vec
 	}
-	return rows.Err()
+	return err
 }
 
 // Upsert stores a pre-normalized vector for the given item ID.
@@ -90,34 +249,123 @@ func (vs *VecStore) Upsert(ctx context.Context, itemID string, vector []float32)
 	vs.mu.Lock()
 	defer vs.mu.Unlock()
 
-	_, err := vs.db.ExecContext(ctx, `
-		INSERT INTO vectors (item_id, embedding, dimensions)
-		VALUES (?, ?, ?)
-		ON CONFLICT(item_id) DO UPDATE SET
-			embedding=excluded.embedding, dimensions=excluded.dimensions
-	`, itemID, blob, len(normalized))
+	var existed bool
+	err := vs.writeTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM vectors WHERE item_id = ?)", itemID).Scan(&existed)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO vectors (item_id, embedding, dimensions, model)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT(item_id) DO UPDATE SET
+				embedding=excluded.embedding, dimensions=excluded.dimensions, model=excluded.model
+		`, itemID, blob, len(normalized), vs.model)
+		if err != nil {
+			return err
+		}
+		if vs.index != nil {
+			// A vector whose dimension differs from the index is left to exact search
+			if _, err := vs.index.insert(itemID, normalized); err != nil {
+				return fmt.Errorf("index vector: %w", err)
+			}
+		}
+		return nil
+	})
 	if err != nil {
 		return err
 	}
 
-	vs.vectors[itemID] = normalized
+	vs.vecs.put(itemID, normalized)
+	if !existed {
+		vs.count++
+	}
 	return nil
 }
 
 // Search returns the top-K items by cosine similarity to the query vector.
-// Uses a min-heap to efficiently track only the top-K results.
 func (vs *VecStore) Search(ctx context.Context, queryVec []float32, limit int) ([]ScoredResult, error) {
+	return vs.SearchWithOptions(ctx, queryVec, limit, VectorSearchOptions{})
+}
+
+// SearchWithOptions is Search with an optional allow-list and a choice of
+// exact or approximate search. Approximate search is used only when an
+// HNSW index is enabled, opts.Exact is false, both the store and the
+// vectors the options leave hold at least HNSWConfig.ExactBelow vectors,
+// and those are at least hnswMinShare of the store. A filtered
+// approximate search that finds fewer than limit results falls back to
+// exact search.
+func (vs *VecStore) SearchWithOptions(ctx context.Context, queryVec []float32, limit int, opts VectorSearchOptions) ([]ScoredResult, error) {
 	if limit <= 0 {
 		limit = 10
 	}
 	normalizedQuery := normalize(queryVec)
 
 	vs.mu.RLock()
+	defer vs.mu.RUnlock()
+
+	accept := opts.accept()
+	if vs.useIndex(opts) {
+		results, err := vs.index.search(normalizedQuery, limit, vs.hnswCfg.EfSearch, accept)
+		if err != nil {
+			return nil, fmt.Errorf("vector index search: %w", err)
+		}
+		if len(results) >= limit || (accept == nil && len(results) == vs.index.Len()) {
+			return results, nil
+		}
+	}
+	return vs.exactSearch(normalizedQuery, limit, opts.Allowed, accept)
+}
+
+// accept returns the predicate item IDs must pass, or nil if all pass.
+func (opts VectorSearchOptions) accept() func(id string) bool {
+	switch {
+	case opts.Allowed != nil:
+		return func(id string) bool { return opts.Allowed[id] }
+	case len(opts.Excluded) > 0:
+		return func(id string) bool { return !opts.Excluded[id] }
+	}
+	return nil
+}
+
+// hnswMinShare is the smallest share of the store a filtered query may
+// leave and still use the HNSW index. Below it the beam rarely fills with
+// accepted nodes, so the search walks most of the graph and reads nearly
+// every vector, which costs more than scoring the accepted ones.
+const hnswMinShare = 0.15
+
+// useIndex reports whether a query should use the HNSW index.
+func (vs *VecStore) useIndex(opts VectorSearchOptions) bool {
+	if vs.index == nil || opts.Exact {
+		return false
+	}
+	threshold := vs.hnswCfg.ExactBelow
+	if vs.count < threshold {
+		return false
+	}
+	searched := vs.count
+	switch {
+	case opts.Allowed != nil:
+		searched = len(opts.Allowed)
+	case len(opts.Excluded) > 0:
+		searched = vs.count - len(opts.Excluded)
+	}
+	return searched >= threshold && float64(searched) >= hnswMinShare*float64(vs.count)
+}
+
+// exactSearch scores every vector passing accept (all when nil), using a
+// min-heap to track only the top-K results. A narrow allow-list is read
+// directly instead of scanning. Vectors whose dimension differs from the
+// query cannot be scored; the first such search logs a warning. Callers
+// must hold vs.mu.
+func (vs *VecStore) exactSearch(normalizedQuery []float32, limit int, allowed map[string]bool, accept func(id string) bool) ([]ScoredResult, error) {
 	h := &minHeap{}
 	heap.Init(h)
-	for id, vec := range vs.vectors {
+	skipped := 0
+	consider := func(id string, vec []float32) {
 		if len(vec) != len(normalizedQuery) {
-			continue
+			skipped++
+			return
 		}
 		score := dotProduct(normalizedQuery, vec)
 		if h.Len() < limit {
@@ -127,7 +375,34 @@ func (vs *VecStore) Search(ctx context.Context, queryVec []float32, limit int) (
 			heap.Fix(h, 0)
 		}
 	}
-	vs.mu.RUnlock()
+	if allowed != nil && len(allowed) < vs.count {
+		// Narrow filter: only read and score the allowed vectors
+		ids := make([]string, 0, len(allowed))
+		for id := range allowed {
+			ids = append(ids, id)
+		}
+		vecs, err := vs.vecs.get(ids, nil)
+		if err != nil {
+			return nil, fmt.Errorf("read vectors: %w", err)
+		}
+		for i, vec := range vecs {
+			if vec != nil {
+				consider(ids[i], vec)
+			}
+		}
+	} else {
+		err := vs.vecs.scan(func(id string, vec []float32) {
+			if accept == nil || accept(id) {
+				consider(id, vec)
+			}
+		})
+		if err != nil {
+			return nil, fmt.Errorf("scan vectors: %w", err)
+		}
+	}
+	if skipped > 0 && vs.dimsWarned.CompareAndSwap(false, true) {
+		log.Printf("Warning: skipped %d stored vectors whose dimension differs from the %d-dimensional query; the index mixes embedding models — run `codex-cli reembed`", skipped, len(normalizedQuery))
+	}
 
 	// Extract results in descending score order
 	results := make([]ScoredResult, h.Len())
@@ -157,12 +432,28 @@ func (vs *VecStore) Delete(ctx context.Context, itemID string) error {
 	vs.mu.Lock()
 	defer vs.mu.Unlock()
 
-	_, err := vs.db.ExecContext(ctx, "DELETE FROM vectors WHERE item_id = ?", itemID)
+	var deleted int64
+	err := vs.writeTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, "DELETE FROM vectors WHERE item_id = ?", itemID)
+		if err != nil {
+			return err
+		}
+		if deleted, err = res.RowsAffected(); err != nil {
+			return err
+		}
+		if vs.index != nil {
+			if err := vs.index.remove(itemID); err != nil {
+				return fmt.Errorf("unindex vector: %w", err)
+			}
+		}
+		return nil
+	})
 	if err != nil {
 		return err
 	}
 
-	delete(vs.vectors, itemID)
+	vs.vecs.drop(itemID)
+	vs.count -= int(deleted)
 	return nil
 }
 
@@ -170,7 +461,21 @@ func (vs *VecStore) Delete(ctx context.Context, itemID string) error {
 func (vs *VecStore) Count() int {
 	vs.mu.RLock()
 	defer vs.mu.RUnlock()
-	return len(vs.vectors)
+	return vs.count
+}
+
+// IndexStatus describes the vector search mode, e.g. for status output.
+func (vs *VecStore) IndexStatus() string {
+	vs.mu.RLock()
+	defer vs.mu.RUnlock()
+	if vs.index == nil {
+		return "exact (brute force)"
+	}
+	mode := "exact below threshold"
+	if vs.count >= vs.hnswCfg.ExactBelow {
+		mode = "approximate"
+	}
+	return fmt.Sprintf("HNSW, %d nodes, M=%d, ef=%d (%s)", vs.index.Len(), vs.hnswCfg.M, vs.hnswCfg.EfSearch, mode)
 }
 
 // --- math helpers ---
@@ -0,0 +1,182 @@
+// Package walk lists the files of a directory tree to index. It honours
+// .gitignore, .git/info/exclude and .codexignore files, include and exclude
+// globs, a maximum file size and a symlink policy, and recognises binary,
+// minified and generated files by their content.
+package walk
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// IgnoreFile is the project-specific ignore file, read like .gitignore.
+// Its rules take precedence over .gitignore in the same directory.
+const IgnoreFile = ".codexignore"
+
+// rule is one compiled gitignore pattern.
+type rule struct {
+	re      *regexp.Regexp
+	negate  bool // "!pattern" re-includes
+	dirOnly bool // "pattern/" matches directories only
+}
+
+// ruleSet holds the rules read from the ignore files of one directory.
+// Patterns are matched against paths relative to dir.
+type ruleSet struct {
+	dir   string
+	rules []rule
+}
+
+// match reports whether the set decides rel (slash-separated, relative to
+// its dir): matched is false when no rule applies, otherwise ignored is the
+// verdict of the last matching rule.
+func (s *ruleSet) match(rel string, isDir bool) (ignored, matched bool) {
+	for _, r := range s.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		if r.re.MatchString(rel) {
+			ignored, matched = !r.negate, true
+		}
+	}
+	return ignored, matched
+}
+
+// parsePatterns compiles gitignore-style lines, skipping blanks, comments
+// and patterns that fail to compile.
+func parsePatterns(lines []string) []rule {
+	var rules []rule
+	for _, line := range lines {
+		if r, ok := compilePattern(line); ok {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+// readRules reads the ignore files in order into one rule set for dir.
+// Missing files are skipped; the set is nil when none had rules.
+func readRules(dir string, files ...string) *ruleSet {
+	set := &ruleSet{dir: dir}
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			continue
+		}
+		var lines []string
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		f.Close()
+		set.rules = append(set.rules, parsePatterns(lines)...)
+	}
+	if len(set.rules) == 0 {
+		return nil
+	}
+	return set
+}
+
+// compilePattern converts one line of a gitignore file to a rule.
+// See gitignore(5) for the syntax.
+func compilePattern(line string) (rule, bool) {
+	line = trimTrailingSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule{}, false
+	}
+
+	var r rule
+	if strings.HasPrefix(line, "!") {
+		r.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return rule{}, false
+	}
+
+	// A slash anywhere but the end anchors the pattern to the file's directory
+	var expr strings.Builder
+	expr.WriteString("^")
+	if strings.Contains(line, "/") {
+		line = strings.TrimPrefix(line, "/")
+	} else {
+		expr.WriteString("(?:.*/)?")
+	}
+	translateGlob(&expr, line)
+	expr.WriteString("$")
+
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return rule{}, false
+	}
+	r.re = re
+	return r, true
+}
+
+// translateGlob writes the regular expression for a gitignore glob.
+func translateGlob(expr *strings.Builder, glob string) {
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/") && (i == 0 || glob[i-1] == '/'):
+			expr.WriteString("(?:.*/)?")
+			i += 2
+		case glob[i:] == "**" && (i == 0 || glob[i-1] == '/'):
+			expr.WriteString(".*")
+			i++
+		case c == '*':
+			expr.WriteString("[^/]*")
+		case c == '?':
+			expr.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				expr.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			expr.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+}
+
+// trimTrailingSpace drops trailing spaces unless escaped with a backslash.
+func trimTrailingSpace(line string) string {
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	return strings.ReplaceAll(line, `\ `, " ")
+}
+
+// gitRoot returns the nearest directory at or above dir containing .git,
+// or "" outside a repository.
+func gitRoot(dir string) string {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
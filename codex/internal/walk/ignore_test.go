@@ -0,0 +1,66 @@
+package walk
+
+import "testing"
+
+func TestCompilePattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		isDir   bool
+		want    bool
+	}{
+		{"*.log", "debug.log", false, true},
+		{"*.log", "logs/debug.log", false, true},
+		{"*.log", "debug.txt", false, false},
+		{"vendor/", "vendor", true, true},
+		{"vendor/", "vendor", false, false},
+		{"vendor/", "pkg/vendor", true, true},
+		{"/build", "build", true, true},
+		{"/build", "src/build", true, false},
+		{"docs/*.md", "docs/a.md", false, true},
+		{"docs/*.md", "docs/sub/a.md", false, false},
+		{"docs/*.md", "src/docs/a.md", false, false},
+		{"**/gen", "a/b/gen", true, true},
+		{"**/gen", "gen", true, true},
+		{"out/**", "out/a/b.go", false, true},
+		{"a/**/b", "a/b", false, true},
+		{"a/**/b", "a/x/y/b", false, true},
+		{"file?.go", "file1.go", false, true},
+		{"file?.go", "file10.go", false, false},
+		{"[!a]*.go", "b.go", false, true},
+		{"[!a]*.go", "a.go", false, false},
+		{`\#notes`, "#notes", false, true},
+		{"trailing   ", "trailing", false, true},
+		{`a.min.js`, "a_min_js", false, false},
+	}
+	for _, tt := range tests {
+		t.Run("Given "+tt.pattern+", When matching "+tt.path+", Then the verdict is as in git", func(t *testing.T) {
+			r, ok := compilePattern(tt.pattern)
+			if !ok {
+				t.Fatalf("compilePattern(%q) failed", tt.pattern)
+			}
+			set := &ruleSet{rules: []rule{r}}
+			if got, _ := set.match(tt.path, tt.isDir); got != tt.want {
+				t.Errorf("match(%q, dir=%v) = %v, want %v (re %s)", tt.path, tt.isDir, got, tt.want, r.re)
+			}
+		})
+	}
+
+	t.Run("Given comments and blank lines, When compiling, Then they have no rule", func(t *testing.T) {
+		for _, line := range []string{"", "   ", "# comment", "!", "/"} {
+			if _, ok := compilePattern(line); ok {
+				t.Errorf("compilePattern(%q) produced a rule", line)
+			}
+		}
+	})
+
+	t.Run("Given a negation after a match, When matching, Then the last rule wins", func(t *testing.T) {
+		set := &ruleSet{rules: parsePatterns([]string{"*.go", "!keep.go"})}
+		if ignored, _ := set.match("keep.go", false); ignored {
+			t.Error("keep.go ignored, want re-included")
+		}
+		if ignored, _ := set.match("drop.go", false); !ignored {
+			t.Error("drop.go not ignored")
+		}
+	})
+}
@@ -0,0 +1,473 @@
+package walk
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Symlink policies
+const (
+	SymlinksFiles  = "files"  // index symlinked files, do not descend into symlinked directories (default)
+	SymlinksSkip   = "skip"   // ignore all symlinks
+	SymlinksFollow = "follow" // follow symlinked files and directories, once per target directory
+)
+
+// DefaultMaxFileSize is the largest file indexed when Options.MaxFileSize is unset.
+const DefaultMaxFileSize = 1 << 20
+
+// Options configures which files a walk yields.
+type Options struct {
+	// Include limits files to those matching one of these globs, relative
+	// to the walk root (gitignore syntax). Empty includes every file.
+	Include []string
+
+	// Exclude skips files and directories matching one of these globs,
+	// in addition to the ignore files.
+	Exclude []string
+
+	// MaxFileSize skips larger files, in bytes. 0 uses DefaultMaxFileSize
+	// and a negative value disables the limit.
+	MaxFileSize int64
+
+	// Symlinks is the symlink policy: SymlinksFiles (default), SymlinksSkip
+	// or SymlinksFollow.
+	Symlinks string
+
+	// NoIgnoreFiles disables .gitignore, .git/info/exclude and .codexignore.
+	NoIgnoreFiles bool
+
+	// Accept, when set, further restricts files by path, e.g. to the
+	// extensions the caller can index. It is checked before reading.
+	Accept func(path string) bool
+
+	// CheckContent, when set, replaces ContentSkip in ReadFile, e.g. to let
+	// through binary formats the caller can convert to text.
+	CheckContent func(path string, content []byte) string
+}
+
+// Validate checks the symlink policy.
+func (o Options) Validate() error {
+	switch o.Symlinks {
+	case "", SymlinksFiles, SymlinksSkip, SymlinksFollow:
+		return nil
+	}
+	return fmt.Errorf("unknown symlink policy %q (want %s, %s or %s)", o.Symlinks, SymlinksFiles, SymlinksSkip, SymlinksFollow)
+}
+
+// maxFileSize returns the effective size limit, or -1 for none.
+func (o Options) maxFileSize() int64 {
+	switch {
+	case o.MaxFileSize == 0:
+		return DefaultMaxFileSize
+	case o.MaxFileSize < 0:
+		return -1
+	}
+	return o.MaxFileSize
+}
+
+// Filter decides which paths under a root are indexed. Ignore files are
+// read lazily per directory, so a Filter also answers for paths reported by
+// a file watcher. It is safe for concurrent use.
+type Filter struct {
+	root    string
+	opts    Options
+	include *ruleSet
+	exclude *ruleSet
+	base    []*ruleSet // ignore rules from the repository root down to root's parent
+
+	mu   sync.Mutex
+	dirs map[string]*ruleSet // ignore rules read from each directory under root
+}
+
+// NewFilter creates a filter for the tree at root.
+func NewFilter(root string, opts Options) (*Filter, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("invalid root %s: %w", root, err)
+	}
+
+	f := &Filter{
+		root: abs,
+		opts: opts,
+		dirs: make(map[string]*ruleSet),
+	}
+	if len(opts.Include) > 0 {
+		f.include = &ruleSet{dir: abs, rules: parsePatterns(opts.Include)}
+	}
+	if len(opts.Exclude) > 0 {
+		f.exclude = &ruleSet{dir: abs, rules: parsePatterns(opts.Exclude)}
+	}
+
+	// Indexing a subdirectory still honours the ignore files above it
+	if repo := gitRoot(abs); repo != "" && !opts.NoIgnoreFiles {
+		if set := readRules(repo, filepath.Join(repo, ".git", "info", "exclude")); set != nil {
+			f.base = append(f.base, set)
+		}
+		for dir := repo; dir != abs; {
+			if set := readDirRules(dir); set != nil {
+				f.base = append(f.base, set)
+			}
+			rel, _ := filepath.Rel(dir, abs)
+			dir = filepath.Join(dir, strings.SplitN(rel, string(filepath.Separator), 2)[0])
+		}
+	}
+	return f, nil
+}
+
+// Root returns the absolute root directory of the filter.
+func (f *Filter) Root() string {
+	return f.root
+}
+
+// Options returns the options the filter was created with.
+func (f *Filter) Options() Options {
+	return f.opts
+}
+
+// readDirRules reads the .gitignore and .codexignore files in dir.
+func readDirRules(dir string) *ruleSet {
+	return readRules(dir, filepath.Join(dir, ".gitignore"), filepath.Join(dir, IgnoreFile))
+}
+
+// dirRules returns the cached ignore rules of a directory under root.
+func (f *Filter) dirRules(dir string) *ruleSet {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	set, ok := f.dirs[dir]
+	if !ok {
+		set = readDirRules(dir)
+		f.dirs[dir] = set
+	}
+	return set
+}
+
+// Forget drops the cached ignore rules of dir so they are re-read, e.g.
+// after its .gitignore changed.
+func (f *Filter) Forget(dir string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.dirs, dir)
+}
+
+// Skip reports whether path (absolute, under root) is left out by name: it
+// or a directory above it is hidden, ignored or excluded, or, for files, it
+// fails the include globs or Accept. It does not look at the file itself.
+func (f *Filter) Skip(path string, isDir bool) bool {
+	rel, err := filepath.Rel(f.root, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return rel != "."
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	dir := f.root
+	for _, part := range parts[:len(parts)-1] {
+		dir = filepath.Join(dir, part)
+		if f.skip(dir, true) {
+			return true
+		}
+	}
+	return f.skip(path, isDir)
+}
+
+// skip decides path by its own name, assuming its parents were not skipped.
+func (f *Filter) skip(path string, isDir bool) bool {
+	rel, _ := filepath.Rel(f.root, path)
+	rel = filepath.ToSlash(rel)
+	parts := strings.Split(rel, "/")
+	if strings.HasPrefix(parts[len(parts)-1], ".") {
+		return true
+	}
+
+	if !f.opts.NoIgnoreFiles && f.ignored(path, isDir, parts) {
+		return true
+	}
+	if f.exclude != nil {
+		if ignored, _ := f.exclude.match(rel, isDir); ignored {
+			return true
+		}
+	}
+	if f.include != nil && !isDir {
+		if included, _ := f.include.match(rel, false); !included {
+			return true
+		}
+	}
+	return !isDir && f.opts.Accept != nil && !f.opts.Accept(path)
+}
+
+// ignored applies the ignore files from the repository root down to the
+// directory containing path. Deeper files take precedence and, within a
+// directory, the last matching rule wins.
+func (f *Filter) ignored(path string, isDir bool, parts []string) bool {
+	sets := append([]*ruleSet(nil), f.base...)
+	dir := f.root
+	for i := 0; i < len(parts); i++ {
+		if set := f.dirRules(dir); set != nil {
+			sets = append(sets, set)
+		}
+		dir = filepath.Join(dir, parts[i])
+	}
+
+	ignored := false
+	for _, set := range sets {
+		rel, err := filepath.Rel(set.dir, path)
+		if err != nil {
+			continue
+		}
+		if v, ok := set.match(filepath.ToSlash(rel), isDir); ok {
+			ignored = v
+		}
+	}
+	return ignored
+}
+
+// Reasons a file is skipped for its content
+const (
+	SkipBinary    = "binary"
+	SkipMinified  = "minified"
+	SkipGenerated = "generated"
+	SkipTooLarge  = "too large"
+
+	// SkipUnsupported is for files with an extension the caller reads
+	// whose content it cannot, e.g. a YAML file that is not an OpenAPI spec.
+	SkipUnsupported = "unsupported"
+)
+
+// sniffLen is how much of a file is inspected for binary content.
+const sniffLen = 8000
+
+// generatedRe matches the standard marker of generated Go files
+// (https://go.dev/s/generatedcode), also used by other generators.
+var generatedRe = regexp.MustCompile(`(?m)^(//|#) Code generated .* DO NOT EDIT\.$`)
+
+// ContentSkip returns why a file should not be indexed for its content, or
+// "" to index it. Binary files contain a NUL byte early on, like git's check.
+// Minified files are named *.min.* or have very long average lines.
+func ContentSkip(path string, content []byte) string {
+	head := content
+	if len(head) > sniffLen {
+		head = head[:sniffLen]
+	}
+	if bytes.IndexByte(head, 0) >= 0 {
+		return SkipBinary
+	}
+
+	name := strings.ToLower(filepath.Base(path))
+	if strings.Contains(name, ".min.") {
+		return SkipMinified
+	}
+	if len(content) >= 1024 && len(content)/(bytes.Count(content, []byte("\n"))+1) > 500 {
+		return SkipMinified
+	}
+
+	if generatedRe.Match(head) {
+		return SkipGenerated
+	}
+	return ""
+}
+
+// Skipped describes a file a walk left out after looking at it.
+type Skipped struct {
+	Path   string
+	Reason string // a Skip* reason
+}
+
+// Walk calls fn with the content of each file under the filter's root that
+// is not skipped, in lexical order. Files skipped for their size or content
+// go to skipped, when non-nil. An error from fn stops the walk and is
+// returned; unreadable directories and files are logged and skipped.
+func (f *Filter) Walk(fn func(path string, content []byte) error, skipped func(Skipped)) error {
+	if skipped == nil {
+		skipped = func(Skipped) {}
+	}
+	return f.Files(func(path string) error {
+		content, reason, err := f.ReadFile(path)
+		switch {
+		case err != nil:
+			log.Printf("Warning: failed to read %s: %v\n", path, err)
+			return nil
+		case reason != "":
+			skipped(Skipped{Path: path, Reason: reason})
+			return nil
+		}
+		return fn(path, content)
+	}, skipped)
+}
+
+// Files calls fn with the path of each file under the filter's root that
+// passes Skip and the size limit, in lexical order, without reading it.
+// Files over the limit go to skipped, when non-nil. Callers read the files
+// with ReadFile, possibly concurrently. An error from fn stops the walk and
+// is returned; unreadable directories are logged and skipped.
+func (f *Filter) Files(fn func(path string) error, skipped func(Skipped)) error {
+	info, err := os.Stat(f.root)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", f.root)
+	}
+	if skipped == nil {
+		skipped = func(Skipped) {}
+	}
+
+	visited := make(map[string]bool) // real paths of directories walked, for following symlinks
+	if real, err := filepath.EvalSymlinks(f.root); err == nil {
+		visited[real] = true
+	}
+	return f.walkDir(f.root, visited, fn, skipped)
+}
+
+// Paths is Files for a list of paths, such as those reported by a file
+// watcher: fn is called with each path that is a regular file (or, unless
+// symlinks are skipped, a link to one) passing Skip and the size limit.
+// Paths that do not exist or are directories are passed over.
+func (f *Filter) Paths(paths []string, fn func(path string) error, skipped func(Skipped)) error {
+	if skipped == nil {
+		skipped = func(Skipped) {}
+	}
+	for _, path := range paths {
+		if f.opts.Symlinks == SymlinksSkip {
+			if info, err := os.Lstat(path); err != nil || info.Mode()&fs.ModeSymlink != 0 {
+				continue
+			}
+		}
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() || f.Skip(path, false) {
+			continue
+		}
+		if f.TooLarge(info.Size()) {
+			skipped(Skipped{Path: path, Reason: SkipTooLarge})
+			continue
+		}
+		if err := fn(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TooLarge reports whether a file of size bytes is over the size limit.
+func (f *Filter) TooLarge(size int64) bool {
+	limit := f.opts.maxFileSize()
+	return limit >= 0 && size > limit
+}
+
+// ReadFile reads a file and checks its content. reason is non-empty, and
+// content nil, when the file should not be indexed (see ContentSkip).
+func (f *Filter) ReadFile(path string) (content []byte, reason string, err error) {
+	content, err = os.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+	if reason := f.CheckContent(path, content); reason != "" {
+		return nil, reason, nil
+	}
+	return content, "", nil
+}
+
+// CheckContent returns why a file should not be indexed for its content,
+// or "" to index it, through Options.CheckContent or ContentSkip.
+func (f *Filter) CheckContent(path string, content []byte) string {
+	if f.opts.CheckContent != nil {
+		return f.opts.CheckContent(path, content)
+	}
+	return ContentSkip(path, content)
+}
+
+func (f *Filter) walkDir(dir string, visited map[string]bool, fn func(string) error, skipped func(Skipped)) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		log.Printf("Warning: failed to read directory %s: %v\n", dir, err)
+		return nil
+	}
+
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		mode := entry.Type()
+
+		var info fs.FileInfo
+		if mode&fs.ModeSymlink != 0 {
+			if f.opts.Symlinks == SymlinksSkip {
+				continue
+			}
+			info, err = os.Stat(path)
+			if err != nil {
+				continue // dangling link
+			}
+			if info.IsDir() && f.opts.Symlinks != SymlinksFollow {
+				continue
+			}
+		} else if entry.IsDir() {
+			info, err = entry.Info()
+			if err != nil {
+				continue
+			}
+		} else if mode.IsRegular() {
+			info, err = entry.Info()
+			if err != nil {
+				continue
+			}
+		} else {
+			continue // devices, sockets, pipes
+		}
+
+		if f.skip(path, info.IsDir()) {
+			continue
+		}
+
+		if info.IsDir() {
+			real, err := filepath.EvalSymlinks(path)
+			if err != nil || visited[real] {
+				continue
+			}
+			visited[real] = true
+			if err := f.walkDir(path, visited, fn, skipped); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if f.TooLarge(info.Size()) {
+			skipped(Skipped{Path: path, Reason: SkipTooLarge})
+			continue
+		}
+		if err := fn(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseSize parses a byte size such as "1048576", "512KB", "2MB" or "1GiB".
+func ParseSize(size string) (int64, error) {
+	s := strings.TrimSpace(strings.ToUpper(size))
+	mult := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		mult   int64
+	}{
+		{"GIB", 1 << 30}, {"MIB", 1 << 20}, {"KIB", 1 << 10},
+		{"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10},
+		{"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}, {"B", 1},
+	} {
+		if strings.HasSuffix(s, unit.suffix) {
+			s, mult = strings.TrimSpace(strings.TrimSuffix(s, unit.suffix)), unit.mult
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", size)
+	}
+	return n * mult, nil
+}
@@ -0,0 +1,275 @@
+package walk
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeTree creates files (slash-separated paths) under a temp dir.
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// walkPaths returns the relative paths yielded and skipped by a walk.
+func walkPaths(t *testing.T, root string, opts Options) (paths []string, skipped map[string]string) {
+	t.Helper()
+	filter, err := NewFilter(root, opts)
+	if err != nil {
+		t.Fatalf("NewFilter: %v", err)
+	}
+	skipped = make(map[string]string)
+	err = filter.Walk(func(path string, content []byte) error {
+		rel, _ := filepath.Rel(root, path)
+		paths = append(paths, filepath.ToSlash(rel))
+		return nil
+	}, func(s Skipped) {
+		rel, _ := filepath.Rel(root, s.Path)
+		skipped[filepath.ToSlash(rel)] = s.Reason
+	})
+	if err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+	return paths, skipped
+}
+
+func TestFilter_IgnoreFiles(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		".git/HEAD":               "ref: refs/heads/main\n",
+		".git/info/exclude":       "scratch.go\n",
+		".gitignore":              "vendor/\n*.log\n/build\n",
+		".codexignore":            "testdata/\n",
+		"main.go":                 "package main\n",
+		"scratch.go":              "package main\n",
+		"debug.log":               "x\n",
+		"vendor/lib/lib.go":       "package lib\n",
+		"build/out.go":            "package out\n",
+		"pkg/build/keep.go":       "package build\n",
+		"pkg/testdata/fixture.go": "package testdata\n",
+		"pkg/.gitignore":          "*.gen.go\n!keep.gen.go\n",
+		"pkg/a.gen.go":            "package pkg\n",
+		"pkg/keep.gen.go":         "package pkg\n",
+		".github/ci.yml":          "on: push\n",
+	})
+
+	t.Run("Given ignore files, When walking, Then ignored and hidden paths are left out", func(t *testing.T) {
+		got, _ := walkPaths(t, root, Options{})
+		want := []string{"main.go", "pkg/build/keep.go", "pkg/keep.gen.go"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("paths = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("Given a subdirectory of the repository, When walking it, Then ignore files above it apply", func(t *testing.T) {
+		got, _ := walkPaths(t, filepath.Join(root, "pkg"), Options{})
+		want := []string{"build/keep.go", "keep.gen.go"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("paths = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("Given ignore files are disabled, When walking, Then only hidden paths are left out", func(t *testing.T) {
+		got, _ := walkPaths(t, root, Options{NoIgnoreFiles: true})
+		if len(got) != 9 {
+			t.Errorf("paths = %q, want all 9 visible files", got)
+		}
+	})
+
+	t.Run("Given a watched path, When asking the filter, Then it answers without a walk", func(t *testing.T) {
+		filter, _ := NewFilter(root, Options{})
+		for path, want := range map[string]bool{
+			"main.go":                false,
+			"vendor/new.go":          true,
+			"pkg/b.gen.go":           true,
+			"pkg/testdata/new.go":    true,
+			".git/index":             true,
+			"../outside/file.go":     true,
+			"pkg/newdir/code.go":     false,
+			"pkg/newdir/code.gen.go": true,
+		} {
+			if got := filter.Skip(filepath.Join(root, filepath.FromSlash(path)), false); got != want {
+				t.Errorf("Skip(%s) = %v, want %v", path, got, want)
+			}
+		}
+	})
+}
+
+func TestFilter_Globs(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"api/api.go":        "package api\n",
+		"api/api_test.go":   "package api\n",
+		"api/api.pb.go":     "package api\n",
+		"docs/guide.md":     "# Guide\n",
+		"node_modules/x.js": "x()\n",
+	})
+
+	tests := []struct {
+		name string
+		opts Options
+		want []string
+	}{
+		{"Given include globs, When walking, Then only matching files are yielded", Options{Include: []string{"*.go"}}, []string{"api/api.go", "api/api.pb.go", "api/api_test.go"}},
+		{"Given exclude globs, When walking, Then matching files and directories are left out", Options{Exclude: []string{"*.pb.go", "node_modules/", "*_test.go"}}, []string{"api/api.go", "docs/guide.md"}},
+		{"Given both, When walking, Then excludes win", Options{Include: []string{"api/**"}, Exclude: []string{"*_test.go"}}, []string{"api/api.go", "api/api.pb.go"}},
+		{"Given Accept, When walking, Then rejected paths are left out", Options{Accept: func(p string) bool { return strings.HasSuffix(p, ".md") }}, []string{"docs/guide.md"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := walkPaths(t, root, tt.opts)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("paths = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilter_ContentChecks(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"ok.go":        "package ok\n",
+		"big.go":       "package big\n" + strings.Repeat("// padding\n", 200),
+		"image.go":     "GIF89a\x00\x01",
+		"app.min.js":   "a();\n",
+		"bundle.js":    strings.Repeat("var a=1;", 400),
+		"api.pb.go":    "// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage api\n",
+		"generated.go": "package x\n\n// Code generated elsewhere, not a marker\n",
+	})
+
+	got, skipped := walkPaths(t, root, Options{MaxFileSize: 1024})
+
+	t.Run("Given files over the limit or with skippable content, When walking, Then they are reported with a reason", func(t *testing.T) {
+		want := map[string]string{
+			"big.go":     SkipTooLarge,
+			"image.go":   SkipBinary,
+			"app.min.js": SkipMinified,
+			"bundle.js":  SkipTooLarge,
+			"api.pb.go":  SkipGenerated,
+		}
+		if !reflect.DeepEqual(skipped, want) {
+			t.Errorf("skipped = %v, want %v", skipped, want)
+		}
+		if want := []string{"generated.go", "ok.go"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("paths = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("Given a long single-line file under the limit, When checking content, Then it is minified", func(t *testing.T) {
+		if reason := ContentSkip("bundle.js", []byte(strings.Repeat("var a=1;", 400))); reason != SkipMinified {
+			t.Errorf("ContentSkip = %q, want %q", reason, SkipMinified)
+		}
+	})
+
+	t.Run("Given no limit, When walking, Then large files are yielded", func(t *testing.T) {
+		_, skipped := walkPaths(t, root, Options{MaxFileSize: -1})
+		if _, ok := skipped["big.go"]; ok {
+			t.Errorf("big.go skipped: %v", skipped)
+		}
+	})
+
+	t.Run("Given a list of paths, When filtering them, Then missing, hidden and too large files are left out", func(t *testing.T) {
+		filter, _ := NewFilter(root, Options{MaxFileSize: 1024})
+		var got []string
+		var tooLarge []string
+		paths := []string{"ok.go", "big.go", "missing.go", ".hidden.go", "."}
+		for i, p := range paths {
+			paths[i] = filepath.Join(root, p)
+		}
+		filter.Paths(paths, func(path string) error {
+			got = append(got, filepath.Base(path))
+			return nil
+		}, func(s Skipped) {
+			tooLarge = append(tooLarge, filepath.Base(s.Path))
+		})
+		if !reflect.DeepEqual(got, []string{"ok.go"}) || !reflect.DeepEqual(tooLarge, []string{"big.go"}) {
+			t.Errorf("paths = %q, too large = %q", got, tooLarge)
+		}
+	})
+}
+
+func TestFilter_Symlinks(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"src/a.go":         "package src\n",
+		"shared/shared.go": "package shared\n",
+	})
+	if err := os.Symlink(filepath.Join(root, "src", "a.go"), filepath.Join(root, "link.go")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	os.Symlink(filepath.Join(root, "shared"), filepath.Join(root, "src", "shared"))
+	os.Symlink(root, filepath.Join(root, "shared", "loop"))
+
+	tests := []struct {
+		policy string
+		want   []string
+	}{
+		{SymlinksSkip, []string{"shared/shared.go", "src/a.go"}},
+		{"", []string{"link.go", "shared/shared.go", "src/a.go"}},
+		{SymlinksFollow, []string{"link.go", "shared/shared.go", "src/a.go"}},
+	}
+	for _, tt := range tests {
+		t.Run("Given policy "+tt.policy+", When walking, Then symlinks are handled accordingly", func(t *testing.T) {
+			got, _ := walkPaths(t, root, Options{Symlinks: tt.policy})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("paths = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("Given follow and a symlinked directory seen only through the link, When walking, Then it is descended once", func(t *testing.T) {
+		outside := writeTree(t, map[string]string{"ext.go": "package ext\n"})
+		os.Symlink(outside, filepath.Join(root, "src", "ext"))
+		os.Symlink(outside, filepath.Join(root, "src", "ext2"))
+		got, _ := walkPaths(t, root, Options{Symlinks: SymlinksFollow})
+		n := 0
+		for _, p := range got {
+			if strings.HasSuffix(p, "ext.go") {
+				n++
+			}
+		}
+		if n != 1 {
+			t.Errorf("paths = %q, want ext.go once", got)
+		}
+	})
+
+	t.Run("Given an unknown policy, When creating a filter, Then it fails", func(t *testing.T) {
+		if _, err := NewFilter(root, Options{Symlinks: "sometimes"}); err == nil {
+			t.Error("want error")
+		}
+	})
+}
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]int64{
+		"1048576": 1 << 20,
+		"512KB":   512 << 10,
+		"2mb":     2 << 20,
+		"1GiB":    1 << 30,
+		"10 k":    10 << 10,
+		"-1":      -1,
+	}
+	for in, want := range tests {
+		t.Run("Given "+in+", When parsing, Then bytes are returned", func(t *testing.T) {
+			got, err := ParseSize(in)
+			if err != nil || got != want {
+				t.Errorf("ParseSize(%q) = %d, %v, want %d", in, got, err, want)
+			}
+		})
+	}
+
+	t.Run("Given garbage, When parsing, Then it fails", func(t *testing.T) {
+		if _, err := ParseSize("lots"); err == nil {
+			t.Error("want error")
+		}
+	})
+}
@@ -0,0 +1,306 @@
+// Package watch reports changes to the files of a directory tree, applying
+// the same rules as a walk. Events are debounced, so a burst of writes from
+// an editor save or a git checkout arrives as one Batch.
+package watch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/anthropics/aef/codex/internal/walk"
+)
+
+// Defaults for the debounce timing
+const (
+	DefaultDebounce = 500 * time.Millisecond
+	DefaultMaxDelay = 10 * time.Second
+)
+
+// Batch is the set of changes collected during one quiet period.
+type Batch struct {
+	// Changed holds files that were created or written and may need
+	// re-indexing. They still have to pass walk.Filter.Paths.
+	Changed []string
+
+	// Removed holds paths that no longer exist, through deletion or a
+	// rename. A removed directory stands for every file below it.
+	Removed []string
+
+	// Rescan is set when an ignore file changed or events were lost, so the
+	// whole tree should be walked again.
+	Rescan bool
+}
+
+// Empty reports whether the batch has nothing to do.
+func (b Batch) Empty() bool {
+	return len(b.Changed) == 0 && len(b.Removed) == 0 && !b.Rescan
+}
+
+// Watcher watches the directories of a tree that its filter does not skip.
+// fsnotify is not recursive, so directories created later are added as they
+// appear.
+type Watcher struct {
+	filter   *walk.Filter
+	fs       *fsnotify.Watcher
+	debounce time.Duration
+	maxDelay time.Duration
+
+	dirs    map[string]bool // watched directories
+	pending map[string]bool // paths touched since the last batch
+	rescan  bool
+	first   time.Time // when the oldest pending change arrived
+}
+
+// Option configures a Watcher.
+type Option func(*Watcher)
+
+// WithDebounce sets how long the tree must be quiet before a batch is
+// delivered (default DefaultDebounce).
+func WithDebounce(d time.Duration) Option {
+	return func(w *Watcher) {
+		if d > 0 {
+			w.debounce = d
+		}
+	}
+}
+
+// WithMaxDelay bounds how long changes wait while events keep arriving
+// (default DefaultMaxDelay).
+func WithMaxDelay(d time.Duration) Option {
+	return func(w *Watcher) {
+		if d > 0 {
+			w.maxDelay = d
+		}
+	}
+}
+
+// New starts watching the filter's root and the directories below it.
+func New(filter *walk.Filter, opts ...Option) (*Watcher, error) {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file watcher: %w", err)
+	}
+
+	w := &Watcher{
+		filter:   filter,
+		fs:       fsw,
+		debounce: DefaultDebounce,
+		maxDelay: DefaultMaxDelay,
+		dirs:     make(map[string]bool),
+		pending:  make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	if err := w.fs.Add(filter.Root()); err != nil {
+		fsw.Close()
+		return nil, fmt.Errorf("failed to watch %s: %w", filter.Root(), err)
+	}
+	w.dirs[filter.Root()] = true
+	w.addTree(filter.Root(), false)
+	return w, nil
+}
+
+// Close stops watching.
+func (w *Watcher) Close() error {
+	return w.fs.Close()
+}
+
+// addTree watches the directories below dir. With touch, the files found
+// are marked pending: they may have been written before the watch was in
+// place, as when a directory is moved or checked out into the tree.
+func (w *Watcher) addTree(dir string, touch bool) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if !info.IsDir() {
+			if touch {
+				w.touch(path)
+			}
+			continue
+		}
+		if entry.Type()&os.ModeSymlink != 0 && w.filter.Options().Symlinks != walk.SymlinksFollow {
+			continue
+		}
+		if w.dirs[path] || w.filter.Skip(path, true) {
+			continue
+		}
+		if err := w.fs.Add(path); err != nil {
+			log.Printf("Warning: failed to watch %s: %v\n", path, err)
+			continue
+		}
+		w.dirs[path] = true
+		w.addTree(path, touch)
+	}
+}
+
+// removeTree stops watching dir and the directories below it.
+func (w *Watcher) removeTree(dir string) {
+	prefix := dir + string(filepath.Separator)
+	for path := range w.dirs {
+		if path == dir || strings.HasPrefix(path, prefix) {
+			w.fs.Remove(path) // fails when the directory is already gone
+			delete(w.dirs, path)
+		}
+	}
+}
+
+// touch marks path as changed.
+func (w *Watcher) touch(path string) {
+	w.started()
+	w.pending[path] = true
+}
+
+// requestRescan asks for a full walk in the next batch.
+func (w *Watcher) requestRescan() {
+	w.started()
+	w.rescan = true
+}
+
+// started notes when the first change of a batch arrived.
+func (w *Watcher) started() {
+	if len(w.pending) == 0 && !w.rescan {
+		w.first = time.Now()
+	}
+}
+
+// handle records one event.
+func (w *Watcher) handle(ev fsnotify.Event) {
+	if ev.Op == fsnotify.Chmod {
+		return
+	}
+	path := filepath.Clean(ev.Name)
+
+	switch filepath.Base(path) {
+	case ".gitignore", walk.IgnoreFile:
+		w.filter.Forget(filepath.Dir(path))
+		w.requestRescan()
+		return
+	}
+
+	if ev.Has(fsnotify.Remove) || ev.Has(fsnotify.Rename) {
+		// A renamed directory keeps its watch under the old name; drop it
+		w.removeTree(path)
+		w.filter.Forget(path)
+	}
+	if ev.Has(fsnotify.Create) {
+		if info, err := os.Stat(path); err == nil && info.IsDir() && !w.filter.Skip(path, true) {
+			if err := w.fs.Add(path); err == nil {
+				w.dirs[path] = true
+			}
+			w.addTree(path, true)
+			return
+		}
+	}
+	w.touch(path)
+}
+
+// take turns the pending paths into a batch and resets them.
+func (w *Watcher) take() Batch {
+	b := Batch{Rescan: w.rescan}
+	for path := range w.pending {
+		info, err := os.Stat(path)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			b.Removed = append(b.Removed, path)
+		case err == nil && !info.IsDir():
+			b.Changed = append(b.Changed, path)
+		}
+	}
+	sort.Strings(b.Changed)
+	sort.Strings(b.Removed)
+
+	w.pending = make(map[string]bool)
+	w.rescan = false
+	return b
+}
+
+// Run delivers batches of changes to fn until ctx is cancelled. Each batch
+// waits until no event has arrived for the debounce period, or for at most
+// the maximum delay. Batches are delivered one at a time; events arriving
+// while fn runs are collected into the next batch. On cancellation Run waits
+// for fn to return and then returns nil.
+func (w *Watcher) Run(ctx context.Context, fn func(ctx context.Context, b Batch)) error {
+	timer := time.NewTimer(time.Hour)
+	timer.Stop()
+	defer timer.Stop()
+
+	running := false
+	ready := false // the quiet period ended while fn was running
+	finished := make(chan struct{})
+
+	flush := func() {
+		b := w.take()
+		if b.Empty() {
+			return
+		}
+		running, ready = true, false
+		go func() {
+			fn(ctx, b)
+			finished <- struct{}{}
+		}()
+	}
+	schedule := func() {
+		wait := min(w.debounce, w.maxDelay-time.Since(w.first))
+		timer.Reset(max(wait, 0))
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			if running {
+				<-finished
+			}
+			return nil
+
+		case ev, ok := <-w.fs.Events:
+			if !ok {
+				return nil
+			}
+			w.handle(ev)
+			schedule()
+
+		case err, ok := <-w.fs.Errors:
+			if !ok {
+				return nil
+			}
+			if errors.Is(err, fsnotify.ErrEventOverflow) {
+				log.Printf("Warning: file events were lost, rescanning %s\n", w.filter.Root())
+				w.requestRescan()
+				schedule()
+				continue
+			}
+			log.Printf("Warning: file watcher error: %v\n", err)
+
+		case <-timer.C:
+			if running {
+				ready = true
+				continue
+			}
+			flush()
+
+		case <-finished:
+			running = false
+			if ready {
+				flush()
+			}
+		}
+	}
+}
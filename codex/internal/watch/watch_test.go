@@ -0,0 +1,147 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/anthropics/aef/codex/internal/walk"
+)
+
+// startWatcher watches dir and returns a channel of delivered batches.
+func startWatcher(t *testing.T, dir string) <-chan Batch {
+	t.Helper()
+	filter, err := walk.NewFilter(dir, walk.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := New(filter, WithDebounce(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	batches := make(chan Batch, 10)
+	done := make(chan struct{})
+	go func() {
+		w.Run(ctx, func(ctx context.Context, b Batch) { batches <- b })
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+		w.Close()
+	})
+	return batches
+}
+
+// next waits for the next batch.
+func next(t *testing.T, batches <-chan Batch) Batch {
+	t.Helper()
+	select {
+	case b := <-batches:
+		return b
+	case <-time.After(5 * time.Second):
+		t.Fatal("no batch delivered")
+		return Batch{}
+	}
+}
+
+func write(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	t.Run("Given a burst of writes, When the tree goes quiet, Then they arrive as one batch", func(t *testing.T) {
+		dir := t.TempDir()
+		batches := startWatcher(t, dir)
+
+		for range 5 {
+			write(t, filepath.Join(dir, "a.go"), "package a")
+			write(t, filepath.Join(dir, "b.go"), "package b")
+		}
+
+		b := next(t, batches)
+		want := []string{filepath.Join(dir, "a.go"), filepath.Join(dir, "b.go")}
+		if !reflect.DeepEqual(b.Changed, want) || len(b.Removed) != 0 || b.Rescan {
+			t.Errorf("batch = %+v, want changed %q", b, want)
+		}
+	})
+
+	t.Run("Given a new directory, When files are written in it, Then they are reported", func(t *testing.T) {
+		dir := t.TempDir()
+		batches := startWatcher(t, dir)
+
+		write(t, filepath.Join(dir, "pkg", "sub", "c.go"), "package sub")
+		b := next(t, batches)
+		if want := []string{filepath.Join(dir, "pkg", "sub", "c.go")}; !reflect.DeepEqual(b.Changed, want) {
+			t.Errorf("changed = %q, want %q", b.Changed, want)
+		}
+
+		// The new directories are watched too
+		write(t, filepath.Join(dir, "pkg", "sub", "d.go"), "package sub")
+		b = next(t, batches)
+		if want := []string{filepath.Join(dir, "pkg", "sub", "d.go")}; !reflect.DeepEqual(b.Changed, want) {
+			t.Errorf("changed = %q, want %q", b.Changed, want)
+		}
+	})
+
+	t.Run("Given files and a directory, When they are deleted and renamed, Then old paths are removed and new ones changed", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, filepath.Join(dir, "old.go"), "package old")
+		write(t, filepath.Join(dir, "gone.go"), "package gone")
+		write(t, filepath.Join(dir, "lib", "e.go"), "package lib")
+		batches := startWatcher(t, dir)
+
+		os.Rename(filepath.Join(dir, "old.go"), filepath.Join(dir, "new.go"))
+		os.Remove(filepath.Join(dir, "gone.go"))
+		os.Rename(filepath.Join(dir, "lib"), filepath.Join(dir, "lib2"))
+
+		b := next(t, batches)
+		wantRemoved := []string{filepath.Join(dir, "gone.go"), filepath.Join(dir, "lib"), filepath.Join(dir, "old.go")}
+		wantChanged := []string{filepath.Join(dir, "lib2", "e.go"), filepath.Join(dir, "new.go")}
+		if !reflect.DeepEqual(b.Removed, wantRemoved) || !reflect.DeepEqual(b.Changed, wantChanged) {
+			t.Errorf("batch = %+v, want removed %q and changed %q", b, wantRemoved, wantChanged)
+		}
+
+		// The renamed directory is watched under its new name
+		write(t, filepath.Join(dir, "lib2", "f.go"), "package lib")
+		b = next(t, batches)
+		if want := []string{filepath.Join(dir, "lib2", "f.go")}; !reflect.DeepEqual(b.Changed, want) {
+			t.Errorf("changed = %q, want %q", b.Changed, want)
+		}
+	})
+
+	t.Run("Given an ignore file, When it changes, Then a rescan is requested", func(t *testing.T) {
+		dir := t.TempDir()
+		batches := startWatcher(t, dir)
+
+		write(t, filepath.Join(dir, walk.IgnoreFile), "*.txt\n")
+		if b := next(t, batches); !b.Rescan {
+			t.Errorf("batch = %+v, want rescan", b)
+		}
+	})
+
+	t.Run("Given an ignored directory, When files change in it, Then nothing is reported", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, filepath.Join(dir, ".gitignore"), "build/\n")
+		os.MkdirAll(filepath.Join(dir, "build"), 0755)
+		batches := startWatcher(t, dir)
+
+		write(t, filepath.Join(dir, "build", "out.go"), "package out")
+		write(t, filepath.Join(dir, "main.go"), "package main")
+		b := next(t, batches)
+		if want := []string{filepath.Join(dir, "main.go")}; !reflect.DeepEqual(b.Changed, want) {
+			t.Errorf("changed = %q, want %q", b.Changed, want)
+		}
+	})
+}
@@ -51,6 +51,14 @@ recall:
 #   models_path: ~/.edi/models
 #   metadata_db: ~/.edi/codex.db
 #   binary_path: ~/.edi/bin/recall-mcp
+#   embedding_backend: ollama  # "ollama", "openai-compatible" or "llama.cpp"
+#   embedding_url: http://localhost:11434/api/embed
+#   embedding_model: nomic-embed-text
+#   feedback_ranking: off  # "off", "rrf" or "prior"
+#   index:  # files for directory indexing, on top of .gitignore and .codexignore
+#     exclude: ["*.pb.go", "testdata/"]
+#     max_file_size: 1MB
+#     symlinks: files  # "files", "skip" or "follow"
 
 # Session briefing
 briefing:
@@ -81,7 +89,15 @@ func WriteDefaultWithBackend(path string, backend string) error {
 # codex:
 #   models_path: ~/.edi/models
 #   metadata_db: ~/.edi/codex.db
-#   binary_path: ~/.edi/bin/recall-mcp`
+#   binary_path: ~/.edi/bin/recall-mcp
+#   embedding_backend: ollama  # "ollama", "openai-compatible" or "llama.cpp"
+#   embedding_url: http://localhost:11434/api/embed
+#   embedding_model: nomic-embed-text
+#   feedback_ranking: off  # "off", "rrf" or "prior"
+#   index:  # files for directory indexing, on top of .gitignore and .codexignore
+#     exclude: ["*.pb.go", "testdata/"]
+#     max_file_size: 1MB
+#     symlinks: files  # "files", "skip" or "follow"`
 
 	if backend == "codex" {
 		codexSection = `# Codex v1 backend configuration
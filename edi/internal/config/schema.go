@@ -34,9 +34,30 @@ type RecallConfig struct {
 
 // CodexConfig configures the Codex v1 backend (hybrid vector search)
 type CodexConfig struct {
-	ModelsPath   string `yaml:"models_path" mapstructure:"models_path"`     // Path to ONNX reranker models
-	MetadataDB   string `yaml:"metadata_db" mapstructure:"metadata_db"`     // Path to SQLite metadata DB
-	BinaryPath   string `yaml:"binary_path" mapstructure:"binary_path"`     // Path to recall-mcp binary (optional)
+	ModelsPath   string  `yaml:"models_path" mapstructure:"models_path"`     // Path to ONNX reranker models
+	MetadataDB   string  `yaml:"metadata_db" mapstructure:"metadata_db"`     // Path to SQLite metadata DB
+	BinaryPath   string  `yaml:"binary_path" mapstructure:"binary_path"`     // Path to recall-mcp binary (optional)
+	ProjectBoost float64 `yaml:"project_boost" mapstructure:"project_boost"` // Score multiplier for current-project results in scope "both" (optional)
+
+	// FeedbackRanking ranks with recorded recall_feedback: "off" (default), "rrf" or "prior"
+	FeedbackRanking string `yaml:"feedback_ranking" mapstructure:"feedback_ranking"`
+
+	// Embedding backend (optional; defaults to Ollama with nomic-embed-text)
+	EmbeddingBackend string `yaml:"embedding_backend" mapstructure:"embedding_backend"` // "ollama", "openai-compatible" or "llama.cpp"
+	EmbeddingURL     string `yaml:"embedding_url" mapstructure:"embedding_url"`         // Embedding endpoint, backend default if empty
+	EmbeddingModel   string `yaml:"embedding_model" mapstructure:"embedding_model"`     // Model name, selects the prefix/dimension profile
+
+	// Index selects the files Codex indexes from a directory
+	Index CodexIndexConfig `yaml:"index" mapstructure:"index"`
+}
+
+// CodexIndexConfig selects files for Codex directory indexing, on top of
+// .gitignore, .git/info/exclude and .codexignore
+type CodexIndexConfig struct {
+	Include     []string `yaml:"include" mapstructure:"include"`             // Globs files must match (gitignore syntax)
+	Exclude     []string `yaml:"exclude" mapstructure:"exclude"`             // Globs of files and directories to skip
+	MaxFileSize string   `yaml:"max_file_size" mapstructure:"max_file_size"` // e.g. "512KB"; Codex default 1MB
+	Symlinks    string   `yaml:"symlinks" mapstructure:"symlinks"`           // "files" (default), "skip" or "follow"
 }
 
 // BriefingConfig configures session briefing generation
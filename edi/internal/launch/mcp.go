@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/anthropics/aef/edi/internal/config"
@@ -24,6 +25,54 @@ type MCPConfig struct {
 	MCPServers map[string]MCPServerConfig `json:"mcpServers"`
 }
 
+// recallPassthroughEnv lists the variables forwarded to recall-mcp as
+// ${VAR} references when they are set in the launching environment.
+var recallPassthroughEnv = []string{
+	// API keys
+	"ANTHROPIC_API_KEY",
+	"CODEX_API_KEY",
+	"CODEX_EMBEDDING_API_KEY",
+
+	// Embedding
+	"LOCAL_EMBEDDING_URL",
+	"LOCAL_EMBEDDING_MODEL",
+	"CODEX_EMBEDDING_BACKEND",
+	"CODEX_EMBEDDING_QUERY_PREFIX",
+	"CODEX_EMBEDDING_DOCUMENT_PREFIX",
+	"CODEX_EMBEDDING_DIMENSIONS",
+	"CODEX_EMBEDDING_MAX_TOKENS",
+	"CODEX_EMBEDDING_MODEL_CHECK",
+	"CODEX_CHUNK_MAX_TOKENS",
+	"CODEX_VECTOR_INDEX",
+
+	// Contextual enrichment
+	"CODEX_ENRICHMENT_PROVIDER",
+	"CODEX_ENRICHMENT_MODEL",
+	"CODEX_ENRICHMENT_URL",
+
+	// Ranking
+	"CODEX_FEEDBACK_RANKING",
+	"CODEX_FEEDBACK_WEIGHT",
+	"CODEX_FEEDBACK_HALF_LIFE_DAYS",
+	"CODEX_FEEDBACK_PROJECT_WEIGHT",
+	"CODEX_FUSION",
+	"CODEX_FUSION_K",
+	"CODEX_FUSION_WEIGHTS",
+	"CODEX_FUSION_CODE_WEIGHTS",
+
+	// Query expansion
+	"CODEX_EXPANSION_PROVIDER",
+	"CODEX_EXPANSION_MODEL",
+	"CODEX_EXPANSION_URL",
+	"CODEX_EXPANSION_PARAPHRASES",
+
+	// Index file selection
+	"CODEX_INDEX_INCLUDE",
+	"CODEX_INDEX_EXCLUDE",
+	"CODEX_INDEX_MAX_FILE_SIZE",
+	"CODEX_INDEX_SYMLINKS",
+}
+
 // GetRecallMCPConfig returns the MCP server configuration for RECALL based on the backend setting
 func GetRecallMCPConfig(cfg *config.Config, sessionID string) MCPServerConfig {
 	if cfg.Recall.Backend == "codex" {
@@ -70,19 +119,32 @@ func getCodexMCPConfig(cfg *config.Config, sessionID string) MCPServerConfig {
 	if cfg.Codex.MetadataDB != "" {
 		env["CODEX_METADATA_DB"] = expandPath(cfg.Codex.MetadataDB)
 	}
+	if cfg.Codex.ProjectBoost > 0 {
+		env["CODEX_PROJECT_BOOST"] = strconv.FormatFloat(cfg.Codex.ProjectBoost, 'f', -1, 64)
+	}
 
-	// Pass through API keys and embedding config from environment
-	if key := os.Getenv("ANTHROPIC_API_KEY"); key != "" {
-		env["ANTHROPIC_API_KEY"] = "${ANTHROPIC_API_KEY}"
+	// Pass through API keys and Codex settings from the environment
+	for _, key := range recallPassthroughEnv {
+		if os.Getenv(key) != "" {
+			env[key] = "${" + key + "}"
+		}
+	}
+
+	// Embedding backend, feedback ranking and index file selection from config take precedence over the environment
+	if cfg.Codex.EmbeddingBackend != "" {
+		env["CODEX_EMBEDDING_BACKEND"] = cfg.Codex.EmbeddingBackend
+	}
+	if cfg.Codex.EmbeddingURL != "" {
+		env["LOCAL_EMBEDDING_URL"] = cfg.Codex.EmbeddingURL
 	}
-	if url := os.Getenv("LOCAL_EMBEDDING_URL"); url != "" {
-		env["LOCAL_EMBEDDING_URL"] = "${LOCAL_EMBEDDING_URL}"
+	if cfg.Codex.EmbeddingModel != "" {
+		env["LOCAL_EMBEDDING_MODEL"] = cfg.Codex.EmbeddingModel
 	}
-	if model := os.Getenv("LOCAL_EMBEDDING_MODEL"); model != "" {
-		env["LOCAL_EMBEDDING_MODEL"] = "${LOCAL_EMBEDDING_MODEL}"
+	if cfg.Codex.FeedbackRanking != "" {
+		env["CODEX_FEEDBACK_RANKING"] = cfg.Codex.FeedbackRanking
 	}
-	if key := os.Getenv("CODEX_API_KEY"); key != "" {
-		env["CODEX_API_KEY"] = "${CODEX_API_KEY}"
+	for key, val := range CodexIndexEnv(cfg.Codex.Index) {
+		env[key] = val
 	}
 
 	// Pass project context for attribution
@@ -110,6 +172,25 @@ func getCodexMCPConfig(cfg *config.Config, sessionID string) MCPServerConfig {
 	}
 }
 
+// CodexIndexEnv returns the environment variables carrying the index file
+// selection to Codex binaries (recall-mcp, codex-cli). Unset fields are left out.
+func CodexIndexEnv(idx config.CodexIndexConfig) map[string]string {
+	env := make(map[string]string)
+	if len(idx.Include) > 0 {
+		env["CODEX_INDEX_INCLUDE"] = strings.Join(idx.Include, ",")
+	}
+	if len(idx.Exclude) > 0 {
+		env["CODEX_INDEX_EXCLUDE"] = strings.Join(idx.Exclude, ",")
+	}
+	if idx.MaxFileSize != "" {
+		env["CODEX_INDEX_MAX_FILE_SIZE"] = idx.MaxFileSize
+	}
+	if idx.Symlinks != "" {
+		env["CODEX_INDEX_SYMLINKS"] = idx.Symlinks
+	}
+	return env
+}
+
 // WriteMCPConfig writes the MCP configuration to .mcp.json in the project directory
 func WriteMCPConfig(projectDir string, cfg *config.Config, sessionID string) error {
 	if !cfg.Recall.Enabled {
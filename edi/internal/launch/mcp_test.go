@@ -113,6 +113,72 @@ func TestGetRecallMCPConfig_CodexWithCustomBinary(t *testing.T) {
 	}
 }
 
+func TestGetRecallMCPConfig_CodexEmbeddingBackend(t *testing.T) {
+	t.Parallel()
+
+	cfg := &config.Config{
+		Recall: config.RecallConfig{
+			Enabled: true,
+			Backend: "codex",
+		},
+		Codex: config.CodexConfig{
+			EmbeddingBackend: "llama.cpp",
+			EmbeddingURL:     "http://gpu-host:8080/v1/embeddings",
+			EmbeddingModel:   "nomic-embed-text-v1.5",
+			FeedbackRanking:  "prior",
+		},
+	}
+
+	mcpCfg := GetRecallMCPConfig(cfg, "test-session")
+
+	want := map[string]string{
+		"CODEX_EMBEDDING_BACKEND": "llama.cpp",
+		"LOCAL_EMBEDDING_URL":     "http://gpu-host:8080/v1/embeddings",
+		"LOCAL_EMBEDDING_MODEL":   "nomic-embed-text-v1.5",
+		"CODEX_FEEDBACK_RANKING":  "prior",
+	}
+	for key, val := range want {
+		if mcpCfg.Env[key] != val {
+			t.Errorf("Expected %s=%q, got %q", key, val, mcpCfg.Env[key])
+		}
+	}
+}
+
+func TestGetRecallMCPConfig_CodexIndex(t *testing.T) {
+	cfg := &config.Config{
+		Recall: config.RecallConfig{
+			Enabled: true,
+			Backend: "codex",
+		},
+		Codex: config.CodexConfig{
+			Index: config.CodexIndexConfig{
+				Include:     []string{"*.go", "docs/**"},
+				Exclude:     []string{"*.pb.go"},
+				MaxFileSize: "256KB",
+				Symlinks:    "skip",
+			},
+		},
+	}
+
+	mcpCfg := GetRecallMCPConfig(cfg, "test-session")
+
+	want := map[string]string{
+		"CODEX_INDEX_INCLUDE":       "*.go,docs/**",
+		"CODEX_INDEX_EXCLUDE":       "*.pb.go",
+		"CODEX_INDEX_MAX_FILE_SIZE": "256KB",
+		"CODEX_INDEX_SYMLINKS":      "skip",
+	}
+	for key, val := range want {
+		if mcpCfg.Env[key] != val {
+			t.Errorf("Expected %s=%q, got %q", key, val, mcpCfg.Env[key])
+		}
+	}
+
+	if env := CodexIndexEnv(config.CodexIndexConfig{}); len(env) != 0 {
+		t.Errorf("Expected no env for an empty index config, got %v", env)
+	}
+}
+
 func TestWriteMCPConfig(t *testing.T) {
 	t.Parallel()
 	tmpDir := t.TempDir()